@@ -0,0 +1,103 @@
+// Code generated by tsgen from the Go response types; DO NOT EDIT.
+
+// From envelope.go
+// ResponseStatus is the "status" member of every envelope
+export type ResponseStatus = "success" | "fail" | "error";
+// APIResponse is the success envelope written by WriteJSON.
+// Members are encoded in field order, so keep the order stable.
+export interface APIResponse<T = unknown> {
+  status: ResponseStatus;
+  statusCode: number;
+  message: string;
+  data: T;
+}
+// ErrorBody is the classic error envelope written by WriteError
+// (application/problem+json clients get ProblemDetails instead)
+export interface ErrorBody {
+  status: ResponseStatus;
+  statusCode: number;
+  code: Code;
+  message: string;
+  details?: unknown;
+}
+// ListMeta describes a list payload. Page and Total are only set by paginated endpoints,
+// Query by endpoints that take filters.
+export interface ListMeta {
+  count: number;
+  page?: number;
+  total?: number;
+  query?: QueryMeta | null;
+}
+// QueryMeta echoes how a list endpoint read its query string, after validation
+// and normalization, so a parameter that had no effect shows up as such
+export interface QueryMeta {
+  filters: Record<string, unknown>;
+  sort: SortMeta | null;
+  fields?: string[];
+  ignored?: IgnoredParam[];
+}
+// SortMeta is the order a list came back in
+export interface SortMeta {
+  field: string;
+  order: string;
+}
+// IgnoredParam is a query parameter that was accepted but had no effect
+export interface IgnoredParam {
+  param: string;
+  value: string;
+  reason: string;
+}
+// List is the data of list endpoints: the meta members, then the items
+export interface List<T> extends ListMeta {
+  data: T[];
+}
+
+// From problem.go
+// ProblemDetails is the RFC 7807 body. Code (see package errcodes) and Errors,
+// carrying field-level validation details, are our extension members.
+export interface ProblemDetails {
+  type: string;
+  title: string;
+  status: number;
+  code: Code;
+  detail?: string;
+  instance?: string;
+  errors?: unknown;
+}
+
+// From ../errcodes/errcodes.go
+// Code is the "code" member of every error envelope
+export type Code = "BAD_REQUEST" | "INVALID_ID" | "VALIDATION_FAILED" | "UNAUTHORIZED" | "FORBIDDEN" | "NOT_FOUND" | "CONFLICT" | "PRECONDITION_FAILED" | "PAYLOAD_TOO_LARGE" | "RATE_LIMITED" | "REQUEST_CANCELED" | "INTERNAL" | "SERVICE_UNAVAILABLE" | "NOT_LOGGED_IN" | "INVALID_CREDENTIALS" | "TOKEN_INVALID" | "TOKEN_EXPIRED" | "TOKEN_WRONG_AUDIENCE" | "PASSWORD_CHANGED" | "ACCOUNT_DEACTIVATED" | "ACCOUNT_GONE" | "PASSWORD_CHANGE_REQUIRED" | "LOGIN_THROTTLED" | "CAPTCHA_REQUIRED" | "CLASS_NOT_ASSIGNED" | "TEACHER_NOT_FOUND" | "STUDENT_NOT_FOUND" | "EMAIL_TAKEN" | "ADMISSION_NUMBER_TAKEN" | "HAS_DEPENDENTS" | "QUOTA_EXCEEDED" | "UPLOAD_PENDING_SCAN" | "UPLOAD_BLOCKED" | "EMAIL_CHANGE_REQUIRED" | "EMAIL_CHANGE_LINK_INVALID" | "CONSENT_LINK_INVALID" | "PATCH_INVALID" | "PATCH_TEST_FAILED";
+
+// From ../../internal/models/validator.go
+// ValidationError is your clean, public-facing error format.
+// Msg is English; Localize rewrites it for the client's language (see messages.go).
+export interface ValidationError {
+  field: string;
+  msg: string;
+  tag?: string;
+  index?: number | null;
+  allowed?: string[];
+}
+
+// From ../../internal/models/bulk.go
+// BulkStatus is the outcome of one item in a BulkResult
+export type BulkStatus = "created" | "updated" | "deleted" | "not_found" | "invalid" | "conflict" | "failed" | "skipped";
+// BulkItem is the outcome for one element of a bulk request, in request order
+export interface BulkItem<T> {
+  index: number;
+  id?: number;
+  status: BulkStatus;
+  code: number;
+  error?: string;
+  errors?: ValidationError[];
+  data?: T | null;
+}
+// BulkResult is the response body of the bulk endpoints, so clients handle
+// mixed outcomes the same way everywhere. Skipped items count as failed.
+export interface BulkResult<T> {
+  dry_run?: boolean;
+  succeeded: number;
+  failed: number;
+  items: BulkItem<T>[];
+}
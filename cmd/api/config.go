@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"os"
+	mw "simpleapi/internal/api/middlewares"
+	"simpleapi/internal/buildinfo"
+	"simpleapi/internal/models"
+	"simpleapi/pkg/clock"
+	"simpleapi/pkg/phone"
+	"simpleapi/pkg/redact"
+	"simpleapi/pkg/secrets"
+	"simpleapi/pkg/utils"
+	"strconv"
+	"strings"
+)
+
+// setupLogging makes structured logs the default, each line carrying the
+// version (LOG_FORMAT=text|json); the log package's output goes through them too
+func setupLogging() {
+	var logHandler slog.Handler
+	switch v := os.Getenv("LOG_FORMAT"); v {
+	case "", "text":
+		logHandler = slog.NewTextHandler(os.Stderr, nil)
+	case "json":
+		logHandler = slog.NewJSONHandler(os.Stderr, nil)
+	default:
+		log.Fatalf("Invalid LOG_FORMAT %q, want text or json", v)
+	}
+	// Secrets and personal data stay out of the logs unless LOG_REDACT=false;
+	// LOG_REDACT_FIELDS adds comma-separated field patterns, like *nin*,guardian
+	if on, _ := strconv.ParseBool(os.Getenv("LOG_REDACT")); on || os.Getenv("LOG_REDACT") == "" {
+		fields := redact.DefaultLogFields
+		for _, f := range strings.Split(os.Getenv("LOG_REDACT_FIELDS"), ",") {
+			if f = strings.TrimSpace(f); f != "" {
+				fields = append(fields, f)
+			}
+		}
+		redacting, err := redact.NewLogHandler(logHandler, fields)
+		if err != nil {
+			log.Fatalf("Invalid LOG_REDACT_FIELDS: %v", err)
+		}
+		logHandler = redacting
+	}
+	slog.SetDefault(slog.New(logHandler).With("version", buildinfo.Version()))
+	build := buildinfo.Get()
+	slog.Info("starting "+buildinfo.Product, "commit", build.Commit, "built", build.Date, "go", build.GoVersion)
+}
+
+// loadTokens sets up JWT signing from JWT_SECRET_KEY, which it returns for the
+// self-check, and follows its rotations
+func loadTokens(store *secrets.Store, clk clock.Clock) (*utils.TokenService, string) {
+	jwtSecret, err := store.Get(context.Background(), "JWT_SECRET_KEY")
+	if err != nil {
+		log.Fatalf("Could not load JWT_SECRET_KEY: %v", err)
+	}
+	// JWT_PREVIOUS_SECRET_KEY keeps tokens signed before a rotation valid across a restart
+	previousJWTSecret, err := store.Get(context.Background(), "JWT_PREVIOUS_SECRET_KEY")
+	if err != nil {
+		log.Fatalf("Could not load JWT_PREVIOUS_SECRET_KEY: %v", err)
+	}
+	// JWT_TTL=mobile=1h,kiosk=8h overrides token lifetimes per audience (see utils.Audience)
+	tokenTTLs, err := utils.ParseTokenTTLs(os.Getenv("JWT_TTL"))
+	if err != nil {
+		log.Fatalf("Invalid JWT_TTL: %v", err)
+	}
+	// JWT_ISSUER names this deployment in tokens (default "school-app"); changing it signs everyone out
+	tokens, err := utils.NewTokenService(utils.TokenConfig{
+		Keys:   [][]byte{[]byte(jwtSecret), []byte(previousJWTSecret)},
+		Issuer: os.Getenv("JWT_ISSUER"),
+		TTLs:   tokenTTLs,
+		Clock:  clk,
+	})
+	if err != nil {
+		log.Fatalf("Could not set up tokens: %v", err)
+	}
+	store.OnRotate("JWT_SECRET_KEY", func(v string) {
+		if err := tokens.Rotate([]byte(v)); err != nil {
+			log.Printf("Ignoring rotated JWT_SECRET_KEY: %v", err)
+		}
+	})
+	return tokens, jwtSecret
+}
+
+// loadSigner sets up the key that signs exported transcripts, ID card QR codes
+// and download links; without it ?format=signed, /idcard and the .../link
+// endpoints answer 503
+func loadSigner(store *secrets.Store) *utils.DocumentSigner {
+	signingKey, err := store.Get(context.Background(), "TRANSCRIPT_SIGNING_KEY")
+	if err != nil {
+		log.Fatalf("Could not load TRANSCRIPT_SIGNING_KEY: %v", err)
+	}
+	if signingKey == "" {
+		log.Println("TRANSCRIPT_SIGNING_KEY is not set, signed transcript exports, ID cards and download links are disabled")
+	}
+	// TRANSCRIPT_PREVIOUS_SIGNING_KEYS (comma-separated) keep documents signed before a rotation verifying across a restart
+	previousSigningKeys, err := store.Get(context.Background(), "TRANSCRIPT_PREVIOUS_SIGNING_KEYS")
+	if err != nil {
+		log.Fatalf("Could not load TRANSCRIPT_PREVIOUS_SIGNING_KEYS: %v", err)
+	}
+	signingKeys := [][]byte{[]byte(signingKey)}
+	for _, k := range strings.Split(previousSigningKeys, ",") {
+		signingKeys = append(signingKeys, []byte(strings.TrimSpace(k)))
+	}
+	signer := utils.NewDocumentSigner(signingKeys...)
+	store.OnRotate("TRANSCRIPT_SIGNING_KEY", func(v string) {
+		if err := signer.Rotate([]byte(v)); err != nil {
+			log.Printf("Ignoring rotated TRANSCRIPT_SIGNING_KEY: %v", err)
+		}
+	})
+	return signer
+}
+
+// loadPasswordHasher mixes PASSWORD_PEPPER into password hashes; "2:new,1:old"
+// keeps old hashes verifying during a rotation
+func loadPasswordHasher(store *secrets.Store) *utils.PasswordHasher {
+	pepperValue, err := store.Get(context.Background(), "PASSWORD_PEPPER")
+	if err != nil {
+		log.Fatalf("Could not load PASSWORD_PEPPER: %v", err)
+	}
+	peppers, err := utils.ParsePeppers(pepperValue)
+	if err != nil {
+		log.Fatalf("Invalid PASSWORD_PEPPER: %v", err)
+	}
+	passwords := utils.NewPasswordHasher(peppers)
+	store.OnRotate("PASSWORD_PEPPER", func(v string) {
+		peppers, err := utils.ParsePeppers(v)
+		if err != nil {
+			log.Printf("Ignoring rotated PASSWORD_PEPPER: %v", err)
+			return
+		}
+		passwords.SetPeppers(peppers)
+	})
+	return passwords
+}
+
+// loadKioskAuth lets the front-desk tablets in (KIOSK_API_KEYS=frontdesk=<key>,gate:checkin=<key>);
+// unset, /kiosk refuses every request
+func loadKioskAuth(store *secrets.Store) *mw.KioskAuth {
+	kioskKeysValue, err := store.Get(context.Background(), "KIOSK_API_KEYS")
+	if err != nil {
+		log.Fatalf("Could not load KIOSK_API_KEYS: %v", err)
+	}
+	kioskKeys, err := mw.ParseKioskKeys(kioskKeysValue)
+	if err != nil {
+		log.Fatalf("Invalid KIOSK_API_KEYS: %v", err)
+	}
+	kioskAuth := mw.NewKioskAuth(kioskKeys)
+	store.OnRotate("KIOSK_API_KEYS", func(v string) {
+		keys, err := mw.ParseKioskKeys(v)
+		if err != nil {
+			log.Printf("Ignoring rotated KIOSK_API_KEYS: %v", err)
+			return
+		}
+		kioskAuth.SetKeys(keys)
+	})
+	return kioskAuth
+}
+
+// setupValidation reads phone numbers typed without a country code in
+// PHONE_DEFAULT_REGION (e.g. NG). Validation messages are English plus
+// VALIDATION_LANGUAGE (e.g. fr), picked per request from Accept-Language;
+// VALIDATION_MESSAGES_FILE adds languages or overrides messages.
+func setupValidation() {
+	if err := phone.SetDefaultRegion(os.Getenv("PHONE_DEFAULT_REGION")); err != nil {
+		log.Fatalf("Invalid PHONE_DEFAULT_REGION: %v", err)
+	}
+	if path := os.Getenv("VALIDATION_MESSAGES_FILE"); path != "" {
+		if err := models.LoadMessagesFile(path); err != nil {
+			log.Fatalf("Could not load VALIDATION_MESSAGES_FILE: %v", err)
+		}
+	}
+	if err := models.SetSecondLanguage(os.Getenv("VALIDATION_LANGUAGE")); err != nil {
+		log.Fatalf("Invalid VALIDATION_LANGUAGE: %v", err)
+	}
+}
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"simpleapi/internal/eventbus"
+	"simpleapi/internal/jobs"
+	"simpleapi/internal/metrics"
+	"simpleapi/internal/models"
+	"simpleapi/internal/scan"
+	"simpleapi/internal/selfcheck"
+	"simpleapi/internal/siem"
+	"simpleapi/internal/webhook"
+	"slices"
+	"strings"
+	"time"
+)
+
+// background is the job machinery the handlers hand work to
+type background struct {
+	queue          *jobs.Queue
+	archiver       *jobs.YearArchiver
+	rollover       *jobs.Rollover
+	backuper       *jobs.Backuper
+	uploadScans    *jobs.UploadScans
+	retention      *jobs.Retention
+	notices        *jobs.AttendanceNotices
+	campaigns      *jobs.CampaignSender
+	trashRetention time.Duration
+}
+
+// startJobs starts the queue, picks up the work a restart cut short and starts
+// the periodic jobs. The publishers it returns are for main to close.
+func startJobs(a *app) (*background, []io.Closer) {
+	bg := &background{}
+
+	// Deleted teachers stay restorable for TRASH_RETENTION (e.g. 720h), then get purged
+	bg.trashRetention = envDuration("TRASH_RETENTION", 30*24*time.Hour)
+	jobs.StartTrashPurge(context.Background(), a.teachers, a.clk, bg.trashRetention, time.Hour)
+
+	// Slow work (academic-year archives, backups) runs on an in-process queue (JOB_WORKERS, default 2).
+	// A failing job is tried JOB_MAX_ATTEMPTS times (default 3), JOB_RETRY_BACKOFF apart
+	// (default 30s, doubling), then dead-lettered and the admins emailed
+	deadLetters := &jobs.DeadLetterNotices{Mailer: a.mailer, Teachers: a.teachers, School: a.school}
+	bg.queue = jobs.StartQueue(context.Background(), jobs.QueueConfig{
+		Workers:     envCount("JOB_WORKERS", 2),
+		Size:        100,
+		MaxAttempts: envCount("JOB_MAX_ATTEMPTS", 3),
+		Backoff:     envDuration("JOB_RETRY_BACKOFF", 30*time.Second),
+		DeadLetter:  deadLetters.Notify,
+	})
+	bg.archiver = &jobs.YearArchiver{
+		Archives: a.archives,
+		Students: a.students,
+		Teachers: a.teachers,
+		Scores:   a.scores,
+		Storage:  a.files,
+		Clock:    a.clk,
+	}
+	bg.archiver.Recover(context.Background())
+	bg.rollover = &jobs.Rollover{Plans: a.rollovers, Units: a.units, Archiver: bg.archiver}
+	bg.rollover.Recover(context.Background())
+
+	// Backups dump every table the API needs except their own bookkeeping
+	bg.backuper = &jobs.Backuper{
+		Backups: a.backups,
+		DB:      a.db,
+		Tables:  slices.DeleteFunc(slices.Clone(selfcheck.RequiredTables), func(t string) bool { return t == "backups" }),
+		Storage: a.files,
+		Clock:   a.clk,
+	}
+	if a.backups != nil {
+		bg.backuper.Recover(context.Background())
+	}
+
+	// Uploaded files stay blocked until the virus scanner clears them (UPLOAD_SCANNER, see package scan)
+	scanner, err := scan.FromEnv()
+	if err != nil {
+		log.Fatalf("Could not configure upload scanner: %v", err)
+	}
+	if _, ok := scanner.(scan.Nop); ok {
+		log.Println("UPLOAD_SCANNER is not set, uploads are not virus scanned")
+	}
+	bg.uploadScans = &jobs.UploadScans{
+		Uploads:  a.uploads,
+		Teachers: a.teachers,
+		Storage:  a.files,
+		Scanner:  scanner,
+		Notifier: a.notifier,
+		Queue:    bg.queue,
+	}
+	bg.queue.Enqueue(bg.uploadScans.RescanJob()) // Scans a restart cut short
+
+	// Old messages and logs are anonymized or deleted (RETENTION, e.g. messages=2y,logs=180d)
+	retentionPolicies, err := models.ParseRetention(os.Getenv("RETENTION"))
+	if err != nil {
+		log.Fatalf("Invalid RETENTION: %v", err)
+	}
+	bg.retention = &jobs.Retention{Store: a.retention, Policies: retentionPolicies, Clock: a.clk}
+
+	// The school KPIs on /metrics are recounted every KPI_REFRESH_INTERVAL (default 5m).
+	// Not on the scheduler: each instance exports its own gauges, so each refreshes them.
+	kpiRefresh := &jobs.KPIRefresh{Reports: a.reports, Gauges: metrics.SchoolKPIs, Clock: a.clk}
+	kpiRefresh.Start(context.Background(), envDuration("KPI_REFRESH_INTERVAL", 5*time.Minute))
+
+	// With MySQL the connection pool is sampled for /metrics every DB_POOL_SAMPLE_INTERVAL
+	// (default 15s); once queries wait more than DB_POOL_WAIT_THRESHOLD (default 1s) in
+	// all during one interval, a warning is logged and /readyz answers 503. 0 turns that off.
+	if a.db != nil {
+		poolThreshold := time.Second
+		if v := os.Getenv("DB_POOL_WAIT_THRESHOLD"); v != "" {
+			if poolThreshold, err = time.ParseDuration(v); err != nil || poolThreshold < 0 {
+				log.Fatalf("Invalid DB_POOL_WAIT_THRESHOLD %q", v)
+			}
+		}
+		poolMonitor := &jobs.PoolMonitor{DB: a.db, Gauges: metrics.DBPool, Threshold: poolThreshold, Clock: a.clk}
+		poolMonitor.Start(context.Background(), envDuration("DB_POOL_SAMPLE_INTERVAL", 15*time.Second))
+	}
+
+	publishers := startOutboxRelay(a)
+
+	bg.notices = &jobs.AttendanceNotices{
+		Attendance: a.attendance,
+		Teachers:   a.teachers,
+		Students:   a.students,
+		Events:     a.events,
+		Notifier:   a.notifier,
+		School:     a.school,
+		Clock:      a.clk,
+	}
+	startScheduler(a, bg)
+
+	// Authentication events join the audit trail, on a queue of their own (see jobs.AuthAudit)
+	metrics.AuthEvents.SetClock(a.clk)
+	authAudit := &jobs.AuthAudit{Audit: a.audit, Queue: jobs.StartQueue(context.Background(), jobs.QueueConfig{Workers: 1, Size: 1000})}
+	metrics.AuthEvents.SetSink(authAudit.Record)
+
+	bg.campaigns = &jobs.CampaignSender{
+		Campaigns: a.campaigns,
+		Teachers:  a.teachers,
+		Students:  a.students,
+		Mailer:    a.mailer,
+		School:    a.school,
+	}
+	bg.campaigns.Recover(context.Background())
+	return bg, publishers
+}
+
+// startOutboxRelay relays audited changes. They are events, kept in the outbox
+// table until the relay posts them to OUTBOX_WEBHOOK_URL, signed with
+// OUTBOX_WEBHOOK_SECRET (see package webhook), publishes them on the EVENT_BUS
+// (see package eventbus) and forwards them to SIEM_FORWARD_URL, every
+// OUTBOX_RELAY_INTERVAL (default 5s). With none of these they are marked
+// delivered unsent.
+func startOutboxRelay(a *app) []io.Closer {
+	var outboxPublishers jobs.Publishers
+	var closers []io.Closer
+	if url := os.Getenv("OUTBOX_WEBHOOK_URL"); url != "" {
+		secret, err := a.secrets.Get(context.Background(), "OUTBOX_WEBHOOK_SECRET")
+		if err != nil || secret == "" {
+			log.Fatalf("OUTBOX_WEBHOOK_URL needs OUTBOX_WEBHOOK_SECRET: %v", err)
+		}
+		outboxPublishers = append(outboxPublishers, webhook.New(url, []byte(secret), a.clk))
+	}
+	if kind := os.Getenv("EVENT_BUS"); kind != "" {
+		busURL, err := a.secrets.Get(context.Background(), "EVENT_BUS_URL")
+		if err != nil || busURL == "" {
+			log.Fatalf("EVENT_BUS needs EVENT_BUS_URL: %v", err)
+		}
+		bus, err := eventbus.Open(kind, busURL)
+		if err != nil {
+			log.Fatalf("Could not set up the event bus: %v", err)
+		}
+		closers = append(closers, bus)
+		busPublisher, err := eventbus.NewPublisher(bus, os.Getenv("EVENT_BUS_TOPIC"))
+		if err != nil {
+			log.Fatalf("Could not set up the event bus: %v", err)
+		}
+		outboxPublishers = append(outboxPublishers, busPublisher)
+		log.Printf("Publishing change events to %s", bus.Name())
+	}
+	// SIEM_FORWARD_URL sends every event to the district's SIEM as well, over
+	// syslog or HTTP, formatted as SIEM_FORMAT (see package siem)
+	if siemURL := os.Getenv("SIEM_FORWARD_URL"); siemURL != "" {
+		var token string
+		if strings.HasPrefix(siemURL, "http") {
+			var err error
+			if token, err = a.secrets.Get(context.Background(), "SIEM_FORWARD_TOKEN"); err != nil {
+				log.Fatalf("Could not read SIEM_FORWARD_TOKEN: %v", err)
+			}
+		}
+		forwarder, err := siem.NewForwarder(siemURL, os.Getenv("SIEM_FORMAT"), token)
+		if err != nil {
+			log.Fatalf("Could not set up the SIEM forwarder: %v", err)
+		}
+		closers = append(closers, forwarder)
+		outboxPublishers = append(outboxPublishers, forwarder)
+		log.Printf("Forwarding audit events to %s", forwarder.Name())
+	}
+	outboxRelay := &jobs.OutboxRelay{Outbox: a.outbox, Locks: a.locks, Clock: a.clk}
+	if len(outboxPublishers) > 0 {
+		outboxRelay.Publisher = outboxPublishers
+	}
+	outboxRelay.Start(context.Background(), envDuration("OUTBOX_RELAY_INTERVAL", 5*time.Second))
+	return closers
+}
+
+// startScheduler runs the recurring jobs on a cron-like scheduler in the
+// school's time zone. Each SCHEDULE_* setting is a cron expression (see
+// jobs.Schedule) or "off".
+func startScheduler(a *app, bg *background) {
+	scheduler := jobs.NewScheduler(a.clk, a.locks)
+	for _, s := range []struct {
+		env, spec string
+		job       jobs.Job
+	}{
+		{"SCHEDULE_ATTENDANCE_REMINDER", "0 10 * * mon-fri", bg.notices.RemindJob()}, // The time is the register cutoff
+		{"SCHEDULE_ABSENCE_SUMMARY", "0 16 * * fri", bg.notices.SummaryJob()},
+		{"SCHEDULE_ATTENDANCE_THRESHOLDS", "0 17 * * mon-fri", bg.notices.ThresholdJob()}, // After the day's registers
+		{"SCHEDULE_RESET_TOKEN_EXPIRY", "*/15 * * * *", jobs.ExpireResetTokensJob(a.teachers, a.clk)},
+		{"SCHEDULE_UPLOAD_RESCAN", "*/10 * * * *", bg.uploadScans.RescanJob()}, // Retries scans that failed or were lost
+		{"SCHEDULE_RETENTION", "30 2 * * *", bg.retention.Job()},
+		{"SCHEDULE_ATTENDANCE_SUMMARY", "15 0 * * *", jobs.SummarizeAttendanceJob(a.reports, a.clk)}, // Reports read the days before today from it
+	} {
+		spec := os.Getenv(s.env)
+		if spec == "" {
+			spec = s.spec
+		}
+		if spec == "off" {
+			continue
+		}
+		if err := scheduler.Add(spec, s.job); err != nil {
+			log.Fatalf("Invalid %s: %v", s.env, err)
+		}
+	}
+	scheduler.Start(context.Background())
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+	"simpleapi/internal/api/router"
+	"simpleapi/internal/approvals"
+	"simpleapi/internal/breach"
+	"simpleapi/internal/captcha"
+	"simpleapi/internal/enrollment"
+	"simpleapi/internal/jobs"
+	"simpleapi/internal/loginguard"
+	"simpleapi/internal/metrics"
+	"simpleapi/internal/policy"
+	"simpleapi/internal/quota"
+	"simpleapi/internal/ratelimit"
+	"simpleapi/internal/web"
+	"simpleapi/pkg/secrets"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// routes creates the handlers and the router, and returns the rate limits
+// for the middleware chain too
+func routes(a *app, bg *background, kioskAuth *mw.KioskAuth) (http.Handler, func(http.Handler) http.Handler) {
+	// SMS delivery webhooks must carry SMS_WEBHOOK_TOKEN as ?token=; unset, the webhook is off
+	smsWebhookToken := loadSecret(a.secrets, "SMS_WEBHOOK_TOKEN")
+	// POST /setup creates the first admin of a fresh install; with SETUP_TOKEN set it must be sent in X-Setup-Token
+	setupToken := loadSecret(a.secrets, "SETUP_TOKEN")
+	// Prometheus scrapes /metrics with METRICS_TOKEN as a bearer token; unset, /metrics refuses every request
+	metricsToken := loadSecret(a.secrets, "METRICS_TOKEN")
+
+	// Login flags passwords found in breaches (PASSWORD_BREACH_CHECK=bloom|hibp, see package breach)
+	breaches, err := breach.CheckerFromEnv()
+	if err != nil {
+		log.Fatalf("Could not configure the password breach check: %v", err)
+	}
+	logins := loadLoginGuard(a)
+
+	// The /reports figures are cached for REPORTS_CACHE_TTL (default 1h)
+	reportsCacheTTL := envDuration("REPORTS_CACHE_TTL", time.Hour)
+
+	// Level 2: Create the Handler (injects Repo)
+	// Teachers change grades, attendance and comments of the classes they are assigned to only
+	classPolicy := policy.New(a.assignments)
+	// Quotas are enforced by the repositories; the monitor warns when one is nearly used up
+	quotaNotices := &jobs.QuotaNotices{Mailer: a.mailer, Teachers: a.teachers, School: a.school}
+	quotaMonitor := quota.New(a.schools, a.school, quotaNotices, bg.queue, a.clk)
+	appURL := strings.TrimSuffix(os.Getenv("APP_URL"), "/")
+	teacherHandler := handlers.NewTeacherHandler(a.teachers, a.reference, breaches, logins, a.passwords, a.tokens, a.cookies, a.delivery, a.clk)
+	studentHandler := handlers.NewStudentHandler(a.students, a.customFields, a.reference, a.clk, quotaMonitor, enrollment.New(a.students, a.reference))
+	commentHandler := handlers.NewCommentHandler(a.comments, a.students, a.scores, classPolicy)
+	gradingHandler := handlers.NewGradingHandler(a.grading, a.scores, a.students, classPolicy)
+	transcriptHandler := handlers.NewTranscriptHandler(a.students, a.scores, a.grading, a.signer, a.clk, a.school)
+	idCardHandler := handlers.NewIDCardHandler(a.students, a.signer, a.clk, a.school)
+	kioskHandler := handlers.NewKioskHandler(a.attendance, a.students, a.signer, a.clk)
+	smsHandler := handlers.NewSMSHandler(a.notifier, a.students, smsWebhookToken)
+	eventHandler := handlers.NewEventHandler(a.events, a.clk, a.school)
+	trashHandler := handlers.NewTrashHandler(a.teachers, bg.trashRetention)
+	historyHandler := handlers.NewHistoryHandler(a.audit, a.teachers, a.students)
+	directoryHandler := handlers.NewDirectoryHandler(a.teachers, a.responses, 5*time.Minute)
+	photoHandler := handlers.NewPhotoHandler(a.students, a.files, a.signer, a.clk)
+	attendanceHandler := handlers.NewAttendanceHandler(a.attendance, a.students, classPolicy, a.clk)
+	registerHandler := handlers.NewClassRegisterHandler(a.students, a.events, a.files, classPolicy, a.clk, a.school)
+	promotionHandler := handlers.NewPromotionHandler(a.promotions, a.students, a.scores, a.attendance)
+	rolloverHandler := handlers.NewRolloverHandler(a.rollovers, a.students, a.teachers, a.assignments, a.reference, bg.rollover, bg.queue)
+	configHandler := handlers.NewConfigHandler(a.grading)
+	threadNotices := &jobs.ThreadNotices{Students: a.students, Notifier: a.notifier}
+	threadHandler := handlers.NewThreadHandler(a.threads, a.students, a.uploads, a.files, threadNotices, bg.uploadScans, bg.queue, quotaMonitor, a.signer, a.clk)
+	uploadHandler := handlers.NewUploadHandler(a.uploads, a.files)
+	assignmentHandler := handlers.NewAssignmentHandler(a.teachers, a.assignments, a.units)
+	emailChangeNotices := &jobs.EmailChangeNotices{Mailer: a.mailer, AppURL: appURL, School: a.school, Teachers: a.teachers, Clock: a.clk}
+	emailChangeHandler := handlers.NewEmailChangeHandler(a.emailChanges, emailChangeNotices, bg.queue, a.clk)
+	retentionHandler := handlers.NewRetentionHandler(bg.retention)
+	auditHandler := handlers.NewAuditHandler(a.audit, a.clk)
+	downloadHandler := handlers.NewDownloadHandler(a.files, a.signer, a.clk)
+	securityHandler := handlers.NewSecurityHandler(metrics.AuthEvents)
+	jobHandler := handlers.NewJobHandler(bg.queue)
+	diagnosticsHandler := handlers.NewDiagnosticsHandler(a.db, bg.queue, a.clk)
+	customFieldHandler := handlers.NewCustomFieldHandler(a.customFields)
+	schemaHandler := handlers.NewSchemaHandler(a.customFields)
+	preferenceHandler := handlers.NewPreferenceHandler(a.teachers)
+	schoolHandler := handlers.NewSchoolHandler(a.schools, a.school, a.files, quotaMonitor)
+	referenceHandler := handlers.NewReferenceHandler(a.reference)
+	approvalEngine := approvals.New(a.approvals,
+		approvals.Absence(),
+		approvals.GradeCorrection(a.scores, a.grading, a.students, classPolicy),
+		approvals.FeeWaiver(a.students),
+	)
+	approvalNotices := &jobs.ApprovalNotices{Mailer: a.mailer, Teachers: a.teachers, AppURL: appURL}
+	approvalHandler := handlers.NewApprovalHandler(approvalEngine, a.approvals, approvalNotices, bg.queue)
+	communicationHandler := handlers.NewCommunicationHandler(a.campaigns, bg.campaigns, bg.queue)
+	setupHandler := handlers.NewSetupHandler(a.teachers, a.passwords, setupToken)
+	medicalHandler := handlers.NewMedicalHandler(a.students, a.medical, a.clk, a.school)
+	tripNotices := &jobs.TripConsentNotices{Notifier: a.notifier, AppURL: appURL}
+	tripHandler := handlers.NewTripHandler(a.trips, a.students, classPolicy, tripNotices, bg.queue, a.clk)
+	examHandler := handlers.NewExamHandler(a.exams, a.students, a.teachers, a.clk, a.school)
+	letterMails := &jobs.LetterMails{Mailer: a.mailer, Documents: a.documents, Clock: a.clk}
+	letterHandler := handlers.NewLetterHandler(a.letters, a.documents, a.students, a.files, letterMails, bg.queue, a.clk, a.school)
+	reportHandler := handlers.NewReportHandler(a.reports, a.responses, bg.notices, a.clk, reportsCacheTTL)
+	metricsHandler := handlers.NewMetricsHandler(metricsToken)
+	healthHandler := handlers.NewHealthHandler(metrics.DBPool)
+	archiveHandler := handlers.NewArchiveHandler(a.archives, bg.archiver, bg.queue, a.files)
+	backupHandler := handlers.NewBackupHandler(a.backups, bg.backuper, bg.queue, a.files)
+
+	// SERVE_SPA=true serves the admin SPA embedded at build time (see package web) under /
+	var spaHandler *handlers.SPAHandler
+	if serveSPA, _ := strconv.ParseBool(os.Getenv("SERVE_SPA")); serveSPA {
+		files, ok := web.Dist()
+		if !ok {
+			log.Fatalf("SERVE_SPA is on but this binary has no SPA build; build it into internal/web/dist first")
+		}
+		if spaHandler, err = handlers.NewSPAHandler(files); err != nil {
+			log.Fatalf("Could not load the SPA: %v", err)
+		}
+	}
+
+	// Every client is rate limited by role (RATE_LIMITS, see ratelimit.ParseQuotas)
+	rateQuotas, err := ratelimit.ParseQuotas(os.Getenv("RATE_LIMITS"))
+	if err != nil {
+		log.Fatalf("Invalid RATE_LIMITS: %v", err)
+	}
+	mw.SetRateLimitStore(a.rateStore)
+	rateLimiter := mw.NewRoleRateLimiter(rateQuotas, kioskAuth, a.tokens, a.cookies, a.delivery)
+	// On top of that, the school's requests quota caps all clients together
+	schoolLimiter := mw.NewSchoolRateLimiter(quotaMonitor)
+	limits := func(next http.Handler) http.Handler {
+		return rateLimiter.Middleware(schoolLimiter.Middleware(next))
+	}
+
+	authMiddleware := mw.NewAuthMiddleware(a.teachers, a.tokens, a.cookies, a.delivery)
+	// Level 3: Create the Router (injects Handler)
+	mux := router.Router(router.Handlers{
+		Teachers:     teacherHandler,
+		Students:     studentHandler,
+		Comments:     commentHandler,
+		Grading:      gradingHandler,
+		Transcripts:  transcriptHandler,
+		SMS:          smsHandler,
+		Events:       eventHandler,
+		Trash:        trashHandler,
+		History:      historyHandler,
+		Directory:    directoryHandler,
+		Photos:       photoHandler,
+		Attendance:   attendanceHandler,
+		Promotions:   promotionHandler,
+		Rollover:     rolloverHandler,
+		Config:       configHandler,
+		Threads:      threadHandler,
+		Archives:     archiveHandler,
+		Backups:      backupHandler,
+		Uploads:      uploadHandler,
+		Assignments:  assignmentHandler,
+		EmailChanges: emailChangeHandler,
+		IDCards:      idCardHandler,
+		Kiosk:        kioskHandler,
+		Retention:    retentionHandler,
+		Security:     securityHandler,
+		Reports:      reportHandler,
+		CustomFields: customFieldHandler,
+		Metrics:      metricsHandler,
+		Health:       healthHandler,
+		Schemas:      schemaHandler,
+		Preferences:  preferenceHandler,
+		School:       schoolHandler,
+		Reference:    referenceHandler,
+		Approvals:    approvalHandler,
+		Registers:    registerHandler,
+		Campaigns:    communicationHandler,
+		Setup:        setupHandler,
+		Medical:      medicalHandler,
+		Trips:        tripHandler,
+		Exams:        examHandler,
+		Letters:      letterHandler,
+		Audit:        auditHandler,
+		Downloads:    downloadHandler,
+		Jobs:         jobHandler,
+		Diagnostics:  diagnosticsHandler,
+		SPA:          spaHandler,
+		Limits:       limits,
+	}, authMiddleware, kioskAuth)
+	return mux, limits
+}
+
+// loadLoginGuard slows an account down after failed logins, then asks for a
+// CAPTCHA (LOGIN_BACKOFF, CAPTCHA_PROVIDER; see package loginguard). It's nil
+// when LOGIN_BACKOFF turns that off.
+func loadLoginGuard(a *app) *loginguard.Guard {
+	loginPolicy, err := loginguard.ParsePolicy(os.Getenv("LOGIN_BACKOFF"))
+	if err != nil {
+		log.Fatalf("Invalid LOGIN_BACKOFF: %v", err)
+	}
+	captchaVerifier, err := captcha.VerifierFromEnv(loadSecret(a.secrets, "CAPTCHA_SECRET"))
+	if err != nil {
+		log.Fatalf("Could not configure the CAPTCHA: %v", err)
+	}
+	if loginPolicy == nil {
+		return nil
+	}
+	return loginguard.New(a.responses, *loginPolicy, captchaVerifier, a.clk)
+}
+
+// loadSecret reads an optional secret, empty when it isn't set
+func loadSecret(store *secrets.Store, name string) string {
+	v, err := store.Get(context.Background(), name)
+	if err != nil {
+		log.Fatalf("Could not load %s: %v", name, err)
+	}
+	return v
+}
@@ -1,54 +1,179 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
-	"simpleapi/internal/api/handlers"
 	mw "simpleapi/internal/api/middlewares"
-	"simpleapi/internal/api/router"
-	"simpleapi/internal/repository"
+	"simpleapi/internal/mail"
+	"simpleapi/internal/models"
+	"simpleapi/internal/schoolprofile"
+	"simpleapi/internal/selfcheck"
+	"simpleapi/internal/sms"
+	"simpleapi/internal/storage"
+	"simpleapi/internal/tracing"
+	"simpleapi/pkg/clock"
+	"simpleapi/pkg/secrets"
+	"simpleapi/pkg/utils"
+	"strconv"
+	"strings"
+	"time"
+	_ "time/tzdata" // Embed zone data so SCHOOL_TIMEZONE works in minimal containers
 
 	"github.com/joho/godotenv"
 )
 
+// app is what main sets up before the background jobs and the handlers,
+// which share it
+type app struct {
+	clk     *clock.System
+	secrets *secrets.Store
+	stores
+	shared
+
+	tokens    *utils.TokenService
+	cookies   *utils.CookieManager
+	delivery  utils.TokenDelivery
+	passwords *utils.PasswordHasher
+	signer    *utils.DocumentSigner
+
+	school   *schoolprofile.Profile
+	files    *storage.Local
+	notifier *sms.Notifier
+	mailer   mail.Sender
+}
+
 func main() {
 	// 1. Load Config
 	if err := godotenv.Load(); err != nil {
 		log.Println("No .env file found, relying on system env")
 	}
+	setupLogging()
 
-	// 2. Initialize Database (The Pro Way: returns the instance, no global var)
-	db, err := repository.NewDB()
+	// Tracing first, so startup DB calls are already instrumented
+	shutdownTracing, err := tracing.Init(context.Background())
 	if err != nil {
-		log.Fatalf("Could not connect to DB: %v", err)
+		log.Fatalf("Could not initialize tracing: %v", err)
 	}
-	defer db.Close() // Main owns the cleanup
+	defer shutdownTracing(context.Background())
 
-	// 3. WIRING: Dependency Injection Chain
-	// Level 1: Create the Repository (injects DB)
-	teacherRepo := repository.NewTeacherRepository(db)
-	studentRepo := repository.NewStudentRepository(db)
+	a := &app{}
+	// One clock for the whole app, in the school's time zone (SCHOOL_TIMEZONE, e.g. Africa/Lagos)
+	if a.clk, err = clock.FromEnv(os.Getenv("SCHOOL_TIMEZONE")); err != nil {
+		log.Fatalf("Invalid SCHOOL_TIMEZONE: %v", err)
+	}
 
-	// Level 2: Create the Handler (injects Repo)
-	teacherHandler := handlers.NewTeacherHandler(teacherRepo)
-	studentHandler := handlers.NewStudentHandler(studentRepo)
+	// Secrets: external manager (SECRETS_PROVIDER) -> NAME_FILE -> NAME env
+	secretsProvider, err := secrets.ProviderFromEnv()
+	if err != nil {
+		log.Fatalf("Could not configure secrets provider: %v", err)
+	}
+	a.secrets = secrets.NewStore(secretsProvider)
+
+	var jwtSecret string
+	a.tokens, jwtSecret = loadTokens(a.secrets, a.clk)
+	// COOKIE_* name and scope the session cookies; COOKIE_SECURE=false for plain-HTTP development
+	if a.cookies, err = utils.CookiesFromEnv(); err != nil {
+		log.Fatalf("Invalid cookie settings: %v", err)
+	}
+	// AUTH_TOKEN_DELIVERY=negotiate|cookie|bearer: how login hands out the JWT (see utils.TokenDelivery)
+	if a.delivery, err = utils.ParseTokenDelivery(os.Getenv("AUTH_TOKEN_DELIVERY")); err != nil {
+		log.Fatalf("Invalid AUTH_TOKEN_DELIVERY: %v", err)
+	}
+	a.signer = loadSigner(a.secrets)
+	a.passwords = loadPasswordHasher(a.secrets)
 
-	authMiddleware := mw.NewAuthMiddleware(teacherRepo)
-	// Level 3: Create the Router (injects Handler)
-	// Note: We need to update your router.Router() function to accept this argument!
-	mux := router.Router(teacherHandler, studentHandler,authMiddleware)
+	// 2. Initialize Database (The Pro Way: returns the instance, no global var)
+	a.stores = openStores(a.secrets, a.clk)
+	if a.db != nil {
+		defer a.db.Close() // Main owns the cleanup
+	}
 
-	port := os.Getenv("SERVER_PORT")
+	// Re-read secrets periodically so rotations in the manager reach the app (SECRETS_REFRESH_INTERVAL, e.g. 5m)
+	if interval, err := time.ParseDuration(os.Getenv("SECRETS_REFRESH_INTERVAL")); err == nil && interval > 0 {
+		a.secrets.StartRotation(context.Background(), interval)
+	}
 
 	cert := "cert.pem"
 	key := "key.pem"
 
-	tlsConfig := &tls.Config{
-		MinVersion: tls.VersionTLS12,
+	// Fail fast with actionable messages instead of erroring on the first request
+	a.shared = openSharedState(a.secrets, a.clk)
+	checks := selfcheck.Run(context.Background(), selfcheck.Config{
+		DB:        a.db,
+		JWTSecret: jwtSecret,
+		CertFile:  cert,
+		KeyFile:   key,
+		Redis:     a.redis,
+	})
+	if !selfcheck.Report(os.Stdout, checks) {
+		if a.db != nil {
+			a.db.Close()
+		}
+		log.Fatalln("Startup self-check failed, refusing to start")
+	}
+
+	// The school's profile (PUT /admin/school) takes over from SCHOOL_NAME and
+	// SCHOOL_TIMEZONE once saved. Other instances pick up a change within a minute.
+	a.school = schoolprofile.New(a.schools, a.clk, models.School{Name: os.Getenv("SCHOOL_NAME"), Timezone: os.Getenv("SCHOOL_TIMEZONE"),
+		AttendanceThresholds: models.DefaultAttendanceThresholds})
+	if err := a.school.Refresh(context.Background()); err != nil {
+		log.Fatalf("Could not load the school profile: %v", err)
+	}
+	a.school.Start(context.Background(), time.Minute)
+
+	// Uploads (photos, documents, year archives) go through the storage abstraction; local disk for now
+	uploadsDir := os.Getenv("UPLOADS_DIR")
+	if uploadsDir == "" {
+		uploadsDir = "uploads"
+	}
+	if a.files, err = storage.NewLocal(uploadsDir); err != nil {
+		log.Fatalf("Could not prepare uploads storage: %v", err)
+	}
+
+	// SMS to parents (SMS_PROVIDER=log|twilio|africastalking, SMS_SENDER_ID, SCHOOL_NAME)
+	smsSender, err := sms.SenderFromEnv()
+	if err != nil {
+		log.Fatalf("Could not configure SMS provider: %v", err)
+	}
+	a.notifier = sms.NewNotifier(smsSender, a.messages, os.Getenv("SMS_SENDER_ID"), a.school)
+
+	// Email to staff (MAIL_PROVIDER=log|smtp, MAIL_FROM, SMTP_*); APP_URL is the frontend links point at
+	if a.mailer, err = mail.SenderFromEnv(); err != nil {
+		log.Fatalf("Could not configure mail provider: %v", err)
+	}
+
+	bg, publishers := startJobs(a)
+	for _, p := range publishers {
+		defer p.Close()
 	}
+	setupValidation()
+
+	// Level 2 and 3: handlers and router, see routes
+	handler := newHandler(a, bg)
+	// Create custom server
+	server := &http.Server{
+		Addr:    os.Getenv("SERVER_PORT"),
+		Handler: handler,
+		TLSConfig: &tls.Config{
+			MinVersion: tls.VersionTLS12,
+		},
+	}
+
+	fmt.Println("Server is running on port:", server.Addr)
+	err = server.ListenAndServeTLS(cert, key)
+	if err != nil {
+		log.Fatalln("Error starting the server", err)
+	}
+}
+
+// newHandler is the router behind the middlewares every request goes through
+func newHandler(a *app, bg *background) http.Handler {
+	kioskAuth := loadKioskAuth(a.secrets)
+	mux, limits := routes(a, bg, kioskAuth)
 
 	// rl := mw.NewRateLimiter(5, time.Minute)
 	// hppOptions := mw.HPPOptions{
@@ -59,17 +184,46 @@ func main() {
 	// }
 	// secureMux := mw.Cors(rl.Middleware(mw.ResponseTimeMiddleware(mw.SecurityHeaders(mw.Compression(mw.Hpp(hppOptions)(mux))))))
 	// secureMux:= applyMiddlewares(mux, mw.Hpp(hppOptions), mw.Compression, mw.SecurityHeaders, mw.ResponseTimeMiddleware, rl.Middleware, mw.Cors)
-	secureMux := mw.SecurityHeaders(mux)
-	// Create custom server
-	server := &http.Server{
-		Addr:      port,
-		Handler:   secureMux,
-		TLSConfig: tlsConfig,
+	// Only trust X-Forwarded-For from our own load balancers (TRUSTED_PROXIES is a comma-separated CIDR list)
+	realIP, err := mw.NewRealIP(strings.Split(os.Getenv("TRUSTED_PROXIES"), ","))
+	if err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
 	}
-
-	fmt.Println("Server is running on port:", port)
-	err = server.ListenAndServeTLS(cert, key)
+	// SECURITY_CSP, HSTS_* and friends, see mw.SecurityHeadersFromEnv
+	securityHeaders, err := mw.SecurityHeadersFromEnv()
 	if err != nil {
-		log.Fatalln("Error starting the server", err)
+		log.Fatalf("Invalid security headers config: %v", err)
+	}
+	// ERROR_FORMAT=problem makes RFC 7807 the default; clients can still opt in via Accept
+	errorFormat := mw.NegotiateErrorFormat(utils.ParseErrorFormat(os.Getenv("ERROR_FORMAT")))
+	// RESPONSE_REDACTION=true hides fields tagged visibility:"..." from viewers not listed (see package redact)
+	redaction, _ := strconv.ParseBool(os.Getenv("RESPONSE_REDACTION"))
+	redactFields := mw.RedactFields(redaction)
+	return realIP.Middleware(mw.Tracing(mw.SecurityHeaders(securityHeaders)(errorFormat(redactFields(mw.Locale(limits(mux)))))))
+}
+
+// envDuration reads a positive duration from name, def when it's unset
+func envDuration(name string, def time.Duration) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		log.Fatalf("Invalid %s %q", name, v)
+	}
+	return d
+}
+
+// envCount reads a count of at least 1 from name, def when it's unset
+func envCount(name string, def int) int {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 {
+		log.Fatalf("Invalid %s %q", name, v)
 	}
+	return n
 }
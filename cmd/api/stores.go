@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"os"
+	"simpleapi/internal/cache"
+	"simpleapi/internal/database"
+	"simpleapi/internal/jobs"
+	"simpleapi/internal/ratelimit"
+	"simpleapi/internal/redis"
+	"simpleapi/internal/repository"
+	"simpleapi/internal/repository/memory"
+	"simpleapi/pkg/clock"
+	"simpleapi/pkg/secrets"
+)
+
+// stores are the repositories, all on MySQL or all in memory
+type stores struct {
+	db           *sql.DB // nil with DB_DRIVER=memory
+	teachers     repository.TeacherStore
+	students     repository.StudentStore
+	comments     repository.CommentStore
+	grading      repository.GradingStore
+	scores       repository.ScoreStore
+	messages     repository.MessageStore
+	events       repository.EventStore
+	audit        repository.AuditStore
+	attendance   repository.AttendanceStore
+	promotions   repository.PromotionStore
+	threads      repository.ThreadStore
+	archives     repository.ArchiveStore
+	uploads      repository.UploadStore
+	assignments  repository.ClassAssignmentStore
+	emailChanges repository.EmailChangeStore
+	retention    repository.RetentionStore
+	reports      repository.ReportStore
+	customFields repository.CustomFieldStore
+	outbox       repository.OutboxStore
+	schools      repository.SchoolStore
+	reference    repository.ReferenceStore
+	approvals    repository.ApprovalStore
+	campaigns    repository.CampaignStore
+	medical      repository.MedicalStore
+	trips        repository.TripStore
+	rollovers    repository.RolloverStore
+	exams        repository.ExamStore
+	letters      repository.LetterTemplateStore
+	documents    repository.DocumentStore
+	units        repository.UnitOfWork
+	backups      repository.BackupStore // MySQL only: memory mode has no database to back up
+}
+
+// openStores connects to the database; DB_DRIVER=memory runs the whole API
+// on in-process maps (demos, frontend work)
+func openStores(store *secrets.Store, clk *clock.System) stores {
+	if os.Getenv("DB_DRIVER") == "memory" {
+		log.Println("DB_DRIVER=memory: using in-memory store, data is lost on restart")
+		memDB := memory.NewDB(clk)
+		return stores{
+			teachers:     memory.NewTeacherRepository(memDB),
+			students:     memory.NewStudentRepository(memDB),
+			comments:     memory.NewCommentRepository(memDB),
+			grading:      memory.NewGradingRepository(memDB),
+			scores:       memory.NewScoreRepository(memDB),
+			messages:     memory.NewMessageRepository(memDB),
+			events:       memory.NewEventRepository(memDB),
+			audit:        memory.NewAuditRepository(memDB),
+			attendance:   memory.NewAttendanceRepository(memDB),
+			promotions:   memory.NewPromotionRepository(memDB),
+			threads:      memory.NewThreadRepository(memDB),
+			archives:     memory.NewArchiveRepository(memDB),
+			uploads:      memory.NewUploadRepository(memDB),
+			assignments:  memory.NewClassAssignmentRepository(memDB),
+			emailChanges: memory.NewEmailChangeRepository(memDB),
+			retention:    memory.NewRetentionRepository(memDB),
+			reports:      memory.NewReportRepository(memDB),
+			customFields: memory.NewCustomFieldRepository(memDB),
+			outbox:       memory.NewOutboxRepository(memDB),
+			schools:      memory.NewSchoolRepository(memDB),
+			reference:    memory.NewReferenceRepository(memDB),
+			approvals:    memory.NewApprovalRepository(memDB),
+			campaigns:    memory.NewCampaignRepository(memDB),
+			medical:      memory.NewMedicalRepository(memDB),
+			trips:        memory.NewTripRepository(memDB),
+			rollovers:    memory.NewRolloverRepository(memDB),
+			exams:        memory.NewExamRepository(memDB),
+			letters:      memory.NewLetterTemplateRepository(memDB),
+			documents:    memory.NewDocumentRepository(memDB),
+			units:        memory.NewUnitOfWork(memDB),
+		}
+	}
+
+	db, err := database.OpenWithSecrets(context.Background(), store)
+	if err != nil {
+		log.Fatalf("Could not connect to DB: %v", err)
+	}
+	// 3. WIRING: Dependency Injection Chain
+	// Level 1: Create the Repository (injects DB)
+	return stores{
+		db:           db,
+		teachers:     repository.NewTeacherRepository(db),
+		students:     repository.NewStudentRepository(db),
+		comments:     repository.NewCommentRepository(db),
+		grading:      repository.NewGradingRepository(db),
+		scores:       repository.NewScoreRepository(db),
+		messages:     repository.NewMessageRepository(db),
+		events:       repository.NewEventRepository(db),
+		audit:        repository.NewAuditRepository(db),
+		attendance:   repository.NewAttendanceRepository(db),
+		promotions:   repository.NewPromotionRepository(db),
+		threads:      repository.NewThreadRepository(db),
+		archives:     repository.NewArchiveRepository(db),
+		uploads:      repository.NewUploadRepository(db),
+		assignments:  repository.NewClassAssignmentRepository(db),
+		emailChanges: repository.NewEmailChangeRepository(db),
+		retention:    repository.NewRetentionRepository(db),
+		reports:      repository.NewReportRepository(db),
+		customFields: repository.NewCustomFieldRepository(db),
+		outbox:       repository.NewOutboxRepository(db),
+		schools:      repository.NewSchoolRepository(db),
+		reference:    repository.NewReferenceRepository(db),
+		approvals:    repository.NewApprovalRepository(db),
+		campaigns:    repository.NewCampaignRepository(db),
+		medical:      repository.NewMedicalRepository(db),
+		trips:        repository.NewTripRepository(db),
+		rollovers:    repository.NewRolloverRepository(db),
+		exams:        repository.NewExamRepository(db),
+		letters:      repository.NewLetterTemplateRepository(db),
+		documents:    repository.NewDocumentRepository(db),
+		units:        repository.NewUnitOfWork(db),
+		backups:      repository.NewBackupRepository(db),
+	}
+}
+
+// shared is what instances must agree on: rate limit buckets, cached
+// responses and scheduler locks
+type shared struct {
+	redis     *redis.Client // nil unless SHARED_STATE=redis
+	rateStore ratelimit.Store
+	responses cache.Cache
+	locks     jobs.Locks
+}
+
+// openSharedState keeps the shared state in process or, with
+// SHARED_STATE=redis, in Redis (REDIS_URL). Redis is required to run several
+// instances behind a load balancer; memory, the default, is for one.
+func openSharedState(store *secrets.Store, clk *clock.System) shared {
+	switch v := os.Getenv("SHARED_STATE"); v {
+	case "", "memory":
+		return shared{
+			rateStore: ratelimit.NewMemory(clk),
+			responses: cache.NewMemory(clk),
+			locks:     jobs.LocalLocks{},
+		}
+	case "redis":
+		if os.Getenv("DB_DRIVER") == "memory" {
+			log.Fatalln("SHARED_STATE=redis needs MySQL: DB_DRIVER=memory data can't be shared between instances")
+		}
+		redisURL, err := store.Get(context.Background(), "REDIS_URL")
+		if err != nil {
+			log.Fatalf("Could not load REDIS_URL: %v", err)
+		}
+		client, err := redis.New(redisURL)
+		if err != nil {
+			log.Fatalf("Invalid REDIS_URL: %v", err)
+		}
+		return shared{
+			redis:     client,
+			rateStore: ratelimit.NewRedis(client),
+			responses: cache.NewRedis(client),
+			locks:     jobs.NewRedisLocks(client),
+		}
+	default:
+		log.Fatalf("Invalid SHARED_STATE %q, want memory or redis", v)
+		return shared{}
+	}
+}
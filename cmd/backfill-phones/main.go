@@ -0,0 +1,126 @@
+// Command backfill-phones rewrites the phone numbers already stored in an
+// existing database in E.164. The columns come from schoolctl migrate.
+//
+//	go run ./cmd/backfill-phones -dry-run   # show what would change
+//	go run ./cmd/backfill-phones            # normalize numbers
+//
+// It reads the same DB_* settings and secrets as the API, plus PHONE_DEFAULT_REGION
+// for numbers typed without a country code. Numbers that can't be read are
+// listed and left untouched so someone can fix them by hand.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"simpleapi/internal/database"
+	"simpleapi/pkg/phone"
+	"simpleapi/pkg/secrets"
+
+	"github.com/joho/godotenv"
+)
+
+// phoneColumn is one column this tool normalizes
+type phoneColumn struct {
+	table, column string
+}
+
+var columns = []phoneColumn{
+	{"teachers", "phone"},
+	{"students", "guardian_phone"},
+}
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report changes without writing anything")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, relying on system env")
+	}
+	if err := phone.SetDefaultRegion(os.Getenv("PHONE_DEFAULT_REGION")); err != nil {
+		log.Fatalf("Invalid PHONE_DEFAULT_REGION: %v", err)
+	}
+
+	ctx := context.Background()
+	provider, err := secrets.ProviderFromEnv()
+	if err != nil {
+		log.Fatalf("Could not configure secrets provider: %v", err)
+	}
+	db, err := database.OpenWithSecrets(ctx, secrets.NewStore(provider))
+	if err != nil {
+		log.Fatalf("Could not connect to DB: %v", err)
+	}
+	defer db.Close()
+
+	for _, c := range columns {
+		exists, err := columnExists(ctx, db, c)
+		if err != nil {
+			log.Fatalf("Could not inspect %s.%s: %v", c.table, c.column, err)
+		}
+		if !exists {
+			log.Fatalf("%s.%s does not exist; run schoolctl migrate first", c.table, c.column)
+		}
+
+		updated, invalid, err := backfill(ctx, db, c, *dryRun)
+		if err != nil {
+			log.Fatalf("Could not backfill %s.%s: %v", c.table, c.column, err)
+		}
+		fmt.Printf("%s.%s: %d normalized, %d invalid\n", c.table, c.column, updated, invalid)
+	}
+}
+
+func columnExists(ctx context.Context, db *sql.DB, c phoneColumn) (bool, error) {
+	var n int
+	err := db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?",
+		c.table, c.column).Scan(&n)
+	return n > 0, err
+}
+
+// backfill rewrites every non-empty number in E.164, one row at a time so a
+// long run doesn't hold locks on the whole table
+func backfill(ctx context.Context, db *sql.DB, c phoneColumn, dryRun bool) (updated, invalid int, err error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT id, %s FROM %s WHERE %s <> ''", c.column, c.table, c.column))
+	if err != nil {
+		return 0, 0, err
+	}
+	type row struct {
+		id  int
+		raw string
+	}
+	var pending []row
+	for rows.Next() {
+		var r row
+		if err := rows.Scan(&r.id, &r.raw); err != nil {
+			rows.Close()
+			return 0, 0, err
+		}
+		pending = append(pending, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, 0, err
+	}
+
+	for _, r := range pending {
+		normalized, err := phone.Normalize(r.raw)
+		if err != nil {
+			fmt.Printf("  %s %d: cannot read %q, left as is\n", c.table, r.id, r.raw)
+			invalid++
+			continue
+		}
+		if normalized == r.raw {
+			continue
+		}
+		if dryRun {
+			fmt.Printf("  %s %d: %q -> %q\n", c.table, r.id, r.raw, normalized)
+		} else if _, err := db.ExecContext(ctx, fmt.Sprintf("UPDATE %s SET %s = ? WHERE id = ?", c.table, c.column), normalized, r.id); err != nil {
+			return updated, invalid, err
+		}
+		updated++
+	}
+	return updated, invalid, nil
+}
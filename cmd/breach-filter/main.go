@@ -0,0 +1,115 @@
+// Command breach-filter builds the Bloom filter PASSWORD_BREACH_CHECK=bloom
+// reads (see package breach) from a list of breached SHA-1 hashes, one per
+// line, such as Pwned Passwords' "HASH:COUNT" download:
+//
+//	breach-filter -in pwned-passwords-sha1.txt -out breached.bloom
+//	breach-filter -in pwned-passwords-sha1.txt -out breached.bloom -min-count 10 -fp 0.0001
+//
+// The full corpus makes a filter of well over a gigabyte; -min-count keeps
+// only hashes seen that many times, the passwords attackers try first. A
+// false positive asks a teacher to change a password that was fine.
+package main
+
+import (
+	"bufio"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"simpleapi/internal/breach"
+	"strconv"
+	"strings"
+)
+
+func main() {
+	log.SetFlags(0)
+	in := flag.String("in", "", "list of SHA-1 hashes, HASH or HASH:COUNT per line")
+	out := flag.String("out", "breached.bloom", "where to write the filter")
+	fp := flag.Float64("fp", 0.001, "false positive rate")
+	minCount := flag.Int("min-count", 0, "skip hashes seen fewer times than this (needs HASH:COUNT lines)")
+	flag.Parse()
+	if *in == "" {
+		log.Fatal("breach-filter: -in is required")
+	}
+	if *fp <= 0 || *fp >= 1 {
+		log.Fatal("breach-filter: -fp must be between 0 and 1")
+	}
+
+	// Two passes: the first counts the hashes so the filter is sized for them
+	n, err := scan(*in, *minCount, func([20]byte) {})
+	if err != nil {
+		log.Fatalf("breach-filter: %v", err)
+	}
+	filter := breach.NewBloom(n, *fp)
+	if _, err := scan(*in, *minCount, filter.Add); err != nil {
+		log.Fatalf("breach-filter: %v", err)
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		log.Fatalf("breach-filter: %v", err)
+	}
+	w := bufio.NewWriter(f)
+	size, err := filter.WriteTo(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		log.Fatalf("breach-filter: writing %s: %v", *out, err)
+	}
+	fmt.Printf("wrote %s: %d hashes, %d bytes\n", *out, n, size)
+}
+
+// scan calls add with every hash in the file seen at least minCount times
+func scan(path string, minCount int, add func([20]byte)) (uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	var n uint64
+	r := bufio.NewReaderSize(f, 1<<20)
+	for line := 1; ; line++ {
+		text, readErr := r.ReadString('\n')
+		hash, ok, err := parseLine(strings.TrimSpace(text), minCount)
+		if err != nil {
+			return 0, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if ok {
+			add(hash)
+			n++
+		}
+		if readErr == io.EOF {
+			return n, nil
+		}
+		if readErr != nil {
+			return 0, readErr
+		}
+	}
+}
+
+// parseLine reads "HASH" or "HASH:COUNT"; ok is false for blank lines and
+// hashes seen fewer than minCount times
+func parseLine(text string, minCount int) (hash [20]byte, ok bool, err error) {
+	if text == "" {
+		return hash, false, nil
+	}
+	digest, count, hasCount := strings.Cut(text, ":")
+	if hasCount && minCount > 0 {
+		if c, err := strconv.Atoi(count); err != nil || c < minCount {
+			return hash, false, nil
+		}
+	}
+	b, err := hex.DecodeString(digest)
+	if err != nil || len(b) != len(hash) {
+		return hash, false, fmt.Errorf("not a SHA-1 hash")
+	}
+	copy(hash[:], b)
+	return hash, true, nil
+}
@@ -0,0 +1,188 @@
+// Command configctl copies a school's setup between environments through
+// GET/PUT /api/v1/admin/config (see models.SchoolConfig).
+//
+//	configctl export -url https://staging.example.org > school.json
+//	configctl validate -file school.json
+//	configctl diff   -url https://prod.example.org -file school.json   # what import would change
+//	configctl import -url https://prod.example.org -file school.json
+//
+// -url defaults to CONFIGCTL_URL. Requests are authenticated with an admin's
+// token from CONFIGCTL_TOKEN (log in with "X-Client-Type: api" to get one).
+// import replaces the whole setup: entries missing from the file are deleted,
+// so run diff first.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"simpleapi/internal/models"
+	"strings"
+	"time"
+)
+
+// envelope is the API's response wrapper (utils.APIResponse / utils.ErrorBody)
+type envelope struct {
+	StatusCode int             `json:"statusCode"`
+	Code       string          `json:"code"`
+	Message    string          `json:"message"`
+	Data       json.RawMessage `json:"data"`
+	Details    json.RawMessage `json:"details"`
+}
+
+func main() {
+	log.SetFlags(0)
+	if len(os.Args) < 2 {
+		log.Fatalln("usage: configctl export|validate|diff|import [flags]")
+	}
+	cmd := os.Args[1]
+
+	fs := flag.NewFlagSet(cmd, flag.ExitOnError)
+	baseURL := fs.String("url", os.Getenv("CONFIGCTL_URL"), "API base URL, e.g. https://school.example.org")
+	file := fs.String("file", "", "config document (JSON)")
+	fs.Parse(os.Args[2:])
+
+	client := &apiClient{base: strings.TrimSuffix(*baseURL, "/"), token: os.Getenv("CONFIGCTL_TOKEN"), http: &http.Client{Timeout: 30 * time.Second}}
+
+	switch cmd {
+	case "export":
+		var cfg models.SchoolConfig
+		if err := client.do(http.MethodGet, "/api/v1/admin/config", nil, &cfg); err != nil {
+			log.Fatalf("export failed: %v", err)
+		}
+		printJSON(cfg)
+	case "validate":
+		cfg := readConfig(*file)
+		if errs := cfg.Validate(); len(errs) > 0 {
+			printValidation(errs)
+			os.Exit(1)
+		}
+		fmt.Println("config is valid")
+	case "diff", "import":
+		cfg := readConfig(*file)
+		if errs := cfg.Validate(); len(errs) > 0 {
+			printValidation(errs)
+			os.Exit(1)
+		}
+		path := "/api/v1/admin/config"
+		if cmd == "diff" {
+			path += "?dry_run=true"
+		}
+		var plan models.ConfigPlan
+		if err := client.do(http.MethodPut, path, cfg, &plan); err != nil {
+			log.Fatalf("%s failed: %v", cmd, err)
+		}
+		printPlan(plan)
+	default:
+		log.Fatalf("unknown command %q; want export, validate, diff or import", cmd)
+	}
+}
+
+type apiClient struct {
+	base  string
+	token string
+	http  *http.Client
+}
+
+// do sends body as JSON and decodes the envelope's data into out
+func (c *apiClient) do(method, path string, body, out any) error {
+	if c.base == "" {
+		return errors.New("no API URL: pass -url or set CONFIGCTL_URL")
+	}
+	if c.token == "" {
+		return errors.New("no token: set CONFIGCTL_TOKEN")
+	}
+
+	var payload io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequest(method, c.base+path, payload)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Client-Type", "api")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var env envelope
+	if err := json.NewDecoder(resp.Body).Decode(&env); err != nil {
+		return fmt.Errorf("%s: unexpected response: %w", resp.Status, err)
+	}
+	if resp.StatusCode >= 300 {
+		if len(env.Details) > 0 {
+			return fmt.Errorf("%s (%s): %s\n%s", resp.Status, env.Code, env.Message, env.Details)
+		}
+		return fmt.Errorf("%s (%s): %s", resp.Status, env.Code, env.Message)
+	}
+	return json.Unmarshal(env.Data, out)
+}
+
+func readConfig(path string) *models.SchoolConfig {
+	if path == "" {
+		log.Fatalln("pass -file with the config document")
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatalf("could not read %s: %v", path, err)
+	}
+	var cfg models.SchoolConfig
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields() // Same as the API: a typo must not be silently dropped
+	if err := dec.Decode(&cfg); err != nil {
+		log.Fatalf("%s is not a config document: %v", path, err)
+	}
+	return &cfg
+}
+
+func printValidation(errs []models.ValidationError) {
+	for _, e := range errs {
+		if e.Index != nil {
+			fmt.Fprintf(os.Stderr, "grading_schemes[%d].%s: %s\n", *e.Index, e.Field, e.Msg)
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "%s: %s\n", e.Field, e.Msg)
+	}
+}
+
+func printPlan(plan models.ConfigPlan) {
+	if len(plan.Changes) == 0 {
+		fmt.Println("no changes")
+		return
+	}
+	for _, c := range plan.Changes {
+		fmt.Printf("%-6s %s/%s\n", c.Action, c.Section, c.Key)
+	}
+	if plan.DryRun {
+		fmt.Printf("%d change(s) would be made; run import to apply them\n", len(plan.Changes))
+		return
+	}
+	fmt.Printf("%d change(s) applied\n", len(plan.Changes))
+}
+
+func printJSON(v any) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,135 @@
+// Command restore loads a backup made by POST /admin/backup back into the database.
+//
+//	go run ./cmd/restore -key backups/20261016T020000Z-7.jsonl.gz -dry-run   # check it loads, change nothing
+//	go run ./cmd/restore -key backups/20261016T020000Z-7.jsonl.gz -yes       # from the API's storage
+//	go run ./cmd/restore -file backup-20261016T020000Z-7.jsonl.gz -yes       # from a downloaded copy
+//
+// The key is the backup's storage_key (GET /admin/backups); -file takes the file
+// GET /admin/backups/{id}/download saved. -sha256 checks the dump against the
+// checksum the backup recorded before anything is touched.
+//
+// Every table in the dump is emptied and refilled in a single transaction, so
+// either the whole backup goes in or nothing changes. Tables the dump doesn't
+// contain, including the backups table itself, are left alone. Stop the API
+// first: requests served during the restore would be lost.
+//
+// It reads the same DB_* settings and secrets as the API, plus UPLOADS_DIR for -key.
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"simpleapi/internal/backup"
+	"simpleapi/internal/database"
+	"simpleapi/internal/storage"
+	"simpleapi/pkg/secrets"
+	"strings"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	key := flag.String("key", "", "storage key of the backup (its storage_key)")
+	file := flag.String("file", "", "path of a downloaded backup")
+	checksum := flag.String("sha256", "", "expected SHA-256 of the dump")
+	yes := flag.Bool("yes", false, "confirm replacing the database contents")
+	dryRun := flag.Bool("dry-run", false, "load the backup and roll back, changing nothing")
+	flag.Parse()
+
+	if (*key == "") == (*file == "") {
+		log.Fatalln("Give exactly one of -key or -file")
+	}
+	if !*yes && !*dryRun {
+		log.Fatalln("Restoring replaces the contents of every table in the backup; pass -yes to confirm or -dry-run to check it first")
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found, relying on system env")
+	}
+
+	ctx := context.Background()
+	dump, err := openDump(ctx, *key, *file)
+	if err != nil {
+		log.Fatalf("Could not open backup: %v", err)
+	}
+	defer dump.Close()
+
+	// The dump is read once, so verifying means keeping a copy to restore from
+	var src io.Reader = dump
+	if *checksum != "" {
+		tmp, err := verify(dump, *checksum)
+		if err != nil {
+			log.Fatalf("Backup failed verification: %v", err)
+		}
+		defer os.Remove(tmp.Name())
+		defer tmp.Close()
+		src = tmp
+	}
+
+	provider, err := secrets.ProviderFromEnv()
+	if err != nil {
+		log.Fatalf("Could not configure secrets provider: %v", err)
+	}
+	db, err := database.OpenWithSecrets(ctx, secrets.NewStore(provider))
+	if err != nil {
+		log.Fatalf("Could not connect to DB: %v", err)
+	}
+	defer db.Close()
+
+	stats, err := backup.Restore(ctx, db, src, *dryRun)
+	if err != nil {
+		log.Fatalf("Restore failed, nothing was changed: %v", err)
+	}
+	if *dryRun {
+		fmt.Printf("dry run: %d tables, %d rows would be restored\n", stats.Tables, stats.Rows)
+		return
+	}
+	fmt.Printf("restored %d tables, %d rows\n", stats.Tables, stats.Rows)
+}
+
+func openDump(ctx context.Context, key, file string) (io.ReadCloser, error) {
+	if file != "" {
+		return os.Open(file)
+	}
+	uploadsDir := os.Getenv("UPLOADS_DIR")
+	if uploadsDir == "" {
+		uploadsDir = "uploads"
+	}
+	store, err := storage.NewLocal(uploadsDir)
+	if err != nil {
+		return nil, err
+	}
+	return store.Get(ctx, key)
+}
+
+// verify copies the dump to a temp file while hashing it and returns the copy,
+// rewound, if the hash matches
+func verify(r io.Reader, want string) (*os.File, error) {
+	tmp, err := os.CreateTemp("", "restore-*.jsonl.gz")
+	if err != nil {
+		return nil, err
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, hash), r); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	if got := hex.EncodeToString(hash.Sum(nil)); !strings.EqualFold(got, want) {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, fmt.Errorf("sha256 is %s, expected %s", got, want)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	return tmp, nil
+}
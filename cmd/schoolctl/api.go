@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"simpleapi/internal/buildinfo"
+	"simpleapi/internal/models"
+	"strings"
+	"time"
+)
+
+// envelope is the API's response wrapper (utils.APIResponse / utils.ErrorBody)
+type envelope struct {
+	StatusCode int             `json:"statusCode"`
+	Code       string          `json:"code"`
+	Message    string          `json:"message"`
+	Data       json.RawMessage `json:"data"`
+	Details    json.RawMessage `json:"details"`
+}
+
+type apiClient struct {
+	base    string
+	token   string
+	http    *http.Client
+	checked bool // The server's version was found compatible
+}
+
+// apiFlags adds -url and -skip-version-check to fs; the client is usable once fs is parsed
+func apiFlags(fs *flag.FlagSet) func() *apiClient {
+	baseURL := fs.String("url", os.Getenv("SCHOOLCTL_URL"), "API base URL, e.g. https://school.example.org")
+	skipCheck := fs.Bool("skip-version-check", false, "drive a server of another major version anyway")
+	return func() *apiClient {
+		return &apiClient{base: strings.TrimSuffix(*baseURL, "/"), token: os.Getenv("SCHOOLCTL_TOKEN"), http: &http.Client{Timeout: 30 * time.Second}, checked: *skipCheck}
+	}
+}
+
+// checkVersion refuses, before the first call, a server whose major version
+// differs from schoolctl's: the endpoints and payloads may have changed
+func (c *apiClient) checkVersion() error {
+	if c.checked {
+		return nil
+	}
+	resp, err := c.http.Get(c.base + "/version")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		// A server from before GET /version: nothing to compare
+		log.Printf("warning: %s doesn't tell its version, schoolctl %s may not match it", c.base, buildinfo.Version())
+		c.checked = true
+		return nil
+	}
+
+	var env envelope
+	var server models.BuildInfo
+	if err := json.NewDecoder(resp.Body).Decode(&env); err != nil {
+		return fmt.Errorf("GET /version: %s: unexpected response: %w", resp.Status, err)
+	}
+	if err := json.Unmarshal(env.Data, &server); err != nil {
+		return fmt.Errorf("GET /version: unexpected data: %w", err)
+	}
+	if err := buildinfo.Compatible(buildinfo.Version(), strings.TrimPrefix(server.Version, "v")); err != nil {
+		return fmt.Errorf("%w: use the schoolctl of the server's release, or pass -skip-version-check", err)
+	}
+	c.checked = true
+	return nil
+}
+
+// do sends body as JSON and decodes the envelope's data into out
+func (c *apiClient) do(method, path string, body, out any) error {
+	if c.base == "" {
+		return errors.New("no API URL: pass -url or set SCHOOLCTL_URL")
+	}
+	if c.token == "" {
+		return errors.New("no token: set SCHOOLCTL_TOKEN")
+	}
+	if err := c.checkVersion(); err != nil {
+		return err
+	}
+
+	var payload io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequest(method, c.base+"/api/v1"+path, payload)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Client-Type", "api")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var env envelope
+	if err := json.NewDecoder(resp.Body).Decode(&env); err != nil {
+		return fmt.Errorf("%s: unexpected response: %w", resp.Status, err)
+	}
+	if resp.StatusCode >= 300 {
+		if len(env.Details) > 0 {
+			return fmt.Errorf("%s (%s): %s\n%s", resp.Status, env.Code, env.Message, env.Details)
+		}
+		return fmt.Errorf("%s (%s): %s", resp.Status, env.Code, env.Message)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(env.Data, out)
+}
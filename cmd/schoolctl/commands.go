@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"simpleapi/internal/migrations"
+	"simpleapi/internal/models"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// unlock reactivates deactivated accounts
+func unlock(args []string) {
+	fs := flag.NewFlagSet("unlock", flag.ExitOnError)
+	client := apiFlags(fs)
+	idList := fs.String("ids", "", "teacher IDs, comma separated")
+	direct := fs.Bool("db", false, "write to the database instead of calling the API (break-glass)")
+	fs.Parse(args)
+
+	var ids []int
+	for s := range strings.SplitSeq(*idList, ",") {
+		id, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || id <= 0 {
+			log.Fatalf("-ids: %q is not a teacher ID", s)
+		}
+		ids = append(ids, id)
+	}
+
+	if *direct {
+		unlockDB(ids)
+		return
+	}
+	var result models.BulkResult[models.Teacher]
+	body := models.TeacherStatusUpdate{IDs: ids, Status: "active"}
+	if err := client().do(http.MethodPatch, "/admin/teachers/status", body, &result); err != nil {
+		log.Fatalf("unlock failed: %v", err)
+	}
+	var done []int
+	for _, item := range result.Items {
+		if item.Status == models.BulkUpdated {
+			done = append(done, item.ID)
+		}
+	}
+	printUnlocked(ids, done)
+}
+
+func printUnlocked(requested, done []int) {
+	for _, id := range requested {
+		if slices.Contains(done, id) {
+			fmt.Printf("teacher %d is active\n", id)
+		} else {
+			fmt.Printf("teacher %d not found\n", id)
+		}
+	}
+}
+
+// rotateJWTKey makes a new signing key. With JWT_SECRET_KEY_FILE set it
+// replaces that file, which the API reads again every SECRETS_REFRESH_INTERVAL
+// (or when it restarts); otherwise the key is printed for the operator to
+// store where the API reads it. A running API keeps accepting the tokens the
+// old key signed until they expire; to keep them valid across a restart too,
+// move the old key to JWT_PREVIOUS_SECRET_KEY first.
+func rotateJWTKey(args []string) {
+	fs := flag.NewFlagSet("rotate-jwt-key", flag.ExitOnError)
+	printKey := fs.Bool("print", false, "print the key even when JWT_SECRET_KEY_FILE is set")
+	fs.Parse(args)
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		log.Fatalf("Could not generate a key: %v", err)
+	}
+	key := base64.RawURLEncoding.EncodeToString(raw)
+
+	path := os.Getenv("JWT_SECRET_KEY_FILE")
+	if path == "" || *printKey {
+		fmt.Println(key)
+		fmt.Fprintln(os.Stderr, "Store this as JWT_SECRET_KEY where the API reads it (secrets manager, JWT_SECRET_KEY_FILE or env).")
+		return
+	}
+
+	// Write beside it and rename, so the API never reads half a key
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".jwt-key-*")
+	if err != nil {
+		log.Fatalf("Could not write the key: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(key + "\n"); err != nil {
+		log.Fatalf("Could not write the key: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		log.Fatalf("Could not write the key: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		log.Fatalf("Could not replace %s: %v", path, err)
+	}
+	fmt.Printf("wrote a new key to %s; the API uses it from its next secrets refresh or restart\n", path)
+}
+
+// migrate brings the database up to the API's schema, in process: see
+// package migrations
+func migrate(args []string) {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
+	dryRun := fs.Bool("dry-run", false, "report changes without writing anything")
+	fs.Parse(args)
+
+	ctx := context.Background()
+	db, _ := openDB(ctx)
+	defer db.Close()
+	if err := migrations.Run(ctx, db, os.Stdout, *dryRun); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// backup starts a backup and, with -wait, follows it until it is done
+func backup(args []string) {
+	fs := flag.NewFlagSet("backup", flag.ExitOnError)
+	client := apiFlags(fs)
+	wait := fs.Bool("wait", false, "poll the backup until it finishes")
+	fs.Parse(args)
+
+	api := client()
+	var b models.Backup
+	if err := api.do(http.MethodPost, "/admin/backup", nil, &b); err != nil {
+		log.Fatalf("backup failed: %v", err)
+	}
+	fmt.Printf("backup %d %s\n", b.ID, b.Status)
+	if !*wait {
+		return
+	}
+	for b.Status == models.JobPending || b.Status == models.JobRunning {
+		time.Sleep(2 * time.Second)
+		if err := api.do(http.MethodGet, fmt.Sprintf("/admin/backups/%d", b.ID), nil, &b); err != nil {
+			log.Fatalf("could not follow backup %d: %v", b.ID, err)
+		}
+	}
+	if b.Status == models.JobFailed {
+		log.Fatalf("backup %d failed: %s", b.ID, b.Error)
+	}
+	fmt.Printf("backup %d done: %d tables, %d rows, %d bytes; restore with cmd/restore -key %s\n", b.ID, b.Tables, b.Rows, b.Size, b.StorageKey)
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"simpleapi/internal/database"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"simpleapi/pkg/secrets"
+	"simpleapi/pkg/utils"
+	"strings"
+)
+
+// createAdmin adds an admin straight to the database, for a fresh install or
+// when every admin is gone. It refuses while an admin account exists: use
+// unlock for one that was deactivated. The password is read from stdin and
+// must be changed at the first login.
+func createAdmin(args []string) {
+	fs := flag.NewFlagSet("create-admin", flag.ExitOnError)
+	t := models.Teacher{Role: models.RoleAdmin}
+	fs.StringVar(&t.Email, "email", "", "the admin's email, used to log in")
+	fs.StringVar(&t.FirstName, "first", "", "first name")
+	fs.StringVar(&t.LastName, "last", "", "last name")
+	fs.StringVar(&t.Class, "class", "", "class, as for any teacher")
+	fs.StringVar(&t.Subject, "subject", "", "subject, as for any teacher")
+	fs.Parse(args)
+
+	fmt.Fprint(os.Stderr, "Password: ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		log.Fatalf("could not read the password: %v", err)
+	}
+	t.Password = strings.TrimRight(line, "\r\n")
+	if errs := models.ValidateOne(t); len(errs) > 0 {
+		for _, e := range errs {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", e.Field, e.Msg)
+		}
+		os.Exit(1)
+	}
+
+	ctx := context.Background()
+	db, store := openDB(ctx)
+	defer db.Close()
+	passwords := loadPasswordHasher(ctx, store)
+
+	var admins int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM teachers WHERE role = ? AND deleted_at IS NULL", models.RoleAdmin).Scan(&admins); err != nil {
+		log.Fatalf("Could not count admins: %v", err)
+	}
+	if admins > 0 {
+		log.Fatalf("%d admin account(s) already exist; log in with one, or reactivate it with schoolctl unlock -db", admins)
+	}
+
+	hash, err := passwords.HashPassword(t.Password)
+	if err != nil {
+		log.Fatalf("Could not hash the password: %v", err)
+	}
+	// CreateBulk doesn't take a role: teachers become admins only here
+	res, err := db.ExecContext(ctx,
+		"INSERT INTO teachers (first_name, last_name, email, class, subject, password_hash, role, force_password_change) VALUES (?, ?, ?, ?, ?, ?, ?, TRUE)",
+		t.FirstName, t.LastName, t.Email, t.Class, t.Subject, hash, t.Role)
+	if err != nil {
+		log.Fatalf("Could not create the admin: %v", err)
+	}
+	id, _ := res.LastInsertId()
+	fmt.Printf("created admin %d (%s); they must change the password at the first login\n", id, t.Email)
+}
+
+// unlockDB is unlock -db: it reactivates the accounts like the endpoint does,
+// for when no admin can log in to call it
+func unlockDB(ids []int) {
+	ctx := context.Background()
+	db, _ := openDB(ctx)
+	defer db.Close()
+
+	done, err := repository.NewTeacherRepository(db).SetActiveBulk(ctx, ids, true, nil)
+	if err != nil {
+		log.Fatalf("Could not reactivate the accounts: %v", err)
+	}
+	printUnlocked(ids, done)
+}
+
+// loadPasswordHasher peppers hashes with PASSWORD_PEPPER as the API does, or
+// the API couldn't verify the hashes written here
+func loadPasswordHasher(ctx context.Context, store *secrets.Store) *utils.PasswordHasher {
+	value, err := store.Get(ctx, "PASSWORD_PEPPER")
+	if err != nil {
+		log.Fatalf("Could not load PASSWORD_PEPPER: %v", err)
+	}
+	peppers, err := utils.ParsePeppers(value)
+	if err != nil {
+		log.Fatalf("Invalid PASSWORD_PEPPER: %v", err)
+	}
+	return utils.NewPasswordHasher(peppers)
+}
+
+// openDB connects like the API does. main has already loaded the .env file.
+func openDB(ctx context.Context) (*sql.DB, *secrets.Store) {
+	provider, err := secrets.ProviderFromEnv()
+	if err != nil {
+		log.Fatalf("Could not configure secrets provider: %v", err)
+	}
+	store := secrets.NewStore(provider)
+	db, err := database.OpenWithSecrets(ctx, store)
+	if err != nil {
+		log.Fatalf("Could not connect to DB: %v", err)
+	}
+	return db, store
+}
@@ -0,0 +1,48 @@
+// Command schoolctl runs the operator's chores without hand-written curl
+// calls against protected endpoints.
+//
+//	schoolctl create-admin -email head@example.org -first Ada -last Obi -class Staff -subject Admin
+//	schoolctl unlock -ids 12,40          # reactivate accounts (PATCH /admin/teachers/status)
+//	schoolctl unlock -ids 12 -db         # same, straight in the database
+//	schoolctl rotate-jwt-key             # new signing key, into JWT_SECRET_KEY_FILE or printed
+//	schoolctl migrate -dry-run           # list the pending schema migrations
+//	schoolctl backup -wait               # POST /admin/backup and follow it
+//
+// Commands that call the API take -url (default SCHOOLCTL_URL) and an admin's
+// token from SCHOOLCTL_TOKEN (log in with "X-Client-Type: api" to get one).
+// They first ask GET /version and refuse a server of another major version
+// than theirs, unless given -skip-version-check.
+// The break-glass ones (create-admin, unlock -db) go to the database with the
+// same DB_* settings and secrets as the API and record no actor.
+//
+// schoolctl reads the API's .env when run beside it, like the API does.
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/joho/godotenv"
+)
+
+// commands maps each subcommand to what runs it, with the arguments after its name
+var commands = map[string]func(args []string){
+	"create-admin":   createAdmin,
+	"unlock":         unlock,
+	"rotate-jwt-key": rotateJWTKey,
+	"migrate":        migrate,
+	"backup":         backup,
+}
+
+func main() {
+	log.SetFlags(0)
+	godotenv.Load() // Optional: the environment may hold the settings instead
+	if len(os.Args) < 2 {
+		log.Fatalln("usage: schoolctl create-admin|unlock|rotate-jwt-key|migrate|backup [flags]")
+	}
+	run, ok := commands[os.Args[1]]
+	if !ok {
+		log.Fatalf("unknown command %q; want create-admin, unlock, rotate-jwt-key, migrate or backup", os.Args[1])
+	}
+	run(os.Args[2:])
+}
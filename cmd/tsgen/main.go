@@ -0,0 +1,219 @@
+// Command tsgen writes TypeScript definitions for the API's response envelope,
+// so the frontend's types never drift from the Go structs. It reads Go source
+// files (not compiled types) and converts every exported struct and string
+// enum type in them:
+//
+//	go run ./cmd/tsgen -out api/envelope.ts pkg/utils/envelope.go ...
+//
+// It is run by go generate in pkg/utils. Members follow the json tags: "-" is
+// skipped, omitempty makes a member optional, embedded structs become extends.
+// A `ts:"..."` tag overrides a member's type; `ts:"T"` on a non-generic struct
+// makes the interface generic in T (e.g. APIResponse<T>.data).
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+func main() {
+	out := flag.String("out", "", "TypeScript file to write")
+	flag.Parse()
+	if *out == "" || flag.NArg() == 0 {
+		log.Fatal("usage: tsgen -out file.ts source.go...")
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by tsgen from the Go response types; DO NOT EDIT.\n")
+	for _, path := range flag.Args() {
+		if err := convertFile(&buf, path); err != nil {
+			log.Fatalf("tsgen: %s: %v", path, err)
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(*out), 0o755); err != nil {
+		log.Fatalf("tsgen: %v", err)
+	}
+	if err := os.WriteFile(*out, buf.Bytes(), 0o644); err != nil {
+		log.Fatalf("tsgen: %v", err)
+	}
+}
+
+func convertFile(buf *bytes.Buffer, path string) error {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(buf, "\n// From %s\n", filepath.ToSlash(filepath.Clean(path)))
+
+	enums := stringEnums(file)
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			ts := spec.(*ast.TypeSpec)
+			if !ts.Name.IsExported() {
+				continue
+			}
+			doc := ts.Doc
+			if doc == nil {
+				doc = gen.Doc
+			}
+			switch t := ts.Type.(type) {
+			case *ast.StructType:
+				writeComment(buf, doc)
+				writeInterface(buf, ts, t)
+			case *ast.Ident:
+				if values := enums[ts.Name.Name]; t.Name == "string" && len(values) > 0 {
+					writeComment(buf, doc)
+					fmt.Fprintf(buf, "export type %s = %s;\n", ts.Name.Name, strings.Join(values, " | "))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// stringEnums collects the constants of each named string type, in source order
+func stringEnums(file *ast.File) map[string][]string {
+	enums := make(map[string][]string)
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			vs := spec.(*ast.ValueSpec)
+			typ, ok := vs.Type.(*ast.Ident)
+			if !ok {
+				continue
+			}
+			for _, v := range vs.Values {
+				if lit, ok := v.(*ast.BasicLit); ok && lit.Kind == token.STRING {
+					value, _ := strconv.Unquote(lit.Value)
+					enums[typ.Name] = append(enums[typ.Name], strconv.Quote(value))
+				}
+			}
+		}
+	}
+	return enums
+}
+
+func writeComment(buf *bytes.Buffer, doc *ast.CommentGroup) {
+	if doc == nil {
+		return
+	}
+	for _, line := range strings.Split(strings.TrimSpace(doc.Text()), "\n") {
+		fmt.Fprintf(buf, "// %s\n", line)
+	}
+}
+
+func writeInterface(buf *bytes.Buffer, spec *ast.TypeSpec, st *ast.StructType) {
+	var params []string
+	if spec.TypeParams != nil {
+		for _, field := range spec.TypeParams.List {
+			for _, name := range field.Names {
+				params = append(params, name.Name)
+			}
+		}
+	}
+
+	var extends, members []string
+	for _, field := range st.Fields.List {
+		tag := reflect.StructTag("")
+		if field.Tag != nil {
+			raw, _ := strconv.Unquote(field.Tag.Value)
+			tag = reflect.StructTag(raw)
+		}
+		if len(field.Names) == 0 {
+			extends = append(extends, tsType(field.Type))
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag.Get("json"), ",")
+		if name == "-" || !field.Names[0].IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Names[0].Name
+		}
+		typ := tsType(field.Type)
+		if override := tag.Get("ts"); override != "" {
+			typ = override
+			if override == "T" && len(params) == 0 {
+				params = append(params, "T = unknown")
+			}
+		}
+		optional := ""
+		if strings.Contains(opts, "omitempty") {
+			optional = "?"
+		}
+		members = append(members, fmt.Sprintf("  %s%s: %s;", name, optional, typ))
+	}
+
+	header := "export interface " + spec.Name.Name
+	if len(params) > 0 {
+		header += "<" + strings.Join(params, ", ") + ">"
+	}
+	if len(extends) > 0 {
+		header += " extends " + strings.Join(extends, ", ")
+	}
+	fmt.Fprintf(buf, "%s {\n%s\n}\n", header, strings.Join(members, "\n"))
+}
+
+// tsType maps a Go type expression; named types are assumed to be generated too
+func tsType(expr ast.Expr) string {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		switch t.Name {
+		case "string":
+			return "string"
+		case "bool":
+			return "boolean"
+		case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64", "float32", "float64":
+			return "number"
+		case "any":
+			return "unknown"
+		}
+		return t.Name
+	case *ast.StarExpr:
+		return tsType(t.X) + " | null"
+	case *ast.ArrayType:
+		elem := tsType(t.Elt)
+		if strings.Contains(elem, " ") {
+			elem = "(" + elem + ")"
+		}
+		return elem + "[]"
+	case *ast.MapType:
+		return fmt.Sprintf("Record<%s, %s>", tsType(t.Key), tsType(t.Value))
+	case *ast.SelectorExpr:
+		if t.Sel.Name == "Time" {
+			return "string" // RFC 3339
+		}
+		return t.Sel.Name
+	case *ast.IndexExpr:
+		return fmt.Sprintf("%s<%s>", tsType(t.X), tsType(t.Index))
+	case *ast.IndexListExpr:
+		args := make([]string, len(t.Indices))
+		for i, index := range t.Indices {
+			args[i] = tsType(index)
+		}
+		return fmt.Sprintf("%s<%s>", tsType(t.X), strings.Join(args, ", "))
+	case *ast.InterfaceType:
+		return "unknown"
+	}
+	return "unknown"
+}
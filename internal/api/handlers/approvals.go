@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"simpleapi/internal/approvals"
+	"simpleapi/internal/jobs"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"simpleapi/pkg/utils"
+)
+
+// ApprovalHandler serves the approvals workflow: anyone signed in submits a
+// request, staff of its approver role (or an admin) approve or reject it, and
+// its requester can cancel it while it is pending. Each submission and
+// decision emails those it concerns (jobs.ApprovalNotices).
+type ApprovalHandler struct {
+	Engine  *approvals.Engine
+	Store   repository.ApprovalStore
+	Notices *jobs.ApprovalNotices
+	Queue   *jobs.Queue
+}
+
+// NewApprovalHandler is the constructor
+func NewApprovalHandler(engine *approvals.Engine, store repository.ApprovalStore, notices *jobs.ApprovalNotices, queue *jobs.Queue) *ApprovalHandler {
+	return &ApprovalHandler{Engine: engine, Store: store, Notices: notices, Queue: queue}
+}
+
+// Submit records a request: POST /approvals
+func (h *ApprovalHandler) Submit(w http.ResponseWriter, r *http.Request) {
+	var req models.ApprovalRequest
+	if err := decodeJSON(r, &req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if errors := models.ValidateOne(req); len(errors) > 0 {
+		writeValidationErrors(w, r, errors)
+		return
+	}
+
+	a, errs, err := h.Engine.Submit(r.Context(), req, *currentUser(r))
+	if len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	if err != nil {
+		logError(r, "Error submitting %s approval: %v", req.Type, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	h.notify(*a)
+	utils.WriteJSON(w, http.StatusCreated, "Request submitted for approval", a)
+}
+
+// GetMine lists the caller's own requests: GET /approvals?state=&type=
+func (h *ApprovalHandler) GetMine(w http.ResponseWriter, r *http.Request) {
+	var filter models.ApprovalFilter
+	if errs := utils.BindQuery(r, &filter); len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	filter.RequestedBy = currentUser(r).ID
+
+	list, err := h.Store.List(r.Context(), filter)
+	if err != nil {
+		logError(r, "Error fetching approvals: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Approvals fetched successfully", list)
+}
+
+// GetPending lists the requests waiting for the caller's decision: GET /approvals/pending
+func (h *ApprovalHandler) GetPending(w http.ResponseWriter, r *http.Request) {
+	list, err := h.Engine.Pending(r.Context(), *currentUser(r))
+	if err != nil {
+		logError(r, "Error fetching pending approvals: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Pending approvals fetched successfully", list)
+}
+
+// GetApproval returns one request, to its requester and those who can decide it
+func (h *ApprovalHandler) GetApproval(w http.ResponseWriter, r *http.Request) {
+	id := utils.PathID(r, "id")
+	a, err := h.Store.GetByID(r.Context(), id)
+	if err != nil {
+		logError(r, "Error fetching approval %d: %v", id, err)
+		utils.ResponseError(w, err, fmt.Sprintf("Approval with ID %d not found", id))
+		return
+	}
+	if !approvals.CanView(*a, *currentUser(r)) {
+		utils.WriteError(w, http.StatusForbidden, "You can only see your own requests and those you decide")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Approval fetched successfully", a)
+}
+
+// Approve approves a pending request and carries it out: POST /approvals/{id}/approve
+func (h *ApprovalHandler) Approve(w http.ResponseWriter, r *http.Request) {
+	h.decide(w, r, true)
+}
+
+// Reject rejects a pending request: POST /approvals/{id}/reject. The comment is required.
+func (h *ApprovalHandler) Reject(w http.ResponseWriter, r *http.Request) {
+	h.decide(w, r, false)
+}
+
+func (h *ApprovalHandler) decide(w http.ResponseWriter, r *http.Request, approve bool) {
+	id := utils.PathID(r, "id")
+	var req models.ApprovalDecision
+	if err := decodeJSON(r, &req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	errors := models.ValidateOne(req)
+	if !approve && req.Comment == "" {
+		errors = append(errors, models.RuleError("comment", "required", ""))
+	}
+	if len(errors) > 0 {
+		writeValidationErrors(w, r, errors)
+		return
+	}
+
+	a, err := h.Engine.Decide(r.Context(), id, approve, *currentUser(r), req.Comment)
+	if err != nil {
+		logError(r, "Error deciding approval %d: %v", id, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	h.notify(*a)
+	utils.WriteJSON(w, http.StatusOK, "Request "+a.State, a)
+}
+
+// Cancel withdraws the caller's pending request: POST /approvals/{id}/cancel
+func (h *ApprovalHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	id := utils.PathID(r, "id")
+	a, err := h.Engine.Cancel(r.Context(), id, *currentUser(r))
+	if err != nil {
+		logError(r, "Error cancelling approval %d: %v", id, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Request cancelled", a)
+}
+
+// notify queues the emails about a's new state. They are a courtesy: the
+// request stands without them, and the pending list shows it either way.
+func (h *ApprovalHandler) notify(a models.Approval) {
+	if err := h.Queue.Enqueue(h.Notices.Job(a)); err != nil {
+		log.Printf("Error queueing notices for approval %d: %v", a.ID, err)
+	}
+}
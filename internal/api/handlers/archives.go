@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"simpleapi/internal/jobs"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"simpleapi/internal/storage"
+	"simpleapi/pkg/utils"
+	"strconv"
+	"strings"
+)
+
+// ArchiveHandler snapshots academic years for statutory record keeping.
+// Building a bundle can take a while, so it runs on the job queue and clients
+// poll the archive until it is done.
+type ArchiveHandler struct {
+	Archives repository.ArchiveStore
+	Archiver *jobs.YearArchiver
+	Queue    *jobs.Queue
+	Storage  storage.Storage
+}
+
+// NewArchiveHandler is the constructor
+func NewArchiveHandler(archives repository.ArchiveStore, archiver *jobs.YearArchiver, queue *jobs.Queue, store storage.Storage) *ArchiveHandler {
+	return &ArchiveHandler{Archives: archives, Archiver: archiver, Queue: queue, Storage: store}
+}
+
+// ArchiveYear starts an archive of the year in the path, written as in term codes
+// with the slash escaped: POST /admin/academic-years/2025%2F26/archive
+func (h *ArchiveHandler) ArchiveYear(w http.ResponseWriter, r *http.Request) {
+	year := r.PathValue("year")
+	if year == "" || len(year) > 20 || models.AcademicYear(year) != year {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid academic year, expected e.g. 2025%2F26 for 2025/26")
+		return
+	}
+
+	// Refuse years nobody was graded in; an immutable empty archive is just noise
+	grades, err := h.Archiver.Scores.ListByYear(r.Context(), year)
+	if err != nil {
+		logError(r, "Error checking grades of %s: %v", year, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	if len(grades) == 0 {
+		utils.WriteError(w, http.StatusNotFound, fmt.Sprintf("No grades recorded for academic year %s", year))
+		return
+	}
+
+	archive, err := h.Archives.Create(r.Context(), year, currentUserID(r))
+	if err != nil {
+		if errors.Is(err, models.ErrConflict) {
+			utils.WriteError(w, http.StatusConflict, fmt.Sprintf("An archive of %s is already in progress", year))
+			return
+		}
+		logError(r, "Error creating archive of %s: %v", year, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+
+	if err := h.Queue.Enqueue(h.Archiver.Job(*archive)); err != nil {
+		// Don't leave it pending forever: nothing will ever pick it up
+		h.Archives.Finish(r.Context(), archive.ID, models.YearArchive{Status: models.JobFailed, Error: err.Error()})
+		utils.WriteError(w, http.StatusServiceUnavailable, "Too many background jobs, try again later")
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/admin/archives/%d", archive.ID))
+	utils.WriteJSON(w, http.StatusAccepted, "Archive started", archive)
+}
+
+// ListArchives lists archives newest first; ?year=2025/26 narrows it to one year
+func (h *ArchiveHandler) ListArchives(w http.ResponseWriter, r *http.Request) {
+	archives, err := h.Archives.List(r.Context(), r.URL.Query().Get("year"))
+	if err != nil {
+		logError(r, "Error listing archives: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Archives fetched successfully", archives)
+}
+
+func (h *ArchiveHandler) GetArchive(w http.ResponseWriter, r *http.Request) {
+	archive, ok := h.archiveFromPath(w, r)
+	if !ok {
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Archive fetched successfully", archive)
+}
+
+// DownloadArchive streams the zip. X-Checksum-SHA256 lets the client verify the
+// copy it keeps against the checksum recorded when the archive was made.
+func (h *ArchiveHandler) DownloadArchive(w http.ResponseWriter, r *http.Request) {
+	archive, ok := h.archiveFromPath(w, r)
+	if !ok {
+		return
+	}
+	if archive.Status != models.JobDone {
+		utils.WriteError(w, http.StatusConflict, fmt.Sprintf("Archive is %s, not ready for download", archive.Status))
+		return
+	}
+
+	rc, err := h.Storage.Get(r.Context(), archive.StorageKey)
+	if err != nil {
+		log.Printf("Error reading archive %d: %v", archive.ID, err)
+		utils.WriteError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	defer rc.Close()
+
+	name := fmt.Sprintf("academic-year-%s-archive-%d.zip", strings.ReplaceAll(archive.Year, "/", "-"), archive.ID)
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	w.Header().Set("Content-Length", strconv.FormatInt(archive.Size, 10))
+	w.Header().Set("X-Checksum-SHA256", archive.SHA256)
+	io.Copy(w, rc)
+}
+
+func (h *ArchiveHandler) archiveFromPath(w http.ResponseWriter, r *http.Request) (*models.YearArchive, bool) {
+	id := utils.PathID(r, "id")
+	archive, err := h.Archives.GetByID(r.Context(), id)
+	if err != nil {
+		if !errors.Is(err, models.ErrNotFound) {
+			logError(r, "Error fetching archive %d: %v", id, err)
+		}
+		utils.ResponseError(w, err, "")
+		return nil, false
+	}
+	return archive, true
+}
@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"mime"
+	"net/http"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"simpleapi/pkg/utils"
+)
+
+// AssignmentHandler manages which classes teachers are assigned to, which
+// decides whose grades, attendance and comments they may change (package policy)
+type AssignmentHandler struct {
+	Teachers    repository.TeacherStore
+	Assignments repository.ClassAssignmentStore
+	Units       repository.UnitOfWork // For imports, which change many teachers at once
+}
+
+// NewAssignmentHandler is the constructor
+func NewAssignmentHandler(teachers repository.TeacherStore, assignments repository.ClassAssignmentStore, units repository.UnitOfWork) *AssignmentHandler {
+	return &AssignmentHandler{Teachers: teachers, Assignments: assignments, Units: units}
+}
+
+// GetClasses returns a teacher's class and assigned classes: GET /admin/teachers/{id}/classes
+func (h *AssignmentHandler) GetClasses(w http.ResponseWriter, r *http.Request) {
+	teacher, ok := h.teacherFromPath(w, r)
+	if !ok {
+		return
+	}
+	h.writeAssignments(w, r, teacher, "Class assignments fetched successfully")
+}
+
+// SetClasses replaces a teacher's assigned classes: PUT /admin/teachers/{id}/classes.
+// The teacher's own class needn't be listed; they always teach it.
+func (h *AssignmentHandler) SetClasses(w http.ResponseWriter, r *http.Request) {
+	teacher, ok := h.teacherFromPath(w, r)
+	if !ok {
+		return
+	}
+	var req models.ClassAssignments
+	if err := decodeJSON(r, &req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if errors := models.ValidateOne(req); len(errors) > 0 {
+		writeValidationErrors(w, r, errors)
+		return
+	}
+
+	if err := h.Assignments.SetClasses(r.Context(), teacher.ID, req.Classes); err != nil {
+		logError(r, "Error assigning classes to teacher %d: %v", teacher.ID, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	h.writeAssignments(w, r, teacher, "Class assignments updated successfully")
+}
+
+// ImportAssignments sets up the timetable from a CSV of teacher_email, class,
+// subject and role rows: POST /teachers/assignments/import. The file is the
+// whole truth for the teachers it lists: a subject_teacher row assigns the
+// class, classes they have but the file leaves out are removed, and a
+// class_teacher row makes the class their own. Teachers it doesn't list are
+// left alone. Every row must check out or nothing is applied; ?dry_run=true
+// previews the changes without making them.
+func (h *AssignmentHandler) ImportAssignments(w http.ResponseWriter, r *http.Request) {
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "text/csv" {
+		utils.WriteError(w, http.StatusBadRequest, "Content-Type must be text/csv")
+		return
+	}
+	rows, err := readAssignmentsCSV(r.Body)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid CSV: "+err.Error())
+		return
+	}
+
+	result := models.AssignmentImport{DryRun: isDryRun(r)}
+	var invalid []models.ValidationError
+	// Planned in the unit of work too, so the diff is of what it changes
+	err = h.Units.Do(r.Context(), func(ctx context.Context, repos repository.Repos) error {
+		plan, errs, err := planAssignments(ctx, repos, rows)
+		if err != nil || len(errs) > 0 {
+			invalid = errs
+			return err
+		}
+		result.Teachers = len(plan.teachers)
+		if result.Changes, err = plan.diff(ctx, repos); err != nil {
+			return err
+		}
+		if result.DryRun {
+			return nil
+		}
+		return plan.apply(ctx, repos, result.Changes, currentUserID(r))
+	})
+	if err != nil {
+		logError(r, "Error importing class assignments: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	if len(invalid) > 0 {
+		writeValidationErrors(w, r, invalid)
+		return
+	}
+
+	message := "Class assignments imported successfully"
+	if result.DryRun {
+		message = "Dry run: no class assignments were changed"
+	}
+	utils.WriteJSON(w, http.StatusOK, message, result)
+}
+
+func (h *AssignmentHandler) writeAssignments(w http.ResponseWriter, r *http.Request, teacher *models.Teacher, message string) {
+	classes, err := h.Assignments.ClassesOf(r.Context(), teacher.ID)
+	if err != nil {
+		logError(r, "Error fetching classes of teacher %d: %v", teacher.ID, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, message, models.ClassAssignments{
+		TeacherID: teacher.ID,
+		HomeClass: teacher.Class,
+		Classes:   classes,
+	})
+}
+
+func (h *AssignmentHandler) teacherFromPath(w http.ResponseWriter, r *http.Request) (*models.Teacher, bool) {
+	id := utils.PathID(r, "id")
+	teacher, err := h.Teachers.GetByID(r.Context(), id)
+	if err != nil {
+		logError(r, "Error fetching teacher %d: %v", id, err)
+		utils.ResponseError(w, err, fmt.Sprintf("Teacher with ID %d not found", id))
+		return nil, false
+	}
+	return teacher, true
+}
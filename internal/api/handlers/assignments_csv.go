@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"simpleapi/internal/models"
+	"simpleapi/internal/query"
+	"simpleapi/internal/repository"
+	"slices"
+	"strings"
+)
+
+// assignmentCSVColumns are the columns of an assignment import, all required, in any order
+var assignmentCSVColumns = []string{"teacher_email", "class", "subject", "role"}
+
+// readAssignmentsCSV parses an assignment import. Like readStudentsCSV, unknown
+// columns are rejected.
+func readAssignmentsCSV(body io.Reader) ([]models.AssignmentRow, error) {
+	in := csv.NewReader(body)
+	in.TrimLeadingSpace = true
+
+	header, err := in.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("missing header row")
+	}
+	if err != nil {
+		return nil, err
+	}
+	position := make(map[string]int, len(header))
+	for i, column := range header {
+		if !slices.Contains(assignmentCSVColumns, column) {
+			return nil, fmt.Errorf("unknown column %q", column)
+		}
+		position[column] = i
+	}
+	for _, column := range assignmentCSVColumns {
+		if _, ok := position[column]; !ok {
+			return nil, fmt.Errorf("missing column %q", column)
+		}
+	}
+
+	rows := make([]models.AssignmentRow, 0)
+	for {
+		record, err := in.Read()
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err // csv.ParseError already names the line
+		}
+		field := func(column string) string { return strings.TrimSpace(record[position[column]]) }
+		rows = append(rows, models.AssignmentRow{
+			TeacherEmail: field("teacher_email"),
+			Class:        field("class"),
+			Subject:      field("subject"),
+			Role:         field("role"),
+		})
+	}
+}
+
+// assignmentPlan is an import checked against the database: for every teacher
+// the file lists, in file order, their new home class (if a row makes them
+// class teacher) and the full set of classes they teach as a subject teacher
+type assignmentPlan struct {
+	teachers []*models.Teacher
+	home     map[int]string
+	classes  map[int][]string
+}
+
+// planAssignments checks every row, and returns the plan or what is wrong
+// with the rows (their Index is the row's, the first after the header being 0).
+// A class exists if a student is in it or a teacher is its class teacher.
+func planAssignments(ctx context.Context, repos repository.Repos, rows []models.AssignmentRow) (*assignmentPlan, []models.ValidationError, error) {
+	known, err := knownClasses(ctx, repos)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	plan := &assignmentPlan{home: make(map[int]string), classes: make(map[int][]string)}
+	byEmail := make(map[string]*models.Teacher)
+	var invalid []models.ValidationError
+	for i, row := range rows {
+		reject := func(field, msg string) {
+			invalid = append(invalid, models.ValidationError{Field: field, Msg: msg, Index: &i})
+		}
+		if !slices.Contains(models.AssignmentRoles, row.Role) {
+			reject("role", "must be one of "+strings.Join(models.AssignmentRoles, ", "))
+		}
+		if !known[row.Class] {
+			reject("class", fmt.Sprintf("no class %q: it has no students and no class teacher", row.Class))
+		}
+
+		teacher, ok := byEmail[row.TeacherEmail]
+		if !ok {
+			teacher, err = repos.Teachers.GetByEmail(ctx, row.TeacherEmail)
+			if errors.Is(err, models.ErrNotFound) {
+				reject("teacher_email", fmt.Sprintf("no teacher with email %q", row.TeacherEmail))
+				continue
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+			byEmail[row.TeacherEmail] = teacher
+			plan.teachers = append(plan.teachers, teacher)
+			plan.classes[teacher.ID] = []string{}
+		}
+		// Teachers have one subject: the column confirms the coordinator means them
+		if !strings.EqualFold(row.Subject, teacher.Subject) {
+			reject("subject", fmt.Sprintf("%s teaches %s", row.TeacherEmail, teacher.Subject))
+		}
+
+		switch row.Role {
+		case models.AssignmentClassTeacher:
+			if class, ok := plan.home[teacher.ID]; ok && class != row.Class {
+				reject("role", fmt.Sprintf("%s is already class teacher of %s in this file", row.TeacherEmail, class))
+			}
+			plan.home[teacher.ID] = row.Class
+		case models.AssignmentSubjectTeacher:
+			plan.classes[teacher.ID] = append(plan.classes[teacher.ID], row.Class)
+		}
+	}
+	if len(invalid) > 0 {
+		return nil, invalid, nil
+	}
+	for id, classes := range plan.classes {
+		plan.classes[id] = slices.Compact(slices.Sorted(slices.Values(classes)))
+	}
+	return plan, nil, nil
+}
+
+func knownClasses(ctx context.Context, repos repository.Repos) (map[string]bool, error) {
+	enrolled, err := repos.Students.CountByClass(ctx)
+	if err != nil {
+		return nil, err
+	}
+	teachers, err := repos.Teachers.GetAll(ctx, query.Options{})
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(enrolled))
+	for class := range enrolled {
+		known[class] = true
+	}
+	for _, t := range teachers {
+		known[t.Class] = true
+	}
+	return known, nil
+}
+
+// diff lists what applying the plan changes, teacher by teacher
+func (p *assignmentPlan) diff(ctx context.Context, repos repository.Repos) ([]models.AssignmentChange, error) {
+	changes := make([]models.AssignmentChange, 0)
+	for _, t := range p.teachers {
+		change := models.AssignmentChange{TeacherID: t.ID, TeacherEmail: t.Email}
+		if class, ok := p.home[t.ID]; ok && class != t.Class {
+			change.Action, change.Class, change.Previous = models.AssignmentHomeClass, class, t.Class
+			changes = append(changes, change)
+		}
+		change.Previous = ""
+
+		current, err := repos.ClassAssignments.ClassesOf(ctx, t.ID)
+		if err != nil {
+			return nil, err
+		}
+		for _, class := range p.classes[t.ID] {
+			if !slices.Contains(current, class) {
+				change.Action, change.Class = models.AssignmentAdded, class
+				changes = append(changes, change)
+			}
+		}
+		for _, class := range current {
+			if !slices.Contains(p.classes[t.ID], class) {
+				change.Action, change.Class = models.AssignmentRemoved, class
+				changes = append(changes, change)
+			}
+		}
+	}
+	return changes, nil
+}
+
+// apply makes the changes diff listed. Home classes go through Patch, so
+// they're audited like any other edit of the teacher.
+func (p *assignmentPlan) apply(ctx context.Context, repos repository.Repos, changes []models.AssignmentChange, actorID *int) error {
+	reassigned := make(map[int]bool)
+	for _, c := range changes {
+		switch c.Action {
+		case models.AssignmentHomeClass:
+			if _, err := repos.Teachers.Patch(ctx, c.TeacherID, map[string]interface{}{"class": c.Class}, actorID); err != nil {
+				return err
+			}
+		default:
+			reassigned[c.TeacherID] = true
+		}
+	}
+	for _, t := range p.teachers {
+		if !reassigned[t.ID] {
+			continue
+		}
+		if err := repos.ClassAssignments.SetClasses(ctx, t.ID, p.classes[t.ID]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"simpleapi/internal/models"
+	"simpleapi/internal/policy"
+	"simpleapi/internal/query"
+	"simpleapi/internal/repository"
+	"simpleapi/pkg/clock"
+	"simpleapi/pkg/utils"
+	"time"
+)
+
+// AttendanceHandler serves class registers. A teacher of the class (or an admin)
+// takes the register once a day; the scheduler chases registers that are late.
+type AttendanceHandler struct {
+	Attendance repository.AttendanceStore
+	Students   repository.StudentStore
+	Policy     *policy.Policy
+	Clock      clock.Clock
+}
+
+// NewAttendanceHandler is the constructor
+func NewAttendanceHandler(attendance repository.AttendanceStore, students repository.StudentStore, p *policy.Policy, clk clock.Clock) *AttendanceHandler {
+	return &AttendanceHandler{Attendance: attendance, Students: students, Policy: p, Clock: clk}
+}
+
+// TakeRegister records a class's attendance: PUT /classes/{class}/attendance/{date}.
+// Sending it again for the same day replaces it.
+func (h *AttendanceHandler) TakeRegister(w http.ResponseWriter, r *http.Request) {
+	class, date, ok := h.registerFromPath(w, r)
+	if !ok {
+		return
+	}
+	if date > h.Clock.Now().Format(models.DateLayout) {
+		utils.WriteError(w, http.StatusBadRequest, "Attendance can't be taken for a future date")
+		return
+	}
+	if !authorizeClass(w, r, h.Policy, class) {
+		return
+	}
+
+	var reg models.AttendanceRegister
+	if err := decodeJSON(r, &reg); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if errs := models.ValidateOne(reg); len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	// Every mark must be for a student on this class's roster, once
+	students, err := h.Students.GetAll(r.Context(), query.Options{Where: models.ClassRoster(class)})
+	if err != nil {
+		logError(r, "Error fetching students of %s: %v", class, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	inClass := make(map[int]bool, len(students))
+	for _, s := range students {
+		inClass[s.ID] = true
+	}
+	seen := make(map[int]bool, len(reg.Records))
+	for _, m := range reg.Records {
+		if !inClass[m.StudentID] {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Sprintf("Student %d is not in class %s", m.StudentID, class))
+			return
+		}
+		if seen[m.StudentID] {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Sprintf("Student %d is marked twice", m.StudentID))
+			return
+		}
+		seen[m.StudentID] = true
+	}
+
+	reg.Class, reg.Date, reg.TakenBy = class, date, currentUserID(r)
+	saved, err := h.Attendance.SaveRegister(r.Context(), reg)
+	if err != nil {
+		logError(r, "Error saving register of %s on %s: %v", class, date, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Register saved successfully", saved)
+}
+
+// GetRegister returns a class's attendance for a day
+func (h *AttendanceHandler) GetRegister(w http.ResponseWriter, r *http.Request) {
+	class, date, ok := h.registerFromPath(w, r)
+	if !ok {
+		return
+	}
+	reg, err := h.Attendance.GetRegister(r.Context(), class, date)
+	if err != nil {
+		utils.ResponseError(w, err, fmt.Sprintf("No register taken for %s on %s", class, date))
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Register fetched successfully", reg)
+}
+
+func (h *AttendanceHandler) registerFromPath(w http.ResponseWriter, r *http.Request) (class, date string, ok bool) {
+	class, date = r.PathValue("class"), r.PathValue("date")
+	if class == "" || len(class) > 50 {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid class")
+		return "", "", false
+	}
+	if _, err := time.Parse(models.DateLayout, date); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid date, expected YYYY-MM-DD")
+		return "", "", false
+	}
+	return class, date, true
+}
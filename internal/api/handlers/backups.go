@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"simpleapi/internal/jobs"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"simpleapi/internal/storage"
+	"simpleapi/pkg/utils"
+	"strconv"
+)
+
+// BackupHandler starts and serves database backups. Like archives, a backup runs
+// on the job queue and clients poll it until it is done. Restoring is deliberately
+// not an endpoint: it replaces everything, so it is done with cmd/restore.
+type BackupHandler struct {
+	Backups  repository.BackupStore // nil with DB_DRIVER=memory
+	Backuper *jobs.Backuper
+	Queue    *jobs.Queue
+	Storage  storage.Storage
+}
+
+// NewBackupHandler is the constructor
+func NewBackupHandler(backups repository.BackupStore, backuper *jobs.Backuper, queue *jobs.Queue, store storage.Storage) *BackupHandler {
+	return &BackupHandler{Backups: backups, Backuper: backuper, Queue: queue, Storage: store}
+}
+
+// StartBackup dumps the database in the background: POST /admin/backup
+func (h *BackupHandler) StartBackup(w http.ResponseWriter, r *http.Request) {
+	if !h.available(w) {
+		return
+	}
+	backup, err := h.Backups.Create(r.Context(), currentUserID(r))
+	if err != nil {
+		if errors.Is(err, models.ErrConflict) {
+			utils.WriteError(w, http.StatusConflict, "A backup is already in progress")
+			return
+		}
+		logError(r, "Error creating backup: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+
+	if err := h.Queue.Enqueue(h.Backuper.Job(*backup)); err != nil {
+		// Don't leave it pending forever: nothing will ever pick it up
+		h.Backups.Finish(r.Context(), backup.ID, models.Backup{Status: models.JobFailed, Error: err.Error()})
+		utils.WriteError(w, http.StatusServiceUnavailable, "Too many background jobs, try again later")
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/admin/backups/%d", backup.ID))
+	utils.WriteJSON(w, http.StatusAccepted, "Backup started", backup)
+}
+
+// ListBackups lists backups newest first
+func (h *BackupHandler) ListBackups(w http.ResponseWriter, r *http.Request) {
+	if !h.available(w) {
+		return
+	}
+	backups, err := h.Backups.List(r.Context())
+	if err != nil {
+		logError(r, "Error listing backups: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Backups fetched successfully", backups)
+}
+
+func (h *BackupHandler) GetBackup(w http.ResponseWriter, r *http.Request) {
+	backup, ok := h.backupFromPath(w, r)
+	if !ok {
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Backup fetched successfully", backup)
+}
+
+// DownloadBackup streams the dump, e.g. to keep a copy off site or to feed cmd/restore -file
+func (h *BackupHandler) DownloadBackup(w http.ResponseWriter, r *http.Request) {
+	backup, ok := h.backupFromPath(w, r)
+	if !ok {
+		return
+	}
+	if backup.Status != models.JobDone {
+		utils.WriteError(w, http.StatusConflict, fmt.Sprintf("Backup is %s, not ready for download", backup.Status))
+		return
+	}
+
+	rc, err := h.Storage.Get(r.Context(), backup.StorageKey)
+	if err != nil {
+		log.Printf("Error reading backup %d: %v", backup.ID, err)
+		utils.WriteError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	defer rc.Close()
+
+	name := fmt.Sprintf("backup-%s-%d.jsonl.gz", backup.CreatedAt.UTC().Format("20060102T150405Z"), backup.ID)
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	w.Header().Set("Content-Length", strconv.FormatInt(backup.Size, 10))
+	w.Header().Set("X-Checksum-SHA256", backup.SHA256)
+	io.Copy(w, rc)
+}
+
+// available answers 503 when there is no database to back up (DB_DRIVER=memory)
+func (h *BackupHandler) available(w http.ResponseWriter) bool {
+	if h.Backups == nil {
+		utils.WriteError(w, http.StatusServiceUnavailable, "Backups need the MySQL driver")
+		return false
+	}
+	return true
+}
+
+func (h *BackupHandler) backupFromPath(w http.ResponseWriter, r *http.Request) (*models.Backup, bool) {
+	if !h.available(w) {
+		return nil, false
+	}
+	id := utils.PathID(r, "id")
+	backup, err := h.Backups.GetByID(r.Context(), id)
+	if err != nil {
+		if !errors.Is(err, models.ErrNotFound) {
+			logError(r, "Error fetching backup %d: %v", id, err)
+		}
+		utils.ResponseError(w, err, "")
+		return nil, false
+	}
+	return backup, true
+}
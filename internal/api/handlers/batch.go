@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"simpleapi/internal/models"
+	"simpleapi/internal/tracing"
+	"simpleapi/pkg/utils"
+	"strconv"
+	"strings"
+)
+
+const maxBatchBytes = 5 << 20 // 5 MB, room for an atomic batch of imports
+
+// batchHeaders are the request headers sub-requests inherit: who is calling
+// and what they accept. Conditional headers (If-None-Match...) stay behind,
+// they were meant for POST /batch itself.
+var batchHeaders = []string{"Authorization", "Cookie", "Accept", "Accept-Language", "User-Agent"}
+
+// atomicRoutes are the routes an ?atomic=true batch may target: list writes
+// whose repository call applies the whole list all-or-nothing. The value is
+// the status the route answers when every item succeeds.
+var atomicRoutes = map[string]int{
+	"POST /students":  http.StatusCreated,
+	"POST /teachers":  http.StatusCreated,
+	"PATCH /teachers": http.StatusOK,
+}
+
+// BatchHandler runs several API requests sent as one (POST /batch), so the
+// admin SPA can load a screen in one round trip over a slow connection. Each
+// sub-request goes through the router like a request of its own: auth, roles,
+// path checks and the rate limiters included.
+type BatchHandler struct {
+	API http.Handler // The v1 router as mounted under /api/v1, behind the rate limiters
+}
+
+// NewBatchHandler is the constructor
+func NewBatchHandler(api http.Handler) *BatchHandler {
+	return &BatchHandler{API: api}
+}
+
+// Batch runs the sub-requests one after the other and answers 200 with every
+// outcome; one failing doesn't stop the rest. With ?atomic=true they must all
+// be writes to the same route in atomicRoutes: their bodies are joined into
+// one list and sent as one request, so either every item is applied or none is.
+func (h *BatchHandler) Batch(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxBatchBytes)
+	var reqs []models.BatchRequest
+	if err := decodeJSON(r, &reqs); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if len(reqs) == 0 {
+		utils.WriteError(w, http.StatusBadRequest, "No requests provided")
+		return
+	}
+	if len(reqs) > models.MaxBatchRequests {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Sprintf("A batch holds at most %d requests", models.MaxBatchRequests))
+		return
+	}
+	if errors := models.ValidateBatch(reqs); len(errors) > 0 {
+		writeValidationErrors(w, r, errors)
+		return
+	}
+	for i, req := range reqs {
+		u, err := url.Parse(req.Path)
+		if err != nil || u.Host != "" {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Sprintf("Request %d: invalid path", i))
+			return
+		}
+		if path.Clean(u.Path) == "/batch" {
+			utils.WriteError(w, http.StatusBadRequest, "Batches can't be nested")
+			return
+		}
+	}
+
+	atomic, _ := strconv.ParseBool(r.URL.Query().Get("atomic"))
+	if atomic {
+		responses, ok := h.runAtomic(w, r, reqs)
+		if ok {
+			utils.WriteJSON(w, http.StatusOK, "Batch executed successfully", responses)
+		}
+		return
+	}
+
+	responses := make([]models.BatchResponse, len(reqs))
+	for i, req := range reqs {
+		responses[i] = h.run(w, r, req).response(i)
+	}
+	utils.WriteJSON(w, http.StatusOK, "Batch executed successfully", responses)
+}
+
+// runAtomic joins the batch into one bulk request and hands each sub-request
+// its share of the result. It answers the client itself when the batch can't
+// be joined.
+func (h *BatchHandler) runAtomic(w http.ResponseWriter, r *http.Request, reqs []models.BatchRequest) ([]models.BatchResponse, bool) {
+	first := reqs[0]
+	route, _, _ := strings.Cut(first.Path, "?")
+	success, ok := atomicRoutes[first.Method+" "+route]
+	if !ok {
+		utils.WriteError(w, http.StatusBadRequest, "An atomic batch can only hold POST /students, POST /teachers or PATCH /teachers requests")
+		return nil, false
+	}
+
+	var items []json.RawMessage
+	counts := make([]int, len(reqs))
+	for i, req := range reqs {
+		if req.Method != first.Method || req.Path != first.Path || req.ContentType != first.ContentType {
+			utils.WriteError(w, http.StatusBadRequest, "Every request of an atomic batch must have the same method, path and content type")
+			return nil, false
+		}
+		elems, err := batchElements(req.Body)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Sprintf("Request %d: body must be an object or a list of objects", i))
+			return nil, false
+		}
+		counts[i] = len(elems)
+		items = append(items, elems...)
+	}
+
+	joined := first
+	joined.Body, _ = json.Marshal(items)
+	return splitBulk(h.run(w, r, joined), counts, success), true
+}
+
+// run sends one sub-request of the batch answering w through the router and
+// buffers its response
+func (h *BatchHandler) run(w http.ResponseWriter, r *http.Request, req models.BatchRequest) *batchWriter {
+	ctx, span := tracing.Start(r.Context(), "batch "+req.Method+" "+req.Path)
+	defer span.End()
+
+	bw := newBatchWriter(w)
+	sub, err := http.NewRequestWithContext(ctx, req.Method, req.Path, bytes.NewReader(req.Body))
+	if err != nil {
+		utils.WriteError(bw, http.StatusBadRequest, "Invalid path")
+		return bw
+	}
+	for _, name := range batchHeaders {
+		if values := r.Header.Values(name); len(values) > 0 {
+			sub.Header[name] = values
+		}
+	}
+	if len(req.Body) > 0 {
+		sub.Header.Set("Content-Type", cmp.Or(req.ContentType, "application/json"))
+	}
+	sub.RemoteAddr, sub.Host, sub.TLS = r.RemoteAddr, r.Host, r.TLS
+
+	h.API.ServeHTTP(bw, sub)
+	return bw
+}
+
+// splitBulk gives each sub-request of an atomic batch the items of the bulk
+// response that came from its body, renumbered from 0. A response without
+// per-item results (validation errors, a 500) goes to every sub-request as is;
+// validation error indices then count through the joined list.
+func splitBulk(bw *batchWriter, counts []int, success int) []models.BatchResponse {
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	result, message, ok := bw.bulkResult(total)
+
+	responses := make([]models.BatchResponse, len(counts))
+	start := 0
+	for i, n := range counts {
+		if !ok {
+			responses[i] = bw.response(i)
+			continue
+		}
+		part := models.BulkResult[json.RawMessage]{DryRun: result.DryRun}
+		for _, item := range result.Items[start : start+n] {
+			part.Add(item)
+		}
+		start += n
+
+		pw := newBatchWriter(bw)
+		writeBulk(pw, part, success, message)
+		responses[i] = pw.response(i)
+	}
+	return responses
+}
+
+// batchElements reads a sub-request body of an atomic batch: a list of items, or a single one
+func batchElements(body json.RawMessage) ([]json.RawMessage, error) {
+	var list []json.RawMessage
+	if err := json.Unmarshal(body, &list); err == nil {
+		return list, nil
+	}
+	var item map[string]json.RawMessage
+	if err := json.Unmarshal(body, &item); err != nil {
+		return nil, err
+	}
+	return []json.RawMessage{body}, nil
+}
+
+// batchWriter buffers the response of one sub-request
+type batchWriter struct {
+	header  http.Header
+	status  int
+	body    bytes.Buffer
+	problem bool
+	redact  bool
+}
+
+// newBatchWriter takes the error format and redaction of outer, the response
+// the sub-request is part of
+func newBatchWriter(outer http.ResponseWriter) *batchWriter {
+	return &batchWriter{header: http.Header{}, problem: utils.WantsProblem(outer), redact: utils.RedactsFields(outer)}
+}
+
+func (bw *batchWriter) Header() http.Header {
+	return bw.header
+}
+
+func (bw *batchWriter) WriteHeader(code int) {
+	if bw.status == 0 {
+		bw.status = code
+	}
+}
+
+func (bw *batchWriter) Write(b []byte) (int, error) {
+	bw.WriteHeader(http.StatusOK)
+	return bw.body.Write(b)
+}
+
+func (bw *batchWriter) WantsProblemJSON() bool {
+	return bw.problem
+}
+
+func (bw *batchWriter) RedactsFields() bool {
+	return bw.redact
+}
+
+func (bw *batchWriter) response(index int) models.BatchResponse {
+	res := models.BatchResponse{Index: index, Status: cmp.Or(bw.status, http.StatusOK)}
+	switch {
+	case bw.body.Len() == 0:
+	case isJSONType(bw.header.Get("Content-Type")) && json.Valid(bw.body.Bytes()):
+		res.Body = bytes.Clone(bw.body.Bytes())
+	default:
+		res.Body, _ = json.Marshal(bw.body.Bytes()) // []byte encodes as base64
+		res.Encoding = "base64"
+	}
+	return res
+}
+
+// bulkResult finds the BulkResult of n items in a buffered bulk response, in
+// "data" on success and in "details" (or "errors", for problem+json) otherwise
+func (bw *batchWriter) bulkResult(n int) (models.BulkResult[json.RawMessage], string, bool) {
+	var body struct {
+		Message string          `json:"message"`
+		Detail  string          `json:"detail"`
+		Data    json.RawMessage `json:"data"`
+		Details json.RawMessage `json:"details"`
+		Errors  json.RawMessage `json:"errors"`
+	}
+	if err := json.Unmarshal(bw.body.Bytes(), &body); err != nil {
+		return models.BulkResult[json.RawMessage]{}, "", false
+	}
+	for _, raw := range []json.RawMessage{body.Data, body.Details, body.Errors} {
+		var result models.BulkResult[json.RawMessage]
+		if err := json.Unmarshal(raw, &result); err == nil && result.Items != nil && len(result.Items) == n {
+			return result, cmp.Or(body.Message, body.Detail), true
+		}
+	}
+	return models.BulkResult[json.RawMessage]{}, "", false
+}
+
+func isJSONType(contentType string) bool {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
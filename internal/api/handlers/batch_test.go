@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// batchResult decodes the responses of POST /batch
+func batchResult(t *testing.T, rec *httptest.ResponseRecorder) []struct {
+	Index  int `json:"index"`
+	Status int `json:"status"`
+} {
+	t.Helper()
+	var body struct {
+		Data []struct {
+			Index  int `json:"index"`
+			Status int `json:"status"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decoding %s: %v", rec.Body, err)
+	}
+	return body.Data
+}
+
+func TestBatchSubRequestContentType(t *testing.T) {
+	var got []string
+	api := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Header.Get("Content-Type"))
+		w.WriteHeader(http.StatusNoContent)
+	})
+	body := `[
+		{"method": "POST", "path": "/students", "body": {"first_name": "Ada"}},
+		{"method": "PATCH", "path": "/students/1", "content_type": "application/json-patch+json", "body": [{"op": "remove", "path": "/custom_fields/bus"}]},
+		{"method": "GET", "path": "/students/1"}
+	]`
+	rec := httptest.NewRecorder()
+	NewBatchHandler(api).Batch(rec, httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(body)))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	want := []string{"application/json", "application/json-patch+json", ""}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("sub-request content types = %q, want %q", got, want)
+	}
+}
+
+func TestBatchChargesEverySubRequest(t *testing.T) {
+	// A limiter allowing two requests, wrapped around the API the way the
+	// router mounts it for the batch handler
+	charged := 0
+	limit := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			charged++
+			if charged > 2 {
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+	api := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	body := `[
+		{"method": "GET", "path": "/students/1"},
+		{"method": "GET", "path": "/students/2"},
+		{"method": "GET", "path": "/students/3"}
+	]`
+	rec := httptest.NewRecorder()
+	NewBatchHandler(limit(api)).Batch(rec, httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(body)))
+
+	if charged != 3 {
+		t.Errorf("limiter charged %d times, want 3", charged)
+	}
+	statuses := []int{}
+	for _, res := range batchResult(t, rec) {
+		statuses = append(statuses, res.Status)
+	}
+	if len(statuses) != 3 || statuses[0] != http.StatusNoContent || statuses[2] != http.StatusTooManyRequests {
+		t.Errorf("statuses = %v, want [204 204 429]", statuses)
+	}
+}
+
+func TestBatchRejectsNested(t *testing.T) {
+	api := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("nested batch reached the API")
+	})
+	rec := httptest.NewRecorder()
+	body := `[{"method": "POST", "path": "/batch", "body": []}]`
+	NewBatchHandler(api).Batch(rec, httptest.NewRequest(http.MethodPost, "/batch", strings.NewReader(body)))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400", rec.Code)
+	}
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"simpleapi/internal/locale"
+	"simpleapi/internal/models"
+	"simpleapi/pkg/utils"
+	"strings"
+)
+
+// writeBulk sends a BulkResult with the status it calls for (see BulkResult.Status).
+// When nothing succeeded it goes out as an error, with the result as details.
+func writeBulk[T any](w http.ResponseWriter, result models.BulkResult[T], success int, message string) {
+	code := result.Status(success)
+	if code >= 400 {
+		utils.WriteError(w, code, message, result)
+		return
+	}
+	utils.WriteJSON(w, code, message, result)
+}
+
+// writeBulkError answers a failed all-or-nothing batch of n items. An error the
+// repository pinned on one item (models.ItemError) is reported on that item and
+// the rest are marked skipped; anything else goes through ResponseError.
+func writeBulkError[T any](w http.ResponseWriter, n int, err error, message string) {
+	var itemErr *models.ItemError
+	if !errors.As(err, &itemErr) {
+		utils.ResponseError(w, err, message)
+		return
+	}
+
+	var result models.BulkResult[T]
+	for i := range n {
+		if i != itemErr.Index {
+			result.Add(models.BulkItem[T]{Status: models.BulkSkipped, Code: http.StatusFailedDependency})
+			continue
+		}
+		item := models.BulkItem[T]{ID: itemErr.ID, Code: utils.ErrorStatus(err), Error: err.Error()}
+		switch item.Code {
+		case http.StatusNotFound:
+			item.Status = models.BulkNotFound
+		case http.StatusConflict:
+			item.Status = models.BulkConflict
+		case http.StatusBadRequest:
+			item.Status = models.BulkInvalid
+		default:
+			log.Printf("Bulk item %d failed: %v", i, err)
+			item.Status, item.Error = models.BulkFailed, "Internal Server Error" // Like ResponseError, never leak 500 details
+		}
+		result.Add(item)
+	}
+	writeBulk(w, result, http.StatusOK, message)
+}
+
+// uniqueKey is a unique field of a batch's items: Field as in
+// models.ConflictError, Name as in validation errors, and the item's value
+type uniqueKey struct {
+	Field, Name string
+	Value       func(i int) string
+}
+
+// uniqueErrors reports the items of a batch of n whose value of a key repeats
+// an earlier item's or is taken (the repository's Conflicts). Values compare
+// ignoring case, like the unique keys; empty ones are never a clash.
+func uniqueErrors(n int, taken []models.ConflictError, keys ...uniqueKey) []models.ValidationError {
+	inUse := make(map[models.ConflictError]bool)
+	for _, c := range taken {
+		inUse[models.ConflictError{Field: c.Field, Value: strings.ToLower(c.Value)}] = true
+	}
+
+	var errs []models.ValidationError
+	for _, k := range keys {
+		seen := make(map[string]bool)
+		for i := range n {
+			value := k.Value(i)
+			v := strings.ToLower(value)
+			switch {
+			case v == "":
+			case seen[v]:
+				errs = append(errs, withIndex([]models.ValidationError{models.RuleError(k.Name, "duplicate_in_batch", value)}, i)...)
+			case inUse[models.ConflictError{Field: k.Field, Value: v}]:
+				errs = append(errs, withIndex([]models.ValidationError{models.RuleError(k.Name, "taken", value)}, i)...)
+			}
+			seen[v] = true
+		}
+	}
+	return errs
+}
+
+// writeValidationReport answers a validate endpoint with what was found in a
+// batch of n. It is a 200 either way: the check itself succeeded.
+func writeValidationReport(w http.ResponseWriter, r *http.Request, n int, errs []models.ValidationError) {
+	lang := locale.Language(r.Context())
+	w.Header().Set("Content-Language", lang)
+	w.Header().Add("Vary", "Accept-Language")
+
+	message := "Validation passed"
+	if len(errs) > 0 {
+		message = models.Message(lang, "validation_failed")
+	}
+	utils.WriteJSON(w, http.StatusOK, message, models.ValidationReport{
+		Valid:   len(errs) == 0,
+		Checked: n,
+		Errors:  models.Localize(errs, lang),
+	})
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"simpleapi/internal/expr"
+	"simpleapi/internal/models"
+	"simpleapi/internal/policy"
+	"simpleapi/internal/query"
+	"simpleapi/internal/render"
+	"simpleapi/internal/repository"
+	"simpleapi/internal/schoolprofile"
+	"simpleapi/internal/storage"
+	"simpleapi/pkg/clock"
+	"simpleapi/pkg/utils"
+	"strconv"
+	"time"
+)
+
+// ClassRegisterHandler prints a class's attendance register for a month, for
+// teachers to fill in by hand when the network is down and enter afterwards
+type ClassRegisterHandler struct {
+	Students repository.StudentStore
+	Events   repository.EventStore
+	Photos   storage.Storage
+	Policy   *policy.Policy
+	Clock    clock.Clock
+	School   *schoolprofile.Profile
+}
+
+// NewClassRegisterHandler is the constructor
+func NewClassRegisterHandler(students repository.StudentStore, events repository.EventStore, photos storage.Storage, p *policy.Policy, clk clock.Clock, school *schoolprofile.Profile) *ClassRegisterHandler {
+	return &ClassRegisterHandler{Students: students, Events: events, Photos: photos, Policy: p, Clock: clk, School: school}
+}
+
+// GetPrintableRegister returns the register of a month:
+// GET /classes/{class}/register?format=pdf|csv&month=YYYY-MM. The month
+// defaults to the current one and the format to pdf. Its columns are the
+// school days: weekdays outside the calendar's holidays. Its rows are the
+// class's roster, every student in the class with ?include_inactive=true.
+func (h *ClassRegisterHandler) GetPrintableRegister(w http.ResponseWriter, r *http.Request) {
+	class := r.PathValue("class")
+	if class == "" || len(class) > 50 {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid class")
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "pdf"
+	}
+	if format != "pdf" && format != "csv" {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid format, expected pdf or csv")
+		return
+	}
+	now := h.Clock.Now()
+	month, ok := reportMonth(w, r, "month", now.Format(models.MonthLayout))
+	if !ok {
+		return
+	}
+	if !authorizeClass(w, r, h.Policy, class) {
+		return
+	}
+
+	where := models.ClassRoster(class)
+	if all, _ := strconv.ParseBool(r.URL.Query().Get("include_inactive")); all {
+		where = expr.Equal(models.StudentFields, "class", class)
+	}
+	students, err := h.Students.GetAll(r.Context(), query.Options{
+		Where: where,
+		Sort:  []query.Sort{{Field: "last_name"}, {Field: "first_name"}},
+	})
+	if err != nil {
+		logError(r, "Error fetching students of class %s: %v", class, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	days, err := h.schoolDays(r, class, month)
+	if err != nil {
+		logError(r, "Error fetching holidays of class %s: %v", class, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+
+	sheet := render.RegisterSheet{
+		School:    h.School.Name(),
+		Class:     class,
+		Month:     month,
+		Days:      days,
+		Students:  make([]render.RegisterStudent, len(students)),
+		PrintedAt: now,
+	}
+	for i, s := range students {
+		sheet.Students[i] = render.RegisterStudent{Name: s.LastName + ", " + s.FirstName, AdmissionNumber: s.AdmissionNumber}
+	}
+	filename := fmt.Sprintf("register-%s-%s", class, month.Format(models.MonthLayout))
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".csv"))
+		render.RegisterCSV(w, sheet)
+		return
+	}
+
+	for i, s := range students {
+		sheet.Students[i].Photo = h.thumbnail(r, s.ID)
+	}
+	pdf, err := render.RegisterPDF(sheet)
+	if err != nil {
+		logError(r, "Error rendering the register of class %s: %v", class, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".pdf"))
+	w.Write(pdf)
+}
+
+// schoolDays lists the weekdays of month that no holiday on the class's calendar covers
+func (h *ClassRegisterHandler) schoolDays(r *http.Request, class string, month time.Time) ([]time.Time, error) {
+	first := month
+	last := month.AddDate(0, 1, -1)
+	holidays, err := h.Events.List(r.Context(), models.EventFilter{
+		From:  first.Format(models.DateLayout),
+		To:    last.Format(models.DateLayout),
+		Type:  models.EventHoliday,
+		Class: class,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	days := make([]time.Time, 0, 23)
+	for d := first; !d.After(last); d = d.AddDate(0, 0, 1) {
+		if d.Weekday() == time.Saturday || d.Weekday() == time.Sunday {
+			continue
+		}
+		date, closed := d.Format(models.DateLayout), false
+		for _, e := range holidays {
+			if e.StartDate <= date && date <= e.EndDate { // ISO dates compare correctly as strings
+				closed = true
+				break
+			}
+		}
+		if !closed {
+			days = append(days, d)
+		}
+	}
+	return days, nil
+}
+
+// thumbnail reads a student's photo thumbnail, nil when they have none or it
+// can't be read: the register prints without it
+func (h *ClassRegisterHandler) thumbnail(r *http.Request, studentID int) []byte {
+	rc, err := h.Photos.Get(r.Context(), studentPhotoKey(studentID, "thumbnail"))
+	if err != nil {
+		if !errors.Is(err, storage.ErrNotFound) {
+			log.Printf("Error reading photo of student %d: %v", studentID, err)
+		}
+		return nil
+	}
+	defer rc.Close()
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, rc); err != nil {
+		log.Printf("Error reading photo of student %d: %v", studentID, err)
+		return nil
+	}
+	return buf.Bytes()
+}
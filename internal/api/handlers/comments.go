@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"simpleapi/internal/models"
+	"simpleapi/internal/policy"
+	"simpleapi/internal/repository"
+	"simpleapi/pkg/utils"
+	"sort"
+)
+
+// CommentHandler serves report-card comments and the report card itself
+type CommentHandler struct {
+	Comments repository.CommentStore
+	Students repository.StudentStore
+	Scores   repository.ScoreStore
+	Policy   *policy.Policy
+}
+
+// NewCommentHandler is the constructor
+func NewCommentHandler(comments repository.CommentStore, students repository.StudentStore, scores repository.ScoreStore, p *policy.Policy) *CommentHandler {
+	return &CommentHandler{Comments: comments, Students: students, Scores: scores, Policy: p}
+}
+
+func (h *CommentHandler) AddComment(w http.ResponseWriter, r *http.Request) {
+	studentID := utils.PathID(r, "id")
+
+	var comment models.StudentComment
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&comment); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if errors := models.ValidateOne(comment); len(errors) > 0 {
+		writeValidationErrors(w, r, errors)
+		return
+	}
+
+	student, err := h.Students.GetByID(r.Context(), studentID)
+	if err != nil {
+		logError(r, "Error fetching student %d: %v", studentID, err)
+		utils.ResponseError(w, err, fmt.Sprintf("Student with ID %d not found", studentID))
+		return
+	}
+
+	// Who may write what (teachers of other classes may write nothing):
+	//   general -> only the class teacher of the student's class
+	//   subject -> any teacher of the class, always filed under their own subject
+	if !authorizeClass(w, r, h.Policy, student.Class) {
+		return
+	}
+	teacher := currentUser(r)
+	switch comment.Kind {
+	case models.CommentKindGeneral:
+		if teacher.Class != student.Class {
+			utils.WriteError(w, http.StatusForbidden, "Only the class teacher can write the general comment")
+			return
+		}
+		comment.Subject = ""
+	case models.CommentKindSubject:
+		comment.Subject = teacher.Subject
+	}
+	comment.StudentID = studentID
+	comment.TeacherID = teacher.ID
+
+	created, err := h.Comments.Create(r.Context(), comment)
+	if err != nil {
+		logError(r, "Error creating comment for student %d: %v", studentID, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, "Comment added successfully", created)
+}
+
+func (h *CommentHandler) ModerateComment(w http.ResponseWriter, r *http.Request) {
+	var path struct {
+		StudentID int `path:"id"`
+		CommentID int `path:"commentId"`
+	}
+	if errs := utils.BindPath(r, &path); len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	studentID, commentID := path.StudentID, path.CommentID
+
+	var mod models.CommentModeration
+	if err := json.NewDecoder(r.Body).Decode(&mod); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if errors := models.ValidateOne(mod); len(errors) > 0 {
+		writeValidationErrors(w, r, errors)
+		return
+	}
+
+	comment, err := h.Comments.SetFlag(r.Context(), studentID, commentID, mod)
+	if err != nil {
+		logError(r, "Error moderating comment %d: %v", commentID, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, "Comment moderated successfully", comment)
+}
+
+// GetReportCard aggregates the student's report card, one block per term.
+// ?term= narrows it down to a single term.
+func (h *CommentHandler) GetReportCard(w http.ResponseWriter, r *http.Request) {
+	studentID := utils.PathID(r, "id")
+
+	student, err := h.Students.GetByID(r.Context(), studentID)
+	if err != nil {
+		logError(r, "Error fetching student %d: %v", studentID, err)
+		utils.ResponseError(w, err, fmt.Sprintf("Student with ID %d not found", studentID))
+		return
+	}
+
+	term := r.URL.Query().Get("term")
+	comments, err := h.Comments.ListByStudent(r.Context(), studentID, term, false)
+	if err != nil {
+		logError(r, "Error fetching comments for student %d: %v", studentID, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	scores, err := h.Scores.ListByStudent(r.Context(), studentID, term)
+	if err != nil {
+		logError(r, "Error fetching scores for student %d: %v", studentID, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+
+	card := models.ReportCard{Student: *student, Terms: make([]models.ReportCardTerm, 0)}
+	// Comments and scores both come back ordered by term; blockFor finds or opens a term's block
+	blockFor := func(term string) *models.ReportCardTerm {
+		for i := range card.Terms {
+			if card.Terms[i].Term == term {
+				return &card.Terms[i]
+			}
+		}
+		card.Terms = append(card.Terms, models.ReportCardTerm{
+			Term:            term,
+			SubjectComments: make([]models.StudentComment, 0),
+			Scores:          make([]models.StudentScore, 0),
+		})
+		return &card.Terms[len(card.Terms)-1]
+	}
+	for _, c := range comments {
+		block := blockFor(c.Term)
+		if c.Kind == models.CommentKindGeneral {
+			general := c
+			block.GeneralComment = &general
+		} else {
+			block.SubjectComments = append(block.SubjectComments, c)
+		}
+	}
+	for _, sc := range scores {
+		block := blockFor(sc.Term)
+		block.Scores = append(block.Scores, sc)
+	}
+	// A term with scores but no comments was appended after the others
+	sort.Slice(card.Terms, func(i, j int) bool { return card.Terms[i].Term < card.Terms[j].Term })
+
+	utils.WriteJSON(w, http.StatusOK, "Report card fetched successfully", card)
+}
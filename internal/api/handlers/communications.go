@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"simpleapi/internal/jobs"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"simpleapi/pkg/utils"
+)
+
+// CommunicationHandler sends bulk email to staff, students or guardians chosen
+// by a filter. The recipients are fixed when the campaign is created; sending
+// runs on the job queue and clients poll the campaign to follow it.
+type CommunicationHandler struct {
+	Campaigns repository.CampaignStore
+	Sender    *jobs.CampaignSender
+	Queue     *jobs.Queue
+}
+
+// NewCommunicationHandler is the constructor
+func NewCommunicationHandler(campaigns repository.CampaignStore, sender *jobs.CampaignSender, queue *jobs.Queue) *CommunicationHandler {
+	return &CommunicationHandler{Campaigns: campaigns, Sender: sender, Queue: queue}
+}
+
+// campaignDetail is GET /admin/communications/{id}: the campaign and how each recipient fared
+type campaignDetail struct {
+	*models.Campaign
+	Deliveries []models.CampaignRecipient `json:"deliveries"`
+}
+
+// CreateCampaign resolves the recipients and queues the send: POST /admin/communications
+func (h *CommunicationHandler) CreateCampaign(w http.ResponseWriter, r *http.Request) {
+	var req models.CampaignRequest
+	if err := decodeJSON(r, &req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	errors := models.ValidateOne(req)
+	if req.Recipients.Role != "" && req.Recipients.Audience != models.AudienceStaff {
+		errors = append(errors, models.RuleError("Role", "staff_only", ""))
+	}
+	if len(errors) > 0 {
+		writeValidationErrors(w, r, errors)
+		return
+	}
+
+	recipients, err := h.Sender.Resolve(r.Context(), req.Recipients)
+	if err != nil {
+		logError(r, "Error resolving campaign recipients: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	if len(recipients) == 0 {
+		utils.WriteError(w, http.StatusUnprocessableEntity, "No one with an email address matches these recipients")
+		return
+	}
+	if errs := h.Sender.CheckTemplates(req.Subject, req.Body, recipients); len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	campaign, err := h.Campaigns.Create(r.Context(), models.Campaign{
+		Subject:    req.Subject,
+		Body:       req.Body,
+		Recipients: req.Recipients,
+		CreatedBy:  currentUser(r).ID,
+	}, recipients)
+	if err != nil {
+		logError(r, "Error creating campaign: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+
+	if err := h.Queue.Enqueue(h.Sender.Job(*campaign)); err != nil {
+		// Don't leave it pending forever: nothing will ever pick it up
+		h.Campaigns.Finish(r.Context(), campaign.ID, models.JobFailed, err.Error())
+		utils.WriteError(w, http.StatusServiceUnavailable, "Too many background jobs, try again later")
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/admin/communications/%d", campaign.ID))
+	utils.WriteJSON(w, http.StatusAccepted, "Campaign queued for sending", campaign)
+}
+
+// ListCampaigns lists campaigns newest first, with their delivery counts
+func (h *CommunicationHandler) ListCampaigns(w http.ResponseWriter, r *http.Request) {
+	campaigns, err := h.Campaigns.List(r.Context())
+	if err != nil {
+		logError(r, "Error listing campaigns: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Campaigns fetched successfully", campaigns)
+}
+
+// GetCampaign returns a campaign with the delivery of each of its recipients
+func (h *CommunicationHandler) GetCampaign(w http.ResponseWriter, r *http.Request) {
+	id := utils.PathID(r, "id")
+	campaign, err := h.Campaigns.GetByID(r.Context(), id)
+	if err != nil {
+		logError(r, "Error fetching campaign %d: %v", id, err)
+		utils.ResponseError(w, err, fmt.Sprintf("Campaign with ID %d not found", id))
+		return
+	}
+	deliveries, err := h.Campaigns.Recipients(r.Context(), id)
+	if err != nil {
+		logError(r, "Error fetching recipients of campaign %d: %v", id, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Campaign fetched successfully", campaignDetail{Campaign: campaign, Deliveries: deliveries})
+}
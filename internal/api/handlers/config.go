@@ -0,0 +1,65 @@
+package handlers
+
+import (
+	"net/http"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"simpleapi/internal/schoolconfig"
+	"simpleapi/pkg/utils"
+)
+
+// ConfigHandler exports and imports the school's setup as one document
+// (models.SchoolConfig), to copy a configured environment to another
+type ConfigHandler struct {
+	Schemes repository.GradingStore
+}
+
+// NewConfigHandler is the constructor
+func NewConfigHandler(schemes repository.GradingStore) *ConfigHandler {
+	return &ConfigHandler{Schemes: schemes}
+}
+
+// ExportConfig returns the current setup; PUT it elsewhere to replicate it
+func (h *ConfigHandler) ExportConfig(w http.ResponseWriter, r *http.Request) {
+	cfg, err := schoolconfig.Export(r.Context(), h.Schemes)
+	if err != nil {
+		logError(r, "Error exporting config: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Config exported successfully", cfg)
+}
+
+// ImportConfig replaces the setup with the document in the body. The document
+// is the whole setup: entries it leaves out are deleted. ?dry_run=true only
+// returns the diff.
+func (h *ConfigHandler) ImportConfig(w http.ResponseWriter, r *http.Request) {
+	var cfg models.SchoolConfig
+	if err := decodeJSON(r, &cfg); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid config document")
+		return
+	}
+	if errs := cfg.Validate(); len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	current, err := h.Schemes.List(r.Context())
+	if err != nil {
+		logError(r, "Error fetching grading schemes: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	plan := models.ConfigPlan{DryRun: isDryRun(r), Changes: schoolconfig.Plan(current, &cfg)}
+	if plan.DryRun || len(plan.Changes) == 0 {
+		utils.WriteJSON(w, http.StatusOK, "Config diff computed", plan)
+		return
+	}
+
+	if err := h.Schemes.ReplaceAll(r.Context(), schoolconfig.Schemes(&cfg)); err != nil {
+		logError(r, "Error importing config: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Config imported successfully", plan)
+}
@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"simpleapi/internal/api/middlewares"
+	"simpleapi/internal/expr"
+	"simpleapi/internal/locale"
+	"simpleapi/internal/models"
+	"simpleapi/internal/policy"
+	"simpleapi/internal/query"
+	"simpleapi/pkg/errcodes"
+	"simpleapi/pkg/utils"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// logError logs why a request failed, unless the client cancelled it: browsers
+// abort requests on navigation, and a query cut short by that isn't a failure.
+// ResponseError answers those with 499 and CountCanceled counts them.
+func logError(r *http.Request, format string, args ...any) {
+	if errors.Is(r.Context().Err(), context.Canceled) {
+		return
+	}
+	log.Printf(format, args...)
+}
+
+// currentUser returns the teacher attached by the Protect middleware, or nil on public routes
+func currentUser(r *http.Request) *models.Teacher {
+	user, _ := r.Context().Value(middlewares.UserKey).(*models.Teacher)
+	return user
+}
+
+// currentUserID is the actor ID for audit entries (nil when unauthenticated)
+func currentUserID(r *http.Request) *int {
+	if user := currentUser(r); user != nil {
+		id := user.ID
+		return &id
+	}
+	return nil
+}
+
+// currentKiosk returns the key attached by KioskAuth, or nil outside /kiosk routes
+func currentKiosk(r *http.Request) *middlewares.KioskKey {
+	key, _ := r.Context().Value(middlewares.KioskKeyKey).(*middlewares.KioskKey)
+	return key
+}
+
+// authorizeClass answers 403 CLASS_NOT_ASSIGNED unless the current user
+// teaches class (see package policy), and 401 when there is no user at all
+func authorizeClass(w http.ResponseWriter, r *http.Request, p *policy.Policy, class string) bool {
+	err := p.CanModifyClass(r.Context(), currentUser(r), class)
+	if errors.Is(err, policy.ErrNoUser) {
+		utils.WriteErrorCode(w, http.StatusUnauthorized, errcodes.NotLoggedIn, "You are not logged in!")
+		return false
+	}
+	if errors.Is(err, models.ErrForbidden) {
+		utils.WriteErrorCode(w, http.StatusForbidden, errcodes.ClassNotAssigned, fmt.Sprintf("You are not assigned to class %s", class))
+		return false
+	}
+	if err != nil {
+		logError(r, "Error checking class assignments: %v", err)
+		utils.ResponseError(w, err, "")
+		return false
+	}
+	return true
+}
+
+// isDryRun reports ?dry_run=true: run every check, then roll back and return a preview
+func isDryRun(r *http.Request) bool {
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run"))
+	return dryRun
+}
+
+// parseFilter parses ?filter= against an entity's fields (see package expr)
+func parseFilter(s string, fields expr.Fields) (expr.Expr, []models.ValidationError) {
+	e, err := expr.Parse(s, fields)
+	if err != nil {
+		return nil, []models.ValidationError{models.RuleError("filter", "filter", err.Error())}
+	}
+	return e, nil
+}
+
+// listParams are the paging and field selection parameters list endpoints share
+type listParams struct {
+	Page    int      `query:"page" validate:"omitempty,gte=1"`
+	PerPage int      `query:"per_page" validate:"omitempty,gte=1,lte=100"`
+	Fields  []string `query:"fields"`
+}
+
+// defaultPerPage is the page size when only ?page= is given
+const defaultPerPage = 50
+
+// listOptions completes a list endpoint's query.Options: where is its parsed
+// filter and meta the sort ApplySort settled. ?page= and ?per_page= (at most
+// 100) page the list; without either it comes whole. ?fields= trims items to
+// some of selectable (the ID always stays).
+func listOptions(r *http.Request, where expr.Expr, meta *utils.QueryMeta, selectable []string) (query.Options, []models.ValidationError) {
+	opts := query.Options{Where: where}
+	var params listParams
+	if errs := utils.BindQuery(r, &params); len(errs) > 0 {
+		return opts, errs
+	}
+	for _, f := range params.Fields {
+		if !slices.Contains(selectable, f) {
+			return opts, []models.ValidationError{models.RuleError("fields", "oneof", strings.Join(selectable, " "))}
+		}
+	}
+	opts.Fields = params.Fields
+	meta.Fields = params.Fields
+
+	if meta.Sort != nil {
+		opts.Sort = []query.Sort{{Field: meta.Sort.Field, Desc: meta.Sort.Order == "DESC"}}
+	}
+	if params.Page > 0 || params.PerPage > 0 {
+		opts.Page = query.Page{Number: max(params.Page, 1), Size: params.PerPage}
+		if opts.Page.Size == 0 {
+			opts.Page.Size = defaultPerPage
+		}
+	}
+	return opts, nil
+}
+
+// decodeJSON strictly decodes the request body into dst (unknown fields are rejected)
+func decodeJSON(r *http.Request, dst any) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(dst)
+}
+
+// writeValidationErrors sends a 400 with the validation messages in the
+// request's language: Accept-Language, else the account's preference
+func writeValidationErrors(w http.ResponseWriter, r *http.Request, errs []models.ValidationError) {
+	lang := locale.Language(r.Context())
+	w.Header().Set("Content-Language", lang)
+	w.Header().Add("Vary", "Accept-Language")
+	utils.WriteError(w, http.StatusBadRequest, models.Message(lang, "validation_failed"), models.Localize(errs, lang))
+}
+
+// withIndex sets the batch index of errs, for checks made outside ValidateBatch
+func withIndex(errs []models.ValidationError, i int) []models.ValidationError {
+	for j := range errs {
+		idx := i
+		errs[j].Index = &idx
+	}
+	return errs
+}
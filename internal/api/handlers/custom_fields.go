@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"simpleapi/pkg/utils"
+)
+
+// CustomFieldHandler lets admins define the extra fields of an entity. The
+// values are set and read with the entity itself (e.g. PATCH /students/{id}).
+type CustomFieldHandler struct {
+	Repo repository.CustomFieldStore
+}
+
+// NewCustomFieldHandler is the constructor
+func NewCustomFieldHandler(repo repository.CustomFieldStore) *CustomFieldHandler {
+	return &CustomFieldHandler{Repo: repo}
+}
+
+// ListFields returns an entity's fields: GET /admin/custom-fields?entity=student
+func (h *CustomFieldHandler) ListFields(w http.ResponseWriter, r *http.Request) {
+	entity := r.URL.Query().Get("entity")
+	if entity == "" {
+		entity = models.CustomFieldStudent
+	}
+	if entity != models.CustomFieldStudent {
+		utils.WriteError(w, http.StatusBadRequest, "entity must be one of: "+models.CustomFieldStudent)
+		return
+	}
+
+	fields, err := h.Repo.List(r.Context(), entity)
+	if err != nil {
+		logError(r, "Error fetching custom fields: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Custom fields fetched successfully", utils.NewList(fields))
+}
+
+// CreateField defines a field: POST /admin/custom-fields
+func (h *CustomFieldHandler) CreateField(w http.ResponseWriter, r *http.Request) {
+	var field models.CustomField
+	if err := decodeJSON(r, &field); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	errs := models.ValidateOne(field)
+	if len(errs) == 0 {
+		// Student is the only entity so far; its own fields can't be shadowed in ?filter=
+		errs = field.Check(models.StudentFields)
+	}
+	if len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	created, err := h.Repo.Create(r.Context(), field, currentUserID(r))
+	if err != nil {
+		logError(r, "Error creating custom field %s: %v", field.Key, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, "Custom field created successfully", created)
+}
+
+// DeleteField removes a field and every value stored for it: DELETE /admin/custom-fields/{id}
+func (h *CustomFieldHandler) DeleteField(w http.ResponseWriter, r *http.Request) {
+	id := utils.PathID(r, "id")
+	if err := h.Repo.Delete(r.Context(), id, currentUserID(r)); err != nil {
+		logError(r, "Error deleting custom field %d: %v", id, err)
+		utils.ResponseError(w, err, fmt.Sprintf("Custom field with ID %d not found", id))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
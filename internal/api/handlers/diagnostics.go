@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"expvar"
+	"net/http"
+	"net/url"
+	"os"
+	"simpleapi/internal/buildinfo"
+	"simpleapi/internal/jobs"
+	"simpleapi/internal/metrics"
+	"simpleapi/internal/models"
+	"simpleapi/internal/selfcheck"
+	"simpleapi/pkg/clock"
+	"simpleapi/pkg/utils"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// diagnosticsPingTimeout bounds the database ping of GET /admin/diagnostics
+const diagnosticsPingTimeout = 2 * time.Second
+
+// diagnosticsSettings are the environment settings the diagnostics report when
+// set, besides every SCHEDULE_*. Those holding secrets only show as redacted.
+var diagnosticsSettings = []string{
+	"APP_URL", "AUTH_TOKEN_DELIVERY", "CAPTCHA_PROVIDER", "CAPTCHA_SECRET", "CAPTCHA_VERIFY_URL", "CLAMAV_ADDR",
+	"COOKIE_ACCESS_NAME", "COOKIE_DOMAIN", "COOKIE_PATH", "COOKIE_REFRESH_NAME", "COOKIE_REFRESH_PATH", "COOKIE_SAMESITE",
+	"COOKIE_SECURE",
+	"DB_CONN_MAX_LIFETIME", "DB_CONNECT_RETRIES", "DB_DRIVER", "DB_HOST", "DB_MAX_IDLE_CONNS", "DB_MAX_OPEN_CONNS",
+	"DB_NAME", "DB_PASSWORD", "DB_POOL_SAMPLE_INTERVAL", "DB_POOL_WAIT_THRESHOLD", "DB_PORT", "DB_USERNAME",
+	"ERROR_FORMAT", "EVENT_BUS", "EVENT_BUS_TOPIC", "EVENT_BUS_URL", "HSTS_INCLUDE_SUBDOMAINS", "HSTS_MAX_AGE",
+	"HSTS_PRELOAD", "ICAP_URL", "JOB_MAX_ATTEMPTS", "JOB_RETRY_BACKOFF", "JOB_WORKERS", "JWT_ISSUER",
+	"JWT_PREVIOUS_SECRET_KEY", "JWT_SECRET_KEY", "JWT_TTL", "KIOSK_API_KEYS", "KPI_REFRESH_INTERVAL", "LOGIN_BACKOFF",
+	"LOG_FORMAT", "LOG_REDACT", "LOG_REDACT_FIELDS", "MAIL_FROM",
+	"MAIL_PROVIDER", "METRICS_TOKEN", "OTEL_EXPORTER_OTLP_ENDPOINT", "OTEL_SERVICE_NAME", "OUTBOX_RELAY_INTERVAL",
+	"OUTBOX_WEBHOOK_SECRET", "OUTBOX_WEBHOOK_URL", "PASSWORD_BREACH_CHECK", "PASSWORD_BREACH_FILTER",
+	"PASSWORD_BREACH_URL", "PASSWORD_PEPPER", "PERMISSIONS_POLICY", "PHONE_DEFAULT_REGION", "RATE_LIMITS",
+	"REDIS_URL", "REFERRER_POLICY", "REPORTS_CACHE_TTL", "RESPONSE_REDACTION", "RETENTION", "SCHOOL_NAME",
+	"SCHOOL_TIMEZONE", "SECRETS_PROVIDER", "SECRETS_REFRESH_INTERVAL", "SECURITY_CSP", "SECURITY_CSP_RELAX",
+	"SERVER_HEADER", "SERVER_PORT", "SERVE_SPA", "SETUP_TOKEN", "SHARED_STATE", "SIEM_FORMAT", "SIEM_FORWARD_TOKEN",
+	"SIEM_FORWARD_URL", "SMS_PROVIDER", "SMS_SENDER_ID", "SMTP_ADDR", "SMTP_PASSWORD", "SMTP_USERNAME",
+	"TRACING_ENABLED", "TRANSCRIPT_PREVIOUS_SIGNING_KEYS", "TRANSCRIPT_SIGNING_KEY", "TRASH_RETENTION", "TRUSTED_PROXIES", "UPLOADS_DIR",
+	"UPLOAD_SCANNER", "VALIDATION_LANGUAGE",
+}
+
+// DiagnosticsHandler answers support's questions about an installation in
+// one call, without shell access to the server
+type DiagnosticsHandler struct {
+	DB        *sql.DB // nil with DB_DRIVER=memory
+	Queue     *jobs.Queue
+	Clock     clock.Clock
+	StartedAt time.Time
+}
+
+// NewDiagnosticsHandler is the constructor; the process is taken to start now
+func NewDiagnosticsHandler(db *sql.DB, queue *jobs.Queue, clk clock.Clock) *DiagnosticsHandler {
+	return &DiagnosticsHandler{DB: db, Queue: queue, Clock: clk, StartedAt: clk.Now()}
+}
+
+// GetDiagnostics returns the build, uptime, configuration (secrets redacted),
+// database latency and pending migrations, job queue and cache figures of the
+// instance that answers: GET /admin/diagnostics
+func (h *DiagnosticsHandler) GetDiagnostics(w http.ResponseWriter, r *http.Request) {
+	d := models.Diagnostics{
+		Build:     buildinfo.Get(),
+		StartedAt: h.StartedAt.UTC(),
+		Uptime:    h.Clock.Now().Sub(h.StartedAt).Truncate(time.Second).String(),
+		Config:    configSummary(),
+		Database: models.DatabaseDiagnostics{
+			Driver:  "mysql",
+			Latency: metrics.QueryLatency.Summary(),
+		},
+		Jobs:   h.Queue.Stats(),
+		Caches: cacheStats(),
+	}
+
+	if h.DB == nil {
+		d.Database.Driver = "memory"
+		d.Database.PendingMigrations = []models.PendingMigration{} // Nothing to migrate in memory
+	} else if ms, err := h.ping(r.Context()); err != nil {
+		d.Database.PingError = err.Error()
+	} else {
+		d.Database.PingMS = &ms
+		if d.Database.PendingMigrations, err = selfcheck.Pending(r.Context(), h.DB); err != nil {
+			logError(r, "Error checking migrations: %v", err)
+			d.Database.SchemaError = err.Error()
+		}
+	}
+	utils.WriteJSON(w, http.StatusOK, "Diagnostics fetched successfully", d)
+}
+
+// ping times a round trip to the database, in milliseconds
+func (h *DiagnosticsHandler) ping(ctx context.Context) (float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, diagnosticsPingTimeout)
+	defer cancel()
+	start := time.Now()
+	if err := h.DB.PingContext(ctx); err != nil {
+		return 0, err
+	}
+	return float64(time.Since(start).Microseconds()) / 1000, nil
+}
+
+// configSummary returns the settings that are set. Secrets read "[redacted]";
+// URLs lose their credentials and query, where tokens tend to hide.
+func configSummary() map[string]string {
+	config := make(map[string]string)
+	for _, name := range diagnosticsSettings {
+		if v := os.Getenv(name); v != "" {
+			config[name] = redactSetting(name, v)
+		}
+	}
+	for _, kv := range os.Environ() {
+		if name, v, _ := strings.Cut(kv, "="); strings.HasPrefix(name, "SCHEDULE_") && v != "" {
+			config[name] = v
+		}
+	}
+	return config
+}
+
+func redactSetting(name, value string) string {
+	for _, secret := range []string{"SECRET", "PASSWORD", "PEPPER", "TOKEN", "KEY"} {
+		if strings.Contains(name, secret) {
+			return "[redacted]"
+		}
+	}
+	if u, err := url.Parse(value); err == nil && u.Scheme != "" && u.Host != "" {
+		u.RawQuery = ""
+		return u.Redacted()
+	}
+	return value
+}
+
+// cacheStats turns the lookup counters of cache.Load into hit rates
+func cacheStats() map[string]models.CacheStat {
+	stats := make(map[string]models.CacheStat)
+	count := func(m *expvar.Map, add func(*models.CacheStat, int64)) {
+		m.Do(func(kv expvar.KeyValue) {
+			n, _ := strconv.ParseInt(kv.Value.String(), 10, 64)
+			s := stats[kv.Key]
+			add(&s, n)
+			stats[kv.Key] = s
+		})
+	}
+	count(metrics.CacheHits, func(s *models.CacheStat, n int64) { s.Hits = n })
+	count(metrics.CacheMisses, func(s *models.CacheStat, n int64) { s.Misses = n })
+	for name, s := range stats {
+		if lookups := s.Hits + s.Misses; lookups > 0 {
+			s.HitRate = float64(s.Hits) / float64(lookups)
+			stats[name] = s
+		}
+	}
+	return stats
+}
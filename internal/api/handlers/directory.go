@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"simpleapi/internal/cache"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"simpleapi/pkg/utils"
+	"strconv"
+	"time"
+)
+
+// DirectoryHandler serves the public staff directory for the school website.
+// The list changes rarely, so it is cached for cacheTTL.
+type DirectoryHandler struct {
+	Repo     repository.TeacherStore
+	Cache    cache.Cache
+	cacheTTL time.Duration
+}
+
+// directoryCacheKey is where the list is cached
+const directoryCacheKey = "directory"
+
+// NewDirectoryHandler is the constructor
+func NewDirectoryHandler(repo repository.TeacherStore, responses cache.Cache, cacheTTL time.Duration) *DirectoryHandler {
+	return &DirectoryHandler{Repo: repo, Cache: responses, cacheTTL: cacheTTL}
+}
+
+func (h *DirectoryHandler) GetDirectory(w http.ResponseWriter, r *http.Request) {
+	entries, err := cache.Load(r.Context(), h.Cache, directoryCacheKey, h.cacheTTL, h.Repo.ListDirectory)
+	if err != nil {
+		logError(r, "Error fetching staff directory: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+
+	response := utils.NewList(entries)
+
+	// Public data: let browsers and CDNs cache it as long as we do
+	w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(h.cacheTTL.Seconds())))
+	utils.WriteJSON(w, http.StatusOK, "Directory fetched successfully", response)
+}
+
+// Invalidate drops the cached list so an opt-in/out shows up immediately, on every instance
+func (h *DirectoryHandler) Invalidate(ctx context.Context) {
+	if err := h.Cache.Delete(ctx, directoryCacheKey); err != nil {
+		log.Printf("directory: could not drop the cached list: %v", err)
+	}
+}
+
+// SetListing lets a teacher publish or hide themselves; admins can do it for anyone
+func (h *DirectoryHandler) SetListing(w http.ResponseWriter, r *http.Request) {
+	id := utils.PathID(r, "id")
+
+	user := currentUser(r)
+	if user.ID != id && user.Role != models.RoleAdmin {
+		utils.WriteError(w, http.StatusForbidden, "You can only change your own directory listing")
+		return
+	}
+
+	var req models.DirectoryListing
+	if err := decodeJSON(r, &req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.Repo.SetDirectoryListing(r.Context(), id, req.Published); err != nil {
+		logError(r, "Error updating directory listing of teacher %d: %v", id, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	h.Invalidate(r.Context())
+
+	utils.WriteJSON(w, http.StatusOK, "Directory listing updated successfully", req)
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"simpleapi/internal/locale"
+	"simpleapi/internal/models"
+	"simpleapi/internal/storage"
+	"simpleapi/pkg/clock"
+	"simpleapi/pkg/utils"
+	"strconv"
+	"time"
+)
+
+// downloadPath is where a link's token is served, as seen by the client
+const downloadPath = "/api/v1/downloads/"
+
+// DownloadHandler serves the files behind signed download links, so emails
+// can link a document without a bearer token. Links are signed like
+// transcripts (TRANSCRIPT_SIGNING_KEY) and checked by signature and expiry
+// alone: following one runs no query.
+type DownloadHandler struct {
+	Storage storage.Storage
+	Signer  utils.DocumentSigning
+	Clock   clock.Clock
+}
+
+// NewDownloadHandler is the constructor
+func NewDownloadHandler(store storage.Storage, signer utils.DocumentSigning, clk clock.Clock) *DownloadHandler {
+	return &DownloadHandler{Storage: store, Signer: signer, Clock: clk}
+}
+
+// GetDownload streams the file of a link: GET /downloads/{token}. Public. A
+// token names one file until it expires, and altering any of it breaks the
+// signature, so a leaked link gives away that file for at most its lifetime.
+func (h *DownloadHandler) GetDownload(w http.ResponseWriter, r *http.Request) {
+	claims, ok := readDownloadLink(h.Signer, r.PathValue("token"))
+	if !ok {
+		utils.WriteError(w, http.StatusForbidden, "Invalid download link")
+		return
+	}
+	expires := time.Unix(claims.ExpiresAt, 0)
+	if !h.Clock.Now().Before(expires) {
+		utils.WriteError(w, http.StatusGone, "This download link has expired")
+		return
+	}
+
+	rc, err := h.Storage.Get(r.Context(), claims.Key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			utils.WriteError(w, http.StatusNotFound, "This file is no longer available")
+			return
+		}
+		log.Printf("Error reading %s %d for a download link: %v", claims.Kind, claims.ID, err)
+		utils.WriteError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	defer rc.Close()
+
+	w.Header().Set("Content-Type", claims.ContentType)
+	if claims.Name != "" {
+		w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": claims.Name}))
+	}
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	// Shared caches must not keep the file past the link, or for anyone else
+	w.Header().Set("Cache-Control", "private, max-age="+strconv.Itoa(int(expires.Sub(h.Clock.Now()).Seconds())))
+	io.Copy(w, rc)
+}
+
+// writeDownloadLink signs claims into a link valid for ?expires_in seconds
+// (models.DownloadLinkTTL by default) and answers with it
+func writeDownloadLink(w http.ResponseWriter, r *http.Request, signer utils.DocumentSigning, clk clock.Clock, claims models.DownloadClaims) {
+	ttl := models.DownloadLinkTTL
+	if v := r.URL.Query().Get("expires_in"); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil || seconds <= 0 || time.Duration(seconds)*time.Second > models.DownloadLinkMaxTTL {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Sprintf("expires_in must be between 1 and %d seconds", int(models.DownloadLinkMaxTTL.Seconds())))
+			return
+		}
+		ttl = time.Duration(seconds) * time.Second
+	}
+
+	expires := locale.In(r.Context(), clk, clk.Now().Add(ttl)).Truncate(time.Second)
+	claims.Type, claims.ExpiresAt = models.DownloadPayloadType, expires.Unix()
+	encoded, err := json.Marshal(claims)
+	if err != nil {
+		logError(r, "Error encoding download link of %s %d: %v", claims.Kind, claims.ID, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	token, keyID, err := utils.SignToken(signer, base64.RawURLEncoding.EncodeToString(encoded))
+	if errors.Is(err, utils.ErrNoSigningKey) {
+		utils.WriteError(w, http.StatusServiceUnavailable, "Download links are not configured")
+		return
+	}
+	if err != nil {
+		logError(r, "Error signing download link of %s %d: %v", claims.Kind, claims.ID, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, "Download link generated successfully", models.DownloadLink{
+		Path:      downloadPath + token,
+		ExpiresAt: expires,
+		KeyID:     keyID,
+	})
+}
+
+// readDownloadLink decodes a link's token, reporting false unless it is a
+// download link we signed
+func readDownloadLink(signer utils.DocumentSigning, token string) (claims models.DownloadClaims, ok bool) {
+	payload, ok := utils.VerifyToken(signer, token)
+	if !ok {
+		return models.DownloadClaims{}, false
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil || json.Unmarshal(decoded, &claims) != nil || claims.Type != models.DownloadPayloadType {
+		return models.DownloadClaims{}, false
+	}
+	return claims, true
+}
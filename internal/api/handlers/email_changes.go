@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"simpleapi/internal/jobs"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"simpleapi/pkg/clock"
+	"simpleapi/pkg/errcodes"
+	"simpleapi/pkg/utils"
+)
+
+// EmailChangeHandler runs the email change flow: a request sends a confirm link
+// to the new address and an undo link to the old one (jobs.EmailChangeNotices),
+// and the teacher's email only changes once the confirm link is used. PATCH and
+// PUT /teachers/{id} refuse email edits (ErrEmailChangeRequired).
+type EmailChangeHandler struct {
+	Changes repository.EmailChangeStore
+	Notices *jobs.EmailChangeNotices
+	Queue   *jobs.Queue
+	Clock   clock.Clock
+}
+
+// NewEmailChangeHandler is the constructor
+func NewEmailChangeHandler(changes repository.EmailChangeStore, notices *jobs.EmailChangeNotices, queue *jobs.Queue, clk clock.Clock) *EmailChangeHandler {
+	return &EmailChangeHandler{Changes: changes, Notices: notices, Queue: queue, Clock: clk}
+}
+
+// RequestChange starts a change: POST /teachers/{id}/email-change, by the teacher
+// themself or an admin. A new request replaces one still pending.
+func (h *EmailChangeHandler) RequestChange(w http.ResponseWriter, r *http.Request) {
+	id := utils.PathID(r, "id")
+	if user := currentUser(r); user.ID != id && user.Role != models.RoleAdmin {
+		utils.WriteError(w, http.StatusForbidden, "You can only change your own email")
+		return
+	}
+	var req models.EmailChangeRequest
+	if err := decodeJSON(r, &req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if errors := models.ValidateOne(req); len(errors) > 0 {
+		writeValidationErrors(w, r, errors)
+		return
+	}
+
+	confirmToken, undoToken := newEmailChangeToken(), newEmailChangeToken()
+	now := h.Clock.Now()
+	change, err := h.Changes.Create(r.Context(), models.EmailChange{
+		TeacherID:        id,
+		NewEmail:         req.Email,
+		ConfirmTokenHash: hashEmailChangeToken(confirmToken),
+		UndoTokenHash:    hashEmailChangeToken(undoToken),
+		ExpiresAt:        now.Add(models.EmailChangeConfirmTTL),
+		UndoExpiresAt:    now.Add(models.EmailChangeUndoTTL),
+		RequestedBy:      currentUserID(r),
+		CreatedAt:        now,
+	})
+	if err != nil {
+		logError(r, "Error requesting email change for teacher %d: %v", id, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+
+	// Without the emails the change can't be confirmed; asking again replaces it
+	if err := h.Queue.Enqueue(h.Notices.Job(*change, confirmToken, undoToken)); err != nil {
+		log.Printf("Error queueing notices for email change %d: %v", change.ID, err)
+		utils.WriteError(w, http.StatusServiceUnavailable, "The confirmation email could not be sent, please try again")
+		return
+	}
+	utils.WriteJSON(w, http.StatusAccepted, "Check the new address for a confirmation link", change)
+}
+
+// Confirm applies a change with the token from the confirm link: POST /email-change/confirm.
+// Public, the token is the credential.
+func (h *EmailChangeHandler) Confirm(w http.ResponseWriter, r *http.Request) {
+	token, ok := h.token(w, r)
+	if !ok {
+		return
+	}
+	change, err := h.Changes.Confirm(r.Context(), hashEmailChangeToken(token), h.Clock.Now())
+	if err != nil {
+		h.writeError(w, r, err, "confirming")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Email changed successfully", change)
+}
+
+// Undo stops or reverts a change with the token from the notice sent to the old
+// address: POST /email-change/undo. Public, the token is the credential.
+func (h *EmailChangeHandler) Undo(w http.ResponseWriter, r *http.Request) {
+	token, ok := h.token(w, r)
+	if !ok {
+		return
+	}
+	change, err := h.Changes.Undo(r.Context(), hashEmailChangeToken(token), h.Clock.Now())
+	if err != nil {
+		h.writeError(w, r, err, "undoing")
+		return
+	}
+	message := "Email change cancelled"
+	if change.Status == models.EmailChangeUndone {
+		message = "Email change reverted"
+	}
+	utils.WriteJSON(w, http.StatusOK, message, change)
+}
+
+func (h *EmailChangeHandler) token(w http.ResponseWriter, r *http.Request) (string, bool) {
+	var req models.EmailChangeToken
+	if err := decodeJSON(r, &req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return "", false
+	}
+	if errors := models.ValidateOne(req); len(errors) > 0 {
+		writeValidationErrors(w, r, errors)
+		return "", false
+	}
+	return req.Token, true
+}
+
+// writeError gives every unusable link the same answer, so tokens can't be probed
+func (h *EmailChangeHandler) writeError(w http.ResponseWriter, r *http.Request, err error, doing string) {
+	logError(r, "Error %s email change: %v", doing, err)
+	if errcodes.Of(err) == errcodes.EmailChangeLinkInvalid {
+		utils.WriteErrorCode(w, http.StatusNotFound, errcodes.EmailChangeLinkInvalid, "This link is invalid, has expired or was already used")
+		return
+	}
+	utils.ResponseError(w, err, "")
+}
+
+// newEmailChangeToken is 32 random bytes, URL-safe for the links
+func newEmailChangeToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// hashEmailChangeToken is what the store keeps and looks tokens up by
+func hashEmailChangeToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"simpleapi/internal/locale"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"simpleapi/internal/schoolprofile"
+	"simpleapi/pkg/clock"
+	"simpleapi/pkg/utils"
+	"slices"
+	"strings"
+	"time"
+)
+
+// EventHandler manages the school calendar and publishes it as an iCal feed
+type EventHandler struct {
+	Events repository.EventStore
+	Clock  clock.Clock
+	School *schoolprofile.Profile
+}
+
+// NewEventHandler is the constructor
+func NewEventHandler(events repository.EventStore, clk clock.Clock, school *schoolprofile.Profile) *EventHandler {
+	return &EventHandler{Events: events, Clock: clk, School: school}
+}
+
+// decodeEvent reads and validates an event body
+func decodeEvent(w http.ResponseWriter, r *http.Request) (models.Event, bool) {
+	var event models.Event
+	if err := decodeJSON(r, &event); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return event, false
+	}
+	if errors := models.ValidateOne(event); len(errors) > 0 {
+		writeValidationErrors(w, r, errors)
+		return event, false
+	}
+	if errors := event.CheckDates(); len(errors) > 0 {
+		writeValidationErrors(w, r, errors)
+		return event, false
+	}
+	if event.Audience == models.AudienceSchool {
+		event.Class = ""
+	}
+	return event, true
+}
+
+func (h *EventHandler) GetEvents(w http.ResponseWriter, r *http.Request) {
+	// ?from=&to=&type=&class=; dates must be YYYY-MM-DD
+	var filter models.EventFilter
+	if errs := utils.BindQuery(r, &filter); len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	events, err := h.Events.List(r.Context(), filter)
+	if err != nil {
+		logError(r, "Error fetching events: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, "Events fetched successfully", events)
+}
+
+func (h *EventHandler) GetEventByID(w http.ResponseWriter, r *http.Request) {
+	id := utils.PathID(r, "id")
+
+	event, err := h.Events.GetByID(r.Context(), id)
+	if err != nil {
+		logError(r, "Error fetching event %d: %v", id, err)
+		utils.ResponseError(w, err, fmt.Sprintf("Event with ID %d not found", id))
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, "Event fetched successfully", event)
+}
+
+func (h *EventHandler) CreateEvent(w http.ResponseWriter, r *http.Request) {
+	event, ok := decodeEvent(w, r)
+	if !ok {
+		return
+	}
+	event.CreatedBy = currentUserID(r)
+
+	created, err := h.Events.Create(r.Context(), event)
+	if err != nil {
+		logError(r, "Error creating event: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, "Event created successfully", created)
+}
+
+func (h *EventHandler) UpdateEvent(w http.ResponseWriter, r *http.Request) {
+	id := utils.PathID(r, "id")
+
+	event, ok := decodeEvent(w, r)
+	if !ok {
+		return
+	}
+
+	updated, err := h.Events.Update(r.Context(), id, event)
+	if err != nil {
+		logError(r, "Error updating event %d: %v", id, err)
+		utils.ResponseError(w, err, fmt.Sprintf("Event with ID %d not found", id))
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, "Event updated successfully", updated)
+}
+
+func (h *EventHandler) DeleteEvent(w http.ResponseWriter, r *http.Request) {
+	id := utils.PathID(r, "id")
+
+	deleted, err := h.Events.Delete(r.Context(), id)
+	if err != nil {
+		logError(r, "Error deleting event %d: %v", id, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	if !deleted {
+		utils.WriteError(w, http.StatusNotFound, fmt.Sprintf("Event with ID %d not found", id))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetICalFeed serves the calendar as text/calendar for Google/Apple/Outlook subscriptions.
+// Calendar apps can't log in, so the feed is public; ?class= adds that class's events.
+func (h *EventHandler) GetICalFeed(w http.ResponseWriter, r *http.Request) {
+	// ?from=&to=&type=&class=; dates must be YYYY-MM-DD
+	var filter models.EventFilter
+	if errs := utils.BindQuery(r, &filter); len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	events, err := h.Events.List(r.Context(), filter)
+	if err != nil {
+		logError(r, "Error fetching events for iCal feed: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	if filter.Class == "" {
+		// Without a class only whole-school events go out
+		events = slices.DeleteFunc(events, func(e models.Event) bool { return e.Audience != models.AudienceSchool })
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="school-calendar.ics"`)
+	w.Write([]byte(renderICal(events, r.Host, h.School.Name(), h.Clock.Now(), locale.Location(r.Context(), h.Clock))))
+}
+
+// renderICal writes events as all-day VEVENTs (RFC 5545). DTEND is exclusive,
+// hence the extra day on the inclusive end date. All-day dates float, showing
+// on the same days in every zone; loc only names the calendar's zone for the
+// apps that display it. The calendar is named after the school.
+func renderICal(events []models.Event, host, school string, now time.Time, loc *time.Location) string {
+	var b strings.Builder
+	line := func(s string) { b.WriteString(foldICalLine(s) + "\r\n") }
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//school-api//calendar//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	line("X-WR-TIMEZONE:" + loc.String())
+	if school != "" {
+		line("X-WR-CALNAME:" + escapeICal(school))
+	}
+	for _, e := range events {
+		start, _ := time.Parse(models.DateLayout, e.StartDate)
+		end, _ := time.Parse(models.DateLayout, e.EndDate)
+		stamp := e.UpdatedAt
+		if stamp.IsZero() {
+			stamp = now
+		}
+
+		line("BEGIN:VEVENT")
+		line(fmt.Sprintf("UID:event-%d@%s", e.ID, host))
+		line("DTSTAMP:" + stamp.UTC().Format("20060102T150405Z"))
+		line("DTSTART;VALUE=DATE:" + start.Format("20060102"))
+		line("DTEND;VALUE=DATE:" + end.AddDate(0, 0, 1).Format("20060102"))
+		line("SUMMARY:" + escapeICal(e.Title))
+		if e.Description != "" {
+			line("DESCRIPTION:" + escapeICal(e.Description))
+		}
+		line("CATEGORIES:" + strings.ToUpper(e.Type))
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return b.String()
+}
+
+var icalEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+
+func escapeICal(s string) string {
+	return icalEscaper.Replace(s)
+}
+
+// foldICalLine splits lines longer than 75 octets, continuing with a leading space
+// (without cutting a UTF-8 character in half)
+func foldICalLine(s string) string {
+	const limit = 75
+	var b strings.Builder
+	width := 0
+	for _, r := range s {
+		size := len(string(r))
+		if width+size > limit {
+			b.WriteString("\r\n ")
+			width = 1
+		}
+		b.WriteRune(r)
+		width += size
+	}
+	return b.String()
+}
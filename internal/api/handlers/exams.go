@@ -0,0 +1,320 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"simpleapi/internal/exams"
+	"simpleapi/internal/models"
+	"simpleapi/internal/query"
+	"simpleapi/internal/render"
+	"simpleapi/internal/repository"
+	"simpleapi/internal/schoolprofile"
+	"simpleapi/pkg/clock"
+	"simpleapi/pkg/utils"
+	"strconv"
+)
+
+// ExamHandler plans exam sessions: the office defines a session's classes,
+// time and rooms, then generates where every student of those classes sits
+// and which teachers invigilate each room (package exams), and prints the
+// lists for the doors. Staff can read the sessions to find their duties.
+type ExamHandler struct {
+	Exams    repository.ExamStore
+	Students repository.StudentStore
+	Teachers repository.TeacherStore
+	Clock    clock.Clock
+	School   *schoolprofile.Profile
+}
+
+// NewExamHandler is the constructor
+func NewExamHandler(examStore repository.ExamStore, students repository.StudentStore, teachers repository.TeacherStore, clk clock.Clock, school *schoolprofile.Profile) *ExamHandler {
+	return &ExamHandler{Exams: examStore, Students: students, Teachers: teachers, Clock: clk, School: school}
+}
+
+// CreateSession defines a session: POST /exams/sessions
+func (h *ExamHandler) CreateSession(w http.ResponseWriter, r *http.Request) {
+	req, ok := h.readSession(w, r)
+	if !ok {
+		return
+	}
+	session, err := h.Exams.Create(r.Context(), models.ExamSession{
+		Title:     req.Title,
+		Subject:   req.Subject,
+		Date:      req.Date,
+		StartTime: req.StartTime,
+		EndTime:   req.EndTime,
+		Classes:   req.Classes,
+		Rooms:     req.Rooms,
+		CreatedBy: currentUserID(r),
+	})
+	if err != nil {
+		logError(r, "Error creating exam session: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/exams/sessions/%d", session.ID))
+	utils.WriteJSON(w, http.StatusCreated, "Exam session created", session)
+}
+
+// GetSessions lists the sessions in the order they start: GET /exams/sessions,
+// or ?date=2026-06-12 for one day
+func (h *ExamHandler) GetSessions(w http.ResponseWriter, r *http.Request) {
+	date := r.URL.Query().Get("date")
+	if errs := models.ValidateOne(struct {
+		Date string `validate:"omitempty,datetime=2006-01-02"`
+	}{date}); len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	sessions, err := h.Exams.List(r.Context(), date)
+	if err != nil {
+		logError(r, "Error listing exam sessions: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Exam sessions fetched successfully", sessions)
+}
+
+// GetSession returns a session with its seats and invigilators: GET /exams/sessions/{id}
+func (h *ExamHandler) GetSession(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.session(w, r)
+	if !ok {
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Exam session fetched successfully", session)
+}
+
+// UpdateSession replaces a session's details: PUT /exams/sessions/{id}. Its
+// seats and invigilators no longer fit and are cleared, to be generated again.
+func (h *ExamHandler) UpdateSession(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.session(w, r)
+	if !ok {
+		return
+	}
+	req, ok := h.readSession(w, r)
+	if !ok {
+		return
+	}
+	session.Title, session.Subject, session.Date = req.Title, req.Subject, req.Date
+	session.StartTime, session.EndTime = req.StartTime, req.EndTime
+	session.Classes, session.Rooms = req.Classes, req.Rooms
+	session.Seating, session.Seats, session.Invigilators = nil, nil, nil
+	h.save(w, r, *session, "Exam session updated")
+}
+
+// DeleteSession removes a session: DELETE /exams/sessions/{id}
+func (h *ExamHandler) DeleteSession(w http.ResponseWriter, r *http.Request) {
+	id := utils.PathID(r, "id")
+	if err := h.Exams.Delete(r.Context(), id); err != nil {
+		if !errors.Is(err, models.ErrNotFound) {
+			logError(r, "Error deleting exam session %d: %v", id, err)
+		}
+		utils.ResponseError(w, err, fmt.Sprintf("Exam session with ID %d not found", id))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GenerateSeating seats every student of the session's classes:
+// POST /exams/sessions/{id}/seating with {"order": "random", "separate_classes": true}.
+// It replaces earlier seating; a random order records its seed, which can
+// be sent again to repeat the draw.
+func (h *ExamHandler) GenerateSeating(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.session(w, r)
+	if !ok {
+		return
+	}
+	var req models.ExamSeatingRequest
+	if err := decodeJSON(r, &req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if errs := models.ValidateOne(req); len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	if req.Order == "" {
+		req.Order = models.SeatingAlphabetical
+	}
+	if req.Order == models.SeatingRandom && req.Seed == 0 {
+		req.Seed = rand.Int64N(1<<53) + 1 // Stays exact in a JavaScript number
+	}
+
+	var students []models.Student
+	for _, class := range session.Classes {
+		in, err := h.Students.GetAll(r.Context(), query.Options{Where: models.ClassRoster(class)})
+		if err != nil {
+			logError(r, "Error fetching students of class %s: %v", class, err)
+			utils.ResponseError(w, err, "")
+			return
+		}
+		students = append(students, in...)
+	}
+	if len(students) == 0 {
+		utils.WriteError(w, http.StatusBadRequest, "There are no students in the session's classes")
+		return
+	}
+	if len(students) > exams.Capacity(session.Rooms) {
+		writeValidationErrors(w, r, []models.ValidationError{models.RuleError("Rooms", "exam_capacity", strconv.Itoa(len(students)))})
+		return
+	}
+
+	seats, conflicts := exams.Seat(students, session.Rooms, req)
+	session.Seats = seats
+	session.Seating = &models.ExamSeating{ExamSeatingRequest: req, Conflicts: conflicts, GeneratedAt: h.Clock.Now()}
+	h.save(w, r, *session, fmt.Sprintf("Seated %d students", len(seats)))
+}
+
+// AssignInvigilators picks each room's invigilators among the active
+// teachers free at the session's time: POST /exams/sessions/{id}/invigilators,
+// optionally with {"exclude": [12, 31]}. It replaces earlier assignments.
+func (h *ExamHandler) AssignInvigilators(w http.ResponseWriter, r *http.Request) {
+	session, ok := h.session(w, r)
+	if !ok {
+		return
+	}
+	var req models.ExamInvigilationRequest
+	if r.ContentLength != 0 {
+		if err := decodeJSON(r, &req); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+	}
+	if errs := models.ValidateOne(req); len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	teachers, err := h.Teachers.GetAll(r.Context(), query.Options{})
+	if err != nil {
+		logError(r, "Error fetching teachers: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	sameDay, err := h.Exams.List(r.Context(), session.Date)
+	if err != nil {
+		logError(r, "Error listing exam sessions of %s: %v", session.Date, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	invigilators, errs := exams.Invigilate(*session, teachers, sameDay, req.Exclude)
+	if len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	session.Invigilators = invigilators
+	h.save(w, r, *session, fmt.Sprintf("Assigned %d invigilators", len(invigilators)))
+}
+
+// GetAllocation prints the seating room by room, with each room's
+// invigilators: GET /exams/sessions/{id}/allocation?format=pdf|csv, pdf by default
+func (h *ExamHandler) GetAllocation(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "pdf"
+	}
+	if format != "pdf" && format != "csv" {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid format, expected pdf or csv")
+		return
+	}
+	session, ok := h.session(w, r)
+	if !ok {
+		return
+	}
+	if session.Seating == nil {
+		utils.WriteError(w, http.StatusConflict, "Generate the seating with POST /exams/sessions/{id}/seating first")
+		return
+	}
+
+	sheet := render.ExamSheet{
+		School:    h.School.Name(),
+		Title:     session.Title,
+		When:      fmt.Sprintf("%s, %s-%s", session.Date, session.StartTime, session.EndTime),
+		Rooms:     make([]render.ExamSheetRoom, len(session.Rooms)),
+		PrintedAt: h.Clock.Now(),
+	}
+	index := make(map[string]int, len(session.Rooms))
+	for i, room := range session.Rooms {
+		sheet.Rooms[i].Name = room.Name
+		index[room.Name] = i
+	}
+	for _, inv := range session.Invigilators {
+		room := &sheet.Rooms[index[inv.Room]]
+		room.Invigilators = append(room.Invigilators, inv.FirstName+" "+inv.LastName)
+	}
+	for _, seat := range session.Seats {
+		room := &sheet.Rooms[index[seat.Room]]
+		room.Seats = append(room.Seats, render.ExamSheetSeat{
+			Seat: seat.Seat, Row: seat.Row, Column: seat.Column,
+			Name: seat.LastName + ", " + seat.FirstName, Class: seat.Class, AdmissionNumber: seat.AdmissionNumber,
+		})
+	}
+	filename := fmt.Sprintf("exam-%d-seating", session.ID)
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".csv"))
+		render.ExamCSV(w, sheet)
+		return
+	}
+	pdf, err := render.ExamPDF(sheet)
+	if err != nil {
+		logError(r, "Error rendering the seating of exam session %d: %v", session.ID, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".pdf"))
+	w.Write(pdf)
+}
+
+// readSession decodes and checks the body of a session. It answers the
+// request itself and returns false when the body doesn't pass.
+func (h *ExamHandler) readSession(w http.ResponseWriter, r *http.Request) (models.ExamSessionRequest, bool) {
+	var req models.ExamSessionRequest
+	if err := decodeJSON(r, &req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return req, false
+	}
+	errs := models.ValidateOne(req)
+	if len(errs) == 0 {
+		errs = req.Check()
+	}
+	// ISO dates compare correctly as strings
+	if len(errs) == 0 && req.Date < clock.Today(h.Clock).Format(models.DateLayout) {
+		errs = []models.ValidationError{models.RuleError("Date", "date_in_past", "")}
+	}
+	if len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return req, false
+	}
+	return req, true
+}
+
+// session reads the session of the path's {id}, answering the request when there's none
+func (h *ExamHandler) session(w http.ResponseWriter, r *http.Request) (*models.ExamSession, bool) {
+	id := utils.PathID(r, "id")
+	session, err := h.Exams.GetByID(r.Context(), id)
+	if err != nil {
+		if !errors.Is(err, models.ErrNotFound) {
+			logError(r, "Error fetching exam session %d: %v", id, err)
+		}
+		utils.ResponseError(w, err, fmt.Sprintf("Exam session with ID %d not found", id))
+		return nil, false
+	}
+	return session, true
+}
+
+func (h *ExamHandler) save(w http.ResponseWriter, r *http.Request, session models.ExamSession, message string) {
+	saved, err := h.Exams.Save(r.Context(), session)
+	if err != nil {
+		if !errors.Is(err, models.ErrNotFound) {
+			logError(r, "Error saving exam session %d: %v", session.ID, err)
+		}
+		utils.ResponseError(w, err, fmt.Sprintf("Exam session with ID %d not found", session.ID))
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, message, saved)
+}
@@ -0,0 +1,274 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"simpleapi/internal/models"
+	"simpleapi/internal/policy"
+	"simpleapi/internal/repository"
+	"simpleapi/pkg/utils"
+)
+
+// GradingHandler manages grading schemes and grades the raw scores teachers post,
+// so report cards and transcripts all use the same boundaries
+type GradingHandler struct {
+	Schemes  repository.GradingStore
+	Scores   repository.ScoreStore
+	Students repository.StudentStore
+	Policy   *policy.Policy
+}
+
+// NewGradingHandler is the constructor
+func NewGradingHandler(schemes repository.GradingStore, scores repository.ScoreStore, students repository.StudentStore, p *policy.Policy) *GradingHandler {
+	return &GradingHandler{Schemes: schemes, Scores: scores, Students: students, Policy: p}
+}
+
+// decodeScheme reads and validates a scheme body, normalizing its boundaries
+func decodeScheme(w http.ResponseWriter, r *http.Request) (models.GradingScheme, bool) {
+	var scheme models.GradingScheme
+	if err := decodeJSON(r, &scheme); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return scheme, false
+	}
+	if errors := models.ValidateOne(scheme); len(errors) > 0 {
+		writeValidationErrors(w, r, errors)
+		return scheme, false
+	}
+	if errors := scheme.Normalize(); len(errors) > 0 {
+		writeValidationErrors(w, r, errors)
+		return scheme, false
+	}
+	return scheme, true
+}
+
+func (h *GradingHandler) GetSchemes(w http.ResponseWriter, r *http.Request) {
+	schemes, err := h.Schemes.List(r.Context())
+	if err != nil {
+		logError(r, "Error fetching grading schemes: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, "Grading schemes fetched successfully", schemes)
+}
+
+func (h *GradingHandler) CreateScheme(w http.ResponseWriter, r *http.Request) {
+	scheme, ok := decodeScheme(w, r)
+	if !ok {
+		return
+	}
+
+	created, err := h.Schemes.Create(r.Context(), scheme)
+	if err != nil {
+		logError(r, "Error creating grading scheme: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, "Grading scheme created successfully", created)
+}
+
+func (h *GradingHandler) UpdateScheme(w http.ResponseWriter, r *http.Request) {
+	id := utils.PathID(r, "id")
+
+	scheme, ok := decodeScheme(w, r)
+	if !ok {
+		return
+	}
+
+	updated, err := h.Schemes.Update(r.Context(), id, scheme)
+	if err != nil {
+		logError(r, "Error updating grading scheme %d: %v", id, err)
+		utils.ResponseError(w, err, fmt.Sprintf("Grading scheme with ID %d not found", id))
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, "Grading scheme updated successfully", updated)
+}
+
+func (h *GradingHandler) DeleteScheme(w http.ResponseWriter, r *http.Request) {
+	id := utils.PathID(r, "id")
+
+	deleted, err := h.Schemes.Delete(r.Context(), id)
+	if err != nil {
+		logError(r, "Error deleting grading scheme %d: %v", id, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	if !deleted {
+		utils.WriteError(w, http.StatusNotFound, fmt.Sprintf("Grading scheme with ID %d not found", id))
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PostScore records a raw score for the teacher's own subject and grades it with the
+// scheme for that subject (or the school default). A score is posted once per term:
+// changing it afterwards is a correction (POST /grades/{id}/corrections).
+func (h *GradingHandler) PostScore(w http.ResponseWriter, r *http.Request) {
+	studentID := utils.PathID(r, "id")
+
+	var score models.StudentScore
+	if err := decodeJSON(r, &score); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if errors := models.ValidateOne(score); len(errors) > 0 {
+		writeValidationErrors(w, r, errors)
+		return
+	}
+
+	student, err := h.Students.GetByID(r.Context(), studentID)
+	if err != nil {
+		logError(r, "Error fetching student %d: %v", studentID, err)
+		utils.ResponseError(w, err, fmt.Sprintf("Student with ID %d not found", studentID))
+		return
+	}
+	if !authorizeClass(w, r, h.Policy, student.Class) {
+		return
+	}
+
+	teacher := currentUser(r)
+	scheme, err := h.Schemes.ForSubject(r.Context(), teacher.Subject)
+	if errors.Is(err, models.ErrNotFound) {
+		utils.WriteError(w, http.StatusConflict, fmt.Sprintf("No grading scheme is configured for %s", teacher.Subject))
+		return
+	}
+	if err != nil {
+		logError(r, "Error fetching grading scheme for %s: %v", teacher.Subject, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+
+	// Client-supplied grade/subject are ignored: the server is the single source of grading
+	score.StudentID = studentID
+	score.TeacherID = teacher.ID
+	score.Subject = teacher.Subject
+	score.Grade = scheme.GradeFor(score.Score)
+	score.SchemeID = scheme.ID
+
+	saved, err := h.Scores.Create(r.Context(), score)
+	if err != nil {
+		logError(r, "Error saving score for student %d: %v", studentID, err)
+		utils.ResponseError(w, err, fmt.Sprintf("A %s score for %s is already recorded; submit a correction instead", score.Subject, score.Term))
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, "Score recorded successfully", saved)
+}
+
+// GetScores lists a student's graded scores; ?term= narrows it down to a single term
+func (h *GradingHandler) GetScores(w http.ResponseWriter, r *http.Request) {
+	studentID := utils.PathID(r, "id")
+
+	scores, err := h.Scores.ListByStudent(r.Context(), studentID, r.URL.Query().Get("term"))
+	if err != nil {
+		logError(r, "Error fetching scores for student %d: %v", studentID, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, "Scores fetched successfully", scores)
+}
+
+// GetClassStats shows how a class did in a subject and term:
+// GET /classes/{class}/grade-stats?term=2025/26-T1&subject=Mathematics. It
+// gives the min, max, mean, median and standard deviation of the scores, how
+// many got each grade, and every student's rank. The subject defaults to the
+// teacher's own; only the class's teachers and admins may look.
+func (h *GradingHandler) GetClassStats(w http.ResponseWriter, r *http.Request) {
+	class := r.PathValue("class")
+	if class == "" || len(class) > 50 {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid class")
+		return
+	}
+	var q models.ClassGradeStatsQuery
+	if errs := utils.BindQuery(r, &q); len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	if q.Subject == "" {
+		q.Subject = currentUser(r).Subject
+	}
+	if !authorizeClass(w, r, h.Policy, class) {
+		return
+	}
+
+	stats, err := h.Scores.ClassStats(r.Context(), class, q.Subject, q.Term)
+	if err != nil {
+		logError(r, "Error computing %s grade stats of class %s: %v", q.Subject, class, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Grade statistics fetched successfully", stats)
+}
+
+// CorrectScore appends a correction to a posted score. The score is regraded with
+// its subject's current scheme, and the admin making the request is the approver.
+func (h *GradingHandler) CorrectScore(w http.ResponseWriter, r *http.Request) {
+	scoreID := utils.PathID(r, "id")
+
+	var correction models.GradeCorrection
+	if err := decodeJSON(r, &correction); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if errors := models.ValidateOne(correction); len(errors) > 0 {
+		writeValidationErrors(w, r, errors)
+		return
+	}
+
+	score, err := h.Scores.GetByID(r.Context(), scoreID)
+	if err != nil {
+		logError(r, "Error fetching score %d: %v", scoreID, err)
+		utils.ResponseError(w, err, fmt.Sprintf("Grade with ID %d not found", scoreID))
+		return
+	}
+
+	scheme, err := h.Schemes.ForSubject(r.Context(), score.Subject)
+	if errors.Is(err, models.ErrNotFound) {
+		utils.WriteError(w, http.StatusConflict, fmt.Sprintf("No grading scheme is configured for %s", score.Subject))
+		return
+	}
+	if err != nil {
+		logError(r, "Error fetching grading scheme for %s: %v", score.Subject, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+
+	correction.ScoreID = scoreID
+	correction.Grade = scheme.GradeFor(correction.Score)
+	correction.SchemeID = scheme.ID
+	correction.ApprovedBy = currentUser(r).ID
+
+	saved, err := h.Scores.AddCorrection(r.Context(), correction)
+	if err != nil {
+		logError(r, "Error correcting score %d: %v", scoreID, err)
+		utils.ResponseError(w, err, fmt.Sprintf("Grade with ID %d not found", scoreID))
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, "Grade corrected successfully", saved)
+}
+
+// GetCorrections lists the corrections of a score, oldest first
+func (h *GradingHandler) GetCorrections(w http.ResponseWriter, r *http.Request) {
+	scoreID := utils.PathID(r, "id")
+
+	if _, err := h.Scores.GetByID(r.Context(), scoreID); err != nil {
+		logError(r, "Error fetching score %d: %v", scoreID, err)
+		utils.ResponseError(w, err, fmt.Sprintf("Grade with ID %d not found", scoreID))
+		return
+	}
+
+	corrections, err := h.Scores.ListCorrections(r.Context(), scoreID)
+	if err != nil {
+		logError(r, "Error fetching corrections of score %d: %v", scoreID, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, "Corrections fetched successfully", corrections)
+}
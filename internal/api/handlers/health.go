@@ -0,0 +1,36 @@
+package handlers
+
+import (
+	"net/http"
+	"simpleapi/internal/buildinfo"
+	"simpleapi/internal/metrics"
+	"simpleapi/pkg/errcodes"
+	"simpleapi/pkg/utils"
+)
+
+// HealthHandler tells load balancers whether to send this instance traffic
+type HealthHandler struct {
+	Pool *metrics.PoolGauges
+}
+
+// NewHealthHandler is the constructor
+func NewHealthHandler(pool *metrics.PoolGauges) *HealthHandler {
+	return &HealthHandler{Pool: pool}
+}
+
+// GetReady answers 200, or 503 while the database pool is saturated (see
+// jobs.PoolMonitor), so the balancer can favour less loaded instances:
+// GET /readyz
+func (h *HealthHandler) GetReady(w http.ResponseWriter, r *http.Request) {
+	if h.Pool.Saturated() {
+		utils.WriteErrorCode(w, http.StatusServiceUnavailable, errcodes.Unavailable, "Degraded: queries are waiting for database connections")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Ready", nil)
+}
+
+// GetVersion tells which build answers, for deploy checks and schoolctl's
+// compatibility check: GET /version
+func (h *HealthHandler) GetVersion(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSON(w, http.StatusOK, "Version fetched successfully", buildinfo.Get())
+}
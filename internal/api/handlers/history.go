@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"simpleapi/pkg/utils"
+)
+
+// HistoryHandler serves per-field change timelines rebuilt from the audit log
+type HistoryHandler struct {
+	Audit    repository.AuditStore
+	Teachers repository.TeacherStore
+	Students repository.StudentStore
+}
+
+// NewHistoryHandler is the constructor
+func NewHistoryHandler(audit repository.AuditStore, teachers repository.TeacherStore, students repository.StudentStore) *HistoryHandler {
+	return &HistoryHandler{Audit: audit, Teachers: teachers, Students: students}
+}
+
+func (h *HistoryHandler) GetTeacherHistory(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r, "teacher", "Teacher", func(ctx context.Context, id int) error {
+		_, err := h.Teachers.GetByID(ctx, id)
+		return err
+	})
+}
+
+func (h *HistoryHandler) GetStudentHistory(w http.ResponseWriter, r *http.Request) {
+	h.serve(w, r, "student", "Student", func(ctx context.Context, id int) error {
+		_, err := h.Students.GetByID(ctx, id)
+		return err
+	})
+}
+
+// serve writes the timeline for one entity. exists is only consulted when the audit
+// log is empty, so trashed (or purged) records keep their history.
+func (h *HistoryHandler) serve(w http.ResponseWriter, r *http.Request, entity, label string, exists func(context.Context, int) error) {
+	id := utils.PathID(r, "id")
+
+	entries, err := h.Audit.ListByEntity(r.Context(), entity, id)
+	if err != nil {
+		logError(r, "Error fetching %s %d history: %v", entity, id, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	if len(entries) == 0 {
+		if err := exists(r.Context(), id); err != nil {
+			utils.ResponseError(w, err, fmt.Sprintf("%s with ID %d not found", label, id))
+			return
+		}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, label+" history fetched successfully", models.HistoryFromAudit(entries))
+}
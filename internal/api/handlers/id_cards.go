@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"simpleapi/internal/locale"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"simpleapi/internal/schoolprofile"
+	"simpleapi/pkg/clock"
+	"simpleapi/pkg/utils"
+	"strings"
+	"time"
+)
+
+// IDCardHandler issues the data printed on student ID cards, with a QR code
+// signed like transcripts (TRANSCRIPT_SIGNING_KEY), and verifies scanned ones
+type IDCardHandler struct {
+	Students repository.StudentStore
+	Signer   utils.DocumentSigning
+	Clock    clock.Clock
+	School   *schoolprofile.Profile
+}
+
+// NewIDCardHandler is the constructor
+func NewIDCardHandler(students repository.StudentStore, signer utils.DocumentSigning, clk clock.Clock, school *schoolprofile.Profile) *IDCardHandler {
+	return &IDCardHandler{Students: students, Signer: signer, Clock: clk, School: school}
+}
+
+// GetIDCard returns a student's card: GET /students/{id}/idcard. Every call
+// signs a fresh QR payload valid for models.IDCardValidity.
+func (h *IDCardHandler) GetIDCard(w http.ResponseWriter, r *http.Request) {
+	id := utils.PathID(r, "id")
+
+	student, err := h.Students.GetByID(r.Context(), id)
+	if err != nil {
+		logError(r, "Error fetching student %d: %v", id, err)
+		utils.ResponseError(w, err, fmt.Sprintf("Student with ID %d not found", id))
+		return
+	}
+
+	now := locale.In(r.Context(), h.Clock, h.Clock.Now())
+	expires := now.Add(models.IDCardValidity).Truncate(time.Second)
+	claims, err := json.Marshal(models.IDCardClaims{Type: models.IDCardPayloadType, StudentID: id, ExpiresAt: expires.Unix()})
+	if err != nil {
+		logError(r, "Error encoding ID card of student %d: %v", id, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	qr, keyID, err := utils.SignToken(h.Signer, base64.RawURLEncoding.EncodeToString(claims))
+	if errors.Is(err, utils.ErrNoSigningKey) {
+		utils.WriteError(w, http.StatusServiceUnavailable, "ID card signing is not configured")
+		return
+	}
+	if err != nil {
+		logError(r, "Error signing ID card of student %d: %v", id, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, "ID card generated successfully", models.IDCard{
+		IDCardHolder: models.NewIDCardHolder(*student),
+		SchoolName:   h.School.Name(),
+		IssuedAt:     now,
+		ExpiresAt:    expires,
+		QRPayload:    qr,
+		KeyID:        keyID,
+	})
+}
+
+// VerifyIDCard checks a scanned QR payload: GET /verify-card?payload=. Public,
+// for gate scanners. A card that doesn't pass is still a 200, with valid=false
+// and the reason.
+func (h *IDCardHandler) VerifyIDCard(w http.ResponseWriter, r *http.Request) {
+	raw := r.URL.Query().Get("payload")
+	if raw == "" {
+		utils.WriteError(w, http.StatusBadRequest, "payload is required")
+		return
+	}
+
+	result, err := h.verify(r, raw)
+	if err != nil {
+		logError(r, "Error verifying ID card: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "ID card verified", result)
+}
+
+func (h *IDCardHandler) verify(r *http.Request, raw string) (models.IDCardVerification, error) {
+	claims, reason := readIDCard(h.Signer, raw)
+	if reason != "" {
+		return models.IDCardVerification{Reason: reason}, nil
+	}
+
+	expires := locale.In(r.Context(), h.Clock, time.Unix(claims.ExpiresAt, 0))
+	result := models.IDCardVerification{StudentID: claims.StudentID, ExpiresAt: &expires}
+	if !h.Clock.Now().Before(expires) {
+		result.Reason = models.IDCardExpired
+		return result, nil
+	}
+
+	student, err := h.Students.GetByID(r.Context(), claims.StudentID)
+	if errors.Is(err, models.ErrNotFound) {
+		result.Reason = models.IDCardUnknownStudent
+		return result, nil
+	}
+	if err != nil {
+		return result, fmt.Errorf("fetching student %d: %w", claims.StudentID, err)
+	}
+
+	holder := models.NewIDCardHolder(*student)
+	result.Valid = true
+	result.Student = &holder
+	return result, nil
+}
+
+// readIDCard decodes a QR payload and checks its signature. reason is set
+// (models.IDCardMalformed or IDCardBadSignature) unless it's a card we issued.
+func readIDCard(signer utils.DocumentSigning, raw string) (claims models.IDCardClaims, reason string) {
+	payload, _, _ := strings.Cut(raw, ".")
+	decoded, err := base64.RawURLEncoding.DecodeString(payload)
+	if !strings.Contains(raw, ".") || err != nil || json.Unmarshal(decoded, &claims) != nil || claims.Type != models.IDCardPayloadType {
+		return models.IDCardClaims{}, models.IDCardMalformed
+	}
+	if _, ok := utils.VerifyToken(signer, raw); !ok {
+		return models.IDCardClaims{}, models.IDCardBadSignature
+	}
+	return claims, ""
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"simpleapi/internal/models"
+	"simpleapi/pkg/utils"
+	"testing"
+)
+
+func TestReadIDCardAfterRotation(t *testing.T) {
+	signer := utils.NewDocumentSigner([]byte("before"))
+	claims, _ := json.Marshal(models.IDCardClaims{Type: models.IDCardPayloadType, StudentID: 7, ExpiresAt: 1})
+	qr, _, err := utils.SignToken(signer, base64.RawURLEncoding.EncodeToString(claims))
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer.Rotate([]byte("after"))
+
+	if got, reason := readIDCard(signer, qr); reason != "" || got.StudentID != 7 {
+		t.Errorf("card printed before the rotation: claims %+v, reason %q", got, reason)
+	}
+	if _, reason := readIDCard(utils.NewDocumentSigner([]byte("other")), qr); reason != models.IDCardBadSignature {
+		t.Errorf("card from another key: reason %q, want %q", reason, models.IDCardBadSignature)
+	}
+	if _, reason := readIDCard(signer, "ADM-0042"); reason != models.IDCardMalformed {
+		t.Errorf("admission number: reason %q, want %q", reason, models.IDCardMalformed)
+	}
+}
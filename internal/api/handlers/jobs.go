@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"simpleapi/internal/jobs"
+	"simpleapi/internal/models"
+	"simpleapi/pkg/utils"
+	"slices"
+)
+
+// JobHandler lets admins see what the job queue is doing and run failed jobs
+// again. The queue lives in the process, so this is the answering instance's.
+type JobHandler struct {
+	Queue *jobs.Queue
+}
+
+// NewJobHandler is the constructor
+func NewJobHandler(queue *jobs.Queue) *JobHandler {
+	return &JobHandler{Queue: queue}
+}
+
+var jobStatuses = []string{models.JobPending, models.JobRunning, models.JobDone, models.JobFailed, models.JobDead}
+
+// ListJobs lists the jobs the queue remembers newest first, with the error of
+// each failed attempt: GET /admin/jobs?status=failed
+func (h *JobHandler) ListJobs(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status != "" && !slices.Contains(jobStatuses, status) {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Sprintf("Invalid status %q, expected one of %v", status, jobStatuses))
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Jobs fetched successfully", h.Queue.List(status))
+}
+
+// RetryJob queues a failed or dead-lettered job again: POST /admin/jobs/{id}/retry
+func (h *JobHandler) RetryJob(w http.ResponseWriter, r *http.Request) {
+	rec, err := h.Queue.Retry(utils.PathID(r, "id"))
+	switch {
+	case errors.Is(err, jobs.ErrQueueFull):
+		utils.WriteError(w, http.StatusServiceUnavailable, "Too many background jobs, try again later")
+		return
+	case errors.Is(err, models.ErrConflict):
+		utils.WriteError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		utils.ResponseError(w, err, "Job not found")
+		return
+	}
+	utils.WriteJSON(w, http.StatusAccepted, "Job queued again", rec)
+}
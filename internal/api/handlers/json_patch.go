@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"mime"
+	"net/http"
+	"reflect"
+	"simpleapi/internal/models"
+	"simpleapi/pkg/errcodes"
+	"simpleapi/pkg/jsonpatch"
+	"simpleapi/pkg/utils"
+	"slices"
+	"strings"
+)
+
+// maxPatchOps is the most operations one JSON Patch may have
+const maxPatchOps = 100
+
+// acceptPatch lists the bodies PATCH takes, for the Accept-Patch header (RFC 5789)
+const acceptPatch = "application/json, " + jsonpatch.MediaType
+
+// teacherPatchRules are the teacher fields a JSON Patch may touch: those of
+// the map format. Names and email can't be removed; a new email is refused
+// like in the map format (see EmailChangeHandler).
+var teacherPatchRules = jsonpatch.Rules{
+	"/first_name": {jsonpatch.OpReplace, jsonpatch.OpTest},
+	"/last_name":  {jsonpatch.OpReplace, jsonpatch.OpTest},
+	"/email":      {jsonpatch.OpReplace, jsonpatch.OpTest},
+	"/phone":      {jsonpatch.OpAdd, jsonpatch.OpReplace, jsonpatch.OpRemove, jsonpatch.OpTest},
+	"/class":      {jsonpatch.OpAdd, jsonpatch.OpReplace, jsonpatch.OpRemove, jsonpatch.OpTest},
+	"/subject":    {jsonpatch.OpAdd, jsonpatch.OpReplace, jsonpatch.OpRemove, jsonpatch.OpTest},
+}
+
+// studentPatchRules: like StudentPatch, only custom fields. They are what a
+// patch can express; which of them a caller may use is policy.StudentPatchRules.
+var studentPatchRules = jsonpatch.Rules{
+	"/custom_fields/*": {jsonpatch.OpAdd, jsonpatch.OpReplace, jsonpatch.OpRemove, jsonpatch.OpTest},
+}
+
+// isJSONPatch reports whether a PATCH body is a JSON Patch rather than our map format
+func isJSONPatch(r *http.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType == jsonpatch.MediaType
+}
+
+// readJSONPatch decodes the body as a JSON Patch, answering when it isn't one
+func readJSONPatch(w http.ResponseWriter, r *http.Request) (jsonpatch.Patch, bool) {
+	p, err := jsonpatch.Decode(r.Body, maxPatchOps)
+	if err != nil {
+		writePatchError(w, r, err)
+		return nil, false
+	}
+	return p, true
+}
+
+// writePatchError answers a patch that couldn't be applied: 409 when a test
+// failed (the client should read the record again), otherwise 400
+func writePatchError(w http.ResponseWriter, r *http.Request, err error) {
+	logError(r, "Error applying JSON Patch: %v", err)
+	w.Header().Set("Accept-Patch", acceptPatch)
+	switch {
+	case errors.Is(err, jsonpatch.ErrTestFailed):
+		utils.WriteErrorCode(w, http.StatusConflict, errcodes.PatchTestFailed, err.Error())
+	case errors.Is(err, jsonpatch.ErrInvalid):
+		utils.WriteErrorCode(w, http.StatusBadRequest, errcodes.PatchInvalid, err.Error())
+	default:
+		utils.ResponseError(w, err, "")
+	}
+}
+
+// teacherPatchUpdates applies p to t's patchable fields and returns those it
+// changed in the map format of PATCH /teachers/{id}; a removed field is emptied
+func teacherPatchUpdates(t models.Teacher, p jsonpatch.Patch) (map[string]any, error) {
+	before := map[string]any{
+		"first_name": t.FirstName, "last_name": t.LastName, "email": t.Email,
+		"phone": t.Phone, "class": t.Class, "subject": t.Subject,
+	}
+	doc := maps.Clone(before)
+	if err := p.Apply(doc, teacherPatchRules); err != nil {
+		return nil, err
+	}
+	updates := make(map[string]any)
+	for field, old := range before {
+		value, ok := doc[field]
+		if !ok {
+			value = ""
+		}
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s must be a string", jsonpatch.ErrInvalid, field)
+		}
+		if s != old {
+			updates[field] = s
+		}
+	}
+	return updates, nil
+}
+
+// studentPatchFromJSONPatch applies p to the student's custom fields and
+// returns the changes as a StudentPatch; a removed field is null
+func studentPatchFromJSONPatch(s models.Student, p jsonpatch.Patch) (models.StudentPatch, error) {
+	before := maps.Clone(s.CustomFields)
+	if before == nil {
+		before = make(map[string]any)
+	}
+	fields := maps.Clone(before)
+	if err := p.Apply(map[string]any{"custom_fields": fields}, studentPatchRules); err != nil {
+		return models.StudentPatch{}, err
+	}
+	patch := models.StudentPatch{CustomFields: make(map[string]any)}
+	for key, value := range fields {
+		if old, ok := before[key]; !ok || !reflect.DeepEqual(old, value) {
+			patch.CustomFields[key] = value
+		}
+	}
+	for key := range before {
+		if _, ok := fields[key]; !ok {
+			patch.CustomFields[key] = nil
+		}
+	}
+	return patch, nil
+}
+
+// customFieldOps expresses a patch in the map format as the JSON Patch
+// operations it amounts to, so one set of rules checks both formats
+func customFieldOps(patch models.StudentPatch) jsonpatch.Patch {
+	var ops jsonpatch.Patch
+	for _, key := range slices.Sorted(maps.Keys(patch.CustomFields)) {
+		op := jsonpatch.OpReplace
+		if patch.CustomFields[key] == nil {
+			op = jsonpatch.OpRemove
+		}
+		// Escaped like a JSON Pointer token (RFC 6901)
+		token := strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+		ops = append(ops, jsonpatch.Operation{Op: op, Path: "/custom_fields/" + token})
+	}
+	return ops
+}
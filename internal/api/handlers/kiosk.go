@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"simpleapi/pkg/clock"
+	"simpleapi/pkg/utils"
+	"time"
+)
+
+// KioskHandler is the API of the front-desk tablets (see middlewares.KioskAuth):
+// students arriving late or leaving early check in by scanning their ID card
+type KioskHandler struct {
+	Attendance repository.AttendanceStore
+	Students   repository.StudentStore
+	Signer     utils.DocumentSigning // Checks scanned ID cards
+	Clock      clock.Clock
+}
+
+// NewKioskHandler is the constructor
+func NewKioskHandler(attendance repository.AttendanceStore, students repository.StudentStore, signer utils.DocumentSigning, clk clock.Clock) *KioskHandler {
+	return &KioskHandler{Attendance: attendance, Students: students, Signer: signer, Clock: clk}
+}
+
+// Checkin records a late arrival (direction "in") or an early departure
+// ("out"): POST /kiosk/checkin. The kiosk's key must allow the operation.
+func (h *KioskHandler) Checkin(w http.ResponseWriter, r *http.Request) {
+	var req models.KioskCheckinRequest
+	if err := decodeJSON(r, &req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if errors := models.ValidateOne(req); len(errors) > 0 {
+		writeValidationErrors(w, r, errors)
+		return
+	}
+
+	op, kind := models.KioskCheckin, models.MovementLateArrival
+	if req.Direction == "out" {
+		op, kind = models.KioskCheckout, models.MovementEarlyDeparture
+	}
+	kiosk := currentKiosk(r)
+	if !kiosk.May(op) {
+		utils.WriteError(w, http.StatusForbidden, fmt.Sprintf("This kiosk is not allowed to %s", op))
+		return
+	}
+
+	now := h.Clock.Now()
+	student, ok := h.scannedStudent(w, r, req.Scan, now)
+	if !ok {
+		return
+	}
+
+	movement, err := h.Attendance.RecordMovement(r.Context(), models.AttendanceMovement{
+		StudentID: student.ID,
+		Class:     student.Class,
+		Date:      now.Format(models.DateLayout),
+		Kind:      kind,
+		Reason:    req.Reason,
+		Kiosk:     kiosk.Name,
+		At:        now,
+	})
+	if errors.Is(err, models.ErrConflict) {
+		utils.WriteError(w, http.StatusConflict, fmt.Sprintf("%s's %s is already recorded today", student.FirstName, kindLabel(kind)))
+		return
+	}
+	if err != nil {
+		logError(r, "Error recording %s of student %d at kiosk %s: %v", kind, student.ID, kiosk.Name, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, fmt.Sprintf("Recorded the %s of %s %s", kindLabel(kind), student.FirstName, student.LastName), movement)
+}
+
+// ListMovements returns a day's kiosk check-ins for staff: GET /attendance/movements?date=
+// (default today)
+func (h *KioskHandler) ListMovements(w http.ResponseWriter, r *http.Request) {
+	date := r.URL.Query().Get("date")
+	if date == "" {
+		date = h.Clock.Now().Format(models.DateLayout)
+	} else if _, err := time.Parse(models.DateLayout, date); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid date, expected YYYY-MM-DD")
+		return
+	}
+	movements, err := h.Attendance.ListMovements(r.Context(), date)
+	if err != nil {
+		logError(r, "Error listing movements on %s: %v", date, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Movements fetched successfully", movements)
+}
+
+// scannedStudent resolves what the tablet read: a signed ID card, or failing
+// that an admission number. A card that is forged or expired is refused
+// rather than read as an admission number.
+func (h *KioskHandler) scannedStudent(w http.ResponseWriter, r *http.Request, scan string, now time.Time) (*models.Student, bool) {
+	claims, reason := readIDCard(h.Signer, scan)
+	var student *models.Student
+	var err error
+	switch reason {
+	case "":
+		if now.Unix() >= claims.ExpiresAt {
+			utils.WriteError(w, http.StatusBadRequest, "This ID card has expired")
+			return nil, false
+		}
+		student, err = h.Students.GetByID(r.Context(), claims.StudentID)
+	case models.IDCardBadSignature:
+		utils.WriteError(w, http.StatusBadRequest, "This ID card is not valid")
+		return nil, false
+	default:
+		student, err = h.Students.FindByKey(r.Context(), scan)
+	}
+	if errors.Is(err, models.ErrNotFound) {
+		utils.WriteError(w, http.StatusNotFound, "No student matches this card or admission number")
+		return nil, false
+	}
+	if err != nil {
+		logError(r, "Error finding scanned student: %v", err)
+		utils.ResponseError(w, err, "")
+		return nil, false
+	}
+	return student, true
+}
+
+func kindLabel(kind string) string {
+	if kind == models.MovementEarlyDeparture {
+		return "early departure"
+	}
+	return "late arrival"
+}
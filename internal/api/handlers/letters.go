@@ -0,0 +1,325 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"simpleapi/internal/jobs"
+	"simpleapi/internal/letters"
+	"simpleapi/internal/mail"
+	"simpleapi/internal/models"
+	"simpleapi/internal/render"
+	"simpleapi/internal/repository"
+	"simpleapi/internal/schoolprofile"
+	"simpleapi/internal/storage"
+	"simpleapi/pkg/clock"
+	"simpleapi/pkg/utils"
+	"strconv"
+)
+
+// LetterHandler serves letter templates and the letters printed from them.
+// Admins write templates with merge fields (package letters); the office
+// prints one for a student as PDF or HTML, which is kept with the student's
+// documents and can be emailed as well.
+type LetterHandler struct {
+	Templates repository.LetterTemplateStore
+	Documents repository.DocumentStore
+	Students  repository.StudentStore
+	Storage   storage.Storage
+	Mails     *jobs.LetterMails
+	Queue     *jobs.Queue
+	Clock     clock.Clock
+	School    *schoolprofile.Profile
+}
+
+// NewLetterHandler is the constructor
+func NewLetterHandler(templates repository.LetterTemplateStore, documents repository.DocumentStore, students repository.StudentStore, store storage.Storage, mails *jobs.LetterMails, queue *jobs.Queue, clk clock.Clock, school *schoolprofile.Profile) *LetterHandler {
+	return &LetterHandler{Templates: templates, Documents: documents, Students: students, Storage: store, Mails: mails, Queue: queue, Clock: clk, School: school}
+}
+
+// documentTypes are the content types of the rendered formats
+var documentTypes = map[string]string{
+	models.DocumentPDF:  "application/pdf",
+	models.DocumentHTML: "text/html; charset=utf-8",
+}
+
+// CreateTemplate adds a template: POST /admin/letter-templates
+func (h *LetterHandler) CreateTemplate(w http.ResponseWriter, r *http.Request) {
+	req, ok := readLetterTemplate(w, r)
+	if !ok {
+		return
+	}
+	t, err := h.Templates.Create(r.Context(), models.LetterTemplate{
+		Name:      req.Name,
+		Kind:      req.Kind,
+		Subject:   req.Subject,
+		Body:      req.Body,
+		CreatedBy: currentUserID(r),
+	})
+	if err != nil {
+		logError(r, "Error creating letter template: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/admin/letter-templates/%d", t.ID))
+	utils.WriteJSON(w, http.StatusCreated, "Letter template created", t)
+}
+
+// GetTemplates lists the templates by name: GET /admin/letter-templates, or
+// ?kind=fee_reminder for one kind
+func (h *LetterHandler) GetTemplates(w http.ResponseWriter, r *http.Request) {
+	kind := r.URL.Query().Get("kind")
+	if errs := models.ValidateOne(struct {
+		Kind string `validate:"omitempty,oneof=admission fee_reminder disciplinary other"`
+	}{kind}); len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	templates, err := h.Templates.List(r.Context(), kind)
+	if err != nil {
+		logError(r, "Error listing letter templates: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Letter templates fetched successfully", templates)
+}
+
+// GetTemplate returns a template: GET /admin/letter-templates/{id}
+func (h *LetterHandler) GetTemplate(w http.ResponseWriter, r *http.Request) {
+	t, ok := h.template(w, r, utils.PathID(r, "id"))
+	if !ok {
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Letter template fetched successfully", t)
+}
+
+// UpdateTemplate replaces a template: PUT /admin/letter-templates/{id}.
+// Letters printed from it before keep their text.
+func (h *LetterHandler) UpdateTemplate(w http.ResponseWriter, r *http.Request) {
+	id := utils.PathID(r, "id")
+	req, ok := readLetterTemplate(w, r)
+	if !ok {
+		return
+	}
+	t, err := h.Templates.Update(r.Context(), id, req, currentUserID(r))
+	if err != nil {
+		if !errors.Is(err, models.ErrNotFound) {
+			logError(r, "Error updating letter template %d: %v", id, err)
+		}
+		utils.ResponseError(w, err, fmt.Sprintf("Letter template with ID %d not found", id))
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Letter template updated", t)
+}
+
+// DeleteTemplate removes a template: DELETE /admin/letter-templates/{id}.
+// The documents printed from it stay.
+func (h *LetterHandler) DeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	id := utils.PathID(r, "id")
+	if err := h.Templates.Delete(r.Context(), id, currentUserID(r)); err != nil {
+		if !errors.Is(err, models.ErrNotFound) {
+			logError(r, "Error deleting letter template %d: %v", id, err)
+		}
+		utils.ResponseError(w, err, fmt.Sprintf("Letter template with ID %d not found", id))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// CreateLetter prints a template for a student: POST /students/{id}/letters
+// with {"template_id": 3, "format": "pdf", "email": true}. The letter is
+// stored as one of the student's documents; with email it is also sent to
+// the student's address, from the queue.
+func (h *LetterHandler) CreateLetter(w http.ResponseWriter, r *http.Request) {
+	studentID := utils.PathID(r, "id")
+
+	var req models.LetterRequest
+	if err := decodeJSON(r, &req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if errs := models.ValidateOne(req); len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	if req.Format == "" {
+		req.Format = models.DocumentPDF
+	}
+
+	student, err := h.Students.GetByID(r.Context(), studentID)
+	if err != nil {
+		if !errors.Is(err, models.ErrNotFound) {
+			logError(r, "Error fetching student %d: %v", studentID, err)
+		}
+		utils.ResponseError(w, err, fmt.Sprintf("Student with ID %d not found", studentID))
+		return
+	}
+	if req.Email && student.Email == "" {
+		writeValidationErrors(w, r, []models.ValidationError{models.RuleError("Email", "letter_email", "")})
+		return
+	}
+	t, ok := h.template(w, r, req.TemplateID)
+	if !ok {
+		return
+	}
+
+	school := h.School.Get()
+	date := clock.Today(h.Clock).Format(models.DateLayout)
+	subject, body, err := letters.Merge(*t, models.StudentLetterFields(*student, school, date))
+	if err != nil {
+		// Templates are checked when saved, so this is one saved before a merge field was renamed
+		writeValidationErrors(w, r, []models.ValidationError{models.RuleError("TemplateID", "template", err.Error())})
+		return
+	}
+	letter := render.Letter{School: school.Name, Address: school.Address, Date: date, Subject: subject, Body: body}
+	var content []byte
+	if req.Format == models.DocumentPDF {
+		content, err = render.LetterPDF(letter)
+	} else {
+		var buf bytes.Buffer
+		err = render.LetterHTML(&buf, letter)
+		content = buf.Bytes()
+	}
+	if err != nil {
+		logError(r, "Error rendering letter template %d for student %d: %v", t.ID, studentID, err)
+		utils.WriteError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+
+	token := make([]byte, 16)
+	rand.Read(token)
+	doc := models.Document{
+		StudentID:   studentID,
+		TemplateID:  &t.ID,
+		Title:       subject,
+		Format:      req.Format,
+		ContentType: documentTypes[req.Format],
+		Size:        int64(len(content)),
+		StorageKey:  fmt.Sprintf("documents/students/%d/%s", studentID, hex.EncodeToString(token)),
+		CreatedBy:   currentUserID(r),
+	}
+	if err := h.Storage.Put(r.Context(), doc.StorageKey, bytes.NewReader(content)); err != nil {
+		log.Printf("Error storing letter for student %d: %v", studentID, err)
+		utils.WriteError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	created, err := h.Documents.Create(r.Context(), doc)
+	if err != nil {
+		h.Storage.Delete(r.Context(), doc.StorageKey)
+		if !errors.Is(err, models.ErrNotFound) {
+			logError(r, "Error recording letter for student %d: %v", studentID, err)
+		}
+		utils.ResponseError(w, err, fmt.Sprintf("Student with ID %d not found", studentID))
+		return
+	}
+
+	msg := "Letter created"
+	if req.Email {
+		mailed := mail.Message{To: student.Email, Subject: subject, Body: body}
+		if err := h.Queue.Enqueue(h.Mails.Job(*created, mailed)); err != nil {
+			log.Printf("Error queueing email of document %d: %v", created.ID, err)
+			msg = "Letter created, but it could not be emailed"
+		}
+	}
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/documents/%d", created.ID))
+	utils.WriteJSON(w, http.StatusCreated, msg, created)
+}
+
+// GetStudentDocuments lists a student's documents, newest first: GET /students/{id}/documents
+func (h *LetterHandler) GetStudentDocuments(w http.ResponseWriter, r *http.Request) {
+	id := utils.PathID(r, "id")
+	if _, err := h.Students.GetByID(r.Context(), id); err != nil {
+		if !errors.Is(err, models.ErrNotFound) {
+			logError(r, "Error fetching student %d: %v", id, err)
+		}
+		utils.ResponseError(w, err, fmt.Sprintf("Student with ID %d not found", id))
+		return
+	}
+	documents, err := h.Documents.ListByStudent(r.Context(), id)
+	if err != nil {
+		logError(r, "Error listing documents of student %d: %v", id, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Documents fetched successfully", documents)
+}
+
+// GetDocument returns a document's details: GET /documents/{id}
+func (h *LetterHandler) GetDocument(w http.ResponseWriter, r *http.Request) {
+	doc, ok := h.document(w, r)
+	if !ok {
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Document fetched successfully", doc)
+}
+
+// DownloadDocument streams a document's file: GET /documents/{id}/file
+func (h *LetterHandler) DownloadDocument(w http.ResponseWriter, r *http.Request) {
+	doc, ok := h.document(w, r)
+	if !ok {
+		return
+	}
+
+	rc, err := h.Storage.Get(r.Context(), doc.StorageKey)
+	if err != nil {
+		log.Printf("Error reading document %d: %v", doc.ID, err)
+		utils.WriteError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	defer rc.Close()
+
+	name := fmt.Sprintf("student-%d-document-%d.%s", doc.StudentID, doc.ID, doc.Format)
+	w.Header().Set("Content-Type", doc.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	w.Header().Set("Content-Length", strconv.FormatInt(doc.Size, 10))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	io.Copy(w, rc)
+}
+
+// readLetterTemplate decodes and checks a template, its merge fields included
+func readLetterTemplate(w http.ResponseWriter, r *http.Request) (models.LetterTemplateRequest, bool) {
+	var req models.LetterTemplateRequest
+	if err := decodeJSON(r, &req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return req, false
+	}
+	errs := models.ValidateOne(req)
+	if len(errs) == 0 {
+		errs = letters.Check(req)
+	}
+	if len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return req, false
+	}
+	return req, true
+}
+
+func (h *LetterHandler) template(w http.ResponseWriter, r *http.Request, id int) (*models.LetterTemplate, bool) {
+	t, err := h.Templates.GetByID(r.Context(), id)
+	if err != nil {
+		if !errors.Is(err, models.ErrNotFound) {
+			logError(r, "Error fetching letter template %d: %v", id, err)
+		}
+		utils.ResponseError(w, err, fmt.Sprintf("Letter template with ID %d not found", id))
+		return nil, false
+	}
+	return t, true
+}
+
+func (h *LetterHandler) document(w http.ResponseWriter, r *http.Request) (*models.Document, bool) {
+	id := utils.PathID(r, "id")
+	doc, err := h.Documents.GetByID(r.Context(), id)
+	if err != nil {
+		if !errors.Is(err, models.ErrNotFound) {
+			logError(r, "Error fetching document %d: %v", id, err)
+		}
+		utils.ResponseError(w, err, fmt.Sprintf("Document with ID %d not found", id))
+		return nil, false
+	}
+	return doc, true
+}
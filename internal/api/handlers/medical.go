@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"simpleapi/internal/expr"
+	"simpleapi/internal/models"
+	"simpleapi/internal/query"
+	"simpleapi/internal/render"
+	"simpleapi/internal/repository"
+	"simpleapi/internal/schoolprofile"
+	"simpleapi/pkg/clock"
+	"simpleapi/pkg/errcodes"
+	"simpleapi/pkg/redact"
+	"simpleapi/pkg/utils"
+	"slices"
+)
+
+// MedicalHandler serves students' medical records. Admins and nurses see
+// every student's; a teacher only those of the class they are class teacher
+// of (Teacher.Class), not of every class they teach. Each read is audited.
+type MedicalHandler struct {
+	Students repository.StudentStore
+	Medical  repository.MedicalStore
+	Clock    clock.Clock
+	School   *schoolprofile.Profile
+}
+
+// NewMedicalHandler is the constructor
+func NewMedicalHandler(students repository.StudentStore, medical repository.MedicalStore, clk clock.Clock, school *schoolprofile.Profile) *MedicalHandler {
+	return &MedicalHandler{Students: students, Medical: medical, Clock: clk, School: school}
+}
+
+// maxEmergencySheet is the most students one emergency sheet lists
+const maxEmergencySheet = 200
+
+// emergencySheetQuery is the query string of GET /trips/emergency-sheet: a
+// class, some students by ID, or both
+type emergencySheetQuery struct {
+	Class    string `query:"class" validate:"max=50"`
+	Students []int  `query:"students" validate:"max=200,dive,gte=1"`
+	Title    string `query:"title" validate:"max=100"`
+}
+
+// mayReadMedical reports whether user may read student's medical record
+func mayReadMedical(user *models.Teacher, student models.Student) bool {
+	switch user.Role {
+	case models.RoleAdmin, models.RoleNurse:
+		return true
+	case models.RoleTeacher:
+		return user.Class != "" && user.Class == student.Class
+	}
+	return false
+}
+
+// medicalViewer is who the record is redacted for: its notes are for admins
+// and nurses. Unlike other responses this doesn't wait for RESPONSE_REDACTION.
+func medicalViewer(user *models.Teacher) redact.Viewer {
+	return redact.Viewer{ID: user.ID, Role: user.Role}
+}
+
+// GetMedical returns a student's medical record: GET /students/{id}/medical.
+// A student without one gets an empty record.
+func (h *MedicalHandler) GetMedical(w http.ResponseWriter, r *http.Request) {
+	id := utils.PathID(r, "id")
+	user := currentUser(r)
+
+	student, err := h.Students.GetByID(r.Context(), id)
+	if err != nil {
+		logError(r, "Error fetching student %d: %v", id, err)
+		utils.ResponseError(w, err, fmt.Sprintf("Student with ID %d not found", id))
+		return
+	}
+	if !mayReadMedical(user, *student) {
+		utils.WriteErrorCode(w, http.StatusForbidden, errcodes.ClassNotAssigned,
+			fmt.Sprintf("Only the class teacher of %s, nurses and admins may see this student's medical record", student.Class))
+		return
+	}
+
+	records, err := h.Medical.Get(r.Context(), []int{id}, currentUserID(r), models.MedicalReadView)
+	if err != nil {
+		logError(r, "Error reading the medical record of student %d: %v", id, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	record := models.MedicalRecord{StudentID: id, EmergencyContacts: make([]models.EmergencyContact, 0)}
+	if len(records) > 0 {
+		record = records[0]
+	}
+	utils.WriteJSON(w, http.StatusOK, "Medical record fetched successfully", redact.Apply(record, medicalViewer(user)))
+}
+
+// UpdateMedical replaces a student's medical record: PUT /students/{id}/medical,
+// for admins and nurses
+func (h *MedicalHandler) UpdateMedical(w http.ResponseWriter, r *http.Request) {
+	id := utils.PathID(r, "id")
+
+	var record models.MedicalRecord
+	if err := decodeJSON(r, &record); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if record.StudentID != 0 && record.StudentID != id {
+		utils.WriteError(w, http.StatusBadRequest, "student_id doesn't match the URL")
+		return
+	}
+	record.StudentID = id
+	if errors := models.ValidateOne(record); len(errors) > 0 {
+		writeValidationErrors(w, r, errors)
+		return
+	}
+	record.NormalizePhones()
+
+	saved, err := h.Medical.Save(r.Context(), record, currentUserID(r))
+	if err != nil {
+		logError(r, "Error saving the medical record of student %d: %v", id, err)
+		utils.ResponseError(w, err, fmt.Sprintf("Student with ID %d not found", id))
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Medical record saved", redact.Apply(*saved, medicalViewer(currentUser(r))))
+}
+
+// GetEmergencySheet prints the medical records of the students going on a
+// trip, with their emergency contacts and guardian's phone, as a PDF:
+// GET /trips/emergency-sheet?class=&students=1,2&title=. A teacher may only
+// print students of their own class; each record printed is audited.
+func (h *MedicalHandler) GetEmergencySheet(w http.ResponseWriter, r *http.Request) {
+	var q emergencySheetQuery
+	if errs := utils.BindQuery(r, &q); len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	if q.Class == "" && len(q.Students) == 0 {
+		utils.WriteError(w, http.StatusBadRequest, "Name a class, students, or both")
+		return
+	}
+	user := currentUser(r)
+
+	students := make([]models.Student, 0)
+	if q.Class != "" {
+		var err error
+		students, err = h.Students.GetAll(r.Context(), query.Options{
+			Where: expr.Equal(models.StudentFields, "class", q.Class),
+			Sort:  []query.Sort{{Field: "last_name"}, {Field: "first_name"}},
+		})
+		if err != nil {
+			logError(r, "Error fetching students of class %s: %v", q.Class, err)
+			utils.ResponseError(w, err, "")
+			return
+		}
+	}
+	for _, id := range q.Students {
+		if slices.ContainsFunc(students, func(s models.Student) bool { return s.ID == id }) {
+			continue
+		}
+		student, err := h.Students.GetByID(r.Context(), id)
+		if err != nil {
+			logError(r, "Error fetching student %d: %v", id, err)
+			utils.ResponseError(w, err, fmt.Sprintf("Student with ID %d not found", id))
+			return
+		}
+		students = append(students, *student)
+	}
+	if len(students) == 0 {
+		utils.WriteError(w, http.StatusNotFound, fmt.Sprintf("Class %s has no students", q.Class))
+		return
+	}
+	if len(students) > maxEmergencySheet {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Sprintf("An emergency sheet lists at most %d students", maxEmergencySheet))
+		return
+	}
+	ids := make([]int, len(students))
+	for i, s := range students {
+		if !mayReadMedical(user, s) {
+			utils.WriteErrorCode(w, http.StatusForbidden, errcodes.ClassNotAssigned,
+				fmt.Sprintf("Only the class teacher of %s, nurses and admins may print %s %s's medical record", s.Class, s.FirstName, s.LastName))
+			return
+		}
+		ids[i] = s.ID
+	}
+
+	records, err := h.Medical.Get(r.Context(), ids, currentUserID(r), models.MedicalReadEmergencySheet)
+	if err != nil {
+		logError(r, "Error reading medical records for an emergency sheet: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	byStudent := make(map[int]models.MedicalRecord, len(records))
+	for _, m := range records {
+		byStudent[m.StudentID] = m
+	}
+
+	now := h.Clock.Now()
+	sheet := render.EmergencySheet{
+		School:    h.School.Name(),
+		Title:     q.Title,
+		Students:  make([]render.EmergencyStudent, len(students)),
+		PrintedBy: user.FirstName + " " + user.LastName,
+		PrintedAt: now,
+	}
+	for i, s := range students {
+		m := byStudent[s.ID]
+		row := render.EmergencyStudent{
+			Name:            s.LastName + ", " + s.FirstName,
+			Class:           s.Class,
+			AdmissionNumber: s.AdmissionNumber,
+			Allergies:       m.Allergies,
+			Conditions:      m.Conditions,
+			Medications:     m.Medications,
+		}
+		if user.Role == models.RoleAdmin || user.Role == models.RoleNurse {
+			row.DateOfBirth = s.DateOfBirth // Teachers don't see dates of birth (see Student)
+		}
+		for _, c := range m.EmergencyContacts {
+			contact := c.Name
+			if c.Relationship != "" {
+				contact += " (" + c.Relationship + ")"
+			}
+			row.Contacts = append(row.Contacts, contact+" "+c.Phone)
+		}
+		if s.GuardianPhone != "" {
+			row.Contacts = append(row.Contacts, "Guardian "+s.GuardianPhone)
+		}
+		sheet.Students[i] = row
+	}
+
+	pdf, err := render.EmergencyPDF(sheet)
+	if err != nil {
+		logError(r, "Error rendering an emergency sheet: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "emergency-"+now.Format(models.DateLayout)+".pdf"))
+	w.Write(pdf)
+}
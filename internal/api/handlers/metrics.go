@@ -0,0 +1,34 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"simpleapi/internal/metrics"
+	"simpleapi/pkg/utils"
+	"strings"
+)
+
+// MetricsHandler serves the OpenMetrics exposition Prometheus scrapes
+type MetricsHandler struct {
+	// Token must come as "Authorization: Bearer <token>"; empty disables the endpoint
+	Token string
+}
+
+// NewMetricsHandler is the constructor
+func NewMetricsHandler(token string) *MetricsHandler {
+	return &MetricsHandler{Token: token}
+}
+
+// GetMetrics writes the school KPIs and process counters: GET /metrics
+func (h *MetricsHandler) GetMetrics(w http.ResponseWriter, r *http.Request) {
+	token, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if h.Token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(h.Token)) != 1 {
+		utils.WriteError(w, http.StatusUnauthorized, "Invalid metrics token")
+		return
+	}
+	w.Header().Set("Content-Type", metrics.OpenMetricsContentType)
+	if err := metrics.WriteOpenMetrics(w); err != nil {
+		log.Printf("metrics: writing /metrics: %v", err) // The client went away
+	}
+}
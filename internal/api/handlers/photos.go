@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"simpleapi/internal/imaging"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"simpleapi/internal/storage"
+	"simpleapi/pkg/clock"
+	"simpleapi/pkg/utils"
+	"strings"
+)
+
+const (
+	maxPhotoArchiveBytes = 200 << 20 // 200 MB zip
+	maxPhotosPerArchive  = 2000
+)
+
+// PhotoHandler manages student photos (bulk import and download)
+type PhotoHandler struct {
+	Students repository.StudentStore
+	Storage  storage.Storage
+	Signer   utils.DocumentSigning
+	Clock    clock.Clock
+}
+
+// NewPhotoHandler is the constructor
+func NewPhotoHandler(students repository.StudentStore, store storage.Storage, signer utils.DocumentSigning, clk clock.Clock) *PhotoHandler {
+	return &PhotoHandler{Students: students, Storage: store, Signer: signer, Clock: clk}
+}
+
+// PhotoImportResult is one line of the per-file import report
+type PhotoImportResult struct {
+	File      string `json:"file"`
+	StudentID int    `json:"student_id,omitempty"`
+	Status    string `json:"status"` // "imported" or "failed"
+	Error     string `json:"error,omitempty"`
+}
+
+// studentPhotoKey is where a student's photo variant lives in storage
+func studentPhotoKey(studentID int, variant string) string {
+	return fmt.Sprintf("students/%d/%s.jpg", studentID, variant)
+}
+
+// ImportPhotos accepts a zip (multipart field "file") of images named by admission number
+// or email, e.g. "ADM-0042.jpg" or "jane@school.org.png". Each file is processed
+// independently; the response reports success or failure per file.
+func (h *PhotoHandler) ImportPhotos(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, maxPhotoArchiveBytes)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Expected a zip archive in form field 'file'")
+		return
+	}
+	defer file.Close()
+
+	archive, err := zip.NewReader(file, header.Size)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Uploaded file is not a valid zip archive")
+		return
+	}
+	if len(archive.File) > maxPhotosPerArchive {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Sprintf("Archive has %d entries, max is %d", len(archive.File), maxPhotosPerArchive))
+		return
+	}
+
+	results := make([]PhotoImportResult, 0, len(archive.File))
+	imported := 0
+	for _, f := range archive.File {
+		name := path.Base(f.Name)
+		// Skip folders and OS junk (__MACOSX/, .DS_Store)
+		if f.FileInfo().IsDir() || strings.HasPrefix(name, ".") || strings.HasPrefix(f.Name, "__MACOSX/") {
+			continue
+		}
+
+		res := h.importOne(r, f, name)
+		if res.Status == "imported" {
+			imported++
+		}
+		results = append(results, res)
+	}
+
+	response := struct {
+		Imported int                 `json:"imported"`
+		Failed   int                 `json:"failed"`
+		Results  []PhotoImportResult `json:"results"`
+	}{
+		Imported: imported,
+		Failed:   len(results) - imported,
+		Results:  results,
+	}
+
+	utils.WriteJSON(w, http.StatusOK, "Photo import finished", response)
+}
+
+func (h *PhotoHandler) importOne(r *http.Request, f *zip.File, name string) PhotoImportResult {
+	res := PhotoImportResult{File: f.Name, Status: "failed"}
+
+	ext := strings.ToLower(path.Ext(name))
+	if ext != ".jpg" && ext != ".jpeg" && ext != ".png" {
+		res.Error = "unsupported file type (use .jpg or .png)"
+		return res
+	}
+	// Cheap zip-bomb guard before we decompress anything
+	if f.UncompressedSize64 > imaging.MaxFileBytes {
+		res.Error = fmt.Sprintf("file larger than %d MB", imaging.MaxFileBytes>>20)
+		return res
+	}
+
+	key := strings.TrimSuffix(name, path.Ext(name))
+	student, err := h.Students.FindByKey(r.Context(), key)
+	if err != nil {
+		if errors.Is(err, models.ErrNotFound) {
+			res.Error = fmt.Sprintf("no student with admission number or email %q", key)
+		} else {
+			log.Printf("Error looking up student %s: %v", key, err)
+			res.Error = "lookup failed"
+		}
+		return res
+	}
+	res.StudentID = student.ID
+
+	rc, err := f.Open()
+	if err != nil {
+		res.Error = "cannot read file from archive"
+		return res
+	}
+	variants, err := imaging.Process(rc)
+	rc.Close()
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+
+	if err := h.Storage.Put(r.Context(), studentPhotoKey(student.ID, "original"), bytes.NewReader(variants.Original)); err != nil {
+		log.Printf("Error storing photo for student %d: %v", student.ID, err)
+		res.Error = "storage failed"
+		return res
+	}
+	if err := h.Storage.Put(r.Context(), studentPhotoKey(student.ID, "thumbnail"), bytes.NewReader(variants.Thumbnail)); err != nil {
+		log.Printf("Error storing thumbnail for student %d: %v", student.ID, err)
+		res.Error = "storage failed"
+		return res
+	}
+
+	res.Status = "imported"
+	return res
+}
+
+// GetPhoto streams a student's photo; ?size=thumbnail returns the small variant
+func (h *PhotoHandler) GetPhoto(w http.ResponseWriter, r *http.Request) {
+	id := utils.PathID(r, "id")
+	rc, ok := h.open(w, r, id, photoVariant(r))
+	if !ok {
+		return
+	}
+	defer rc.Close()
+
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	io.Copy(w, rc)
+}
+
+// GetPhotoLink returns a signed link to the photo, for emails:
+// GET /students/{id}/photo/link?size=thumbnail&expires_in=3600
+func (h *PhotoHandler) GetPhotoLink(w http.ResponseWriter, r *http.Request) {
+	id := utils.PathID(r, "id")
+	variant := photoVariant(r)
+	rc, ok := h.open(w, r, id, variant)
+	if !ok {
+		return
+	}
+	rc.Close()
+
+	writeDownloadLink(w, r, h.Signer, h.Clock, models.DownloadClaims{
+		Kind:        models.DownloadPhoto,
+		ID:          id,
+		Key:         studentPhotoKey(id, variant),
+		ContentType: "image/jpeg",
+	})
+}
+
+// photoVariant reads ?size, the original unless it asks for the thumbnail
+func photoVariant(r *http.Request) string {
+	if r.URL.Query().Get("size") == "thumbnail" {
+		return "thumbnail"
+	}
+	return "original"
+}
+
+// open reads the student's photo, answering 404 if there is none
+func (h *PhotoHandler) open(w http.ResponseWriter, r *http.Request, id int, variant string) (io.ReadCloser, bool) {
+	rc, err := h.Storage.Get(r.Context(), studentPhotoKey(id, variant))
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			utils.WriteError(w, http.StatusNotFound, "Student has no photo")
+			return nil, false
+		}
+		log.Printf("Error reading photo of student %d: %v", id, err)
+		utils.WriteError(w, http.StatusInternalServerError, "Internal Server Error")
+		return nil, false
+	}
+	return rc, true
+}
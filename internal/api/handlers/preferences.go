@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"net/http"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"simpleapi/pkg/utils"
+)
+
+// PreferenceHandler manages the language and time zone stored on an account,
+// which apply when a request doesn't send Accept-Language or X-Timezone
+type PreferenceHandler struct {
+	Repo repository.TeacherStore
+}
+
+// NewPreferenceHandler is the constructor
+func NewPreferenceHandler(repo repository.TeacherStore) *PreferenceHandler {
+	return &PreferenceHandler{Repo: repo}
+}
+
+// GetPreferences returns a teacher's stored preferences; empty values mean
+// the school's defaults
+func (h *PreferenceHandler) GetPreferences(w http.ResponseWriter, r *http.Request) {
+	id := utils.PathID(r, "id")
+	if !ownAccountOrAdmin(w, r, id) {
+		return
+	}
+
+	teacher, err := h.Repo.GetByID(r.Context(), id)
+	if err != nil {
+		logError(r, "Error fetching teacher %d: %v", id, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Preferences fetched successfully", models.Preferences{Language: teacher.Language, Timezone: teacher.Timezone})
+}
+
+// SetPreferences lets a teacher pick their language and time zone; admins can do it for anyone
+func (h *PreferenceHandler) SetPreferences(w http.ResponseWriter, r *http.Request) {
+	id := utils.PathID(r, "id")
+	if !ownAccountOrAdmin(w, r, id) {
+		return
+	}
+
+	var req models.Preferences
+	if err := decodeJSON(r, &req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if errors := models.ValidateOne(req); len(errors) > 0 {
+		writeValidationErrors(w, r, errors)
+		return
+	}
+
+	if err := h.Repo.SetPreferences(r.Context(), id, req); err != nil {
+		logError(r, "Error updating preferences of teacher %d: %v", id, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Preferences updated successfully", req)
+}
+
+// ownAccountOrAdmin answers 403 unless the current user is teacher id or an admin
+func ownAccountOrAdmin(w http.ResponseWriter, r *http.Request, id int) bool {
+	user := currentUser(r)
+	if user.ID != id && user.Role != models.RoleAdmin {
+		utils.WriteError(w, http.StatusForbidden, "You can only see or change your own preferences")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"simpleapi/internal/models"
+	"simpleapi/internal/promotion"
+	"simpleapi/internal/query"
+	"simpleapi/internal/repository"
+	"simpleapi/pkg/utils"
+	"slices"
+)
+
+// PromotionHandler runs year-end promotions: the rules engine proposes an
+// outcome per student, admins settle the reviews and override what they
+// disagree with, then applying the report moves the promoted students up.
+type PromotionHandler struct {
+	Promotions repository.PromotionStore
+	Students   repository.StudentStore
+	Scores     repository.ScoreStore
+	Attendance repository.AttendanceStore
+}
+
+// NewPromotionHandler is the constructor
+func NewPromotionHandler(promotions repository.PromotionStore, students repository.StudentStore, scores repository.ScoreStore, attendance repository.AttendanceStore) *PromotionHandler {
+	return &PromotionHandler{Promotions: promotions, Students: students, Scores: scores, Attendance: attendance}
+}
+
+// CreateReport evaluates the criteria for a year and saves the draft report:
+// POST /admin/academic-years/2025%2F26/promotions
+func (h *PromotionHandler) CreateReport(w http.ResponseWriter, r *http.Request) {
+	year := r.PathValue("year")
+	if year == "" || len(year) > 20 || models.AcademicYear(year) != year {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid academic year, expected e.g. 2025%2F26 for 2025/26")
+		return
+	}
+
+	var req models.PromotionRequest
+	if err := decodeJSON(r, &req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if errs := models.ValidateOne(req); len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	if c := req.Criteria; c.AttendanceTo < c.AttendanceFrom { // ISO dates compare correctly as strings
+		writeValidationErrors(w, r, []models.ValidationError{models.RuleError("AttendanceTo", "end_before_start", "")})
+		return
+	}
+
+	scores, err := h.Scores.ListByYear(r.Context(), year)
+	if err != nil {
+		logError(r, "Error fetching grades of %s: %v", year, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	students, err := h.Students.GetAll(r.Context(), query.Options{})
+	if err != nil {
+		logError(r, "Error fetching students: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	students = slices.DeleteFunc(students, func(s models.Student) bool { return !s.OnRoll() })
+	var tallies map[int]models.AttendanceTally
+	if c := req.Criteria; c.MinAttendance > 0 {
+		if tallies, err = h.Attendance.TallyByStudent(r.Context(), c.AttendanceFrom, c.AttendanceTo); err != nil {
+			logError(r, "Error tallying attendance: %v", err)
+			utils.ResponseError(w, err, "")
+			return
+		}
+	}
+
+	decisions := promotion.Decide(req, students, scores, tallies)
+	if len(decisions) == 0 {
+		utils.WriteError(w, http.StatusBadRequest, "None of the classes in next_class has students")
+		return
+	}
+
+	report, err := h.Promotions.Create(r.Context(), models.PromotionReport{
+		Year:      year,
+		Criteria:  req.Criteria,
+		NextClass: req.NextClass,
+		Decisions: decisions,
+		CreatedBy: currentUserID(r),
+	})
+	if err != nil {
+		logError(r, "Error saving promotion report for %s: %v", year, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/admin/promotions/%d", report.ID))
+	utils.WriteJSON(w, http.StatusCreated, "Promotion report created", report)
+}
+
+// ListReports lists reports newest first; ?year=2025/26 narrows it to one year
+func (h *PromotionHandler) ListReports(w http.ResponseWriter, r *http.Request) {
+	reports, err := h.Promotions.List(r.Context(), r.URL.Query().Get("year"))
+	if err != nil {
+		logError(r, "Error listing promotion reports: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Promotion reports fetched successfully", reports)
+}
+
+func (h *PromotionHandler) GetReport(w http.ResponseWriter, r *http.Request) {
+	id := utils.PathID(r, "id")
+	report, err := h.Promotions.GetByID(r.Context(), id)
+	if err != nil {
+		if !errors.Is(err, models.ErrNotFound) {
+			logError(r, "Error fetching promotion report %d: %v", id, err)
+		}
+		utils.ResponseError(w, err, "")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Promotion report fetched successfully", report)
+}
+
+// OverrideDecision sets the outcome for one student, with a note saying why
+func (h *PromotionHandler) OverrideDecision(w http.ResponseWriter, r *http.Request) {
+	id := utils.PathID(r, "id")
+	studentID := utils.PathID(r, "studentId")
+
+	var o models.PromotionOverride
+	if err := decodeJSON(r, &o); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if errs := models.ValidateOne(o); len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	report, err := h.Promotions.Override(r.Context(), id, studentID, o, currentUserID(r))
+	if err != nil {
+		if errors.Is(err, models.ErrConflict) {
+			utils.WriteError(w, http.StatusConflict, "The promotion report has already been applied")
+			return
+		}
+		if !errors.Is(err, models.ErrNotFound) {
+			logError(r, "Error overriding promotion of student %d in report %d: %v", studentID, id, err)
+		}
+		utils.ResponseError(w, err, "")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Decision overridden", report)
+}
+
+// ApplyReport runs the bulk class promotion. Every review must be settled first.
+func (h *PromotionHandler) ApplyReport(w http.ResponseWriter, r *http.Request) {
+	id := utils.PathID(r, "id")
+
+	report, err := h.Promotions.Apply(r.Context(), id, currentUserID(r))
+	if err != nil {
+		var dep *models.DependencyError
+		switch {
+		case errors.As(err, &dep):
+			utils.WriteError(w, http.StatusConflict, dep.Reason, dep)
+		case errors.Is(err, models.ErrConflict):
+			utils.WriteError(w, http.StatusConflict, "The promotion report has already been applied")
+		default:
+			if !errors.Is(err, models.ErrNotFound) {
+				logError(r, "Error applying promotion report %d: %v", id, err)
+			}
+			utils.ResponseError(w, err, "")
+		}
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Promotions applied", report)
+}
@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"net/http"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"simpleapi/pkg/utils"
+)
+
+// ReferenceHandler serves the school's classes and subjects, what teachers'
+// and students' class and subject fields are checked against
+type ReferenceHandler struct {
+	Store repository.ReferenceStore
+}
+
+// NewReferenceHandler is the constructor
+func NewReferenceHandler(store repository.ReferenceStore) *ReferenceHandler {
+	return &ReferenceHandler{Store: store}
+}
+
+// GetReference lists the classes and subjects, e.g. for a form's dropdowns
+func (h *ReferenceHandler) GetReference(w http.ResponseWriter, r *http.Request) {
+	d, err := h.Store.Get(r.Context())
+	if err != nil {
+		logError(r, "Error fetching reference data: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Reference data fetched successfully", d)
+}
+
+// UpdateReference replaces both lists: PUT /admin/reference. Teachers and
+// students keep their class and subject; only new values are checked.
+func (h *ReferenceHandler) UpdateReference(w http.ResponseWriter, r *http.Request) {
+	var req models.ReferenceData
+	if err := decodeJSON(r, &req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if errs := models.ValidateOne(req); len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	saved, err := h.Store.Replace(r.Context(), req, currentUserID(r))
+	if err != nil {
+		logError(r, "Error saving reference data: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Reference data updated successfully", saved)
+}
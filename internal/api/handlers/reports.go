@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"simpleapi/internal/cache"
+	"simpleapi/internal/jobs"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"simpleapi/pkg/clock"
+	"simpleapi/pkg/utils"
+	"strconv"
+	"time"
+)
+
+// ReportHandler serves the read-only school-wide reports under /reports. The
+// figures move slowly and the queries scan whole tables, so each report is
+// cached for cacheTTL, per combination of query parameters.
+type ReportHandler struct {
+	Repo       repository.ReportStore
+	Cache      cache.Cache
+	Attendance *jobs.AttendanceNotices // Evaluates the attendance thresholds
+	Clock      clock.Clock
+	cacheTTL   time.Duration
+}
+
+// NewReportHandler is the constructor
+func NewReportHandler(repo repository.ReportStore, reports cache.Cache, attendance *jobs.AttendanceNotices, clk clock.Clock, cacheTTL time.Duration) *ReportHandler {
+	return &ReportHandler{Repo: repo, Cache: reports, Attendance: attendance, Clock: clk, cacheTTL: cacheTTL}
+}
+
+// GetEnrollment counts students per class: GET /reports/enrollment
+func (h *ReportHandler) GetEnrollment(w http.ResponseWriter, r *http.Request) {
+	serveReport(h, w, r, "enrollment", h.Repo.Enrollment)
+}
+
+// GetGenders counts students per class and gender: GET /reports/genders
+func (h *ReportHandler) GetGenders(w http.ResponseWriter, r *http.Request) {
+	serveReport(h, w, r, "genders", h.Repo.Genders)
+}
+
+// GetAttendance gives each class's attendance rate per month:
+// GET /reports/attendance?from=2025-09&to=2026-07. The range defaults to the
+// twelve months up to to, and to to the current month.
+func (h *ReportHandler) GetAttendance(w http.ResponseWriter, r *http.Request) {
+	to, ok := reportMonth(w, r, "to", h.Clock.Now().Format(models.MonthLayout))
+	if !ok {
+		return
+	}
+	from, ok := reportMonth(w, r, "from", to.AddDate(0, -11, 0).Format(models.MonthLayout))
+	if !ok {
+		return
+	}
+	if to.Before(from) {
+		utils.WriteError(w, http.StatusBadRequest, "from must not be after to")
+		return
+	}
+
+	first, last := from.Format(models.DateLayout), to.AddDate(0, 1, -1).Format(models.DateLayout)
+	serveReport(h, w, r, "attendance?"+first+"/"+last, func(ctx context.Context) ([]models.AttendanceRate, error) {
+		return h.Repo.AttendanceRates(ctx, first, last)
+	})
+}
+
+// GetAtRisk lists the students whose attendance is below one of the school's
+// thresholds, lowest rate first: GET /reports/attendance/at-risk, or
+// ?class=JSS1 for one class. It isn't cached, so a register taken or a
+// threshold changed shows at once.
+func (h *ReportHandler) GetAtRisk(w http.ResponseWriter, r *http.Request) {
+	report, err := h.Attendance.AtRisk(r.Context(), r.URL.Query().Get("class"))
+	if err != nil {
+		logError(r, "Error evaluating attendance thresholds: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "At-risk students fetched successfully", report)
+}
+
+// GetGrades counts the grades given per subject: GET /reports/grades, or
+// ?term=2025/26-T1 for one term
+func (h *ReportHandler) GetGrades(w http.ResponseWriter, r *http.Request) {
+	term := r.URL.Query().Get("term")
+	serveReport(h, w, r, "grades?"+term, func(ctx context.Context) ([]models.GradeCount, error) {
+		return h.Repo.GradeDistribution(ctx, term)
+	})
+}
+
+// reportMonth reads a month query parameter, writing a 400 if it's malformed
+func reportMonth(w http.ResponseWriter, r *http.Request, name, fallback string) (time.Time, bool) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		value = fallback
+	}
+	month, err := time.Parse(models.MonthLayout, value)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid "+name+", expected YYYY-MM")
+		return time.Time{}, false
+	}
+	return month, true
+}
+
+// serveReport answers with the cached report under key, running load when
+// there is none or it has expired
+func serveReport[T any](h *ReportHandler, w http.ResponseWriter, r *http.Request, key string, load func(context.Context) ([]T, error)) {
+	report, err := cachedOr(h, r.Context(), key, load)
+	if err != nil {
+		logError(r, "Error building %s report: %v", key, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	// Staff-only figures: browsers may keep them as long as we do, shared caches not at all
+	w.Header().Set("Cache-Control", "private, max-age="+strconv.Itoa(int(h.cacheTTL.Seconds())))
+	utils.WriteJSON(w, http.StatusOK, "Report generated successfully", report)
+}
+
+func cachedOr[T any](h *ReportHandler, ctx context.Context, key string, load func(context.Context) ([]T, error)) (models.Report[T], error) {
+	return cache.Load(ctx, h.Cache, "reports:"+key, h.cacheTTL, func(ctx context.Context) (models.Report[T], error) {
+		rows, err := load(ctx)
+		if err != nil {
+			return models.Report[T]{}, err
+		}
+		return models.Report[T]{GeneratedAt: h.Clock.Now(), Rows: rows}, nil
+	})
+}
@@ -0,0 +1,30 @@
+package handlers
+
+import (
+	"net/http"
+	"simpleapi/internal/jobs"
+	"simpleapi/pkg/utils"
+)
+
+// RetentionHandler reports on the data retention policy, which the scheduler
+// applies (SCHEDULE_RETENTION)
+type RetentionHandler struct {
+	Retention *jobs.Retention
+}
+
+// NewRetentionHandler is the constructor
+func NewRetentionHandler(retention *jobs.Retention) *RetentionHandler {
+	return &RetentionHandler{Retention: retention}
+}
+
+// GetReport is a dry run of the policy: GET /admin/retention/report lists, per
+// category, how many records the next run would anonymize or delete
+func (h *RetentionHandler) GetReport(w http.ResponseWriter, r *http.Request) {
+	results, err := h.Retention.Run(r.Context(), true)
+	if err != nil {
+		logError(r, "Error building retention report: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Retention report generated successfully", results)
+}
@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"simpleapi/internal/jobs"
+	"simpleapi/internal/models"
+	"simpleapi/internal/query"
+	"simpleapi/internal/repository"
+	"simpleapi/internal/rollover"
+	"simpleapi/pkg/utils"
+	"slices"
+)
+
+// RolloverHandler is the year-end rollover wizard: the planner proposes where
+// every class, student and teacher goes next year, admins adjust the plan,
+// and executing it runs on the job queue while clients poll the plan's progress.
+type RolloverHandler struct {
+	Plans       repository.RolloverStore
+	Students    repository.StudentStore
+	Teachers    repository.TeacherStore
+	Assignments repository.ClassAssignmentStore
+	Reference   repository.ReferenceStore
+	Rollover    *jobs.Rollover
+	Queue       *jobs.Queue
+}
+
+// NewRolloverHandler is the constructor
+func NewRolloverHandler(plans repository.RolloverStore, students repository.StudentStore, teachers repository.TeacherStore,
+	assignments repository.ClassAssignmentStore, reference repository.ReferenceStore, ro *jobs.Rollover, queue *jobs.Queue) *RolloverHandler {
+	return &RolloverHandler{Plans: plans, Students: students, Teachers: teachers, Assignments: assignments,
+		Reference: reference, Rollover: ro, Queue: queue}
+}
+
+// CreatePlan proposes a rollover and saves it as a draft: POST /admin/rollover/plan
+func (h *RolloverHandler) CreatePlan(w http.ResponseWriter, r *http.Request) {
+	var req models.RolloverRequest
+	if err := decodeJSON(r, &req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if errs := models.ValidateOne(req); len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	in, reference, err := h.planInput(r)
+	if err != nil {
+		logError(r, "Error gathering the school for a rollover plan: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	plan, errs := rollover.Propose(req, in)
+	if len(plan.Students) == 0 {
+		utils.WriteError(w, http.StatusBadRequest, "There are no students to roll over")
+		return
+	}
+	if errs = append(errs, reference.CheckRollover(plan)...); len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	plan.CreatedBy = currentUserID(r)
+	created, err := h.Plans.Create(r.Context(), plan)
+	if err != nil {
+		logError(r, "Error saving rollover plan for %s: %v", req.FromYear, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/admin/rollover/plans/%d", created.ID))
+	utils.WriteJSON(w, http.StatusCreated, "Rollover plan created", created)
+}
+
+// ListPlans lists plans newest first; ?from_year=2025/26 narrows it to one year
+func (h *RolloverHandler) ListPlans(w http.ResponseWriter, r *http.Request) {
+	plans, err := h.Plans.List(r.Context(), r.URL.Query().Get("from_year"))
+	if err != nil {
+		logError(r, "Error listing rollover plans: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Rollover plans fetched successfully", plans)
+}
+
+// GetPlan returns a plan, with its progress while it executes
+func (h *RolloverHandler) GetPlan(w http.ResponseWriter, r *http.Request) {
+	id := utils.PathID(r, "id")
+	plan, err := h.Plans.GetByID(r.Context(), id)
+	if err != nil {
+		if !errors.Is(err, models.ErrNotFound) {
+			logError(r, "Error fetching rollover plan %d: %v", id, err)
+		}
+		utils.ResponseError(w, err, "")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Rollover plan fetched successfully", plan)
+}
+
+// PatchPlan adjusts a draft or failed plan: class mappings, students' actions,
+// teachers' classes, the alumni class and whether to archive first
+func (h *RolloverHandler) PatchPlan(w http.ResponseWriter, r *http.Request) {
+	id := utils.PathID(r, "id")
+
+	var patch models.RolloverPatch
+	if err := decodeJSON(r, &patch); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if errs := models.ValidateOne(patch); len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	plan, err := h.Plans.GetByID(r.Context(), id)
+	if err != nil {
+		if !errors.Is(err, models.ErrNotFound) {
+			logError(r, "Error fetching rollover plan %d: %v", id, err)
+		}
+		utils.ResponseError(w, err, "")
+		return
+	}
+	if !plan.Editable() {
+		utils.WriteError(w, http.StatusConflict, fmt.Sprintf("The rollover plan is %s and can no longer change", plan.Status))
+		return
+	}
+	if errs := rollover.Adjust(plan, patch); len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	if !h.checkPlan(w, r, *plan) {
+		return
+	}
+
+	saved, err := h.Plans.Save(r.Context(), *plan)
+	if err != nil {
+		if errors.Is(err, models.ErrConflict) {
+			utils.WriteError(w, http.StatusConflict, "The rollover plan was executed meanwhile and can no longer change")
+			return
+		}
+		logError(r, "Error saving rollover plan %d: %v", id, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Rollover plan updated", saved)
+}
+
+// Execute queues a plan for execution: POST /admin/rollover/execute with
+// {"plan_id": 3}. Poll the plan for its progress and outcome; a failed plan
+// was rolled back and can be adjusted and executed again.
+func (h *RolloverHandler) Execute(w http.ResponseWriter, r *http.Request) {
+	var req models.RolloverExecution
+	if err := decodeJSON(r, &req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if errs := models.ValidateOne(req); len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	plan, err := h.Plans.GetByID(r.Context(), req.PlanID)
+	if err != nil {
+		if !errors.Is(err, models.ErrNotFound) {
+			logError(r, "Error fetching rollover plan %d: %v", req.PlanID, err)
+		}
+		utils.ResponseError(w, err, "")
+		return
+	}
+	// The class list may have changed since the plan was last adjusted
+	if !h.checkPlan(w, r, *plan) {
+		return
+	}
+
+	plan, err = h.Plans.Start(r.Context(), req.PlanID, currentUserID(r))
+	if err != nil {
+		if errors.Is(err, models.ErrConflict) {
+			utils.WriteError(w, http.StatusConflict, "The plan was executed already, another rollover is in progress, or the year has been rolled over")
+			return
+		}
+		logError(r, "Error starting rollover plan %d: %v", req.PlanID, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+
+	if err := h.Queue.Enqueue(h.Rollover.Job(*plan)); err != nil {
+		// Don't leave it pending forever: nothing will ever pick it up
+		h.Plans.Finish(r.Context(), plan.ID, models.RolloverPlan{Status: models.JobFailed, Error: err.Error(), Students: plan.Students})
+		utils.WriteError(w, http.StatusServiceUnavailable, "Too many background jobs, try again later")
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/admin/rollover/plans/%d", plan.ID))
+	utils.WriteJSON(w, http.StatusAccepted, "Rollover started", plan)
+}
+
+// planInput reads what the planner needs: students, teachers and their
+// assigned classes, the class list and the alumni classes of earlier rollovers
+func (h *RolloverHandler) planInput(r *http.Request) (rollover.Input, *models.ReferenceData, error) {
+	ctx := r.Context()
+	var in rollover.Input
+	students, err := h.Students.GetAll(ctx, query.Options{})
+	if err != nil {
+		return in, nil, err
+	}
+	// Applicants, the withdrawn and alumni don't move with a class
+	students = slices.DeleteFunc(students, func(s models.Student) bool { return !s.OnRoll() })
+	teachers, err := h.Teachers.GetAll(ctx, query.Options{})
+	if err != nil {
+		return in, nil, err
+	}
+	assignments := make(map[int][]string, len(teachers))
+	for _, t := range teachers {
+		if assignments[t.ID], err = h.Assignments.ClassesOf(ctx, t.ID); err != nil {
+			return in, nil, err
+		}
+	}
+	reference, err := h.Reference.Get(ctx)
+	if err != nil {
+		return in, nil, err
+	}
+	earlier, err := h.Plans.List(ctx, "")
+	if err != nil {
+		return in, nil, err
+	}
+
+	in = rollover.Input{Students: students, Teachers: teachers, Assignments: assignments, Classes: reference.Classes}
+	for _, p := range earlier {
+		if p.Status == models.JobDone {
+			in.Alumni = append(in.Alumni, p.AlumniClass)
+		}
+	}
+	return in, reference, nil
+}
+
+// checkPlan runs the checks a plan must pass before it can execute. It
+// answers the request itself and returns false when the plan fails them.
+func (h *RolloverHandler) checkPlan(w http.ResponseWriter, r *http.Request, plan models.RolloverPlan) bool {
+	reference, err := h.Reference.Get(r.Context())
+	if err != nil {
+		logError(r, "Error fetching reference data: %v", err)
+		utils.ResponseError(w, err, "")
+		return false
+	}
+	if errs := append(rollover.Check(plan), reference.CheckRollover(plan)...); len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"maps"
+	"net/http"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"simpleapi/pkg/utils"
+	"slices"
+	"strings"
+)
+
+// SchemaHandler describes the create and edit payloads as JSON Schema, so the
+// admin frontend can build its forms and check them with the server's rules
+type SchemaHandler struct {
+	Fields repository.CustomFieldStore
+}
+
+// NewSchemaHandler is the constructor
+func NewSchemaHandler(fields repository.CustomFieldStore) *SchemaHandler {
+	return &SchemaHandler{Fields: fields}
+}
+
+// GetSchema returns an entity's schema, with its custom fields: GET /schemas/{entity}
+func (h *SchemaHandler) GetSchema(w http.ResponseWriter, r *http.Request) {
+	name := r.PathValue("entity")
+	entity, ok := models.SchemaEntities[name]
+	if !ok {
+		utils.WriteError(w, http.StatusNotFound, "No schema for "+name+", want one of: "+strings.Join(slices.Sorted(maps.Keys(models.SchemaEntities)), ", "))
+		return
+	}
+
+	var custom []models.CustomField
+	if entity.Custom != "" {
+		var err error
+		if custom, err = h.Fields.List(r.Context(), entity.Custom); err != nil {
+			logError(r, "Error fetching custom fields of %s: %v", entity.Custom, err)
+			utils.ResponseError(w, err, "")
+			return
+		}
+	}
+	utils.WriteJSON(w, http.StatusOK, "Schema fetched successfully", entity.Schema(name, custom))
+}
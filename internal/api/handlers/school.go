@@ -0,0 +1,194 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"simpleapi/internal/imaging"
+	"simpleapi/internal/models"
+	"simpleapi/internal/quota"
+	"simpleapi/internal/repository"
+	"simpleapi/internal/schoolprofile"
+	"simpleapi/internal/storage"
+	"simpleapi/pkg/utils"
+)
+
+// schoolLogoKey is where the logo lives in storage
+const schoolLogoKey = "school/logo.png"
+
+// SchoolHandler serves the school's profile: its public part to anyone (the
+// login page, the kiosk) and the whole of it to admins, who edit it
+type SchoolHandler struct {
+	Store   repository.SchoolStore
+	Profile *schoolprofile.Profile
+	Storage storage.Storage
+	Quotas  *quota.Monitor
+}
+
+// NewSchoolHandler is the constructor
+func NewSchoolHandler(store repository.SchoolStore, profile *schoolprofile.Profile, files storage.Storage, quotas *quota.Monitor) *SchoolHandler {
+	return &SchoolHandler{Store: store, Profile: profile, Storage: files, Quotas: quotas}
+}
+
+// GetSchool returns the public profile: name, address, contact email,
+// current term and the logo's URL
+func (h *SchoolHandler) GetSchool(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	utils.WriteJSON(w, http.StatusOK, "School fetched successfully", h.Profile.Get().Public())
+}
+
+// GetLogo streams the logo, a PNG
+func (h *SchoolHandler) GetLogo(w http.ResponseWriter, r *http.Request) {
+	if !h.Profile.Get().HasLogo {
+		utils.WriteError(w, http.StatusNotFound, "The school has no logo")
+		return
+	}
+	rc, err := h.Storage.Get(r.Context(), schoolLogoKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			utils.WriteError(w, http.StatusNotFound, "The school has no logo")
+			return
+		}
+		log.Printf("Error reading the school logo: %v", err)
+		utils.WriteError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	defer rc.Close()
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	io.Copy(w, rc)
+}
+
+// GetAdminSchool returns the whole profile, as saved. Before the first save
+// it is the SCHOOL_NAME and SCHOOL_TIMEZONE fallback.
+func (h *SchoolHandler) GetAdminSchool(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSON(w, http.StatusOK, "School fetched successfully", h.Profile.Get())
+}
+
+// UpdateSchool replaces the profile: PUT /admin/school. The logo, the quotas
+// and the attendance thresholds have their own endpoints; has_logo, quotas
+// and attendance_thresholds in the body are ignored.
+func (h *SchoolHandler) UpdateSchool(w http.ResponseWriter, r *http.Request) {
+	var req models.School
+	if err := decodeJSON(r, &req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if errors := models.ValidateOne(req); len(errors) > 0 {
+		writeValidationErrors(w, r, errors)
+		return
+	}
+	current := h.Profile.Get()
+	req.HasLogo, req.Quotas, req.AttendanceThresholds = current.HasLogo, current.Quotas, current.AttendanceThresholds
+	h.save(w, r, req, "School updated successfully")
+}
+
+// GetQuotas shows each quota with how much of it is used: GET /admin/school/quotas
+func (h *SchoolHandler) GetQuotas(w http.ResponseWriter, r *http.Request) {
+	statuses, err := h.Quotas.Statuses(r.Context())
+	if err != nil {
+		logError(r, "Error measuring quota usage: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Quotas fetched successfully", statuses)
+}
+
+// UpdateQuotas sets the school's limits: PUT /admin/school/quotas. Zero lifts
+// one. Lowering a limit below what is used refuses new use, nothing is removed.
+func (h *SchoolHandler) UpdateQuotas(w http.ResponseWriter, r *http.Request) {
+	var req models.SchoolQuotas
+	if err := decodeJSON(r, &req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if errors := models.ValidateOne(req); len(errors) > 0 {
+		writeValidationErrors(w, r, errors)
+		return
+	}
+
+	s := h.Profile.Get()
+	s.Quotas = req
+	h.save(w, r, s, "Quotas updated successfully")
+}
+
+// UpdateAttendanceThresholds sets the attendance rates below which students
+// are alerted and reported at risk: PUT /admin/school/attendance-thresholds.
+// A notice_below of 0 turns the alerts off.
+func (h *SchoolHandler) UpdateAttendanceThresholds(w http.ResponseWriter, r *http.Request) {
+	var req models.AttendanceThresholds
+	if err := decodeJSON(r, &req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	errs := models.ValidateOne(req)
+	if len(errs) == 0 {
+		errs = req.Check()
+	}
+	if len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	s := h.Profile.Get()
+	s.AttendanceThresholds = req
+	h.save(w, r, s, "Attendance thresholds updated successfully")
+}
+
+// UploadLogo sets the logo from a JPEG or PNG in multipart field "file"
+func (h *SchoolHandler) UploadLogo(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, imaging.MaxFileBytes+1<<20)
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Expected an image in form field 'file'")
+		return
+	}
+	defer file.Close()
+
+	logo, err := imaging.Logo(file)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err := h.Storage.Put(r.Context(), schoolLogoKey, bytes.NewReader(logo)); err != nil {
+		logError(r, "Error storing the school logo: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+
+	s := h.Profile.Get()
+	s.HasLogo = true
+	h.save(w, r, s, "Logo uploaded successfully")
+}
+
+// DeleteLogo removes the logo
+func (h *SchoolHandler) DeleteLogo(w http.ResponseWriter, r *http.Request) {
+	if err := h.Storage.Delete(r.Context(), schoolLogoKey); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		logError(r, "Error deleting the school logo: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+
+	s := h.Profile.Get()
+	s.HasLogo = false
+	h.save(w, r, s, "Logo removed successfully")
+}
+
+// save stores the profile and applies it on this instance straight away
+func (h *SchoolHandler) save(w http.ResponseWriter, r *http.Request, s models.School, message string) {
+	if s.Name == "" {
+		utils.WriteError(w, http.StatusConflict, "Set the school's name with PUT /admin/school first")
+		return
+	}
+	saved, err := h.Store.Save(r.Context(), s, currentUserID(r))
+	if err != nil {
+		logError(r, "Error saving the school profile: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	h.Profile.Apply(*saved)
+	utils.WriteJSON(w, http.StatusOK, message, saved)
+}
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"net/http"
+	"simpleapi/internal/metrics"
+	"simpleapi/internal/models"
+	"simpleapi/pkg/utils"
+	"time"
+)
+
+// securityTopIPs is how many offending IPs each window of the summary lists
+const securityTopIPs = 10
+
+// SecurityHandler serves the admin security dashboard from the authentication
+// events this process counted (metrics.AuthEvents)
+type SecurityHandler struct {
+	Events *metrics.AuthLog
+}
+
+// NewSecurityHandler is the constructor
+func NewSecurityHandler(events *metrics.AuthLog) *SecurityHandler {
+	return &SecurityHandler{Events: events}
+}
+
+// GetSummary counts logins, failures and rejected sessions over the last 24
+// hours and 7 days, with the IPs behind the most failures: GET /admin/security/summary
+func (h *SecurityHandler) GetSummary(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSON(w, http.StatusOK, "Security summary fetched successfully", models.SecuritySummary{
+		Since: h.Events.Since(),
+		Windows: []models.SecurityWindow{
+			h.Events.Window("24h", 24*time.Hour, securityTopIPs),
+			h.Events.Window("7d", 7*24*time.Hour, securityTopIPs),
+		},
+	})
+}
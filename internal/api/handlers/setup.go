@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"simpleapi/pkg/redact"
+	"simpleapi/pkg/utils"
+	"sync/atomic"
+)
+
+// SetupHandler creates the first admin of a fresh install. POST /setup works
+// while there are no teachers at all; after that it answers 404 for good.
+// Class and subject aren't checked against reference data, which an admin
+// has yet to enter.
+type SetupHandler struct {
+	Repo      repository.TeacherStore
+	Passwords utils.PasswordHashing
+	// Token (SETUP_TOKEN) must be sent in X-Setup-Token, so whoever reaches a
+	// new deployment first can't claim it; empty lets anyone set up
+	Token string
+
+	// done is set once setup is known to be over, sparing the lookup
+	done atomic.Bool
+}
+
+// NewSetupHandler is the constructor
+func NewSetupHandler(repo repository.TeacherStore, passwords utils.PasswordHashing, token string) *SetupHandler {
+	return &SetupHandler{Repo: repo, Passwords: passwords, Token: token}
+}
+
+// Setup creates the first admin: POST /setup, with the body of POST /register
+func (h *SetupHandler) Setup(w http.ResponseWriter, r *http.Request) {
+	if h.done.Load() {
+		utils.WriteError(w, http.StatusNotFound, "Setup is already done")
+		return
+	}
+	if h.Token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("X-Setup-Token")), []byte(h.Token)) != 1 {
+		utils.WriteError(w, http.StatusUnauthorized, "Invalid setup token")
+		return
+	}
+
+	var admin models.Teacher
+	if err := decodeJSON(r, &admin); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if errors := models.ValidateOne(admin); len(errors) > 0 {
+		writeValidationErrors(w, r, errors)
+		return
+	}
+	admin.NormalizePhone()
+
+	hash, err := h.Passwords.HashPassword(admin.Password)
+	if err != nil {
+		logError(r, "Error hashing the first admin's password: %v", err)
+		utils.WriteError(w, http.StatusInternalServerError, "Server error processing credentials")
+		return
+	}
+	admin.PasswordHash, admin.Password = hash, ""
+
+	created, err := h.Repo.CreateFirstAdmin(r.Context(), admin)
+	if errors.Is(err, models.ErrConflict) {
+		h.done.Store(true)
+		utils.WriteError(w, http.StatusNotFound, "Setup is already done")
+		return
+	}
+	if err != nil {
+		logError(r, "Error creating the first admin: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	h.done.Store(true)
+	w = utils.ForViewer(w, redact.Viewer{ID: created.ID, Role: created.Role})
+	utils.WriteJSON(w, http.StatusCreated, "Setup complete, log in as the new admin", created)
+}
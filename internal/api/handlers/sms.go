@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"simpleapi/internal/sms"
+	"simpleapi/pkg/utils"
+)
+
+// SMSHandler lets admins text parents and receives the providers' delivery webhooks
+type SMSHandler struct {
+	Notifier *sms.Notifier
+	Students repository.StudentStore
+	// WebhookToken must match ?token= on delivery webhooks; empty disables the webhook
+	WebhookToken string
+}
+
+// NewSMSHandler is the constructor
+func NewSMSHandler(notifier *sms.Notifier, students repository.StudentStore, webhookToken string) *SMSHandler {
+	return &SMSHandler{Notifier: notifier, Students: students, WebhookToken: webhookToken}
+}
+
+func (h *SMSHandler) SendSMS(w http.ResponseWriter, r *http.Request) {
+	var req models.SMSRequest
+	if err := decodeJSON(r, &req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if errors := models.ValidateOne(req); len(errors) > 0 {
+		writeValidationErrors(w, r, errors)
+		return
+	}
+
+	// Without an explicit number the text goes to the student's guardian
+	if req.To == "" {
+		student, err := h.Students.GetByID(r.Context(), *req.StudentID)
+		if err != nil {
+			logError(r, "Error fetching student %d: %v", *req.StudentID, err)
+			utils.ResponseError(w, err, fmt.Sprintf("Student with ID %d not found", *req.StudentID))
+			return
+		}
+		if student.GuardianPhone == "" {
+			utils.WriteError(w, http.StatusConflict, fmt.Sprintf("Student %d has no guardian phone number on file", student.ID))
+			return
+		}
+		req.To = student.GuardianPhone
+	}
+
+	msg, err := h.Notifier.Send(r.Context(), req)
+	if err != nil && msg != nil {
+		// Recorded but the provider refused it; the stored row carries the reason
+		log.Printf("Error sending SMS %d: %v", msg.ID, err)
+		utils.WriteError(w, http.StatusBadGateway, "SMS provider did not accept the message", msg)
+		return
+	}
+	if err != nil {
+		logError(r, "Error sending SMS: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusCreated, "SMS sent successfully", msg)
+}
+
+// GetMessages lists sent SMS; ?student_id= and ?status= filter
+func (h *SMSHandler) GetMessages(w http.ResponseWriter, r *http.Request) {
+	var filter models.SMSFilter
+	if errs := utils.BindQuery(r, &filter); len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	messages, err := h.Notifier.Messages.List(r.Context(), filter)
+	if err != nil {
+		logError(r, "Error fetching SMS messages: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, "Messages fetched successfully", messages)
+}
+
+// DeliveryReport receives the provider's delivery-status webhook.
+// Providers retry on non-2xx, so unknown messages are acknowledged rather than rejected.
+func (h *SMSHandler) DeliveryReport(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if h.WebhookToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(h.WebhookToken)) != 1 {
+		utils.WriteError(w, http.StatusUnauthorized, "Invalid webhook token")
+		return
+	}
+
+	report, err := h.Notifier.Sender.ParseReport(r)
+	if errors.Is(err, sms.ErrBadReport) {
+		utils.WriteError(w, http.StatusBadRequest, "Malformed delivery report")
+		return
+	}
+	if err != nil {
+		logError(r, "Error parsing delivery report: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+
+	found, err := h.Notifier.ApplyReport(r.Context(), report)
+	if err != nil {
+		logError(r, "Error recording delivery report for %s: %v", report.ProviderMessageID, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	if !found {
+		log.Printf("Delivery report for unknown message %s ignored", report.ProviderMessageID)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"path"
+	"simpleapi/pkg/utils"
+	"strings"
+	"time"
+)
+
+// spaFile is one file of the build, read once with its ETag
+type spaFile struct {
+	data []byte
+	etag string
+}
+
+// SPAHandler serves the admin SPA's build. Bundles under assets/ or static/
+// carry a content hash in their names, so browsers keep them for a year;
+// everything else, index.html first, is revalidated by ETag on every load so
+// a deploy shows up at once. Paths without a file extension that match no
+// file get index.html, for the SPA's router to resolve (history mode).
+type SPAHandler struct {
+	files map[string]spaFile
+}
+
+// NewSPAHandler reads the build in files, which must have an index.html at its root
+func NewSPAHandler(files fs.FS) (*SPAHandler, error) {
+	h := &SPAHandler{files: make(map[string]spaFile)}
+	err := fs.WalkDir(files, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(files, name)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		h.files[name] = spaFile{data: data, etag: `"` + hex.EncodeToString(sum[:8]) + `"`}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("spa: reading the build: %w", err)
+	}
+	if _, ok := h.files["index.html"]; !ok {
+		return nil, fmt.Errorf("spa: the build has no index.html")
+	}
+	return h, nil
+}
+
+func (h *SPAHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// What isn't under /api/v1 yet starts with /api is a mistyped API call:
+	// it gets the API's 404, not the SPA
+	if r.URL.Path == "/api" || strings.HasPrefix(r.URL.Path, "/api/") {
+		utils.WriteError(w, http.StatusNotFound, "Not Found")
+		return
+	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		utils.WriteError(w, http.StatusMethodNotAllowed, "Method Not Allowed")
+		return
+	}
+
+	name := strings.TrimPrefix(path.Clean(r.URL.Path), "/")
+	if name == "" {
+		name = "index.html"
+	}
+	f, ok := h.files[name]
+	if !ok || hidden(name) {
+		// A missing script or stylesheet is a 404: index.html in its place
+		// would only fail to parse
+		if path.Ext(name) != "" {
+			http.NotFound(w, r)
+			return
+		}
+		name, f = "index.html", h.files["index.html"]
+	}
+
+	if immutable(name) {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+	w.Header().Set("ETag", f.etag)
+	http.ServeContent(w, r, name, time.Time{}, bytes.NewReader(f.data))
+}
+
+// immutable reports whether name is a hashed bundle, which never changes in place
+func immutable(name string) bool {
+	return strings.HasPrefix(name, "assets/") || strings.HasPrefix(name, "static/")
+}
+
+// hidden reports whether name has a dot-file in it, such as dist's .gitkeep
+func hidden(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") {
+			return true
+		}
+	}
+	return false
+}
@@ -1,82 +1,370 @@
 package handlers
 
 import (
-	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"mime"
 	"net/http"
+	"simpleapi/internal/enrollment"
 	"simpleapi/internal/models"
+	"simpleapi/internal/policy"
+	"simpleapi/internal/query"
+	"simpleapi/internal/quota"
 	"simpleapi/internal/repository"
+	"simpleapi/pkg/clock"
+	"simpleapi/pkg/errcodes"
+	"simpleapi/pkg/jsonpatch"
 	"simpleapi/pkg/utils"
+	"strings"
+	"time"
 )
 
 type StudentHandler struct {
-	Repo *repository.StudentRepositoty
+	Repo      repository.StudentStore
+	Fields    repository.CustomFieldStore
+	Reference repository.ReferenceStore
+	Clock     clock.Clock
+	Quotas    *quota.Monitor // Warns as the school nears its student quota
+	// Enrollment changes students' lifecycle states
+	Enrollment *enrollment.Service
 }
 
-func NewStudentHandler(repo *repository.StudentRepositoty) *StudentHandler {
-	return &StudentHandler{Repo: repo}
+func NewStudentHandler(repo repository.StudentStore, fields repository.CustomFieldStore, reference repository.ReferenceStore, clk clock.Clock, quotas *quota.Monitor, lifecycle *enrollment.Service) *StudentHandler {
+	return &StudentHandler{Repo: repo, Fields: fields, Reference: reference, Clock: clk, Quotas: quotas, Enrollment: lifecycle}
+}
+
+// studentOptionsFromQuery keeps list and count endpoints on the same filters.
+// ?guardian_phone= is normalized like stored numbers; ?min_age=&max_age= are
+// ages in completed years on today's date in the school's time zone. The meta
+// echoes the filters as applied, for the response's "query" member. ?filter=
+// can test custom fields too, by key.
+func (h *StudentHandler) studentOptionsFromQuery(r *http.Request) (query.Options, *utils.QueryMeta, []models.ValidationError, error) {
+	var filter models.StudentFilter
+	if errs := utils.BindQuery(r, &filter); len(errs) > 0 {
+		return query.Options{}, nil, errs, nil
+	}
+	filter.GuardianPhone, _ = models.ParsePhone(filter.GuardianPhone) // Already validated
+	filter.Nationality = strings.ToUpper(filter.Nationality)
+	filter.Today = h.Clock.Now().Format(models.DateLayout)
+	fields := models.StudentFields
+	if filter.Expr != "" {
+		defs, err := h.Fields.List(r.Context(), models.CustomFieldStudent)
+		if err != nil {
+			return query.Options{}, nil, nil, err
+		}
+		fields = models.CustomFilterFields(fields, defs)
+	}
+	var errs []models.ValidationError
+	if filter.Where, errs = parseFilter(filter.Expr, fields); len(errs) > 0 {
+		return query.Options{}, nil, errs, nil
+	}
+	meta := utils.EchoQuery(r, &filter, "format", "page", "per_page", "fields")
+	meta.ApplySort(&filter.SortBy, &filter.SortOrder, models.StudentSorts)
+	opts, errs := listOptions(r, filter.Condition(), meta, models.StudentListFields)
+	return opts, meta, errs, nil
 }
 
 func (h *StudentHandler) GetStudents(w http.ResponseWriter, r *http.Request) {
-	filter := models.StudentFilter{
-		FirstName: r.URL.Query().Get("first_name"),
-		LastName:  r.URL.Query().Get("last_name"),
-		Email:     r.URL.Query().Get("email"),
-		Class:     r.URL.Query().Get("class"),
-		SortBy:    r.URL.Query().Get("sortby"),
-		SortOrder: r.URL.Query().Get("order"),
+	opts, meta, errs, err := h.studentOptionsFromQuery(r)
+	if err != nil {
+		logError(r, "Error fetching custom fields: %v", err)
+		utils.ResponseError(w, err, "")
+		return
 	}
-	students, err := h.Repo.GetAll(r.Context(), filter)
+	if len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	students, err := h.Repo.GetAll(r.Context(), opts)
 	if err != nil {
-		log.Printf("Error fetching students list: %v", err)
+		logError(r, "Error fetching students list: %v", err)
 		utils.ResponseError(w, err, "")
 		return
 	}
 
-	response := struct {
-		Count int              `json:"count"`
-		Data  []models.Student `json:"data"`
-	}{
-		Count: len(students),
-		Data:  students,
+	// ?format=csv downloads the same list as a spreadsheet (it re-imports via
+	// POST /students). It isn't redacted, so like the import it's the office's.
+	if r.URL.Query().Get("format") == "csv" {
+		if user := currentUser(r); user == nil || (user.Role != models.RoleAdmin && user.Role != models.RoleRegistrar) {
+			utils.WriteError(w, http.StatusForbidden, "Only the office can export students as CSV")
+			return
+		}
+		writeStudentsCSV(w, students)
+		return
+	}
+
+	response := utils.NewList(students)
+	response.Query = meta
+	if opts.Page.Size > 0 {
+		if response.Total, err = h.Repo.Count(r.Context(), opts); err != nil {
+			logError(r, "Error counting students: %v", err)
+			utils.ResponseError(w, err, "")
+			return
+		}
+		response.Page = opts.Page.Number
 	}
 
 	utils.WriteJSON(w, 200, "Students fetched successfully", response)
 }
 
-func (h *StudentHandler) CreateStudents(w http.ResponseWriter, r *http.Request) {
-	var newStudents []models.Student
+func (h *StudentHandler) CountStudents(w http.ResponseWriter, r *http.Request) {
+	opts, meta, errs, err := h.studentOptionsFromQuery(r)
+	if err != nil {
+		logError(r, "Error fetching custom fields: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	if len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	count, err := h.Repo.Count(r.Context(), opts)
+	if err != nil {
+		logError(r, "Error counting students: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
 
-	decoder := json.NewDecoder(r.Body)
+	response := utils.ListMeta{Count: count, Query: meta}
 
-	decoder.DisallowUnknownFields()
+	utils.WriteJSON(w, http.StatusOK, "Students counted successfully", response)
+}
 
-	if err := decoder.Decode(&newStudents); err != nil {
-		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+func (h *StudentHandler) CountStudentsByClass(w http.ResponseWriter, r *http.Request) {
+	counts, err := h.Repo.CountByClass(r.Context())
+	if err != nil {
+		logError(r, "Error counting students by class: %v", err)
+		utils.ResponseError(w, err, "")
 		return
 	}
 
-	studentValidationErrors := models.ValidateBatch(newStudents)
+	utils.WriteJSON(w, http.StatusOK, "Students counted successfully", counts)
+}
+
+func (h *StudentHandler) GetStudentByID(w http.ResponseWriter, r *http.Request) {
+	id := utils.PathID(r, "id")
+
+	student, err := h.Repo.GetByID(r.Context(), id)
+	if err != nil {
+		logError(r, "Error fetching student %d: %v", id, err)
+		utils.ResponseError(w, err, fmt.Sprintf("Student with ID %d not found", id))
+		return
+	}
+
+	// Students carry no updated_at, so the ETag alone drives revalidation
+	utils.WriteJSONCached(w, r, http.StatusOK, "Student fetched successfully", student, time.Time{})
+}
 
+// CreateStudents takes a JSON array, or a CSV file with a header row when the
+// body is sent as text/csv (the same columns GET /students?format=csv produces)
+func (h *StudentHandler) CreateStudents(w http.ResponseWriter, r *http.Request) {
+	newStudents, ok := readStudents(w, r)
+	if !ok {
+		return
+	}
+
+	studentValidationErrors, err := h.check(r, newStudents)
+	if err != nil {
+		utils.ResponseError(w, err, "")
+		return
+	}
 	if len(studentValidationErrors) > 0 {
-		utils.WriteError(w, 400, "Validation failed", studentValidationErrors)
+		writeValidationErrors(w, r, studentValidationErrors)
 		return
 	}
+	for i := range newStudents {
+		newStudents[i].NormalizePhone()
+	}
 
 	added, err := h.Repo.CreateBulk(r.Context(), newStudents)
 	if err != nil {
-		log.Printf("Error creating students builk %v", err)
+		logError(r, "Error creating students builk %v", err)
+		writeBulkError[models.Student](w, len(newStudents), err, "")
+		return
+	}
+
+	var result models.BulkResult[models.Student]
+	for i := range added {
+		result.Add(models.BulkItem[models.Student]{ID: added[i].ID, Status: models.BulkCreated, Code: http.StatusCreated, Data: &added[i]})
+	}
+
+	h.Quotas.Check(w, r)
+	writeBulk(w, result, http.StatusCreated, "Students created successfully")
+}
+
+// ValidateStudents runs the checks of POST /students on a batch, JSON or CSV,
+// without creating anyone: POST /students/validate. It reports every problem
+// at once, emails and admission numbers taken or repeated in the batch
+// included, for the import screen.
+func (h *StudentHandler) ValidateStudents(w http.ResponseWriter, r *http.Request) {
+	students, ok := readStudents(w, r)
+	if !ok {
+		return
+	}
+
+	errs, err := h.check(r, students)
+	if err != nil {
 		utils.ResponseError(w, err, "")
 		return
 	}
+	taken, err := h.Repo.Conflicts(r.Context(), students)
+	if err != nil {
+		logError(r, "Error looking up taken student emails and admission numbers: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	errs = append(errs, uniqueErrors(len(students), taken,
+		uniqueKey{Field: "email", Name: "Email", Value: func(i int) string { return students[i].Email }},
+		uniqueKey{Field: "admission_number", Name: "AdmissionNumber", Value: func(i int) string { return students[i].AdmissionNumber }},
+	)...)
+	writeValidationReport(w, r, len(students), errs)
+}
 
-	response := struct {
-		Count int              `json:"count"`
-		Data  []models.Student `json:"data"`
-	}{
-		Count: len(added),
-		Data:  added,
+// readStudents decodes a batch of students, JSON or CSV (text/csv),
+// answering 400 if it can't
+func readStudents(w http.ResponseWriter, r *http.Request) ([]models.Student, bool) {
+	var students []models.Student
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "text/csv" {
+		var err error
+		if students, err = readStudentsCSV(r.Body); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "Invalid CSV: "+err.Error())
+			return nil, false
+		}
+	} else if err := decodeJSON(r, &students); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return nil, false
 	}
+	return students, true
+}
+
+// check runs the checks of a new batch that need no writes: struct tags,
+// dates, custom field values and the school's classes
+func (h *StudentHandler) check(r *http.Request, students []models.Student) ([]models.ValidationError, error) {
+	defs, err := h.Fields.List(r.Context(), models.CustomFieldStudent)
+	if err != nil {
+		logError(r, "Error fetching custom fields: %v", err)
+		return nil, err
+	}
+	reference, err := h.Reference.Get(r.Context())
+	if err != nil {
+		logError(r, "Error fetching reference data: %v", err)
+		return nil, err
+	}
+
+	errs := models.ValidateBatch(students)
+	today := h.Clock.Now().Format(models.DateLayout)
+	for i, student := range students {
+		checks := append(student.CheckDates(today), models.CheckCustomValues(defs, student.CustomFields, true)...)
+		checks = append(checks, reference.CheckStudent(student)...)
+		errs = append(errs, withIndex(checks, i)...)
+	}
+	return errs, nil
+}
 
-	utils.WriteJSON(w, 201, "Students created successfully", response)
+// PatchStudent changes a student's custom field values: PATCH /students/{id}.
+// Values sent replace the current ones, null removes one, and fields left out
+// keep their value. A JSON Patch (application/json-patch+json) may instead
+// add, replace, remove or test paths under /custom_fields.
+func (h *StudentHandler) PatchStudent(w http.ResponseWriter, r *http.Request) {
+	id := utils.PathID(r, "id")
+	// The route is protected; a write nobody can be audited for is refused all the same
+	if currentUser(r) == nil {
+		utils.WriteErrorCode(w, http.StatusUnauthorized, errcodes.NotLoggedIn, "You are not logged in!")
+		return
+	}
+
+	var patch models.StudentPatch
+	var jsonPatch jsonpatch.Patch
+	if isJSONPatch(r) {
+		var ok bool
+		if jsonPatch, ok = readJSONPatch(w, r); !ok {
+			return
+		}
+	} else if err := decodeJSON(r, &patch); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	} else if errs := models.ValidateOne(patch); len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	// Which paths the caller's role may patch (package policy), in both formats
+	rules, err := policy.StudentPatchRules(currentUser(r))
+	if err != nil {
+		utils.ResponseError(w, err, "")
+		return
+	}
+	ops := jsonPatch
+	if ops == nil {
+		ops = customFieldOps(patch)
+	}
+	for _, op := range ops {
+		if !rules.Allows(op.Op, op.Path) {
+			utils.WriteError(w, http.StatusForbidden, fmt.Sprintf("Your role can't %s %s", op.Op, op.Path))
+			return
+		}
+	}
+
+	student, err := h.Repo.GetByID(r.Context(), id)
+	if err != nil {
+		logError(r, "Error fetching student %d: %v", id, err)
+		utils.ResponseError(w, err, fmt.Sprintf("Student with ID %d not found", id))
+		return
+	}
+	if jsonPatch != nil {
+		if patch, err = studentPatchFromJSONPatch(*student, jsonPatch); err != nil {
+			writePatchError(w, r, err)
+			return
+		}
+	}
+	defs, err := h.Fields.List(r.Context(), models.CustomFieldStudent)
+	if err != nil {
+		logError(r, "Error fetching custom fields: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	if errs := models.CheckCustomValues(defs, patch.CustomFields, false); len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	updated, err := h.Repo.SetCustomFields(r.Context(), id, models.MergeCustomValues(student.CustomFields, patch.CustomFields), currentUserID(r))
+	if err != nil {
+		logError(r, "Error updating student %d: %v", id, err)
+		utils.ResponseError(w, err, fmt.Sprintf("Student with ID %d not found", id))
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Student updated successfully", updated)
+}
+
+// ChangeStatus moves a student along their lifecycle, e.g. suspends or
+// re-enrolls them: POST /students/{id}/status with {"status": "withdrawn",
+// "reason": "Moved abroad"}. A student re-enrolling may join another class
+// with "class". Moves package enrollment doesn't allow fail validation.
+func (h *StudentHandler) ChangeStatus(w http.ResponseWriter, r *http.Request) {
+	id := utils.PathID(r, "id")
+
+	var req models.StudentStatusRequest
+	if err := decodeJSON(r, &req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if errs := models.ValidateOne(req); len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	student, errs, err := h.Enrollment.Change(r.Context(), id, req, currentUserID(r))
+	if err != nil {
+		if !errors.Is(err, models.ErrNotFound) {
+			logError(r, "Error changing the status of student %d: %v", id, err)
+		}
+		utils.ResponseError(w, err, fmt.Sprintf("Student with ID %d not found", id))
+		return
+	}
+	if len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Student status updated", student)
 }
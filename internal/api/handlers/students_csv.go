@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"simpleapi/internal/models"
+	"strconv"
+)
+
+// studentCSVColumns is the header of the student export, and the set of columns
+// the import accepts (in any order). id is export-only.
+var studentCSVColumns = []string{
+	"id", "first_name", "last_name", "email", "class", "admission_number", "guardian_phone", "status",
+	"date_of_birth", "gender", "nationality", "enrollment_date",
+}
+
+// studentCSVField points a column at its field
+func studentCSVField(s *models.Student, column string) *string {
+	switch column {
+	case "first_name":
+		return &s.FirstName
+	case "last_name":
+		return &s.LastName
+	case "email":
+		return &s.Email
+	case "class":
+		return &s.Class
+	case "admission_number":
+		return &s.AdmissionNumber
+	case "guardian_phone":
+		return &s.GuardianPhone
+	case "status":
+		return &s.Status
+	case "date_of_birth":
+		return &s.DateOfBirth
+	case "gender":
+		return &s.Gender
+	case "nationality":
+		return &s.Nationality
+	case "enrollment_date":
+		return &s.EnrollmentDate
+	}
+	return nil
+}
+
+func writeStudentsCSV(w http.ResponseWriter, students []models.Student) {
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="students.csv"`)
+	w.WriteHeader(http.StatusOK)
+
+	out := csv.NewWriter(w)
+	out.Write(studentCSVColumns)
+	for _, s := range students {
+		record := make([]string, len(studentCSVColumns))
+		record[0] = strconv.Itoa(s.ID)
+		for i, column := range studentCSVColumns[1:] {
+			record[i+1] = *studentCSVField(&s, column)
+		}
+		out.Write(record)
+	}
+	out.Flush()
+}
+
+// readStudentsCSV parses an import. Unknown columns are rejected, like unknown
+// JSON fields; an id column is ignored so an export can be edited and re-imported.
+func readStudentsCSV(body io.Reader) ([]models.Student, error) {
+	in := csv.NewReader(body)
+	in.TrimLeadingSpace = true
+
+	header, err := in.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("missing header row")
+	}
+	if err != nil {
+		return nil, err
+	}
+	var probe models.Student
+	for _, column := range header {
+		if column != "id" && studentCSVField(&probe, column) == nil {
+			return nil, fmt.Errorf("unknown column %q", column)
+		}
+	}
+
+	students := make([]models.Student, 0)
+	for {
+		record, err := in.Read()
+		if errors.Is(err, io.EOF) {
+			return students, nil
+		}
+		if err != nil {
+			return nil, err // csv.ParseError already names the line
+		}
+		var s models.Student
+		for i, column := range header {
+			if field := studentCSVField(&s, column); field != nil {
+				*field = record[i]
+			}
+		}
+		students = append(students, s)
+	}
+}
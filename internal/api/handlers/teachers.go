@@ -1,26 +1,62 @@
 package handlers
 
 import (
-	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"net/http"
+	"simpleapi/internal/breach"
+	"simpleapi/internal/loginguard"
+	"simpleapi/internal/metrics"
 	"simpleapi/internal/models"
+	"simpleapi/internal/query"
 	"simpleapi/internal/repository"
+	"simpleapi/pkg/clock"
+	"simpleapi/pkg/errcodes"
+	"simpleapi/pkg/redact"
 	"simpleapi/pkg/utils"
+	"slices"
 	"strconv"
 	"time"
 )
 
 // TeacherHandler holds the dependencies for these HTTP endpoints
 type TeacherHandler struct {
-	Repo *repository.TeacherRepository
+	Repo      repository.TeacherStore
+	Reference repository.ReferenceStore
+	Breaches  breach.Checker    // nil unless PASSWORD_BREACH_CHECK is on
+	Logins    *loginguard.Guard // nil when LOGIN_BACKOFF is off
+	Passwords utils.PasswordHashing
+	Tokens    utils.Tokens
+	Cookies   *utils.CookieManager
+	Delivery  utils.TokenDelivery
+	Clock     clock.Clock
 }
 
 // NewTeacherHandler is the constructor
-func NewTeacherHandler(repo *repository.TeacherRepository) *TeacherHandler {
-	return &TeacherHandler{Repo: repo}
+func NewTeacherHandler(repo repository.TeacherStore, reference repository.ReferenceStore, breaches breach.Checker, logins *loginguard.Guard,
+	passwords utils.PasswordHashing, tokens utils.Tokens, cookies *utils.CookieManager, delivery utils.TokenDelivery, clk clock.Clock) *TeacherHandler {
+	return &TeacherHandler{Repo: repo, Reference: reference, Breaches: breaches, Logins: logins, Passwords: passwords,
+		Tokens: tokens, Cookies: cookies, Delivery: delivery, Clock: clk}
+}
+
+// checkReference runs check against the school's classes and subjects. It
+// answers the request itself and returns false when the values are rejected
+// or the lists can't be read.
+func (h *TeacherHandler) checkReference(w http.ResponseWriter, r *http.Request, check func(models.ReferenceData) []models.ValidationError) bool {
+	reference, err := h.Reference.Get(r.Context())
+	if err != nil {
+		logError(r, "Error fetching reference data: %v", err)
+		utils.ResponseError(w, err, "")
+		return false
+	}
+	if errs := check(*reference); len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return false
+	}
+	return true
 }
 
 // --- HANDLERS ---
@@ -40,10 +76,14 @@ func (h *TeacherHandler) RegisterTeacher(w http.ResponseWriter, r *http.Request)
 		utils.WriteError(w, 400, "Validation Failed", errors)
 		return
 	}
+	if !h.checkReference(w, r, func(d models.ReferenceData) []models.ValidationError { return d.CheckTeacher(newTeacher) }) {
+		return
+	}
+	newTeacher.NormalizePhone()
 
 	// --- 3. THE SECURITY STEP ---
 	// Hash the password before it ever touches the database layer
-	hashedPwd, err := utils.HashPassword(newTeacher.Password)
+	hashedPwd, err := h.Passwords.HashPassword(newTeacher.Password)
 	if err != nil {
 		log.Println(err)
 		utils.WriteError(w, 500, "Server error processing credentials")
@@ -70,11 +110,14 @@ func (h *TeacherHandler) RegisterTeacher(w http.ResponseWriter, r *http.Request)
 	}{
 		Data: added[0],
 	}
+	// Whoever registered sees their own account, as the teacher the repository
+	// stored, not the role the request body asked for
+	w = utils.ForViewer(w, redact.Viewer{ID: added[0].ID, Role: models.RoleTeacher})
 	utils.WriteJSON(w, 201, "Registration successful", response)
 }
 
 func (h *TeacherHandler) LoginTeacher(w http.ResponseWriter, r *http.Request) {
-	var req models.Teacher
+	var req models.LoginRequest
 	// Data Validation
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -88,15 +131,20 @@ func (h *TeacherHandler) LoginTeacher(w http.ResponseWriter, r *http.Request) {
 	// 	return
 	// }
 
-	var teacher models.Teacher
+	// Accounts that failed too often wait, or prove they're human, before the
+	// password is even looked at
+	guard, ok := h.guardLogin(w, r, req)
+	if !ok {
+		return
+	}
+
 	// Search for user if user actually exists
-	query := "SELECT id, first_name, last_name, password_hash, is_active, role FROM teachers WHERE email = ?"
-	err := h.Repo.DB.QueryRow(query, req.Email).Scan(&teacher.ID, &teacher.FirstName, &teacher.LastName, &teacher.PasswordHash, &teacher.IsActive, &teacher.Role)
+	teacher, err := h.Repo.GetByEmail(r.Context(), req.Email)
 
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, models.ErrNotFound) {
 			log.Println(err)
-			utils.WriteError(w, 401, "Invalid email or password")
+			h.loginFailed(w, r, req.Email, guard)
 			return
 		}
 		log.Println(err)
@@ -106,215 +154,513 @@ func (h *TeacherHandler) LoginTeacher(w http.ResponseWriter, r *http.Request) {
 
 	// is user active
 	if !teacher.IsActive {
-		utils.WriteError(w, 403, "Account is deactived. Please contact support")
+		metrics.AuthEvents.RecordEvent(metrics.AuthEvent{Event: metrics.AuthLoginDeactivated, IP: utils.ClientIP(r), Email: req.Email, TeacherID: teacher.ID})
+		utils.WriteErrorCode(w, 403, errcodes.AccountDeactivated, "Account is deactived. Please contact support")
 		return
 	}
 	// verify password
-	newHash, didUpgrade, err := utils.UpgradeHashIfNeeded(req.Password, teacher.PasswordHash)
+	newHash, didUpgrade, err := h.Passwords.UpgradeHashIfNeeded(req.Password, teacher.PasswordHash)
 	if err != nil {
 		log.Println(err)
-		utils.WriteError(w, 401, "Invalid email or password")
+		h.loginFailed(w, r, req.Email, guard)
 		return
 	}
+	if h.Logins != nil && guard.Failures > 0 {
+		if err := h.Logins.Succeed(r.Context(), req.Email); err != nil {
+			logError(r, "Error clearing failed logins of teacher %d: %v", teacher.ID, err)
+		}
+	}
 	//  If security parameters were updated, save the new hash to DB
 	if didUpgrade {
-		_, _ = h.Repo.DB.Exec("UPDATE teachers SET password_hash = ? WHERE id = ?", newHash, teacher.ID)
+		_ = h.Repo.UpdatePasswordHash(r.Context(), teacher.ID, newHash)
 		// We don't block login if the upgrade-save fails, but in production, log this.
 	}
-	// Generate Token
-	token, err := utils.GenerateJWT(strconv.Itoa(teacher.ID), teacher.Role)
-	if err != nil {
-		utils.WriteError(w, 500, "Failed to create session")
+	// A breached password still logs in, but only to change it (see Protect).
+	// The check failing (say HIBP is down) must not lock anyone out.
+	if !teacher.ForcePasswordChange && h.Breaches != nil {
+		breached, err := h.Breaches.Breached(r.Context(), req.Password)
+		if err != nil {
+			logError(r, "Error checking teacher %d's password against %s: %v", teacher.ID, h.Breaches.Name(), err)
+		} else if breached {
+			if err := h.Repo.RequirePasswordChange(r.Context(), teacher.ID, "breached"); err != nil {
+				logError(r, "Error flagging teacher %d's breached password: %v", teacher.ID, err)
+			} else {
+				teacher.ForcePasswordChange = true
+			}
+		}
 	}
-	http.SetCookie(w, &http.Cookie{
-		Name:     "session_token",
-		Value:    token,
-		HttpOnly: true,                 // Prevents JavaScript (XSS) access
-		Secure:   true,                 // Only sent over HTTPS
-		SameSite: http.SameSiteLaxMode, // Prevents CSRF
-		Path:     "/",
-		Expires:  time.Now().Add(24 * time.Hour),
-	})
-
-	// Send token as a response or as a cookie-
+	token, bearer, ok := h.startSession(w, r, teacher)
+	if !ok {
+		return
+	}
+	metrics.AuthEvents.RecordEvent(metrics.AuthEvent{Event: metrics.AuthLogin, IP: utils.ClientIP(r), Email: req.Email, TeacherID: teacher.ID})
+
 	// Define and initialize the anonymous struct in one go
 	response := struct {
-		Token string `json:"token"`
-		User  struct {
-			ID        int    `json:"id"`
-			FirstName string `json:"first_name"`
-			LastName  string `json:"last_name"`
-			Role      string `json:"role"`
+		Token     string `json:"token,omitempty"`
+		TokenType string `json:"token_type,omitempty"`
+		User      struct {
+			ID                  int    `json:"id"`
+			FirstName           string `json:"first_name"`
+			LastName            string `json:"last_name"`
+			Role                string `json:"role"`
+			ForcePasswordChange bool   `json:"force_password_change"`
 		} `json:"user"`
 	}{
-		Token: token,
 		User: struct {
-			ID        int    `json:"id"`
-			FirstName string `json:"first_name"`
-			LastName  string `json:"last_name"`
-			Role      string `json:"role"`
+			ID                  int    `json:"id"`
+			FirstName           string `json:"first_name"`
+			LastName            string `json:"last_name"`
+			Role                string `json:"role"`
+			ForcePasswordChange bool   `json:"force_password_change"`
 		}{
-			ID:        teacher.ID,
-			FirstName: teacher.FirstName,
-			LastName:  teacher.LastName,
-			Role:      teacher.Role,
+			ID:                  teacher.ID,
+			FirstName:           teacher.FirstName,
+			LastName:            teacher.LastName,
+			Role:                teacher.Role,
+			ForcePasswordChange: teacher.ForcePasswordChange,
 		},
 	}
 
+	if bearer {
+		response.Token, response.TokenType = token, "Bearer"
+	}
+
 	utils.WriteJSON(w, 200, "Login successfully", response)
 }
 
+// guardLogin refuses a login while the account must wait after failed ones,
+// or when it needs a CAPTCHA and brings none that passes. It returns the
+// account's state for loginFailed. The guard failing must not lock anyone
+// out, so an unreadable state lets the login through.
+func (h *TeacherHandler) guardLogin(w http.ResponseWriter, r *http.Request, req models.LoginRequest) (loginguard.State, bool) {
+	if h.Logins == nil {
+		return loginguard.State{}, true
+	}
+	state, err := h.Logins.Check(r.Context(), req.Email)
+	if err != nil {
+		logError(r, "Error reading failed logins: %v", err)
+		return loginguard.State{}, true
+	}
+	if wait := h.Logins.Wait(state); wait > 0 {
+		metrics.AuthEvents.RecordEvent(metrics.AuthEvent{Event: metrics.AuthLoginThrottled, IP: utils.ClientIP(r), Email: req.Email})
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		utils.WriteErrorCode(w, http.StatusTooManyRequests, errcodes.LoginThrottled, "Too many failed logins, try again later")
+		return state, false
+	}
+	if h.Logins.NeedsCaptcha(state) {
+		passed, err := h.Logins.Captcha.Verify(r.Context(), req.CaptchaToken, utils.ClientIP(r))
+		if err != nil {
+			logError(r, "Error verifying CAPTCHA with %s: %v", h.Logins.Captcha.Name(), err)
+			utils.WriteError(w, http.StatusServiceUnavailable, "Could not verify the CAPTCHA, try again")
+			return state, false
+		}
+		if !passed {
+			metrics.AuthEvents.RecordEvent(metrics.AuthEvent{Event: metrics.AuthLoginThrottled, IP: utils.ClientIP(r), Email: req.Email})
+			utils.WriteErrorCode(w, http.StatusUnauthorized, errcodes.CaptchaRequired, "Solve the CAPTCHA to log in")
+			return state, false
+		}
+	}
+	return state, true
+}
+
+// loginFailed answers a wrong email or password and counts it against the account
+func (h *TeacherHandler) loginFailed(w http.ResponseWriter, r *http.Request, email string, state loginguard.State) {
+	metrics.AuthEvents.RecordEvent(metrics.AuthEvent{Event: metrics.AuthLoginFailed, IP: utils.ClientIP(r), Email: email})
+	if h.Logins != nil {
+		if _, err := h.Logins.Fail(r.Context(), email, state); err != nil {
+			logError(r, "Error recording a failed login: %v", err)
+		}
+	}
+	utils.WriteErrorCode(w, 401, errcodes.InvalidCredentials, "Invalid email or password")
+}
+
+// startSession mints the teacher's token and, for browsers, sets the session
+// cookie, plus the refresh cookie for the web app. bearer says the token goes
+// in the response body instead. It answers the request itself when ok is false.
+func (h *TeacherHandler) startSession(w http.ResponseWriter, r *http.Request, teacher *models.Teacher) (token string, bearer, ok bool) {
+	aud := utils.AudienceFor(r)
+	token, err := h.Tokens.Sign(strconv.Itoa(teacher.ID), teacher.Role, aud)
+	if err != nil {
+		utils.WriteError(w, 500, "Failed to create session")
+		return "", false, false
+	}
+
+	// One channel per login: API clients get the token in the body, browsers only
+	// ever see it as an HttpOnly cookie (see utils.TokenDelivery)
+	if h.Delivery.Negotiates() {
+		w.Header().Add("Vary", "Accept, X-Client-Type")
+	}
+	bearer = h.Delivery.DeliverAsBearer(r)
+	if !bearer {
+		now := h.Clock.Now()
+		h.Cookies.SetAccess(w, token, now.Add(24*time.Hour))
+		// A refresh would always mint a web token, so kiosks don't get one
+		if aud == utils.AudienceWeb {
+			refresh, err := h.Tokens.Sign(strconv.Itoa(teacher.ID), teacher.Role, utils.AudienceRefresh)
+			if err != nil {
+				utils.WriteError(w, 500, "Failed to create session")
+				return "", false, false
+			}
+			h.Cookies.SetRefresh(w, refresh, now.Add(h.Tokens.TTL(utils.AudienceRefresh)))
+		}
+	}
+	return token, bearer, true
+}
+
+// Refresh trades the refresh cookie for a new access token cookie, so the web
+// app stays signed in past the access token's short life: POST /refresh.
+// It checks the account like Protect does, so a deactivated account or a
+// changed password ends the session here too.
+func (h *TeacherHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	refresh := h.Cookies.RefreshToken(r)
+	if refresh == "" || !h.Delivery.AcceptsCookieToken() {
+		utils.WriteErrorCode(w, http.StatusUnauthorized, errcodes.NotLoggedIn, "You are not logged in!")
+		return
+	}
+	claims, err := h.Tokens.Verify(refresh, utils.AudienceRefresh)
+	if err != nil {
+		h.Cookies.Clear(w)
+		utils.WriteErrorCode(w, http.StatusUnauthorized, errcodes.TokenInvalid, "Invalid or expired session, log in again")
+		return
+	}
+
+	id, _ := strconv.Atoi(claims.UserID)
+	teacher, err := h.Repo.GetByID(r.Context(), id)
+	if errors.Is(err, models.ErrNotFound) {
+		h.Cookies.Clear(w)
+		utils.WriteErrorCode(w, http.StatusUnauthorized, errcodes.AccountGone, "The user belonging to this token no longer exists.")
+		return
+	}
+	if err != nil {
+		logError(r, "Error fetching teacher %d for a refresh: %v", id, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	if !teacher.IsActive {
+		h.Cookies.Clear(w)
+		utils.WriteErrorCode(w, http.StatusUnauthorized, errcodes.AccountDeactivated, "Account is deactivated. Please contact support")
+		return
+	}
+	if claims.IssuedAt != nil && teacher.ChangedPasswordAfter(claims.IssuedAt.Time.Unix()) {
+		h.Cookies.Clear(w)
+		utils.WriteErrorCode(w, http.StatusUnauthorized, errcodes.PasswordChanged, "User recently changed password! Please log in again.")
+		return
+	}
+
+	token, err := h.Tokens.Sign(strconv.Itoa(teacher.ID), teacher.Role, utils.AudienceWeb)
+	if err != nil {
+		utils.WriteError(w, 500, "Failed to refresh session")
+		return
+	}
+	h.Cookies.SetAccess(w, token, h.Clock.Now().Add(24*time.Hour))
+	utils.WriteJSON(w, http.StatusOK, "Session refreshed", nil)
+}
+
+// UpdatePassword changes the signed-in teacher's password: PATCH
+// /update-password. It is the one route open to accounts that must change
+// their password. Every other session of the account ends; this one gets a
+// new token.
+func (h *TeacherHandler) UpdatePassword(w http.ResponseWriter, r *http.Request) {
+	var req models.PasswordChange
+	if err := decodeJSON(r, &req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if errs := models.ValidateOne(req); len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	user := currentUser(r)
+	teacher, err := h.Repo.GetByEmail(r.Context(), user.Email)
+	if err != nil {
+		logError(r, "Error fetching teacher %d: %v", user.ID, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	if ok, err := h.Passwords.CheckPassword(req.CurrentPassword, teacher.PasswordHash); err != nil || !ok {
+		writeValidationErrors(w, r, []models.ValidationError{models.RuleError("CurrentPassword", "password_incorrect", "")})
+		return
+	}
+	if req.NewPassword == req.CurrentPassword {
+		writeValidationErrors(w, r, []models.ValidationError{models.RuleError("NewPassword", "password_unchanged", "")})
+		return
+	}
+	if h.Breaches != nil {
+		breached, err := h.Breaches.Breached(r.Context(), req.NewPassword)
+		if err != nil {
+			logError(r, "Error checking a new password against %s: %v", h.Breaches.Name(), err)
+		} else if breached {
+			writeValidationErrors(w, r, []models.ValidationError{models.RuleError("NewPassword", "password_breached", "")})
+			return
+		}
+	}
+
+	hash, err := h.Passwords.HashPassword(req.NewPassword)
+	if err != nil {
+		logError(r, "Error hashing a new password: %v", err)
+		utils.WriteError(w, http.StatusInternalServerError, "Server error processing credentials")
+		return
+	}
+	// Whole seconds, like the token's iat: the new session must not count as
+	// issued before the change
+	if err := h.Repo.ChangePassword(r.Context(), teacher.ID, hash, h.Clock.Now().Truncate(time.Second)); err != nil {
+		logError(r, "Error changing the password of teacher %d: %v", teacher.ID, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+
+	token, bearer, ok := h.startSession(w, r, teacher)
+	if !ok {
+		return
+	}
+	response := struct {
+		Token     string `json:"token,omitempty"`
+		TokenType string `json:"token_type,omitempty"`
+	}{}
+	if bearer {
+		response.Token, response.TokenType = token, "Bearer"
+	}
+	utils.WriteJSON(w, http.StatusOK, "Password updated successfully", response)
+}
+
 func (h *TeacherHandler) Logout(w http.ResponseWriter, r *http.Request) {
-	// 1. Create a cookie with the exact same Name and Path
-	// 2. Set the Value to empty
-	// 3. Set the Expiry to the past (Unix epoch time 0)
-	// 4. Set MaxAge to -1 (Force deletion)
-	http.SetCookie(w, &http.Cookie{
-		Name:     "session_token",
-		Value:    "",
-		Path:     "/",
-		Expires:  time.Unix(0, 0),
-		MaxAge:   -1,
-		HttpOnly: true,
-		Secure:   true,
-		SameSite: http.SameSiteLaxMode,
-	})
+	// Expire both cookies with the exact same Name, Domain and Path they were set with
+	h.Cookies.Clear(w)
 
 	utils.WriteJSON(w, 200, "Logged out successfully", nil)
 }
 
+// teacherOptionsFromQuery keeps list and count endpoints on the same filters.
+// ?phone= is normalized like stored numbers, so "0803 123 4567" finds "+2348031234567".
+// The meta echoes the filters as applied, for the response's "query" member.
+func teacherOptionsFromQuery(r *http.Request) (query.Options, *utils.QueryMeta, []models.ValidationError) {
+	var filter models.TeacherFilter
+	if errs := utils.BindQuery(r, &filter); len(errs) > 0 {
+		return query.Options{}, nil, errs
+	}
+	filter.Phone, _ = models.ParsePhone(filter.Phone) // Already validated
+	var errs []models.ValidationError
+	if filter.Where, errs = parseFilter(filter.Expr, models.TeacherFields); len(errs) > 0 {
+		return query.Options{}, nil, errs
+	}
+	meta := utils.EchoQuery(r, &filter, "page", "per_page", "fields")
+	meta.ApplySort(&filter.SortBy, &filter.SortOrder, models.TeacherSorts)
+	opts, errs := listOptions(r, filter.Condition(), meta, models.TeacherListFields)
+	return opts, meta, errs
+}
+
 func (h *TeacherHandler) GetTeachers(w http.ResponseWriter, r *http.Request) {
-	filter := models.TeacherFilter{
-		FirstName: r.URL.Query().Get("first_name"),
-		LastName:  r.URL.Query().Get("last_name"),
-		Email:     r.URL.Query().Get("email"),
-		Class:     r.URL.Query().Get("class"),
-		Subject:   r.URL.Query().Get("subject"),
-		SortBy:    r.URL.Query().Get("sortby"),
-		SortOrder: r.URL.Query().Get("order"),
+	opts, meta, errs := teacherOptionsFromQuery(r)
+	if len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
 	}
 
-	teachers, err := h.Repo.GetAll(r.Context(), filter)
+	teachers, err := h.Repo.GetAll(r.Context(), opts)
 	if err != nil {
 		// Log the internal error details for the developer
-		log.Printf("Error fetching teachers list: %v", err)
+		logError(r, "Error fetching teachers list: %v", err)
 		utils.ResponseError(w, err, "")
 		return
 	}
 
-	response := struct {
-		Count int              `json:"count"`
-		Data  []models.Teacher `json:"data"`
-	}{
-		Count: len(teachers),
-		Data:  teachers,
+	response := utils.NewList(teachers)
+	response.Query = meta
+	if opts.Page.Size > 0 {
+		if response.Total, err = h.Repo.Count(r.Context(), opts); err != nil {
+			logError(r, "Error counting teachers: %v", err)
+			utils.ResponseError(w, err, "")
+			return
+		}
+		response.Page = opts.Page.Number
 	}
 
 	// util automatically adds "status": "success"
 	utils.WriteJSON(w, http.StatusOK, "Teachers fetched successfully", response)
 }
 
-func (h *TeacherHandler) GetTeacherByID(w http.ResponseWriter, r *http.Request) {
-	idStr := r.PathValue("id")
-	id, err := strconv.Atoi(idStr)
+func (h *TeacherHandler) CountTeachers(w http.ResponseWriter, r *http.Request) {
+	opts, meta, errs := teacherOptionsFromQuery(r)
+	if len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	count, err := h.Repo.Count(r.Context(), opts)
 	if err != nil {
-		utils.WriteError(w, http.StatusBadRequest, "Invalid teacher ID")
+		logError(r, "Error counting teachers: %v", err)
+		utils.ResponseError(w, err, "")
 		return
 	}
 
+	response := utils.ListMeta{Count: count, Query: meta}
+
+	utils.WriteJSON(w, http.StatusOK, "Teachers counted successfully", response)
+}
+
+func (h *TeacherHandler) GetTeacherByID(w http.ResponseWriter, r *http.Request) {
+	id := utils.PathID(r, "id")
+
 	teacher, err := h.Repo.GetByID(r.Context(), id)
 	if err != nil {
 		// Log the error (could be "Not Found" or "DB Connection Failed")
-		log.Printf("Error fetching teacher %d: %v", id, err)
+		logError(r, "Error fetching teacher %d: %v", id, err)
 
 		// If 404, util sends 404. If DB crash, util sends 500.
 		utils.ResponseError(w, err, fmt.Sprintf("Teacher with ID %d not found", id))
 		return
 	}
 
-	utils.WriteJSON(w, http.StatusOK, "Teacher fetched successfully", teacher)
+	// Conditional GET: the mobile app re-fetches profiles a lot, let it revalidate with a 304
+	utils.WriteJSONCached(w, r, http.StatusOK, "Teacher fetched successfully", teacher, teacher.UpdatedAt)
 }
 
 func (h *TeacherHandler) CreateTeachers(w http.ResponseWriter, r *http.Request) {
-	var newTeachers []models.Teacher
-
-	decoder := json.NewDecoder(r.Body)
-
-	decoder.DisallowUnknownFields()
-
-	if err := decoder.Decode(&newTeachers); err != nil {
-		utils.WriteError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+	newTeachers, ok := readTeachers(w, r)
+	if !ok {
 		return
 	}
 
 	teacherValidationErrors := models.ValidateBatch(newTeachers)
 
 	if len(teacherValidationErrors) > 0 {
-		utils.WriteError(w, http.StatusBadRequest, "Validation failed", teacherValidationErrors)
+		writeValidationErrors(w, r, teacherValidationErrors)
 		return
 	}
+	if !h.checkReference(w, r, func(d models.ReferenceData) (errs []models.ValidationError) {
+		for i, t := range newTeachers {
+			errs = append(errs, withIndex(d.CheckTeacher(t), i)...)
+		}
+		return errs
+	}) {
+		return
+	}
+	for i := range newTeachers {
+		newTeachers[i].NormalizePhone()
+	}
 
 	added, err := h.Repo.CreateBulk(r.Context(), newTeachers)
 	if err != nil {
-		log.Printf("Error creating teachers bulk: %v", err)
-		utils.ResponseError(w, err, "")
+		logError(r, "Error creating teachers bulk: %v", err)
+		writeBulkError[models.Teacher](w, len(newTeachers), err, "")
 		return
 	}
 
-	response := struct {
-		Count int              `json:"count"`
-		Data  []models.Teacher `json:"data"`
-	}{
-		Count: len(added),
-		Data:  added,
+	var result models.BulkResult[models.Teacher]
+	for i := range added {
+		result.Add(models.BulkItem[models.Teacher]{ID: added[i].ID, Status: models.BulkCreated, Code: http.StatusCreated, Data: &added[i]})
 	}
 
-	utils.WriteJSON(w, http.StatusCreated, "Teachers created successfully", response)
+	writeBulk(w, result, http.StatusCreated, "Teachers created successfully")
 }
 
-func (h *TeacherHandler) UpdateTeacherFull(w http.ResponseWriter, r *http.Request) {
+// ValidateTeachers runs the checks of POST /teachers on a batch without
+// creating anyone: POST /teachers/validate. It reports every problem at once,
+// emails taken or repeated in the batch included, for the import screen.
+func (h *TeacherHandler) ValidateTeachers(w http.ResponseWriter, r *http.Request) {
+	teachers, ok := readTeachers(w, r)
+	if !ok {
+		return
+	}
 
-	idStr := r.PathValue("id")
-	id, err := strconv.Atoi(idStr)
+	reference, err := h.Reference.Get(r.Context())
 	if err != nil {
-		utils.WriteError(w, http.StatusBadRequest, "Invalid teacher ID")
+		logError(r, "Error fetching reference data: %v", err)
+		utils.ResponseError(w, err, "")
 		return
 	}
+	taken, err := h.Repo.Conflicts(r.Context(), teachers)
+	if err != nil {
+		logError(r, "Error looking up taken teacher emails: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+
+	errs := models.ValidateBatch(teachers)
+	for i, t := range teachers {
+		errs = append(errs, withIndex(reference.CheckTeacher(t), i)...)
+	}
+	errs = append(errs, uniqueErrors(len(teachers), taken,
+		uniqueKey{Field: "email", Name: "Email", Value: func(i int) string { return teachers[i].Email }},
+	)...)
+	writeValidationReport(w, r, len(teachers), errs)
+}
+
+// readTeachers decodes a batch of teachers, answering 400 if it can't
+func readTeachers(w http.ResponseWriter, r *http.Request) ([]models.Teacher, bool) {
+	var teachers []models.Teacher
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&teachers); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body: "+err.Error())
+		return nil, false
+	}
+	return teachers, true
+}
+
+func (h *TeacherHandler) UpdateTeacherFull(w http.ResponseWriter, r *http.Request) {
+
+	id := utils.PathID(r, "id")
 
 	var updatedTeacher models.Teacher
 	if err := json.NewDecoder(r.Body).Decode(&updatedTeacher); err != nil {
 		utils.WriteError(w, http.StatusBadRequest, "Invalid request payload")
 		return
 	}
+	if !h.checkReference(w, r, func(d models.ReferenceData) []models.ValidationError { return d.CheckTeacher(updatedTeacher) }) {
+		return
+	}
 
-	result, err := h.Repo.UpdateFull(r.Context(), id, updatedTeacher)
+	result, err := h.Repo.UpdateFull(r.Context(), id, updatedTeacher, currentUserID(r))
 	if err != nil {
 		log.Printf("Error updating teacher %d: %v", id, err)
-		utils.ResponseError(w, err, fmt.Sprintf("Teacher with ID %d not found", id))
+		message := ""
+		if errors.Is(err, models.ErrNotFound) {
+			message = fmt.Sprintf("Teacher with ID %d not found", id)
+		}
+		utils.ResponseError(w, err, message)
 		return
 	}
 
 	utils.WriteJSON(w, http.StatusOK, "Teacher updated successfully", result)
 }
 
+// PatchTeacher changes some of a teacher's fields: PATCH /teachers/{id}, with
+// a map of field to new value, or a JSON Patch (application/json-patch+json)
+// on the same fields (see teacherPatchRules)
 func (h *TeacherHandler) PatchTeacher(w http.ResponseWriter, r *http.Request) {
-	idStr := r.PathValue("id")
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		utils.WriteError(w, http.StatusBadRequest, "Invalid teacher ID")
-		return
-	}
+	id := utils.PathID(r, "id")
 
 	var updates map[string]interface{}
-	if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
+	if isJSONPatch(r) {
+		p, ok := readJSONPatch(w, r)
+		if !ok {
+			return
+		}
+		teacher, err := h.Repo.GetByID(r.Context(), id)
+		if err != nil {
+			logError(r, "Error fetching teacher %d: %v", id, err)
+			utils.ResponseError(w, err, "")
+			return
+		}
+		if updates, err = teacherPatchUpdates(*teacher, p); err != nil {
+			writePatchError(w, r, err)
+			return
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(&updates); err != nil {
 		utils.WriteError(w, http.StatusBadRequest, "Invalid request payload")
 		return
 	}
+	if !h.checkReference(w, r, func(d models.ReferenceData) []models.ValidationError { return d.CheckTeacherUpdates(updates) }) {
+		return
+	}
 
-	result, err := h.Repo.Patch(r.Context(), id, updates)
+	result, err := h.Repo.Patch(r.Context(), id, updates, currentUserID(r))
 	if err != nil {
 		// Log error (includes validation errors from Repo or DB errors)
-		log.Printf("Error patching teacher %d: %v", id, err)
+		logError(r, "Error patching teacher %d: %v", id, err)
 
 		// This handles both "Not Found" AND "Invalid Input"
 		utils.ResponseError(w, err, "")
@@ -330,33 +676,52 @@ func (h *TeacherHandler) BulkPatchTeachers(w http.ResponseWriter, r *http.Reques
 		utils.WriteError(w, http.StatusBadRequest, "Invalid request payload")
 		return
 	}
+	if !h.checkReference(w, r, func(d models.ReferenceData) (errs []models.ValidationError) {
+		for i, u := range updates {
+			errs = append(errs, withIndex(d.CheckTeacherUpdates(u), i)...)
+		}
+		return errs
+	}) {
+		return
+	}
 
-	updatedIds, err := h.Repo.BulkPatch(r.Context(), updates)
+	dryRun := isDryRun(r)
+	changes, err := h.Repo.BulkPatch(r.Context(), updates, currentUserID(r), dryRun)
 	if err != nil {
-		log.Printf("Error during bulk patch: %v", err)
-		utils.ResponseError(w, err, "Bulk patch failed")
+		logError(r, "Error during bulk patch: %v", err)
+		writeBulkError[models.TeacherChange](w, len(updates), err, "Bulk patch failed")
 		return
 	}
 
-	response := map[string]interface{}{
-		"message":     fmt.Sprintf("Successfully updated %d teachers", len(updatedIds)),
-		"updated_ids": updatedIds,
+	// In a dry run each item's data is the change that would be made
+	result := models.BulkResult[models.TeacherChange]{DryRun: dryRun}
+	for i := range changes {
+		result.Add(models.BulkItem[models.TeacherChange]{ID: changes[i].ID, Status: models.BulkUpdated, Code: http.StatusOK, Data: &changes[i]})
 	}
 
-	utils.WriteJSON(w, http.StatusOK, "Teachers updated successfully", response)
+	message := "Teachers updated successfully"
+	if dryRun {
+		message = "Dry run: no teachers were updated"
+	}
+	writeBulk(w, result, http.StatusOK, message)
 }
 
 func (h *TeacherHandler) DeleteTeacher(w http.ResponseWriter, r *http.Request) {
-	idStr := r.PathValue("id")
-	id, err := strconv.Atoi(idStr)
-	if err != nil {
-		utils.WriteError(w, http.StatusBadRequest, "Invalid teacher ID")
-		return
+	id := utils.PathID(r, "id")
+
+	// Cascade policy: block if the class would be orphaned, unless ?reassign_to= names a successor
+	opts := models.DeleteTeacherOptions{ActorID: currentUserID(r)}
+	if reassign := r.URL.Query().Get("reassign_to"); reassign != "" {
+		var err error
+		if opts.ReassignTo, err = utils.ParseID(reassign); err != nil {
+			utils.WriteErrorCode(w, http.StatusBadRequest, errcodes.InvalidID, "Invalid reassign_to teacher ID")
+			return
+		}
 	}
 
-	deleted, err := h.Repo.Delete(r.Context(), id)
+	deleted, err := h.Repo.Delete(r.Context(), id, opts)
 	if err != nil {
-		log.Printf("Error deleting teacher %d: %v", id, err)
+		logError(r, "Error deleting teacher %d: %v", id, err)
 		utils.ResponseError(w, err, "")
 		return
 	}
@@ -364,7 +729,7 @@ func (h *TeacherHandler) DeleteTeacher(w http.ResponseWriter, r *http.Request) {
 	// If Delete returns false, it means 0 rows affected (Not Found)
 	if !deleted {
 		// No need to log here (it's just a user mistake), but you can if you want
-		utils.WriteError(w, http.StatusNotFound, "Teacher not found")
+		utils.WriteErrorCode(w, http.StatusNotFound, errcodes.TeacherNotFound, "Teacher not found")
 		return
 	}
 
@@ -383,70 +748,84 @@ func (h *TeacherHandler) BulkDeleteTeachers(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
-	validIds, err := h.Repo.BulkDelete(r.Context(), ids)
+	dryRun := isDryRun(r)
+	validIds, err := h.Repo.BulkDelete(r.Context(), ids, currentUserID(r), dryRun)
 	if err != nil {
-		log.Printf("Error during bulk delete: %v", err)
+		logError(r, "Error during bulk delete: %v", err)
 		utils.ResponseError(w, err, "Bulk delete failed")
 		return
 	}
 
-	if len(validIds) == 0 {
-		utils.WriteError(w, http.StatusNotFound, "None of the provided IDs exist")
-		return
-	}
-
-	response := struct {
-		DeletedIDs []int `json:"deleted_ids"`
-	}{
-		DeletedIDs: validIds,
+	// Unknown IDs don't stop the others from being deleted, so this can be a mixed outcome
+	result := idBulkResult(ids, validIds, models.BulkDeleted)
+	result.DryRun = dryRun
+	message := "Teachers deleted successfully"
+	switch {
+	case result.Succeeded == 0:
+		message = "None of the provided IDs exist"
+	case dryRun:
+		message = "Dry run: no teachers were deleted"
 	}
-
-	utils.WriteJSON(w, http.StatusOK, "Teachers deleted successfully", response)
+	writeBulk(w, result, http.StatusOK, message)
 }
 
-func (h *TeacherHandler) GetStudentsByTeacherId(w http.ResponseWriter, r *http.Request) {
-	teacherId := r.PathValue("id")
-
-	var students = make([]models.Student, 0)
+// SetTeachersStatus bulk (de)activates accounts, e.g. end-of-contract offboarding batches.
+// Deactivated users are locked out on their next request by the Protect middleware.
+func (h *TeacherHandler) SetTeachersStatus(w http.ResponseWriter, r *http.Request) {
+	var req models.TeacherStatusUpdate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid payload")
+		return
+	}
 
-	query := `SELECT s.id, s.first_name, s.last_name, s.email, s.class 
-			  FROM teachers t 
-			  INNER JOIN students s ON t.class = s.class
-			  WHERE t.id = ?`
+	if errors := models.ValidateOne(req); len(errors) > 0 {
+		writeValidationErrors(w, r, errors)
+		return
+	}
 
-	rows, err := h.Repo.DB.QueryContext(r.Context(), query, teacherId)
+	active := req.Status == "active"
+	updatedIds, err := h.Repo.SetActiveBulk(r.Context(), req.IDs, active, currentUserID(r))
 	if err != nil {
-		log.Println(err)
-		utils.ResponseError(w, err, "")
+		logError(r, "Error during bulk status update: %v", err)
+		utils.ResponseError(w, err, "Bulk status update failed")
 		return
 	}
-	defer rows.Close()
 
-	for rows.Next() {
-		var student models.Student
+	result := idBulkResult(req.IDs, updatedIds, models.BulkUpdated)
+	message := "Teacher statuses updated successfully"
+	if result.Succeeded == 0 {
+		message = "None of the provided IDs exist"
+	}
+	writeBulk(w, result, http.StatusOK, message)
+}
 
-		err := rows.Scan(&student.ID, &student.FirstName, &student.LastName, &student.Email, &student.Class)
-		if err != nil {
-			log.Println(err)
-			utils.ResponseError(w, err, "")
-			return
+// idBulkResult reports a by-ID bulk operation: requested IDs the repository
+// acted on get status, the others not_found
+func idBulkResult(requested, done []int, status models.BulkStatus) models.BulkResult[models.Teacher] {
+	var result models.BulkResult[models.Teacher]
+	for _, id := range requested {
+		if slices.Contains(done, id) {
+			result.Add(models.BulkItem[models.Teacher]{ID: id, Status: status, Code: http.StatusOK})
+		} else {
+			result.Add(models.BulkItem[models.Teacher]{ID: id, Status: models.BulkNotFound, Code: http.StatusNotFound, Error: "Teacher not found"})
 		}
-		students = append(students, student)
 	}
+	return result
+}
+
+// GetStudentsByTeacherId lists the roster of the teacher's class; with
+// ?include_inactive=true, its applicants, suspended, withdrawn and alumni too
+func (h *TeacherHandler) GetStudentsByTeacherId(w http.ResponseWriter, r *http.Request) {
+	teacherId := utils.PathID(r, "id")
+	includeInactive, _ := strconv.ParseBool(r.URL.Query().Get("include_inactive"))
 
-	err = rows.Err()
+	students, err := h.Repo.GetStudents(r.Context(), teacherId, includeInactive)
 	if err != nil {
 		log.Println(err)
 		utils.ResponseError(w, err, "")
 		return
 	}
-	response := struct {
-		Count int              `json:"count"`
-		Data  []models.Student `json:"data"`
-	}{
-		Count: len(students),
-		Data:  students,
-	}
+	response := utils.NewList(students)
 
 	utils.WriteJSON(w, 200, "Students fetched successfully", response)
 
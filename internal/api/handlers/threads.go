@@ -0,0 +1,446 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"simpleapi/internal/jobs"
+	"simpleapi/internal/models"
+	"simpleapi/internal/quota"
+	"simpleapi/internal/repository"
+	"simpleapi/internal/storage"
+	"simpleapi/pkg/clock"
+	"simpleapi/pkg/errcodes"
+	"simpleapi/pkg/utils"
+	"strconv"
+	"strings"
+)
+
+const (
+	maxAttachmentBytes    = 10 << 20 // Per file
+	maxAttachments        = 5
+	maxThreadMessageBytes = maxAttachments*maxAttachmentBytes + 1<<20 // Files plus the form around them
+)
+
+// ThreadHandler is messaging between staff and guardians, in threads about a
+// student or a class. Staff take part per models.Thread.CanJoin; guardians are
+// texted each new message (jobs.ThreadNotices). Attachments are virus scanned
+// (jobs.UploadScans) before anyone can download them.
+type ThreadHandler struct {
+	Threads  repository.ThreadStore
+	Students repository.StudentStore
+	Uploads  repository.UploadStore
+	Storage  storage.Storage
+	Notices  *jobs.ThreadNotices
+	Scans    *jobs.UploadScans
+	Queue    *jobs.Queue
+	Quotas   *quota.Monitor // Attachments count towards the storage quota
+	Signer   utils.DocumentSigning
+	Clock    clock.Clock
+}
+
+// NewThreadHandler is the constructor
+func NewThreadHandler(threads repository.ThreadStore, students repository.StudentStore, uploads repository.UploadStore, store storage.Storage, notices *jobs.ThreadNotices, scans *jobs.UploadScans, queue *jobs.Queue, quotas *quota.Monitor, signer utils.DocumentSigning, clk clock.Clock) *ThreadHandler {
+	return &ThreadHandler{Threads: threads, Students: students, Uploads: uploads, Storage: store, Notices: notices, Scans: scans, Queue: queue, Quotas: quotas, Signer: signer, Clock: clk}
+}
+
+// notify queues the guardian texts for a new message. A full queue costs the
+// texts, not the message, so it is only logged.
+func (h *ThreadHandler) notify(t models.Thread, m models.ThreadMessage, sender models.Teacher) {
+	if err := h.Queue.Enqueue(h.Notices.Job(t, m, sender)); err != nil {
+		log.Printf("Error queueing notices for message %d in thread %d: %v", m.ID, t.ID, err)
+	}
+}
+
+// CreateThread opens a thread with its first message. A student thread belongs
+// to the student's current class.
+func (h *ThreadHandler) CreateThread(w http.ResponseWriter, r *http.Request) {
+	var req models.NewThread
+	if err := decodeJSON(r, &req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if errors := models.ValidateOne(req); len(errors) > 0 {
+		writeValidationErrors(w, r, errors)
+		return
+	}
+
+	user := currentUser(r)
+	thread := models.Thread{Scope: req.Scope, Class: req.Class, Subject: req.Subject, CreatedBy: user.ID}
+	if req.Scope == models.ThreadStudent {
+		student, err := h.Students.GetByID(r.Context(), *req.StudentID)
+		if err != nil {
+			logError(r, "Error fetching student %d: %v", *req.StudentID, err)
+			utils.ResponseError(w, err, fmt.Sprintf("Student with ID %d not found", *req.StudentID))
+			return
+		}
+		thread.StudentID = &student.ID
+		thread.Class = student.Class
+	}
+	if !thread.CanJoin(*user) {
+		utils.WriteError(w, http.StatusForbidden, "You can only message guardians of your own class")
+		return
+	}
+
+	first := models.ThreadMessage{SenderID: user.ID, Body: req.Body}
+	created, err := h.Threads.Create(r.Context(), thread, first)
+	if err != nil {
+		logError(r, "Error creating thread: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	h.notify(*created, first, *user)
+
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/threads/%d", created.ID))
+	utils.WriteJSON(w, http.StatusCreated, "Thread created successfully", created)
+}
+
+// GetThreads lists the threads the user takes part in, most recently active
+// first; ?student_id= and ?class= narrow it down
+func (h *ThreadHandler) GetThreads(w http.ResponseWriter, r *http.Request) {
+	var filter models.ThreadFilter
+	if errs := utils.BindQuery(r, &filter); len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	user := currentUser(r)
+	if user.Role != models.RoleAdmin {
+		if filter.Class != "" && filter.Class != user.Class {
+			utils.WriteError(w, http.StatusForbidden, "You can only see threads of your own class")
+			return
+		}
+		filter.Class = user.Class
+	}
+
+	threads, err := h.Threads.List(r.Context(), filter, user.ID)
+	if err != nil {
+		logError(r, "Error listing threads: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Threads fetched successfully", threads)
+}
+
+func (h *ThreadHandler) GetThread(w http.ResponseWriter, r *http.Request) {
+	thread, ok := h.threadFromPath(w, r)
+	if !ok {
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Thread fetched successfully", thread)
+}
+
+// GetMessages lists a thread's messages, oldest first
+func (h *ThreadHandler) GetMessages(w http.ResponseWriter, r *http.Request) {
+	thread, ok := h.threadFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	messages, err := h.Threads.ListMessages(r.Context(), thread.ID)
+	if err != nil {
+		logError(r, "Error listing messages of thread %d: %v", thread.ID, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	if err := h.fillScanStatus(r, messages); err != nil {
+		logError(r, "Error fetching scan status of thread %d: %v", thread.ID, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Messages fetched successfully", messages)
+}
+
+// PostMessage adds a message to a thread. The body is JSON ({"body": "..."}),
+// or multipart/form-data with a "body" field and up to five "attachments" files.
+func (h *ThreadHandler) PostMessage(w http.ResponseWriter, r *http.Request) {
+	thread, ok := h.threadFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	user := currentUser(r)
+	msg := models.ThreadMessage{ThreadID: thread.ID, SenderID: user.ID}
+	multipart := strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data")
+	if multipart {
+		r.Body = http.MaxBytesReader(w, r.Body, maxThreadMessageBytes)
+		if err := r.ParseMultipartForm(maxAttachmentBytes); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "Invalid multipart body")
+			return
+		}
+		defer r.MultipartForm.RemoveAll()
+		msg.Body = r.FormValue("body")
+	} else if err := decodeJSON(r, &msg); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	// The path and the session decide these, whatever the body says
+	msg.ThreadID, msg.SenderID, msg.Attachments = thread.ID, user.ID, nil
+	if errors := models.ValidateOne(msg); len(errors) > 0 {
+		writeValidationErrors(w, r, errors)
+		return
+	}
+
+	var uploads []models.Upload
+	if multipart {
+		if uploads, ok = h.storeAttachments(w, r, thread.ID); !ok {
+			return
+		}
+		for _, u := range uploads {
+			msg.Attachments = append(msg.Attachments, models.Attachment{
+				Name: u.Name, ContentType: u.ContentType, Size: u.Size, Key: u.StorageKey, UploadID: u.ID,
+			})
+		}
+	}
+
+	saved, err := h.Threads.AddMessage(r.Context(), msg)
+	if err != nil {
+		logError(r, "Error adding message to thread %d: %v", thread.ID, err)
+		h.discardUploads(r, uploads)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	for _, u := range uploads {
+		h.Scans.Enqueue(u)
+	}
+	for i := range saved.Attachments {
+		saved.Attachments[i].ScanStatus = models.ScanPending
+	}
+	h.notify(*thread, *saved, *user)
+
+	utils.WriteJSON(w, http.StatusCreated, "Message sent successfully", saved)
+}
+
+// storeAttachments uploads the "attachments" files of a multipart message,
+// each recorded as an upload pending its virus scan. On failure it has
+// answered the request and removed what it had stored.
+func (h *ThreadHandler) storeAttachments(w http.ResponseWriter, r *http.Request, threadID int) ([]models.Upload, bool) {
+	files := r.MultipartForm.File["attachments"]
+	if len(files) > maxAttachments {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Sprintf("At most %d attachments per message", maxAttachments))
+		return nil, false
+	}
+
+	uploads := make([]models.Upload, 0, len(files))
+	fail := func(code int, msg string) ([]models.Upload, bool) {
+		h.discardUploads(r, uploads)
+		utils.WriteError(w, code, msg)
+		return nil, false
+	}
+	for _, fh := range files {
+		name := path.Base(strings.ReplaceAll(fh.Filename, `\`, "/"))
+		if fh.Size > maxAttachmentBytes {
+			return fail(http.StatusBadRequest, fmt.Sprintf("%s is larger than %d MB", name, maxAttachmentBytes>>20))
+		}
+		f, err := fh.Open()
+		if err != nil {
+			return fail(http.StatusBadRequest, fmt.Sprintf("Cannot read %s", name))
+		}
+
+		// The stored name is random: the client's file name is only ever a label
+		token := make([]byte, 16)
+		rand.Read(token)
+		u := models.Upload{
+			StorageKey:  fmt.Sprintf("threads/%d/%s", threadID, hex.EncodeToString(token)),
+			Name:        name,
+			ContentType: sniffContentType(f),
+			Size:        fh.Size,
+			UploadedBy:  currentUser(r).ID,
+		}
+		err = h.Storage.Put(r.Context(), u.StorageKey, f)
+		f.Close()
+		if err != nil {
+			log.Printf("Error storing attachment of thread %d: %v", threadID, err)
+			return fail(http.StatusInternalServerError, "Internal Server Error")
+		}
+		created, err := h.Uploads.Create(r.Context(), u)
+		if err != nil {
+			h.Storage.Delete(r.Context(), u.StorageKey)
+			if errors.Is(err, models.ErrForbidden) { // The storage quota (models.QuotaError)
+				h.discardUploads(r, uploads)
+				utils.ResponseError(w, err, fmt.Sprintf("%s doesn't fit in the school's storage quota", name))
+				return nil, false
+			}
+			log.Printf("Error recording attachment of thread %d: %v", threadID, err)
+			return fail(http.StatusInternalServerError, "Internal Server Error")
+		}
+		uploads = append(uploads, *created)
+	}
+	if len(uploads) > 0 {
+		h.Quotas.Check(w, r)
+	}
+	return uploads, true
+}
+
+// discardUploads removes stored files, and their uploads, that didn't make it into a message
+func (h *ThreadHandler) discardUploads(r *http.Request, uploads []models.Upload) {
+	for _, u := range uploads {
+		h.Storage.Delete(r.Context(), u.StorageKey)
+		h.Uploads.Delete(r.Context(), u.ID)
+	}
+}
+
+// fillScanStatus sets the scan status of every attachment of the messages.
+// Attachments from before scanning existed were never scanned and count as clean.
+func (h *ThreadHandler) fillScanStatus(r *http.Request, messages []models.ThreadMessage) error {
+	var ids []int
+	for _, m := range messages {
+		for _, a := range m.Attachments {
+			if a.UploadID != 0 {
+				ids = append(ids, a.UploadID)
+			}
+		}
+	}
+	statuses, err := h.Uploads.Statuses(r.Context(), ids)
+	if err != nil {
+		return err
+	}
+	for _, m := range messages {
+		for i, a := range m.Attachments {
+			m.Attachments[i].ScanStatus = models.ScanClean
+			if a.UploadID != 0 {
+				m.Attachments[i].ScanStatus = statuses[a.UploadID]
+			}
+		}
+	}
+	return nil
+}
+
+// sniffContentType detects a file's type from its first bytes and rewinds it
+func sniffContentType(f io.ReadSeeker) string {
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(f, head)
+	f.Seek(0, io.SeekStart)
+	return http.DetectContentType(head[:n])
+}
+
+// MarkRead marks the thread read up to its latest message for the current user
+func (h *ThreadHandler) MarkRead(w http.ResponseWriter, r *http.Request) {
+	thread, ok := h.threadFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.Threads.MarkRead(r.Context(), thread.ID, currentUser(r).ID); err != nil {
+		logError(r, "Error marking thread %d read: %v", thread.ID, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetAttachment streams an attachment, addressed by its position in the message
+func (h *ThreadHandler) GetAttachment(w http.ResponseWriter, r *http.Request) {
+	a, ok := h.attachmentFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	rc, err := h.Storage.Get(r.Context(), a.Key)
+	if err != nil {
+		log.Printf("Error reading attachment %s: %v", a.Key, err)
+		utils.WriteError(w, http.StatusInternalServerError, "Internal Server Error")
+		return
+	}
+	defer rc.Close()
+
+	w.Header().Set("Content-Type", a.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Name}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	io.Copy(w, rc)
+}
+
+// GetAttachmentLink returns a signed link to an attachment, for emails:
+// GET /threads/{id}/messages/{messageId}/attachments/{index}/link?expires_in=3600
+func (h *ThreadHandler) GetAttachmentLink(w http.ResponseWriter, r *http.Request) {
+	a, ok := h.attachmentFromPath(w, r)
+	if !ok {
+		return
+	}
+	writeDownloadLink(w, r, h.Signer, h.Clock, models.DownloadClaims{
+		Kind:        models.DownloadAttachment,
+		ID:          a.UploadID,
+		Key:         a.Key,
+		Name:        a.Name,
+		ContentType: a.ContentType,
+	})
+}
+
+// attachmentFromPath loads the attachment in the path, answering as
+// threadFromPath does, 404 if there's no such attachment, and as downloadable
+// does unless it may be downloaded
+func (h *ThreadHandler) attachmentFromPath(w http.ResponseWriter, r *http.Request) (*models.Attachment, bool) {
+	thread, ok := h.threadFromPath(w, r)
+	if !ok {
+		return nil, false
+	}
+	messageID := utils.PathID(r, "messageId")
+	index, err := strconv.Atoi(r.PathValue("index"))
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid attachment index")
+		return nil, false
+	}
+
+	msg, err := h.Threads.GetMessage(r.Context(), thread.ID, messageID)
+	if err != nil {
+		logError(r, "Error fetching message %d: %v", messageID, err)
+		utils.ResponseError(w, err, fmt.Sprintf("Message with ID %d not found", messageID))
+		return nil, false
+	}
+	if index < 0 || index >= len(msg.Attachments) {
+		utils.WriteError(w, http.StatusNotFound, fmt.Sprintf("Message %d has no attachment %d", messageID, index))
+		return nil, false
+	}
+	a := msg.Attachments[index]
+	if a.UploadID != 0 && !h.downloadable(w, r, a.UploadID) {
+		return nil, false
+	}
+	return &a, true
+}
+
+// downloadable checks an attachment's virus scan, answering 409 while it is
+// pending and 403 once it is quarantined or rejected
+func (h *ThreadHandler) downloadable(w http.ResponseWriter, r *http.Request, uploadID int) bool {
+	upload, err := h.Uploads.GetByID(r.Context(), uploadID)
+	if err != nil {
+		logError(r, "Error fetching upload %d: %v", uploadID, err)
+		utils.ResponseError(w, err, "")
+		return false
+	}
+	switch {
+	case upload.Downloadable():
+		return true
+	case upload.Status == models.ScanPending:
+		w.Header().Set("Retry-After", "30")
+		utils.WriteErrorCode(w, http.StatusConflict, errcodes.UploadPendingScan, "This file is still being scanned for viruses, try again shortly")
+	default:
+		utils.WriteErrorCode(w, http.StatusForbidden, errcodes.UploadBlocked, "This file was blocked by the virus scanner")
+	}
+	return false
+}
+
+// threadFromPath loads the thread in the path, answering 404 or 403 if the
+// current user can't have it
+func (h *ThreadHandler) threadFromPath(w http.ResponseWriter, r *http.Request) (*models.Thread, bool) {
+	id := utils.PathID(r, "id")
+
+	user := currentUser(r)
+	thread, err := h.Threads.GetByID(r.Context(), id, user.ID)
+	if err != nil {
+		logError(r, "Error fetching thread %d: %v", id, err)
+		utils.ResponseError(w, err, fmt.Sprintf("Thread with ID %d not found", id))
+		return nil, false
+	}
+	if !thread.CanJoin(*user) {
+		utils.WriteError(w, http.StatusForbidden, "You are not a participant of this thread")
+		return nil, false
+	}
+	return thread, true
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"simpleapi/internal/locale"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"simpleapi/internal/schoolprofile"
+	"simpleapi/pkg/clock"
+	"simpleapi/pkg/utils"
+)
+
+// TranscriptHandler builds academic transcripts from graded scores and
+// signs them for external institutions
+type TranscriptHandler struct {
+	Students repository.StudentStore
+	Scores   repository.ScoreStore
+	Schemes  repository.GradingStore
+	Signer   utils.DocumentSigning
+	Clock    clock.Clock
+	School   *schoolprofile.Profile
+}
+
+// NewTranscriptHandler is the constructor
+func NewTranscriptHandler(students repository.StudentStore, scores repository.ScoreStore, schemes repository.GradingStore, signer utils.DocumentSigning, clk clock.Clock, school *schoolprofile.Profile) *TranscriptHandler {
+	return &TranscriptHandler{Students: students, Scores: scores, Schemes: schemes, Signer: signer, Clock: clk, School: school}
+}
+
+// GetTranscript returns the student's transcript.
+// ?format=signed returns the signed export instead of the plain JSON.
+func (h *TranscriptHandler) GetTranscript(w http.ResponseWriter, r *http.Request) {
+	studentID := utils.PathID(r, "id")
+
+	student, err := h.Students.GetByID(r.Context(), studentID)
+	if err != nil {
+		logError(r, "Error fetching student %d: %v", studentID, err)
+		utils.ResponseError(w, err, fmt.Sprintf("Student with ID %d not found", studentID))
+		return
+	}
+
+	transcript, err := h.build(r, *student)
+	if errors.Is(err, models.ErrNotFound) {
+		utils.WriteError(w, http.StatusConflict, "No grading scheme is configured for some of the student's subjects")
+		return
+	}
+	if err != nil {
+		logError(r, "Error building transcript for student %d: %v", studentID, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+
+	if r.URL.Query().Get("format") != "signed" {
+		utils.WriteJSON(w, http.StatusOK, "Transcript fetched successfully", transcript)
+		return
+	}
+
+	payload, err := json.Marshal(transcript)
+	if err != nil {
+		logError(r, "Error encoding transcript for student %d: %v", studentID, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	signature, keyID, err := h.Signer.Sign(payload)
+	if errors.Is(err, utils.ErrNoSigningKey) {
+		utils.WriteError(w, http.StatusServiceUnavailable, "Transcript signing is not configured")
+		return
+	}
+	if err != nil {
+		logError(r, "Error signing transcript for student %d: %v", studentID, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, "Signed transcript generated successfully", models.SignedTranscript{
+		Payload:   base64.RawURLEncoding.EncodeToString(payload),
+		Algorithm: utils.SigningAlgorithm,
+		KeyID:     keyID,
+		Signature: signature,
+	})
+}
+
+// VerifyTranscript lets an external institution check a signed export it received.
+// A valid export comes back decoded; a tampered one just says valid=false.
+func (h *TranscriptHandler) VerifyTranscript(w http.ResponseWriter, r *http.Request) {
+	var signed models.SignedTranscript
+	if err := decodeJSON(r, &signed); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if errors := models.ValidateOne(signed); len(errors) > 0 {
+		writeValidationErrors(w, r, errors)
+		return
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(signed.Payload)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Payload is not valid base64url")
+		return
+	}
+
+	result := models.TranscriptVerification{Valid: h.Signer.Verify(payload, signed.Signature, signed.KeyID)}
+	if result.Valid {
+		var transcript models.Transcript
+		if err := json.Unmarshal(payload, &transcript); err == nil {
+			result.Transcript = &transcript
+		}
+	}
+
+	utils.WriteJSON(w, http.StatusOK, "Transcript verified", result)
+}
+
+// yearSubject keys the per-year, per-subject score buckets
+type yearSubject struct {
+	year, subject string
+}
+
+// build averages each subject's term scores per academic year and grades the averages
+// with the subject's current scheme, so every transcript uses the same boundaries
+func (h *TranscriptHandler) build(r *http.Request, student models.Student) (*models.Transcript, error) {
+	// Scores come back ordered by term, and term codes start with the year,
+	// so years and subjects appear in order as we walk them
+	scores, err := h.Scores.ListByStudent(r.Context(), student.ID, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []yearSubject
+	totals := make(map[yearSubject][]float64)
+	for _, s := range scores {
+		key := yearSubject{models.AcademicYear(s.Term), s.Subject}
+		if _, seen := totals[key]; !seen {
+			keys = append(keys, key)
+		}
+		totals[key] = append(totals[key], s.Score)
+	}
+
+	schemes := make(map[string]*models.GradingScheme)
+	school := h.School.Get().Public()
+	transcript := &models.Transcript{Student: student, Years: make([]models.TranscriptYear, 0), GeneratedAt: locale.In(r.Context(), h.Clock, h.Clock.Now()), School: &school}
+	var allAverages, allPoints []float64
+	usesPoints := false
+
+	for _, key := range keys {
+		scheme, ok := schemes[key.subject]
+		if !ok {
+			if scheme, err = h.Schemes.ForSubject(r.Context(), key.subject); err != nil {
+				return nil, err
+			}
+			schemes[key.subject] = scheme
+		}
+
+		average := round2(mean(totals[key]))
+		subject := models.TranscriptSubject{
+			Subject: key.subject,
+			Terms:   len(totals[key]),
+			Average: average,
+			Grade:   scheme.GradeFor(average),
+			Points:  scheme.PointsFor(average),
+		}
+		usesPoints = usesPoints || scheme.UsesPoints()
+
+		if n := len(transcript.Years); n == 0 || transcript.Years[n-1].Year != key.year {
+			transcript.Years = append(transcript.Years, models.TranscriptYear{Year: key.year, Subjects: make([]models.TranscriptSubject, 0)})
+		}
+		year := &transcript.Years[len(transcript.Years)-1]
+		year.Subjects = append(year.Subjects, subject)
+		allAverages = append(allAverages, subject.Average)
+		allPoints = append(allPoints, subject.Points)
+	}
+
+	for i := range transcript.Years {
+		year := &transcript.Years[i]
+		averages := make([]float64, len(year.Subjects))
+		points := make([]float64, len(year.Subjects))
+		for j, s := range year.Subjects {
+			averages[j], points[j] = s.Average, s.Points
+		}
+		year.Average = round2(mean(averages))
+		if usesPoints {
+			gpa := round2(mean(points))
+			year.GPA = &gpa
+		}
+	}
+	transcript.CumulativeAverage = round2(mean(allAverages))
+	if usesPoints {
+		gpa := round2(mean(allPoints))
+		transcript.CumulativeGPA = &gpa
+	}
+	return transcript, nil
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"simpleapi/pkg/utils"
+	"time"
+)
+
+// TrashHandler lists soft-deleted records and restores them within the retention window
+type TrashHandler struct {
+	Teachers  repository.TeacherStore
+	Retention time.Duration
+}
+
+// NewTrashHandler is the constructor
+func NewTrashHandler(teachers repository.TeacherStore, retention time.Duration) *TrashHandler {
+	return &TrashHandler{Teachers: teachers, Retention: retention}
+}
+
+// GetTrash lists everything in the trash; ?entity=teachers narrows it down.
+// Students have no delete endpoint, so teachers are the only entity for now.
+func (h *TrashHandler) GetTrash(w http.ResponseWriter, r *http.Request) {
+	entity := r.URL.Query().Get("entity")
+	if entity != "" && entity != models.TrashTeachers {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Sprintf("Unsupported entity '%s'", entity))
+		return
+	}
+
+	teachers, err := h.Teachers.ListDeleted(r.Context())
+	if err != nil {
+		logError(r, "Error fetching trash: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+
+	items := make([]models.TrashItem, 0, len(teachers))
+	for _, t := range teachers {
+		items = append(items, models.TrashItem{
+			Entity:    models.TrashTeachers,
+			ID:        t.ID,
+			Name:      t.FirstName + " " + t.LastName,
+			Email:     t.Email,
+			DeletedAt: *t.DeletedAt,
+			DeletedBy: t.DeletedBy,
+			PurgeAt:   t.DeletedAt.Add(h.Retention),
+		})
+	}
+
+	utils.WriteJSON(w, http.StatusOK, "Trash fetched successfully", items)
+}
+
+func (h *TrashHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	var path struct {
+		Entity string `path:"entity"`
+		ID     int    `path:"id"`
+	}
+	if errs := utils.BindPath(r, &path); len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+	if path.Entity != models.TrashTeachers {
+		utils.WriteError(w, http.StatusNotFound, fmt.Sprintf("Unsupported entity '%s'", path.Entity))
+		return
+	}
+	id := path.ID
+
+	teacher, err := h.Teachers.Restore(r.Context(), id, currentUserID(r))
+	if err != nil {
+		logError(r, "Error restoring teacher %d: %v", id, err)
+		utils.ResponseError(w, err, fmt.Sprintf("Teacher with ID %d is not in the trash", id))
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, "Teacher restored successfully", teacher)
+}
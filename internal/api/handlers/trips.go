@@ -0,0 +1,389 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"simpleapi/internal/jobs"
+	"simpleapi/internal/models"
+	"simpleapi/internal/policy"
+	"simpleapi/internal/query"
+	"simpleapi/internal/repository"
+	"simpleapi/pkg/clock"
+	"simpleapi/pkg/errcodes"
+	"simpleapi/pkg/utils"
+)
+
+// TripHandler runs trips and their consent: a trip is for one class, and
+// each student's guardian is texted a link to give or refuse consent
+// (jobs.TripConsentNotices). Guardians have no accounts, so the link's token
+// is their credential; staff record answers that come in on paper. Teachers
+// manage the trips of the classes they teach, admins every trip.
+type TripHandler struct {
+	Trips    repository.TripStore
+	Students repository.StudentStore
+	Policy   *policy.Policy
+	Notices  *jobs.TripConsentNotices
+	Queue    *jobs.Queue
+	Clock    clock.Clock
+}
+
+// NewTripHandler is the constructor
+func NewTripHandler(trips repository.TripStore, students repository.StudentStore, p *policy.Policy, notices *jobs.TripConsentNotices, queue *jobs.Queue, clk clock.Clock) *TripHandler {
+	return &TripHandler{Trips: trips, Students: students, Policy: p, Notices: notices, Queue: queue, Clock: clk}
+}
+
+// CreateTrip creates a trip and texts every guardian of the class for
+// consent: POST /trips
+func (h *TripHandler) CreateTrip(w http.ResponseWriter, r *http.Request) {
+	var req models.TripRequest
+	if err := decodeJSON(r, &req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if errors := models.ValidateOne(req); len(errors) > 0 {
+		writeValidationErrors(w, r, errors)
+		return
+	}
+	today := clock.Today(h.Clock).Format(models.DateLayout)
+	// ISO dates compare correctly as strings
+	if req.Date < today {
+		writeValidationErrors(w, r, []models.ValidationError{models.RuleError("Date", "date_in_past", "")})
+		return
+	}
+	if req.ConsentDeadline != "" && (req.ConsentDeadline < today || req.ConsentDeadline > req.Date) {
+		writeValidationErrors(w, r, []models.ValidationError{models.RuleError("ConsentDeadline", "consent_deadline", "")})
+		return
+	}
+	if !authorizeClass(w, r, h.Policy, req.Class) {
+		return
+	}
+
+	trip, err := h.Trips.Create(r.Context(), models.Trip{
+		Title:           req.Title,
+		Class:           req.Class,
+		Date:            req.Date,
+		Destination:     req.Destination,
+		Details:         req.Details,
+		ConsentDeadline: req.ConsentDeadline,
+		CreatedBy:       currentUserID(r),
+	})
+	if err != nil {
+		logError(r, "Error creating trip: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	requested, ok := h.requestConsents(w, r, *trip)
+	if !ok {
+		return
+	}
+	utils.WriteJSON(w, http.StatusCreated, fmt.Sprintf("Trip created, consent asked of %d guardians", requested), trip)
+}
+
+// GetTrips lists the trips, soonest first: GET /trips?class=
+func (h *TripHandler) GetTrips(w http.ResponseWriter, r *http.Request) {
+	trips, err := h.Trips.List(r.Context(), r.URL.Query().Get("class"))
+	if err != nil {
+		logError(r, "Error listing trips: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Trips fetched successfully", trips)
+}
+
+// GetTrip returns a trip: GET /trips/{id}
+func (h *TripHandler) GetTrip(w http.ResponseWriter, r *http.Request) {
+	id := utils.PathID(r, "id")
+	trip, err := h.Trips.GetByID(r.Context(), id)
+	if err != nil {
+		logError(r, "Error fetching trip %d: %v", id, err)
+		utils.ResponseError(w, err, fmt.Sprintf("Trip with ID %d not found", id))
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Trip fetched successfully", trip)
+}
+
+// RequestConsents texts a new link to the guardians who haven't answered,
+// including those of students who joined the class since:
+// POST /trips/{id}/consent-requests. Earlier links stop working.
+func (h *TripHandler) RequestConsents(w http.ResponseWriter, r *http.Request) {
+	trip, ok := h.trip(w, r)
+	if !ok {
+		return
+	}
+	if clock.Today(h.Clock).Format(models.DateLayout) > trip.LastConsentDay() {
+		utils.WriteError(w, http.StatusConflict, "The trip's consent deadline has passed")
+		return
+	}
+	requested, ok := h.requestConsents(w, r, *trip)
+	if !ok {
+		return
+	}
+	utils.WriteJSON(w, http.StatusAccepted, fmt.Sprintf("Consent asked of %d guardians", requested), nil)
+}
+
+// GetConsentStatus lists the students of the trip's class with their consent,
+// so staff know who can go: GET /trips/{id}/consent-status. Only students
+// whose consent is given can attend; pending counts the never asked too.
+func (h *TripHandler) GetConsentStatus(w http.ResponseWriter, r *http.Request) {
+	trip, ok := h.trip(w, r)
+	if !ok {
+		return
+	}
+	students, err := h.classStudents(r, trip.Class)
+	if err != nil {
+		logError(r, "Error fetching students of class %s: %v", trip.Class, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	consents, err := h.Trips.Consents(r.Context(), trip.ID)
+	if err != nil {
+		logError(r, "Error fetching consents of trip %d: %v", trip.ID, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	byStudent := make(map[int]models.TripConsent, len(consents))
+	for _, c := range consents {
+		byStudent[c.StudentID] = c
+	}
+
+	status := models.ConsentStatus{Trip: *trip, Students: make([]models.ConsentStatusEntry, len(students))}
+	for i, s := range students {
+		entry := models.ConsentStatusEntry{StudentID: s.ID, Name: s.LastName + ", " + s.FirstName, AdmissionNumber: s.AdmissionNumber}
+		if c, ok := byStudent[s.ID]; ok {
+			entry.Consent = &c
+		}
+		switch {
+		case entry.Consent != nil && entry.Consent.Status == models.ConsentGiven:
+			entry.CanAttend = true
+			status.Given++
+		case entry.Consent != nil && entry.Consent.Status == models.ConsentRefused:
+			status.Refused++
+		default:
+			status.Pending++
+		}
+		status.Students[i] = entry
+	}
+	utils.WriteJSON(w, http.StatusOK, "Consent status fetched successfully", status)
+}
+
+// RecordConsent enters an answer that reached staff another way, e.g. a
+// signed paper slip: PUT /trips/{id}/consents/{student_id}
+func (h *TripHandler) RecordConsent(w http.ResponseWriter, r *http.Request) {
+	studentID := utils.PathID(r, "student_id")
+	var req models.ConsentRecord
+	if err := decodeJSON(r, &req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if errors := models.ValidateOne(req); len(errors) > 0 {
+		writeValidationErrors(w, r, errors)
+		return
+	}
+	trip, ok := h.trip(w, r)
+	if !ok {
+		return
+	}
+	student, err := h.Students.GetByID(r.Context(), studentID)
+	if err != nil {
+		logError(r, "Error fetching student %d: %v", studentID, err)
+		utils.ResponseError(w, err, fmt.Sprintf("Student with ID %d not found", studentID))
+		return
+	}
+	if student.Class != trip.Class {
+		utils.WriteError(w, http.StatusBadRequest, fmt.Sprintf("%s %s is not in class %s", student.FirstName, student.LastName, trip.Class))
+		return
+	}
+
+	consent, err := h.Trips.Answer(r.Context(), models.TripConsent{
+		TripID:       trip.ID,
+		StudentID:    student.ID,
+		Status:       req.Decision,
+		Via:          models.ConsentViaStaff,
+		GuardianName: req.GuardianName,
+		Note:         req.Note,
+	}, currentUserID(r))
+	if err != nil {
+		logError(r, "Error recording consent of student %d for trip %d: %v", student.ID, trip.ID, err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Consent recorded", consent)
+}
+
+// ViewInvitation shows a guardian the trip their link is about:
+// POST /trip-consent/view. Public, the token is the credential.
+func (h *TripHandler) ViewInvitation(w http.ResponseWriter, r *http.Request) {
+	var req models.ConsentToken
+	if err := decodeJSON(r, &req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if errors := models.ValidateOne(req); len(errors) > 0 {
+		writeValidationErrors(w, r, errors)
+		return
+	}
+	invitation, err := h.invitation(r, req.Token)
+	if err != nil {
+		h.writeLinkError(w, r, err)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Consent request fetched successfully", invitation)
+}
+
+// Respond records a guardian's answer: POST /trip-consent/respond. The
+// guardian may change it with the same link until the deadline.
+func (h *TripHandler) Respond(w http.ResponseWriter, r *http.Request) {
+	var req models.ConsentResponse
+	if err := decodeJSON(r, &req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if errors := models.ValidateOne(req); len(errors) > 0 {
+		writeValidationErrors(w, r, errors)
+		return
+	}
+	invitation, err := h.invitation(r, req.Token)
+	if err == nil && !invitation.CanRespond {
+		err = fmt.Errorf("trip %d closed for consent on %s: %w", invitation.Trip.ID, invitation.LastDay, models.ErrConsentLinkInvalid)
+	}
+	if err != nil {
+		h.writeLinkError(w, r, err)
+		return
+	}
+
+	consent, err := h.Trips.Answer(r.Context(), models.TripConsent{
+		TripID:       invitation.Trip.ID,
+		StudentID:    invitation.Consent.StudentID,
+		Status:       req.Decision,
+		Via:          models.ConsentViaLink,
+		GuardianName: req.GuardianName,
+		Note:         req.Note,
+		TokenHash:    hashConsentToken(req.Token),
+	}, nil)
+	if err != nil {
+		h.writeLinkError(w, r, err)
+		return
+	}
+	message := "Thank you, your consent is recorded"
+	if consent.Status == models.ConsentRefused {
+		message = "Thank you, your refusal is recorded"
+	}
+	utils.WriteJSON(w, http.StatusOK, message, consent)
+}
+
+// trip loads the trip of the path and checks the user teaches its class
+func (h *TripHandler) trip(w http.ResponseWriter, r *http.Request) (*models.Trip, bool) {
+	id := utils.PathID(r, "id")
+	trip, err := h.Trips.GetByID(r.Context(), id)
+	if err != nil {
+		logError(r, "Error fetching trip %d: %v", id, err)
+		utils.ResponseError(w, err, fmt.Sprintf("Trip with ID %d not found", id))
+		return nil, false
+	}
+	if !authorizeClass(w, r, h.Policy, trip.Class) {
+		return nil, false
+	}
+	return trip, true
+}
+
+// requestConsents asks the guardians of the class who haven't answered, and
+// returns how many were asked
+func (h *TripHandler) requestConsents(w http.ResponseWriter, r *http.Request, trip models.Trip) (int, bool) {
+	students, err := h.classStudents(r, trip.Class)
+	if err != nil {
+		logError(r, "Error fetching students of class %s: %v", trip.Class, err)
+		utils.ResponseError(w, err, "")
+		return 0, false
+	}
+	now := h.Clock.Now()
+	tokens := make(map[int]string, len(students))
+	consents := make([]models.TripConsent, len(students))
+	for i, s := range students {
+		tokens[s.ID] = newConsentToken()
+		consents[i] = models.TripConsent{TripID: trip.ID, StudentID: s.ID, TokenHash: hashConsentToken(tokens[s.ID]), RequestedAt: now}
+	}
+	requested, err := h.Trips.Request(r.Context(), consents)
+	if err != nil {
+		logError(r, "Error requesting consents for trip %d: %v", trip.ID, err)
+		utils.ResponseError(w, err, "")
+		return 0, false
+	}
+
+	byID := make(map[int]models.Student, len(students))
+	for _, s := range students {
+		byID[s.ID] = s
+	}
+	requests := make([]jobs.ConsentRequest, len(requested))
+	for i, c := range requested {
+		requests[i] = jobs.ConsentRequest{Student: byID[c.StudentID], Token: tokens[c.StudentID]}
+	}
+	// The tokens only travel in the texts: without them nobody can answer,
+	// but asking again makes new ones
+	if len(requests) > 0 {
+		if err := h.Queue.Enqueue(h.Notices.Job(trip, requests)); err != nil {
+			log.Printf("Error queueing consent requests for trip %d: %v", trip.ID, err)
+			utils.WriteError(w, http.StatusServiceUnavailable, "The consent requests could not be sent, ask again with POST /trips/{id}/consent-requests")
+			return 0, false
+		}
+	}
+	return len(requests), true
+}
+
+func (h *TripHandler) classStudents(r *http.Request, class string) ([]models.Student, error) {
+	return h.Students.GetAll(r.Context(), query.Options{
+		Where: models.ClassRoster(class),
+		Sort:  []query.Sort{{Field: "last_name"}, {Field: "first_name"}},
+	})
+}
+
+// invitation looks up the consent of a link's token, with its trip
+func (h *TripHandler) invitation(r *http.Request, token string) (*models.ConsentInvitation, error) {
+	consent, err := h.Trips.ConsentByToken(r.Context(), hashConsentToken(token))
+	if err != nil {
+		return nil, err
+	}
+	trip, err := h.Trips.GetByID(r.Context(), consent.TripID)
+	if err != nil {
+		return nil, err
+	}
+	student, err := h.Students.GetByID(r.Context(), consent.StudentID)
+	if err != nil {
+		return nil, err
+	}
+	today := clock.Today(h.Clock).Format(models.DateLayout)
+	return &models.ConsentInvitation{
+		Trip:        *trip,
+		StudentName: student.FirstName + " " + student.LastName,
+		Consent:     *consent,
+		LastDay:     trip.LastConsentDay(),
+		CanRespond:  today <= trip.LastConsentDay(),
+	}, nil
+}
+
+// writeLinkError gives every unusable link the same answer, so tokens can't be probed
+func (h *TripHandler) writeLinkError(w http.ResponseWriter, r *http.Request, err error) {
+	logError(r, "Error answering trip consent: %v", err)
+	if errcodes.Of(err) == errcodes.ConsentLinkInvalid {
+		utils.WriteErrorCode(w, http.StatusNotFound, errcodes.ConsentLinkInvalid, "This link is invalid, was replaced by a newer one, or its deadline has passed")
+		return
+	}
+	utils.ResponseError(w, err, "")
+}
+
+// newConsentToken is 32 random bytes, URL-safe for the links
+func newConsentToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// hashConsentToken is what the store keeps and looks tokens up by
+func hashConsentToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"simpleapi/internal/storage"
+	"simpleapi/pkg/utils"
+)
+
+// UploadHandler is the admin side of upload virus scanning: the quarantine
+// queue, and releasing or rejecting what the scanner flagged
+type UploadHandler struct {
+	Uploads repository.UploadStore
+	Storage storage.Storage
+}
+
+// NewUploadHandler is the constructor
+func NewUploadHandler(uploads repository.UploadStore, store storage.Storage) *UploadHandler {
+	return &UploadHandler{Uploads: uploads, Storage: store}
+}
+
+// GetUploads lists uploads newest first; ?status=quarantined is the review queue
+func (h *UploadHandler) GetUploads(w http.ResponseWriter, r *http.Request) {
+	var filter models.UploadFilter
+	if errs := utils.BindQuery(r, &filter); len(errs) > 0 {
+		writeValidationErrors(w, r, errs)
+		return
+	}
+
+	uploads, err := h.Uploads.List(r.Context(), filter)
+	if err != nil {
+		logError(r, "Error listing uploads: %v", err)
+		utils.ResponseError(w, err, "")
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Uploads fetched successfully", uploads)
+}
+
+func (h *UploadHandler) GetUpload(w http.ResponseWriter, r *http.Request) {
+	id := utils.PathID(r, "id")
+
+	upload, err := h.Uploads.GetByID(r.Context(), id)
+	if err != nil {
+		logError(r, "Error fetching upload %d: %v", id, err)
+		utils.ResponseError(w, err, fmt.Sprintf("Upload with ID %d not found", id))
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, "Upload fetched successfully", upload)
+}
+
+// ReviewUpload settles a quarantined upload: "release" makes it downloadable
+// (a false positive), "reject" deletes the file for good
+func (h *UploadHandler) ReviewUpload(w http.ResponseWriter, r *http.Request) {
+	id := utils.PathID(r, "id")
+	var req models.UploadReview
+	if err := decodeJSON(r, &req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if errors := models.ValidateOne(req); len(errors) > 0 {
+		writeValidationErrors(w, r, errors)
+		return
+	}
+
+	status := models.ScanReleased
+	if req.Decision == "reject" {
+		status = models.ScanRejected
+	}
+	upload, err := h.Uploads.Review(r.Context(), id, status, currentUser(r).ID, req.Note)
+	if err != nil {
+		if errors.Is(err, models.ErrConflict) {
+			utils.WriteError(w, http.StatusConflict, "Only quarantined uploads can be reviewed")
+			return
+		}
+		logError(r, "Error reviewing upload %d: %v", id, err)
+		utils.ResponseError(w, err, fmt.Sprintf("Upload with ID %d not found", id))
+		return
+	}
+
+	if status == models.ScanRejected {
+		// The row stays as the record of the decision; the file goes
+		if err := h.Storage.Delete(r.Context(), upload.StorageKey); err != nil {
+			log.Printf("Error deleting rejected upload %d: %v", id, err)
+		}
+	}
+	utils.WriteJSON(w, http.StatusOK, "Upload reviewed successfully", upload)
+}
@@ -0,0 +1,25 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"simpleapi/internal/metrics"
+)
+
+// CountCanceled counts the requests whose client went away before the
+// response was ready in metrics.RequestsCanceled, by route. Like TraceRoute,
+// wrap it around the ServeMux so the matched pattern is known.
+func CountCanceled(mux http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.ServeHTTP(w, r)
+
+		if errors.Is(r.Context().Err(), context.Canceled) {
+			route := r.Pattern
+			if route == "" {
+				route = "unmatched"
+			}
+			metrics.RequestsCanceled.Add(route, 1)
+		}
+	})
+}
@@ -0,0 +1,124 @@
+package middlewares
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"simpleapi/internal/metrics"
+	"simpleapi/internal/models"
+	"simpleapi/pkg/errcodes"
+	"simpleapi/pkg/utils"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// KioskKeyKey holds the *KioskKey that authenticated the request
+const KioskKeyKey contextKey = "kioskKey"
+
+// KioskKey is one front-desk tablet's API key and the operations it may run
+type KioskKey struct {
+	Name       string
+	Operations []string
+	hash       [32]byte
+}
+
+// May reports whether the key is allowed op
+func (k *KioskKey) May(op string) bool {
+	return slices.Contains(k.Operations, op)
+}
+
+// ParseKioskKeys reads KIOSK_API_KEYS: comma-separated name=key pairs, where
+// the name may list the operations allowed, e.g.
+// "frontdesk=k1,gate:checkin=k2". A name without a list may run every
+// operation in models.KioskOperations.
+func ParseKioskKeys(s string) ([]KioskKey, error) {
+	var keys []KioskKey
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, secret, ok := strings.Cut(part, "=")
+		secret = strings.TrimSpace(secret)
+		if !ok || secret == "" {
+			// By position: without the "=", name would be the secret itself
+			return nil, fmt.Errorf("kiosk key %d is malformed, want <name>[:<operation>+...]=<key>", len(keys)+1)
+		}
+		// Keys are guessable if short: ask for what `openssl rand -hex 16` gives
+		if len(secret) < 32 {
+			return nil, fmt.Errorf("kiosk key %q is too short, use at least 32 characters", name)
+		}
+		name, ops, hasOps := strings.Cut(strings.TrimSpace(name), ":")
+		if name == "" {
+			return nil, fmt.Errorf("kiosk key %d has no name", len(keys)+1)
+		}
+		key := KioskKey{Name: name, Operations: models.KioskOperations, hash: sha256.Sum256([]byte(secret))}
+		if hasOps {
+			key.Operations = strings.Split(ops, "+")
+			for _, op := range key.Operations {
+				if !slices.Contains(models.KioskOperations, op) {
+					return nil, fmt.Errorf("kiosk key %q: unknown operation %q, want one of %s", name, op, strings.Join(models.KioskOperations, ", "))
+				}
+			}
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// KioskAuth authenticates front-desk tablets by the X-API-Key header. It is
+// separate from Protect: a kiosk never logs in as a teacher, and its key only
+// opens the routes of the operations it is allowed.
+type KioskAuth struct {
+	mu   sync.RWMutex
+	keys []KioskKey
+}
+
+// NewKioskAuth is the constructor
+func NewKioskAuth(keys []KioskKey) *KioskAuth {
+	return &KioskAuth{keys: keys}
+}
+
+// SetKeys replaces the keys, for rotation
+func (k *KioskAuth) SetKeys(keys []KioskKey) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.keys = keys
+}
+
+// lookup finds the key in constant time, so response times don't leak how
+// much of a guess was right
+func (k *KioskAuth) lookup(secret string) *KioskKey {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	hash := sha256.Sum256([]byte(secret))
+	var found *KioskKey
+	for i := range k.keys {
+		if subtle.ConstantTimeCompare(hash[:], k.keys[i].hash[:]) == 1 {
+			found = &k.keys[i]
+		}
+	}
+	return found
+}
+
+// Middleware admits requests with a valid X-API-Key and puts its key in the
+// request context (KioskKeyKey). Handlers check the operation with May.
+func (k *KioskAuth) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secret := r.Header.Get("X-API-Key")
+		if secret == "" {
+			utils.WriteErrorCode(w, http.StatusUnauthorized, errcodes.Unauthorized, "Missing X-API-Key")
+			return
+		}
+		key := k.lookup(secret)
+		if key == nil {
+			metrics.AuthEvents.Record(metrics.AuthKioskKeyInvalid, utils.ClientIP(r))
+			utils.WriteErrorCode(w, http.StatusUnauthorized, errcodes.Unauthorized, "Invalid API key")
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), KioskKeyKey, key)))
+	})
+}
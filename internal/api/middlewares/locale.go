@@ -0,0 +1,35 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"simpleapi/internal/locale"
+	"simpleapi/internal/models"
+	"simpleapi/pkg/utils"
+	"strings"
+)
+
+// TimezoneHeader names the caller's time zone, e.g. "X-Timezone: Africa/Nairobi"
+const TimezoneHeader = "X-Timezone"
+
+// Locale puts the language negotiated from Accept-Language and the zone in
+// X-Timezone on the request's context (see package locale). Either header
+// may be missing; Protect then fills in the account's preferences. An
+// unknown zone is a 400 rather than silently showing the school's times.
+func Locale(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var l locale.Locale
+		if accept := r.Header.Get("Accept-Language"); accept != "" {
+			l.Language = models.LanguageFor(accept)
+		}
+		if zone := strings.TrimSpace(r.Header.Get(TimezoneHeader)); zone != "" {
+			loc, err := locale.LoadLocation(zone)
+			if err != nil {
+				utils.WriteError(w, http.StatusBadRequest, fmt.Sprintf("Unknown time zone %q in %s, use an IANA name such as Africa/Lagos", zone, TimezoneHeader))
+				return
+			}
+			l.Location = loc
+		}
+		next.ServeHTTP(w, r.WithContext(locale.NewContext(r.Context(), l)))
+	})
+}
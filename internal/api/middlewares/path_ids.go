@@ -0,0 +1,70 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/url"
+	"simpleapi/pkg/errcodes"
+	"simpleapi/pkg/utils"
+	"strings"
+)
+
+// PathIDs checks the ID wildcards of the route a request matches before any
+// handler (or Protect's user lookup) runs: {id} and every {somethingId}. A value
+// that isn't an ID (see utils.ParseID) gets 400 INVALID_ID, e.g. "Invalid
+// student ID" for /students/abc, so handlers read IDs with utils.PathID and
+// never touch the database with one that can't exist.
+func PathIDs(mux *http.ServeMux) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pattern := mux.Handler(r); pattern != "" {
+			if label, ok := badPathID(pattern, r.URL); !ok {
+				utils.WriteErrorCode(w, http.StatusBadRequest, errcodes.InvalidID, "Invalid "+label+"ID")
+				return
+			}
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// badPathID walks the pattern's segments alongside the path's. It reports
+// false, with how to name the ID ("student "), at the first ID wildcard whose
+// value isn't an ID.
+func badPathID(pattern string, u *url.URL) (string, bool) {
+	if _, path, ok := strings.Cut(pattern, " "); ok {
+		pattern = path // Drop the method
+	}
+	patternSegs := strings.Split(strings.Trim(pattern, "/"), "/")
+	pathSegs := strings.Split(strings.Trim(u.EscapedPath(), "/"), "/")
+
+	for i, seg := range patternSegs {
+		if i >= len(pathSegs) {
+			break
+		}
+		name, ok := strings.CutPrefix(seg, "{")
+		if !ok || strings.HasSuffix(name, "...}") {
+			continue
+		}
+		name = strings.TrimSuffix(name, "}")
+
+		var label string
+		switch {
+		case name == "id":
+			// Named after the collection before it: /students/{id} holds a student ID
+			if i > 0 && !strings.HasPrefix(patternSegs[i-1], "{") {
+				label = strings.ReplaceAll(strings.TrimSuffix(patternSegs[i-1], "s"), "-", " ") + " "
+			}
+		case strings.HasSuffix(name, "Id"):
+			label = strings.TrimSuffix(name, "Id") + " "
+		default:
+			continue
+		}
+
+		value, err := url.PathUnescape(pathSegs[i])
+		if err != nil {
+			return label, false
+		}
+		if _, err := utils.ParseID(value); err != nil {
+			return label, false
+		}
+	}
+	return "", true
+}
@@ -0,0 +1,36 @@
+package middlewares
+
+import (
+	"net/http"
+	"simpleapi/pkg/utils"
+	"strings"
+)
+
+// NegotiateErrorFormat picks the error format of each response: RFC 7807 when
+// the client sends "Accept: application/problem+json" or when defaultFormat
+// (ERROR_FORMAT) is problem, the classic envelope otherwise. Writers wrapping
+// the one it marks must have an Unwrap method, or utils.WriteError won't see it.
+func NegotiateErrorFormat(defaultFormat utils.ErrorFormat) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if defaultFormat == utils.ErrorFormatProblem || strings.Contains(r.Header.Get("Accept"), utils.ProblemContentType) {
+				w = &problemWriter{ResponseWriter: w}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// problemWriter marks the response as wanting problem+json errors
+type problemWriter struct {
+	http.ResponseWriter
+}
+
+func (p *problemWriter) WantsProblemJSON() bool {
+	return true
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (p *problemWriter) Unwrap() http.ResponseWriter {
+	return p.ResponseWriter
+}
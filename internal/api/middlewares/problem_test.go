@@ -0,0 +1,42 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"simpleapi/internal/models"
+	"simpleapi/pkg/redact"
+	"simpleapi/pkg/utils"
+	"testing"
+)
+
+func TestNegotiateErrorFormat(t *testing.T) {
+	tests := []struct {
+		name        string
+		defaultFmt  utils.ErrorFormat
+		accept      string
+		viewer      bool // Protect wraps the marked writer again
+		contentType string
+	}{
+		{"classic by default", utils.ErrorFormatJSON, "application/json", false, "application/json"},
+		{"client asks for problem+json", utils.ErrorFormatJSON, utils.ProblemContentType, false, utils.ProblemContentType},
+		{"ERROR_FORMAT=problem", utils.ErrorFormatProblem, "application/json", false, utils.ProblemContentType},
+		{"seen through the viewer writer", utils.ErrorFormatJSON, utils.ProblemContentType, true, utils.ProblemContentType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NegotiateErrorFormat(tt.defaultFmt)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.viewer {
+					w = utils.ForViewer(w, redact.Viewer{ID: 1, Role: models.RoleAdmin})
+				}
+				utils.WriteError(w, http.StatusNotFound, "Student not found")
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/students/9", nil)
+			r.Header.Set("Accept", tt.accept)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, r)
+			if got := rec.Header().Get("Content-Type"); got != tt.contentType {
+				t.Errorf("Content-Type = %q, want %q", got, tt.contentType)
+			}
+		})
+	}
+}
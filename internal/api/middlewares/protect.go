@@ -2,12 +2,22 @@ package middlewares
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"simpleapi/internal/locale"
+	"simpleapi/internal/metrics"
+	"simpleapi/internal/models"
+	"slices"
 	"strconv"
 	"strings"
 
 	"simpleapi/internal/repository" // Import your repo
+	"simpleapi/internal/tracing"
+	"simpleapi/pkg/errcodes"
+	"simpleapi/pkg/redact"
 	"simpleapi/pkg/utils"
+
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // DENY BY DEFAULT FIXME ------------------ REMEMBER TO IMPLEMENT THIS PATTERN
@@ -18,43 +28,66 @@ const UserKey contextKey = "currentUser"
 
 // AuthMiddleware holds the dependencies (The Database Repo)
 type AuthMiddleware struct {
-	Repo *repository.TeacherRepository
+	Repo     repository.TeacherStore
+	Tokens   utils.TokenVerifier
+	Cookies  *utils.CookieManager
+	Delivery utils.TokenDelivery
 }
 
 // NewAuthMiddleware is the constructor
-func NewAuthMiddleware(repo *repository.TeacherRepository) *AuthMiddleware {
-	return &AuthMiddleware{Repo: repo}
+func NewAuthMiddleware(repo repository.TeacherStore, tokens utils.TokenVerifier, cookies *utils.CookieManager, delivery utils.TokenDelivery) *AuthMiddleware {
+	return &AuthMiddleware{Repo: repo, Tokens: tokens, Cookies: cookies, Delivery: delivery}
 }
 
-// Protect is the actual middleware function (mirrors your TS 'protect')
+// Protect is the actual middleware function (mirrors your TS 'protect').
+// It admits web and mobile tokens; kiosk tokens only pass ProtectReadOnly.
+// Accounts that must change their password only pass ProtectPasswordChange.
 func (m *AuthMiddleware) Protect(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		var tokenString string
+	return m.protect(next, false, utils.AudienceWeb, utils.AudienceMobile)
+}
 
-		// 1. EXTRACT TOKEN (Hybrid: Cookie or Header)
-		// Check Cookie first (Web Client)
-		if cookie, err := r.Cookie("session_token"); err == nil {
-			tokenString = cookie.Value
-		}
+// ProtectReadOnly is Protect for the read-only routes a kiosk display shows,
+// which admit kiosk tokens too
+func (m *AuthMiddleware) ProtectReadOnly(next http.Handler) http.Handler {
+	return m.protect(next, false, utils.AudienceWeb, utils.AudienceMobile, utils.AudienceKiosk)
+}
 
-		// If no cookie, check Header (Mobile/API Client)
-		if tokenString == "" {
-			authHeader := r.Header.Get("Authorization")
-			if authHeader != "" && strings.HasPrefix(authHeader, "Bearer ") {
-				tokenString = strings.TrimPrefix(authHeader, "Bearer ")
-			}
-		}
+// ProtectPasswordChange is Protect for PATCH /update-password, the one route
+// open to accounts flagged with ForcePasswordChange
+func (m *AuthMiddleware) ProtectPasswordChange(next http.Handler) http.Handler {
+	return m.protect(next, true, utils.AudienceWeb, utils.AudienceMobile)
+}
+
+func (m *AuthMiddleware) protect(next http.Handler, allowPasswordChange bool, audiences ...utils.Audience) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := tracing.Start(r.Context(), "auth.protect")
+		defer span.End()
+		r = r.WithContext(ctx)
+
+		// 1. EXTRACT TOKEN (Cookie or Header, as far as AUTH_TOKEN_DELIVERY allows)
+		tokenString := tokenFromRequest(r, m.Cookies, m.Delivery)
 
 		// If still empty -> 401
 		if tokenString == "" {
-			utils.WriteError(w, http.StatusUnauthorized, "You are not logged in!")
+			utils.WriteErrorCode(w, http.StatusUnauthorized, errcodes.NotLoggedIn, "You are not logged in!")
 			return
 		}
 
 		// 2. VALIDATE TOKEN (Check Signature)
-		claims, err := utils.ValidateJWT(tokenString)
+		claims, err := m.Tokens.Verify(tokenString, audiences...)
+		if errors.Is(err, utils.ErrWrongAudience) {
+			metrics.AuthEvents.Record(metrics.AuthTokenInvalid, utils.ClientIP(r))
+			utils.WriteErrorCode(w, http.StatusForbidden, errcodes.TokenWrongAudience, "This client's session can't be used here")
+			return
+		}
 		if err != nil {
-			utils.WriteError(w, http.StatusUnauthorized, "Invalid or expired token")
+			code := errcodes.TokenInvalid
+			if errors.Is(err, utils.ErrTokenExpired) {
+				code = errcodes.TokenExpired
+			} else {
+				metrics.AuthEvents.Record(metrics.AuthTokenInvalid, utils.ClientIP(r))
+			}
+			utils.WriteErrorCode(w, http.StatusUnauthorized, code, "Invalid or expired token")
 			return
 		}
 
@@ -64,9 +97,21 @@ func (m *AuthMiddleware) Protect(next http.Handler) http.Handler {
 		userID, _ := strconv.Atoi(claims.UserID)
 
 		currentUser, err := m.Repo.GetByID(r.Context(), userID)
+		if errors.Is(err, context.Canceled) {
+			utils.ResponseError(w, err, "")
+			return
+		}
 		if err != nil {
 			// If error is "No Rows Found", it means User was DELETED
-			utils.WriteError(w, http.StatusUnauthorized, "The user belonging to this token no longer exists.")
+			metrics.AuthEvents.RecordEvent(metrics.AuthEvent{Event: metrics.AuthSessionRevoked, IP: utils.ClientIP(r), TeacherID: userID})
+			utils.WriteErrorCode(w, http.StatusUnauthorized, errcodes.AccountGone, "The user belonging to this token no longer exists.")
+			return
+		}
+
+		// Deactivated accounts lose every live session immediately (offboarding)
+		if !currentUser.IsActive {
+			metrics.AuthEvents.RecordEvent(metrics.AuthEvent{Event: metrics.AuthSessionRevoked, IP: utils.ClientIP(r), TeacherID: currentUser.ID})
+			utils.WriteErrorCode(w, http.StatusUnauthorized, errcodes.AccountDeactivated, "Account is deactivated. Please contact support")
 			return
 		}
 
@@ -77,13 +122,52 @@ func (m *AuthMiddleware) Protect(next http.Handler) http.Handler {
 		if claims.IssuedAt != nil {
 			// Extract the .Time (Go Time object) and convert to .Unix() (int64)
 			if currentUser.ChangedPasswordAfter(claims.IssuedAt.Time.Unix()) {
-				utils.WriteError(w, http.StatusUnauthorized, "User recently changed password! Please log in again.")
+				metrics.AuthEvents.RecordEvent(metrics.AuthEvent{Event: metrics.AuthSessionRevoked, IP: utils.ClientIP(r), TeacherID: currentUser.ID})
+				utils.WriteErrorCode(w, http.StatusUnauthorized, errcodes.PasswordChanged, "User recently changed password! Please log in again.")
 				return
 			}
 		}
+		// A password found in a breach (or otherwise flagged) has to go first
+		if currentUser.ForcePasswordChange && !allowPasswordChange {
+			utils.WriteErrorCode(w, http.StatusForbidden, errcodes.PasswordChangeRequired, "You must change your password before continuing")
+			return
+		}
 		// 5. SUCCESS: Attach the FULL User to Context
 		// Now handlers don't need to query the DB anymore!
-		ctx := context.WithValue(r.Context(), UserKey, currentUser)
+		span.SetAttributes(attribute.Int("enduser.id", currentUser.ID))
+		ctx = context.WithValue(r.Context(), UserKey, currentUser)
+		ctx = locale.WithPreferences(ctx, currentUser.Language, currentUser.Timezone)
+		w = utils.ForViewer(w, redact.Viewer{ID: currentUser.ID, Role: currentUser.Role})
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// tokenFromRequest finds the JWT: the session cookie first (web client), then
+// the Authorization header (mobile/API client), as far as AUTH_TOKEN_DELIVERY allows
+func tokenFromRequest(r *http.Request, cookies *utils.CookieManager, delivery utils.TokenDelivery) string {
+	if token := cookies.AccessToken(r); token != "" && delivery.AcceptsCookieToken() {
+		return token
+	}
+	if delivery.AcceptsBearerToken() {
+		authHeader := r.Header.Get("Authorization")
+		if authHeader != "" && strings.HasPrefix(authHeader, "Bearer ") {
+			return strings.TrimPrefix(authHeader, "Bearer ")
+		}
+	}
+	return ""
+}
+
+// RestrictTo only lets users with one of the given roles through.
+// It must run after Protect, which puts the user on the context.
+func (m *AuthMiddleware) RestrictTo(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := r.Context().Value(UserKey).(*models.Teacher)
+			if !ok || !slices.Contains(roles, user.Role) {
+				utils.WriteError(w, http.StatusForbidden, "You do not have permission to perform this action")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"simpleapi/pkg/utils"
+	"testing"
+)
+
+func TestTokenFromRequest(t *testing.T) {
+	cookies, err := utils.NewCookieManager(utils.CookieConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		delivery utils.TokenDelivery
+		cookie   string
+		bearer   string
+		want     string
+	}{
+		{"negotiate prefers the cookie", utils.TokenDeliveryNegotiate, "c", "b", "c"},
+		{"negotiate takes a bearer token", utils.TokenDeliveryNegotiate, "", "b", "b"},
+		{"cookie mode ignores the header", utils.TokenDeliveryCookie, "", "b", ""},
+		{"cookie mode", utils.TokenDeliveryCookie, "c", "b", "c"},
+		{"bearer mode ignores the cookie", utils.TokenDeliveryBearer, "c", "", ""},
+		{"bearer mode", utils.TokenDeliveryBearer, "c", "b", "b"},
+		{"nothing", utils.TokenDeliveryNegotiate, "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/students", nil)
+			if tt.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: utils.SessionCookieName, Value: tt.cookie})
+			}
+			if tt.bearer != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.bearer)
+			}
+			if got := tokenFromRequest(r, cookies, tt.delivery); got != tt.want {
+				t.Errorf("token = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
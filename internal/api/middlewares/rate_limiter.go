@@ -1,54 +1,172 @@
 package middlewares
 
 import (
-	"fmt"
+	"log"
+	"math"
 	"net/http"
+	"simpleapi/internal/models"
+	"simpleapi/internal/quota"
+	"simpleapi/internal/ratelimit"
+	"simpleapi/pkg/clock"
+	"simpleapi/pkg/utils"
+	"strconv"
 	"sync"
 	"time"
 )
 
-type rateLimiter struct {
-	mu        sync.Mutex
-	visitors  map[string]int
-	limit     int
-	resetTime time.Duration
+// rateLimitStore holds the buckets of every limiter; memory until SetRateLimitStore
+var (
+	rateLimitStoreMu sync.RWMutex
+	rateLimitStore   ratelimit.Store = ratelimit.NewMemory(clock.New(time.UTC))
+)
+
+// SetRateLimitStore makes every limiter keep its buckets in store, e.g. Redis
+// so the limits hold across instances (SHARED_STATE=redis)
+func SetRateLimitStore(store ratelimit.Store) {
+	rateLimitStoreMu.Lock()
+	defer rateLimitStoreMu.Unlock()
+	rateLimitStore = store
 }
 
-func (rl *rateLimiter) resetVisitorCount() {
-	for {
-		time.Sleep(rl.resetTime)
-		rl.mu.Lock()
-		rl.visitors = make(map[string]int)
-		rl.mu.Unlock()
-	}
+func currentRateLimitStore() ratelimit.Store {
+	rateLimitStoreMu.RLock()
+	defer rateLimitStoreMu.RUnlock()
+	return rateLimitStore
 }
 
-func (rl *rateLimiter) Middleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		rl.mu.Lock()
-		defer rl.mu.Unlock()
+// limit takes a request from key's bucket and sets the RateLimit-* headers. It
+// reports whether the request may go on, having answered 429 if not. Limits
+// are soft: if the store fails, the request goes through.
+func limit(w http.ResponseWriter, r *http.Request, key string, q ratelimit.Quota) bool {
+	if q.Unlimited() {
+		return true
+	}
+	store := currentRateLimitStore()
+	d, err := store.Take(r.Context(), key, q)
+	if err != nil {
+		log.Printf("rate limit: %s store failed, letting the request through: %v", store.Name(), err)
+		return true
+	}
 
-		visitorIP := r.RemoteAddr
+	h := w.Header()
+	h.Set("RateLimit-Limit", strconv.Itoa(d.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(d.Remaining))
+	h.Set("RateLimit-Reset", seconds(d.Reset))
+	h.Set("RateLimit-Policy", strconv.Itoa(q.Limit)+";w="+seconds(q.Window)+";burst="+strconv.Itoa(q.Burst))
+	if !d.Allowed {
+		h.Set("Retry-After", seconds(d.RetryAfter))
+		utils.WriteError(w, http.StatusTooManyRequests, "Too many requests, please slow down")
+		return false
+	}
+	return true
+}
 
-		rl.visitors[visitorIP]++
+// seconds rounds up, so a client that waits that long is let through
+func seconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
 
-		fmt.Printf("\nVisitor count from %v is %v\n", visitorIP, rl.visitors[visitorIP])
+// RateLimiter limits each client IP on one route to limit requests per window,
+// with no burst allowance. Public routes use it on top of the more generous
+// RoleRateLimiter.
+type RateLimiter struct {
+	quota ratelimit.Quota
+}
 
-		if rl.visitors[visitorIP] > rl.limit {
-			http.Error(w, "Too many requests", http.StatusTooManyRequests)
-			return
+// NewRateLimiter is the constructor
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{quota: ratelimit.Quota{Limit: limit, Window: window}}
+}
+
+func (rl *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The pattern keeps apart the buckets of limiters on different routes
+		if limit(w, r, "route:"+r.Pattern+":"+utils.ClientIP(r), rl.quota) {
+			next.ServeHTTP(w, r)
 		}
+	})
+}
 
-		next.ServeHTTP(w, r)
+// RoleRateLimiter limits every request by who makes it: a kiosk by its API
+// key, a signed-in user by their ID with the quota of their role, anyone else
+// by IP as anonymous. It only reads credentials, checking their signature but
+// not the database: Protect and KioskAuth still decide what gets in.
+type RoleRateLimiter struct {
+	quotas   ratelimit.Quotas
+	kiosks   *KioskAuth
+	tokens   utils.TokenVerifier
+	cookies  *utils.CookieManager
+	delivery utils.TokenDelivery
+}
+
+// NewRoleRateLimiter is the constructor
+func NewRoleRateLimiter(quotas ratelimit.Quotas, kiosks *KioskAuth, tokens utils.TokenVerifier, cookies *utils.CookieManager,
+	delivery utils.TokenDelivery) *RoleRateLimiter {
+	return &RoleRateLimiter{quotas: quotas, kiosks: kiosks, tokens: tokens, cookies: cookies, delivery: delivery}
+}
+
+func (rl *RoleRateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, class := rl.client(r)
+		q, ok := rl.quotas[class]
+		if !ok {
+			q = rl.quotas[ratelimit.ClassAnonymous]
+		}
+		if limit(w, r, key, q) {
+			next.ServeHTTP(w, r)
+		}
 	})
 }
 
-func NewRateLimiter(limit int, resetTime time.Duration) *rateLimiter {
-	rateLimit := &rateLimiter{
-		visitors:  make(map[string]int),
-		limit:     limit,
-		resetTime: resetTime,
+func (rl *RoleRateLimiter) client(r *http.Request) (key, class string) {
+	if secret := r.Header.Get("X-API-Key"); secret != "" {
+		if k := rl.kiosks.lookup(secret); k != nil {
+			return "kiosk:" + k.Name, ratelimit.ClassKiosk
+		}
 	}
-	go rateLimit.resetVisitorCount()
-	return rateLimit
+	if token := tokenFromRequest(r, rl.cookies, rl.delivery); token != "" {
+		claims, err := rl.tokens.Verify(token, utils.AudienceWeb, utils.AudienceMobile, utils.AudienceKiosk)
+		if err == nil {
+			return "user:" + claims.UserID, claims.Role
+		}
+	}
+	return "ip:" + utils.ClientIP(r), ratelimit.ClassAnonymous
+}
+
+// SchoolRateLimiter caps every request to the school together at its
+// requests-per-minute quota (models.SchoolQuotas), on top of the per-client
+// limits. Past models.QuotaWarnPercent of it, responses carry a Quota-Warning.
+type SchoolRateLimiter struct {
+	monitor *quota.Monitor
+}
+
+// NewSchoolRateLimiter is the constructor
+func NewSchoolRateLimiter(monitor *quota.Monitor) *SchoolRateLimiter {
+	return &SchoolRateLimiter{monitor: monitor}
+}
+
+func (rl *SchoolRateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		perMinute := rl.monitor.Quotas().RequestsPerMinute
+		if perMinute <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+		store := currentRateLimitStore()
+		d, err := store.Take(r.Context(), "school", ratelimit.Quota{Limit: perMinute, Window: time.Minute})
+		if err != nil {
+			log.Printf("rate limit: %s store failed, letting the request through: %v", store.Name(), err)
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !d.Allowed {
+			w.Header().Set("Retry-After", seconds(d.RetryAfter))
+			utils.WriteError(w, http.StatusTooManyRequests, "The school's request quota is used up, please slow down")
+			return
+		}
+		if used := int64(d.Limit - d.Remaining); models.NearLimit(used, int64(perMinute)) {
+			rl.monitor.Warn(w, models.QuotaStatus{Quota: models.QuotaRequests, Used: used, Limit: int64(perMinute), Warning: true})
+		}
+		next.ServeHTTP(w, r)
+	})
 }
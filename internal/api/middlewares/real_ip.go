@@ -0,0 +1,93 @@
+package middlewares
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"simpleapi/pkg/utils"
+	"strings"
+)
+
+// RealIP resolves the client IP behind our load balancer.
+// X-Forwarded-For / X-Real-IP are only believed when the TCP peer is one of the
+// trusted proxy CIDRs; otherwise anyone could spoof their IP with a header.
+type RealIP struct {
+	trusted []*net.IPNet
+}
+
+// NewRealIP parses the trusted proxy list (e.g. TRUSTED_PROXIES="10.0.0.0/8,192.168.1.5").
+// Bare IPs are treated as single-host networks.
+func NewRealIP(trustedProxies []string) (*RealIP, error) {
+	rip := &RealIP{}
+	for _, entry := range trustedProxies {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil && ip.To4() != nil {
+				entry += "/32"
+			} else {
+				entry += "/128"
+			}
+		}
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+		}
+		rip.trusted = append(rip.trusted, network)
+	}
+	return rip, nil
+}
+
+func (rip *RealIP) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := rip.resolve(r)
+		next.ServeHTTP(w, r.WithContext(utils.WithClientIP(r.Context(), ip)))
+	})
+}
+
+func (rip *RealIP) resolve(r *http.Request) string {
+	peer, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		peer = r.RemoteAddr
+	}
+	if !rip.isTrusted(peer) {
+		return peer
+	}
+
+	// Walk X-Forwarded-For right to left: the first hop we don't trust is the client.
+	// Entries left of it were supplied by the client and can't be believed. A
+	// proxy may append its hop as a header line of its own rather than to the
+	// list, so the lines are joined in order first (RFC 7230 section 3.2.2).
+	if xff := strings.Join(r.Header.Values("X-Forwarded-For"), ","); xff != "" {
+		hops := strings.Split(xff, ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if net.ParseIP(hop) == nil {
+				break
+			}
+			if !rip.isTrusted(hop) || i == 0 {
+				return hop
+			}
+		}
+	}
+
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(realIP) != nil {
+		return realIP
+	}
+	return peer
+}
+
+func (rip *RealIP) isTrusted(ipStr string) bool {
+	ip := net.ParseIP(ipStr)
+	if ip == nil {
+		return false
+	}
+	for _, network := range rip.trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package middlewares
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRealIPResolve(t *testing.T) {
+	rip, err := NewRealIP([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		peer   string
+		xff    []string
+		realIP string
+		want   string
+	}{
+		{"untrusted peer ignores headers", "203.0.113.9:1234", []string{"198.51.100.1"}, "", "203.0.113.9"},
+		{"one line", "10.0.0.1:1234", []string{"198.51.100.1, 10.0.0.2"}, "", "198.51.100.1"},
+		{"spoofed entry left of the client", "10.0.0.1:1234", []string{"1.2.3.4, 198.51.100.1"}, "", "198.51.100.1"},
+		{"hop appended as a second line", "10.0.0.1:1234", []string{"1.2.3.4", "198.51.100.1"}, "", "198.51.100.1"},
+		{"trusted hops on their own line", "10.0.0.1:1234", []string{"1.2.3.4, 198.51.100.1", "10.0.0.3"}, "", "198.51.100.1"},
+		{"every hop trusted", "10.0.0.1:1234", []string{"10.0.0.5", "10.0.0.3"}, "", "10.0.0.5"},
+		{"garbage stops the walk", "10.0.0.1:1234", []string{"not-an-ip"}, "198.51.100.7", "198.51.100.7"},
+		{"no headers", "10.0.0.1:1234", nil, "", "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.peer
+			for _, line := range tt.xff {
+				r.Header.Add("X-Forwarded-For", line)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+			if got := rip.resolve(r); got != tt.want {
+				t.Errorf("resolve = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
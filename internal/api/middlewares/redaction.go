@@ -0,0 +1,32 @@
+package middlewares
+
+import "net/http"
+
+// RedactFields hides the fields each viewer may not see (tags visibility:"...",
+// see package redact) from every response below it when on is true, as
+// RESPONSE_REDACTION asks. Writers wrapping the one it marks must have an
+// Unwrap method, or utils.WriteJSON won't see it.
+func RedactFields(on bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !on {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(&redactingWriter{ResponseWriter: w}, r)
+		})
+	}
+}
+
+// redactingWriter marks the response as redacted
+type redactingWriter struct {
+	http.ResponseWriter
+}
+
+func (rw *redactingWriter) RedactsFields() bool {
+	return true
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (rw *redactingWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
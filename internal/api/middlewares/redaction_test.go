@@ -0,0 +1,41 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"simpleapi/internal/models"
+	"simpleapi/pkg/redact"
+	"simpleapi/pkg/utils"
+	"strings"
+	"testing"
+)
+
+func TestRedactFields(t *testing.T) {
+	student := models.Student{FirstName: "Ada", Email: "ada@example.com"}
+	tests := []struct {
+		name      string
+		on        bool
+		viewer    *redact.Viewer // Set by Protect for a logged-in user
+		showEmail bool
+	}{
+		{"off", false, nil, true},
+		{"anonymous viewer", true, nil, false},
+		{"viewer allowed the field", true, &redact.Viewer{ID: 1, Role: models.RoleTeacher}, true},
+		{"viewer not allowed the field", true, &redact.Viewer{ID: 2, Role: models.RoleNurse}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := RedactFields(tt.on)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.viewer != nil {
+					w = utils.ForViewer(w, *tt.viewer)
+				}
+				utils.WriteJSON(w, http.StatusOK, "Student fetched", student)
+			}))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/students/1", nil))
+			if got := strings.Contains(rec.Body.String(), student.Email); got != tt.showEmail {
+				t.Errorf("email shown = %v, want %v: %s", got, tt.showEmail, rec.Body)
+			}
+		})
+	}
+}
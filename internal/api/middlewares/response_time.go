@@ -3,6 +3,7 @@ package middlewares
 import (
 	"fmt"
 	"net/http"
+	"simpleapi/pkg/utils"
 	"time"
 )
 
@@ -22,7 +23,7 @@ func ResponseTimeMiddleware(next http.Handler) http.Handler {
 
 		duration = time.Since(start)
 
-		fmt.Printf("Method: %s, URL: %s, Status: %d, Duration: %v\n", r.Method, r.URL, wrappedWriter.status, duration.String())
+		fmt.Printf("Client: %s, Method: %s, URL: %s, Status: %d, Duration: %v\n", utils.ClientIP(r), r.Method, r.URL, wrappedWriter.status, duration.String())
 
 		fmt.Println("Sent Response from Response Time Middleware")
 	})
@@ -1,26 +1,307 @@
 package middlewares
 
-import "net/http"
-
-func SecurityHeaders(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("X-DNS-Prefetch-Control", "off")
-		w.Header().Set("X-Frame-Options", "DENY") // prevents the site from being embeded in an iframe preventing click jacking attacks
-		w.Header().Set("X-Frame-Options", "DENY")
-		w.Header().Set("X-XSS-Protection", "1; mode=block")
-		w.Header().Set("X-Content-Type-Options", "nosniff")
-		w.Header().Set("Strict-Transport-Security", "max-age=63072000; includeSubDomains; preload")
-		w.Header().Set("Content-Security-Policy", "default-src 'self'")
-		w.Header().Set("Referrer-Policy", "no-referrer")
-		w.Header().Set("X-Powered-By", "Django")
-		w.Header().Set("Server", "")
-		w.Header().Set("X-Permitted-Cross-Domain-Policies", "none")
-		w.Header().Set("Cache-Control", "no-store, no-cache, must-revalidate, max-age=0")
-		w.Header().Set("Cross-Origin-Resource-Policy", "same-origin")
-		w.Header().Set("Cross-Origin-Opener-Policy", "same-origin")
-		w.Header().Set("Cross-Origin-Embedder-Policy", "require-corp")
-		w.Header().Set("Permissions-Policy", "geolocation=(self), microphone=()")
-
-		next.ServeHTTP(w, r)
-	})
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"simpleapi/internal/buildinfo"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CSP is a Content-Security-Policy, kept as directives in order so the header
+// is the same on every response. Build one with ParseCSP or Set/Allow.
+type CSP struct {
+	directives []cspDirective
+}
+
+type cspDirective struct {
+	name    string
+	sources []string
+}
+
+// ParseCSP reads a policy in header syntax, e.g. "default-src 'self'; img-src 'self' data:"
+func ParseCSP(s string) (CSP, error) {
+	var csp CSP
+	for _, part := range strings.Split(s, ";") {
+		fields := strings.Fields(part)
+		if len(fields) == 0 {
+			continue
+		}
+		name := strings.ToLower(fields[0])
+		if strings.ContainsAny(name, "',") {
+			return CSP{}, fmt.Errorf("bad CSP directive %q", strings.TrimSpace(part))
+		}
+		csp = csp.Allow(name, fields[1:]...)
+	}
+	return csp, nil
+}
+
+// Set replaces a directive's sources, adding the directive if it's missing
+func (c CSP) Set(name string, sources ...string) CSP {
+	out := CSP{directives: make([]cspDirective, 0, len(c.directives)+1)}
+	found := false
+	for _, d := range c.directives {
+		if d.name == name {
+			d = cspDirective{name: name, sources: slices.Clone(sources)}
+			found = true
+		}
+		out.directives = append(out.directives, d)
+	}
+	if !found {
+		out.directives = append(out.directives, cspDirective{name: name, sources: slices.Clone(sources)})
+	}
+	return out
+}
+
+// Allow adds sources to a directive. A directive the policy doesn't have yet
+// starts from default-src, since that's what the browser fell back to before.
+func (c CSP) Allow(name string, sources ...string) CSP {
+	current, ok := c.sources(name)
+	if !ok && strings.HasSuffix(name, "-src") {
+		current, _ = c.sources("default-src")
+	}
+	merged := slices.Clone(current)
+	for _, s := range sources {
+		if !slices.Contains(merged, s) {
+			merged = append(merged, s)
+		}
+	}
+	// 'none' means nothing else, so it has to go once anything is allowed
+	if len(merged) > 1 {
+		merged = slices.DeleteFunc(merged, func(s string) bool { return s == "'none'" })
+	}
+	return c.Set(name, merged...)
+}
+
+// Merge allows every source of other on top of c
+func (c CSP) Merge(other CSP) CSP {
+	for _, d := range other.directives {
+		c = c.Allow(d.name, d.sources...)
+	}
+	return c
+}
+
+func (c CSP) sources(name string) ([]string, bool) {
+	for _, d := range c.directives {
+		if d.name == name {
+			return d.sources, true
+		}
+	}
+	return nil, false
+}
+
+// String renders the policy as the header value
+func (c CSP) String() string {
+	parts := make([]string, len(c.directives))
+	for i, d := range c.directives {
+		parts[i] = strings.TrimSpace(d.name + " " + strings.Join(d.sources, " "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// CSPRelaxation loosens the policy under a path prefix, e.g. for a page a
+// deployment serves that loads scripts or styles from a CDN
+type CSPRelaxation struct {
+	Prefix string
+	Allow  CSP // Sources added on top of the base policy
+}
+
+// SecurityHeadersConfig drives SecurityHeaders; see SecurityHeadersFromEnv for the defaults
+type SecurityHeadersConfig struct {
+	CSP         CSP
+	Relaxations []CSPRelaxation
+
+	// HSTS; a zero MaxAge leaves Strict-Transport-Security out entirely
+	HSTSMaxAge            time.Duration
+	HSTSIncludeSubdomains bool
+	HSTSPreload           bool
+
+	ReferrerPolicy    string
+	PermissionsPolicy string
+
+	Server string // The Server header; empty sends it blank
+}
+
+var (
+	// The API only serves JSON, so nothing needs to load from it
+	defaultCSP = "default-src 'self'; frame-ancestors 'none'; base-uri 'none'; form-action 'self'"
+
+	referrerPolicies = []string{
+		"no-referrer", "no-referrer-when-downgrade", "origin", "origin-when-cross-origin",
+		"same-origin", "strict-origin", "strict-origin-when-cross-origin", "unsafe-url",
+	}
+)
+
+// SecurityHeadersFromEnv reads the security headers config:
+//
+//	SECURITY_CSP             base policy, e.g. "default-src 'self'; img-src 'self' data:"
+//	SECURITY_CSP_RELAX       per-route additions, e.g. "/status=style-src 'unsafe-inline', /portal=script-src https://cdn.example.com"
+//	HSTS_MAX_AGE             e.g. 8760h; 0 turns HSTS off
+//	HSTS_INCLUDE_SUBDOMAINS  true|false
+//	HSTS_PRELOAD             true|false; needs a max age of a year and subdomains
+//	REFERRER_POLICY          e.g. strict-origin-when-cross-origin
+//	PERMISSIONS_POLICY       e.g. "geolocation=(self), camera=()"
+//	SERVER_HEADER            off (default), version for "school-api/1.4.0", or a value to send as is
+//
+// Unset variables keep the defaults below. No route is relaxed unless
+// SECURITY_CSP_RELAX names it: every route gets the base policy and COEP.
+func SecurityHeadersFromEnv() (SecurityHeadersConfig, error) {
+	cfg := SecurityHeadersConfig{
+		HSTSMaxAge:            2 * 365 * 24 * time.Hour,
+		HSTSIncludeSubdomains: true,
+		HSTSPreload:           true,
+		ReferrerPolicy:        "no-referrer",
+		PermissionsPolicy:     "geolocation=(self), microphone=()",
+	}
+
+	var err error
+	if cfg.CSP, err = ParseCSP(envOr("SECURITY_CSP", defaultCSP)); err != nil {
+		return cfg, fmt.Errorf("SECURITY_CSP: %w", err)
+	}
+	if cfg.Relaxations, err = parseCSPRelaxations(os.Getenv("SECURITY_CSP_RELAX")); err != nil {
+		return cfg, fmt.Errorf("SECURITY_CSP_RELAX: %w", err)
+	}
+
+	if v := os.Getenv("HSTS_MAX_AGE"); v != "" {
+		if cfg.HSTSMaxAge, err = time.ParseDuration(v); err != nil || cfg.HSTSMaxAge < 0 {
+			return cfg, fmt.Errorf("HSTS_MAX_AGE: want a duration such as 8760h, got %q", v)
+		}
+	}
+	for _, b := range []struct {
+		env string
+		dst *bool
+	}{
+		{"HSTS_INCLUDE_SUBDOMAINS", &cfg.HSTSIncludeSubdomains},
+		{"HSTS_PRELOAD", &cfg.HSTSPreload},
+	} {
+		if v := os.Getenv(b.env); v != "" {
+			if *b.dst, err = strconv.ParseBool(v); err != nil {
+				return cfg, fmt.Errorf("%s: want true or false, got %q", b.env, v)
+			}
+		}
+	}
+	// Browsers' preload lists refuse anything weaker, and getting off them takes months
+	if cfg.HSTSPreload && cfg.HSTSMaxAge > 0 && (cfg.HSTSMaxAge < 365*24*time.Hour || !cfg.HSTSIncludeSubdomains) {
+		return cfg, fmt.Errorf("HSTS_PRELOAD needs HSTS_MAX_AGE of at least 8760h and HSTS_INCLUDE_SUBDOMAINS")
+	}
+
+	if v := os.Getenv("REFERRER_POLICY"); v != "" {
+		if !slices.Contains(referrerPolicies, v) {
+			return cfg, fmt.Errorf("REFERRER_POLICY: unknown policy %q", v)
+		}
+		cfg.ReferrerPolicy = v
+	}
+	if v := os.Getenv("PERMISSIONS_POLICY"); v != "" {
+		cfg.PermissionsPolicy = v
+	}
+	// Off by default: the version tells an attacker which advisories apply
+	switch v := os.Getenv("SERVER_HEADER"); v {
+	case "", "off":
+	case "version":
+		cfg.Server = buildinfo.Product + "/" + buildinfo.Version()
+	default:
+		cfg.Server = v
+	}
+	return cfg, nil
+}
+
+func envOr(name, fallback string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// parseCSPRelaxations reads "<prefix>=<directives>" entries separated by commas
+func parseCSPRelaxations(s string) ([]CSPRelaxation, error) {
+	var relaxations []CSPRelaxation
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" || entry == "off" {
+			continue
+		}
+		prefix, policy, ok := strings.Cut(entry, "=")
+		prefix = strings.TrimRight(strings.TrimSpace(prefix), "/")
+		if !ok || !strings.HasPrefix(prefix, "/") {
+			return nil, fmt.Errorf("bad entry %q, want /<path>=<directives>", entry)
+		}
+		allow, err := ParseCSP(policy)
+		if err != nil {
+			return nil, err
+		}
+		relaxations = append(relaxations, CSPRelaxation{Prefix: prefix, Allow: allow})
+	}
+	return relaxations, nil
+}
+
+// hstsValue renders Strict-Transport-Security, "" when HSTS is off
+func (cfg SecurityHeadersConfig) hstsValue() string {
+	if cfg.HSTSMaxAge <= 0 {
+		return ""
+	}
+	v := "max-age=" + strconv.Itoa(int(cfg.HSTSMaxAge.Seconds()))
+	if cfg.HSTSIncludeSubdomains {
+		v += "; includeSubDomains"
+	}
+	if cfg.HSTSPreload {
+		v += "; preload"
+	}
+	return v
+}
+
+// SecurityHeaders sets the hardening headers on every response. Routes under a
+// relaxation get its sources added to the CSP and no Cross-Origin-Embedder-Policy,
+// which would otherwise block the CDN assets the relaxation is there for.
+func SecurityHeaders(cfg SecurityHeadersConfig) func(http.Handler) http.Handler {
+	// Render everything once; the longest prefix wins when relaxations overlap
+	type relaxed struct {
+		prefix, csp string
+	}
+	routes := make([]relaxed, len(cfg.Relaxations))
+	for i, rx := range cfg.Relaxations {
+		routes[i] = relaxed{prefix: rx.Prefix, csp: cfg.CSP.Merge(rx.Allow).String()}
+	}
+	slices.SortFunc(routes, func(a, b relaxed) int { return len(b.prefix) - len(a.prefix) })
+	csp := cfg.CSP.String()
+	hsts := cfg.hstsValue()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			h := w.Header()
+			h.Set("X-DNS-Prefetch-Control", "off")
+			h.Set("X-Frame-Options", "DENY") // prevents the site from being embeded in an iframe preventing click jacking attacks
+			h.Set("X-XSS-Protection", "1; mode=block")
+			h.Set("X-Content-Type-Options", "nosniff")
+			if hsts != "" {
+				h.Set("Strict-Transport-Security", hsts)
+			}
+			h.Set("Referrer-Policy", cfg.ReferrerPolicy)
+			h.Set("X-Powered-By", "Django")
+			h.Set("Server", cfg.Server)
+			h.Set("X-Permitted-Cross-Domain-Policies", "none")
+			h.Set("Cache-Control", "no-store, no-cache, must-revalidate, max-age=0")
+			h.Set("Cross-Origin-Resource-Policy", "same-origin")
+			h.Set("Cross-Origin-Opener-Policy", "same-origin")
+			if cfg.PermissionsPolicy != "" {
+				h.Set("Permissions-Policy", cfg.PermissionsPolicy)
+			}
+
+			policy, relaxedRoute := csp, false
+			for _, rt := range routes {
+				if r.URL.Path == rt.prefix || strings.HasPrefix(r.URL.Path, rt.prefix+"/") {
+					policy, relaxedRoute = rt.csp, true
+					break
+				}
+			}
+			h.Set("Content-Security-Policy", policy)
+			if !relaxedRoute {
+				h.Set("Cross-Origin-Embedder-Policy", "require-corp")
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
 }
@@ -0,0 +1,54 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSecurityHeadersRelaxations(t *testing.T) {
+	for _, env := range []string{"SECURITY_CSP", "SECURITY_CSP_RELAX", "HSTS_MAX_AGE", "HSTS_INCLUDE_SUBDOMAINS",
+		"HSTS_PRELOAD", "REFERRER_POLICY", "PERMISSIONS_POLICY", "SERVER_HEADER"} {
+		t.Setenv(env, "")
+	}
+	cfg, err := SecurityHeadersFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Relaxations) != 0 {
+		t.Fatalf("default relaxations = %v, want none", cfg.Relaxations)
+	}
+
+	t.Setenv("SECURITY_CSP_RELAX", "/status=style-src 'unsafe-inline'")
+	relaxedCfg, err := SecurityHeadersFromEnv()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	strict := "default-src 'self'; frame-ancestors 'none'; base-uri 'none'; form-action 'self'"
+	tests := []struct {
+		name     string
+		cfg      SecurityHeadersConfig
+		path     string
+		wantCSP  string
+		wantCOEP string
+	}{
+		{"default docs", cfg, "/docs", strict, "require-corp"},
+		{"default status", cfg, "/status", strict, "require-corp"},
+		{"opted-in route", relaxedCfg, "/status/db", strict + "; style-src 'self' 'unsafe-inline'", ""},
+		{"prefix only matches whole segments", relaxedCfg, "/statusx", strict, "require-corp"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := SecurityHeaders(tt.cfg)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+			if got := w.Header().Get("Content-Security-Policy"); got != tt.wantCSP {
+				t.Errorf("CSP = %q, want %q", got, tt.wantCSP)
+			}
+			if got := w.Header().Get("Cross-Origin-Embedder-Policy"); got != tt.wantCOEP {
+				t.Errorf("COEP = %q, want %q", got, tt.wantCOEP)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package middlewares
+
+import (
+	"fmt"
+	"net/http"
+	"simpleapi/internal/tracing"
+	"simpleapi/pkg/utils"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing starts the server span for every request, continuing any trace
+// the caller sent in traceparent/tracestate headers. Mount it outermost.
+func Tracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+
+		ctx, span := tracing.Tracer().Start(ctx, r.Method+" "+r.URL.Path,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("url.path", r.URL.Path),
+				attribute.String("client.address", utils.ClientIP(r)),
+			),
+		)
+		defer span.End()
+
+		wrappedWriter := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(wrappedWriter, r.WithContext(ctx))
+
+		span.SetAttributes(attribute.Int("http.response.status_code", wrappedWriter.status))
+		if wrappedWriter.status >= 500 {
+			span.SetStatus(codes.Error, fmt.Sprintf("HTTP %d", wrappedWriter.status))
+		}
+	})
+}
+
+// TraceRoute renames the server span to the matched route pattern
+// ("GET /teachers/{id}") so traces group by endpoint, not by raw URL.
+// Wrap it directly around a ServeMux: the mux fills in r.Pattern while routing.
+func TraceRoute(mux http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mux.ServeHTTP(w, r)
+
+		if r.Pattern != "" {
+			span := trace.SpanFromContext(r.Context())
+			span.SetName(r.Pattern)
+			span.SetAttributes(attribute.String("http.route", r.Pattern))
+		}
+	})
+}
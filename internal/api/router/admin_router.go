@@ -0,0 +1,24 @@
+package router
+
+import (
+	"expvar"
+	"net/http"
+	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+	"simpleapi/internal/models"
+)
+
+func registerAdminRoutes(mux *http.ServeMux, th *handlers.TeacherHandler, trash *handlers.TrashHandler, assignments *handlers.AssignmentHandler, security *handlers.SecurityHandler, am *mw.AuthMiddleware) {
+	adminOnly := func(next http.HandlerFunc) http.Handler {
+		return am.Protect(am.RestrictTo(models.RoleAdmin)(next))
+	}
+	mux.Handle("PATCH /admin/teachers/status", adminOnly(th.SetTeachersStatus))
+	mux.Handle("GET /admin/teachers/{id}/classes", adminOnly(assignments.GetClasses))
+	mux.Handle("PUT /admin/teachers/{id}/classes", adminOnly(assignments.SetClasses))
+	mux.Handle("POST /teachers/assignments/import", adminOnly(assignments.ImportAssignments))
+	mux.Handle("GET /admin/trash", adminOnly(trash.GetTrash))
+	mux.Handle("POST /admin/trash/{entity}/{id}/restore", adminOnly(trash.Restore))
+	// Process counters (package metrics) and Go runtime stats, as expvar JSON
+	mux.Handle("GET /admin/metrics", adminOnly(expvar.Handler().ServeHTTP))
+	mux.Handle("GET /admin/security/summary", adminOnly(security.GetSummary))
+}
@@ -0,0 +1,21 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+)
+
+// Who may decide is per request (its approver role), so the handler checks it
+func registerApprovalRoutes(mux *http.ServeMux, h *handlers.ApprovalHandler, am *mw.AuthMiddleware) {
+	protect := func(next http.HandlerFunc) http.Handler {
+		return am.Protect(next)
+	}
+	mux.Handle("POST /approvals", protect(h.Submit))
+	mux.Handle("GET /approvals", protect(h.GetMine))
+	mux.Handle("GET /approvals/pending", protect(h.GetPending))
+	mux.Handle("GET /approvals/{id}", protect(h.GetApproval))
+	mux.Handle("POST /approvals/{id}/approve", protect(h.Approve))
+	mux.Handle("POST /approvals/{id}/reject", protect(h.Reject))
+	mux.Handle("POST /approvals/{id}/cancel", protect(h.Cancel))
+}
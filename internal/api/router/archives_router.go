@@ -0,0 +1,18 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+	"simpleapi/internal/models"
+)
+
+func registerArchiveRoutes(mux *http.ServeMux, h *handlers.ArchiveHandler, am *mw.AuthMiddleware) {
+	adminOnly := func(next http.HandlerFunc) http.Handler {
+		return am.Protect(am.RestrictTo(models.RoleAdmin)(next))
+	}
+	mux.Handle("POST /admin/academic-years/{year}/archive", adminOnly(h.ArchiveYear))
+	mux.Handle("GET /admin/archives", adminOnly(h.ListArchives))
+	mux.Handle("GET /admin/archives/{id}", adminOnly(h.GetArchive))
+	mux.Handle("GET /admin/archives/{id}/download", adminOnly(h.DownloadArchive))
+}
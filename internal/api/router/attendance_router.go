@@ -0,0 +1,15 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+)
+
+func registerAttendanceRoutes(mux *http.ServeMux, h *handlers.AttendanceHandler, am *mw.AuthMiddleware) {
+	protect := func(next http.HandlerFunc) http.Handler {
+		return am.Protect(next)
+	}
+	mux.Handle("PUT /classes/{class}/attendance/{date}", protect(h.TakeRegister))
+	mux.Handle("GET /classes/{class}/attendance/{date}", protect(h.GetRegister))
+}
@@ -0,0 +1,12 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+	"simpleapi/internal/models"
+)
+
+func registerAuditRoutes(mux *http.ServeMux, h *handlers.AuditHandler, am *mw.AuthMiddleware) {
+	mux.Handle("GET /admin/audit-logs/export", am.Protect(am.RestrictTo(models.RoleAdmin)(http.HandlerFunc(h.ExportAuditLog))))
+}
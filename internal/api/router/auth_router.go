@@ -3,13 +3,17 @@ package router
 import (
 	"net/http"
 	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+	"simpleapi/internal/models"
 )
 
-func authenticationRoutes(mux *http.ServeMux, h *handlers.TeacherHandler) {
+func authenticationRoutes(mux *http.ServeMux, h *handlers.TeacherHandler, am *mw.AuthMiddleware) {
 	mux.HandleFunc("POST /login", h.LoginTeacher)
 	mux.HandleFunc("POST /logout", h.Logout)
-	mux.HandleFunc("POST /register", h.RegisterTeacher)
-	// mux.HandleFunc("PATCH /update-password")
+	mux.HandleFunc("POST /refresh", h.Refresh)
+	// Accounts are opened by an admin (the first one through POST /setup)
+	mux.Handle("POST /register", am.Protect(am.RestrictTo(models.RoleAdmin)(http.HandlerFunc(h.RegisterTeacher))))
+	mux.Handle("PATCH /update-password", am.ProtectPasswordChange(http.HandlerFunc(h.UpdatePassword)))
 	// mux.HandleFunc("POST /forgot-password")
 	// mux.HandleFunc("POST /reset-password/{reset-token}")
 }
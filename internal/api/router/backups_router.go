@@ -0,0 +1,18 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+	"simpleapi/internal/models"
+)
+
+func registerBackupRoutes(mux *http.ServeMux, h *handlers.BackupHandler, am *mw.AuthMiddleware) {
+	adminOnly := func(next http.HandlerFunc) http.Handler {
+		return am.Protect(am.RestrictTo(models.RoleAdmin)(next))
+	}
+	mux.Handle("POST /admin/backup", adminOnly(h.StartBackup))
+	mux.Handle("GET /admin/backups", adminOnly(h.ListBackups))
+	mux.Handle("GET /admin/backups/{id}", adminOnly(h.GetBackup))
+	mux.Handle("GET /admin/backups/{id}/download", adminOnly(h.DownloadBackup))
+}
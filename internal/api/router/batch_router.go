@@ -0,0 +1,13 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+)
+
+func registerBatchRoutes(mux *http.ServeMux, h *handlers.BatchHandler, am *mw.AuthMiddleware) {
+	// Sub-requests authenticate on their own; Protect here only keeps anonymous
+	// clients from fanning one request out into fifty
+	mux.Handle("POST /batch", am.Protect(http.HandlerFunc(h.Batch)))
+}
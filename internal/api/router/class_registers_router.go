@@ -0,0 +1,11 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+)
+
+func registerClassRegisterRoutes(mux *http.ServeMux, h *handlers.ClassRegisterHandler, am *mw.AuthMiddleware) {
+	mux.Handle("GET /classes/{class}/register", am.Protect(http.HandlerFunc(h.GetPrintableRegister)))
+}
@@ -0,0 +1,20 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+	"simpleapi/internal/models"
+)
+
+func registerCommentRoutes(mux *http.ServeMux, h *handlers.CommentHandler, am *mw.AuthMiddleware) {
+	protect := func(next http.HandlerFunc) http.Handler {
+		return am.Protect(next)
+	}
+	adminOnly := func(next http.HandlerFunc) http.Handler {
+		return am.Protect(am.RestrictTo(models.RoleAdmin)(next))
+	}
+	mux.Handle("POST /students/{id}/comments", protect(h.AddComment))
+	mux.Handle("PATCH /students/{id}/comments/{commentId}", adminOnly(h.ModerateComment))
+	mux.Handle("GET /students/{id}/report-card", protect(h.GetReportCard))
+}
@@ -0,0 +1,17 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+	"simpleapi/internal/models"
+)
+
+func registerCommunicationRoutes(mux *http.ServeMux, h *handlers.CommunicationHandler, am *mw.AuthMiddleware) {
+	adminOnly := func(next http.HandlerFunc) http.Handler {
+		return am.Protect(am.RestrictTo(models.RoleAdmin)(next))
+	}
+	mux.Handle("POST /admin/communications", adminOnly(h.CreateCampaign))
+	mux.Handle("GET /admin/communications", adminOnly(h.ListCampaigns))
+	mux.Handle("GET /admin/communications/{id}", adminOnly(h.GetCampaign))
+}
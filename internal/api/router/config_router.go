@@ -0,0 +1,16 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+	"simpleapi/internal/models"
+)
+
+func registerConfigRoutes(mux *http.ServeMux, h *handlers.ConfigHandler, am *mw.AuthMiddleware) {
+	adminOnly := func(next http.HandlerFunc) http.Handler {
+		return am.Protect(am.RestrictTo(models.RoleAdmin)(next))
+	}
+	mux.Handle("GET /admin/config", adminOnly(h.ExportConfig))
+	mux.Handle("PUT /admin/config", adminOnly(h.ImportConfig))
+}
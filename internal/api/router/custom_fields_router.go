@@ -0,0 +1,17 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+	"simpleapi/internal/models"
+)
+
+func registerCustomFieldRoutes(mux *http.ServeMux, h *handlers.CustomFieldHandler, am *mw.AuthMiddleware) {
+	adminOnly := func(next http.HandlerFunc) http.Handler {
+		return am.Protect(am.RestrictTo(models.RoleAdmin)(next))
+	}
+	mux.Handle("GET /admin/custom-fields", adminOnly(h.ListFields))
+	mux.Handle("POST /admin/custom-fields", adminOnly(h.CreateField))
+	mux.Handle("DELETE /admin/custom-fields/{id}", adminOnly(h.DeleteField))
+}
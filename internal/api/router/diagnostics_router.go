@@ -0,0 +1,12 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+	"simpleapi/internal/models"
+)
+
+func registerDiagnosticsRoutes(mux *http.ServeMux, h *handlers.DiagnosticsHandler, am *mw.AuthMiddleware) {
+	mux.Handle("GET /admin/diagnostics", am.Protect(am.RestrictTo(models.RoleAdmin)(http.HandlerFunc(h.GetDiagnostics))))
+}
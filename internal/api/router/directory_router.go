@@ -0,0 +1,16 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+	"time"
+)
+
+func registerDirectoryRoutes(mux *http.ServeMux, h *handlers.DirectoryHandler, am *mw.AuthMiddleware) {
+	// Public and unauthenticated, so keep scrapers on a short leash
+	rl := mw.NewRateLimiter(30, time.Minute)
+	mux.Handle("GET /directory/teachers", rl.Middleware(http.HandlerFunc(h.GetDirectory)))
+
+	mux.Handle("PUT /teachers/{id}/directory", am.Protect(http.HandlerFunc(h.SetListing)))
+}
@@ -0,0 +1,15 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+	"time"
+)
+
+func registerDownloadRoutes(mux *http.ServeMux, h *handlers.DownloadHandler) {
+	// Links are followed without an account, from emails; the signature stops
+	// guessing, the limit stops a leaked link from being hammered
+	rl := mw.NewRateLimiter(60, time.Minute)
+	mux.Handle("GET /downloads/{token}", rl.Middleware(http.HandlerFunc(h.GetDownload)))
+}
@@ -0,0 +1,15 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+)
+
+func registerEmailChangeRoutes(mux *http.ServeMux, h *handlers.EmailChangeHandler, am *mw.AuthMiddleware) {
+	mux.Handle("POST /teachers/{id}/email-change", am.Protect(http.HandlerFunc(h.RequestChange)))
+	// The links in the emails carry their own token; the undo link must work
+	// for someone locked out of their account
+	mux.HandleFunc("POST /email-change/confirm", h.Confirm)
+	mux.HandleFunc("POST /email-change/undo", h.Undo)
+}
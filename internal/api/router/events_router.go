@@ -0,0 +1,28 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+	"simpleapi/internal/models"
+	"time"
+)
+
+func registerEventRoutes(mux *http.ServeMux, h *handlers.EventHandler, am *mw.AuthMiddleware) {
+	// Kiosk displays show the calendar, so reads take kiosk tokens too
+	readOnly := func(next http.HandlerFunc) http.Handler {
+		return am.ProtectReadOnly(next)
+	}
+	adminOnly := func(next http.HandlerFunc) http.Handler {
+		return am.Protect(am.RestrictTo(models.RoleAdmin)(next))
+	}
+	mux.Handle("GET /events", readOnly(h.GetEvents))
+	mux.Handle("GET /events/{id}", readOnly(h.GetEventByID))
+	mux.Handle("POST /events", adminOnly(h.CreateEvent))
+	mux.Handle("PUT /events/{id}", adminOnly(h.UpdateEvent))
+	mux.Handle("DELETE /events/{id}", adminOnly(h.DeleteEvent))
+
+	// Public so calendar apps can subscribe; polled often, so rate limited
+	rl := mw.NewRateLimiter(60, time.Minute)
+	mux.Handle("GET /calendar.ics", rl.Middleware(http.HandlerFunc(h.GetICalFeed)))
+}
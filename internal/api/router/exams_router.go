@@ -0,0 +1,27 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+	"simpleapi/internal/models"
+)
+
+// The office plans exam sessions; any staff member can read them to find
+// where they invigilate
+func registerExamRoutes(mux *http.ServeMux, h *handlers.ExamHandler, am *mw.AuthMiddleware) {
+	protect := func(next http.HandlerFunc) http.Handler {
+		return am.Protect(next)
+	}
+	officeOnly := func(next http.HandlerFunc) http.Handler {
+		return am.Protect(am.RestrictTo(models.RoleAdmin, models.RoleRegistrar)(next))
+	}
+	mux.Handle("POST /exams/sessions", officeOnly(h.CreateSession))
+	mux.Handle("GET /exams/sessions", protect(h.GetSessions))
+	mux.Handle("GET /exams/sessions/{id}", protect(h.GetSession))
+	mux.Handle("PUT /exams/sessions/{id}", officeOnly(h.UpdateSession))
+	mux.Handle("DELETE /exams/sessions/{id}", officeOnly(h.DeleteSession))
+	mux.Handle("POST /exams/sessions/{id}/seating", officeOnly(h.GenerateSeating))
+	mux.Handle("POST /exams/sessions/{id}/invigilators", officeOnly(h.AssignInvigilators))
+	mux.Handle("GET /exams/sessions/{id}/allocation", protect(h.GetAllocation))
+}
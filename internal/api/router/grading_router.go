@@ -0,0 +1,26 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+	"simpleapi/internal/models"
+)
+
+func registerGradingRoutes(mux *http.ServeMux, h *handlers.GradingHandler, am *mw.AuthMiddleware) {
+	protect := func(next http.HandlerFunc) http.Handler {
+		return am.Protect(next)
+	}
+	adminOnly := func(next http.HandlerFunc) http.Handler {
+		return am.Protect(am.RestrictTo(models.RoleAdmin)(next))
+	}
+	mux.Handle("GET /grading-schemes", protect(h.GetSchemes))
+	mux.Handle("POST /grading-schemes", adminOnly(h.CreateScheme))
+	mux.Handle("PUT /grading-schemes/{id}", adminOnly(h.UpdateScheme))
+	mux.Handle("DELETE /grading-schemes/{id}", adminOnly(h.DeleteScheme))
+	mux.Handle("POST /students/{id}/scores", protect(h.PostScore))
+	mux.Handle("GET /students/{id}/scores", protect(h.GetScores))
+	mux.Handle("GET /classes/{class}/grade-stats", protect(h.GetClassStats))
+	mux.Handle("GET /grades/{id}/corrections", protect(h.GetCorrections))
+	mux.Handle("POST /grades/{id}/corrections", adminOnly(h.CorrectScore))
+}
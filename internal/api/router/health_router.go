@@ -0,0 +1,14 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+)
+
+// registerHealthRoutes mounts the readiness probe and the version. Like
+// /metrics they sit outside /api/v1, and they need no login: the probe tells
+// nothing but "degraded", the version only which build runs.
+func registerHealthRoutes(mux *http.ServeMux, h *handlers.HealthHandler) {
+	mux.HandleFunc("GET /readyz", h.GetReady)
+	mux.HandleFunc("GET /version", h.GetVersion)
+}
@@ -0,0 +1,17 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+	"simpleapi/internal/models"
+)
+
+// History exposes who changed what, so it is admin-only
+func registerHistoryRoutes(mux *http.ServeMux, h *handlers.HistoryHandler, am *mw.AuthMiddleware) {
+	adminOnly := func(next http.HandlerFunc) http.Handler {
+		return am.Protect(am.RestrictTo(models.RoleAdmin)(next))
+	}
+	mux.Handle("GET /teachers/{id}/history", adminOnly(h.GetTeacherHistory))
+	mux.Handle("GET /students/{id}/history", adminOnly(h.GetStudentHistory))
+}
@@ -0,0 +1,17 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+	"time"
+)
+
+func registerIDCardRoutes(mux *http.ServeMux, h *handlers.IDCardHandler, am *mw.AuthMiddleware) {
+	mux.Handle("GET /students/{id}/idcard", am.Protect(http.HandlerFunc(h.GetIDCard)))
+
+	// Gate scanners verify without an account; a gate at the morning rush scans
+	// far more than the transcript verifier sees, hence the higher limit
+	rl := mw.NewRateLimiter(300, time.Minute)
+	mux.Handle("GET /verify-card", rl.Middleware(http.HandlerFunc(h.VerifyIDCard)))
+}
@@ -0,0 +1,16 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+	"simpleapi/internal/models"
+)
+
+func registerJobRoutes(mux *http.ServeMux, h *handlers.JobHandler, am *mw.AuthMiddleware) {
+	adminOnly := func(next http.HandlerFunc) http.Handler {
+		return am.Protect(am.RestrictTo(models.RoleAdmin)(next))
+	}
+	mux.Handle("GET /admin/jobs", adminOnly(h.ListJobs))
+	mux.Handle("POST /admin/jobs/{id}/retry", adminOnly(h.RetryJob))
+}
@@ -0,0 +1,18 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+	"time"
+)
+
+// registerKioskRoutes mounts the kiosk API. It stays this one operation on
+// purpose: a kiosk key opens nothing else (see mw.KioskAuth).
+func registerKioskRoutes(mux *http.ServeMux, h *handlers.KioskHandler, am *mw.AuthMiddleware, ka *mw.KioskAuth) {
+	// A rush at the gate is a few students a minute per tablet; more is a script guessing keys
+	rl := mw.NewRateLimiter(60, time.Minute)
+	mux.Handle("POST /kiosk/checkin", rl.Middleware(ka.Middleware(http.HandlerFunc(h.Checkin))))
+
+	mux.Handle("GET /attendance/movements", am.Protect(http.HandlerFunc(h.ListMovements)))
+}
@@ -0,0 +1,28 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+	"simpleapi/internal/models"
+)
+
+// Admins write the letter templates; the office reads them to print letters
+// for students and keeps the documents that come out
+func registerLetterRoutes(mux *http.ServeMux, h *handlers.LetterHandler, am *mw.AuthMiddleware) {
+	adminOnly := func(next http.HandlerFunc) http.Handler {
+		return am.Protect(am.RestrictTo(models.RoleAdmin)(next))
+	}
+	officeOnly := func(next http.HandlerFunc) http.Handler {
+		return am.Protect(am.RestrictTo(models.RoleAdmin, models.RoleRegistrar)(next))
+	}
+	mux.Handle("POST /admin/letter-templates", adminOnly(h.CreateTemplate))
+	mux.Handle("GET /admin/letter-templates", officeOnly(h.GetTemplates))
+	mux.Handle("GET /admin/letter-templates/{id}", officeOnly(h.GetTemplate))
+	mux.Handle("PUT /admin/letter-templates/{id}", adminOnly(h.UpdateTemplate))
+	mux.Handle("DELETE /admin/letter-templates/{id}", adminOnly(h.DeleteTemplate))
+	mux.Handle("POST /students/{id}/letters", officeOnly(h.CreateLetter))
+	mux.Handle("GET /students/{id}/documents", officeOnly(h.GetStudentDocuments))
+	mux.Handle("GET /documents/{id}", officeOnly(h.GetDocument))
+	mux.Handle("GET /documents/{id}/file", officeOnly(h.DownloadDocument))
+}
@@ -0,0 +1,22 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+	"simpleapi/internal/models"
+)
+
+// Teachers read only their own class's records, which the handler checks;
+// only admins and nurses write them
+func registerMedicalRoutes(mux *http.ServeMux, h *handlers.MedicalHandler, am *mw.AuthMiddleware) {
+	readers := func(next http.HandlerFunc) http.Handler {
+		return am.Protect(am.RestrictTo(models.RoleAdmin, models.RoleNurse, models.RoleTeacher)(next))
+	}
+	writers := func(next http.HandlerFunc) http.Handler {
+		return am.Protect(am.RestrictTo(models.RoleAdmin, models.RoleNurse)(next))
+	}
+	mux.Handle("GET /students/{id}/medical", readers(h.GetMedical))
+	mux.Handle("PUT /students/{id}/medical", writers(h.UpdateMedical))
+	mux.Handle("GET /trips/emergency-sheet", readers(h.GetEmergencySheet))
+}
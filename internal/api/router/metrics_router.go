@@ -0,0 +1,13 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+)
+
+// registerMetricsRoutes mounts the Prometheus endpoint. It sits at /metrics,
+// outside /api/v1, where scrapers look by default; it takes the metrics token
+// (METRICS_TOKEN), not a login.
+func registerMetricsRoutes(mux *http.ServeMux, h *handlers.MetricsHandler) {
+	mux.HandleFunc("GET /metrics", h.GetMetrics)
+}
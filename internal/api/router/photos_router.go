@@ -0,0 +1,17 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+	"simpleapi/internal/models"
+)
+
+func registerPhotoRoutes(mux *http.ServeMux, h *handlers.PhotoHandler, am *mw.AuthMiddleware) {
+	adminOnly := func(next http.HandlerFunc) http.Handler {
+		return am.Protect(am.RestrictTo(models.RoleAdmin)(next))
+	}
+	mux.Handle("POST /students/photos/import", adminOnly(h.ImportPhotos))
+	mux.Handle("GET /students/{id}/photo", am.Protect(http.HandlerFunc(h.GetPhoto)))
+	mux.Handle("GET /students/{id}/photo/link", am.Protect(http.HandlerFunc(h.GetPhotoLink)))
+}
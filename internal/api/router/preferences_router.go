@@ -0,0 +1,12 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+)
+
+func registerPreferenceRoutes(mux *http.ServeMux, h *handlers.PreferenceHandler, am *mw.AuthMiddleware) {
+	mux.Handle("GET /teachers/{id}/preferences", am.Protect(http.HandlerFunc(h.GetPreferences)))
+	mux.Handle("PUT /teachers/{id}/preferences", am.Protect(http.HandlerFunc(h.SetPreferences)))
+}
@@ -0,0 +1,19 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+	"simpleapi/internal/models"
+)
+
+func registerPromotionRoutes(mux *http.ServeMux, h *handlers.PromotionHandler, am *mw.AuthMiddleware) {
+	adminOnly := func(next http.HandlerFunc) http.Handler {
+		return am.Protect(am.RestrictTo(models.RoleAdmin)(next))
+	}
+	mux.Handle("POST /admin/academic-years/{year}/promotions", adminOnly(h.CreateReport))
+	mux.Handle("GET /admin/promotions", adminOnly(h.ListReports))
+	mux.Handle("GET /admin/promotions/{id}", adminOnly(h.GetReport))
+	mux.Handle("PATCH /admin/promotions/{id}/decisions/{studentId}", adminOnly(h.OverrideDecision))
+	mux.Handle("POST /admin/promotions/{id}/apply", adminOnly(h.ApplyReport))
+}
@@ -0,0 +1,16 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+	"simpleapi/internal/models"
+)
+
+func registerReferenceRoutes(mux *http.ServeMux, h *handlers.ReferenceHandler, am *mw.AuthMiddleware) {
+	adminOnly := func(next http.HandlerFunc) http.Handler {
+		return am.Protect(am.RestrictTo(models.RoleAdmin)(next))
+	}
+	mux.Handle("GET /reference", am.Protect(http.HandlerFunc(h.GetReference)))
+	mux.Handle("PUT /admin/reference", adminOnly(h.UpdateReference))
+}
@@ -0,0 +1,19 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+	"simpleapi/internal/models"
+)
+
+func registerReportsRoutes(mux *http.ServeMux, h *handlers.ReportHandler, am *mw.AuthMiddleware) {
+	officeOnly := func(next http.HandlerFunc) http.Handler {
+		return am.Protect(am.RestrictTo(models.RoleAdmin, models.RoleRegistrar)(next))
+	}
+	mux.Handle("GET /reports/enrollment", officeOnly(h.GetEnrollment))
+	mux.Handle("GET /reports/genders", officeOnly(h.GetGenders))
+	mux.Handle("GET /reports/attendance", officeOnly(h.GetAttendance))
+	mux.Handle("GET /reports/attendance/at-risk", officeOnly(h.GetAtRisk))
+	mux.Handle("GET /reports/grades", officeOnly(h.GetGrades))
+}
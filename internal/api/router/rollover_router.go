@@ -0,0 +1,19 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+	"simpleapi/internal/models"
+)
+
+func registerRolloverRoutes(mux *http.ServeMux, h *handlers.RolloverHandler, am *mw.AuthMiddleware) {
+	adminOnly := func(next http.HandlerFunc) http.Handler {
+		return am.Protect(am.RestrictTo(models.RoleAdmin)(next))
+	}
+	mux.Handle("POST /admin/rollover/plan", adminOnly(h.CreatePlan))
+	mux.Handle("GET /admin/rollover/plans", adminOnly(h.ListPlans))
+	mux.Handle("GET /admin/rollover/plans/{id}", adminOnly(h.GetPlan))
+	mux.Handle("PATCH /admin/rollover/plans/{id}", adminOnly(h.PatchPlan))
+	mux.Handle("POST /admin/rollover/execute", adminOnly(h.Execute))
+}
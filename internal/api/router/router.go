@@ -6,7 +6,61 @@ import (
 	"simpleapi/internal/api/middlewares"
 )
 
-func Router(th *handlers.TeacherHandler, sh *handlers.StudentHandler, am *middlewares.AuthMiddleware) *http.ServeMux {
+// Handlers bundles every HTTP handler the router mounts
+type Handlers struct {
+	Teachers     *handlers.TeacherHandler
+	Students     *handlers.StudentHandler
+	Comments     *handlers.CommentHandler
+	Grading      *handlers.GradingHandler
+	Transcripts  *handlers.TranscriptHandler
+	SMS          *handlers.SMSHandler
+	Events       *handlers.EventHandler
+	Trash        *handlers.TrashHandler
+	History      *handlers.HistoryHandler
+	Directory    *handlers.DirectoryHandler
+	Photos       *handlers.PhotoHandler
+	Attendance   *handlers.AttendanceHandler
+	Promotions   *handlers.PromotionHandler
+	Rollover     *handlers.RolloverHandler
+	Config       *handlers.ConfigHandler
+	Threads      *handlers.ThreadHandler
+	Archives     *handlers.ArchiveHandler
+	Backups      *handlers.BackupHandler
+	Uploads      *handlers.UploadHandler
+	Assignments  *handlers.AssignmentHandler
+	EmailChanges *handlers.EmailChangeHandler
+	IDCards      *handlers.IDCardHandler
+	Kiosk        *handlers.KioskHandler
+	Retention    *handlers.RetentionHandler
+	Security     *handlers.SecurityHandler
+	Reports      *handlers.ReportHandler
+	CustomFields *handlers.CustomFieldHandler
+	Metrics      *handlers.MetricsHandler
+	Health       *handlers.HealthHandler
+	Schemas      *handlers.SchemaHandler
+	Preferences  *handlers.PreferenceHandler
+	School       *handlers.SchoolHandler
+	Reference    *handlers.ReferenceHandler
+	Approvals    *handlers.ApprovalHandler
+	Registers    *handlers.ClassRegisterHandler
+	Campaigns    *handlers.CommunicationHandler
+	Setup        *handlers.SetupHandler
+	Medical      *handlers.MedicalHandler
+	Trips        *handlers.TripHandler
+	Exams        *handlers.ExamHandler
+	Letters      *handlers.LetterHandler
+	Audit        *handlers.AuditHandler
+	Downloads    *handlers.DownloadHandler
+	Jobs         *handlers.JobHandler
+	Diagnostics  *handlers.DiagnosticsHandler
+	SPA          *handlers.SPAHandler // nil unless SERVE_SPA is on
+
+	// Limits are the rate limiters of the server's chain. POST /batch charges
+	// each sub-request to them again, so one request can't fan out unthrottled.
+	Limits func(http.Handler) http.Handler
+}
+
+func Router(h Handlers, am *middlewares.AuthMiddleware, ka *middlewares.KioskAuth) *http.ServeMux {
 	// 1. Create the Main Traffic Controller
 	mainMux := http.NewServeMux()
 
@@ -14,12 +68,64 @@ func Router(th *handlers.TeacherHandler, sh *handlers.StudentHandler, am *middle
 	v1 := http.NewServeMux()
 
 	// 3. Hand the V1 canvas to your sub-routers to paint their routes
-	authenticationRoutes(v1, th)
-	registerTeachersRoutes(v1, th,am)
-	registerStudentRoutes(v1, sh)
+	authenticationRoutes(v1, h.Teachers, am)
+	registerTeachersRoutes(v1, h.Teachers, am)
+	registerStudentRoutes(v1, h.Students, am)
+	registerCommentRoutes(v1, h.Comments, am)
+	registerGradingRoutes(v1, h.Grading, am)
+	registerTranscriptRoutes(v1, h.Transcripts, am)
+	registerSMSRoutes(v1, h.SMS, am)
+	registerEventRoutes(v1, h.Events, am)
+	registerDirectoryRoutes(v1, h.Directory, am)
+	registerPhotoRoutes(v1, h.Photos, am)
+	registerAdminRoutes(v1, h.Teachers, h.Trash, h.Assignments, h.Security, am)
+	registerHistoryRoutes(v1, h.History, am)
+	registerAttendanceRoutes(v1, h.Attendance, am)
+	registerPromotionRoutes(v1, h.Promotions, am)
+	registerRolloverRoutes(v1, h.Rollover, am)
+	registerConfigRoutes(v1, h.Config, am)
+	registerThreadRoutes(v1, h.Threads, am)
+	registerArchiveRoutes(v1, h.Archives, am)
+	registerBackupRoutes(v1, h.Backups, am)
+	registerUploadRoutes(v1, h.Uploads, am)
+	registerEmailChangeRoutes(v1, h.EmailChanges, am)
+	registerIDCardRoutes(v1, h.IDCards, am)
+	registerKioskRoutes(v1, h.Kiosk, am, ka)
+	registerRetentionRoutes(v1, h.Retention, am)
+	registerReportsRoutes(v1, h.Reports, am)
+	registerCustomFieldRoutes(v1, h.CustomFields, am)
+	registerSchemaRoutes(v1, h.Schemas, am)
+	registerPreferenceRoutes(v1, h.Preferences, am)
+	registerSchoolRoutes(v1, h.School, am)
+	registerReferenceRoutes(v1, h.Reference, am)
+	registerApprovalRoutes(v1, h.Approvals, am)
+	registerClassRegisterRoutes(v1, h.Registers, am)
+	registerCommunicationRoutes(v1, h.Campaigns, am)
+	registerSetupRoutes(v1, h.Setup)
+	registerMedicalRoutes(v1, h.Medical, am)
+	registerTripRoutes(v1, h.Trips, am)
+	registerExamRoutes(v1, h.Exams, am)
+	registerLetterRoutes(v1, h.Letters, am)
+	registerAuditRoutes(v1, h.Audit, am)
+	registerDownloadRoutes(v1, h.Downloads)
+	registerJobRoutes(v1, h.Jobs, am)
+	registerDiagnosticsRoutes(v1, h.Diagnostics, am)
+
+	// TraceRoute and CountCanceled sit inside StripPrefix so they see the pattern
+	// v1 matched; PathIDs needs v1 itself to find the route whose ID wildcards it checks
+	api := middlewares.TraceRoute(middlewares.CountCanceled(middlewares.PathIDs(v1)))
+	// POST /batch replays its sub-requests through the same chain, behind the limiters
+	batchAPI := api
+	if h.Limits != nil {
+		batchAPI = h.Limits(api)
+	}
+	registerBatchRoutes(v1, handlers.NewBatchHandler(batchAPI), am)
 
 	// 4. Mount the filled-up V1 router onto the main router
 	// Any request starting with "/api/v1/" gets stripped and sent to 'v1'
-	mainMux.Handle("/api/v1/", http.StripPrefix("/api/v1", v1))
+	mainMux.Handle("/api/v1/", http.StripPrefix("/api/v1", api))
+	registerMetricsRoutes(mainMux, h.Metrics)
+	registerHealthRoutes(mainMux, h.Health)
+	registerSPARoutes(mainMux, h.SPA)
 	return mainMux
 }
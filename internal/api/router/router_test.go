@@ -0,0 +1,129 @@
+package router
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"simpleapi/internal/api/middlewares"
+	"simpleapi/internal/repository/memory"
+	"simpleapi/pkg/clock"
+	"simpleapi/pkg/utils"
+	"strings"
+	"testing"
+	"time"
+)
+
+// public are the routes that answer without a login, on purpose. Each has
+// its own guard or none is needed; a route missing from here must ask for a
+// login, so a new one can't go out unprotected by accident.
+var public = map[string]string{
+	"POST /login":                "logging in",
+	"POST /logout":               "clears the session cookie",
+	"POST /refresh":              "checks the refresh token",
+	"POST /setup":                "SETUP_TOKEN, until the first admin exists",
+	"GET /directory/teachers":    "published staff only",
+	"GET /calendar.ics":          "calendar apps subscribe to it",
+	"GET /downloads/{token}":     "signed link",
+	"GET /verify-card":           "signed ID card",
+	"POST /transcripts/verify":   "signed transcript",
+	"POST /email-change/confirm": "token from the email",
+	"POST /email-change/undo":    "token from the email",
+	"POST /trip-consent/view":    "token from the invitation",
+	"POST /trip-consent/respond": "token from the invitation",
+	"POST /webhooks/sms":         "SMS_WEBHOOK_TOKEN",
+	"GET /school":                "the login page shows it",
+	"GET /school/logo":           "the login page shows it",
+	"GET /metrics":               "METRICS_TOKEN",
+	"GET /readyz":                "load balancer probe",
+	"GET /version":               "load balancer probe",
+}
+
+// rootFiles register on the main mux, outside /api/v1
+var rootFiles = map[string]bool{"metrics_router.go": true, "health_router.go": true, "spa_router.go": true}
+
+var routePattern = regexp.MustCompile(`\.Handle(?:Func)?\("([A-Z]+) ([^"]+)"`)
+
+// routes reads the method and path patterns the router files register
+func routes(t *testing.T) map[string]string {
+	t.Helper()
+	files, err := filepath.Glob("*_router.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	routes := make(map[string]string) // Pattern -> the path it's mounted under
+	for _, f := range files {
+		src, err := os.ReadFile(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		prefix := "/api/v1"
+		if rootFiles[f] {
+			prefix = ""
+		}
+		for _, line := range strings.Split(string(src), "\n") {
+			if strings.HasPrefix(strings.TrimSpace(line), "//") {
+				continue // Routes not written yet
+			}
+			if m := routePattern.FindStringSubmatch(line); m != nil {
+				routes[m[1]+" "+m[2]] = prefix
+			}
+		}
+	}
+	if len(routes) < 100 {
+		t.Fatalf("found only %d routes: has the way routes are registered changed?", len(routes))
+	}
+	return routes
+}
+
+// wildcard matches the path wildcards, filled with 1 in the requests
+var wildcard = regexp.MustCompile(`\{[^}]+\}`)
+
+func TestRoutesNeedLogin(t *testing.T) {
+	clk := clock.NewFrozen(time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC))
+	tokens, err := utils.NewTokenService(utils.TokenConfig{Keys: [][]byte{[]byte("test-key")}, Clock: clk})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cookies, err := utils.NewCookieManager(utils.CookieConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	am := middlewares.NewAuthMiddleware(memory.NewTeacherRepository(memory.NewDB(clk)), tokens, cookies, utils.TokenDeliveryNegotiate)
+	// No handler is set: a request reaching one panics, which is how an unprotected route shows
+	mux := Router(Handlers{}, am, middlewares.NewKioskAuth(nil))
+
+	routes := routes(t)
+	for pattern, prefix := range routes {
+		t.Run(pattern, func(t *testing.T) {
+			method, path, _ := strings.Cut(pattern, " ")
+			path = prefix + wildcard.ReplaceAllString(path, "1")
+			if _, ok := public[pattern]; ok {
+				return
+			}
+			status := serve(mux, httptest.NewRequest(method, path, nil))
+			if status != "401" {
+				t.Errorf("%s %s without a login answered %s, want 401; protect it or list it in public", method, path, status)
+			}
+		})
+	}
+	for pattern := range public {
+		if _, ok := routes[pattern]; !ok {
+			t.Errorf("public lists %s, which isn't a route", pattern)
+		}
+	}
+}
+
+// serve returns the status h answers r with, or "reached the handler" when it panics
+func serve(h http.Handler, r *http.Request) (status string) {
+	defer func() {
+		if recover() != nil {
+			status = "reached the handler"
+		}
+	}()
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	return fmt.Sprint(rec.Code)
+}
@@ -0,0 +1,11 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+)
+
+func registerSchemaRoutes(mux *http.ServeMux, h *handlers.SchemaHandler, am *mw.AuthMiddleware) {
+	mux.Handle("GET /schemas/{entity}", am.Protect(http.HandlerFunc(h.GetSchema)))
+}
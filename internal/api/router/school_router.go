@@ -0,0 +1,25 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+	"simpleapi/internal/models"
+)
+
+func registerSchoolRoutes(mux *http.ServeMux, h *handlers.SchoolHandler, am *mw.AuthMiddleware) {
+	adminOnly := func(next http.HandlerFunc) http.Handler {
+		return am.Protect(am.RestrictTo(models.RoleAdmin)(next))
+	}
+	// Public: the login page shows the name and logo
+	mux.HandleFunc("GET /school", h.GetSchool)
+	mux.HandleFunc("GET /school/logo", h.GetLogo)
+
+	mux.Handle("GET /admin/school", adminOnly(h.GetAdminSchool))
+	mux.Handle("PUT /admin/school", adminOnly(h.UpdateSchool))
+	mux.Handle("PUT /admin/school/logo", adminOnly(h.UploadLogo))
+	mux.Handle("DELETE /admin/school/logo", adminOnly(h.DeleteLogo))
+	mux.Handle("GET /admin/school/quotas", adminOnly(h.GetQuotas))
+	mux.Handle("PUT /admin/school/quotas", adminOnly(h.UpdateQuotas))
+	mux.Handle("PUT /admin/school/attendance-thresholds", adminOnly(h.UpdateAttendanceThresholds))
+}
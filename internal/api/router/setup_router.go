@@ -0,0 +1,14 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+	"time"
+)
+
+func registerSetupRoutes(mux *http.ServeMux, h *handlers.SetupHandler) {
+	// Public until the first admin exists: don't let anyone guess SETUP_TOKEN
+	rl := mw.NewRateLimiter(5, time.Minute)
+	mux.Handle("POST /setup", rl.Middleware(http.HandlerFunc(h.Setup)))
+}
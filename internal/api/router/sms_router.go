@@ -0,0 +1,19 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+	"simpleapi/internal/models"
+)
+
+func registerSMSRoutes(mux *http.ServeMux, h *handlers.SMSHandler, am *mw.AuthMiddleware) {
+	adminOnly := func(next http.HandlerFunc) http.Handler {
+		return am.Protect(am.RestrictTo(models.RoleAdmin)(next))
+	}
+	mux.Handle("POST /admin/sms", adminOnly(h.SendSMS))
+	mux.Handle("GET /admin/sms", adminOnly(h.GetMessages))
+
+	// Called by the SMS provider, authenticated by the shared ?token=
+	mux.HandleFunc("POST /webhooks/sms", h.DeliveryReport)
+}
@@ -0,0 +1,15 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+)
+
+// registerSPARoutes mounts the admin SPA at the root, behind every API route
+// (SERVE_SPA). Without it nothing answers outside /api/v1 and /metrics.
+func registerSPARoutes(mux *http.ServeMux, h *handlers.SPAHandler) {
+	if h == nil {
+		return
+	}
+	mux.Handle("/", h)
+}
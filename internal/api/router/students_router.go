@@ -3,9 +3,26 @@ package router
 import (
 	"net/http"
 	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+	"simpleapi/internal/models"
 )
 
-func registerStudentRoutes(mux *http.ServeMux, h *handlers.StudentHandler) {
-	mux.HandleFunc("GET /students", h.GetStudents)
-	mux.HandleFunc("POST /students", h.CreateStudents)
+func registerStudentRoutes(mux *http.ServeMux, h *handlers.StudentHandler, am *mw.AuthMiddleware) {
+	protect := func(next http.HandlerFunc) http.Handler {
+		return am.Protect(next)
+	}
+	officeOnly := func(next http.HandlerFunc) http.Handler {
+		return am.Protect(am.RestrictTo(models.RoleAdmin, models.RoleRegistrar)(next))
+	}
+	// Dates of birth, guardians and phones are children's personal data: staff only,
+	// and the CSV export (GetStudents refuses it to others) and imports are the office's
+	mux.Handle("GET /students", protect(h.GetStudents))
+	mux.Handle("POST /students", officeOnly(h.CreateStudents))
+	mux.Handle("POST /students/validate", protect(h.ValidateStudents))
+	mux.Handle("GET /students/count", protect(h.CountStudents))
+	mux.Handle("GET /students/count-by-class", protect(h.CountStudentsByClass))
+	mux.Handle("GET /students/{id}", protect(h.GetStudentByID))
+	// Student records and their lifecycle are the office's too
+	mux.Handle("PATCH /students/{id}", officeOnly(h.PatchStudent))
+	mux.Handle("POST /students/{id}/status", officeOnly(h.ChangeStatus))
 }
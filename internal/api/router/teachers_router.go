@@ -4,21 +4,29 @@ import (
 	"net/http"
 	"simpleapi/internal/api/handlers"
 	mw "simpleapi/internal/api/middlewares"
+	"simpleapi/internal/models"
 )
 
 func registerTeachersRoutes(mux *http.ServeMux, h *handlers.TeacherHandler, am *mw.AuthMiddleware) {
 	protect := func(next http.HandlerFunc) http.Handler {
 		return am.Protect(next)
 	}
+	adminOnly := func(next http.HandlerFunc) http.Handler {
+		return am.Protect(am.RestrictTo(models.RoleAdmin)(next))
+	}
 	mux.Handle("GET /teachers", protect(h.GetTeachers))
 	mux.Handle("POST /teachers", protect(h.CreateTeachers))
-	mux.HandleFunc("PATCH /teachers", h.BulkPatchTeachers)
-	mux.HandleFunc("DELETE /teachers", h.BulkDeleteTeachers)
-	mux.HandleFunc("GET /teachers/{id}", h.GetTeacherByID)
-	mux.HandleFunc("PUT /teachers/{id}", h.UpdateTeacherFull)
-	mux.HandleFunc("PATCH /teachers/{id}", h.PatchTeacher)
-	mux.HandleFunc("DELETE /teachers/{id}", h.DeleteTeacher)
+	mux.Handle("POST /teachers/validate", protect(h.ValidateTeachers))
+	mux.Handle("GET /teachers/count", protect(h.CountTeachers))
+	// Teachers' records carry phones and their students' guardians: staff only
+	mux.Handle("GET /teachers/{id}", protect(h.GetTeacherByID))
+	// Changing or removing staff accounts is the admin's
+	mux.Handle("PATCH /teachers", adminOnly(h.BulkPatchTeachers))
+	mux.Handle("DELETE /teachers", adminOnly(h.BulkDeleteTeachers))
+	mux.Handle("PUT /teachers/{id}", adminOnly(h.UpdateTeacherFull))
+	mux.Handle("PATCH /teachers/{id}", adminOnly(h.PatchTeacher))
+	mux.Handle("DELETE /teachers/{id}", adminOnly(h.DeleteTeacher))
 
-	mux.HandleFunc("GET /teachers/{id}/students", h.GetStudentsByTeacherId)
-	mux.HandleFunc("GET /teachers/{id}/studentCount", h.GetStudentsByTeacherId)
+	mux.Handle("GET /teachers/{id}/students", protect(h.GetStudentsByTeacherId))
+	mux.Handle("GET /teachers/{id}/studentCount", protect(h.GetStudentsByTeacherId))
 }
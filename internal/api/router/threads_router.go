@@ -0,0 +1,21 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+)
+
+func registerThreadRoutes(mux *http.ServeMux, h *handlers.ThreadHandler, am *mw.AuthMiddleware) {
+	protect := func(next http.HandlerFunc) http.Handler {
+		return am.Protect(next)
+	}
+	mux.Handle("POST /threads", protect(h.CreateThread))
+	mux.Handle("GET /threads", protect(h.GetThreads))
+	mux.Handle("GET /threads/{id}", protect(h.GetThread))
+	mux.Handle("GET /threads/{id}/messages", protect(h.GetMessages))
+	mux.Handle("POST /threads/{id}/messages", protect(h.PostMessage))
+	mux.Handle("POST /threads/{id}/read", protect(h.MarkRead))
+	mux.Handle("GET /threads/{id}/messages/{messageId}/attachments/{index}", protect(h.GetAttachment))
+	mux.Handle("GET /threads/{id}/messages/{messageId}/attachments/{index}/link", protect(h.GetAttachmentLink))
+}
@@ -0,0 +1,16 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+	"time"
+)
+
+func registerTranscriptRoutes(mux *http.ServeMux, h *handlers.TranscriptHandler, am *mw.AuthMiddleware) {
+	mux.Handle("GET /students/{id}/transcript", am.Protect(http.HandlerFunc(h.GetTranscript)))
+
+	// External institutions verify without an account, so rate limit it like the directory
+	rl := mw.NewRateLimiter(30, time.Minute)
+	mux.Handle("POST /transcripts/verify", rl.Middleware(http.HandlerFunc(h.VerifyTranscript)))
+}
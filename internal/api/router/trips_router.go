@@ -0,0 +1,24 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+	"simpleapi/internal/models"
+)
+
+// Teachers manage the trips of the classes they teach, which the handler
+// checks; guardians answer through the texted links, which carry their own token
+func registerTripRoutes(mux *http.ServeMux, h *handlers.TripHandler, am *mw.AuthMiddleware) {
+	staff := func(next http.HandlerFunc) http.Handler {
+		return am.Protect(am.RestrictTo(models.RoleAdmin, models.RoleTeacher)(next))
+	}
+	mux.Handle("POST /trips", staff(h.CreateTrip))
+	mux.Handle("GET /trips", staff(h.GetTrips))
+	mux.Handle("GET /trips/{id}", staff(h.GetTrip))
+	mux.Handle("POST /trips/{id}/consent-requests", staff(h.RequestConsents))
+	mux.Handle("GET /trips/{id}/consent-status", staff(h.GetConsentStatus))
+	mux.Handle("PUT /trips/{id}/consents/{student_id}", staff(h.RecordConsent))
+	mux.HandleFunc("POST /trip-consent/view", h.ViewInvitation)
+	mux.HandleFunc("POST /trip-consent/respond", h.Respond)
+}
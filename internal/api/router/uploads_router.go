@@ -0,0 +1,17 @@
+package router
+
+import (
+	"net/http"
+	"simpleapi/internal/api/handlers"
+	mw "simpleapi/internal/api/middlewares"
+	"simpleapi/internal/models"
+)
+
+func registerUploadRoutes(mux *http.ServeMux, h *handlers.UploadHandler, am *mw.AuthMiddleware) {
+	adminOnly := func(next http.HandlerFunc) http.Handler {
+		return am.Protect(am.RestrictTo(models.RoleAdmin)(next))
+	}
+	mux.Handle("GET /admin/uploads", adminOnly(h.GetUploads))
+	mux.Handle("GET /admin/uploads/{id}", adminOnly(h.GetUpload))
+	mux.Handle("POST /admin/uploads/{id}/review", adminOnly(h.ReviewUpload))
+}
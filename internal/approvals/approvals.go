@@ -0,0 +1,154 @@
+// Package approvals runs the requests that only take effect once someone
+// else signs them off: a teacher's leave, a grade correction, a fee waiver.
+// Each kind of request is a Type, which says who decides it, what its
+// payload must hold and what approving it does; the Engine moves every
+// approval through the same states (see models.Approval).
+package approvals
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+)
+
+// Type is one kind of approval
+type Type struct {
+	Name string
+	// ApproverRole decides requests of the type. Admins can decide any.
+	ApproverRole string
+	// Check validates a payload submitted by requester and returns it as it
+	// is stored. Invalid input comes back as errs, with a nil error.
+	Check func(ctx context.Context, payload json.RawMessage, requester models.Teacher) (stored json.RawMessage, errs []models.ValidationError, err error)
+	// Apply carries out an approved request, nil when the approval itself is the record
+	Apply func(ctx context.Context, a models.Approval, approverID int) error
+}
+
+// Engine submits and decides approvals of its registered types
+type Engine struct {
+	store repository.ApprovalStore
+	types map[string]Type
+}
+
+// New is the constructor
+func New(store repository.ApprovalStore, types ...Type) *Engine {
+	e := &Engine{store: store, types: make(map[string]Type, len(types))}
+	for _, t := range types {
+		e.types[t.Name] = t
+	}
+	return e
+}
+
+// Submit records a pending approval of req by requester
+func (e *Engine) Submit(ctx context.Context, req models.ApprovalRequest, requester models.Teacher) (*models.Approval, []models.ValidationError, error) {
+	t, ok := e.types[req.Type]
+	if !ok {
+		return nil, nil, fmt.Errorf("approvals: %s requests are not handled: %w", req.Type, models.ErrInvalidInput)
+	}
+	payload, errs, err := t.Check(ctx, req.Payload, requester)
+	if err != nil || len(errs) > 0 {
+		return nil, errs, err
+	}
+	a, err := e.store.Create(ctx, models.Approval{
+		Type:         t.Name,
+		Payload:      payload,
+		RequestedBy:  requester.ID,
+		ApproverRole: t.ApproverRole,
+		Note:         req.Note,
+	})
+	return a, nil, err
+}
+
+// Pending lists the approvals waiting for user's decision: those for their
+// role, every one for admins, never their own
+func (e *Engine) Pending(ctx context.Context, user models.Teacher) ([]models.Approval, error) {
+	filter := models.ApprovalFilter{State: models.ApprovalPending}
+	if user.Role != models.RoleAdmin {
+		filter.ApproverRole = user.Role
+	}
+	approvals, err := e.store.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	pending := approvals[:0]
+	for _, a := range approvals {
+		if a.RequestedBy != user.ID {
+			pending = append(pending, a)
+		}
+	}
+	return pending, nil
+}
+
+// CanView reports whether user may see a: its requester and those who can decide it
+func CanView(a models.Approval, user models.Teacher) bool {
+	return a.RequestedBy == user.ID || user.Role == models.RoleAdmin || user.Role == a.ApproverRole
+}
+
+// Decide approves or rejects a pending approval. Approving carries it out;
+// when that fails the approval goes back to pending and the error is returned.
+func (e *Engine) Decide(ctx context.Context, id int, approve bool, user models.Teacher, comment string) (*models.Approval, error) {
+	a, err := e.store.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user.Role != models.RoleAdmin && user.Role != a.ApproverRole {
+		return nil, fmt.Errorf("approvals: approval %d is for the %s role to decide: %w", id, a.ApproverRole, models.ErrForbidden)
+	}
+	if a.RequestedBy == user.ID {
+		return nil, fmt.Errorf("approvals: approval %d can't be decided by its requester: %w", id, models.ErrForbidden)
+	}
+
+	if !approve {
+		return e.store.Transition(ctx, id, models.ApprovalPending, models.ApprovalRejected, user.ID, comment)
+	}
+	// Claiming the approval first keeps a second approver from applying it again
+	approved, err := e.store.Transition(ctx, id, models.ApprovalPending, models.ApprovalApproved, user.ID, comment)
+	if err != nil {
+		return nil, err
+	}
+	if apply := e.types[a.Type].Apply; apply != nil {
+		if err := apply(ctx, *approved, user.ID); err != nil {
+			if _, reopenErr := e.store.Transition(ctx, id, models.ApprovalApproved, models.ApprovalPending, user.ID, ""); reopenErr != nil {
+				log.Printf("approvals: approval %d is approved but was not applied: %v", id, reopenErr)
+			}
+			return nil, fmt.Errorf("approvals: applying approval %d: %w", id, err)
+		}
+	}
+	return approved, nil
+}
+
+// Cancel withdraws a pending approval; only its requester can
+func (e *Engine) Cancel(ctx context.Context, id int, user models.Teacher) (*models.Approval, error) {
+	a, err := e.store.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if a.RequestedBy != user.ID {
+		return nil, fmt.Errorf("approvals: approval %d can only be cancelled by its requester: %w", id, models.ErrForbidden)
+	}
+	return e.store.Transition(ctx, id, models.ApprovalPending, models.ApprovalCancelled, user.ID, "")
+}
+
+// decode unmarshals and validates a payload, then re-encodes it so only its
+// known fields are stored
+func decode[T any](payload json.RawMessage, check func(T) []models.ValidationError) (T, json.RawMessage, []models.ValidationError) {
+	var v T
+	if err := json.Unmarshal(payload, &v); err != nil {
+		return v, nil, []models.ValidationError{models.RuleError("Payload", "approval_payload", "")}
+	}
+	if errs := models.ValidateOne(v); len(errs) > 0 {
+		return v, nil, errs
+	}
+	if check != nil {
+		if errs := check(v); len(errs) > 0 {
+			return v, nil, errs
+		}
+	}
+	stored, err := json.Marshal(v)
+	if err != nil {
+		return v, nil, []models.ValidationError{models.RuleError("Payload", "approval_payload", "")}
+	}
+	return v, stored, nil
+}
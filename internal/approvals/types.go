@@ -0,0 +1,94 @@
+package approvals
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/policy"
+	"simpleapi/internal/repository"
+)
+
+// Absence is a staff member's leave, decided by an admin. The approved
+// request is the record of the leave.
+func Absence() Type {
+	return Type{
+		Name:         models.ApprovalAbsence,
+		ApproverRole: models.RoleAdmin,
+		Check: func(ctx context.Context, payload json.RawMessage, requester models.Teacher) (json.RawMessage, []models.ValidationError, error) {
+			_, stored, errs := decode(payload, models.AbsenceRequest.CheckDates)
+			return stored, errs, nil
+		},
+	}
+}
+
+// GradeCorrection corrects a posted score, requested by a teacher of the
+// student's class and decided by an admin. Approving it adds the
+// GradeCorrection, graded by the subject's scheme at that time.
+func GradeCorrection(scores repository.ScoreStore, schemes repository.GradingStore, students repository.StudentStore, p *policy.Policy) Type {
+	return Type{
+		Name:         models.ApprovalGradeCorrection,
+		ApproverRole: models.RoleAdmin,
+		Check: func(ctx context.Context, payload json.RawMessage, requester models.Teacher) (json.RawMessage, []models.ValidationError, error) {
+			req, stored, errs := decode[models.GradeCorrectionRequest](payload, nil)
+			if len(errs) > 0 {
+				return nil, errs, nil
+			}
+			score, err := scores.GetByID(ctx, req.ScoreID)
+			if err != nil {
+				return nil, nil, err
+			}
+			student, err := students.GetByID(ctx, score.StudentID)
+			if err != nil {
+				return nil, nil, err
+			}
+			if err := p.CanModifyStudent(ctx, &requester, *student); err != nil {
+				return nil, nil, err
+			}
+			return stored, nil, nil
+		},
+		Apply: func(ctx context.Context, a models.Approval, approverID int) error {
+			var req models.GradeCorrectionRequest
+			if err := json.Unmarshal(a.Payload, &req); err != nil {
+				return fmt.Errorf("approvals: approval %d payload: %w", a.ID, err)
+			}
+			score, err := scores.GetByID(ctx, req.ScoreID)
+			if err != nil {
+				return err
+			}
+			scheme, err := schemes.ForSubject(ctx, score.Subject)
+			if err != nil {
+				return fmt.Errorf("approvals: grading scheme for %s: %w", score.Subject, err)
+			}
+			_, err = scores.AddCorrection(ctx, models.GradeCorrection{
+				ScoreID:    req.ScoreID,
+				Score:      req.Score,
+				Grade:      scheme.GradeFor(req.Score),
+				SchemeID:   scheme.ID,
+				Reason:     req.Reason,
+				ApprovedBy: approverID,
+			})
+			return err
+		},
+	}
+}
+
+// FeeWaiver waives part of a student's fees for a term, decided by the
+// registrar's office. The API keeps no fee accounts: the approved request is
+// the record the bursar works from.
+func FeeWaiver(students repository.StudentStore) Type {
+	return Type{
+		Name:         models.ApprovalFeeWaiver,
+		ApproverRole: models.RoleRegistrar,
+		Check: func(ctx context.Context, payload json.RawMessage, requester models.Teacher) (json.RawMessage, []models.ValidationError, error) {
+			req, stored, errs := decode[models.FeeWaiverRequest](payload, nil)
+			if len(errs) > 0 {
+				return nil, errs, nil
+			}
+			if _, err := students.GetByID(ctx, req.StudentID); err != nil {
+				return nil, nil, err
+			}
+			return stored, nil, nil
+		},
+	}
+}
@@ -0,0 +1,286 @@
+// Package backup writes and reads logical dumps of the database: gzip'd JSON
+// lines, one header line per table followed by one line per row. Plain Go and
+// SQL, so it works wherever the API does (no mysqldump binary needed) and a
+// dump can be inspected with zcat.
+package backup
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Format identifies the dump layout; Restore refuses anything else
+const Format = "simpleapi-backup/1"
+
+// timeLayout is how DATETIME/TIMESTAMP values are written, ready to insert back
+const timeLayout = "2006-01-02 15:04:05.999999"
+
+// header is the first line of a dump
+type header struct {
+	Format    string    `json:"format"`
+	CreatedAt time.Time `json:"created_at"`
+	Tables    []string  `json:"tables"`
+}
+
+// tableStart precedes a table's rows
+type tableStart struct {
+	Table   string   `json:"table"`
+	Columns []string `json:"columns"`
+}
+
+// binary holds bytes that aren't valid UTF-8 (e.g. BLOBs)
+type binary struct {
+	Base64 string `json:"base64"`
+}
+
+// Stats is what a dump or restore went through
+type Stats struct {
+	Tables int
+	Rows   int
+}
+
+// Dump writes every row of tables to w, gzip'd. Rows are read table by table,
+// so memory use doesn't grow with the database.
+func Dump(ctx context.Context, db *sql.DB, tables []string, now time.Time, w io.Writer) (Stats, error) {
+	var stats Stats
+	zw := gzip.NewWriter(w)
+	enc := json.NewEncoder(zw)
+
+	if err := enc.Encode(header{Format: Format, CreatedAt: now.UTC(), Tables: tables}); err != nil {
+		return stats, err
+	}
+	for _, table := range tables {
+		n, err := dumpTable(ctx, db, table, enc)
+		if err != nil {
+			return stats, fmt.Errorf("backup: dump %s: %w", table, err)
+		}
+		stats.Tables++
+		stats.Rows += n
+	}
+	return stats, zw.Close()
+}
+
+func dumpTable(ctx context.Context, db *sql.DB, table string, enc *json.Encoder) (int, error) {
+	rows, err := db.QueryContext(ctx, "SELECT * FROM "+quote(table))
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	if err := enc.Encode(tableStart{Table: table, Columns: columns}); err != nil {
+		return 0, err
+	}
+
+	values := make([]any, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	n := 0
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return n, err
+		}
+		row := make([]any, len(values))
+		for i, v := range values {
+			row[i] = encodeValue(v)
+		}
+		if err := enc.Encode(row); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, rows.Err()
+}
+
+// encodeValue turns what the driver scanned into something JSON keeps intact
+func encodeValue(v any) any {
+	switch v := v.(type) {
+	case []byte:
+		if utf8.Valid(v) {
+			return string(v)
+		}
+		return binary{Base64: base64.StdEncoding.EncodeToString(v)}
+	case time.Time:
+		return v.Format(timeLayout)
+	default:
+		return v
+	}
+}
+
+// decodeValue reverses encodeValue. Numbers stay json.Number so large IDs
+// and DECIMALs go back exactly as they came out.
+func decodeValue(v any) any {
+	switch v := v.(type) {
+	case json.Number:
+		return v.String()
+	case map[string]any:
+		if s, ok := v["base64"].(string); ok {
+			if b, err := base64.StdEncoding.DecodeString(s); err == nil {
+				return b
+			}
+		}
+		return nil
+	default:
+		return v
+	}
+}
+
+// Restore replaces the contents of every table in the dump with the dumped rows,
+// in one transaction: either the whole dump goes in or nothing changes. With
+// dryRun the transaction is rolled back at the end, which still proves the dump
+// loads. Tables the dump doesn't mention are left alone.
+func Restore(ctx context.Context, db *sql.DB, r io.Reader, dryRun bool) (Stats, error) {
+	var stats Stats
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return stats, fmt.Errorf("backup: not a gzip'd dump: %w", err)
+	}
+	dec := json.NewDecoder(bufio.NewReader(zr))
+	dec.UseNumber()
+
+	var h header
+	if err := dec.Decode(&h); err != nil || h.Format != Format {
+		return stats, fmt.Errorf("backup: not a %s dump", Format)
+	}
+
+	// One connection for the whole restore: FOREIGN_KEY_CHECKS is per session,
+	// and tables come back in dump order, not dependency order
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return stats, err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "SET FOREIGN_KEY_CHECKS = 0"); err != nil {
+		return stats, err
+	}
+	defer conn.ExecContext(context.Background(), "SET FOREIGN_KEY_CHECKS = 1")
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return stats, err
+	}
+	defer tx.Rollback()
+
+	var current *tableStart
+	var insert *sql.Stmt
+	for {
+		var line json.RawMessage
+		if err := dec.Decode(&line); err == io.EOF {
+			break
+		} else if err != nil {
+			return stats, fmt.Errorf("backup: corrupt dump: %w", err)
+		}
+
+		if len(line) > 0 && line[0] == '{' {
+			var t tableStart
+			if err := json.Unmarshal(line, &t); err != nil || t.Table == "" || len(t.Columns) == 0 {
+				return stats, errors.New("backup: corrupt dump: bad table header")
+			}
+			if err := checkTable(ctx, tx, t); err != nil {
+				return stats, err
+			}
+			if insert != nil {
+				insert.Close()
+			}
+			if _, err := tx.ExecContext(ctx, "DELETE FROM "+quote(t.Table)); err != nil {
+				return stats, fmt.Errorf("backup: clear %s: %w", t.Table, err)
+			}
+			if insert, err = tx.PrepareContext(ctx, insertSQL(t)); err != nil {
+				return stats, fmt.Errorf("backup: prepare %s: %w", t.Table, err)
+			}
+			current = &t
+			stats.Tables++
+			continue
+		}
+
+		if current == nil {
+			return stats, errors.New("backup: corrupt dump: row before table header")
+		}
+		var row []any
+		d := json.NewDecoder(bytes.NewReader(line))
+		d.UseNumber()
+		if err := d.Decode(&row); err != nil || len(row) != len(current.Columns) {
+			return stats, fmt.Errorf("backup: corrupt dump: bad row in %s", current.Table)
+		}
+		for i := range row {
+			row[i] = decodeValue(row[i])
+		}
+		if _, err := insert.ExecContext(ctx, row...); err != nil {
+			return stats, fmt.Errorf("backup: insert into %s: %w", current.Table, err)
+		}
+		stats.Rows++
+	}
+	if insert != nil {
+		insert.Close()
+	}
+
+	if dryRun {
+		return stats, nil // The deferred Rollback undoes everything
+	}
+	if err := tx.Commit(); err != nil {
+		return stats, err
+	}
+	return stats, nil
+}
+
+// checkTable makes sure the dump's table and columns exist here, so a dump from
+// a different schema version fails up front instead of halfway through
+func checkTable(ctx context.Context, tx *sql.Tx, t tableStart) error {
+	rows, err := tx.QueryContext(ctx,
+		"SELECT column_name FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ?", t.Table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	have := make(map[string]bool)
+	for rows.Next() {
+		var c string
+		if err := rows.Scan(&c); err != nil {
+			return err
+		}
+		have[strings.ToLower(c)] = true
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if len(have) == 0 {
+		return fmt.Errorf("backup: table %s does not exist in this database", t.Table)
+	}
+	for _, c := range t.Columns {
+		if !have[strings.ToLower(c)] {
+			return fmt.Errorf("backup: column %s.%s does not exist in this database; run its migration first", t.Table, c)
+		}
+	}
+	return nil
+}
+
+func insertSQL(t tableStart) string {
+	cols := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		cols[i] = quote(c)
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", quote(t.Table), strings.Join(cols, ", "),
+		strings.TrimSuffix(strings.Repeat("?,", len(cols)), ","))
+}
+
+// quote backtick-quotes an identifier; names come from our own table list or
+// from a dump checked against information_schema
+func quote(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
@@ -0,0 +1,98 @@
+package breach
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// bloomMagic starts a filter file; the header goes on with the number of
+// hash functions (uint32) and of bits (uint64), big-endian, then the bits
+var bloomMagic = []byte("SBF1")
+
+// Bloom is a Bloom filter of SHA-1 password hashes: it may mistake an unknown
+// password for a breached one, at the rate it was built for, but never the
+// other way round
+type Bloom struct {
+	k    uint32
+	m    uint64
+	bits []byte
+}
+
+// NewBloom sizes an empty filter for n hashes at a false positive rate fp
+func NewBloom(n uint64, fp float64) *Bloom {
+	if n == 0 {
+		n = 1
+	}
+	m := uint64(math.Ceil(-float64(n) * math.Log(fp) / (math.Ln2 * math.Ln2)))
+	k := uint32(math.Max(1, math.Round(float64(m)/float64(n)*math.Ln2)))
+	return &Bloom{k: k, m: m, bits: make([]byte, (m+7)/8)}
+}
+
+// LoadBloom reads a filter written by WriteTo
+func LoadBloom(path string) (*Bloom, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("breach: reading the filter: %w", err)
+	}
+	if len(data) < 16 || !bytes.Equal(data[:4], bloomMagic) {
+		return nil, fmt.Errorf("breach: %s is not a filter built by cmd/breach-filter", path)
+	}
+	b := &Bloom{k: binary.BigEndian.Uint32(data[4:8]), m: binary.BigEndian.Uint64(data[8:16]), bits: data[16:]}
+	if b.k == 0 || b.m == 0 || uint64(len(b.bits)) != (b.m+7)/8 {
+		return nil, fmt.Errorf("breach: %s is truncated or corrupt", path)
+	}
+	return b, nil
+}
+
+func (b *Bloom) Name() string { return "bloom" }
+
+// Add puts a SHA-1 hash in the filter
+func (b *Bloom) Add(hash [sha1.Size]byte) {
+	h1, h2 := split(hash)
+	for i := uint64(0); i < uint64(b.k); i++ {
+		bit := (h1 + i*h2) % b.m
+		b.bits[bit/8] |= 1 << (bit % 8)
+	}
+}
+
+// Contains reports whether the hash may be in the filter
+func (b *Bloom) Contains(hash [sha1.Size]byte) bool {
+	h1, h2 := split(hash)
+	for i := uint64(0); i < uint64(b.k); i++ {
+		bit := (h1 + i*h2) % b.m
+		if b.bits[bit/8]&(1<<(bit%8)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (b *Bloom) Breached(ctx context.Context, password string) (bool, error) {
+	return b.Contains(Hash(password)), nil
+}
+
+// WriteTo saves the filter for LoadBloom
+func (b *Bloom) WriteTo(w io.Writer) (int64, error) {
+	header := make([]byte, 16)
+	copy(header, bloomMagic)
+	binary.BigEndian.PutUint32(header[4:8], b.k)
+	binary.BigEndian.PutUint64(header[8:16], b.m)
+	n, err := w.Write(header)
+	if err != nil {
+		return int64(n), err
+	}
+	n2, err := w.Write(b.bits)
+	return int64(n + n2), err
+}
+
+// split turns a hash into the two values double hashing derives the k
+// positions from. SHA-1 is uniform already, so its bytes serve as they are.
+func split(hash [sha1.Size]byte) (h1, h2 uint64) {
+	return binary.BigEndian.Uint64(hash[0:8]), binary.BigEndian.Uint64(hash[8:16]) | 1
+}
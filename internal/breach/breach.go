@@ -0,0 +1,49 @@
+// Package breach tells whether a password is known from data breaches, so
+// login can make its owner pick another (see TeacherHandler.LoginTeacher).
+//
+//	PASSWORD_BREACH_CHECK=off | bloom | hibp   (empty means off)
+//	PASSWORD_BREACH_FILTER=/var/lib/school/breached.bloom   (bloom; built with cmd/breach-filter)
+//	PASSWORD_BREACH_URL=https://api.pwnedpasswords.com      (hibp; the default)
+//
+// bloom answers from a local file and never sends anything out; it has rare
+// false positives. hibp asks Have I Been Pwned's range API, which only ever
+// sees the first five hex digits of the password's SHA-1.
+package breach
+
+import (
+	"context"
+	"crypto/sha1"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Checker looks passwords up in a breach corpus
+type Checker interface {
+	Name() string
+	Breached(ctx context.Context, password string) (bool, error)
+}
+
+// CheckerFromEnv builds the checker selected by PASSWORD_BREACH_CHECK, nil when off
+func CheckerFromEnv() (Checker, error) {
+	switch strings.ToLower(os.Getenv("PASSWORD_BREACH_CHECK")) {
+	case "", "off":
+		return nil, nil
+	case "bloom":
+		path := os.Getenv("PASSWORD_BREACH_FILTER")
+		if path == "" {
+			return nil, fmt.Errorf("breach: PASSWORD_BREACH_CHECK=bloom needs PASSWORD_BREACH_FILTER")
+		}
+		return LoadBloom(path)
+	case "hibp":
+		return NewHIBP(os.Getenv("PASSWORD_BREACH_URL")), nil
+	default:
+		return nil, fmt.Errorf("breach: unknown PASSWORD_BREACH_CHECK %q (want off, bloom or hibp)", os.Getenv("PASSWORD_BREACH_CHECK"))
+	}
+}
+
+// Hash is the SHA-1 a password is known by in breach corpora such as
+// Pwned Passwords. It is only ever a lookup key, never stored.
+func Hash(password string) [sha1.Size]byte {
+	return sha1.Sum([]byte(password))
+}
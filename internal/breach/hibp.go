@@ -0,0 +1,66 @@
+package breach
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultHIBPURL is Pwned Passwords' range API
+const DefaultHIBPURL = "https://api.pwnedpasswords.com"
+
+// HIBP asks Have I Been Pwned's range API (k-anonymity): it sends the first
+// five hex digits of the SHA-1 and matches the rest among the suffixes returned
+type HIBP struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewHIBP is the constructor; an empty baseURL means DefaultHIBPURL
+func NewHIBP(baseURL string) *HIBP {
+	if baseURL == "" {
+		baseURL = DefaultHIBPURL
+	}
+	return &HIBP{baseURL: strings.TrimSuffix(baseURL, "/"), client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+func (c *HIBP) Name() string { return "hibp" }
+
+func (c *HIBP) Breached(ctx context.Context, password string) (bool, error) {
+	sum := Hash(password)
+	digest := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := digest[:5], digest[5:]
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/range/"+prefix, nil)
+	if err != nil {
+		return false, err
+	}
+	// Padding hides from onlookers how many suffixes the prefix has; padded
+	// entries come with a count of 0
+	req.Header.Set("Add-Padding", "true")
+	req.Header.Set("User-Agent", "school-api")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return false, fmt.Errorf("breach: hibp: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("breach: hibp: unexpected status %s", resp.Status)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		s, count, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if ok && strings.EqualFold(s, suffix) {
+			return count != "0", nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("breach: hibp: %w", err)
+	}
+	return false, nil
+}
@@ -0,0 +1,84 @@
+// Package buildinfo tells which build of the API is running. Release builds
+// stamp it with -ldflags:
+//
+//	go build -ldflags "-X simpleapi/internal/buildinfo.version=1.4.0 \
+//	  -X simpleapi/internal/buildinfo.commit=$(git rev-parse HEAD) \
+//	  -X simpleapi/internal/buildinfo.date=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd/api
+//
+// Without them it falls back to what the Go toolchain stamps into the binary
+// (module version, VCS revision and time).
+package buildinfo
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"simpleapi/internal/models"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Product names the API in the Server header and the logs
+const Product = "school-api"
+
+// Set with -ldflags -X at build time
+var version, commit, date string
+
+// Get returns the build of the running binary. Fields nothing stamped (go
+// run, a build outside the git checkout) are left empty, but for the version
+// which is then "dev".
+var Get = sync.OnceValue(func() models.BuildInfo {
+	b := models.BuildInfo{GoVersion: runtime.Version()}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if v := info.Main.Version; v != "" && v != "(devel)" {
+			b.Version = v
+		}
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				b.Commit = s.Value
+			case "vcs.time":
+				b.Date = s.Value
+			case "vcs.modified":
+				b.Modified = s.Value == "true"
+			}
+		}
+	}
+	if version != "" {
+		b.Version = version
+	}
+	if commit != "" {
+		b.Commit, b.Modified = commit, false
+	}
+	if date != "" {
+		b.Date = date
+	}
+	if b.Version == "" {
+		b.Version = "dev"
+	}
+	return b
+})
+
+// Version is the running version without its "v", "dev" for an unversioned build
+func Version() string {
+	return strings.TrimPrefix(Get().Version, "v")
+}
+
+// Compatible says whether a client of version client may drive a server of
+// version server: their major versions must match. A dev build on either
+// side is trusted, since it is someone working on the code.
+func Compatible(client, server string) error {
+	cm, cok := major(client)
+	sm, sok := major(server)
+	if !cok || !sok || cm == sm {
+		return nil
+	}
+	return fmt.Errorf("client %s can't drive server %s: major versions differ", client, server)
+}
+
+func major(v string) (int, bool) {
+	head, _, _ := strings.Cut(strings.TrimPrefix(v, "v"), ".")
+	n, err := strconv.Atoi(head)
+	return n, err == nil
+}
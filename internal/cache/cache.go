@@ -0,0 +1,127 @@
+// Package cache keeps computed responses for a while: in this process, or with
+// SHARED_STATE=redis in Redis, so every API instance serves and invalidates
+// the same copy.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"maps"
+	"simpleapi/internal/metrics"
+	"simpleapi/internal/redis"
+	"simpleapi/pkg/clock"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Cache stores values, encoded, under keys for a time to live
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+// Load returns the value cached under key, or runs load and caches what it
+// returns for ttl. The cache is best effort: when it fails, load runs anyway.
+func Load[T any](ctx context.Context, c Cache, key string, ttl time.Duration, load func(context.Context) (T, error)) (T, error) {
+	name, _, _ := strings.Cut(key, ":")
+	if b, ok, err := c.Get(ctx, key); err != nil {
+		log.Printf("cache: get %s: %v", key, err)
+	} else if ok {
+		var v T
+		if err := json.Unmarshal(b, &v); err == nil {
+			metrics.CacheHits.Add(name, 1)
+			return v, nil
+		}
+		// Cached by an older version with another shape: load it afresh
+	}
+	metrics.CacheMisses.Add(name, 1)
+
+	// Two requests racing on a missing key both run load, which is harmless
+	v, err := load(ctx)
+	if err != nil {
+		return v, err
+	}
+	if b, err := json.Marshal(v); err != nil {
+		log.Printf("cache: encode %s: %v", key, err)
+	} else if err := c.Set(ctx, key, b, ttl); err != nil {
+		log.Printf("cache: set %s: %v", key, err)
+	}
+	return v, nil
+}
+
+// Memory keeps the values in this process
+type Memory struct {
+	mu      sync.Mutex
+	clock   clock.Clock
+	entries map[string]memoryEntry
+}
+
+type memoryEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewMemory is the constructor
+func NewMemory(clk clock.Clock) *Memory {
+	return &Memory{clock: clk, entries: make(map[string]memoryEntry)}
+}
+
+func (m *Memory) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.entries[key]
+	if !ok || !m.clock.Now().Before(e.expiresAt) {
+		return nil, false, nil
+	}
+	return e.value, true, nil
+}
+
+func (m *Memory) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.clock.Now()
+	// Keys vary with query parameters, so drop expired ones rather than let them pile up
+	maps.DeleteFunc(m.entries, func(_ string, e memoryEntry) bool { return !now.Before(e.expiresAt) })
+	m.entries[key] = memoryEntry{value: value, expiresAt: now.Add(ttl)}
+	return nil
+}
+
+func (m *Memory) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, key)
+	return nil
+}
+
+// Redis keeps the values in Redis, under "cache:" keys that expire on their own
+type Redis struct {
+	client *redis.Client
+}
+
+// NewRedis is the constructor
+func NewRedis(client *redis.Client) *Redis {
+	return &Redis{client: client}
+}
+
+func (r *Redis) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := r.client.Do(ctx, "GET", "cache:"+key)
+	if err != nil || reply == nil {
+		return nil, false, err
+	}
+	s, _ := reply.(string)
+	return []byte(s), true, nil
+}
+
+func (r *Redis) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	_, err := r.client.Do(ctx, "SET", "cache:"+key, string(value), "PX", strconv.FormatInt(max(ttl.Milliseconds(), 1), 10))
+	return err
+}
+
+func (r *Redis) Delete(ctx context.Context, key string) error {
+	_, err := r.client.Do(ctx, "DEL", "cache:"+key)
+	return err
+}
@@ -0,0 +1,40 @@
+// Package captcha verifies the CAPTCHA token a client sends with a login once
+// the account has failed too often (see package loginguard).
+//
+//	CAPTCHA_PROVIDER=off | turnstile   (empty means off)
+//	CAPTCHA_SECRET=...                  (the provider's secret key, a secret)
+//	CAPTCHA_VERIFY_URL=...              (turnstile; Cloudflare's siteverify by default)
+//
+// Off, logins never ask for one and only the delays of package loginguard apply.
+package captcha
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Verifier checks a token the client got from the provider's widget
+type Verifier interface {
+	Name() string
+	// Verify reports whether token is a valid, unused solution; remoteIP is
+	// the client's, which providers use as an extra signal
+	Verify(ctx context.Context, token, remoteIP string) (bool, error)
+}
+
+// VerifierFromEnv builds the verifier selected by CAPTCHA_PROVIDER, nil when
+// off. secret is CAPTCHA_SECRET, which main loads through the secrets store.
+func VerifierFromEnv(secret string) (Verifier, error) {
+	switch strings.ToLower(os.Getenv("CAPTCHA_PROVIDER")) {
+	case "", "off":
+		return nil, nil
+	case "turnstile":
+		if secret == "" {
+			return nil, fmt.Errorf("captcha: CAPTCHA_PROVIDER=turnstile needs CAPTCHA_SECRET")
+		}
+		return NewTurnstile(secret, os.Getenv("CAPTCHA_VERIFY_URL")), nil
+	default:
+		return nil, fmt.Errorf("captcha: unknown CAPTCHA_PROVIDER %q (want off or turnstile)", os.Getenv("CAPTCHA_PROVIDER"))
+	}
+}
@@ -0,0 +1,191 @@
+// Package database is the single place that opens the MySQL connection pool.
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"log/slog"
+	"os"
+	"simpleapi/pkg/secrets"
+	"strconv"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Config describes how to reach MySQL and how to size the pool.
+// Password is a func so a rotated secret is picked up by every new pooled
+// connection without restarting.
+type Config struct {
+	Username string
+	Password func() string
+	Host     string
+	Port     string
+	Name     string
+
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+
+	// ConnectRetries is how many extra pings to try at startup (the DB container
+	// is often still booting when the API starts). Backoff doubles each attempt.
+	ConnectRetries int
+	RetryBackoff   time.Duration
+
+	Logger *slog.Logger
+}
+
+// Pool defaults (Excellent choice, keeping these)
+const (
+	defaultMaxOpenConns    = 25
+	defaultMaxIdleConns    = 5
+	defaultConnMaxLifetime = 5 * time.Minute
+	defaultConnectRetries  = 5
+	defaultRetryBackoff    = 500 * time.Millisecond
+	maxRetryBackoff        = 10 * time.Second
+)
+
+// ConfigFromEnv reads DB_HOST, DB_PORT, DB_NAME and the optional pool knobs
+// (DB_MAX_OPEN_CONNS, DB_MAX_IDLE_CONNS, DB_CONN_MAX_LIFETIME, DB_CONNECT_RETRIES).
+// Credentials are left to the caller since they come from the secrets store.
+func ConfigFromEnv() Config {
+	cfg := Config{
+		Host:            os.Getenv("DB_HOST"),
+		Port:            os.Getenv("DB_PORT"),
+		Name:            os.Getenv("DB_NAME"),
+		MaxOpenConns:    envInt("DB_MAX_OPEN_CONNS", defaultMaxOpenConns),
+		MaxIdleConns:    envInt("DB_MAX_IDLE_CONNS", defaultMaxIdleConns),
+		ConnMaxLifetime: defaultConnMaxLifetime,
+		ConnectRetries:  envInt("DB_CONNECT_RETRIES", defaultConnectRetries),
+		RetryBackoff:    defaultRetryBackoff,
+	}
+	if d, err := time.ParseDuration(os.Getenv("DB_CONN_MAX_LIFETIME")); err == nil && d > 0 {
+		cfg.ConnMaxLifetime = d
+	}
+	return cfg
+}
+
+// Open builds the pool and waits (with backoff) until MySQL answers a ping.
+// It returns the *sql.DB object so main.go can control its lifecycle.
+func Open(ctx context.Context, cfg Config) (*sql.DB, error) {
+	logger := cfg.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if cfg.Password == nil {
+		cfg.Password = func() string { return "" }
+	}
+
+	// Pro Tip: parseTime=true is required for scanning MySQL DATETIME into Go time.Time
+	dsn := fmt.Sprintf("%s@tcp(%s:%s)/%s?tls=skip-verify&parseTime=true",
+		cfg.Username, cfg.Host, cfg.Port, cfg.Name)
+
+	mycfg, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("database: invalid configuration: %w", err)
+	}
+	// Every timestamp is stored in UTC, whatever the zone of the MySQL server:
+	// the driver writes and reads DATETIMEs as UTC (Loc), and the session's
+	// time_zone makes NOW() and TIMESTAMP columns agree. Handlers convert to
+	// the school's or the caller's zone on the way out (package locale).
+	mycfg.Loc = time.UTC
+	if mycfg.Params == nil {
+		mycfg.Params = make(map[string]string)
+	}
+	mycfg.Params["time_zone"] = "'+00:00'"
+	db := sql.OpenDB(&rotatingConnector{cfg: mycfg, password: cfg.Password})
+
+	db.SetMaxOpenConns(orDefault(cfg.MaxOpenConns, defaultMaxOpenConns))
+	db.SetMaxIdleConns(orDefault(cfg.MaxIdleConns, defaultMaxIdleConns))
+	lifetime := cfg.ConnMaxLifetime
+	if lifetime <= 0 {
+		lifetime = defaultConnMaxLifetime
+	}
+	db.SetConnMaxLifetime(lifetime)
+
+	// Verify connection, retrying while the database boots
+	backoff := cfg.RetryBackoff
+	if backoff <= 0 {
+		backoff = defaultRetryBackoff
+	}
+	attempts := cfg.ConnectRetries + 1
+	for attempt := 1; ; attempt++ {
+		err = db.PingContext(ctx)
+		if err == nil {
+			break
+		}
+		if attempt >= attempts {
+			db.Close()
+			return nil, fmt.Errorf("database: ping failed after %d attempts: %w", attempt, err)
+		}
+
+		logger.Warn("database not reachable yet, retrying",
+			"attempt", attempt, "max_attempts", attempts, "backoff", backoff.String(), "error", err)
+		select {
+		case <-ctx.Done():
+			db.Close()
+			return nil, fmt.Errorf("database: gave up waiting: %w", ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxRetryBackoff)
+	}
+
+	logger.Info("connected to database",
+		"host", cfg.Host, "port", cfg.Port, "database", cfg.Name,
+		"max_open_conns", orDefault(cfg.MaxOpenConns, defaultMaxOpenConns))
+	return db, nil
+}
+
+// OpenWithSecrets opens the pool like the API does: DB_USERNAME and
+// DB_PASSWORD come from store, the rest from ConfigFromEnv. A rotated
+// password reaches every new connection.
+func OpenWithSecrets(ctx context.Context, store *secrets.Store) (*sql.DB, error) {
+	user, err := store.Get(ctx, "DB_USERNAME")
+	if err != nil {
+		return nil, fmt.Errorf("database: loading DB_USERNAME: %w", err)
+	}
+	if _, err := store.Get(ctx, "DB_PASSWORD"); err != nil {
+		return nil, fmt.Errorf("database: loading DB_PASSWORD: %w", err)
+	}
+	cfg := ConfigFromEnv()
+	cfg.Username = user
+	cfg.Password = func() string { return store.Current("DB_PASSWORD") }
+	return Open(ctx, cfg)
+}
+
+// rotatingConnector reads the current password for each new connection.
+// Together with ConnMaxLifetime, the pool drains onto a rotated password within minutes.
+type rotatingConnector struct {
+	cfg      *mysql.Config
+	password func() string
+}
+
+func (c *rotatingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	cfg := c.cfg.Clone()
+	cfg.Passwd = c.password()
+	connector, err := mysql.NewConnector(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return connector.Connect(ctx)
+}
+
+func (c *rotatingConnector) Driver() driver.Driver {
+	return &mysql.MySQLDriver{}
+}
+
+func envInt(name string, def int) int {
+	if n, err := strconv.Atoi(os.Getenv(name)); err == nil && n >= 0 {
+		return n
+	}
+	return def
+}
+
+func orDefault(n, def int) int {
+	if n <= 0 {
+		return def
+	}
+	return n
+}
@@ -0,0 +1,66 @@
+// Package enrollment moves students through their lifecycle: an applicant
+// enrolls, an enrolled student may be suspended and reinstated, withdraws or
+// graduates to alumni, and a withdrawn student may re-enroll. Every change
+// goes through Service.Change, which refuses the moves transitions doesn't
+// list; the store records each one with its reason in the audit log.
+package enrollment
+
+import (
+	"context"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"slices"
+)
+
+// transitions are the states each state can move to. Alumni is final.
+var transitions = map[string][]string{
+	models.StudentApplicant: {models.StudentEnrolled, models.StudentWithdrawn},
+	models.StudentEnrolled:  {models.StudentSuspended, models.StudentWithdrawn, models.StudentAlumni},
+	models.StudentSuspended: {models.StudentEnrolled, models.StudentWithdrawn},
+	models.StudentWithdrawn: {models.StudentEnrolled},
+	models.StudentAlumni:    nil,
+}
+
+// Allowed reports whether a student can move from one state to another
+func Allowed(from, to string) bool {
+	return slices.Contains(transitions[from], to)
+}
+
+// Service changes students' states
+type Service struct {
+	Students  repository.StudentStore
+	Reference repository.ReferenceStore
+}
+
+// New is the constructor
+func New(students repository.StudentStore, reference repository.ReferenceStore) *Service {
+	return &Service{Students: students, Reference: reference}
+}
+
+// Change moves the student to req.Status, and to req.Class when they
+// (re-)enroll with one. A move transitions doesn't list, or a class outside
+// the school's, comes back as errs with a nil error.
+func (s *Service) Change(ctx context.Context, id int, req models.StudentStatusRequest, actorID *int) (*models.Student, []models.ValidationError, error) {
+	student, err := s.Students.GetByID(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !Allowed(student.Status, req.Status) {
+		return nil, []models.ValidationError{models.RuleError("Status", "student_transition", student.Status)}, nil
+	}
+	if req.Class != "" {
+		if req.Status != models.StudentEnrolled {
+			return nil, []models.ValidationError{models.RuleError("Class", "status_class", "")}, nil
+		}
+		reference, err := s.Reference.Get(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		if errs := reference.CheckStudent(models.Student{Class: req.Class}); len(errs) > 0 {
+			return nil, errs, nil
+		}
+	}
+	// The store checks the state again, for a change made meanwhile
+	changed, err := s.Students.SetStatus(ctx, id, student.Status, req, actorID)
+	return changed, nil, err
+}
@@ -0,0 +1,170 @@
+// Package exams plans exam sessions: where each student sits and which
+// teachers invigilate each room. Plans are generated whole and saved on the
+// session; generating again replaces them.
+package exams
+
+import (
+	"cmp"
+	"math/rand/v2"
+	"simpleapi/internal/models"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Capacity is how many students the rooms seat together
+func Capacity(rooms []models.ExamRoom) int {
+	n := 0
+	for _, r := range rooms {
+		n += r.Capacity
+	}
+	return n
+}
+
+// Seat places students in the rooms, filling each in turn, in the order req
+// asks for (a random order draws from req.Seed). With SeparateClasses each
+// seat takes the first student in that order who isn't a classmate of the
+// students beside and in front, favouring the classes with the most students
+// left so that no class is bunched up at the end. It returns the seats and
+// how many students still sit beside or behind a classmate. The rooms must
+// hold every student (see Capacity).
+func Seat(students []models.Student, rooms []models.ExamRoom, req models.ExamSeatingRequest) ([]models.ExamSeat, int) {
+	queue := slices.Clone(students)
+	slices.SortFunc(queue, func(a, b models.Student) int {
+		return cmp.Or(
+			strings.Compare(strings.ToLower(a.LastName), strings.ToLower(b.LastName)),
+			strings.Compare(strings.ToLower(a.FirstName), strings.ToLower(b.FirstName)),
+			cmp.Compare(a.ID, b.ID),
+		)
+	})
+	if req.Order == models.SeatingRandom {
+		r := rand.New(rand.NewPCG(uint64(req.Seed), 0))
+		r.Shuffle(len(queue), func(i, j int) { queue[i], queue[j] = queue[j], queue[i] })
+	}
+	left := make(map[string]int)
+	for _, s := range queue {
+		left[s.Class]++
+	}
+
+	seats := make([]models.ExamSeat, 0, len(queue))
+	conflicts := 0
+	for _, room := range rooms {
+		width := room.Width()
+		first := len(seats) // The room's seats are seats[first:]
+		for n := 0; n < room.Capacity && len(queue) > 0; n++ {
+			var beside, ahead string
+			if n%width > 0 {
+				beside = seats[first+n-1].Class
+			}
+			if n >= width {
+				ahead = seats[first+n-width].Class
+			}
+
+			pick := 0
+			if req.SeparateClasses {
+				pick = separated(queue, left, beside, ahead)
+			}
+			s := queue[pick]
+			queue = slices.Delete(queue, pick, pick+1)
+			left[s.Class]--
+			if s.Class == beside || s.Class == ahead {
+				conflicts++
+			}
+			seats = append(seats, models.ExamSeat{
+				Room:            room.Name,
+				Seat:            n + 1,
+				Row:             n/width + 1,
+				Column:          n%width + 1,
+				StudentID:       s.ID,
+				FirstName:       s.FirstName,
+				LastName:        s.LastName,
+				Class:           s.Class,
+				AdmissionNumber: s.AdmissionNumber,
+			})
+		}
+	}
+	return seats, conflicts
+}
+
+// separated picks the index in queue of the next student to seat beside and
+// behind students of those classes: of the classes that differ, the one with
+// the most students left, its first student in the queue. With no such
+// student the queue's first is seated anyway.
+func separated(queue []models.Student, left map[string]int, beside, ahead string) int {
+	pick := -1
+	for i, s := range queue {
+		if s.Class == beside || s.Class == ahead {
+			continue
+		}
+		if pick < 0 || left[s.Class] > left[queue[pick].Class] {
+			pick = i
+		}
+	}
+	return max(pick, 0)
+}
+
+// Invigilate assigns each room of session the teachers it needs. Teachers
+// in exclude, and those invigilating another session that overlaps it, are
+// never picked. Of the others, those who don't teach the session's subject
+// come first, then those with the fewest duties on the day, so that the
+// work is spread. The error is a validation error when too few are free.
+func Invigilate(session models.ExamSession, teachers []models.Teacher, others []models.ExamSession, exclude []int) ([]models.ExamInvigilator, []models.ValidationError) {
+	busy := make(map[int]bool)
+	for _, id := range exclude {
+		busy[id] = true
+	}
+	duties := make(map[int]int)
+	for _, o := range others {
+		if o.ID == session.ID || o.Date != session.Date {
+			continue
+		}
+		for _, inv := range o.Invigilators {
+			duties[inv.TeacherID]++
+			if o.Overlaps(session) {
+				busy[inv.TeacherID] = true
+			}
+		}
+	}
+
+	var free []models.Teacher
+	for _, t := range teachers {
+		if t.IsActive && t.Role == models.RoleTeacher && !busy[t.ID] {
+			free = append(free, t)
+		}
+	}
+	slices.SortFunc(free, func(a, b models.Teacher) int {
+		return cmp.Or(
+			cmp.Compare(teaches(a, session.Subject), teaches(b, session.Subject)),
+			cmp.Compare(duties[a.ID], duties[b.ID]),
+			cmp.Compare(a.ID, b.ID),
+		)
+	})
+
+	needed := 0
+	for _, room := range session.Rooms {
+		needed += room.Needs()
+	}
+	if len(free) < needed {
+		return nil, []models.ValidationError{models.RuleError("Rooms", "exam_invigilators", strconv.Itoa(len(free)))}
+	}
+
+	invigilators := make([]models.ExamInvigilator, 0, needed)
+	for _, room := range session.Rooms {
+		for range room.Needs() {
+			t := free[0]
+			free = free[1:]
+			invigilators = append(invigilators, models.ExamInvigilator{
+				Room: room.Name, TeacherID: t.ID, FirstName: t.FirstName, LastName: t.LastName,
+			})
+		}
+	}
+	return invigilators, nil
+}
+
+// teaches is 1 when t teaches subject, for sorting them last
+func teaches(t models.Teacher, subject string) int {
+	if subject != "" && strings.EqualFold(t.Subject, subject) {
+		return 1
+	}
+	return 0
+}
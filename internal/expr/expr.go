@@ -0,0 +1,310 @@
+// Package expr parses the ?filter= expressions of list endpoints, for
+// reports the simple equality parameters can't express, e.g.
+//
+//	class eq "10A" and (date_of_birth ge "2010-01-01" or gender in ("female", "other"))
+//
+// Comparisons are eq, ne, gt, ge, lt, le, in (a list) and, for text fields,
+// contains and startswith; they combine with and, or, not and parentheses.
+// null works with eq and ne; other comparisons with a null field never match,
+// as in SQL.
+//
+// Only the fields an entity lists in its Fields can be used, and values are
+// checked against the field's type while parsing. The resulting Expr becomes
+// parameterized SQL (SQL), so values never reach the query text, or is tested
+// against in-memory records (Match).
+package expr
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Type is the type of a filterable field, which decides the values and
+// operators it takes
+type Type int
+
+const (
+	Text   Type = iota // "quoted strings"
+	Number             // 12, 3.5
+	Date               // "YYYY-MM-DD"
+	Bool               // true, false
+)
+
+// Field is one filterable field of an entity
+type Field struct {
+	Type Type
+	// Column is the SQL expression the field compares as, e.g. with a
+	// COLLATE clause; empty means the field name
+	Column string
+	// Equal replaces == for text values in Match, for columns whose
+	// collation isn't plain byte equality (e.g. names, see models.NamesMatch)
+	Equal func(a, b string) bool
+}
+
+// Fields is an entity's whitelist: field name -> Field
+type Fields map[string]Field
+
+// Expr is a parsed filter expression
+type Expr interface {
+	sql(b *strings.Builder, args *[]any)
+	match(get func(field string) any) truth
+}
+
+// SQL renders e as a condition with ? placeholders, ready to append after AND
+func SQL(e Expr) (string, []any) {
+	var b strings.Builder
+	var args []any
+	e.sql(&b, &args)
+	return b.String(), args
+}
+
+// Match tests a record against e. get returns a field's value as a string
+// (text and dates), an int or float64, a bool, or nil for NULL.
+func Match(e Expr, get func(field string) any) bool {
+	return e.match(get) == isTrue
+}
+
+// Compare builds the comparison name op value in code, for the filters of
+// fixed query parameters (e.g. ?class=) and of jobs. Numbers may be any int
+// or float64. It panics on a field outside fields or an unknown operator:
+// both are constants at the call site, unlike parsed input.
+func Compare(fields Fields, name, op string, value any) Expr {
+	field, ok := fields[name]
+	if !ok {
+		panic(fmt.Sprintf("expr: unknown field %q", name))
+	}
+	if sqlOps[op] == "" && !textOps[op] {
+		panic(fmt.Sprintf("expr: unknown operator %q", op))
+	}
+	if i, ok := value.(int); ok {
+		value = float64(i)
+	}
+	return comparison{name: name, field: field, op: op, values: []any{value}}
+}
+
+// Equal is Compare with eq
+func Equal(fields Fields, name string, value any) Expr {
+	return Compare(fields, name, "eq", value)
+}
+
+// All joins exprs with and, skipping nils; it is nil when nothing is left
+func All(exprs ...Expr) Expr {
+	var all Expr
+	for _, e := range exprs {
+		switch {
+		case e == nil:
+		case all == nil:
+			all = e
+		default:
+			all = and{all, e}
+		}
+	}
+	return all
+}
+
+// truth is SQL's three-valued logic, which Match follows so that memory and
+// MySQL agree on NULLs: a comparison with NULL is unknown, and so is NOT unknown
+type truth int8
+
+const (
+	isFalse truth = iota
+	isTrue
+	unknown
+)
+
+func truthOf(b bool) truth {
+	if b {
+		return isTrue
+	}
+	return isFalse
+}
+
+type and struct{ left, right Expr }
+
+func (e and) sql(b *strings.Builder, args *[]any) {
+	b.WriteString("(")
+	e.left.sql(b, args)
+	b.WriteString(" AND ")
+	e.right.sql(b, args)
+	b.WriteString(")")
+}
+
+func (e and) match(get func(string) any) truth {
+	left, right := e.left.match(get), e.right.match(get)
+	switch {
+	case left == isFalse || right == isFalse:
+		return isFalse
+	case left == unknown || right == unknown:
+		return unknown
+	}
+	return isTrue
+}
+
+type or struct{ left, right Expr }
+
+func (e or) sql(b *strings.Builder, args *[]any) {
+	b.WriteString("(")
+	e.left.sql(b, args)
+	b.WriteString(" OR ")
+	e.right.sql(b, args)
+	b.WriteString(")")
+}
+
+func (e or) match(get func(string) any) truth {
+	left, right := e.left.match(get), e.right.match(get)
+	switch {
+	case left == isTrue || right == isTrue:
+		return isTrue
+	case left == unknown || right == unknown:
+		return unknown
+	}
+	return isFalse
+}
+
+type not struct{ expr Expr }
+
+func (e not) sql(b *strings.Builder, args *[]any) {
+	b.WriteString("NOT (")
+	e.expr.sql(b, args)
+	b.WriteString(")")
+}
+
+func (e not) match(get func(string) any) truth {
+	switch e.expr.match(get) {
+	case isTrue:
+		return isFalse
+	case isFalse:
+		return isTrue
+	}
+	return unknown
+}
+
+// comparison is one field test. values hold strings (text and dates),
+// float64s, bools or a single nil for null.
+type comparison struct {
+	name   string
+	field  Field
+	op     string
+	values []any
+}
+
+func (c comparison) nullTest() bool {
+	return len(c.values) == 1 && c.values[0] == nil
+}
+
+var sqlOps = map[string]string{"eq": "=", "ne": "<>", "gt": ">", "ge": ">=", "lt": "<", "le": "<="}
+
+func (c comparison) sql(b *strings.Builder, args *[]any) {
+	column := c.field.Column
+	if column == "" {
+		column = c.name
+	}
+	b.WriteString(column)
+
+	switch {
+	case c.nullTest() && c.op == "eq":
+		b.WriteString(" IS NULL")
+	case c.nullTest():
+		b.WriteString(" IS NOT NULL")
+	case c.op == "in":
+		b.WriteString(" IN (")
+		for i, v := range c.values {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString("?")
+			*args = append(*args, v)
+		}
+		b.WriteString(")")
+	case c.op == "contains":
+		b.WriteString(" LIKE ?")
+		*args = append(*args, "%"+escapeLike(c.values[0].(string))+"%")
+	case c.op == "startswith":
+		b.WriteString(" LIKE ?")
+		*args = append(*args, escapeLike(c.values[0].(string))+"%")
+	default:
+		fmt.Fprintf(b, " %s ?", sqlOps[c.op])
+		*args = append(*args, c.values[0])
+	}
+}
+
+// escapeLike makes LIKE wildcards in a value literal (MySQL escapes with \)
+var escapeLike = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace
+
+func (c comparison) match(get func(string) any) truth {
+	actual := get(c.name)
+	if c.nullTest() {
+		return truthOf((actual == nil) == (c.op == "eq"))
+	}
+	if actual == nil {
+		return unknown
+	}
+	if i, ok := actual.(int); ok {
+		actual = float64(i)
+	}
+
+	switch c.op {
+	case "in":
+		for _, v := range c.values {
+			if c.compare(actual, v) == 0 {
+				return isTrue
+			}
+		}
+		return isFalse
+	case "contains", "startswith":
+		// Like MySQL's default collations, ignore case
+		s, want := strings.ToLower(actual.(string)), strings.ToLower(c.values[0].(string))
+		if c.op == "contains" {
+			return truthOf(strings.Contains(s, want))
+		}
+		return truthOf(strings.HasPrefix(s, want))
+	}
+
+	cmp := c.compare(actual, c.values[0])
+	switch c.op {
+	case "eq":
+		return truthOf(cmp == 0)
+	case "ne":
+		return truthOf(cmp != 0)
+	case "gt":
+		return truthOf(cmp > 0)
+	case "ge":
+		return truthOf(cmp >= 0)
+	case "lt":
+		return truthOf(cmp < 0)
+	case "le":
+		return truthOf(cmp <= 0)
+	}
+	return isFalse
+}
+
+// compare orders two values of the field's type; dates compare as their
+// YYYY-MM-DD text
+func (c comparison) compare(a, b any) int {
+	switch a := a.(type) {
+	case string:
+		b := b.(string)
+		if c.field.Equal != nil && c.field.Equal(a, b) {
+			return 0
+		}
+		return strings.Compare(a, b)
+	case float64:
+		b := b.(float64)
+		switch {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+		return 0
+	case bool:
+		if a == b.(bool) {
+			return 0
+		}
+		if b.(bool) {
+			return -1
+		}
+		return 1
+	}
+	return -1
+}
@@ -0,0 +1,113 @@
+package expr
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testFields = Fields{
+	"class":         {Type: Text},
+	"last_name":     {Type: Text, Column: "last_name COLLATE utf8mb4_0900_ai_ci"},
+	"date_of_birth": {Type: Date},
+	"score":         {Type: Number},
+	"active":        {Type: Bool},
+}
+
+func TestSQL(t *testing.T) {
+	tests := []struct {
+		filter string
+		sql    string
+		args   []any
+	}{
+		{`class eq "10A"`, "class = ?", []any{"10A"}},
+		{`score ge 50`, "score >= ?", []any{50.0}},
+		{`active eq true`, "active = ?", []any{true}},
+		{`date_of_birth lt "2010-01-01"`, "date_of_birth < ?", []any{"2010-01-01"}},
+		{`class eq null`, "class IS NULL", nil},
+		{`class ne null`, "class IS NOT NULL", nil},
+		{`class in ("10A", "10B")`, "class IN (?, ?)", []any{"10A", "10B"}},
+		{`last_name eq "Obi"`, "last_name COLLATE utf8mb4_0900_ai_ci = ?", []any{"Obi"}},
+		{`class contains "1"`, "class LIKE ?", []any{"%1%"}},
+		{`class startswith "10_%\\"`, "class LIKE ?", []any{`10\_\%\\%`}}, // Wildcards in the value stay literal
+		{`class eq "10A" and score gt 50 or active eq false`,
+			"((class = ? AND score > ?) OR active = ?)", []any{"10A", 50.0, false}},
+		{`class eq "10A" and (score gt 50 or not active eq true)`,
+			"(class = ? AND (score > ? OR NOT (active = ?)))", []any{"10A", 50.0, true}},
+		{`CLASS EQ "x' OR 1=1 --"`, "class = ?", []any{"x' OR 1=1 --"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			e, err := Parse(tt.filter, testFields)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sql, args := SQL(e)
+			if sql != tt.sql {
+				t.Errorf("SQL = %q, want %q", sql, tt.sql)
+			}
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("args = %#v, want %#v", args, tt.args)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		`password eq "x"`,             // Not in the whitelist
+		`class = "10A"`,               // Operators are words
+		`class eq 10`,                 // Text takes strings
+		`score eq "50"`,               // Numbers take numbers
+		`date_of_birth eq "1/2/2010"`, // Dates are YYYY-MM-DD
+		`active gt true`,
+		`score contains "5"`,
+		`class eq "10A" and`,
+		`(class eq "10A"`,
+		`class eq "10A"; DROP TABLE students`,
+		`class in ()`,
+		`class eq "10A` + strings.Repeat(" ", MaxLength) + `"`,
+		strings.Repeat(`score eq 1 or `, MaxComparisons) + `score eq 1`,
+		strings.Repeat("not ", maxDepth+1) + `active eq true`,
+	}
+	for _, filter := range tests {
+		t.Run(filter, func(t *testing.T) {
+			_, err := Parse(filter, testFields)
+			var syntax *SyntaxError
+			if !errors.As(err, &syntax) {
+				t.Errorf("Parse error = %v, want a SyntaxError", err)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	record := map[string]any{"class": "10A", "last_name": nil, "date_of_birth": "2011-05-04", "score": 72, "active": true}
+	get := func(field string) any { return record[field] }
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		{`class eq "10A"`, true},
+		{`class contains "0a"`, true}, // Case-insensitive like MySQL's collations
+		{`class in ("9B", "10A")`, true},
+		{`score gt 70 and date_of_birth ge "2011-01-01"`, true},
+		{`score lt 50 or active eq false`, false},
+		{`last_name eq null`, true},
+		{`last_name eq "Obi"`, false},
+		{`not last_name eq "Obi"`, false}, // NOT unknown is unknown, as in SQL
+		{`not last_name eq "Obi" or class eq "10A"`, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			e, err := Parse(tt.filter, testFields)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := Match(e, get); got != tt.want {
+				t.Errorf("Match = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,340 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limits keep a filter from turning into an expensive query
+const (
+	MaxLength      = 1000 // Characters of the whole expression
+	MaxComparisons = 20
+	MaxInValues    = 50
+	maxDepth       = 10 // Nested parentheses and nots
+)
+
+// SyntaxError is what Parse reports, with the position (in bytes, from 0)
+// of the token it stopped at
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s at position %d", e.Msg, e.Pos)
+}
+
+// Parse reads a filter expression against an entity's fields. An empty
+// expression is no filter: nil, nil.
+func Parse(s string, fields Fields) (Expr, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	if len(s) > MaxLength {
+		return nil, &SyntaxError{Pos: MaxLength, Msg: fmt.Sprintf("filter is longer than %d characters", MaxLength)}
+	}
+	tokens, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens, fields: fields}
+	e, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t, "unexpected %s", t)
+	}
+	return e, nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokNumber
+	tokLParen
+	tokRParen
+	tokComma
+)
+
+type token struct {
+	kind tokenKind
+	text string // Words are lowercased, strings unquoted
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of filter"
+	case tokString:
+		return strconv.Quote(t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+func lex(s string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokRParen, ")", i})
+			i++
+		case c == ',':
+			tokens = append(tokens, token{tokComma, ",", i})
+			i++
+		case c == '"':
+			text, end, err := lexString(s, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{tokString, text, i})
+			i = end
+		case c == '-' || c >= '0' && c <= '9':
+			start := i
+			i++
+			for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{tokNumber, s[start:i], start})
+		case isLetter(c):
+			start := i
+			for i < len(s) && (isLetter(s[i]) || s[i] >= '0' && s[i] <= '9') {
+				i++
+			}
+			tokens = append(tokens, token{tokWord, strings.ToLower(s[start:i]), start})
+		default:
+			return nil, &SyntaxError{Pos: i, Msg: fmt.Sprintf("unexpected character %q", c)}
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(s)}), nil
+}
+
+// isLetter reports the bytes words start with: field names, operators and keywords are ASCII
+func isLetter(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// lexString reads the "quoted" string at s[start]; \" and \\ are the only escapes
+func lexString(s string, start int) (string, int, error) {
+	var b strings.Builder
+	for i := start + 1; i < len(s); i++ {
+		switch s[i] {
+		case '"':
+			return b.String(), i + 1, nil
+		case '\\':
+			if i+1 < len(s) && (s[i+1] == '"' || s[i+1] == '\\') {
+				i++
+				b.WriteByte(s[i])
+				continue
+			}
+			return "", 0, &SyntaxError{Pos: i, Msg: `only \" and \\ can be escaped`}
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", 0, &SyntaxError{Pos: start, Msg: "unterminated string"}
+}
+
+// parser is a recursive descent over
+//
+//	or         = and { "or" and }
+//	and        = unary { "and" unary }
+//	unary      = "not" unary | "(" or ")" | comparison
+//	comparison = field op value | field "in" "(" value { "," value } ")"
+type parser struct {
+	tokens      []token
+	next        int
+	fields      Fields
+	depth       int
+	comparisons int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) take() token {
+	t := p.tokens[p.next]
+	if t.kind != tokEOF {
+		p.next++
+	}
+	return t
+}
+
+func (p *parser) errorf(t token, format string, args ...any) error {
+	return &SyntaxError{Pos: t.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *parser) isWord(word string) bool {
+	t := p.peek()
+	return t.kind == tokWord && t.text == word
+}
+
+func (p *parser) or() (Expr, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.isWord("or") {
+		p.take()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = or{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (Expr, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.isWord("and") {
+		p.take()
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = and{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) unary() (Expr, error) {
+	t := p.peek()
+	if t.kind != tokLParen && !p.isWord("not") {
+		return p.comparison()
+	}
+
+	if p.depth++; p.depth > maxDepth {
+		return nil, p.errorf(t, "filter is nested more than %d levels deep", maxDepth)
+	}
+	defer func() { p.depth-- }()
+
+	p.take()
+	if t.kind == tokWord { // not
+		e, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return not{e}, nil
+	}
+	e, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if closing := p.take(); closing.kind != tokRParen {
+		return nil, p.errorf(closing, "expected ) but found %s", closing)
+	}
+	return e, nil
+}
+
+var textOps = map[string]bool{"contains": true, "startswith": true}
+
+func (p *parser) comparison() (Expr, error) {
+	t := p.take()
+	if t.kind != tokWord {
+		return nil, p.errorf(t, "expected a field name but found %s", t)
+	}
+	field, ok := p.fields[t.text]
+	if !ok {
+		return nil, p.errorf(t, "unknown field %s", t)
+	}
+	if p.comparisons++; p.comparisons > MaxComparisons {
+		return nil, p.errorf(t, "filter has more than %d comparisons", MaxComparisons)
+	}
+
+	opTok := p.take()
+	op := opTok.text
+	switch {
+	case opTok.kind != tokWord || (sqlOps[op] == "" && op != "in" && !textOps[op]):
+		return nil, p.errorf(opTok, "expected an operator (eq, ne, gt, ge, lt, le, in, contains, startswith) but found %s", opTok)
+	case textOps[op] && field.Type != Text:
+		return nil, p.errorf(opTok, "%s only works on text fields", op)
+	case op != "eq" && op != "ne" && op != "in" && field.Type == Bool:
+		return nil, p.errorf(opTok, "%s can't compare true/false fields", op)
+	}
+
+	c := comparison{name: t.text, field: field, op: op}
+	if op != "in" {
+		v, err := p.value(field, op == "eq" || op == "ne")
+		if err != nil {
+			return nil, err
+		}
+		c.values = []any{v}
+		return c, nil
+	}
+
+	if open := p.take(); open.kind != tokLParen {
+		return nil, p.errorf(open, "expected ( after in but found %s", open)
+	}
+	for {
+		v, err := p.value(field, false)
+		if err != nil {
+			return nil, err
+		}
+		if c.values = append(c.values, v); len(c.values) > MaxInValues {
+			return nil, p.errorf(t, "in takes at most %d values", MaxInValues)
+		}
+		sep := p.take()
+		if sep.kind == tokRParen {
+			return c, nil
+		}
+		if sep.kind != tokComma {
+			return nil, p.errorf(sep, "expected , or ) but found %s", sep)
+		}
+	}
+}
+
+// value reads a literal of the field's type; null only where allowNull
+func (p *parser) value(field Field, allowNull bool) (any, error) {
+	t := p.take()
+	if t.kind == tokWord && t.text == "null" {
+		if !allowNull {
+			return nil, p.errorf(t, "null only works with eq and ne")
+		}
+		return nil, nil
+	}
+
+	switch field.Type {
+	case Text:
+		if t.kind == tokString {
+			return t.text, nil
+		}
+		return nil, p.errorf(t, "expected a \"quoted\" text value but found %s", t)
+	case Date:
+		if t.kind == tokString {
+			if _, err := time.Parse(time.DateOnly, t.text); err == nil {
+				return t.text, nil
+			}
+		}
+		return nil, p.errorf(t, "expected a \"YYYY-MM-DD\" date but found %s", t)
+	case Number:
+		if t.kind == tokNumber {
+			if n, err := strconv.ParseFloat(t.text, 64); err == nil {
+				return n, nil
+			}
+		}
+		return nil, p.errorf(t, "expected a number but found %s", t)
+	case Bool:
+		if t.kind == tokWord && (t.text == "true" || t.text == "false") {
+			return t.text == "true", nil
+		}
+		return nil, p.errorf(t, "expected true or false but found %s", t)
+	}
+	return nil, p.errorf(t, "unexpected %s", t)
+}
@@ -0,0 +1,118 @@
+// Package imaging is the photo pipeline (and the logo's): decode, validate, resize, re-encode.
+// Re-encoding also strips EXIF (GPS location!) from phone photos.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	"golang.org/x/image/draw"
+)
+
+// Limits that keep a malicious file from eating all our memory
+const (
+	MaxFileBytes = 10 << 20 // 10 MB per image
+	MaxDimension = 8000     // Reject anything larger before decoding pixels
+	OriginalMax  = 1600     // Longest edge we keep for the "original"
+	ThumbnailMax = 256      // Longest edge of the thumbnail
+	LogoMax      = 512      // Longest edge of the school's logo
+	jpegQuality  = 85
+)
+
+var ErrUnsupported = errors.New("unsupported image (only JPEG and PNG are accepted)")
+
+// Variants is the output of the pipeline, both JPEG encoded
+type Variants struct {
+	Original  []byte
+	Thumbnail []byte
+}
+
+// Process validates an uploaded image and produces the original and thumbnail variants
+func Process(r io.Reader) (*Variants, error) {
+	img, err := decode(r)
+	if err != nil {
+		return nil, err
+	}
+	original, err := encode(fit(img, OriginalMax))
+	if err != nil {
+		return nil, err
+	}
+	thumb, err := encode(fit(img, ThumbnailMax))
+	if err != nil {
+		return nil, err
+	}
+	return &Variants{Original: original, Thumbnail: thumb}, nil
+}
+
+// Logo validates an uploaded logo and re-encodes it as a PNG of at most
+// LogoMax pixels a side, keeping any transparency
+func Logo(r io.Reader) ([]byte, error) {
+	img, err := decode(r)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, fit(img, LogoMax)); err != nil {
+		return nil, fmt.Errorf("encode image: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// decode reads and checks an upload before decoding its pixels
+func decode(r io.Reader) (image.Image, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxFileBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("read image: %w", err)
+	}
+	if len(data) > MaxFileBytes {
+		return nil, fmt.Errorf("image larger than %d MB", MaxFileBytes>>20)
+	}
+
+	// Check dimensions from the header before allocating the full bitmap
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || (format != "jpeg" && format != "png") {
+		return nil, ErrUnsupported
+	}
+	if cfg.Width > MaxDimension || cfg.Height > MaxDimension {
+		return nil, fmt.Errorf("image is %dx%d, max is %dx%d", cfg.Width, cfg.Height, MaxDimension, MaxDimension)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode image: %w", err)
+	}
+	return img, nil
+}
+
+// fit scales img down (never up) so its longest edge is at most maxEdge
+func fit(img image.Image, maxEdge int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= maxEdge && h <= maxEdge {
+		return img
+	}
+
+	if w >= h {
+		h = h * maxEdge / w
+		w = maxEdge
+	} else {
+		w = w * maxEdge / h
+		h = maxEdge
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, max(w, 1), max(h, 1)))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Over, nil)
+	return dst
+}
+
+func encode(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: jpegQuality}); err != nil {
+		return nil, fmt.Errorf("encode image: %w", err)
+	}
+	return buf.Bytes(), nil
+}
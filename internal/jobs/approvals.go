@@ -0,0 +1,89 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"simpleapi/internal/expr"
+	"simpleapi/internal/mail"
+	"simpleapi/internal/models"
+	"simpleapi/internal/query"
+	"simpleapi/internal/repository"
+	"strings"
+)
+
+// ApprovalNotices emails the people an approval's state change concerns: its
+// approvers when it is submitted, its requester when it is decided
+type ApprovalNotices struct {
+	Mailer   mail.Sender
+	Teachers repository.TeacherStore
+	AppURL   string // APP_URL: the frontend, which serves /approvals/{id}
+}
+
+// Job wraps the sends for the queue, so deciding doesn't wait on the mail server
+func (n *ApprovalNotices) Job(a models.Approval) Job {
+	return Job{
+		Name: fmt.Sprintf("approval %d %s notices", a.ID, a.State),
+		Run:  func(ctx context.Context) error { return n.notify(ctx, a) },
+	}
+}
+
+func (n *ApprovalNotices) notify(ctx context.Context, a models.Approval) error {
+	what := strings.ReplaceAll(a.Type, "_", " ")
+	link := fmt.Sprintf("%s/approvals/%d", n.AppURL, a.ID)
+
+	switch a.State {
+	case models.ApprovalPending:
+		requester, err := n.Teachers.GetByID(ctx, a.RequestedBy)
+		if err != nil {
+			return err
+		}
+		approvers, err := n.Teachers.GetAll(ctx, query.Options{Where: expr.All(
+			expr.Equal(models.TeacherFields, "role", a.ApproverRole),
+			expr.Equal(models.TeacherFields, "is_active", true),
+		)})
+		if err != nil {
+			return err
+		}
+		sent := 0
+		for _, t := range approvers {
+			if t.ID == a.RequestedBy {
+				continue
+			}
+			msg := mail.Message{
+				To:      t.Email,
+				Subject: fmt.Sprintf("Approval needed: %s request", what),
+				Body: fmt.Sprintf("%s %s submitted a request that needs your approval (%s).\n\nReview it here:\n%s\n",
+					requester.FirstName, requester.LastName, what, link),
+			}
+			if err := n.Mailer.Send(ctx, msg); err != nil {
+				log.Printf("jobs: approval %d notice to teacher %d: %v", a.ID, t.ID, err)
+				continue
+			}
+			sent++
+		}
+		if sent > 0 {
+			log.Printf("jobs: emailed %d approvers about approval %d", sent, a.ID)
+		}
+		return nil
+
+	case models.ApprovalApproved, models.ApprovalRejected:
+		requester, err := n.Teachers.GetByID(ctx, a.RequestedBy)
+		if err != nil {
+			return err
+		}
+		comment := ""
+		if a.Comment != "" {
+			comment = fmt.Sprintf("\nComment: %s\n", a.Comment)
+		}
+		msg := mail.Message{
+			To:      requester.Email,
+			Subject: fmt.Sprintf("Your %s request was %s", what, a.State),
+			Body:    fmt.Sprintf("Your %s request was %s.\n%s\nDetails:\n%s\n", what, a.State, comment, link),
+		}
+		if err := n.Mailer.Send(ctx, msg); err != nil {
+			return fmt.Errorf("jobs: approval %d notice to the requester: %w", a.ID, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package jobs
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"simpleapi/internal/models"
+	"simpleapi/internal/query"
+	"simpleapi/internal/repository"
+	"simpleapi/internal/storage"
+	"simpleapi/pkg/clock"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// YearArchiver builds academic-year archive bundles: a zip holding
+// snapshot.json (everything) and one CSV per section for spreadsheet users
+type YearArchiver struct {
+	Archives repository.ArchiveStore
+	Students repository.StudentStore
+	Teachers repository.TeacherStore
+	Scores   repository.ScoreStore
+	Storage  storage.Storage
+	Clock    clock.Clock
+}
+
+// ArchiveKey is where an archive's bundle lives in storage. The archive ID is
+// part of the key, so a new archive of the same year never overwrites an old one.
+func ArchiveKey(a models.YearArchive) string {
+	return fmt.Sprintf("archives/%s/%d.zip", strings.ReplaceAll(a.Year, "/", "-"), a.ID)
+}
+
+// Job wraps Run for the queue
+func (ar *YearArchiver) Job(a models.YearArchive) Job {
+	return Job{
+		Name:    fmt.Sprintf("archive %s (#%d)", a.Year, a.ID),
+		Run:     func(ctx context.Context) error { return ar.Run(ctx, a) },
+		NoRetry: true,
+	}
+}
+
+// Run builds and stores the bundle, recording the outcome on the archive
+func (ar *YearArchiver) Run(ctx context.Context, a models.YearArchive) error {
+	if err := ar.Archives.MarkRunning(ctx, a.ID); err != nil {
+		return err
+	}
+
+	result, err := ar.build(ctx, a)
+	if err != nil {
+		result = models.YearArchive{Status: models.JobFailed, Error: err.Error()}
+	}
+	if ferr := ar.Archives.Finish(ctx, a.ID, result); ferr != nil {
+		return ferr
+	}
+	return err
+}
+
+// Recover fails archives a restart left behind; their jobs died with the old process
+func (ar *YearArchiver) Recover(ctx context.Context) {
+	n, err := ar.Archives.FailUnfinished(ctx, "interrupted by a restart, archive the year again")
+	if err != nil {
+		log.Printf("jobs: could not recover archives: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("jobs: marked %d interrupted archives failed", n)
+	}
+}
+
+func (ar *YearArchiver) build(ctx context.Context, a models.YearArchive) (models.YearArchive, error) {
+	snapshot, err := ar.snapshot(ctx, a)
+	if err != nil {
+		return models.YearArchive{}, err
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	if err := writeBundle(zw, snapshot); err != nil {
+		return models.YearArchive{}, fmt.Errorf("write bundle: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return models.YearArchive{}, fmt.Errorf("write bundle: %w", err)
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+	result := models.YearArchive{
+		Status:     models.JobDone,
+		StorageKey: ArchiveKey(a),
+		Size:       int64(buf.Len()),
+		SHA256:     hex.EncodeToString(sum[:]),
+	}
+	if err := ar.Storage.Put(ctx, result.StorageKey, &buf); err != nil {
+		return models.YearArchive{}, err
+	}
+	return result, nil
+}
+
+// snapshot gathers the year. Enrollment is every student graded during the
+// year, and classes are the classes those students are in.
+func (ar *YearArchiver) snapshot(ctx context.Context, a models.YearArchive) (models.YearSnapshot, error) {
+	grades, err := ar.Scores.ListByYear(ctx, a.Year)
+	if err != nil {
+		return models.YearSnapshot{}, err
+	}
+	students, err := ar.Students.GetAll(ctx, query.Options{})
+	if err != nil {
+		return models.YearSnapshot{}, err
+	}
+	teachers, err := ar.Teachers.GetAll(ctx, query.Options{})
+	if err != nil {
+		return models.YearSnapshot{}, err
+	}
+
+	graded := make(map[int]bool)
+	for _, s := range grades {
+		graded[s.StudentID] = true
+	}
+	enrollment := make([]models.Student, 0)
+	classes := make(map[string]*models.ArchiveClass)
+	for _, s := range students {
+		if !graded[s.ID] {
+			continue
+		}
+		enrollment = append(enrollment, s)
+		if classes[s.Class] == nil {
+			classes[s.Class] = &models.ArchiveClass{Name: s.Class, Teachers: make([]models.ArchiveTeacher, 0)}
+		}
+		classes[s.Class].Students++
+	}
+	for _, t := range teachers {
+		if c := classes[t.Class]; c != nil {
+			c.Teachers = append(c.Teachers, models.ArchiveTeacher{
+				ID: t.ID, FirstName: t.FirstName, LastName: t.LastName, Email: t.Email, Subject: t.Subject,
+			})
+		}
+	}
+
+	snapshot := models.YearSnapshot{
+		Year:        a.Year,
+		ArchiveID:   a.ID,
+		GeneratedAt: ar.Clock.Now(),
+		Classes:     make([]models.ArchiveClass, 0, len(classes)),
+		Enrollment:  enrollment,
+		Grades:      grades,
+	}
+	for _, c := range classes {
+		snapshot.Classes = append(snapshot.Classes, *c)
+	}
+	sort.Slice(snapshot.Classes, func(i, j int) bool { return snapshot.Classes[i].Name < snapshot.Classes[j].Name })
+	return snapshot, nil
+}
+
+func writeBundle(zw *zip.Writer, s models.YearSnapshot) error {
+	f, err := zw.Create("snapshot.json")
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(s); err != nil {
+		return err
+	}
+
+	classes := [][]string{{"class", "students", "teacher_id", "teacher_name", "teacher_email", "subject"}}
+	for _, c := range s.Classes {
+		if len(c.Teachers) == 0 {
+			classes = append(classes, []string{c.Name, strconv.Itoa(c.Students), "", "", "", ""})
+		}
+		for _, t := range c.Teachers {
+			classes = append(classes, []string{c.Name, strconv.Itoa(c.Students), strconv.Itoa(t.ID), t.FirstName + " " + t.LastName, t.Email, t.Subject})
+		}
+	}
+
+	enrollment := [][]string{{"student_id", "first_name", "last_name", "email", "class", "admission_number", "date_of_birth", "gender", "nationality", "enrollment_date"}}
+	for _, st := range s.Enrollment {
+		enrollment = append(enrollment, []string{strconv.Itoa(st.ID), st.FirstName, st.LastName, st.Email, st.Class,
+			st.AdmissionNumber, st.DateOfBirth, st.Gender, st.Nationality, st.EnrollmentDate})
+	}
+
+	grades := [][]string{{"student_id", "term", "subject", "score", "grade", "teacher_id", "scheme_id"}}
+	for _, g := range s.Grades {
+		grades = append(grades, []string{strconv.Itoa(g.StudentID), g.Term, g.Subject,
+			strconv.FormatFloat(g.Score, 'f', -1, 64), g.Grade, strconv.Itoa(g.TeacherID), strconv.Itoa(g.SchemeID)})
+	}
+
+	for _, file := range []struct {
+		name    string
+		records [][]string
+	}{{"classes.csv", classes}, {"enrollment.csv", enrollment}, {"grades.csv", grades}} {
+		if err := writeCSV(zw, file.name, file.records); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeCSV(zw *zip.Writer, name string, records [][]string) error {
+	f, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	w := csv.NewWriter(f)
+	if err := w.WriteAll(records); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
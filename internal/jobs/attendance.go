@@ -0,0 +1,314 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"log"
+	"simpleapi/internal/models"
+	"simpleapi/internal/query"
+	"simpleapi/internal/repository"
+	"simpleapi/internal/schoolprofile"
+	"simpleapi/internal/sms"
+	"simpleapi/pkg/clock"
+	"sort"
+)
+
+// AttendanceNotices are the scheduled attendance texts: reminders to class
+// teachers whose register is late, the weekly absence summary to guardians,
+// and alerts to guardians and class teachers when a student's attendance
+// falls below the school's thresholds
+type AttendanceNotices struct {
+	Attendance repository.AttendanceStore
+	Teachers   repository.TeacherStore
+	Students   repository.StudentStore
+	Events     repository.EventStore
+	Notifier   *sms.Notifier
+	School     *schoolprofile.Profile // Its AttendanceThresholds
+	Clock      clock.Clock
+}
+
+// RemindJob texts the class teacher of every class with students whose register
+// hasn't been taken today. It runs at the cutoff time, so being late is the
+// only reason it finds anything. Classes on holiday (a holiday event covering
+// today, for the school or the class) are left alone.
+func (an *AttendanceNotices) RemindJob() Job {
+	return Job{Name: "attendance reminders", Run: an.remind}
+}
+
+func (an *AttendanceNotices) remind(ctx context.Context) error {
+	today := clock.Today(an.Clock).Format(models.DateLayout)
+
+	submitted, err := an.Attendance.SubmittedClasses(ctx, today)
+	if err != nil {
+		return err
+	}
+	skip := make(map[string]bool)
+	for _, class := range submitted {
+		skip[class] = true
+	}
+
+	holidays, err := an.Events.List(ctx, models.EventFilter{From: today, To: today, Type: models.EventHoliday})
+	if err != nil {
+		return err
+	}
+	for _, e := range holidays {
+		if e.Audience == models.AudienceSchool {
+			return nil
+		}
+		skip[e.Class] = true
+	}
+
+	enrolled, err := an.Students.CountByClass(ctx)
+	if err != nil {
+		return err
+	}
+	teachers, err := an.Teachers.GetAll(ctx, query.Options{})
+	if err != nil {
+		return err
+	}
+
+	sent := 0
+	for _, t := range teachers {
+		if t.Class == "" || skip[t.Class] || enrolled[t.Class] == 0 {
+			continue
+		}
+		if _, err := an.Notifier.SendAttendanceReminder(ctx, t, today); err != nil {
+			if !errors.Is(err, sms.ErrNoPhone) {
+				log.Printf("jobs: attendance reminder to teacher %d: %v", t.ID, err)
+			}
+			continue
+		}
+		sent++
+	}
+	if sent > 0 {
+		log.Printf("jobs: sent %d attendance reminders for %s", sent, today)
+	}
+	return nil
+}
+
+// SummaryJob texts the guardian of every student absent at least once in the
+// seven days up to today
+func (an *AttendanceNotices) SummaryJob() Job {
+	return Job{Name: "weekly absence summaries", Run: an.summarize}
+}
+
+func (an *AttendanceNotices) summarize(ctx context.Context) error {
+	today := clock.Today(an.Clock)
+	from := today.AddDate(0, 0, -6).Format(models.DateLayout)
+
+	absences, err := an.Attendance.ListAbsences(ctx, from, today.Format(models.DateLayout))
+	if err != nil {
+		return err
+	}
+	// ListAbsences is sorted by student, so each student's dates are one run
+	byStudent := make(map[int][]string)
+	var order []int
+	for _, a := range absences {
+		if _, ok := byStudent[a.StudentID]; !ok {
+			order = append(order, a.StudentID)
+		}
+		byStudent[a.StudentID] = append(byStudent[a.StudentID], a.Date)
+	}
+
+	sent := 0
+	for _, id := range order {
+		student, err := an.Students.GetByID(ctx, id)
+		if err != nil {
+			log.Printf("jobs: absence summary for student %d: %v", id, err)
+			continue
+		}
+		if _, err := an.Notifier.SendAbsenceSummary(ctx, *student, byStudent[id]); err != nil {
+			if !errors.Is(err, sms.ErrNoPhone) {
+				log.Printf("jobs: absence summary for student %d: %v", id, err)
+			}
+			continue
+		}
+		sent++
+	}
+	if sent > 0 {
+		log.Printf("jobs: sent %d absence summaries for the week to %s", sent, today.Format(models.DateLayout))
+	}
+	return nil
+}
+
+// ThresholdJob alerts the guardian and class teacher of every student whose
+// attendance fell below one of the school's thresholds (models.AttendanceThresholds)
+// since they were last alerted. Each student is alerted once per level: again
+// only when they fall from notice to escalated, or when they recover and then
+// fall back. A student with too few marks in the window keeps their alert as is.
+func (an *AttendanceNotices) ThresholdJob() Job {
+	return Job{Name: "attendance threshold alerts", Run: an.checkThresholds}
+}
+
+func (an *AttendanceNotices) checkThresholds(ctx context.Context) error {
+	th := an.School.Get().AttendanceThresholds
+	if th.NoticeBelow == 0 {
+		return nil
+	}
+	from, to := an.window(th)
+	tallies, err := an.Attendance.TallyByStudent(ctx, from, to)
+	if err != nil {
+		return err
+	}
+	alerts, err := an.Attendance.Alerts(ctx)
+	if err != nil {
+		return err
+	}
+	students, err := an.Students.GetAll(ctx, query.Options{Where: models.EnrolledStudents()})
+	if err != nil {
+		return err
+	}
+	teachers, err := an.Teachers.GetAll(ctx, query.Options{})
+	if err != nil {
+		return err
+	}
+	classTeachers := make(map[string][]models.Teacher)
+	for _, t := range teachers {
+		if t.Class != "" {
+			classTeachers[t.Class] = append(classTeachers[t.Class], t)
+		}
+	}
+
+	raised := 0
+	for _, student := range students {
+		tally := tallies[student.ID]
+		if tally.Present+tally.Late+tally.Absent < th.MinDays {
+			continue
+		}
+		level, last := th.Level(tally), alerts[student.ID]
+		switch {
+		case models.AlertRank(level) > models.AlertRank(last.Level):
+			if !an.alert(ctx, student, classTeachers[student.Class], level, tally.Rate(), th) {
+				continue // Tried again on the next run
+			}
+			last = models.AttendanceAlert{StudentID: student.ID, Level: level, Rate: tally.Rate(), NotifiedAt: an.Clock.Now()}
+			raised++
+		case level != last.Level:
+			// Better than when alerted: lower the level without a text, so
+			// that falling back is alerted again
+			last.Level, last.Rate = level, tally.Rate()
+		default:
+			continue
+		}
+		if err := an.Attendance.SetAlert(ctx, last); err != nil {
+			log.Printf("jobs: attendance alert for student %d: %v", student.ID, err)
+		}
+	}
+	if raised > 0 {
+		log.Printf("jobs: raised %d attendance alerts for %s to %s", raised, from, to)
+	}
+	return nil
+}
+
+// alert texts the student's guardian and class teachers, reporting whether
+// every text that could go out did. A missing phone isn't a failure: there
+// is nobody to try again for.
+func (an *AttendanceNotices) alert(ctx context.Context, student models.Student, teachers []models.Teacher, level string, rate float64, th models.AttendanceThresholds) bool {
+	threshold := th.NoticeBelow
+	if level == models.AttendanceAlertEscalated {
+		threshold = th.EscalateBelow
+	}
+	ok := true
+	if _, err := an.Notifier.SendAttendanceAlert(ctx, student, level, rate, th.WindowDays, threshold); err != nil && !errors.Is(err, sms.ErrNoPhone) {
+		log.Printf("jobs: attendance alert to the guardian of student %d: %v", student.ID, err)
+		ok = false
+	}
+	for _, t := range teachers {
+		if _, err := an.Notifier.SendAttendanceAtRisk(ctx, t, student, rate, th.WindowDays, threshold); err != nil && !errors.Is(err, sms.ErrNoPhone) {
+			log.Printf("jobs: attendance alert to teacher %d about student %d: %v", t.ID, student.ID, err)
+			ok = false
+		}
+	}
+	return ok
+}
+
+// AtRisk lists the students below one of the school's thresholds right now,
+// lowest rate first, with when they were alerted. It is what GET
+// /reports/attendance/at-risk serves; class "" is every class.
+func (an *AttendanceNotices) AtRisk(ctx context.Context, class string) (models.AtRiskReport, error) {
+	th := an.School.Get().AttendanceThresholds
+	from, to := an.window(th)
+	report := models.AtRiskReport{From: from, To: to, Thresholds: th, Students: make([]models.AtRiskStudent, 0)}
+	if th.NoticeBelow == 0 {
+		return report, nil
+	}
+
+	tallies, err := an.Attendance.TallyByStudent(ctx, from, to)
+	if err != nil {
+		return report, err
+	}
+	alerts, err := an.Attendance.Alerts(ctx)
+	if err != nil {
+		return report, err
+	}
+	students, err := an.Students.GetAll(ctx, query.Options{Where: models.EnrolledStudents()})
+	if err != nil {
+		return report, err
+	}
+	for _, s := range students {
+		if class != "" && s.Class != class {
+			continue
+		}
+		tally := tallies[s.ID]
+		level := th.Level(tally)
+		if level == models.AttendanceAlertNone {
+			continue
+		}
+		row := models.AtRiskStudent{StudentID: s.ID, FirstName: s.FirstName, LastName: s.LastName, Class: s.Class,
+			AttendanceTally: tally, Rate: tally.Rate(), Level: level}
+		if a, ok := alerts[s.ID]; ok && models.AlertRank(a.Level) >= models.AlertRank(level) {
+			row.NotifiedAt = &a.NotifiedAt
+		}
+		report.Students = append(report.Students, row)
+	}
+	sort.SliceStable(report.Students, func(i, j int) bool {
+		a, b := report.Students[i], report.Students[j]
+		if a.Rate != b.Rate {
+			return a.Rate < b.Rate
+		}
+		return a.StudentID < b.StudentID
+	})
+	return report, nil
+}
+
+// window is the thresholds' period, the WindowDays up to today (DateLayout)
+func (an *AttendanceNotices) window(th models.AttendanceThresholds) (from, to string) {
+	today := clock.Today(an.Clock)
+	return today.AddDate(0, 0, 1-max(th.WindowDays, 1)).Format(models.DateLayout), today.Format(models.DateLayout)
+}
+
+// ExpireResetTokensJob clears password-reset tokens past their expiry
+func ExpireResetTokensJob(teachers repository.TeacherStore, clk clock.Clock) Job {
+	return Job{
+		Name: "reset token expiry",
+		Run: func(ctx context.Context) error {
+			n, err := teachers.ExpireResetTokens(ctx, clk.Now())
+			if err != nil {
+				return err
+			}
+			if n > 0 {
+				log.Printf("jobs: expired %d password-reset tokens", n)
+			}
+			return nil
+		},
+	}
+}
+
+// SummarizeAttendanceJob materializes the daily attendance tallies of the days
+// before today, which the attendance reports read instead of every mark. Run
+// after midnight, it adds the previous day; a missed run is caught up by the next.
+func SummarizeAttendanceJob(reports repository.ReportStore, clk clock.Clock) Job {
+	return Job{
+		Name: "attendance summary",
+		Run: func(ctx context.Context) error {
+			n, err := reports.SummarizeAttendance(ctx, clock.Today(clk).Format(models.DateLayout))
+			if err != nil {
+				return err
+			}
+			if n > 0 {
+				log.Printf("jobs: summarized attendance of %d class days", n)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,108 @@
+package jobs
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"simpleapi/internal/backup"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"simpleapi/internal/storage"
+	"simpleapi/pkg/clock"
+)
+
+// Backuper dumps the database to storage (see package backup for the format)
+type Backuper struct {
+	Backups repository.BackupStore
+	DB      *sql.DB
+	Tables  []string
+	Storage storage.Storage
+	Clock   clock.Clock
+}
+
+// BackupKey is where a backup's dump lives in storage
+func BackupKey(b models.Backup) string {
+	return fmt.Sprintf("backups/%s-%d.jsonl.gz", b.CreatedAt.UTC().Format("20060102T150405Z"), b.ID)
+}
+
+// Job wraps Run for the queue
+func (bk *Backuper) Job(b models.Backup) Job {
+	return Job{
+		Name:    fmt.Sprintf("backup #%d", b.ID),
+		Run:     func(ctx context.Context) error { return bk.Run(ctx, b) },
+		NoRetry: true,
+	}
+}
+
+// Run dumps the database and records the outcome on the backup
+func (bk *Backuper) Run(ctx context.Context, b models.Backup) error {
+	if err := bk.Backups.MarkRunning(ctx, b.ID); err != nil {
+		return err
+	}
+
+	result, err := bk.dump(ctx, b)
+	if err != nil {
+		result = models.Backup{Status: models.JobFailed, Error: err.Error()}
+	}
+	if ferr := bk.Backups.Finish(ctx, b.ID, result); ferr != nil {
+		return ferr
+	}
+	return err
+}
+
+// Recover fails backups a restart left behind; their jobs died with the old process
+func (bk *Backuper) Recover(ctx context.Context) {
+	n, err := bk.Backups.FailUnfinished(ctx, "interrupted by a restart, start a new backup")
+	if err != nil {
+		log.Printf("jobs: could not recover backups: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("jobs: marked %d interrupted backups failed", n)
+	}
+}
+
+// dump streams the dump straight into storage through a pipe, hashing and
+// counting on the way, so the database never has to fit in memory
+func (bk *Backuper) dump(ctx context.Context, b models.Backup) (models.Backup, error) {
+	pr, pw := io.Pipe()
+	hash := sha256.New()
+	counter := &countingWriter{w: io.MultiWriter(pw, hash)}
+
+	var stats backup.Stats
+	go func() {
+		var err error
+		stats, err = backup.Dump(ctx, bk.DB, bk.Tables, bk.Clock.Now(), counter)
+		pw.CloseWithError(err)
+	}()
+
+	key := BackupKey(b)
+	if err := bk.Storage.Put(ctx, key, pr); err != nil {
+		pr.CloseWithError(err) // Unblock the dump if storage gave up early
+		return models.Backup{}, err
+	}
+
+	return models.Backup{
+		Status:     models.JobDone,
+		StorageKey: key,
+		Size:       counter.n,
+		SHA256:     hex.EncodeToString(hash.Sum(nil)),
+		Tables:     stats.Tables,
+		Rows:       stats.Rows,
+	}, nil
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,205 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"simpleapi/internal/expr"
+	"simpleapi/internal/mail"
+	"simpleapi/internal/models"
+	"simpleapi/internal/query"
+	"simpleapi/internal/repository"
+	"simpleapi/internal/schoolprofile"
+	"strings"
+	"text/template"
+)
+
+// CampaignSender sends bulk email campaigns: it resolves a filter to
+// recipients when the campaign is created, then mails them one by one from
+// the queue, recording each delivery so the campaign can be reviewed later
+type CampaignSender struct {
+	Campaigns repository.CampaignStore
+	Teachers  repository.TeacherStore
+	Students  repository.StudentStore
+	Mailer    mail.Sender
+	School    *schoolprofile.Profile
+}
+
+// ParseCampaignTemplate parses a campaign's subject or body. Fields outside
+// models.CampaignMergeFields fail when executed, which CheckTemplates catches.
+func ParseCampaignTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Option("missingkey=error").Parse(text)
+}
+
+// Resolve lists the addresses filter chooses, in name order. An address is
+// listed once, for its first match. Guardians have no email on file, only
+// GuardianPhone, so a guardian is reached at their student's address.
+func (s *CampaignSender) Resolve(ctx context.Context, filter models.RecipientFilter) ([]models.CampaignRecipient, error) {
+	var recipients []models.CampaignRecipient
+	switch filter.Audience {
+	case models.AudienceStaff:
+		where := []expr.Expr{expr.Equal(models.TeacherFields, "is_active", true)}
+		if filter.Role != "" {
+			where = append(where, expr.Equal(models.TeacherFields, "role", filter.Role))
+		}
+		if filter.Class != "" {
+			where = append(where, expr.Equal(models.TeacherFields, "class", filter.Class))
+		}
+		teachers, err := s.Teachers.GetAll(ctx, query.Options{
+			Where: expr.All(where...),
+			Sort:  []query.Sort{{Field: "last_name"}, {Field: "first_name"}},
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range teachers {
+			recipients = append(recipients, models.CampaignRecipient{
+				Email: t.Email, FirstName: t.FirstName, LastName: t.LastName, Class: t.Class,
+			})
+		}
+
+	case models.AudienceStudents, models.AudienceGuardians:
+		where := models.EnrolledStudents()
+		if filter.Class != "" {
+			where = models.ClassRoster(filter.Class)
+		}
+		students, err := s.Students.GetAll(ctx, query.Options{
+			Where: where,
+			Sort:  []query.Sort{{Field: "last_name"}, {Field: "first_name"}},
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, st := range students {
+			rc := models.CampaignRecipient{Email: st.Email, Student: st.FirstName + " " + st.LastName, Class: st.Class}
+			if filter.Audience == models.AudienceStudents {
+				rc.FirstName, rc.LastName = st.FirstName, st.LastName
+			}
+			recipients = append(recipients, rc)
+		}
+
+	default:
+		return nil, fmt.Errorf("jobs: unknown campaign audience %q: %w", filter.Audience, models.ErrInvalidInput)
+	}
+
+	seen := make(map[string]bool, len(recipients))
+	unique := recipients[:0]
+	for _, rc := range recipients {
+		key := strings.ToLower(rc.Email)
+		if rc.Email == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, rc)
+	}
+	return unique, nil
+}
+
+// CheckTemplates renders the subject and body for every recipient, so a
+// campaign that would fail half-way (an unknown merge field, say) is refused
+// before anything is sent. The errors name the field, as the validator does.
+func (s *CampaignSender) CheckTemplates(subject, body string, recipients []models.CampaignRecipient) []models.ValidationError {
+	var errs []models.ValidationError
+	for _, f := range []struct{ field, text string }{{"Subject", subject}, {"Body", body}} {
+		tmpl, err := ParseCampaignTemplate(strings.ToLower(f.field), f.text)
+		if err == nil {
+			for _, rc := range recipients {
+				if err = tmpl.Execute(new(strings.Builder), rc.MergeFields(s.School.Name())); err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			errs = append(errs, models.RuleError(f.field, "template", err.Error()))
+		}
+	}
+	return errs
+}
+
+// Job wraps Run for the queue
+func (s *CampaignSender) Job(c models.Campaign) Job {
+	return Job{
+		Name:    fmt.Sprintf("campaign %d (%d recipients)", c.ID, c.Total),
+		Run:     func(ctx context.Context) error { return s.Run(ctx, c) },
+		NoRetry: true,
+	}
+}
+
+// Run mails every recipient still pending. A failed send is recorded on its
+// recipient and the rest carry on; the campaign fails only when it can't be
+// worked through at all.
+func (s *CampaignSender) Run(ctx context.Context, c models.Campaign) error {
+	if err := s.Campaigns.MarkRunning(ctx, c.ID); err != nil {
+		return err
+	}
+
+	err := s.send(ctx, c)
+	status, errText := models.JobDone, ""
+	if err != nil {
+		status, errText = models.JobFailed, err.Error()
+	}
+	if ferr := s.Campaigns.Finish(ctx, c.ID, status, errText); ferr != nil {
+		return ferr
+	}
+	return err
+}
+
+func (s *CampaignSender) send(ctx context.Context, c models.Campaign) error {
+	subject, err := ParseCampaignTemplate("subject", c.Subject)
+	if err != nil {
+		return err
+	}
+	body, err := ParseCampaignTemplate("body", c.Body)
+	if err != nil {
+		return err
+	}
+	recipients, err := s.Campaigns.Recipients(ctx, c.ID)
+	if err != nil {
+		return err
+	}
+
+	school := s.School.Name()
+	sent, failed := 0, 0
+	for _, rc := range recipients {
+		if rc.Status != models.RecipientPending {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		fields := rc.MergeFields(school)
+		var subj, text strings.Builder
+		err := subject.Execute(&subj, fields)
+		if err == nil {
+			err = body.Execute(&text, fields)
+		}
+		if err == nil {
+			err = s.Mailer.Send(ctx, mail.Message{To: rc.Email, Subject: subj.String(), Body: text.String()})
+		}
+		sendErr := ""
+		if err != nil {
+			sendErr = err.Error()
+			failed++
+		} else {
+			sent++
+		}
+		if err := s.Campaigns.RecordDelivery(ctx, rc.ID, sendErr); err != nil {
+			return err
+		}
+	}
+	log.Printf("jobs: campaign %d sent to %d recipients, %d failed", c.ID, sent, failed)
+	return nil
+}
+
+// Recover fails campaigns a restart left behind; their jobs died with the old
+// process. Their unsent recipients stay pending, as the review shows.
+func (s *CampaignSender) Recover(ctx context.Context) {
+	n, err := s.Campaigns.FailUnfinished(ctx, "interrupted by a restart, some recipients were not sent to")
+	if err != nil {
+		log.Printf("jobs: could not recover campaigns: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("jobs: marked %d interrupted campaigns failed", n)
+	}
+}
@@ -0,0 +1,60 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"simpleapi/internal/metrics"
+	"simpleapi/pkg/clock"
+	"time"
+)
+
+// PoolMonitor samples the database connection pool into metrics.DBPool. When
+// queries waited longer than Threshold in all for a connection since the
+// previous sample the pool is saturated: it logs a warning and GET /readyz
+// answers 503 until a calmer interval. Exhaustion otherwise only shows as
+// requests timing out here and there. Every instance runs its own.
+type PoolMonitor struct {
+	DB        *sql.DB
+	Gauges    *metrics.PoolGauges
+	Threshold time.Duration // 0: never saturated
+	Clock     clock.Clock
+
+	last sql.DBStats // Previous sample, for the wait per interval
+}
+
+// Run takes a sample
+func (p *PoolMonitor) Run(context.Context) error {
+	stats := p.DB.Stats()
+	waited := stats.WaitDuration - p.last.WaitDuration
+	waits := stats.WaitCount - p.last.WaitCount
+	p.last = stats
+
+	saturated := p.Threshold > 0 && waited > p.Threshold
+	if saturated {
+		log.Printf("jobs: database pool saturated: %d queries waited %s for a connection (threshold %s), %d of %d connections in use",
+			waits, waited.Round(time.Millisecond), p.Threshold, stats.InUse, stats.MaxOpenConnections)
+	} else if p.Gauges.Saturated() {
+		log.Printf("jobs: database pool recovered: %s waited for a connection", waited.Round(time.Millisecond))
+	}
+	p.Gauges.Set(stats, waited, saturated, p.Clock.Now())
+	return nil
+}
+
+// Start runs Run once now and then every interval, until ctx is cancelled.
+// The first sample counts the waits since the pool opened, i.e. none.
+func (p *PoolMonitor) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		p.Run(ctx)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				p.Run(ctx)
+			}
+		}
+	}()
+}
@@ -0,0 +1,67 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"simpleapi/internal/expr"
+	"simpleapi/internal/mail"
+	"simpleapi/internal/models"
+	"simpleapi/internal/query"
+	"simpleapi/internal/repository"
+	"simpleapi/internal/schoolprofile"
+	"strings"
+)
+
+// DeadLetterNotices emails the school's admins when a job of the queue runs
+// out of attempts, so a failed import or notice doesn't go unnoticed
+type DeadLetterNotices struct {
+	Mailer   mail.Sender
+	Teachers repository.TeacherStore
+	School   *schoolprofile.Profile
+}
+
+// Notify is the queue's DeadLetter hook. It runs on the worker that gave up,
+// not as a job of its own: a notice failing with the mail server can't then
+// be dead-lettered in turn.
+func (n *DeadLetterNotices) Notify(ctx context.Context, rec models.JobRecord) {
+	admins, err := n.Teachers.GetAll(ctx, query.Options{Where: expr.All(
+		expr.Equal(models.TeacherFields, "role", models.RoleAdmin),
+		expr.Equal(models.TeacherFields, "is_active", true),
+	)})
+	if err != nil {
+		log.Printf("jobs: dead-letter notice for job %d: %v", rec.ID, err)
+		return
+	}
+	school := n.School.Name()
+	if school == "" {
+		school = "The school"
+	}
+
+	var errs strings.Builder
+	for _, a := range rec.Errors {
+		fmt.Fprintf(&errs, "  attempt %d, %s: %s\n", a.Attempt, a.At.Format("2006-01-02 15:04 MST"), a.Error)
+	}
+	retry := fmt.Sprintf("Once the cause is fixed, run it again with POST /admin/jobs/%d/retry.", rec.ID)
+	if !rec.Retryable {
+		retry = "It records its own failure and can't be retried: request it again once the cause is fixed."
+	}
+	msg := mail.Message{
+		Subject: fmt.Sprintf("%s: background job %q failed", school, rec.Name),
+		Body: fmt.Sprintf("The background job %q (#%d) was given up on after %d attempts:\n\n%s\n%s\n"+
+			"GET /admin/jobs?status=dead lists every job given up on.\n",
+			rec.Name, rec.ID, rec.Attempts, errs.String(), retry),
+	}
+	sent := 0
+	for _, a := range admins {
+		msg.To = a.Email
+		if err := n.Mailer.Send(ctx, msg); err != nil {
+			log.Printf("jobs: dead-letter notice for job %d to teacher %d: %v", rec.ID, a.ID, err)
+			continue
+		}
+		sent++
+	}
+	if sent > 0 {
+		log.Printf("jobs: emailed %d admins about dead job %d", sent, rec.ID)
+	}
+}
@@ -0,0 +1,90 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"simpleapi/internal/locale"
+	"simpleapi/internal/mail"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"simpleapi/internal/schoolprofile"
+	"simpleapi/pkg/clock"
+	"time"
+)
+
+// EmailChangeNotices sends the two emails of a requested email change: the
+// confirm link to the new address, and a notice with an undo link to the old one,
+// so a hijacked session can't quietly move an account to another mailbox
+type EmailChangeNotices struct {
+	Mailer mail.Sender
+	AppURL string // APP_URL: the frontend, which serves /email-change/confirm and /email-change/undo
+	School *schoolprofile.Profile
+	// Deadlines are written in the teacher's time zone preference, else the school's
+	Teachers repository.TeacherStore
+	Clock    clock.Clock
+}
+
+// Job wraps both sends for the queue. The tokens are only ever in these emails:
+// the store keeps their hashes.
+func (n *EmailChangeNotices) Job(c models.EmailChange, confirmToken, undoToken string) Job {
+	return Job{
+		Name: fmt.Sprintf("email change %d notices", c.ID),
+		Run:  func(ctx context.Context) error { return n.send(ctx, c, confirmToken, undoToken) },
+	}
+}
+
+func (n *EmailChangeNotices) send(ctx context.Context, c models.EmailChange, confirmToken, undoToken string) error {
+	profile := n.School.Get()
+	school := profile.Name
+	if school == "" {
+		school = "school"
+	}
+	// Whom to ask when the email is unexpected, if the profile says
+	contact := ""
+	if profile.ContactEmail != "" {
+		contact = fmt.Sprintf("\nQuestions? Write to %s.\n", profile.ContactEmail)
+	}
+	loc := n.location(ctx, c.TeacherID)
+	confirm := mail.Message{
+		To:      c.NewEmail,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("Someone asked to change the email of your %s account from %s to this address.\n\n"+
+			"To confirm, open this link before %s:\n%s\n\n"+
+			"If this wasn't you, ignore this email and nothing will change.\n%s",
+			school, c.OldEmail, c.ExpiresAt.In(loc).Format("2006-01-02 15:04 MST"), n.link("confirm", confirmToken), contact),
+	}
+	undo := mail.Message{
+		To:      c.OldEmail,
+		Subject: "Your email address is being changed",
+		Body: fmt.Sprintf("Someone asked to change the email of your %s account to %s. "+
+			"The change applies once the new address confirms it.\n\n"+
+			"If this wasn't you, open this link before %s to stop or reverse the change, then change your password:\n%s\n%s",
+			school, c.NewEmail, c.UndoExpiresAt.In(loc).Format("2006-01-02 15:04 MST"), n.link("undo", undoToken), contact),
+	}
+
+	// The old address hears about it even if the new one can't be reached
+	undoErr := n.Mailer.Send(ctx, undo)
+	if err := n.Mailer.Send(ctx, confirm); err != nil {
+		return fmt.Errorf("jobs: email change %d confirmation: %w", c.ID, err)
+	}
+	if undoErr != nil {
+		return fmt.Errorf("jobs: email change %d notice to the old address: %w", c.ID, undoErr)
+	}
+	return nil
+}
+
+// location is the teacher's preferred time zone, the school's when they have
+// none or can't be read: the emails must go out either way
+func (n *EmailChangeNotices) location(ctx context.Context, teacherID int) *time.Location {
+	if t, err := n.Teachers.GetByID(ctx, teacherID); err == nil && t.Timezone != "" {
+		if loc, err := locale.LoadLocation(t.Timezone); err == nil {
+			return loc
+		}
+	}
+	return n.Clock.Location()
+}
+
+func (n *EmailChangeNotices) link(action, token string) string {
+	return fmt.Sprintf("%s/email-change/%s?token=%s", n.AppURL, action, url.QueryEscape(token))
+}
@@ -0,0 +1,53 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"simpleapi/internal/metrics"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"simpleapi/pkg/clock"
+	"time"
+)
+
+// KPIRefresh recounts the school figures exported on /metrics, so scrapes
+// never hit the database themselves. Every instance runs its own.
+type KPIRefresh struct {
+	Reports repository.ReportStore
+	Gauges  *metrics.KPIGauges
+	Clock   clock.Clock
+}
+
+// Run counts the figures of the current school day
+func (k *KPIRefresh) Run(ctx context.Context) error {
+	now := k.Clock.Now()
+	kpis, err := k.Reports.KPIs(ctx, clock.SchoolDate(k.Clock, now).Format(models.DateLayout))
+	if err != nil {
+		return fmt.Errorf("jobs: counting school KPIs: %w", err)
+	}
+	k.Gauges.Set(kpis, now)
+	return nil
+}
+
+// Start runs Run once now and then every interval, until ctx is cancelled
+func (k *KPIRefresh) Start(ctx context.Context, interval time.Duration) {
+	refresh := func() {
+		if err := k.Run(ctx); err != nil {
+			log.Printf("jobs: KPI refresh failed: %v", err)
+		}
+	}
+	go func() {
+		refresh()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				refresh()
+			}
+		}
+	}()
+}
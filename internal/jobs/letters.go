@@ -0,0 +1,33 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"simpleapi/internal/mail"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"simpleapi/pkg/clock"
+)
+
+// LetterMails emails letters printed from templates. The mailer sends plain
+// text only, so the message is the merged letter itself; the PDF or HTML
+// stays with the student's documents.
+type LetterMails struct {
+	Mailer    mail.Sender
+	Documents repository.DocumentStore
+	Clock     clock.Clock
+}
+
+// Job wraps the sending for the queue, so printing a letter doesn't wait on
+// the mail provider. The document records where and when it went.
+func (l *LetterMails) Job(doc models.Document, msg mail.Message) Job {
+	return Job{
+		Name: fmt.Sprintf("document %d email", doc.ID),
+		Run: func(ctx context.Context) error {
+			if err := l.Mailer.Send(ctx, msg); err != nil {
+				return fmt.Errorf("jobs: emailing document %d: %w", doc.ID, err)
+			}
+			return l.Documents.MarkEmailed(ctx, doc.ID, msg.To, l.Clock.Now())
+		},
+	}
+}
@@ -0,0 +1,46 @@
+package jobs
+
+import (
+	"context"
+	"simpleapi/internal/redis"
+	"strconv"
+	"time"
+)
+
+// runLockTTL is how long a scheduled run's lock outlives its minute: long
+// enough to cover clock drift between instances
+const runLockTTL = 10 * time.Minute
+
+// Locks decide which instance does a piece of work that must happen once
+type Locks interface {
+	// TryLock takes key for ttl, and reports false if someone already holds it
+	TryLock(ctx context.Context, key string, ttl time.Duration) (bool, error)
+}
+
+// LocalLocks is for a single instance, which has nobody to share the work
+// with: every lock is granted
+type LocalLocks struct{}
+
+func (LocalLocks) TryLock(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	return true, nil
+}
+
+// RedisLocks are locks in Redis, shared by every instance (SHARED_STATE=redis).
+// They're never released, only expire, so a run that finishes early still
+// can't be repeated by an instance whose clock is late.
+type RedisLocks struct {
+	client *redis.Client
+}
+
+// NewRedisLocks is the constructor
+func NewRedisLocks(client *redis.Client) *RedisLocks {
+	return &RedisLocks{client: client}
+}
+
+func (l *RedisLocks) TryLock(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	reply, err := l.client.Do(ctx, "SET", "lock:"+key, "1", "NX", "PX", strconv.FormatInt(ttl.Milliseconds(), 10))
+	if err != nil {
+		return false, err
+	}
+	return reply == "OK", nil // nil when the key exists
+}
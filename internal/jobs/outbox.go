@@ -0,0 +1,123 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"simpleapi/pkg/clock"
+	"time"
+)
+
+const (
+	// outboxBatch is how many events one round of the relay publishes at most
+	outboxBatch = 50
+	// outboxKeep is how long delivered events stay in the table, for inspection
+	outboxKeep = 7 * 24 * time.Hour
+	// outboxMaxBackoff caps the wait between attempts at an event
+	outboxMaxBackoff = time.Hour
+)
+
+// Publisher delivers an outbox event to its subscribers (see packages webhook
+// and eventbus)
+type Publisher interface {
+	Publish(ctx context.Context, e models.OutboxEvent) error
+}
+
+// Publishers delivers each event to every one of its publishers. A failure
+// fails the event, so a retry sends it again to those that had taken it:
+// receivers already dedupe on the event ID.
+type Publishers []Publisher
+
+func (ps Publishers) Publish(ctx context.Context, e models.OutboxEvent) error {
+	for _, p := range ps {
+		if err := p.Publish(ctx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OutboxRelay publishes the events of the outbox table and marks them
+// delivered. Events are written in the transaction of their change, so a
+// crash between commit and publication only delays them: the next round
+// picks them up. Delivery is at least once; receivers dedupe on the event ID.
+type OutboxRelay struct {
+	Outbox repository.OutboxStore
+	// Publisher is nil when neither a webhook nor a message bus is configured:
+	// events are then marked delivered without being sent, so they don't pile up
+	Publisher Publisher
+	Locks     Locks
+	Clock     clock.Clock
+
+	lastPurge time.Time
+}
+
+// Run publishes the due events in order, stopping at the first failure
+// (most likely the endpoint is down): that event waits out its backoff and
+// the rest go in the next round. Delivered events older than a week are purged.
+func (o *OutboxRelay) Run(ctx context.Context) error {
+	now := o.Clock.Now()
+	events, err := o.Outbox.Pending(ctx, now, outboxBatch)
+	if err != nil {
+		return fmt.Errorf("jobs: reading the outbox: %w", err)
+	}
+	for _, e := range events {
+		if o.Publisher != nil {
+			if err := o.Publisher.Publish(ctx, e); err != nil {
+				retryAt := o.Clock.Now().Add(outboxBackoff(e.Attempts))
+				if markErr := o.Outbox.MarkFailed(ctx, e.ID, err.Error(), retryAt); markErr != nil {
+					return fmt.Errorf("jobs: recording failed event %d: %w", e.ID, markErr)
+				}
+				return fmt.Errorf("jobs: publishing event %d (attempt %d): %w", e.ID, e.Attempts+1, err)
+			}
+		}
+		if err := o.Outbox.MarkDelivered(ctx, e.ID, o.Clock.Now()); err != nil {
+			return fmt.Errorf("jobs: marking event %d delivered: %w", e.ID, err)
+		}
+	}
+
+	if now.Sub(o.lastPurge) >= time.Hour {
+		if _, err := o.Outbox.PurgeDelivered(ctx, now.Add(-outboxKeep)); err != nil {
+			return fmt.Errorf("jobs: purging the outbox: %w", err)
+		}
+		o.lastPurge = now
+	}
+	return nil
+}
+
+// outboxBackoff is the wait after a failed attempt: 10s doubling up to an hour
+func outboxBackoff(attempts int) time.Duration {
+	if attempts >= 9 {
+		return outboxMaxBackoff
+	}
+	return min(10*time.Second<<attempts, outboxMaxBackoff)
+}
+
+// Start runs a round every interval until ctx is cancelled. With several
+// instances a shared lock gives each interval's round to one of them.
+func (o *OutboxRelay) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			ok, err := o.Locks.TryLock(ctx, "outbox:relay", interval)
+			if err != nil {
+				log.Printf("jobs: outbox relay lock: %v", err)
+				continue
+			}
+			if !ok {
+				continue
+			}
+			if err := o.Run(ctx); err != nil {
+				log.Printf("jobs: outbox relay: %v", err)
+			}
+		}
+	}()
+}
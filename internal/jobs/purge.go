@@ -0,0 +1,41 @@
+// Package jobs holds the background work the API runs on a timer, on the
+// scheduler or on the job queue
+package jobs
+
+import (
+	"context"
+	"log"
+	"simpleapi/internal/repository"
+	"simpleapi/pkg/clock"
+	"time"
+)
+
+// StartTrashPurge hard-deletes trashed teachers older than retention, once at startup
+// and then every interval, until ctx is cancelled
+func StartTrashPurge(ctx context.Context, teachers repository.TeacherStore, clk clock.Clock, retention, interval time.Duration) {
+	purge := func() {
+		cutoff := clk.Now().Add(-retention)
+		n, err := teachers.PurgeDeleted(ctx, cutoff)
+		if err != nil {
+			log.Printf("jobs: trash purge failed: %v", err)
+			return
+		}
+		if n > 0 {
+			log.Printf("jobs: purged %d teachers deleted before %s", n, cutoff.Format(time.RFC3339))
+		}
+	}
+
+	go func() {
+		purge()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				purge()
+			}
+		}
+	}()
+}
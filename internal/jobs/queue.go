@@ -0,0 +1,273 @@
+package jobs
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"simpleapi/internal/models"
+	"simpleapi/pkg/clock"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ErrQueueFull is returned by Enqueue when every slot of the backlog is taken
+var ErrQueueFull = errors.New("jobs: queue is full")
+
+// Job is one unit of background work
+type Job struct {
+	Name string
+	Run  func(ctx context.Context) error
+	// NoRetry is for jobs that record their own outcome (archives, backups,
+	// campaigns): running them again does nothing, they are requested again instead
+	NoRetry bool
+}
+
+// QueueConfig sizes a queue and says what happens to failing jobs
+type QueueConfig struct {
+	Workers int
+	Size    int // backlog of queued jobs
+
+	// MaxAttempts counts the first run: 0 or 1 never retries. A job still
+	// failing after them is dead-lettered and handed to DeadLetter.
+	MaxAttempts int
+	Backoff     time.Duration // before the second attempt, doubling for each next one
+	DeadLetter  func(ctx context.Context, rec models.JobRecord)
+
+	History int // finished jobs remembered for GET /admin/jobs
+	Clock   clock.Clock
+}
+
+// Queue defaults
+const (
+	defaultJobBackoff = 30 * time.Second
+	defaultJobHistory = 200
+)
+
+// Queue runs jobs on a fixed pool of workers. It lives in the process: jobs
+// still queued when it stops are lost, so their owners must cope with that
+// at startup (see YearArchiver.Recover). So are the records of past jobs.
+type Queue struct {
+	ctx  context.Context
+	cfg  QueueConfig
+	jobs chan *queued
+
+	mu       sync.Mutex
+	lastID   int
+	byID     map[int]*queued
+	finished []int // done and dead jobs, oldest first
+}
+
+type queued struct {
+	job Job
+	rec models.JobRecord
+	gen int // bumped on each (re)queueing, so a stale retry timer does nothing
+}
+
+// StartQueue starts workers that pull from the backlog, until ctx is cancelled
+func StartQueue(ctx context.Context, cfg QueueConfig) *Queue {
+	cfg.MaxAttempts = max(cfg.MaxAttempts, 1)
+	if cfg.Backoff <= 0 {
+		cfg.Backoff = defaultJobBackoff
+	}
+	if cfg.History <= 0 {
+		cfg.History = defaultJobHistory
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = clock.New(time.UTC)
+	}
+	q := &Queue{ctx: ctx, cfg: cfg, jobs: make(chan *queued, cfg.Size), byID: make(map[int]*queued)}
+	for range cfg.Workers {
+		go func() {
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case j := <-q.jobs:
+					q.run(j)
+				}
+			}
+		}()
+	}
+	return q
+}
+
+// Enqueue schedules a job without blocking the caller
+func (q *Queue) Enqueue(job Job) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := q.now()
+	q.lastID++
+	j := &queued{job: job, rec: models.JobRecord{
+		ID:          q.lastID,
+		Name:        job.Name,
+		Status:      models.JobPending,
+		MaxAttempts: q.cfg.MaxAttempts,
+		Retryable:   !job.NoRetry,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}}
+	if job.NoRetry {
+		j.rec.MaxAttempts = 1
+	}
+	if !q.push(j) {
+		return ErrQueueFull
+	}
+	q.byID[j.rec.ID] = j
+	return nil
+}
+
+// List returns the jobs the queue remembers, newest first, optionally only
+// those in one status
+func (q *Queue) List(status string) []models.JobRecord {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	recs := make([]models.JobRecord, 0, len(q.byID))
+	for _, j := range q.byID {
+		if status == "" || j.rec.Status == status {
+			recs = append(recs, j.snapshot())
+		}
+	}
+	slices.SortFunc(recs, func(a, b models.JobRecord) int { return cmp.Compare(b.ID, a.ID) })
+	return recs
+}
+
+// Stats sizes up the queue for GET /admin/diagnostics
+func (q *Queue) Stats() models.JobQueueStats {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	stats := models.JobQueueStats{Depth: len(q.jobs), Capacity: cap(q.jobs), Workers: q.cfg.Workers, ByStatus: make(map[string]int)}
+	for _, j := range q.byID {
+		stats.ByStatus[j.rec.Status]++
+	}
+	return stats
+}
+
+// Retry queues a failed or dead job again right away. A dead job gets one
+// more attempt; if that fails too it is dead-lettered again.
+func (q *Queue) Retry(id int) (models.JobRecord, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	j, ok := q.byID[id]
+	if !ok {
+		return models.JobRecord{}, models.ErrNotFound
+	}
+	if !j.rec.Retryable {
+		return j.snapshot(), fmt.Errorf("%w: %s can't be retried, request it again", models.ErrConflict, j.rec.Name)
+	}
+	if j.rec.Status != models.JobFailed && j.rec.Status != models.JobDead {
+		return j.snapshot(), fmt.Errorf("%w: job is %s", models.ErrConflict, j.rec.Status)
+	}
+
+	prev := j.rec
+	if j.rec.Status == models.JobDead {
+		j.rec.MaxAttempts = j.rec.Attempts + 1
+	}
+	j.rec.Status, j.rec.RetryAt, j.rec.UpdatedAt = models.JobPending, nil, q.now()
+	if !q.push(j) {
+		j.rec = prev
+		return j.snapshot(), ErrQueueFull
+	}
+	q.finished = slices.DeleteFunc(q.finished, func(fid int) bool { return fid == id })
+	return j.snapshot(), nil
+}
+
+// push hands j to the workers, holding q.mu
+func (q *Queue) push(j *queued) bool {
+	select {
+	case q.jobs <- j:
+		j.gen++
+		return true
+	default:
+		return false
+	}
+}
+
+func (q *Queue) run(j *queued) {
+	q.mu.Lock()
+	j.rec.Status, j.rec.UpdatedAt = models.JobRunning, q.now()
+	j.rec.Attempts++
+	q.mu.Unlock()
+
+	err := j.job.Run(q.ctx)
+
+	q.mu.Lock()
+	now := q.now()
+	j.rec.UpdatedAt = now
+	if err == nil {
+		j.rec.Status = models.JobDone
+		q.finish(j)
+		q.mu.Unlock()
+		return
+	}
+	j.rec.Errors = append(j.rec.Errors, models.JobAttempt{Attempt: j.rec.Attempts, Error: err.Error(), At: now})
+	if j.rec.Attempts < j.rec.MaxAttempts {
+		delay := q.cfg.Backoff << (j.rec.Attempts - 1)
+		at := now.Add(delay)
+		j.rec.Status, j.rec.RetryAt = models.JobFailed, &at
+		q.retryAfter(j, delay)
+		q.mu.Unlock()
+		log.Printf("jobs: %s failed (attempt %d of %d), retrying in %s: %v", j.job.Name, j.rec.Attempts, j.rec.MaxAttempts, delay, err)
+		return
+	}
+	j.rec.Status = models.JobDead
+	q.finish(j)
+	rec := j.snapshot()
+	q.mu.Unlock()
+
+	log.Printf("jobs: %s failed (attempt %d of %d), dead-lettered: %v", j.job.Name, rec.Attempts, rec.MaxAttempts, err)
+	if q.cfg.DeadLetter != nil {
+		q.cfg.DeadLetter(q.ctx, rec)
+	}
+}
+
+// retryAfter queues j again once delay is over, holding q.mu. With the backlog
+// full, it waits as long again.
+func (q *Queue) retryAfter(j *queued, delay time.Duration) {
+	gen := j.gen
+	time.AfterFunc(delay, func() {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		if j.gen != gen || j.rec.Status != models.JobFailed || q.ctx.Err() != nil {
+			return // retried by an admin meanwhile, or the queue stopped
+		}
+		j.rec.Status, j.rec.RetryAt, j.rec.UpdatedAt = models.JobPending, nil, q.now()
+		if !q.push(j) {
+			at := q.now().Add(delay)
+			j.rec.Status, j.rec.RetryAt = models.JobFailed, &at
+			q.retryAfter(j, delay)
+		}
+	})
+}
+
+// finish remembers j among the finished jobs, holding q.mu. Past History,
+// the oldest done job is forgotten, or the oldest dead one if none is done.
+func (q *Queue) finish(j *queued) {
+	q.finished = append(q.finished, j.rec.ID)
+	if len(q.finished) <= q.cfg.History {
+		return
+	}
+	i := slices.IndexFunc(q.finished, func(id int) bool { return q.byID[id].rec.Status == models.JobDone })
+	if i < 0 {
+		i = 0
+	}
+	delete(q.byID, q.finished[i])
+	q.finished = slices.Delete(q.finished, i, i+1)
+}
+
+func (q *Queue) now() time.Time {
+	return q.cfg.Clock.Now().UTC()
+}
+
+// snapshot copies the record, so callers can read it without the lock
+func (j *queued) snapshot() models.JobRecord {
+	rec := j.rec
+	rec.Errors = slices.Clone(j.rec.Errors)
+	return rec
+}
@@ -0,0 +1,73 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"simpleapi/internal/expr"
+	"simpleapi/internal/mail"
+	"simpleapi/internal/models"
+	"simpleapi/internal/query"
+	"simpleapi/internal/repository"
+	"simpleapi/internal/schoolprofile"
+)
+
+// QuotaNotices emails the school's admins when it nears one of its quotas,
+// so they can tidy up or ask for more before anything is refused
+type QuotaNotices struct {
+	Mailer   mail.Sender
+	Teachers repository.TeacherStore
+	School   *schoolprofile.Profile
+}
+
+// Job wraps the sends for the queue
+func (n *QuotaNotices) Job(s models.QuotaStatus) Job {
+	return Job{
+		Name: fmt.Sprintf("%s quota notices", s.Quota),
+		Run:  func(ctx context.Context) error { return n.notify(ctx, s) },
+	}
+}
+
+func (n *QuotaNotices) notify(ctx context.Context, s models.QuotaStatus) error {
+	admins, err := n.Teachers.GetAll(ctx, query.Options{Where: expr.All(
+		expr.Equal(models.TeacherFields, "role", models.RoleAdmin),
+		expr.Equal(models.TeacherFields, "is_active", true),
+	)})
+	if err != nil {
+		return err
+	}
+	school := n.School.Name()
+	if school == "" {
+		school = "The school"
+	}
+
+	usage, refused := fmt.Sprintf("%d of the %d students", s.Used, s.Limit), "new students"
+	switch s.Quota {
+	case models.QuotaStorage:
+		usage = fmt.Sprintf("%.1f MB of the %.1f MB", float64(s.Used)/(1<<20), float64(s.Limit)/(1<<20))
+		refused = "uploads"
+	case models.QuotaRequests:
+		usage = fmt.Sprintf("%d of the %d requests a minute", s.Used, s.Limit)
+		refused = "requests"
+	}
+	msg := mail.Message{
+		Subject: fmt.Sprintf("%s is nearing its %s quota", school, s.Quota),
+		Body: fmt.Sprintf("%s has used %s allowed by its %s quota (%d%%).\n\n"+
+			"Once it is reached, %s will be refused. If the school needs more, raise the limit "+
+			"with PUT /admin/school/quotas.\n",
+			school, usage, s.Quota, s.Used*100/s.Limit, refused),
+	}
+	sent := 0
+	for _, a := range admins {
+		msg.To = a.Email
+		if err := n.Mailer.Send(ctx, msg); err != nil {
+			log.Printf("jobs: %s quota notice to teacher %d: %v", s.Quota, a.ID, err)
+			continue
+		}
+		sent++
+	}
+	if sent > 0 {
+		log.Printf("jobs: emailed %d admins about the %s quota", sent, s.Quota)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"simpleapi/pkg/clock"
+	"time"
+)
+
+// Retention applies the data retention policy: each category's records older
+// than its period are anonymized or deleted (RETENTION, see models.ParseRetention)
+type Retention struct {
+	Store    repository.RetentionStore
+	Policies []models.RetentionPolicy
+	Clock    clock.Clock
+}
+
+// Run expires every category, or on a dry run reports what it would expire.
+// A category that fails doesn't stop the others; the first error is returned
+// with the results of the rest.
+func (r *Retention) Run(ctx context.Context, dryRun bool) ([]models.RetentionResult, error) {
+	now := r.Clock.Now()
+	results := make([]models.RetentionResult, 0, len(r.Policies))
+	var firstErr error
+	for _, p := range r.Policies {
+		cutoff := now.Add(-p.Period)
+		n, err := r.Store.Expire(ctx, p, cutoff, dryRun)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("jobs: retention of %s: %w", p.Category, err)
+			}
+			continue
+		}
+		results = append(results, models.RetentionResult{
+			Category:   p.Category,
+			Action:     p.Action,
+			PeriodDays: int(p.Period / (24 * time.Hour)),
+			Cutoff:     cutoff,
+			Records:    n,
+			DryRun:     dryRun,
+		})
+	}
+	return results, firstErr
+}
+
+// Job runs the policy for real, for the scheduler
+func (r *Retention) Job() Job {
+	return Job{
+		Name: "data retention",
+		Run: func(ctx context.Context) error {
+			results, err := r.Run(ctx, false)
+			for _, res := range results {
+				if res.Records > 0 {
+					log.Printf("jobs: retention: %s %d %s from before %s", pastTense(res.Action), res.Records, res.Category, res.Cutoff.Format(time.RFC3339))
+				}
+			}
+			return err
+		},
+	}
+}
+
+func pastTense(action string) string {
+	if action == models.RetentionDelete {
+		return "deleted"
+	}
+	return "anonymized"
+}
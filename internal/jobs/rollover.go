@@ -0,0 +1,174 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+)
+
+// rolloverProgressEvery is how many students or teachers the rollover moves
+// between two progress reports
+const rolloverProgressEvery = 25
+
+// Rollover executes school-year rollover plans: it archives the year that
+// ends, then moves the students and teachers to next year's classes in one
+// unit of work, so a plan that fails midway leaves no one half moved. Only
+// the archive stays behind; it is a record of the old year either way.
+type Rollover struct {
+	Plans    repository.RolloverStore
+	Units    repository.UnitOfWork
+	Archiver *YearArchiver
+}
+
+// Job wraps Run for the queue
+func (ro *Rollover) Job(p models.RolloverPlan) Job {
+	return Job{
+		Name:    fmt.Sprintf("rollover %s to %s (#%d)", p.FromYear, p.ToYear, p.ID),
+		Run:     func(ctx context.Context) error { return ro.Run(ctx, p) },
+		NoRetry: true,
+	}
+}
+
+// Run carries the plan out, recording the outcome on it
+func (ro *Rollover) Run(ctx context.Context, p models.RolloverPlan) error {
+	if err := ro.Plans.MarkRunning(ctx, p.ID); err != nil {
+		return err
+	}
+
+	result := p
+	result.Students = append([]models.RolloverStudent(nil), p.Students...)
+	result.Status = models.JobDone
+	err := ro.execute(ctx, &result)
+	if err != nil {
+		result.Status, result.Error = models.JobFailed, err.Error()
+		for i := range result.Students {
+			result.Students[i].Moved = false // Rolled back
+		}
+	}
+	if ferr := ro.Plans.Finish(ctx, p.ID, result); ferr != nil {
+		return ferr
+	}
+	return err
+}
+
+// Recover fails plans a restart interrupted. Their moves were rolled back
+// with the transaction, so they can be executed again.
+func (ro *Rollover) Recover(ctx context.Context) {
+	n, err := ro.Plans.FailUnfinished(ctx, "interrupted by a restart, execute the plan again")
+	if err != nil {
+		log.Printf("jobs: could not recover rollover plans: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("jobs: marked %d interrupted rollover plans failed", n)
+	}
+}
+
+func (ro *Rollover) execute(ctx context.Context, p *models.RolloverPlan) error {
+	if p.Archive {
+		ro.report(ctx, p, models.RolloverProgress{Step: "archive"})
+		a, err := ro.Archiver.Archives.Create(ctx, p.FromYear, p.ExecutedBy)
+		if err != nil {
+			return fmt.Errorf("archive %s: %w", p.FromYear, err)
+		}
+		p.ArchiveID = &a.ID
+		if err := ro.Archiver.Run(ctx, *a); err != nil {
+			return fmt.Errorf("archive %s: %w", p.FromYear, err)
+		}
+	}
+
+	var teachers []models.RolloverTeacher
+	for _, t := range p.Teachers {
+		if t.Changed() {
+			teachers = append(teachers, t)
+		}
+	}
+
+	return ro.Units.Do(ctx, func(ctx context.Context, repos repository.Repos) error {
+		progress := models.RolloverProgress{Step: "students", Total: len(p.Students)}
+		for i := range p.Students {
+			s := &p.Students[i]
+			s.Moved = false
+			if s.Action != models.RolloverRepeat {
+				action := models.AuditStudentPromoted
+				if s.Action == models.RolloverGraduate {
+					action = models.AuditStudentGraduated
+				}
+				moved, err := repos.Students.MoveClass(ctx, s.StudentID, s.Class, s.NextClass, models.AuditEntry{
+					ActorID: p.ExecutedBy,
+					Action:  action,
+					Details: map[string]any{"rollover_id": p.ID, "year": p.ToYear},
+				})
+				if err != nil {
+					return fmt.Errorf("student %d: %w", s.StudentID, err)
+				}
+				s.Moved = moved
+				if moved && s.Action == models.RolloverGraduate {
+					if err := graduate(ctx, repos, s.StudentID, p); err != nil {
+						return fmt.Errorf("student %d: %w", s.StudentID, err)
+					}
+				}
+			}
+			progress.Done++
+			ro.tick(ctx, p, progress)
+		}
+
+		progress = models.RolloverProgress{Step: "teachers", Total: len(teachers)}
+		for _, t := range teachers {
+			if err := moveTeacher(ctx, repos, t, p.ExecutedBy); err != nil {
+				return fmt.Errorf("teacher %d: %w", t.TeacherID, err)
+			}
+			progress.Done++
+			ro.tick(ctx, p, progress)
+		}
+		p.Progress = progress
+		return nil
+	})
+}
+
+// graduate makes an enrolled graduate alumni. A suspended one keeps their
+// status, for the office to settle (see package enrollment).
+func graduate(ctx context.Context, repos repository.Repos, studentID int, p *models.RolloverPlan) error {
+	_, err := repos.Students.SetStatus(ctx, studentID, models.StudentEnrolled, models.StudentStatusRequest{
+		Status: models.StudentAlumni,
+		Reason: "Graduated at the " + p.FromYear + " rollover",
+	}, p.ExecutedBy)
+	if errors.Is(err, models.ErrConflict) {
+		return nil
+	}
+	return err
+}
+
+// moveTeacher gives a teacher next year's home class and assigned classes.
+// Teachers deleted since the plan was made are skipped.
+func moveTeacher(ctx context.Context, repos repository.Repos, t models.RolloverTeacher, actorID *int) error {
+	if t.NextHomeClass != t.HomeClass {
+		_, err := repos.Teachers.Patch(ctx, t.TeacherID, map[string]interface{}{"class": t.NextHomeClass}, actorID)
+		if errors.Is(err, models.ErrNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return repos.ClassAssignments.SetClasses(ctx, t.TeacherID, t.NextClasses)
+}
+
+// tick reports progress every rolloverProgressEvery moves and at the end of a step
+func (ro *Rollover) tick(ctx context.Context, p *models.RolloverPlan, progress models.RolloverProgress) {
+	if progress.Done%rolloverProgressEvery == 0 || progress.Done == progress.Total {
+		ro.report(ctx, p, progress)
+	}
+}
+
+// report records progress outside the unit of work, so it shows while the
+// plan runs. It is only informative: a failure is logged, not fatal.
+func (ro *Rollover) report(ctx context.Context, p *models.RolloverPlan, progress models.RolloverProgress) {
+	p.Progress = progress
+	if err := ro.Plans.Progress(ctx, p.ID, progress); err != nil {
+		log.Printf("jobs: rollover plan %d progress: %v", p.ID, err)
+	}
+}
@@ -0,0 +1,240 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"simpleapi/pkg/clock"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Schedule is a parsed cron expression: minute hour day-of-month month day-of-week,
+// e.g. "0 10 * * mon-fri". Fields take *, numbers, ranges (1-5), steps (*/15, 8-18/2)
+// and comma lists; months and weekdays also take names (jan, mon). Times are the
+// school's, from the scheduler's clock.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64 // Bit n set = value n matches
+	domAny, dowAny                bool
+}
+
+var cronFields = []struct {
+	name     string
+	min, max int
+	names    []string
+}{
+	{"minute", 0, 59, nil},
+	{"hour", 0, 23, nil},
+	{"day of month", 1, 31, nil},
+	{"month", 1, 12, []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	{"day of week", 0, 6, []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+// ParseSchedule reads a five-field cron expression
+func ParseSchedule(spec string) (*Schedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("jobs: schedule %q: want 5 fields (minute hour day month weekday)", spec)
+	}
+	var bits [5]uint64
+	for i, f := range fields {
+		b, err := parseCronField(strings.ToLower(f), cronFields[i].min, cronFields[i].max, cronFields[i].names)
+		if err != nil {
+			return nil, fmt.Errorf("jobs: schedule %q: %s: %w", spec, cronFields[i].name, err)
+		}
+		bits[i] = b
+	}
+	// Like cron, 7 is Sunday too
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+	return &Schedule{
+		minute: bits[0], hour: bits[1], dom: bits[2], month: bits[3], dow: bits[4],
+		domAny: fields[2] == "*", dowAny: fields[4] == "*",
+	}, nil
+}
+
+func parseCronField(field string, min, max int, names []string) (uint64, error) {
+	if names != nil && max == 6 {
+		max = 7 // Sunday as 7
+	}
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		expr, stepText, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepText)
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("bad step %q", stepText)
+			}
+			step = n
+		}
+
+		lo, hi := min, max
+		if expr != "*" {
+			loText, hiText, isRange := strings.Cut(expr, "-")
+			var err error
+			if lo, err = cronValue(loText, min, names); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = cronValue(hiText, min, names); err != nil {
+					return 0, err
+				}
+			} else if hasStep {
+				hi = max // "5/10" means from 5 to the end, every 10
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%q is out of range %d-%d", part, min, max)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+func cronValue(text string, min int, names []string) (int, error) {
+	for i, name := range names {
+		if text == name {
+			return min + i, nil
+		}
+	}
+	n, err := strconv.Atoi(text)
+	if err != nil {
+		return 0, fmt.Errorf("bad value %q", text)
+	}
+	return n, nil
+}
+
+// Matches reports whether the schedule fires in the minute t falls in.
+// As in cron, when both day fields are restricted either one matching is enough.
+func (s *Schedule) Matches(t time.Time) bool {
+	if s.minute&(1<<t.Minute()) == 0 || s.hour&(1<<t.Hour()) == 0 || s.month&(1<<int(t.Month())) == 0 {
+		return false
+	}
+	domOK := s.dom&(1<<t.Day()) != 0
+	dowOK := s.dow&(1<<int(t.Weekday())) != 0
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dowOK
+	case s.dowAny:
+		return domOK
+	default:
+		return domOK || dowOK
+	}
+}
+
+// Scheduler runs jobs at the times their schedules name, in the school's time zone.
+// A run that is still going when its next time comes round is skipped, not doubled.
+// Across daylight saving changes, a time the clocks skip runs at the first minute
+// after the jump, and a time they repeat runs only the first time round.
+// With several API instances, each run happens on the one that takes its lock first.
+type Scheduler struct {
+	clock   clock.Clock
+	locks   Locks
+	entries []*scheduled
+}
+
+type scheduled struct {
+	job      Job
+	schedule *Schedule
+	running  atomic.Bool
+	last     time.Time // Wall clock of the latest run, see wallClock
+}
+
+// NewScheduler is the constructor
+func NewScheduler(clk clock.Clock, locks Locks) *Scheduler {
+	return &Scheduler{clock: clk, locks: locks}
+}
+
+// Add registers a job to run on spec (see Schedule); call it before Start
+func (s *Scheduler) Add(spec string, job Job) error {
+	schedule, err := ParseSchedule(spec)
+	if err != nil {
+		return err
+	}
+	s.entries = append(s.entries, &scheduled{job: job, schedule: schedule})
+	return nil
+}
+
+// Start checks the schedules at the top of every minute until ctx is cancelled
+func (s *Scheduler) Start(ctx context.Context) {
+	go func() {
+		for {
+			now := s.clock.Now()
+			next := now.Truncate(time.Minute).Add(time.Minute)
+			timer := time.NewTimer(next.Sub(now))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			s.tick(ctx, next)
+		}
+	}()
+}
+
+func (s *Scheduler) tick(ctx context.Context, t time.Time) {
+	for _, e := range s.entries {
+		if !e.due(t) {
+			continue
+		}
+		if !e.running.CompareAndSwap(false, true) {
+			log.Printf("jobs: %s is still running, skipping this run", e.job.Name)
+			continue
+		}
+		go func() {
+			defer e.running.Store(false)
+			// One lock per run: whichever instance takes it runs the job
+			ok, err := s.locks.TryLock(ctx, "schedule:"+e.job.Name+":"+t.Format(time.RFC3339), runLockTTL)
+			if err != nil {
+				log.Printf("jobs: %s not run, could not take its lock: %v", e.job.Name, err)
+				return
+			}
+			if !ok {
+				return // Another instance has it
+			}
+			if err := e.job.Run(ctx); err != nil {
+				log.Printf("jobs: %s failed: %v", e.job.Name, err)
+			}
+		}()
+	}
+}
+
+// due reports whether e runs at t, the minute the scheduler reached. Normally
+// that is whether t matches; when the clocks just went forward it is whether
+// any wall-clock minute they skipped does, and when they went back, minutes
+// at or before the latest run's wall clock are ones already had.
+func (e *scheduled) due(t time.Time) bool {
+	wall := wallClock(t)
+	if !wall.After(e.last) {
+		return false
+	}
+	from := wallClock(t.Add(-time.Minute)).Add(time.Minute)
+	if from.After(wall) {
+		from = wall // The clocks went back: only the minute itself
+	}
+	for m := from; !m.After(wall); m = m.Add(time.Minute) {
+		if e.schedule.Matches(m) {
+			e.last = wall
+			return true
+		}
+	}
+	return false
+}
+
+// wallClock is t's date and time of day as read on the clock, in UTC, so
+// that wall-clock minutes step and compare evenly across offset changes
+func wallClock(t time.Time) time.Time {
+	y, mo, d := t.Date()
+	h, mi, _ := t.Clock()
+	return time.Date(y, mo, d, h, mi, 0, 0, time.UTC)
+}
@@ -0,0 +1,121 @@
+package jobs
+
+import (
+	"slices"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestParseSchedule(t *testing.T) {
+	tests := []struct {
+		spec    string
+		at      string // Europe/London wall clock, 2026-03-02 is a Monday
+		matches bool
+	}{
+		{"0 10 * * mon-fri", "2026-03-02 10:00", true},
+		{"0 10 * * mon-fri", "2026-03-07 10:00", false},
+		{"*/15 8-18/2 * * *", "2026-03-02 12:45", true},
+		{"*/15 8-18/2 * * *", "2026-03-02 13:45", false},
+		{"0 0 * * 7", "2026-03-01 00:00", true},   // 7 is Sunday too
+		{"0 9 1 * mon", "2026-03-01 09:00", true}, // Either day field is enough
+		{"0 9 1 * mon", "2026-03-02 09:00", true},
+		{"0 9 1 * mon", "2026-03-03 09:00", false},
+		{"30 7 * jan,mar *", "2026-03-03 07:30", true},
+	}
+	london := mustLoad(t, "Europe/London")
+	for _, tt := range tests {
+		t.Run(tt.spec+" at "+tt.at, func(t *testing.T) {
+			s, err := ParseSchedule(tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := s.Matches(mustWall(t, london, tt.at)); got != tt.matches {
+				t.Errorf("Matches = %v, want %v", got, tt.matches)
+			}
+		})
+	}
+
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "*/0 * * * *", "5-1 * * * *", "* * * foo *"} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("ParseSchedule(%q) accepted", spec)
+		}
+	}
+}
+
+// TestSchedulerDST ticks the scheduler minute by minute from noon the day before
+// the clocks change to noon that day, and checks when each job runs. Runs are listed
+// in UTC, so a time the clocks repeat shows which of its two occurrences ran.
+func TestSchedulerDST(t *testing.T) {
+	tests := []struct {
+		name  string
+		zone  string
+		night string // The local date the clocks change on
+		spec  string
+		runs  []string // UTC
+	}{
+		// Europe/London springs forward at 01:00 GMT to 02:00 BST
+		{"spring forward, before the gap", "Europe/London", "2026-03-29", "59 0 * * *", []string{"2026-03-29 00:59"}},
+		{"spring forward, skipped time runs after the jump", "Europe/London", "2026-03-29", "30 1 * * *", []string{"2026-03-29 01:00"}},
+		{"spring forward, start of the gap", "Europe/London", "2026-03-29", "0 1 * * *", []string{"2026-03-29 01:00"}},
+		{"spring forward, whole skipped hour runs once", "Europe/London", "2026-03-29", "*/15 1 * * *", []string{"2026-03-29 01:00"}},
+		{"spring forward, after the gap", "Europe/London", "2026-03-29", "30 2 * * *", []string{"2026-03-29 01:30"}},
+		{"spring forward, the day before", "Europe/London", "2026-03-29", "0 12 * * *", []string{"2026-03-28 12:00"}},
+		{"spring forward, hourly", "Europe/London", "2026-03-29", "0 0-3 * * *", []string{"2026-03-29 00:00", "2026-03-29 01:00", "2026-03-29 02:00"}},
+		// America/New_York springs forward at 02:00 EST to 03:00 EDT
+		{"spring forward, New York", "America/New_York", "2026-03-08", "30 2 * * *", []string{"2026-03-08 07:00"}},
+
+		// Europe/London falls back at 02:00 BST to 01:00 GMT: 01:00-01:59 happens twice
+		{"fall back, before the repeat", "Europe/London", "2026-10-25", "30 0 * * *", []string{"2026-10-24 23:30"}},
+		{"fall back, repeated time runs the first time only", "Europe/London", "2026-10-25", "30 1 * * *", []string{"2026-10-25 00:30"}},
+		{"fall back, repeated hour runs once per minute", "Europe/London", "2026-10-25", "*/30 1 * * *", []string{"2026-10-25 00:00", "2026-10-25 00:30"}},
+		{"fall back, after the repeat", "Europe/London", "2026-10-25", "0 2 * * *", []string{"2026-10-25 02:00"}},
+		{"fall back, hourly", "Europe/London", "2026-10-25", "0 0-3 * * *", []string{"2026-10-24 23:00", "2026-10-25 00:00", "2026-10-25 02:00", "2026-10-25 03:00"}},
+		// America/New_York falls back at 02:00 EDT to 01:00 EST
+		{"fall back, New York", "America/New_York", "2026-11-01", "15 1 * * *", []string{"2026-11-01 05:15"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc := mustLoad(t, tt.zone)
+			schedule, err := ParseSchedule(tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			e := &scheduled{schedule: schedule}
+
+			night, err := time.Parse(time.DateOnly, tt.night)
+			if err != nil {
+				t.Fatal(err)
+			}
+			y, mo, d := night.Date()
+			from, to := time.Date(y, mo, d-1, 12, 0, 0, 0, loc), time.Date(y, mo, d, 12, 0, 0, 0, loc)
+			var runs []string
+			for m := from.UTC(); m.Before(to); m = m.Add(time.Minute) {
+				if e.due(m.In(loc)) {
+					runs = append(runs, m.Format("2006-01-02 15:04"))
+				}
+			}
+			if !slices.Equal(runs, tt.runs) {
+				t.Errorf("runs = %v, want %v", runs, tt.runs)
+			}
+		})
+	}
+}
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func mustWall(t *testing.T, loc *time.Location, text string) time.Time {
+	t.Helper()
+	at, err := time.ParseInLocation("2006-01-02 15:04", text, loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return at
+}
@@ -0,0 +1,67 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"simpleapi/internal/models"
+	"simpleapi/internal/query"
+	"simpleapi/internal/repository"
+	"simpleapi/internal/sms"
+)
+
+// ThreadNotices texts guardians when staff post in a messaging thread: the
+// student's guardian for a student thread, every guardian of the class for a
+// class thread. Guardians have no accounts, so SMS is how they hear of it.
+type ThreadNotices struct {
+	Students repository.StudentStore
+	Notifier *sms.Notifier
+}
+
+// Job wraps the fan-out for the queue, so posting doesn't wait on the SMS provider
+func (tn *ThreadNotices) Job(t models.Thread, m models.ThreadMessage, sender models.Teacher) Job {
+	return Job{
+		Name: fmt.Sprintf("thread %d message %d notices", t.ID, m.ID),
+		Run:  func(ctx context.Context) error { return tn.notify(ctx, t, m, sender) },
+	}
+}
+
+func (tn *ThreadNotices) notify(ctx context.Context, t models.Thread, m models.ThreadMessage, sender models.Teacher) error {
+	var students []models.Student
+	about := "class " + t.Class
+	if t.Scope == models.ThreadStudent {
+		student, err := tn.Students.GetByID(ctx, *t.StudentID)
+		if err != nil {
+			return err
+		}
+		students = []models.Student{*student}
+		about = student.FirstName + " " + student.LastName
+	} else {
+		var err error
+		if students, err = tn.Students.GetAll(ctx, query.Options{Where: models.ClassRoster(t.Class)}); err != nil {
+			return err
+		}
+	}
+
+	// Siblings in one class share a guardian, who only needs one text
+	texted := make(map[string]bool)
+	sent := 0
+	for _, s := range students {
+		if texted[s.GuardianPhone] {
+			continue
+		}
+		if _, err := tn.Notifier.SendThreadMessage(ctx, s, sender, about, m.Body); err != nil {
+			if !errors.Is(err, sms.ErrNoPhone) {
+				log.Printf("jobs: thread %d notice for student %d: %v", t.ID, s.ID, err)
+			}
+			continue
+		}
+		texted[s.GuardianPhone] = true
+		sent++
+	}
+	if sent > 0 {
+		log.Printf("jobs: texted %d guardians about message %d in thread %d", sent, m.ID, t.ID)
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"simpleapi/internal/models"
+	"simpleapi/internal/sms"
+)
+
+// ConsentRequest is a consent to ask a guardian for: the student, and the
+// token of the link, which only ever travels in the text
+type ConsentRequest struct {
+	Student models.Student
+	Token   string
+}
+
+// TripConsentNotices texts guardians a link to give or refuse consent to a
+// trip. Guardians have no accounts or email on file, so the link in the SMS
+// is their only way in: its token is the credential.
+type TripConsentNotices struct {
+	Notifier *sms.Notifier
+	AppURL   string // APP_URL: the frontend, which serves /trip-consent
+}
+
+// Job wraps the fan-out for the queue, so creating a trip doesn't wait on the SMS provider
+func (n *TripConsentNotices) Job(trip models.Trip, requests []ConsentRequest) Job {
+	return Job{
+		Name: fmt.Sprintf("trip %d consent requests", trip.ID),
+		Run:  func(ctx context.Context) error { return n.send(ctx, trip, requests) },
+	}
+}
+
+// send texts one link per student: siblings on one trip each need an answer
+func (n *TripConsentNotices) send(ctx context.Context, trip models.Trip, requests []ConsentRequest) error {
+	sent := 0
+	for _, req := range requests {
+		link := n.AppURL + "/trip-consent?token=" + url.QueryEscape(req.Token)
+		if _, err := n.Notifier.SendTripConsent(ctx, req.Student, trip, trip.LastConsentDay(), link); err != nil {
+			if !errors.Is(err, sms.ErrNoPhone) {
+				log.Printf("jobs: trip %d consent request for student %d: %v", trip.ID, req.Student.ID, err)
+			}
+			continue
+		}
+		sent++
+	}
+	log.Printf("jobs: texted %d of %d guardians for consent to trip %d", sent, len(requests), trip.ID)
+	return nil
+}
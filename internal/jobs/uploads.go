@@ -0,0 +1,101 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"simpleapi/internal/scan"
+	"simpleapi/internal/sms"
+	"simpleapi/internal/storage"
+)
+
+// UploadScans runs uploaded files through the virus scanner. A file stays
+// pending, and can't be downloaded, until its scan says clean; a flagged file
+// is quarantined for an admin to review and its uploader is texted.
+type UploadScans struct {
+	Uploads  repository.UploadStore
+	Teachers repository.TeacherStore
+	Storage  storage.Storage
+	Scanner  scan.Scanner
+	Notifier *sms.Notifier
+	Queue    *Queue
+}
+
+// Job wraps the scan of one upload for the queue
+func (us *UploadScans) Job(u models.Upload) Job {
+	return Job{
+		Name: fmt.Sprintf("scan of upload %d", u.ID),
+		Run:  func(ctx context.Context) error { return us.scan(ctx, u) },
+	}
+}
+
+// Enqueue queues the scan of an upload. A full queue only delays it: the
+// upload stays pending and RescanJob picks it up.
+func (us *UploadScans) Enqueue(u models.Upload) {
+	if err := us.Queue.Enqueue(us.Job(u)); err != nil {
+		log.Printf("jobs: could not queue scan of upload %d: %v", u.ID, err)
+	}
+}
+
+// RescanJob queues a scan for every pending upload: those whose scan failed
+// (scanner down), didn't fit in the queue, or died with a restart
+func (us *UploadScans) RescanJob() Job {
+	return Job{Name: "pending upload scans", Run: us.rescan}
+}
+
+func (us *UploadScans) rescan(ctx context.Context) error {
+	pending, err := us.Uploads.List(ctx, models.UploadFilter{Status: models.ScanPending})
+	if err != nil {
+		return err
+	}
+	for _, u := range pending {
+		us.Enqueue(u)
+	}
+	return nil
+}
+
+func (us *UploadScans) scan(ctx context.Context, u models.Upload) error {
+	rc, err := us.Storage.Get(ctx, u.StorageKey)
+	if err != nil {
+		return err
+	}
+	verdict, err := us.Scanner.Scan(ctx, rc)
+	rc.Close()
+	if err != nil {
+		return err
+	}
+
+	if verdict.Clean {
+		_, err = us.Uploads.RecordScan(ctx, u.ID, models.ScanClean, "")
+		if errors.Is(err, models.ErrConflict) {
+			return nil // Scanned twice; the first verdict stands
+		}
+		return err
+	}
+
+	updated, err := us.Uploads.RecordScan(ctx, u.ID, models.ScanQuarantined, verdict.Threat)
+	if errors.Is(err, models.ErrConflict) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	log.Printf("jobs: upload %d (%s) quarantined by %s: %s", u.ID, u.Name, us.Scanner.Name(), verdict.Threat)
+	us.notify(ctx, *updated)
+	return nil
+}
+
+// notify texts the uploader. Losing the text doesn't undo the quarantine, so failures are only logged.
+func (us *UploadScans) notify(ctx context.Context, u models.Upload) {
+	teacher, err := us.Teachers.GetByID(ctx, u.UploadedBy)
+	if err != nil {
+		log.Printf("jobs: quarantine notice for upload %d: %v", u.ID, err)
+		return
+	}
+	if _, err := us.Notifier.SendUploadQuarantined(ctx, *teacher, u.Name); err != nil && !errors.Is(err, sms.ErrNoPhone) {
+		log.Printf("jobs: quarantine notice for upload %d: %v", u.ID, err)
+	}
+}
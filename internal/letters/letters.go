@@ -0,0 +1,49 @@
+// Package letters merges letter templates (models.LetterTemplate) with a
+// student's details. Templates are Go text templates whose fields are those
+// of models.LetterMergeFields; package render lays the result out as PDF or
+// HTML.
+package letters
+
+import (
+	"simpleapi/internal/models"
+	"strings"
+	"text/template"
+)
+
+// Parse compiles one part of a template; a field outside
+// models.LetterMergeFields fails when it is executed
+func Parse(name, text string) (*template.Template, error) {
+	return template.New(name).Option("missingkey=error").Parse(text)
+}
+
+// Check parses the subject and body of req and runs them on empty merge
+// fields, so a template that can't be printed is refused when it is saved.
+// The errors name the field, as the validator does.
+func Check(req models.LetterTemplateRequest) []models.ValidationError {
+	var errs []models.ValidationError
+	for _, f := range []struct{ field, text string }{{"Subject", req.Subject}, {"Body", req.Body}} {
+		tmpl, err := Parse(strings.ToLower(f.field), f.text)
+		if err == nil {
+			err = tmpl.Execute(new(strings.Builder), models.LetterMergeFields{})
+		}
+		if err != nil {
+			errs = append(errs, models.RuleError(f.field, "template", err.Error()))
+		}
+	}
+	return errs
+}
+
+// Merge fills t's subject and body with fields
+func Merge(t models.LetterTemplate, fields models.LetterMergeFields) (subject, body string, err error) {
+	var parts [2]strings.Builder
+	for i, text := range []string{t.Subject, t.Body} {
+		tmpl, err := Parse("letter", text)
+		if err != nil {
+			return "", "", err
+		}
+		if err := tmpl.Execute(&parts[i], fields); err != nil {
+			return "", "", err
+		}
+	}
+	return strings.TrimSpace(parts[0].String()), parts[1].String(), nil
+}
@@ -0,0 +1,89 @@
+// Package locale carries the language and time zone a request is rendered
+// in. Staff work across time zones, so dates shown to them (transcript and
+// ID card timestamps, calendar feeds) follow the caller rather than the server:
+//
+//  1. the request's headers, Accept-Language and X-Timezone (middlewares.Locale);
+//  2. else the signed-in teacher's preferences (middlewares.Protect);
+//  3. else English and the school's time zone (SCHOOL_TIMEZONE, the clock's).
+//
+// School days themselves (attendance dates, "today") stay in the school's
+// time zone whoever asks; see clock.SchoolDate.
+package locale
+
+import (
+	"context"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/pkg/clock"
+	"time"
+)
+
+// Locale is what a request asked for. Zero fields are unset and fall back
+// to the school's defaults.
+type Locale struct {
+	Language string         // a language models.SupportsLanguage accepts
+	Location *time.Location // nil: the school's
+}
+
+type contextKey struct{}
+
+// NewContext returns ctx carrying l
+func NewContext(ctx context.Context, l Locale) context.Context {
+	return context.WithValue(ctx, contextKey{}, l)
+}
+
+// FromContext returns the request's locale, the zero Locale when there is none
+func FromContext(ctx context.Context) Locale {
+	l, _ := ctx.Value(contextKey{}).(Locale)
+	return l
+}
+
+// WithPreferences fills in what the request's headers left unset from an
+// account's stored preferences. A preference the school no longer offers
+// (its second language changed) is ignored.
+func WithPreferences(ctx context.Context, language, timezone string) context.Context {
+	l := FromContext(ctx)
+	if l.Language == "" && models.SupportsLanguage(language) {
+		l.Language = language
+	}
+	if l.Location == nil && timezone != "" {
+		if loc, err := LoadLocation(timezone); err == nil {
+			l.Location = loc
+		}
+	}
+	return NewContext(ctx, l)
+}
+
+// LoadLocation parses an IANA zone name such as "Africa/Nairobi". Unlike
+// time.LoadLocation it refuses "" and "Local", which would be the server's zone.
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" || name == "Local" {
+		return nil, fmt.Errorf("locale: unknown time zone %q", name)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("locale: unknown time zone %q", name)
+	}
+	return loc, nil
+}
+
+// Language is the request's message language, English when unset
+func Language(ctx context.Context) string {
+	if lang := FromContext(ctx).Language; lang != "" {
+		return lang
+	}
+	return models.DefaultLanguage
+}
+
+// Location is the request's time zone, the school's when unset
+func Location(ctx context.Context, clk clock.Clock) *time.Location {
+	if loc := FromContext(ctx).Location; loc != nil {
+		return loc
+	}
+	return clk.Location()
+}
+
+// In returns t in the request's time zone
+func In(ctx context.Context, clk clock.Clock, t time.Time) time.Time {
+	return t.In(Location(ctx, clk))
+}
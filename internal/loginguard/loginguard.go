@@ -0,0 +1,161 @@
+// Package loginguard slows down password guessing against one account,
+// whatever address the guesses come from (the rate limiter covers addresses).
+// After a few free failures each further one makes the account wait twice as
+// long before the next attempt, and past another threshold login also asks
+// for a CAPTCHA (see package captcha). A successful login starts over.
+//
+//	LOGIN_BACKOFF=after=3,base=1s,max=15m,captcha=5,reset=24h   (these are the defaults)
+//	LOGIN_BACKOFF=off
+//
+// The counts live in the response cache, so with SHARED_STATE=redis every
+// instance sees them. Updates aren't atomic: failures that race may count
+// once, which the doubling soon makes up for.
+package loginguard
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"simpleapi/internal/cache"
+	"simpleapi/internal/captcha"
+	"simpleapi/pkg/clock"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy is how an account's failures are answered
+type Policy struct {
+	Free         int           // Failures allowed before any delay
+	Base         time.Duration // The first delay; each failure after doubles it
+	Max          time.Duration // The longest delay
+	CaptchaAfter int           // Failures after which login needs a CAPTCHA; 0: never
+	Reset        time.Duration // Failures are forgotten this long after the last one
+}
+
+// DefaultPolicy is what LOGIN_BACKOFF sets when empty
+var DefaultPolicy = Policy{Free: 3, Base: time.Second, Max: 15 * time.Minute, CaptchaAfter: 5, Reset: 24 * time.Hour}
+
+// ParsePolicy reads LOGIN_BACKOFF: comma-separated key=value pairs over
+// DefaultPolicy, or "off" (nil) to turn the guard off
+func ParsePolicy(s string) (*Policy, error) {
+	p := DefaultPolicy
+	if strings.TrimSpace(s) == "off" {
+		return nil, nil
+	}
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, _ := strings.Cut(part, "=")
+		var err error
+		switch strings.TrimSpace(key) {
+		case "after":
+			p.Free, err = strconv.Atoi(value)
+		case "captcha":
+			p.CaptchaAfter, err = strconv.Atoi(value)
+		case "base":
+			p.Base, err = time.ParseDuration(value)
+		case "max":
+			p.Max, err = time.ParseDuration(value)
+		case "reset":
+			p.Reset, err = time.ParseDuration(value)
+		default:
+			return nil, fmt.Errorf("bad login backoff %q, want after, base, max, captcha or reset", part)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("bad login backoff %q: %w", part, err)
+		}
+	}
+	if p.Free < 0 || p.CaptchaAfter < 0 || p.Base <= 0 || p.Max < p.Base || p.Reset < p.Max {
+		return nil, fmt.Errorf("bad login backoff %q: counts can't be negative and base <= max <= reset", s)
+	}
+	return &p, nil
+}
+
+// Delay is how long an account with failures failed logins waits for its next attempt
+func (p Policy) Delay(failures int) time.Duration {
+	n := failures - p.Free
+	if n <= 0 {
+		return 0
+	}
+	d := p.Base
+	for range n - 1 {
+		if d >= p.Max/2 {
+			return p.Max
+		}
+		d *= 2
+	}
+	return min(d, p.Max)
+}
+
+// State is what the guard knows about one account
+type State struct {
+	Failures int       `json:"failures"`
+	RetryAt  time.Time `json:"retry_at"` // No attempt before then
+}
+
+// Guard keeps the failed logins of every account, by email
+type Guard struct {
+	Cache   cache.Cache
+	Policy  Policy
+	Captcha captcha.Verifier // nil: CAPTCHA_PROVIDER is off
+	Clock   clock.Clock
+}
+
+// New is the constructor
+func New(store cache.Cache, policy Policy, verifier captcha.Verifier, clk clock.Clock) *Guard {
+	return &Guard{Cache: store, Policy: policy, Captcha: verifier, Clock: clk}
+}
+
+// Check returns the account's state. A login before RetryAt must be refused
+// without looking at the password.
+func (g *Guard) Check(ctx context.Context, email string) (State, error) {
+	var s State
+	b, ok, err := g.Cache.Get(ctx, key(email))
+	if err != nil || !ok {
+		return s, err
+	}
+	if err := json.Unmarshal(b, &s); err != nil {
+		return State{}, fmt.Errorf("loginguard: bad state: %w", err)
+	}
+	return s, nil
+}
+
+// Wait is how long until the account may try again, 0 if it may now
+func (g *Guard) Wait(s State) time.Duration {
+	return max(s.RetryAt.Sub(g.Clock.Now()), 0)
+}
+
+// NeedsCaptcha reports whether the next login of the account must carry a
+// CAPTCHA token
+func (g *Guard) NeedsCaptcha(s State) bool {
+	return g.Captcha != nil && g.Policy.CaptchaAfter > 0 && s.Failures >= g.Policy.CaptchaAfter
+}
+
+// Fail records a failed login and returns the new state
+func (g *Guard) Fail(ctx context.Context, email string, s State) (State, error) {
+	s.Failures++
+	s.RetryAt = g.Clock.Now().Add(g.Policy.Delay(s.Failures))
+	b, err := json.Marshal(s)
+	if err != nil {
+		return s, err
+	}
+	return s, g.Cache.Set(ctx, key(email), b, g.Policy.Reset)
+}
+
+// Succeed forgets the account's failures
+func (g *Guard) Succeed(ctx context.Context, email string) error {
+	return g.Cache.Delete(ctx, key(email))
+}
+
+// key hashes the email: the cache may be a shared Redis, and this is no
+// place for a list of addresses. Unknown emails get counted too, so the
+// delays don't tell which accounts exist.
+func key(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return "login:" + hex.EncodeToString(sum[:16])
+}
@@ -0,0 +1,20 @@
+package mail
+
+import (
+	"context"
+	"log"
+)
+
+// LogSender prints messages instead of sending them (development and demos)
+type LogSender struct{}
+
+func NewLogSender() *LogSender {
+	return &LogSender{}
+}
+
+func (s *LogSender) Name() string { return "log" }
+
+func (s *LogSender) Send(ctx context.Context, msg Message) error {
+	log.Printf("mail: to=%s subject=%q body=%q", msg.To, msg.Subject, msg.Body)
+	return nil
+}
@@ -0,0 +1,38 @@
+// Package mail sends transactional email (account notices, confirmation links)
+// through SMTP, or a logging stub for development.
+//
+//	MAIL_PROVIDER=log | smtp   (empty means log)
+//	MAIL_FROM=School <no-reply@example.com>
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Message is one outgoing plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender delivers messages through one provider
+type Sender interface {
+	Name() string
+	Send(ctx context.Context, msg Message) error
+}
+
+// SenderFromEnv builds the sender selected by MAIL_PROVIDER
+func SenderFromEnv() (Sender, error) {
+	switch strings.ToLower(os.Getenv("MAIL_PROVIDER")) {
+	case "", "log":
+		return NewLogSender(), nil
+	case "smtp":
+		return NewSMTPFromEnv()
+	default:
+		return nil, fmt.Errorf("mail: unknown MAIL_PROVIDER %q (want log or smtp)", os.Getenv("MAIL_PROVIDER"))
+	}
+}
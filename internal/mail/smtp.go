@@ -0,0 +1,85 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"simpleapi/pkg/secrets"
+	"strings"
+	"time"
+)
+
+// SMTP sends through a relay with PLAIN auth (STARTTLS when the server offers it).
+//
+//	SMTP_ADDR=smtp.example.com:587
+//	SMTP_USERNAME=...
+//	SMTP_PASSWORD=... (or SMTP_PASSWORD_FILE)
+type SMTP struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+func NewSMTPFromEnv() (*SMTP, error) {
+	addr := os.Getenv("SMTP_ADDR")
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("mail: SMTP_ADDR must be host:port: %w", err)
+	}
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		return nil, fmt.Errorf("mail: MAIL_FROM is required with MAIL_PROVIDER=smtp")
+	}
+	s := &SMTP{addr: addr, from: from}
+	if user := os.Getenv("SMTP_USERNAME"); user != "" {
+		password, err := secrets.FromEnv("SMTP_PASSWORD")
+		if err != nil {
+			return nil, err
+		}
+		s.auth = smtp.PlainAuth("", user, password, host)
+	}
+	return s, nil
+}
+
+func (s *SMTP) Name() string { return "smtp" }
+
+func (s *SMTP) Send(ctx context.Context, msg Message) error {
+	// net/smtp has no context support; a deadline on the context still bounds the wait
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(s.addr, s.auth, envelopeAddress(s.from), []string{msg.To}, s.format(msg))
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("mail: smtp send: %w", err)
+		}
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *SMTP) format(msg Message) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", s.from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", stripNewlines(msg.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
+
+// envelopeAddress is the bare address of a "Name <addr>" From header
+func envelopeAddress(from string) string {
+	if i := strings.LastIndex(from, "<"); i >= 0 {
+		return strings.TrimSuffix(from[i+1:], ">")
+	}
+	return from
+}
+
+// stripNewlines keeps a header value from injecting more headers
+var stripNewlines = strings.NewReplacer("\r", "", "\n", " ").Replace
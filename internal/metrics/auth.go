@@ -0,0 +1,185 @@
+package metrics
+
+import (
+	"cmp"
+	"encoding/json"
+	"expvar"
+	"maps"
+	"simpleapi/internal/models"
+	"simpleapi/pkg/clock"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Authentication events
+const (
+	AuthLogin            = "login"
+	AuthLoginFailed      = "login_failed"      // Unknown email or wrong password
+	AuthLoginDeactivated = "login_deactivated" // Right password, deactivated account
+	AuthLoginThrottled   = "login_throttled"   // Refused unseen: the account failed too often lately, or no CAPTCHA
+	AuthTokenInvalid     = "token_invalid"     // Bad signature, garbage, or minted for another audience; expiry is routine and not counted
+	AuthSessionRevoked   = "session_revoked"   // Valid token of a deleted or deactivated account, or from before a password change
+	AuthKioskKeyInvalid  = "kiosk_key_invalid" // Wrong X-API-Key on the kiosk API
+)
+
+// authFailures are the events that count against the client IP
+var authFailures = []string{AuthLoginFailed, AuthLoginDeactivated, AuthLoginThrottled, AuthTokenInvalid, AuthKioskKeyInvalid}
+
+const (
+	// authHistory is how far back summaries can look
+	authHistory = 7 * 24 * time.Hour
+	// maxIPsPerHour bounds memory under a spray from many addresses; later IPs
+	// still count in the totals, just not in the ranking
+	maxIPsPerHour = 1000
+)
+
+// AuthEvents counts authentication events, in hourly buckets for the security
+// summary and as lifetime totals published with expvar ("auth_events"). It
+// only sees this process: with several servers, each has its own counts.
+var AuthEvents = NewAuthLog(clock.New(time.UTC))
+
+func init() {
+	expvar.Publish("auth_events", AuthEvents)
+}
+
+// AuthLog is the store behind AuthEvents
+type AuthLog struct {
+	mu      sync.Mutex
+	clock   clock.Clock
+	started time.Time
+	totals  map[string]int
+	buckets map[time.Time]*authBucket // By the hour they start
+	sink    func(AuthEvent)
+}
+
+// AuthEvent is one authentication event as handed to the log's sink
+type AuthEvent struct {
+	Event     string
+	IP        string
+	Email     string // The email a login was attempted with
+	TeacherID int    // The account, when known; 0 otherwise
+	At        time.Time
+}
+
+type authBucket struct {
+	counts   map[string]int
+	failures map[string]int // By client IP
+}
+
+// NewAuthLog is the constructor
+func NewAuthLog(clk clock.Clock) *AuthLog {
+	return &AuthLog{clock: clk, started: clk.Now(), totals: make(map[string]int), buckets: make(map[time.Time]*authBucket)}
+}
+
+// SetClock makes the log use the server's clock
+func (l *AuthLog) SetClock(clk clock.Clock) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.clock = clk
+	l.started = clk.Now()
+}
+
+// SetSink hands every event recorded from now on to sink as well, e.g. to
+// keep it in the audit trail. sink is called on the request's goroutine and
+// must not block.
+func (l *AuthLog) SetSink(sink func(AuthEvent)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sink = sink
+}
+
+// Record counts one event from the client at ip
+func (l *AuthLog) Record(event, ip string) {
+	l.RecordEvent(AuthEvent{Event: event, IP: ip})
+}
+
+// RecordEvent counts e like Record, for callers that know the account
+func (l *AuthLog) RecordEvent(e AuthEvent) {
+	l.mu.Lock()
+	e.At = l.clock.Now()
+	l.count(e.Event, e.IP, e.At)
+	sink := l.sink
+	l.mu.Unlock()
+
+	if sink != nil {
+		sink(e)
+	}
+}
+
+// count adds an event to its hour and the totals. Caller must hold the lock.
+func (l *AuthLog) count(event, ip string, at time.Time) {
+	hour := at.Truncate(time.Hour)
+	b, ok := l.buckets[hour]
+	if !ok {
+		b = &authBucket{counts: make(map[string]int), failures: make(map[string]int)}
+		l.buckets[hour] = b
+		// A new hour is a good time to drop the ones summaries can't reach anymore
+		for h := range l.buckets {
+			if h.Before(hour.Add(-authHistory)) {
+				delete(l.buckets, h)
+			}
+		}
+	}
+	b.counts[event]++
+	l.totals[event]++
+	if slices.Contains(authFailures, event) && ip != "" {
+		if _, seen := b.failures[ip]; seen || len(b.failures) < maxIPsPerHour {
+			b.failures[ip]++
+		}
+	}
+}
+
+// Window sums the events of the hours overlapping the last window, and ranks
+// the top IPs by failures
+func (l *AuthLog) Window(name string, window time.Duration, top int) models.SecurityWindow {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	from := l.clock.Now().Add(-window).Truncate(time.Hour)
+	w := models.SecurityWindow{Window: name, From: from, Counts: make(map[string]int), TopIPs: make([]models.IPCount, 0)}
+	failures := make(map[string]int)
+	for hour, b := range l.buckets {
+		if hour.Before(from) {
+			continue
+		}
+		for event, n := range b.counts {
+			w.Counts[event] += n
+		}
+		for ip, n := range b.failures {
+			failures[ip] += n
+		}
+	}
+	for ip, n := range failures {
+		w.TopIPs = append(w.TopIPs, models.IPCount{IP: ip, Failures: n})
+	}
+	slices.SortFunc(w.TopIPs, func(a, b models.IPCount) int {
+		return cmp.Or(cmp.Compare(b.Failures, a.Failures), cmp.Compare(a.IP, b.IP))
+	})
+	if len(w.TopIPs) > top {
+		w.TopIPs = w.TopIPs[:top]
+	}
+	return w
+}
+
+// Since is when the log started counting
+func (l *AuthLog) Since() time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.started
+}
+
+// Totals returns a copy of the lifetime totals by event
+func (l *AuthLog) Totals() map[string]int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return maps.Clone(l.totals)
+}
+
+// String is the lifetime totals as JSON, for expvar
+func (l *AuthLog) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b, _ := json.Marshal(l.totals)
+	return string(b)
+}
@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"database/sql"
+	"encoding/json"
+	"expvar"
+	"sync"
+	"time"
+)
+
+// DBPool holds the latest sample of the MySQL connection pool, taken by
+// jobs.PoolMonitor. It's published with expvar ("db_pool"), exported on
+// /metrics and answers GET /readyz.
+var DBPool = &PoolGauges{}
+
+func init() {
+	expvar.Publish("db_pool", DBPool)
+}
+
+// PoolGauges is the store behind DBPool
+type PoolGauges struct {
+	mu        sync.Mutex
+	stats     sql.DBStats
+	waited    time.Duration // Waiting for a connection during the last interval
+	saturated bool
+	sampled   time.Time // Zero until the first sample
+}
+
+// Set records a sample: the pool's stats, the wait added since the previous
+// one and whether that wait is over the threshold
+func (g *PoolGauges) Set(stats sql.DBStats, waited time.Duration, saturated bool, at time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.stats, g.waited, g.saturated, g.sampled = stats, waited, saturated, at
+}
+
+// Get returns the last sample, and false before the first (or without MySQL)
+func (g *PoolGauges) Get() (stats sql.DBStats, waited time.Duration, sampled time.Time, ok bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.stats, g.waited, g.sampled, !g.sampled.IsZero()
+}
+
+// Saturated reports whether requests waited too long for a connection during
+// the last interval
+func (g *PoolGauges) Saturated() bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.saturated
+}
+
+// String is the sample as JSON, for expvar
+func (g *PoolGauges) String() string {
+	stats, waited, sampled, ok := g.Get()
+	if !ok {
+		return "null"
+	}
+	b, _ := json.Marshal(map[string]any{
+		"open":             stats.OpenConnections,
+		"in_use":           stats.InUse,
+		"idle":             stats.Idle,
+		"max_open":         stats.MaxOpenConnections,
+		"wait_count":       stats.WaitCount,
+		"wait_duration_ms": stats.WaitDuration.Milliseconds(),
+		"interval_wait_ms": waited.Milliseconds(),
+		"saturated":        g.Saturated(),
+		"sampled_at":       sampled,
+	})
+	return string(b)
+}
@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"encoding/json"
+	"expvar"
+	"simpleapi/internal/models"
+	"sync"
+	"time"
+)
+
+// SchoolKPIs holds the latest school figures, refreshed from the database by
+// jobs.KPIRefresh. They're published with expvar ("school_kpis") and exported
+// as gauges on /metrics.
+var SchoolKPIs = &KPIGauges{}
+
+func init() {
+	expvar.Publish("school_kpis", SchoolKPIs)
+}
+
+// KPIGauges is the store behind SchoolKPIs
+type KPIGauges struct {
+	mu      sync.Mutex
+	kpis    models.SchoolKPIs
+	updated time.Time // Zero until the first refresh
+}
+
+// Set replaces the figures with ones counted at
+func (g *KPIGauges) Set(kpis models.SchoolKPIs, at time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.kpis = kpis
+	g.updated = at
+}
+
+// Get returns the figures and when they were counted, and false before the first refresh
+func (g *KPIGauges) Get() (models.SchoolKPIs, time.Time, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.kpis, g.updated, !g.updated.IsZero()
+}
+
+// String is the figures as JSON, for expvar
+func (g *KPIGauges) String() string {
+	kpis, updated, ok := g.Get()
+	if !ok {
+		return "null"
+	}
+	b, _ := json.Marshal(map[string]any{
+		"students":         kpis.Students,
+		"teachers":         kpis.Teachers,
+		"attendance_today": kpis.Attendance,
+		"attendance_rate":  kpis.Attendance.Rate(),
+		"updated_at":       updated,
+	})
+	return string(b)
+}
@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"math"
+	"simpleapi/internal/models"
+	"slices"
+	"sync"
+	"time"
+)
+
+// QueryLatency keeps the durations of the latest repository queries, timed by
+// tracing.StartQuery, for GET /admin/diagnostics
+var QueryLatency = NewLatencies(1000)
+
+// Latencies is a ring of the latest durations observed
+type Latencies struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+	full    bool
+}
+
+// NewLatencies keeps the latest size durations
+func NewLatencies(size int) *Latencies {
+	return &Latencies{samples: make([]time.Duration, size)}
+}
+
+// Observe records one duration, pushing out the oldest
+func (l *Latencies) Observe(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.samples[l.next] = d
+	l.next = (l.next + 1) % len(l.samples)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// Summary returns the percentiles of the durations kept
+func (l *Latencies) Summary() models.LatencySummary {
+	l.mu.Lock()
+	n := l.next
+	if l.full {
+		n = len(l.samples)
+	}
+	sorted := slices.Clone(l.samples[:n])
+	l.mu.Unlock()
+	if n == 0 {
+		return models.LatencySummary{}
+	}
+
+	slices.Sort(sorted)
+	at := func(p float64) float64 {
+		i := int(math.Ceil(p*float64(n))) - 1
+		return milliseconds(sorted[max(i, 0)])
+	}
+	return models.LatencySummary{Samples: n, P50: at(0.50), P90: at(0.90), P99: at(0.99), Max: milliseconds(sorted[n-1])}
+}
+
+func milliseconds(d time.Duration) float64 {
+	return math.Round(float64(d)/float64(time.Millisecond)*100) / 100
+}
@@ -0,0 +1,17 @@
+// Package metrics holds the process's counters. They are published with
+// expvar and served to admins as JSON at GET /admin/metrics, next to the Go
+// runtime's own (memstats, cmdline).
+package metrics
+
+import "expvar"
+
+// RequestsCanceled counts, per route pattern, requests the client abandoned
+// before the response was ready (context.Canceled), e.g. on browser navigation
+var RequestsCanceled = expvar.NewMap("requests_canceled")
+
+// CacheHits and CacheMisses count, per cache (the key's prefix, e.g.
+// "reports"), the lookups of cache.Load
+var (
+	CacheHits   = expvar.NewMap("cache_hits")
+	CacheMisses = expvar.NewMap("cache_misses")
+)
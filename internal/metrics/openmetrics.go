@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"expvar"
+	"fmt"
+	"io"
+	"maps"
+	"simpleapi/internal/models"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// OpenMetricsContentType is the media type WriteOpenMetrics writes
+const OpenMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// WriteOpenMetrics writes the school KPIs, the database pool and the
+// process's counters in the OpenMetrics text format, for Prometheus to
+// scrape. The KPIs and the pool are left out until their first sample, so a
+// restart shows as a gap rather than zeros.
+func WriteOpenMetrics(w io.Writer) error {
+	var b strings.Builder
+
+	if kpis, updated, ok := SchoolKPIs.Get(); ok {
+		gauge(&b, "school_students", "Students on the roll", float64(kpis.Students))
+		gauge(&b, "school_teachers_active", "Active teacher accounts", float64(kpis.Teachers))
+		b.WriteString("# TYPE school_attendance_today_marks gauge\n")
+		b.WriteString("# HELP school_attendance_today_marks Attendance marks taken today, by status\n")
+		for _, m := range []struct {
+			status string
+			n      int
+		}{
+			{models.AttendancePresent, kpis.Attendance.Present},
+			{models.AttendanceLate, kpis.Attendance.Late},
+			{models.AttendanceAbsent, kpis.Attendance.Absent},
+		} {
+			fmt.Fprintf(&b, "school_attendance_today_marks{status=\"%s\"} %d\n", labelValue(m.status), m.n)
+		}
+		gauge(&b, "school_attendance_today_percent", "Percentage of today's marks that are present or late", kpis.Attendance.Rate())
+		gauge(&b, "school_kpis_updated_timestamp_seconds", "When the school figures were last counted", float64(updated.UnixMilli())/1000)
+	}
+
+	if stats, waited, _, ok := DBPool.Get(); ok {
+		gauge(&b, "db_pool_open_connections", "Open database connections, in use or idle", float64(stats.OpenConnections))
+		gauge(&b, "db_pool_in_use_connections", "Database connections in use", float64(stats.InUse))
+		gauge(&b, "db_pool_idle_connections", "Idle database connections", float64(stats.Idle))
+		gauge(&b, "db_pool_max_open_connections", "Most database connections the pool opens", float64(stats.MaxOpenConnections))
+		counter(&b, "db_pool_waits", "Times a query waited for a free database connection", float64(stats.WaitCount))
+		counter(&b, "db_pool_wait_seconds", "Time spent waiting for a free database connection", stats.WaitDuration.Seconds())
+		gauge(&b, "db_pool_interval_wait_seconds", "Time spent waiting for a connection during the last sample interval", waited.Seconds())
+		saturated := 0.0
+		if DBPool.Saturated() {
+			saturated = 1
+		}
+		gauge(&b, "db_pool_saturated", "1 while the connection wait is over DB_POOL_WAIT_THRESHOLD", saturated)
+	}
+
+	totals := AuthEvents.Totals()
+	b.WriteString("# TYPE auth_events counter\n")
+	b.WriteString("# HELP auth_events Authentication events since the process started\n")
+	for _, event := range slices.Sorted(maps.Keys(totals)) {
+		fmt.Fprintf(&b, "auth_events_total{event=\"%s\"} %d\n", labelValue(event), totals[event])
+	}
+
+	b.WriteString("# TYPE requests_canceled counter\n")
+	b.WriteString("# HELP requests_canceled Requests the client abandoned before the response was ready\n")
+	RequestsCanceled.Do(func(kv expvar.KeyValue) {
+		fmt.Fprintf(&b, "requests_canceled_total{route=\"%s\"} %s\n", labelValue(kv.Key), kv.Value)
+	})
+
+	b.WriteString("# TYPE cache_lookups counter\n")
+	b.WriteString("# HELP cache_lookups Lookups of the response caches, by cache and result\n")
+	for _, c := range []struct {
+		result string
+		counts *expvar.Map
+	}{{"hit", CacheHits}, {"miss", CacheMisses}} {
+		c.counts.Do(func(kv expvar.KeyValue) {
+			fmt.Fprintf(&b, "cache_lookups_total{cache=\"%s\",result=\"%s\"} %s\n", labelValue(kv.Key), c.result, kv.Value)
+		})
+	}
+
+	b.WriteString("# EOF\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func gauge(b *strings.Builder, name, help string, value float64) {
+	fmt.Fprintf(b, "# TYPE %s gauge\n# HELP %s %s\n%s %s\n", name, name, help, name, strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+func counter(b *strings.Builder, name, help string, value float64) {
+	fmt.Fprintf(b, "# TYPE %s counter\n# HELP %s %s\n%s_total %s\n", name, name, help, name, strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+// labelValue escapes what OpenMetrics requires in a quoted label value
+var labelValue = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace
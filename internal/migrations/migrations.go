@@ -0,0 +1,230 @@
+// Package migrations brings an existing database up to the schema the API
+// expects. Steps run in version order, each once: schema_migrations records
+// the versions applied. Every change checks information_schema before it
+// runs, so a step over a database already changed by hand records itself
+// instead of failing, and a step that failed half way can simply run again.
+//
+//	schoolctl migrate -dry-run   # list the pending steps and their changes
+//	schoolctl migrate
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+)
+
+// Step is one version of the schema: the changes one feature needed
+type Step struct {
+	Version int
+	Name    string
+	Changes []Change
+}
+
+// Change is one table or column a step adds
+type Change struct {
+	table  string
+	column string // Empty for a table
+	create string // CREATE TABLE statement, or the column's definition
+	index  string // Of a column, e.g. "idx_students_gender (gender)"
+	unique bool   // Whether index is a unique one
+	seed   string // Run once the table is created
+}
+
+// Table creates a table; create is its CREATE TABLE IF NOT EXISTS statement
+func Table(name, create string) Change {
+	return Change{table: name, create: create}
+}
+
+// Column adds a column to an existing table
+func Column(table, column, definition string) Change {
+	return Change{table: table, column: column, create: definition}
+}
+
+// Indexed adds an index along with the column, e.g. "idx_students_gender (gender)"
+func (c Change) Indexed(index string) Change {
+	c.index = index
+	return c
+}
+
+// Unique adds a unique index along with the column; rows where it's NULL don't clash
+func (c Change) Unique(index string) Change {
+	c.index, c.unique = index, true
+	return c
+}
+
+// Seeded fills a new table, e.g. with the values already in use elsewhere
+func (c Change) Seeded(insert string) Change {
+	c.seed = insert
+	return c
+}
+
+func (c Change) String() string {
+	if c.column != "" {
+		return c.table + "." + c.column
+	}
+	return c.table
+}
+
+// lockName serializes migrations run at the same time, e.g. by two deploys
+const lockName = "simpleapi.schema_migrations"
+
+const createVersions = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INT PRIMARY KEY,
+	name VARCHAR(100) NOT NULL,
+	applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`
+
+// Run applies the steps not applied yet, in order, reporting each change to
+// out. With dryRun it only reports what it would do.
+func Run(ctx context.Context, db *sql.DB, out io.Writer, dryRun bool) error {
+	return run(ctx, db, Steps, out, dryRun)
+}
+
+func run(ctx context.Context, db *sql.DB, steps []Step, out io.Writer, dryRun bool) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("migrations: %w", err)
+	}
+	defer conn.Close()
+
+	var locked sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, 30)", lockName).Scan(&locked); err != nil {
+		return fmt.Errorf("migrations: taking the lock: %w", err)
+	}
+	if locked.Int64 != 1 {
+		return fmt.Errorf("migrations: another migration is running")
+	}
+	defer conn.ExecContext(context.WithoutCancel(ctx), "SELECT RELEASE_LOCK(?)", lockName)
+
+	applied, err := appliedVersions(ctx, conn, dryRun)
+	if err != nil {
+		return err
+	}
+	pending := 0
+	for _, step := range steps {
+		if applied[step.Version] {
+			continue
+		}
+		pending++
+		fmt.Fprintf(out, "== %04d %s\n", step.Version, step.Name)
+		for _, c := range step.Changes {
+			if err := apply(ctx, conn, c, out, dryRun); err != nil {
+				return fmt.Errorf("migrations: %04d %s: %w", step.Version, step.Name, err)
+			}
+		}
+		if dryRun {
+			continue
+		}
+		if _, err := conn.ExecContext(ctx, "INSERT INTO schema_migrations (version, name) VALUES (?, ?)", step.Version, step.Name); err != nil {
+			return fmt.Errorf("migrations: recording %04d %s: %w", step.Version, step.Name, err)
+		}
+	}
+	if pending == 0 {
+		fmt.Fprintln(out, "the schema is up to date")
+	}
+	return nil
+}
+
+// appliedVersions reads schema_migrations, creating it unless dryRun
+func appliedVersions(ctx context.Context, conn *sql.Conn, dryRun bool) (map[int]bool, error) {
+	applied := make(map[int]bool)
+	exists, err := tableExists(ctx, conn, "schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		if dryRun {
+			return applied, nil
+		}
+		if _, err := conn.ExecContext(ctx, createVersions); err != nil {
+			return nil, fmt.Errorf("migrations: creating schema_migrations: %w", err)
+		}
+	}
+	rows, err := conn.QueryContext(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("migrations: reading schema_migrations: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			return nil, fmt.Errorf("migrations: reading schema_migrations: %w", err)
+		}
+		applied[v] = true
+	}
+	return applied, rows.Err()
+}
+
+// apply makes one change unless it is already there
+func apply(ctx context.Context, conn *sql.Conn, c Change, out io.Writer, dryRun bool) error {
+	var exists bool
+	var err error
+	if c.column == "" {
+		exists, err = tableExists(ctx, conn, c.table)
+	} else {
+		exists, err = columnExists(ctx, conn, c.table, c.column)
+	}
+	if err != nil {
+		return err
+	}
+	switch {
+	case exists:
+		fmt.Fprintf(out, "%s already exists\n", c)
+	case dryRun && c.column != "":
+		fmt.Fprintf(out, "would add %s %s\n", c, c.create)
+	case dryRun && c.seed != "":
+		fmt.Fprintf(out, "would create table %s and fill it with the values in use\n", c)
+	case dryRun:
+		fmt.Fprintf(out, "would create table %s\n", c)
+	case c.column != "":
+		stmt := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", c.table, c.column, c.create)
+		switch {
+		case c.unique:
+			stmt += ", ADD UNIQUE INDEX " + c.index
+		case c.index != "":
+			stmt += ", ADD INDEX " + c.index
+		}
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("adding %s: %w", c, err)
+		}
+		fmt.Fprintf(out, "added %s\n", c)
+	default:
+		if _, err := conn.ExecContext(ctx, c.create); err != nil {
+			return fmt.Errorf("creating %s: %w", c, err)
+		}
+		if c.seed == "" {
+			fmt.Fprintf(out, "created table %s\n", c)
+			return nil
+		}
+		res, err := conn.ExecContext(ctx, c.seed)
+		if err != nil {
+			return fmt.Errorf("filling %s: %w", c, err)
+		}
+		added, _ := res.RowsAffected()
+		fmt.Fprintf(out, "created table %s with %d entries\n", c, added)
+	}
+	return nil
+}
+
+func tableExists(ctx context.Context, conn *sql.Conn, table string) (bool, error) {
+	var n int
+	err := conn.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?", table).Scan(&n)
+	if err != nil {
+		return false, fmt.Errorf("inspecting %s: %w", table, err)
+	}
+	return n > 0, nil
+}
+
+func columnExists(ctx context.Context, conn *sql.Conn, table, column string) (bool, error) {
+	var n int
+	err := conn.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?",
+		table, column).Scan(&n)
+	if err != nil {
+		return false, fmt.Errorf("inspecting %s.%s: %w", table, column, err)
+	}
+	return n > 0, nil
+}
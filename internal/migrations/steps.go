@@ -0,0 +1,725 @@
+package migrations
+
+// Steps is every version of the schema in order. Append new steps at the end
+// with the next version; never renumber or edit one that has shipped, add a
+// step that changes it instead. Later steps may alter tables earlier ones
+// create, e.g. quotas the school table.
+var Steps = []Step{
+	// Phone numbers in E.164; "no number" is an empty string rather than NULL,
+	// so filters and the repositories never special-case NULL. cmd/backfill-phones
+	// then rewrites the numbers stored before in E.164.
+	{
+		Version: 1,
+		Name:    "phones",
+		Changes: []Change{
+			Column("teachers", "phone", "VARCHAR(16) NOT NULL DEFAULT ''").Indexed("idx_teachers_phone (phone)"),
+			Column("students", "guardian_phone", "VARCHAR(16) NOT NULL DEFAULT ''").Indexed("idx_students_guardian_phone (guardian_phone)"),
+		},
+	},
+	// Student demographics; existing students get empty values, which the API
+	// reports as "not recorded"
+	{
+		Version: 2,
+		Name:    "demographics",
+		Changes: []Change{
+			Column("students", "date_of_birth", "DATE NULL").Indexed("idx_students_date_of_birth (date_of_birth)"),
+			Column("students", "gender", "VARCHAR(8) NOT NULL DEFAULT ''").Indexed("idx_students_gender (gender)"),
+			Column("students", "nationality", "CHAR(2) NOT NULL DEFAULT ''").Indexed("idx_students_nationality (nationality)"),
+			Column("students", "enrollment_date", "DATE NULL"),
+		},
+	},
+	// Admin-defined custom fields and the students' values
+	{
+		Version: 3,
+		Name:    "custom-fields",
+		Changes: []Change{
+			Table("custom_fields", `CREATE TABLE IF NOT EXISTS custom_fields (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	entity VARCHAR(20) NOT NULL,
+	field_key VARCHAR(40) NOT NULL,
+	label VARCHAR(100) NOT NULL,
+	type VARCHAR(10) NOT NULL,
+	options JSON NULL,
+	required BOOLEAN NOT NULL DEFAULT FALSE,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE KEY uq_custom_fields_key (entity, field_key)
+)`),
+			Column("students", "custom_fields", "JSON NULL"),
+		},
+	},
+	// Events of audited changes, published by the relay (jobs.OutboxRelay).
+	// Changes made before the table exists have no events.
+	{
+		Version: 4,
+		Name:    "outbox",
+		Changes: []Change{
+			Table("outbox", `CREATE TABLE IF NOT EXISTS outbox (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	event_type VARCHAR(60) NOT NULL,
+	entity VARCHAR(40) NOT NULL,
+	entity_id INT NOT NULL,
+	actor_id INT NULL,
+	data JSON NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	attempts INT NOT NULL DEFAULT 0,
+	next_attempt_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	last_error VARCHAR(500) NULL,
+	delivered_at TIMESTAMP NULL,
+	KEY idx_outbox_pending (delivered_at, next_attempt_at)
+)`),
+		},
+	},
+	// Each account's language and time zone; IANA zone names run to about 30 characters
+	{
+		Version: 5,
+		Name:    "preferences",
+		Changes: []Change{
+			Column("teachers", "language", "VARCHAR(8) NOT NULL DEFAULT ''"),
+			Column("teachers", "timezone", "VARCHAR(64) NOT NULL DEFAULT ''"),
+		},
+	},
+	// The school's profile, one row; until an admin saves it, SCHOOL_NAME and
+	// SCHOOL_TIMEZONE stand in for it
+	{
+		Version: 6,
+		Name:    "school",
+		Changes: []Change{
+			Table("school", `CREATE TABLE IF NOT EXISTS school (
+	id TINYINT PRIMARY KEY,
+	name VARCHAR(200) NOT NULL,
+	address VARCHAR(500) NOT NULL DEFAULT '',
+	contact_email VARCHAR(254) NOT NULL DEFAULT '',
+	timezone VARCHAR(64) NOT NULL DEFAULT '',
+	current_term VARCHAR(20) NOT NULL DEFAULT '',
+	has_logo BOOLEAN NOT NULL DEFAULT FALSE,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	updated_by INT NULL
+)`),
+		},
+	},
+	// Reference data classes and subjects are checked against, filled with the
+	// values in use so checking starts from what the school has
+	{
+		Version: 7,
+		Name:    "reference",
+		Changes: []Change{
+			Table("classes", "CREATE TABLE IF NOT EXISTS classes (name VARCHAR(50) PRIMARY KEY)").
+				Seeded(`INSERT IGNORE INTO classes (name)
+					SELECT class FROM teachers WHERE class <> '' UNION SELECT class FROM students WHERE class <> ''`),
+			Table("subjects", "CREATE TABLE IF NOT EXISTS subjects (name VARCHAR(100) PRIMARY KEY)").
+				Seeded("INSERT IGNORE INTO subjects (name) SELECT DISTINCT subject FROM teachers WHERE subject <> ''"),
+		},
+	},
+	// Forced password changes (breached passwords) and password_changed_at,
+	// which ends the sessions issued before a change
+	{
+		Version: 8,
+		Name:    "password-change",
+		Changes: []Change{
+			Column("teachers", "force_password_change", "BOOLEAN NOT NULL DEFAULT FALSE"),
+			Column("teachers", "password_changed_at", "TIMESTAMP NULL"),
+		},
+	},
+	// Requests waiting for, or carrying, a staff role's sign-off
+	{
+		Version: 9,
+		Name:    "approvals",
+		Changes: []Change{
+			Table("approvals", `CREATE TABLE IF NOT EXISTS approvals (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	type VARCHAR(30) NOT NULL,
+	payload JSON NOT NULL,
+	requested_by INT NOT NULL,
+	approver_role VARCHAR(20) NOT NULL,
+	state VARCHAR(20) NOT NULL DEFAULT 'pending',
+	note VARCHAR(500) NULL,
+	comment VARCHAR(500) NULL,
+	decided_by INT NULL,
+	decided_at TIMESTAMP NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	INDEX idx_approvals_state_role (state, approver_role),
+	INDEX idx_approvals_requested_by (requested_by)
+)`),
+		},
+	},
+	// Bulk email campaigns and the addresses each was sent to
+	{
+		Version: 10,
+		Name:    "communications",
+		Changes: []Change{
+			Table("communication_campaigns", `CREATE TABLE IF NOT EXISTS communication_campaigns (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	subject VARCHAR(200) NOT NULL,
+	body TEXT NOT NULL,
+	audience VARCHAR(20) NOT NULL,
+	class VARCHAR(50) NULL,
+	role VARCHAR(20) NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'pending',
+	error TEXT NULL,
+	created_by INT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	completed_at TIMESTAMP NULL
+)`),
+			Table("communication_recipients", `CREATE TABLE IF NOT EXISTS communication_recipients (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	campaign_id INT NOT NULL,
+	email VARCHAR(255) NOT NULL,
+	first_name VARCHAR(255) NULL,
+	last_name VARCHAR(255) NULL,
+	student VARCHAR(511) NULL,
+	class VARCHAR(50) NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'pending',
+	error TEXT NULL,
+	sent_at TIMESTAMP NULL,
+	INDEX idx_communication_recipients_campaign (campaign_id, status)
+)`),
+		},
+	},
+	// The school's quotas; zeros mean no limits
+	{
+		Version: 11,
+		Name:    "quotas",
+		Changes: []Change{
+			Column("school", "quota_students", "INT NOT NULL DEFAULT 0"),
+			Column("school", "quota_storage_bytes", "BIGINT NOT NULL DEFAULT 0"),
+			Column("school", "quota_requests_per_minute", "INT NOT NULL DEFAULT 0"),
+		},
+	},
+	// Student medical records; every read of them is audited
+	{
+		Version: 12,
+		Name:    "medical",
+		Changes: []Change{
+			Table("student_medical", `CREATE TABLE IF NOT EXISTS student_medical (
+	student_id INT PRIMARY KEY,
+	allergies TEXT NOT NULL,
+	conditions TEXT NOT NULL,
+	medications TEXT NOT NULL,
+	notes TEXT NOT NULL,
+	emergency_contacts JSON NOT NULL,
+	updated_by INT NULL,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	CONSTRAINT fk_student_medical_student FOREIGN KEY (student_id) REFERENCES students(id) ON DELETE CASCADE
+)`),
+		},
+	},
+	// Class trips and the guardian consent asked for each student
+	{
+		Version: 13,
+		Name:    "trips",
+		Changes: []Change{
+			Table("trips", `CREATE TABLE IF NOT EXISTS trips (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	title VARCHAR(150) NOT NULL,
+	class VARCHAR(50) NOT NULL,
+	trip_date DATE NOT NULL,
+	destination VARCHAR(200) NULL,
+	details TEXT NULL,
+	consent_deadline DATE NULL,
+	created_by INT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	INDEX idx_trips_class (class, trip_date),
+	CONSTRAINT fk_trips_class FOREIGN KEY (class) REFERENCES classes(name) ON UPDATE CASCADE
+)`),
+			Table("trip_consents", `CREATE TABLE IF NOT EXISTS trip_consents (
+	trip_id INT NOT NULL,
+	student_id INT NOT NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'pending',
+	via VARCHAR(20) NULL,
+	guardian_name VARCHAR(100) NULL,
+	note VARCHAR(500) NULL,
+	recorded_by INT NULL,
+	token_hash CHAR(64) NULL,
+	requested_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	responded_at TIMESTAMP NULL,
+	PRIMARY KEY (trip_id, student_id),
+	UNIQUE KEY uq_trip_consents_token (token_hash),
+	CONSTRAINT fk_trip_consents_trip FOREIGN KEY (trip_id) REFERENCES trips(id) ON DELETE CASCADE,
+	CONSTRAINT fk_trip_consents_student FOREIGN KEY (student_id) REFERENCES students(id) ON DELETE CASCADE
+)`),
+		},
+	},
+	// Per-class, per-day attendance tallies; the first
+	// SCHEDULE_ATTENDANCE_SUMMARY run fills in the history
+	{
+		Version: 14,
+		Name:    "attendance-summary",
+		Changes: []Change{
+			Table("attendance_daily", `CREATE TABLE IF NOT EXISTS attendance_daily (
+	class VARCHAR(50) NOT NULL,
+	date DATE NOT NULL,
+	present INT NOT NULL DEFAULT 0,
+	late INT NOT NULL DEFAULT 0,
+	absent INT NOT NULL DEFAULT 0,
+	PRIMARY KEY (class, date),
+	INDEX idx_attendance_daily_date (date)
+)`),
+		},
+	},
+	// School-year rollover plans
+	{
+		Version: 15,
+		Name:    "rollover",
+		Changes: []Change{
+			Table("rollover_plans", `CREATE TABLE IF NOT EXISTS rollover_plans (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	from_year VARCHAR(20) NOT NULL,
+	to_year VARCHAR(20) NOT NULL,
+	status VARCHAR(20) NOT NULL DEFAULT 'draft',
+	alumni_class VARCHAR(50) NOT NULL,
+	archive BOOLEAN NOT NULL DEFAULT TRUE,
+	archive_id INT NULL,
+	classes JSON NOT NULL,
+	students JSON NOT NULL,
+	teachers JSON NOT NULL,
+	progress JSON NOT NULL,
+	error TEXT NULL,
+	created_by INT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	executed_by INT NULL,
+	executed_at TIMESTAMP NULL,
+	finished_at TIMESTAMP NULL,
+	INDEX idx_rollover_plans_year (from_year),
+	INDEX idx_rollover_plans_status (status)
+)`),
+		},
+	},
+	// Attendance thresholds, defaulting to models.DefaultAttendanceThresholds,
+	// and the level each student was last alerted at
+	{
+		Version: 16,
+		Name:    "attendance-alerts",
+		Changes: []Change{
+			Column("school", "attendance_notice_below", "INT NOT NULL DEFAULT 90"),
+			Column("school", "attendance_escalate_below", "INT NOT NULL DEFAULT 80"),
+			Column("school", "attendance_window_days", "INT NOT NULL DEFAULT 28"),
+			Column("school", "attendance_min_days", "INT NOT NULL DEFAULT 5"),
+			Table("attendance_alerts", `CREATE TABLE IF NOT EXISTS attendance_alerts (
+	student_id INT NOT NULL PRIMARY KEY,
+	level VARCHAR(20) NOT NULL,
+	rate DECIMAL(5,2) NOT NULL,
+	notified_at DATETIME NOT NULL,
+	CONSTRAINT fk_attendance_alerts_student FOREIGN KEY (student_id) REFERENCES students(id) ON DELETE CASCADE
+)`),
+		},
+	},
+	// Exam sessions with their seating, NULL until generated, and invigilators
+	{
+		Version: 17,
+		Name:    "exams",
+		Changes: []Change{
+			Table("exam_sessions", `CREATE TABLE IF NOT EXISTS exam_sessions (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	title VARCHAR(150) NOT NULL,
+	subject VARCHAR(100) NOT NULL DEFAULT '',
+	exam_date DATE NOT NULL,
+	start_time CHAR(5) NOT NULL,
+	end_time CHAR(5) NOT NULL,
+	classes JSON NOT NULL,
+	rooms JSON NOT NULL,
+	seating JSON NULL,
+	seats JSON NOT NULL,
+	invigilators JSON NOT NULL,
+	created_by INT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	INDEX idx_exam_sessions_date (exam_date, start_time)
+)`),
+		},
+	},
+	// Each student's lifecycle state; existing students start enrolled, and the
+	// index serves class rosters
+	{
+		Version: 18,
+		Name:    "student-status",
+		Changes: []Change{
+			Column("students", "status", "VARCHAR(20) NOT NULL DEFAULT 'enrolled'").Indexed("idx_students_class_status (class, status)"),
+		},
+	},
+	// Letter templates and the documents printed from them; documents keep
+	// their rows when a template is deleted
+	{
+		Version: 19,
+		Name:    "letters",
+		Changes: []Change{
+			Table("letter_templates", `CREATE TABLE IF NOT EXISTS letter_templates (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	name VARCHAR(100) NOT NULL,
+	kind VARCHAR(20) NOT NULL,
+	subject VARCHAR(200) NOT NULL,
+	body TEXT NOT NULL,
+	created_by INT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	INDEX idx_letter_templates_kind (kind, name)
+)`),
+			Table("documents", `CREATE TABLE IF NOT EXISTS documents (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	student_id INT NOT NULL,
+	template_id INT NULL,
+	title VARCHAR(255) NOT NULL,
+	format VARCHAR(10) NOT NULL,
+	content_type VARCHAR(100) NOT NULL,
+	size BIGINT NOT NULL,
+	storage_key VARCHAR(255) NOT NULL,
+	emailed_to VARCHAR(255) NULL,
+	emailed_at DATETIME NULL,
+	created_by INT NULL,
+	created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	INDEX idx_documents_student (student_id, created_at),
+	CONSTRAINT fk_documents_student FOREIGN KEY (student_id) REFERENCES students(id) ON DELETE CASCADE,
+	CONSTRAINT fk_documents_template FOREIGN KEY (template_id) REFERENCES letter_templates(id) ON DELETE SET NULL
+)`),
+		},
+	},
+	// Academic-year archive snapshots; the bundles themselves live in object storage
+	{
+		Version: 20,
+		Name:    "archives",
+		Changes: []Change{
+			Table("academic_year_archives", `CREATE TABLE IF NOT EXISTS academic_year_archives (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	year VARCHAR(20) NOT NULL,
+	status VARCHAR(20) NOT NULL,
+	storage_key VARCHAR(255) NULL,
+	size BIGINT NOT NULL DEFAULT 0,
+	sha256 CHAR(64) NULL,
+	error TEXT NULL,
+	created_by INT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	completed_at TIMESTAMP NULL,
+	INDEX idx_academic_year_archives_year (year, status)
+)`),
+		},
+	},
+	// Database backups; the dumps live in object storage, cmd/restore loads them
+	{
+		Version: 21,
+		Name:    "backups",
+		Changes: []Change{
+			Table("backups", `CREATE TABLE IF NOT EXISTS backups (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	status VARCHAR(20) NOT NULL,
+	storage_key VARCHAR(255) NULL,
+	size BIGINT NOT NULL DEFAULT 0,
+	sha256 CHAR(64) NULL,
+	tables_count INT NOT NULL DEFAULT 0,
+	rows_count BIGINT NOT NULL DEFAULT 0,
+	error TEXT NULL,
+	created_by INT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	completed_at TIMESTAMP NULL,
+	INDEX idx_backups_status (status)
+)`),
+		},
+	},
+	// Daily attendance the scheduler reminds teachers to take, and the reset
+	// token columns its nightly cleanup expires
+	{
+		Version: 22,
+		Name:    "attendance",
+		Changes: []Change{
+			Table("attendance", `CREATE TABLE IF NOT EXISTS attendance (
+	student_id INT NOT NULL,
+	class VARCHAR(50) NOT NULL,
+	date DATE NOT NULL,
+	status VARCHAR(10) NOT NULL,
+	note VARCHAR(255) NULL,
+	taken_by INT NULL,
+	taken_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE KEY uq_attendance_student_date (student_id, date),
+	INDEX idx_attendance_class_date (class, date),
+	INDEX idx_attendance_date_status (date, status)
+)`),
+			Column("teachers", "password_reset_token", "VARCHAR(255) NULL"),
+			Column("teachers", "password_reset_expires", "TIMESTAMP NULL").Indexed("idx_teachers_password_reset_expires (password_reset_expires)"),
+		},
+	},
+	// Year-end promotion reports; criteria and decisions are stored as JSON
+	{
+		Version: 23,
+		Name:    "promotions",
+		Changes: []Change{
+			Table("promotion_reports", `CREATE TABLE IF NOT EXISTS promotion_reports (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	year VARCHAR(20) NOT NULL,
+	status VARCHAR(20) NOT NULL,
+	criteria JSON NOT NULL,
+	next_class JSON NOT NULL,
+	decisions JSON NOT NULL,
+	created_by INT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	applied_by INT NULL,
+	applied_at TIMESTAMP NULL,
+	INDEX idx_promotion_reports_year (year)
+)`),
+		},
+	},
+	// Grading schemes, scores and their append-only corrections. A second score
+	// for a term answers 409 through the key name uq_student_scores_term.
+	{
+		Version: 24,
+		Name:    "grades",
+		Changes: []Change{
+			Table("grading_schemes", `CREATE TABLE IF NOT EXISTS grading_schemes (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	name VARCHAR(100) NOT NULL,
+	subject VARCHAR(100) NOT NULL DEFAULT '',
+	boundaries JSON NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	UNIQUE KEY uq_grading_schemes_subject (subject)
+)`),
+			Table("student_scores", `CREATE TABLE IF NOT EXISTS student_scores (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	student_id INT NOT NULL,
+	teacher_id INT NOT NULL,
+	term VARCHAR(20) NOT NULL,
+	subject VARCHAR(100) NOT NULL,
+	score DECIMAL(5,2) NOT NULL,
+	grade VARCHAR(5) NOT NULL,
+	scheme_id INT NOT NULL,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	UNIQUE KEY uq_student_scores_term (student_id, term, subject)
+)`),
+			Table("grade_corrections", `CREATE TABLE IF NOT EXISTS grade_corrections (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	score_id INT NOT NULL,
+	score DECIMAL(5,2) NOT NULL,
+	grade VARCHAR(5) NOT NULL,
+	scheme_id INT NOT NULL,
+	reason VARCHAR(500) NOT NULL,
+	approved_by INT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	INDEX idx_grade_corrections_score (score_id)
+)`),
+		},
+	},
+	// Guardian messaging: threads, their messages with attachments as JSON,
+	// and the last message each staff member has read
+	{
+		Version: 25,
+		Name:    "threads",
+		Changes: []Change{
+			Table("message_threads", `CREATE TABLE IF NOT EXISTS message_threads (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	scope VARCHAR(10) NOT NULL,
+	student_id INT NULL,
+	class VARCHAR(50) NOT NULL,
+	subject VARCHAR(200) NOT NULL,
+	created_by INT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	last_message_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	INDEX idx_message_threads_student (student_id),
+	INDEX idx_message_threads_class (class, last_message_at)
+)`),
+			Table("thread_messages", `CREATE TABLE IF NOT EXISTS thread_messages (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	thread_id INT NOT NULL,
+	sender_id INT NOT NULL,
+	body VARCHAR(2000) NOT NULL,
+	attachments JSON NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	INDEX idx_thread_messages_thread (thread_id, id)
+)`),
+			Table("thread_reads", `CREATE TABLE IF NOT EXISTS thread_reads (
+	thread_id INT NOT NULL,
+	teacher_id INT NOT NULL,
+	last_read_id INT NOT NULL DEFAULT 0,
+	PRIMARY KEY (thread_id, teacher_id)
+)`),
+		},
+	},
+	// Uploaded files and their virus scans; the files live in the uploads storage
+	{
+		Version: 26,
+		Name:    "uploads",
+		Changes: []Change{
+			Table("uploads", `CREATE TABLE IF NOT EXISTS uploads (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	storage_key VARCHAR(255) NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	content_type VARCHAR(100) NOT NULL,
+	size BIGINT NOT NULL,
+	uploaded_by INT NOT NULL,
+	status VARCHAR(20) NOT NULL,
+	threat VARCHAR(255) NULL,
+	scanned_at TIMESTAMP NULL,
+	reviewed_by INT NULL,
+	reviewed_at TIMESTAMP NULL,
+	review_note VARCHAR(500) NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	INDEX idx_uploads_status (status)
+)`),
+		},
+	},
+	// The classes teachers teach, on top of the one they are class teacher of
+	{
+		Version: 27,
+		Name:    "class-assignments",
+		Changes: []Change{
+			Table("teacher_classes", `CREATE TABLE IF NOT EXISTS teacher_classes (
+	teacher_id INT NOT NULL,
+	class VARCHAR(50) NOT NULL,
+	PRIMARY KEY (teacher_id, class),
+	INDEX idx_teacher_classes_class (class)
+)`),
+		},
+	},
+	// Email changes pending confirmation; only SHA-256 hashes of their tokens are kept
+	{
+		Version: 28,
+		Name:    "email-changes",
+		Changes: []Change{
+			Table("email_changes", `CREATE TABLE IF NOT EXISTS email_changes (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	teacher_id INT NOT NULL,
+	old_email VARCHAR(255) NOT NULL,
+	new_email VARCHAR(255) NOT NULL,
+	status VARCHAR(20) NOT NULL,
+	confirm_token_hash CHAR(64) NOT NULL,
+	undo_token_hash CHAR(64) NOT NULL,
+	expires_at TIMESTAMP NOT NULL,
+	undo_expires_at TIMESTAMP NOT NULL,
+	requested_by INT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	confirmed_at TIMESTAMP NULL,
+	undone_at TIMESTAMP NULL,
+	UNIQUE KEY uq_email_changes_confirm (confirm_token_hash),
+	UNIQUE KEY uq_email_changes_undo (undo_token_hash),
+	INDEX idx_email_changes_teacher (teacher_id, status)
+)`),
+		},
+	},
+	// Late arrivals and early departures recorded at kiosks, one of each kind per
+	// student and day
+	{
+		Version: 29,
+		Name:    "attendance-movements",
+		Changes: []Change{
+			Table("attendance_movements", `CREATE TABLE IF NOT EXISTS attendance_movements (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	student_id INT NOT NULL,
+	class VARCHAR(50) NOT NULL,
+	date DATE NOT NULL,
+	kind VARCHAR(20) NOT NULL,
+	reason VARCHAR(255) NULL,
+	kiosk VARCHAR(100) NOT NULL,
+	at TIMESTAMP NOT NULL,
+	UNIQUE KEY uq_attendance_movements_kind (student_id, date, kind),
+	INDEX idx_attendance_movements_date (date, at)
+)`),
+		},
+	},
+	// Who changed what, written in the same transaction as the change
+	{
+		Version: 30,
+		Name:    "audit",
+		Changes: []Change{
+			Table("audit_log", `CREATE TABLE IF NOT EXISTS audit_log (
+	id BIGINT AUTO_INCREMENT PRIMARY KEY,
+	actor_id INT NULL,
+	action VARCHAR(50) NOT NULL,
+	entity VARCHAR(50) NOT NULL,
+	entity_id INT NOT NULL,
+	details JSON NULL,
+	ip_address VARCHAR(45) NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	INDEX idx_audit_log_entity (entity, entity_id),
+	INDEX idx_audit_log_created_at (created_at)
+)`),
+		},
+	},
+	// Report card comments; subject is empty, not NULL, for a general comment so the
+	// unique key allows one per term
+	{
+		Version: 31,
+		Name:    "comments",
+		Changes: []Change{
+			Table("student_comments", `CREATE TABLE IF NOT EXISTS student_comments (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	student_id INT NOT NULL,
+	teacher_id INT NOT NULL,
+	term VARCHAR(20) NOT NULL,
+	kind VARCHAR(10) NOT NULL,
+	subject VARCHAR(100) NOT NULL DEFAULT '',
+	body VARCHAR(1000) NOT NULL,
+	flagged BOOLEAN NOT NULL DEFAULT FALSE,
+	flag_reason VARCHAR(255) NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE KEY uq_student_comments_term (student_id, term, kind, subject)
+)`),
+		},
+	},
+	// Teachers opt in to the public staff directory
+	{
+		Version: 32,
+		Name:    "directory",
+		Changes: []Change{
+			Column("teachers", "published_in_directory", "BOOLEAN NOT NULL DEFAULT FALSE"),
+		},
+	},
+	// Outgoing SMS and the delivery status the providers report back
+	{
+		Version: 33,
+		Name:    "sms",
+		Changes: []Change{
+			Table("sms_messages", `CREATE TABLE IF NOT EXISTS sms_messages (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	student_id INT NULL,
+	to_number VARCHAR(16) NOT NULL,
+	sender_id VARCHAR(20) NULL,
+	template VARCHAR(50) NOT NULL,
+	body TEXT NOT NULL,
+	provider VARCHAR(20) NOT NULL,
+	provider_message_id VARCHAR(100) NULL,
+	status VARCHAR(20) NOT NULL,
+	error TEXT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	INDEX idx_sms_messages_student (student_id),
+	INDEX idx_sms_messages_status (status),
+	INDEX idx_sms_messages_provider (provider, provider_message_id)
+)`),
+		},
+	},
+	// The school calendar: terms, holidays, exams, meetings and activities
+	{
+		Version: 34,
+		Name:    "events",
+		Changes: []Change{
+			Table("events", `CREATE TABLE IF NOT EXISTS events (
+	id INT AUTO_INCREMENT PRIMARY KEY,
+	title VARCHAR(200) NOT NULL,
+	description VARCHAR(2000) NULL,
+	type VARCHAR(20) NOT NULL,
+	start_date DATE NOT NULL,
+	end_date DATE NOT NULL,
+	audience VARCHAR(10) NOT NULL,
+	class VARCHAR(50) NULL,
+	created_by INT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+	INDEX idx_events_dates (start_date, end_date)
+)`),
+		},
+	},
+	// Who soft-deleted a teacher, for the restore list
+	{
+		Version: 35,
+		Name:    "teacher-deletions",
+		Changes: []Change{
+			Column("teachers", "deleted_by", "INT NULL"),
+		},
+	},
+	// School-issued admission numbers, which key the bulk photo import; NULL
+	// (not '') when a student has none, so the unique index lets them be
+	{
+		Version: 36,
+		Name:    "admission-numbers",
+		Changes: []Change{
+			Column("students", "admission_number", "VARCHAR(32) NULL").Unique("idx_students_admission_number (admission_number)"),
+		},
+	},
+}
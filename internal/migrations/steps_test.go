@@ -0,0 +1,49 @@
+package migrations
+
+import (
+	"simpleapi/internal/selfcheck"
+	"strings"
+	"testing"
+)
+
+func TestSteps(t *testing.T) {
+	names := map[string]bool{}
+	changes := map[string]int{}
+	for i, step := range Steps {
+		if step.Version != i+1 {
+			t.Errorf("step %q has version %d, want %d: versions run 1, 2, 3... in order", step.Name, step.Version, i+1)
+		}
+		if names[step.Name] {
+			t.Errorf("two steps are named %q", step.Name)
+		}
+		names[step.Name] = true
+		if len(step.Changes) == 0 {
+			t.Errorf("step %q changes nothing", step.Name)
+		}
+		for _, c := range step.Changes {
+			if v, ok := changes[c.String()]; ok {
+				t.Errorf("%s is added by versions %d and %d", c, v, step.Version)
+			}
+			changes[c.String()] = step.Version
+			if c.column == "" && !strings.HasPrefix(c.create, "CREATE TABLE IF NOT EXISTS "+c.table+" (") {
+				t.Errorf("%s: the statement must create that table, and only if it doesn't exist", c)
+			}
+			if c.column != "" && (c.create == "" || c.seed != "") {
+				t.Errorf("%s: a column takes a definition and no seed", c)
+			}
+		}
+	}
+
+	// The self-check sends operators to schoolctl migrate for these
+	for column := range selfcheck.RequiredColumns {
+		if _, ok := changes[column]; !ok {
+			t.Errorf("no step adds %s", column)
+		}
+	}
+	// teachers and students predate the steps
+	for _, table := range selfcheck.RequiredTables {
+		if _, ok := changes[table]; !ok && table != "teachers" && table != "students" {
+			t.Errorf("no step creates table %s", table)
+		}
+	}
+}
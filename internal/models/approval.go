@@ -0,0 +1,107 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Approval types, each registered with package approvals
+const (
+	ApprovalAbsence         = "absence"          // A staff member's leave, AbsenceRequest
+	ApprovalGradeCorrection = "grade_correction" // A correction of a posted score, GradeCorrectionRequest
+	ApprovalFeeWaiver       = "fee_waiver"       // A student's fee waiver, FeeWaiverRequest
+)
+
+// States of an approval. Only a pending one moves: to approved or rejected
+// by an approver, or to cancelled by its requester.
+const (
+	ApprovalPending   = "pending"
+	ApprovalApproved  = "approved"
+	ApprovalRejected  = "rejected"
+	ApprovalCancelled = "cancelled"
+)
+
+// ApprovalAudit is the audit action of a move to state
+func ApprovalAudit(state string) string {
+	switch state {
+	case ApprovalApproved:
+		return AuditApprovalApproved
+	case ApprovalRejected:
+		return AuditApprovalRejected
+	case ApprovalCancelled:
+		return AuditApprovalCancelled
+	}
+	return AuditApprovalReopened
+}
+
+// Approval is one row of the approvals table: a request that only takes
+// effect once a staff member with ApproverRole signs it off. Payload is the
+// type's request (AbsenceRequest, ...), as validated on submission.
+type Approval struct {
+	ID           int             `json:"id,omitempty"`
+	Type         string          `json:"type"`
+	Payload      json.RawMessage `json:"payload"`
+	RequestedBy  int             `json:"requested_by"`
+	ApproverRole string          `json:"approver_role"`
+	State        string          `json:"state"`
+	Note         string          `json:"note,omitempty"`    // The requester's
+	Comment      string          `json:"comment,omitempty"` // The decider's
+	DecidedBy    *int            `json:"decided_by,omitempty"`
+	DecidedAt    *time.Time      `json:"decided_at,omitempty"`
+	CreatedAt    time.Time       `json:"created_at"`
+	UpdatedAt    time.Time       `json:"updated_at"`
+}
+
+// ApprovalRequest is the body of POST /approvals
+type ApprovalRequest struct {
+	Type    string          `json:"type" validate:"required,oneof=absence grade_correction fee_waiver"`
+	Payload json.RawMessage `json:"payload" validate:"required"`
+	Note    string          `json:"note,omitempty" validate:"max=500"`
+}
+
+// ApprovalDecision is the body of POST /approvals/{id}/approve and /reject.
+// A rejection must say why.
+type ApprovalDecision struct {
+	Comment string `json:"comment,omitempty" validate:"max=500"`
+}
+
+// ApprovalFilter narrows approval listings
+type ApprovalFilter struct {
+	State string `query:"state" validate:"omitempty,oneof=pending approved rejected cancelled"`
+	Type  string `query:"type" validate:"omitempty,oneof=absence grade_correction fee_waiver"`
+
+	// Set by the handler, not the query string
+	RequestedBy  int    // 0 for anyone's
+	ApproverRole string // "" for every role's
+}
+
+// AbsenceRequest is the payload of an absence: the requester's leave, inclusive school-local days
+type AbsenceRequest struct {
+	From   string `json:"from" validate:"required,datetime=2006-01-02"`
+	To     string `json:"to" validate:"required,datetime=2006-01-02"`
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
+// CheckDates reports an end date before the start date
+func (a AbsenceRequest) CheckDates() []ValidationError {
+	if a.To < a.From {
+		return []ValidationError{RuleError("To", "end_before_start", "")}
+	}
+	return nil
+}
+
+// GradeCorrectionRequest is the payload of a grade correction. Once approved
+// it becomes a GradeCorrection approved by the decider.
+type GradeCorrectionRequest struct {
+	ScoreID int     `json:"score_id" validate:"required,gt=0"`
+	Score   float64 `json:"score" validate:"gte=0,lte=100"`
+	Reason  string  `json:"reason" validate:"required,max=500"`
+}
+
+// FeeWaiverRequest is the payload of a fee waiver
+type FeeWaiverRequest struct {
+	StudentID int     `json:"student_id" validate:"required,gt=0"`
+	Amount    float64 `json:"amount" validate:"gt=0"`
+	Term      string  `json:"term" validate:"required,max=20"`
+	Reason    string  `json:"reason" validate:"required,max=500"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// YearArchive is one row of the academic_year_archives table: a snapshot of an
+// academic year kept for statutory record keeping. Once done, the bundle under
+// StorageKey is never rewritten; archiving the year again makes a new one.
+type YearArchive struct {
+	ID          int        `json:"id"`
+	Year        string     `json:"year"` // As in term codes, e.g. "2025/26"
+	Status      string     `json:"status"`
+	StorageKey  string     `json:"-"`
+	Size        int64      `json:"size,omitempty"`
+	SHA256      string     `json:"sha256,omitempty"` // Of the zip, so copies can be verified later
+	Error       string     `json:"error,omitempty"`
+	CreatedBy   *int       `json:"created_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// YearSnapshot is snapshot.json inside an archive bundle
+type YearSnapshot struct {
+	Year        string         `json:"year"`
+	ArchiveID   int            `json:"archive_id"`
+	GeneratedAt time.Time      `json:"generated_at"`
+	Classes     []ArchiveClass `json:"classes"`
+	Enrollment  []Student      `json:"enrollment"`
+	Grades      []StudentScore `json:"grades"`
+}
+
+// ArchiveClass is a class as it stood when the year was archived
+type ArchiveClass struct {
+	Name     string           `json:"name"`
+	Teachers []ArchiveTeacher `json:"teachers"`
+	Students int              `json:"students"`
+}
+
+// ArchiveTeacher is the part of a teacher record worth keeping in an archive
+type ArchiveTeacher struct {
+	ID        int    `json:"id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Email     string `json:"email"`
+	Subject   string `json:"subject"`
+}
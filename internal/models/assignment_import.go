@@ -0,0 +1,42 @@
+package models
+
+// Roles a row of an assignment import gives the teacher in the class
+const (
+	AssignmentClassTeacher   = "class_teacher"   // The class becomes Teacher.Class
+	AssignmentSubjectTeacher = "subject_teacher" // The class goes into their ClassAssignments
+)
+
+// AssignmentRoles are the roles an import row accepts
+var AssignmentRoles = []string{AssignmentClassTeacher, AssignmentSubjectTeacher}
+
+// AssignmentRow is one row of POST /teachers/assignments/import
+type AssignmentRow struct {
+	TeacherEmail string
+	Class        string
+	Subject      string
+	Role         string
+}
+
+// What an import does to one teacher's classes
+const (
+	AssignmentAdded     = "added"
+	AssignmentRemoved   = "removed"
+	AssignmentHomeClass = "home_class" // Class teacher of Class instead of Previous
+)
+
+// AssignmentChange is one difference between the import and the current assignments
+type AssignmentChange struct {
+	TeacherID    int    `json:"teacher_id"`
+	TeacherEmail string `json:"teacher_email"`
+	Action       string `json:"action"`
+	Class        string `json:"class"`
+	Previous     string `json:"previous,omitempty"` // The home class being replaced
+}
+
+// AssignmentImport is the response of the import: the changes made, or on a
+// dry run the ones that would be. Teachers counts those the file lists.
+type AssignmentImport struct {
+	DryRun   bool               `json:"dry_run,omitempty"`
+	Teachers int                `json:"teachers"`
+	Changes  []AssignmentChange `json:"changes"`
+}
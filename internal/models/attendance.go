@@ -0,0 +1,187 @@
+package models
+
+import "time"
+
+// Attendance marks
+const (
+	AttendancePresent = "present"
+	AttendanceAbsent  = "absent"
+	AttendanceLate    = "late"
+)
+
+// AttendanceMark is one student's line in a register
+type AttendanceMark struct {
+	StudentID int    `json:"student_id" validate:"required,gt=0"`
+	Status    string `json:"status" validate:"required,oneof=present absent late"`
+	Note      string `json:"note,omitempty" validate:"max=255"`
+}
+
+// AttendanceRegister is a class's attendance for one school day, taken by the
+// class teacher. Submitting it again replaces the earlier marks.
+type AttendanceRegister struct {
+	Class   string           `json:"class"`
+	Date    string           `json:"date"` // DateLayout, school-local
+	Records []AttendanceMark `json:"records" validate:"required,min=1,dive"`
+	TakenBy *int             `json:"taken_by,omitempty"`
+	TakenAt time.Time        `json:"taken_at"`
+}
+
+// Absence is a day a student was marked absent
+type Absence struct {
+	StudentID int    `json:"student_id"`
+	Date      string `json:"date"`
+}
+
+// AttendanceTally counts a student's marks over a period
+type AttendanceTally struct {
+	Present int `json:"present"`
+	Late    int `json:"late"`
+	Absent  int `json:"absent"`
+}
+
+// Add counts n more marks of status
+func (t *AttendanceTally) Add(status string, n int) {
+	switch status {
+	case AttendancePresent:
+		t.Present += n
+	case AttendanceLate:
+		t.Late += n
+	case AttendanceAbsent:
+		t.Absent += n
+	}
+}
+
+// Rate is the percentage of marked days the student was in school (late counts as in)
+func (t AttendanceTally) Rate() float64 {
+	total := t.Present + t.Late + t.Absent
+	if total == 0 {
+		return 0
+	}
+	return float64(t.Present+t.Late) * 100 / float64(total)
+}
+
+// Movements recorded at the front desk, outside the register
+const (
+	MovementLateArrival    = "late_arrival"
+	MovementEarlyDeparture = "early_departure"
+)
+
+// AttendanceMovement is a student arriving late or leaving early, as checked
+// in at a kiosk. A late arrival also turns the day's absent mark into late.
+type AttendanceMovement struct {
+	ID        int       `json:"id,omitempty"`
+	StudentID int       `json:"student_id"`
+	Class     string    `json:"class"`
+	Date      string    `json:"date"` // DateLayout, school-local
+	Kind      string    `json:"kind"`
+	Reason    string    `json:"reason,omitempty"`
+	Kiosk     string    `json:"kiosk"` // Name of the API key that recorded it
+	At        time.Time `json:"at"`
+}
+
+// Operations a kiosk API key can be allowed (see KIOSK_API_KEYS)
+const (
+	KioskCheckin  = "checkin"  // Late arrivals
+	KioskCheckout = "checkout" // Early departures
+)
+
+// KioskOperations is every operation of the kiosk API
+var KioskOperations = []string{KioskCheckin, KioskCheckout}
+
+// KioskCheckinRequest is the body of POST /kiosk/checkin. Scan is what the
+// tablet read: an ID card QR payload, or an admission number typed in.
+type KioskCheckinRequest struct {
+	Scan      string `json:"scan" validate:"required,max=512"`
+	Direction string `json:"direction" validate:"required,oneof=in out"` // in: late arrival, out: early departure
+	Reason    string `json:"reason,omitempty" validate:"max=255"`
+}
+
+// Attendance alert levels, from least to most serious. A student with no
+// alert is AttendanceAlertNone.
+const (
+	AttendanceAlertNone      = ""
+	AttendanceAlertNotice    = "notice"
+	AttendanceAlertEscalated = "escalated"
+)
+
+// DefaultAttendanceThresholds are the school's until an admin sets its own
+var DefaultAttendanceThresholds = AttendanceThresholds{NoticeBelow: 90, EscalateBelow: 80, WindowDays: 28, MinDays: 5}
+
+// AttendanceThresholds are the attendance rates (Rate, in percent) below which
+// a student is at risk, edited with PUT /admin/school/attendance-thresholds.
+// A rate is taken over the WindowDays up to today, once the student has at
+// least MinDays marks in it. NoticeBelow 0 turns the alerts off.
+type AttendanceThresholds struct {
+	NoticeBelow   int `json:"notice_below" validate:"gte=0,lte=100"`
+	EscalateBelow int `json:"escalate_below" validate:"gte=0,lte=100"`
+	WindowDays    int `json:"window_days" validate:"gte=1,lte=366"`
+	MinDays       int `json:"min_days" validate:"gte=1,lte=366"`
+}
+
+// Check runs the rules the struct tags can't express
+func (t AttendanceThresholds) Check() []ValidationError {
+	if t.EscalateBelow > t.NoticeBelow {
+		return []ValidationError{RuleError("EscalateBelow", "escalate_above_notice", "")}
+	}
+	return nil
+}
+
+// Level is the alert a tally calls for: none with fewer than MinDays marks,
+// as too few days say little either way
+func (t AttendanceThresholds) Level(tally AttendanceTally) string {
+	if t.NoticeBelow == 0 || tally.Present+tally.Late+tally.Absent < t.MinDays {
+		return AttendanceAlertNone
+	}
+	switch rate := tally.Rate(); {
+	case rate < float64(t.EscalateBelow):
+		return AttendanceAlertEscalated
+	case rate < float64(t.NoticeBelow):
+		return AttendanceAlertNotice
+	}
+	return AttendanceAlertNone
+}
+
+// AlertRank orders the levels: 0 for none, then notice, then escalated
+func AlertRank(level string) int {
+	switch level {
+	case AttendanceAlertNotice:
+		return 1
+	case AttendanceAlertEscalated:
+		return 2
+	}
+	return 0
+}
+
+// AttendanceAlert is the level a student's guardian and class teacher were
+// last told of (table attendance_alerts). It is kept so that a student is
+// alerted once per level rather than on every run, and cleared once their
+// attendance recovers.
+type AttendanceAlert struct {
+	StudentID  int       `json:"student_id"`
+	Level      string    `json:"level"`
+	Rate       float64   `json:"rate"`
+	NotifiedAt time.Time `json:"notified_at"`
+}
+
+// AtRiskStudent is a row of GET /reports/attendance/at-risk
+type AtRiskStudent struct {
+	StudentID int    `json:"student_id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Class     string `json:"class"`
+	AttendanceTally
+	Rate  float64 `json:"rate"`
+	Level string  `json:"level"` // AttendanceAlertNotice or AttendanceAlertEscalated
+	// NotifiedAt is when the guardian and class teacher were alerted, nil if
+	// the scheduled job hasn't alerted them at this level yet
+	NotifiedAt *time.Time `json:"notified_at,omitempty"`
+}
+
+// AtRiskReport is the response of GET /reports/attendance/at-risk: the
+// students below a threshold over From to To (DateLayout), lowest rate first
+type AtRiskReport struct {
+	From       string               `json:"from"`
+	To         string               `json:"to"`
+	Thresholds AttendanceThresholds `json:"thresholds"`
+	Students   []AtRiskStudent      `json:"students"`
+}
@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+// AuditEntry is one row of the audit_log table.
+// ActorID is nil when the action wasn't performed by a logged-in user.
+type AuditEntry struct {
+	ID        int            `json:"id,omitempty"`
+	ActorID   *int           `json:"actor_id,omitempty"`
+	Action    string         `json:"action"`
+	Entity    string         `json:"entity"`
+	EntityID  int            `json:"entity_id"`
+	Details   map[string]any `json:"details,omitempty"`
+	IPAddress string         `json:"ip_address,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+}
+
+// Audit actions
+const (
+	AuditTeacherUpdated       = "teacher.updated"
+	AuditTeacherDeleted       = "teacher.deleted"
+	AuditClassReassigned      = "teacher.class_reassigned"
+	AuditTeacherDeactivated   = "teacher.deactivated"
+	AuditTeacherReactivated   = "teacher.reactivated"
+	AuditTeacherRestored      = "teacher.restored"
+	AuditTeacherPurged        = "teacher.purged"
+	AuditEmailChangeRequested = "teacher.email_change_requested"
+	AuditEmailChanged         = "teacher.email_changed"
+	AuditEmailChangeUndone    = "teacher.email_change_undone"
+	AuditPasswordFlagged      = "teacher.password_change_required"
+	AuditPasswordChanged      = "teacher.password_changed"
+	AuditStudentCreated       = "student.created"
+	AuditStudentPromoted      = "student.promoted"
+	AuditStudentUpdated       = "student.updated"
+	AuditStudentGraduated     = "student.graduated" // Moved to the alumni class by the year's rollover
+	AuditStudentStatusChanged = "student.status_changed"
+	AuditCustomFieldCreated   = "custom_field.created"
+	AuditCustomFieldDeleted   = "custom_field.deleted"
+	AuditRetentionPurged      = "retention.purged"
+	AuditSchoolUpdated        = "school.updated"
+	AuditReferenceUpdated     = "reference.updated"
+	AuditApprovalSubmitted    = "approval.submitted"
+	AuditApprovalApproved     = "approval.approved"
+	AuditApprovalRejected     = "approval.rejected"
+	AuditApprovalCancelled    = "approval.cancelled"
+	// AuditApprovalReopened is an approval put back to pending when carrying it out failed
+	AuditApprovalReopened = "approval.reopened"
+	AuditCampaignCreated  = "campaign.created"
+	// AuditMedicalViewed is a read of a student's medical record, which is audited like a change
+	AuditMedicalViewed   = "student.medical_viewed"
+	AuditMedicalUpdated  = "student.medical_updated"
+	AuditTripCreated     = "trip.created"
+	AuditConsentAnswered = "trip.consent_answered"
+	// Letter templates and the documents printed from them
+	AuditLetterTemplateCreated = "letter_template.created"
+	AuditLetterTemplateUpdated = "letter_template.updated"
+	AuditLetterTemplateDeleted = "letter_template.deleted"
+	AuditDocumentCreated       = "document.created"
+	// AuditAuthPrefix starts the actions of authentication events, e.g.
+	// "auth.login_failed" for metrics.AuthLoginFailed (entity "auth")
+	AuditAuthPrefix  = "auth."
+	AuditLogExported = "audit.exported"
+)
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// Backup is one row of the backups table: a logical dump of the database in
+// object storage, restorable with cmd/restore
+type Backup struct {
+	ID          int        `json:"id"`
+	Status      string     `json:"status"`
+	StorageKey  string     `json:"storage_key,omitempty"` // What to pass to cmd/restore -key
+	Size        int64      `json:"size,omitempty"`
+	SHA256      string     `json:"sha256,omitempty"`
+	Tables      int        `json:"tables,omitempty"`
+	Rows        int        `json:"rows,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	CreatedBy   *int       `json:"created_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "encoding/json"
+
+// MaxBatchRequests caps the sub-requests of one POST /batch
+const MaxBatchRequests = 50
+
+// BatchRequest is one sub-request of POST /batch. Path is relative to /api/v1
+// and may carry a query string, e.g. "/students?class=9A". ContentType is the
+// body's, application/json when left out; e.g. application/json-patch+json.
+type BatchRequest struct {
+	Method      string          `json:"method" validate:"required,oneof=GET POST PUT PATCH DELETE"`
+	Path        string          `json:"path" validate:"required,startswith=/"`
+	ContentType string          `json:"content_type,omitempty" validate:"omitempty,max=100"`
+	Body        json.RawMessage `json:"body,omitempty"`
+}
+
+// BatchResponse is the outcome of one sub-request, in the order they were sent.
+// Body is the sub-request's JSON response as is; anything else (a CSV export,
+// a photo) is a base64 string, flagged by Encoding.
+type BatchResponse struct {
+	Index    int             `json:"index"`
+	Status   int             `json:"status"`
+	Body     json.RawMessage `json:"body,omitempty"`
+	Encoding string          `json:"encoding,omitempty"`
+}
@@ -0,0 +1,76 @@
+package models
+
+import "net/http"
+
+// BulkStatus is the outcome of one item in a BulkResult
+type BulkStatus string
+
+const (
+	BulkCreated  BulkStatus = "created"
+	BulkUpdated  BulkStatus = "updated"
+	BulkDeleted  BulkStatus = "deleted"
+	BulkNotFound BulkStatus = "not_found"
+	BulkInvalid  BulkStatus = "invalid"
+	BulkConflict BulkStatus = "conflict"
+	BulkFailed   BulkStatus = "failed"
+	// BulkSkipped marks items of an all-or-nothing batch that were fine on their
+	// own but were not applied because another item failed
+	BulkSkipped BulkStatus = "skipped"
+)
+
+// BulkItem is the outcome for one element of a bulk request, in request order
+type BulkItem[T any] struct {
+	Index  int               `json:"index"`
+	ID     int               `json:"id,omitempty"`
+	Status BulkStatus        `json:"status"`
+	Code   int               `json:"code"` // The HTTP status the item would get on its own
+	Error  string            `json:"error,omitempty"`
+	Errors []ValidationError `json:"errors,omitempty"`
+	Data   *T                `json:"data,omitempty"` // The resulting entity, when there is one
+}
+
+// BulkResult is the response body of the bulk endpoints, so clients handle
+// mixed outcomes the same way everywhere. Skipped items count as failed.
+type BulkResult[T any] struct {
+	DryRun    bool          `json:"dry_run,omitempty"`
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+	Items     []BulkItem[T] `json:"items"`
+}
+
+// Add appends the next item, numbering it and updating the counts
+func (r *BulkResult[T]) Add(item BulkItem[T]) {
+	item.Index = len(r.Items)
+	r.Items = append(r.Items, item)
+	if item.Code < 300 {
+		r.Succeeded++
+	} else {
+		r.Failed++
+	}
+}
+
+// Status is the HTTP status of the whole response: success when every item
+// succeeded, 207 Multi-Status when some did, and otherwise the code of the
+// first item that failed on its own account (e.g. 404 when no ID exists)
+func (r *BulkResult[T]) Status(success int) int {
+	switch {
+	case r.Failed == 0:
+		return success
+	case r.Succeeded > 0:
+		return http.StatusMultiStatus
+	}
+	for _, item := range r.Items {
+		if item.Code != http.StatusFailedDependency {
+			return item.Code
+		}
+	}
+	return http.StatusFailedDependency
+}
+
+// ValidationReport is the answer of the bulk validate endpoints: every
+// problem the batch would be refused for, found without writing anything
+type ValidationReport struct {
+	Valid   bool              `json:"valid"`
+	Checked int               `json:"checked"` // Items in the batch
+	Errors  []ValidationError `json:"errors"`
+}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Comment kinds on a report card
+const (
+	CommentKindSubject = "subject" // Written by a subject teacher about their subject
+	CommentKindGeneral = "general" // The class teacher's overall comment, one per term
+)
+
+// StudentComment is a teacher's per-term remark on a student's report card
+type StudentComment struct {
+	ID        int    `json:"id,omitempty"`
+	StudentID int    `json:"student_id"`
+	TeacherID int    `json:"teacher_id"`
+	Term      string `json:"term" validate:"required,max=20"` // e.g. "2025/26-T1"
+	Kind      string `json:"kind" validate:"required,oneof=subject general"`
+	Subject   string `json:"subject,omitempty"`
+	Body      string `json:"body" validate:"required,max=1000"`
+
+	// --- MODERATION ---
+	// Flagged comments are hidden from the report card until an admin clears them
+	Flagged    bool   `json:"flagged"`
+	FlagReason string `json:"flag_reason,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CommentModeration is the body of PATCH /students/{id}/comments/{commentId}
+type CommentModeration struct {
+	Flagged bool   `json:"flagged"`
+	Reason  string `json:"reason" validate:"max=255"`
+}
+
+// ReportCardTerm is one term's block on the report card
+type ReportCardTerm struct {
+	Term            string           `json:"term"`
+	GeneralComment  *StudentComment  `json:"general_comment"`
+	SubjectComments []StudentComment `json:"subject_comments"`
+	Scores          []StudentScore   `json:"scores"`
+}
+
+// ReportCard aggregates everything printed on a student's report card
+type ReportCard struct {
+	Student Student          `json:"student"`
+	Terms   []ReportCardTerm `json:"terms"`
+}
@@ -0,0 +1,93 @@
+package models
+
+import "time"
+
+// Audiences of a bulk email campaign
+const (
+	AudienceStaff     = "staff"     // Active teachers, narrowed by role and class
+	AudienceStudents  = "students"  // Students, narrowed by class
+	AudienceGuardians = "guardians" // The guardians of students, narrowed by class
+)
+
+// Delivery states of a campaign recipient
+const (
+	RecipientPending = "pending"
+	RecipientSent    = "sent"
+	RecipientFailed  = "failed"
+)
+
+// RecipientFilter chooses who a campaign goes to. Class and Role are optional:
+// left out, the whole audience gets it.
+type RecipientFilter struct {
+	Audience string `json:"audience" validate:"required,oneof=staff students guardians"`
+	Class    string `json:"class,omitempty" validate:"max=50"`
+	Role     string `json:"role,omitempty" validate:"omitempty,oneof=admin teacher registrar nurse"` // Staff only
+}
+
+// Campaign is one row of the communication_campaigns table: a bulk email and
+// the filter its recipients were chosen by. Subject and Body are text/template
+// sources over CampaignMergeFields, filled in per recipient when sent. The
+// counts are tallied from its recipients.
+type Campaign struct {
+	ID          int             `json:"id"`
+	Subject     string          `json:"subject"`
+	Body        string          `json:"body"`
+	Recipients  RecipientFilter `json:"recipients"`
+	Status      string          `json:"status"` // JobPending until the queue sends it, then JobRunning, JobDone or JobFailed
+	Total       int             `json:"total"`
+	Sent        int             `json:"sent"`
+	Failed      int             `json:"failed"`
+	Error       string          `json:"error,omitempty"`
+	CreatedBy   int             `json:"created_by"`
+	CreatedAt   time.Time       `json:"created_at"`
+	CompletedAt *time.Time      `json:"completed_at,omitempty"`
+}
+
+// CampaignRecipient is one row of the communication_recipients table: an
+// address a campaign resolved to when it was created, and how sending to it went
+type CampaignRecipient struct {
+	ID         int        `json:"id"`
+	CampaignID int        `json:"campaign_id"`
+	Email      string     `json:"email"`
+	FirstName  string     `json:"first_name,omitempty"` // Empty for guardians, whose names aren't on file
+	LastName   string     `json:"last_name,omitempty"`
+	Student    string     `json:"student,omitempty"` // The student a student or guardian is emailed about
+	Class      string     `json:"class,omitempty"`
+	Status     string     `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	SentAt     *time.Time `json:"sent_at,omitempty"`
+}
+
+// CampaignMergeFields are what a campaign's templates can use, e.g.
+// "Dear {{.Name}}" or "{{.Student}} ({{.Class}})"
+type CampaignMergeFields struct {
+	Name      string // The recipient's full name, "Parent/Guardian" for guardians
+	FirstName string
+	LastName  string
+	Student   string
+	Class     string
+	School    string
+}
+
+// MergeFields are the template values of a recipient of school's campaign
+func (r CampaignRecipient) MergeFields(school string) CampaignMergeFields {
+	name := r.FirstName + " " + r.LastName
+	if r.FirstName == "" {
+		name = "Parent/Guardian"
+	}
+	return CampaignMergeFields{
+		Name:      name,
+		FirstName: r.FirstName,
+		LastName:  r.LastName,
+		Student:   r.Student,
+		Class:     r.Class,
+		School:    school,
+	}
+}
+
+// CampaignRequest is the body of POST /admin/communications
+type CampaignRequest struct {
+	Subject    string          `json:"subject" validate:"required,max=200"`
+	Body       string          `json:"body" validate:"required,max=20000"`
+	Recipients RecipientFilter `json:"recipients"`
+}
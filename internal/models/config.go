@@ -0,0 +1,76 @@
+package models
+
+import "fmt"
+
+// ConfigVersion is the layout of SchoolConfig this build reads and writes
+const ConfigVersion = 1
+
+// SchoolConfig is the school's setup as one document (GET/PUT /admin/config),
+// so a configured environment can be copied to another. It holds no IDs or
+// timestamps: entries are matched by their natural key (a scheme's subject).
+type SchoolConfig struct {
+	Version        int                   `json:"version" validate:"required"`
+	GradingSchemes []ConfigGradingScheme `json:"grading_schemes" validate:"dive"`
+}
+
+// ConfigGradingScheme is a GradingScheme as it appears in a SchoolConfig
+type ConfigGradingScheme struct {
+	Name       string          `json:"name" validate:"required,max=100"`
+	Subject    string          `json:"subject,omitempty" validate:"max=100"` // "" is the school-wide default
+	Boundaries []GradeBoundary `json:"boundaries" validate:"required,min=1,dive"`
+}
+
+// Config change actions
+const (
+	ConfigCreate = "create"
+	ConfigUpdate = "update"
+	ConfigDelete = "delete"
+)
+
+// ConfigChange is one line of the diff between the current setup and a document
+type ConfigChange struct {
+	Section string `json:"section"` // e.g. "grading_schemes"
+	Key     string `json:"key"`     // The entry's natural key
+	Action  string `json:"action"`
+	Before  any    `json:"before,omitempty"`
+	After   any    `json:"after,omitempty"`
+}
+
+// ConfigPlan is the answer to PUT /admin/config: what changed, or with
+// ?dry_run=true what would change
+type ConfigPlan struct {
+	DryRun  bool           `json:"dry_run,omitempty"`
+	Changes []ConfigChange `json:"changes"`
+}
+
+// Validate checks the document the way the individual endpoints would check its
+// entries, plus what only makes sense for the whole: one scheme per subject.
+// Like GradingScheme.Normalize it sorts each scheme's boundaries.
+func (c *SchoolConfig) Validate() []ValidationError {
+	if c.Version != ConfigVersion {
+		return []ValidationError{RuleError("Version", "config_version", fmt.Sprint(ConfigVersion))}
+	}
+	if errs := ValidateOne(c); len(errs) > 0 {
+		return errs
+	}
+
+	var errs []ValidationError
+	subjects := make(map[string]bool, len(c.GradingSchemes))
+	for i := range c.GradingSchemes {
+		idx := i
+		s := GradingScheme{Name: c.GradingSchemes[i].Name, Subject: c.GradingSchemes[i].Subject, Boundaries: c.GradingSchemes[i].Boundaries}
+		for _, e := range s.Normalize() {
+			e.Index = &idx
+			errs = append(errs, e)
+		}
+		c.GradingSchemes[i].Boundaries = s.Boundaries
+
+		if subjects[s.Subject] {
+			e := RuleError("Subject", "duplicate_scheme_subject", s.Subject)
+			e.Index = &idx
+			errs = append(errs, e)
+		}
+		subjects[s.Subject] = true
+	}
+	return errs
+}
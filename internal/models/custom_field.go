@@ -0,0 +1,151 @@
+package models
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"simpleapi/internal/expr"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Types of custom field values
+const (
+	CustomText   = "text"
+	CustomNumber = "number"
+	CustomDate   = "date" // DateLayout
+	CustomEnum   = "enum" // One of the field's Options
+)
+
+// Entities that take custom fields
+const CustomFieldStudent = "student"
+
+// MaxCustomText caps text values, which are stored in a JSON column rather than a sized one
+const MaxCustomText = 500
+
+// CustomField is an extra field an admin defined for an entity (table
+// custom_fields), for what every school records but we didn't model. Values
+// live in the entity's custom_fields JSON column, keyed by Key.
+type CustomField struct {
+	ID       int      `json:"id,omitempty"`
+	Entity   string   `json:"entity" validate:"required,oneof=student"`
+	Key      string   `json:"key" validate:"required,max=40"` // Also its name in ?filter=
+	Label    string   `json:"label" validate:"required,max=100"`
+	Type     string   `json:"type" validate:"required,oneof=text number date enum"`
+	Options  []string `json:"options,omitempty" validate:"max=50,dive,required,max=100"`
+	Required bool     `json:"required"` // Enforced on create; a required value can't be removed
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+var customFieldKey = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// Check reports the rules the struct tags can't express. builtin are the
+// entity's own fields, which a key must not shadow in ?filter=.
+func (f CustomField) Check(builtin expr.Fields) []ValidationError {
+	var errs []ValidationError
+	if !customFieldKey.MatchString(f.Key) {
+		errs = append(errs, RuleError("Key", "custom_field_key", ""))
+	} else if _, ok := builtin[f.Key]; ok {
+		errs = append(errs, RuleError("Key", "custom_field_builtin", f.Key))
+	}
+	if (f.Type == CustomEnum) != (len(f.Options) > 0) {
+		errs = append(errs, RuleError("Options", "custom_field_options", ""))
+	}
+	return errs
+}
+
+// FilterField is how ?filter= compares the field. The key is checked by
+// Check, so it's safe in the JSON path.
+func (f CustomField) FilterField() expr.Field {
+	path := "custom_fields->'$." + f.Key + "'"
+	switch f.Type {
+	case CustomNumber:
+		return expr.Field{Type: expr.Number, Column: path}
+	case CustomDate:
+		return expr.Field{Type: expr.Date, Column: "JSON_UNQUOTE(" + path + ")"}
+	}
+	return expr.Field{Type: expr.Text, Column: "JSON_UNQUOTE(" + path + ")"}
+}
+
+// CustomFilterFields adds the custom fields to an entity's ?filter= fields
+func CustomFilterFields(builtin expr.Fields, defs []CustomField) expr.Fields {
+	if len(defs) == 0 {
+		return builtin
+	}
+	fields := maps.Clone(builtin)
+	for _, d := range defs {
+		fields[d.Key] = d.FilterField()
+	}
+	return fields
+}
+
+// CheckCustomValues validates values against the entity's field definitions.
+// A nil value removes the field, as in a PATCH. onCreate also requires every
+// required field.
+func CheckCustomValues(defs []CustomField, values map[string]any, onCreate bool) []ValidationError {
+	var errs []ValidationError
+	byKey := make(map[string]CustomField, len(defs))
+	for _, d := range defs {
+		byKey[d.Key] = d
+		if d.Required {
+			if v, ok := values[d.Key]; (onCreate && !ok) || (ok && v == nil) {
+				errs = append(errs, RuleError("custom_fields."+d.Key, "required", ""))
+			}
+		}
+	}
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		d, ok := byKey[key]
+		if !ok {
+			errs = append(errs, RuleError("custom_fields."+key, "custom_field_unknown", ""))
+			continue
+		}
+		if v := values[key]; v != nil {
+			if e, bad := checkCustomValue(d, v); bad {
+				errs = append(errs, e)
+			}
+		}
+	}
+	return errs
+}
+
+func checkCustomValue(d CustomField, v any) (ValidationError, bool) {
+	field := "custom_fields." + d.Key
+	if d.Type == CustomNumber {
+		if _, ok := v.(float64); !ok {
+			return RuleError(field, "number", ""), true
+		}
+		return ValidationError{}, false
+	}
+	s, ok := v.(string)
+	switch {
+	case !ok && d.Type == CustomDate:
+		return RuleError(field, "datetime", ""), true
+	case !ok:
+		return RuleError(field, "custom_field_text", ""), true
+	case d.Type == CustomText && len(s) > MaxCustomText:
+		return RuleError(field, "max", fmt.Sprint(MaxCustomText)), true
+	case d.Type == CustomDate:
+		if _, err := time.Parse(DateLayout, s); err != nil {
+			return RuleError(field, "datetime", ""), true
+		}
+	case d.Type == CustomEnum && !slices.Contains(d.Options, s):
+		return RuleError(field, "oneof", strings.Join(d.Options, " ")), true
+	}
+	return ValidationError{}, false
+}
+
+// MergeCustomValues applies a PATCH of custom values: nil removes a field
+func MergeCustomValues(current, patch map[string]any) map[string]any {
+	merged := make(map[string]any, len(current)+len(patch))
+	maps.Copy(merged, current)
+	for k, v := range patch {
+		if v == nil {
+			delete(merged, k)
+		} else {
+			merged[k] = v
+		}
+	}
+	return merged
+}
@@ -0,0 +1,65 @@
+package models
+
+import "time"
+
+// Diagnostics is the answer of GET /admin/diagnostics: what support needs to
+// size up an installation, as seen by the instance that answers
+type Diagnostics struct {
+	Build     BuildInfo            `json:"build"`
+	StartedAt time.Time            `json:"started_at"`
+	Uptime    string               `json:"uptime"` // e.g. "72h3m0s"
+	Config    map[string]string    `json:"config"` // Settings that are set, secrets redacted
+	Database  DatabaseDiagnostics  `json:"database"`
+	Jobs      JobQueueStats        `json:"jobs"`
+	Caches    map[string]CacheStat `json:"caches"` // By cache, e.g. "reports"
+}
+
+// BuildInfo identifies the running build (GET /version). Empty fields weren't stamped.
+type BuildInfo struct {
+	Version   string `json:"version"` // "dev" when unversioned
+	Commit    string `json:"commit,omitempty"`
+	Date      string `json:"date,omitempty"`
+	Modified  bool   `json:"modified,omitempty"` // Built from a checkout with local changes
+	GoVersion string `json:"go_version"`
+}
+
+// DatabaseDiagnostics describes the database as the API sees it
+type DatabaseDiagnostics struct {
+	Driver            string             `json:"driver"`
+	PingMS            *float64           `json:"ping_ms,omitempty"` // nil without MySQL, or when the ping failed
+	PingError         string             `json:"ping_error,omitempty"`
+	Latency           LatencySummary     `json:"latency"`                // Of recent repository queries
+	PendingMigrations []PendingMigration `json:"pending_migrations"`     // null when the schema wasn't inspected
+	SchemaError       string             `json:"schema_error,omitempty"` // The schema couldn't be inspected
+}
+
+// LatencySummary gives percentiles of recent durations, in milliseconds
+type LatencySummary struct {
+	Samples int     `json:"samples"`
+	P50     float64 `json:"p50_ms"`
+	P90     float64 `json:"p90_ms"`
+	P99     float64 `json:"p99_ms"`
+	Max     float64 `json:"max_ms"`
+}
+
+// PendingMigration is a part of the schema the API needs that is missing
+type PendingMigration struct {
+	Missing string `json:"missing"`
+	Fix     string `json:"fix"`
+}
+
+// JobQueueStats sizes up the job queue: jobs waiting for a worker, and the
+// jobs it remembers by status
+type JobQueueStats struct {
+	Depth    int            `json:"depth"`
+	Capacity int            `json:"capacity"`
+	Workers  int            `json:"workers"`
+	ByStatus map[string]int `json:"by_status"`
+}
+
+// CacheStat counts lookups in a cache since the process started
+type CacheStat struct {
+	Hits    int64   `json:"hits"`
+	Misses  int64   `json:"misses"`
+	HitRate float64 `json:"hit_rate"` // Hits over lookups, 0 before the first
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// DownloadPayloadType tells a download link apart from other documents signed with the same key
+const DownloadPayloadType = "download"
+
+// What a download link fetches
+const (
+	DownloadPhoto      = "photo"      // A student's photo; ID is the student's
+	DownloadAttachment = "attachment" // A file attached to a thread message; ID is its upload's, 0 if it has none
+)
+
+// A download link lasts DownloadLinkTTL unless ?expires_in asks otherwise, up
+// to DownloadLinkMaxTTL: long enough for an email to be read, not to be archived
+const (
+	DownloadLinkTTL    = time.Hour
+	DownloadLinkMaxTTL = 7 * 24 * time.Hour
+)
+
+// DownloadClaims is what a download link carries, signed: the file's storage
+// key and how to serve it, so following the link looks nothing up
+type DownloadClaims struct {
+	Type        string `json:"typ"`
+	Kind        string `json:"knd"`
+	ID          int    `json:"oid"`
+	Key         string `json:"key"`
+	Name        string `json:"name,omitempty"` // Offered as the filename; photos have none and open inline
+	ContentType string `json:"ct"`
+	ExpiresAt   int64  `json:"exp"` // Unix seconds
+}
+
+// DownloadLink is the answer of the .../link endpoints. Path is under the
+// API's origin and needs no Authorization header.
+type DownloadLink struct {
+	Path      string    `json:"path"`
+	ExpiresAt time.Time `json:"expires_at"`
+	KeyID     string    `json:"key_id"`
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"fmt"
+	"simpleapi/pkg/errcodes"
+	"time"
+)
+
+// States of an email change
+const (
+	EmailChangePending   = "pending"   // Waiting for the new address to confirm
+	EmailChangeConfirmed = "confirmed" // Applied; the old address can still undo it until UndoExpiresAt
+	EmailChangeCancelled = "cancelled" // Replaced by a later request, or stopped from the old address
+	EmailChangeUndone    = "undone"    // Reverted from the old address after it was applied
+)
+
+// How long the links in the two emails work
+const (
+	EmailChangeConfirmTTL = 24 * time.Hour
+	EmailChangeUndoTTL    = 7 * 24 * time.Hour // From the request, so a confirmed change stays undoable for days
+)
+
+// EmailChange is one row of the email_changes table. The confirm link goes to
+// NewEmail, the undo link to OldEmail; only SHA-256 hashes of their tokens are stored.
+type EmailChange struct {
+	ID               int        `json:"id,omitempty"`
+	TeacherID        int        `json:"teacher_id"`
+	OldEmail         string     `json:"old_email"`
+	NewEmail         string     `json:"new_email"`
+	Status           string     `json:"status"`
+	ConfirmTokenHash string     `json:"-"`
+	UndoTokenHash    string     `json:"-"`
+	ExpiresAt        time.Time  `json:"expires_at"`
+	UndoExpiresAt    time.Time  `json:"undo_expires_at"`
+	RequestedBy      *int       `json:"requested_by,omitempty"`
+	CreatedAt        time.Time  `json:"created_at"`
+	ConfirmedAt      *time.Time `json:"confirmed_at,omitempty"`
+	UndoneAt         *time.Time `json:"undone_at,omitempty"`
+}
+
+// EmailChangeRequest is the body of POST /teachers/{id}/email-change
+type EmailChangeRequest struct {
+	Email string `json:"email" validate:"required,email,max=255"`
+}
+
+// EmailChangeToken is the body of POST /email-change/confirm and /email-change/undo:
+// the token from the link in the email
+type EmailChangeToken struct {
+	Token string `json:"token" validate:"required,max=100"`
+}
+
+var (
+	// ErrEmailChangeRequired rejects an email edit through PATCH or PUT
+	ErrEmailChangeRequired error = &CodedError{Code: errcodes.EmailChangeRequired,
+		Err: fmt.Errorf("email can't be edited directly, request a change with POST /teachers/{id}/email-change: %w", ErrInvalidInput)}
+	// ErrEmailChangeLinkInvalid is a confirm or undo token that is unknown, expired or used
+	ErrEmailChangeLinkInvalid error = &CodedError{Code: errcodes.EmailChangeLinkInvalid,
+		Err: fmt.Errorf("email change link is invalid, expired or already used: %w", ErrNotFound)}
+)
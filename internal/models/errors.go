@@ -2,6 +2,8 @@ package models
 
 import (
 	"errors"
+	"fmt"
+	"simpleapi/pkg/errcodes"
 )
 
 var (
@@ -17,6 +19,96 @@ var (
 	// 401: Authentication failed
 	ErrUnauthorized = errors.New("unauthorized")
 
+	// 403: Authenticated, but not allowed to touch this resource
+	ErrForbidden = errors.New("forbidden")
+
 	// 500: Explicit system failure (optional, usually implied by unknown errors)
 	ErrInternal = errors.New("internal system error")
 )
+
+// Not-found errors with a specific code; errors.Is(err, ErrNotFound) still holds
+var (
+	ErrTeacherNotFound error = &CodedError{Code: errcodes.TeacherNotFound, Err: ErrNotFound}
+	ErrStudentNotFound error = &CodedError{Code: errcodes.StudentNotFound, Err: ErrNotFound}
+)
+
+// CodedError gives a domain error a more specific code than its status's
+// generic one (see package errcodes)
+type CodedError struct {
+	Code errcodes.Code
+	Err  error
+}
+
+func (e *CodedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *CodedError) Unwrap() error {
+	return e.Err
+}
+
+func (e *CodedError) ErrorCode() errcodes.Code {
+	return e.Code
+}
+
+// ConflictError is a 409 that knows which unique field clashed.
+// errors.Is(err, ErrConflict) still holds, so existing checks keep working.
+type ConflictError struct {
+	Field string `json:"field"`
+	Value string `json:"value"`
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s '%s' already exists", e.Field, e.Value)
+}
+
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// ErrorCode names the clash for unique fields clients commonly handle
+func (e *ConflictError) ErrorCode() errcodes.Code {
+	switch e.Field {
+	case "email":
+		return errcodes.EmailTaken
+	case "admission_number":
+		return errcodes.AdmissionNumberTaken
+	}
+	return errcodes.Conflict
+}
+
+// DependencyError is a 409 raised when an operation would orphan dependent records
+// (e.g. deleting the only teacher of a class that still has students)
+type DependencyError struct {
+	Reason   string `json:"reason"`
+	Resource string `json:"resource"`
+	Count    int    `json:"count"`
+}
+
+func (e *DependencyError) Error() string {
+	return e.Reason
+}
+
+func (e *DependencyError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+func (e *DependencyError) ErrorCode() errcodes.Code {
+	return errcodes.HasDependents
+}
+
+// ItemError pins a bulk failure on one element of the batch (its position in
+// the request), so handlers can report it per item. errors.Is/As see through it.
+type ItemError struct {
+	Index int
+	ID    int // 0 when the element has no ID yet, e.g. on create
+	Err   error
+}
+
+func (e *ItemError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ItemError) Unwrap() error {
+	return e.Err
+}
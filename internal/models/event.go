@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// DateLayout is the wire and storage format of calendar dates (no time of day)
+const DateLayout = "2006-01-02"
+
+// Event types
+const (
+	EventTerm     = "term"     // Term start/end, half term
+	EventHoliday  = "holiday"  // Public or school holiday
+	EventExam     = "exam"     // Exam period
+	EventMeeting  = "meeting"  // Parents' evening, PTA meeting
+	EventActivity = "activity" // Sports day, excursions, clubs
+)
+
+// Event audiences
+const (
+	AudienceSchool = "school" // Everyone
+	AudienceClass  = "class"  // Only the class named in Event.Class
+)
+
+// Event is an entry on the school calendar. Dates are inclusive school-local days.
+type Event struct {
+	ID          int    `json:"id,omitempty"`
+	Title       string `json:"title" validate:"required,max=200"`
+	Description string `json:"description,omitempty" validate:"max=2000"`
+	Type        string `json:"type" validate:"required,oneof=term holiday exam meeting activity"`
+	StartDate   string `json:"start_date" validate:"required,datetime=2006-01-02"`
+	EndDate     string `json:"end_date" validate:"required,datetime=2006-01-02"`
+	Audience    string `json:"audience" validate:"required,oneof=school class"`
+	Class       string `json:"class,omitempty" validate:"required_if=Audience class,max=50"`
+
+	CreatedBy *int      `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CheckDates reports an end date before the start date (the tags only check the format)
+func (e Event) CheckDates() []ValidationError {
+	if e.EndDate < e.StartDate { // ISO dates compare correctly as strings
+		return []ValidationError{RuleError("EndDate", "end_before_start", "")}
+	}
+	return nil
+}
+
+// EventFilter narrows event listings. From/To select events overlapping that range.
+type EventFilter struct {
+	From  string `query:"from" validate:"omitempty,datetime=2006-01-02"`
+	To    string `query:"to" validate:"omitempty,datetime=2006-01-02"`
+	Type  string `query:"type"`
+	Class string `query:"class"` // School-wide events plus this class's events
+}
@@ -0,0 +1,133 @@
+package models
+
+import "time"
+
+// Seating orders of POST /exams/sessions/{id}/seating
+const (
+	SeatingAlphabetical = "alphabetical" // By last name, then first name
+	SeatingRandom       = "random"
+)
+
+// ExamRoom is a room an exam session sits in. Its seats are numbered from 1,
+// row by row, Columns to a row: two students are neighbours when they sit
+// side by side or one behind the other.
+type ExamRoom struct {
+	Name     string `json:"name" validate:"required,max=50"`
+	Capacity int    `json:"capacity" validate:"gte=1,lte=1000"`
+	Columns  int    `json:"columns,omitempty" validate:"gte=0,lte=100"` // Seats per row; 0 is a single row
+	// Invigilators is how many teachers watch the room; 0 is 1
+	Invigilators int `json:"invigilators,omitempty" validate:"gte=0,lte=10"`
+}
+
+// Width is the room's seats per row
+func (r ExamRoom) Width() int {
+	if r.Columns <= 0 || r.Columns > r.Capacity {
+		return r.Capacity
+	}
+	return r.Columns
+}
+
+// Needs is how many invigilators the room needs
+func (r ExamRoom) Needs() int {
+	return max(r.Invigilators, 1)
+}
+
+// ExamSession is one row of the exam_sessions table: a paper sat at one time
+// by every student of Classes, across Rooms. Seats and Invigilators are empty
+// until generated, and cleared when the session changes.
+type ExamSession struct {
+	ID        int        `json:"id"`
+	Title     string     `json:"title"`
+	Subject   string     `json:"subject,omitempty"`
+	Date      string     `json:"date"`       // DateLayout, school-local
+	StartTime string     `json:"start_time"` // HH:MM
+	EndTime   string     `json:"end_time"`   // HH:MM
+	Classes   []string   `json:"classes"`
+	Rooms     []ExamRoom `json:"rooms"`
+	// Seating is how Seats were generated, nil before they are
+	Seating      *ExamSeating      `json:"seating,omitempty"`
+	Seats        []ExamSeat        `json:"seats"`
+	Invigilators []ExamInvigilator `json:"invigilators"`
+	CreatedBy    *int              `json:"created_by,omitempty"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+}
+
+// Overlaps reports whether two sessions are on at the same time
+func (s ExamSession) Overlaps(o ExamSession) bool {
+	// HH:MM compares correctly as strings
+	return s.Date == o.Date && s.StartTime < o.EndTime && o.StartTime < s.EndTime
+}
+
+// ExamSeat is a student's place in an exam session
+type ExamSeat struct {
+	Room            string `json:"room"`
+	Seat            int    `json:"seat"`
+	Row             int    `json:"row"`
+	Column          int    `json:"column"`
+	StudentID       int    `json:"student_id"`
+	FirstName       string `json:"first_name"`
+	LastName        string `json:"last_name"`
+	Class           string `json:"class"`
+	AdmissionNumber string `json:"admission_number,omitempty"`
+}
+
+// ExamInvigilator is a teacher watching a room of an exam session
+type ExamInvigilator struct {
+	Room      string `json:"room"`
+	TeacherID int    `json:"teacher_id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+}
+
+// ExamSessionRequest is the body of POST /exams/sessions and PUT /exams/sessions/{id}
+type ExamSessionRequest struct {
+	Title     string     `json:"title" validate:"required,max=150"`
+	Subject   string     `json:"subject" validate:"max=100"`
+	Date      string     `json:"date" validate:"required,datetime=2006-01-02"`
+	StartTime string     `json:"start_time" validate:"required,datetime=15:04"`
+	EndTime   string     `json:"end_time" validate:"required,datetime=15:04"`
+	Classes   []string   `json:"classes" validate:"required,min=1,max=50,dive,required,max=50"`
+	Rooms     []ExamRoom `json:"rooms" validate:"required,min=1,max=100,dive"`
+}
+
+// Check runs the rules the struct tags can't express
+func (req ExamSessionRequest) Check() []ValidationError {
+	var errs []ValidationError
+	if req.EndTime <= req.StartTime {
+		errs = append(errs, RuleError("EndTime", "exam_end_time", ""))
+	}
+	seen := make(map[string]bool)
+	for _, room := range req.Rooms {
+		if seen[room.Name] {
+			errs = append(errs, RuleError("Rooms", "duplicate_room", room.Name))
+		}
+		seen[room.Name] = true
+	}
+	return errs
+}
+
+// ExamSeatingRequest is the body of POST /exams/sessions/{id}/seating.
+// SeparateClasses keeps students of the same class from sitting next to or
+// behind one another wherever the mix of classes allows it.
+type ExamSeatingRequest struct {
+	Order           string `json:"order" validate:"omitempty,oneof=alphabetical random"` // Default alphabetical
+	SeparateClasses bool   `json:"separate_classes"`
+	// Seed makes a random order repeatable; 0 draws one, which the session records
+	Seed int64 `json:"seed,omitempty"`
+}
+
+// ExamSeating is how a session's seats were generated. Conflicts counts the
+// students seated beside or behind a classmate all the same, when
+// SeparateClasses couldn't be met.
+type ExamSeating struct {
+	ExamSeatingRequest
+	Conflicts   int       `json:"conflicts"`
+	GeneratedAt time.Time `json:"generated_at"`
+}
+
+// ExamInvigilationRequest is the body of POST /exams/sessions/{id}/invigilators
+type ExamInvigilationRequest struct {
+	// Exclude are teachers not to assign, e.g. those on leave
+	Exclude []int `json:"exclude,omitempty" validate:"max=500"`
+}
@@ -0,0 +1,158 @@
+package models
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// GradeBoundary is one rung of a grading scheme: scores at or above MinScore earn Grade.
+// Points is the grade's weight for GPA (e.g. A = 4.0); leave it 0 if the school doesn't use GPA.
+type GradeBoundary struct {
+	Grade    string  `json:"grade" validate:"required,max=5"`
+	MinScore float64 `json:"min_score" validate:"gte=0,lte=100"`
+	Points   float64 `json:"points,omitempty" validate:"gte=0,lte=10"`
+}
+
+// GradingScheme maps raw scores (0-100) to letter grades, e.g. A >= 70, B >= 60 ...
+// Subject "" is the school-wide default; a subject scheme overrides it for that subject.
+type GradingScheme struct {
+	ID         int             `json:"id,omitempty"`
+	Name       string          `json:"name" validate:"required,max=100"`
+	Subject    string          `json:"subject,omitempty" validate:"max=100"`
+	Boundaries []GradeBoundary `json:"boundaries" validate:"required,min=1,dive"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Normalize sorts the boundaries from highest to lowest and reports rule violations
+// the struct tags can't express. The lowest boundary must be 0 so every score gets a grade.
+func (s *GradingScheme) Normalize() []ValidationError {
+	sort.SliceStable(s.Boundaries, func(i, j int) bool {
+		return s.Boundaries[i].MinScore > s.Boundaries[j].MinScore
+	})
+
+	var errs []ValidationError
+	grades := make(map[string]bool, len(s.Boundaries))
+	for i, b := range s.Boundaries {
+		key := strings.ToUpper(b.Grade)
+		if grades[key] {
+			errs = append(errs, RuleError("Grade", "duplicate_grade", b.Grade))
+		}
+		grades[key] = true
+		if i > 0 && s.Boundaries[i-1].MinScore == b.MinScore {
+			errs = append(errs, RuleError("MinScore", "duplicate_min_score", ""))
+		}
+	}
+	if n := len(s.Boundaries); n > 0 && s.Boundaries[n-1].MinScore != 0 {
+		errs = append(errs, RuleError("MinScore", "lowest_grade_not_zero", ""))
+	}
+	return errs
+}
+
+// GradeFor returns the letter grade for a raw score. Boundaries must be normalized.
+func (s GradingScheme) GradeFor(score float64) string {
+	for _, b := range s.Boundaries {
+		if score >= b.MinScore {
+			return b.Grade
+		}
+	}
+	return ""
+}
+
+// PointsFor returns the GPA points for a raw score. Boundaries must be normalized.
+func (s GradingScheme) PointsFor(score float64) float64 {
+	for _, b := range s.Boundaries {
+		if score >= b.MinScore {
+			return b.Points
+		}
+	}
+	return 0
+}
+
+// UsesPoints reports whether the scheme defines GPA points at all
+func (s GradingScheme) UsesPoints() bool {
+	for _, b := range s.Boundaries {
+		if b.Points > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// StudentScore is a raw score in one subject for one term.
+// Grade and SchemeID are filled in by the server from the applicable grading scheme.
+// Once posted a score is never rewritten: stores return its effective value, i.e.
+// the latest GradeCorrection if there is one, and UpdatedAt is when that was made.
+type StudentScore struct {
+	ID        int     `json:"id,omitempty"`
+	StudentID int     `json:"student_id"`
+	TeacherID int     `json:"teacher_id"`
+	Term      string  `json:"term" validate:"required,max=20"` // e.g. "2025/26-T1"
+	Subject   string  `json:"subject"`
+	Score     float64 `json:"score" validate:"gte=0,lte=100"`
+	Grade     string  `json:"grade"`
+	SchemeID  int     `json:"scheme_id"`
+	// Corrections counts the corrections made to the score (GET /grades/{id}/corrections)
+	Corrections int `json:"corrections"`
+
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GradeCorrection is an append-only amendment of a posted score (table
+// grade_corrections). The original row and earlier corrections stay as they
+// were, so the exam board can see every value a grade has had, why, and who
+// approved it. Grade and SchemeID are computed like a score's.
+type GradeCorrection struct {
+	ID         int       `json:"id,omitempty"`
+	ScoreID    int       `json:"score_id"`
+	Score      float64   `json:"score" validate:"gte=0,lte=100"`
+	Grade      string    `json:"grade"`
+	SchemeID   int       `json:"scheme_id"`
+	Reason     string    `json:"reason" validate:"required,max=500"`
+	ApprovedBy int       `json:"approved_by"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ClassGradeStats is GET /classes/{class}/grade-stats: how the students now in
+// a class did in one subject and term. The figures are of effective scores, so
+// corrections count; they are all 0 when nobody has a score yet.
+type ClassGradeStats struct {
+	Class   string  `json:"class"`
+	Subject string  `json:"subject"`
+	Term    string  `json:"term"`
+	Scores  int     `json:"scores"` // How many students have a score
+	Min     float64 `json:"min"`
+	Max     float64 `json:"max"`
+	Mean    float64 `json:"mean"`
+	Median  float64 `json:"median"`
+	StdDev  float64 `json:"std_dev"` // Of the class as a whole (population)
+
+	Distribution []GradeTally  `json:"distribution"`
+	Students     []StudentRank `json:"students"` // Best first
+}
+
+// GradeTally is how many scores got a grade
+type GradeTally struct {
+	Grade  string `json:"grade"`
+	Scores int    `json:"scores"`
+}
+
+// StudentRank is a student's place in their class. Equal scores share a rank
+// and the next rank skips accordingly: 1, 2, 2, 4.
+type StudentRank struct {
+	StudentID int     `json:"student_id"`
+	FirstName string  `json:"first_name"`
+	LastName  string  `json:"last_name"`
+	Score     float64 `json:"score"`
+	Grade     string  `json:"grade"`
+	Rank      int     `json:"rank"`
+}
+
+// ClassGradeStatsQuery is the query string of GET /classes/{class}/grade-stats.
+// The subject defaults to the teacher's own.
+type ClassGradeStatsQuery struct {
+	Subject string `query:"subject" validate:"max=100"`
+	Term    string `query:"term" validate:"required,max=20"`
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// HistoryEntry is one step of an entity's change timeline. Field is empty for
+// lifecycle events (deleted, restored ...) that don't change a single field.
+type HistoryEntry struct {
+	Action  string    `json:"action"`
+	Field   string    `json:"field,omitempty"`
+	Old     string    `json:"old"`
+	New     string    `json:"new"`
+	ActorID *int      `json:"actor_id,omitempty"`
+	At      time.Time `json:"at"`
+}
+
+// HistoryFromAudit rebuilds the field-level timeline from an entity's audit entries,
+// which must be in chronological order. An entry touching several fields yields
+// one HistoryEntry per field, sorted by field name.
+func HistoryFromAudit(entries []AuditEntry) []HistoryEntry {
+	history := make([]HistoryEntry, 0, len(entries))
+	for _, e := range entries {
+		step := HistoryEntry{Action: e.Action, ActorID: e.ActorID, At: e.CreatedAt}
+
+		switch e.Action {
+		case AuditTeacherUpdated, AuditStudentCreated, AuditEmailChanged:
+			for _, field := range changesFromDetails(e.Details) {
+				history = append(history, field.entry(step))
+			}
+			continue
+		case AuditEmailChangeUndone:
+			// A pending change that was stopped never touched the teacher
+			if changes := changesFromDetails(e.Details); len(changes) > 0 {
+				history = append(history, changes[0].entry(step))
+			}
+			continue
+		case AuditClassReassigned:
+			step.Field = "class"
+			step.Old = detailString(e.Details, "previous_class")
+			step.New = detailString(e.Details, "class")
+		case AuditTeacherDeactivated:
+			step.Field, step.Old, step.New = "is_active", "true", "false"
+		case AuditTeacherReactivated:
+			step.Field, step.Old, step.New = "is_active", "false", "true"
+		}
+		history = append(history, step)
+	}
+	return history
+}
+
+type namedChange struct {
+	name string
+	FieldChange
+}
+
+func (c namedChange) entry(step HistoryEntry) HistoryEntry {
+	step.Field, step.Old, step.New = c.name, c.From, c.To
+	return step
+}
+
+// changesFromDetails reads details["changes"]. A JSON round trip handles both the
+// typed map the in-memory store keeps and the generic one decoded from MySQL.
+func changesFromDetails(details map[string]any) []namedChange {
+	raw, err := json.Marshal(details["changes"])
+	if err != nil {
+		return nil
+	}
+	var changes map[string]FieldChange
+	if err := json.Unmarshal(raw, &changes); err != nil {
+		return nil
+	}
+
+	named := make([]namedChange, 0, len(changes))
+	for name, c := range changes {
+		named = append(named, namedChange{name, c})
+	}
+	sort.Slice(named, func(i, j int) bool { return named[i].name < named[j].name })
+	return named
+}
+
+func detailString(details map[string]any, key string) string {
+	if v, ok := details[key]; ok && v != nil {
+		return fmt.Sprint(v)
+	}
+	return ""
+}
@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+// IDCardValidity is how long a printed card's QR code verifies
+const IDCardValidity = 365 * 24 * time.Hour
+
+// IDCardPayloadType tells a card payload apart from other documents signed with the same key
+const IDCardPayloadType = "idcard"
+
+// Why GET /verify-card turned a card down
+const (
+	IDCardMalformed      = "malformed"         // Not a card payload at all
+	IDCardBadSignature   = "invalid_signature" // Forged, altered, or signed with a retired key
+	IDCardExpired        = "expired"
+	IDCardUnknownStudent = "unknown_student" // The student has since been removed
+)
+
+// IDCardClaims is what the QR code carries, signed: enough to verify a card
+// without looking anything up, so scanners can cache answers when offline
+type IDCardClaims struct {
+	Type      string `json:"typ"`
+	StudentID int    `json:"sid"`
+	ExpiresAt int64  `json:"exp"` // Unix seconds
+}
+
+// IDCardHolder is the student a card belongs to, as printed on it
+type IDCardHolder struct {
+	StudentID       int    `json:"student_id"`
+	AdmissionNumber string `json:"admission_number,omitempty"`
+	FirstName       string `json:"first_name"`
+	LastName        string `json:"last_name"`
+	Class           string `json:"class"`
+}
+
+// NewIDCardHolder picks the printed fields of a student
+func NewIDCardHolder(s Student) IDCardHolder {
+	return IDCardHolder{StudentID: s.ID, AdmissionNumber: s.AdmissionNumber, FirstName: s.FirstName, LastName: s.LastName, Class: s.Class}
+}
+
+// IDCard is everything printed on a student's ID card. QRPayload goes into
+// the QR code as is: base64url claims JSON, a dot, and its base64url HMAC-SHA256.
+type IDCard struct {
+	IDCardHolder
+	SchoolName string    `json:"school_name,omitempty"`
+	IssuedAt   time.Time `json:"issued_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	QRPayload  string    `json:"qr_payload"`
+	KeyID      string    `json:"key_id"`
+}
+
+// IDCardVerification is the answer of GET /verify-card. Student is only set
+// for a valid card, so the guard can compare name and class with the holder.
+type IDCardVerification struct {
+	Valid     bool          `json:"valid"`
+	Reason    string        `json:"reason,omitempty"`
+	StudentID int           `json:"student_id,omitempty"`
+	ExpiresAt *time.Time    `json:"expires_at,omitempty"`
+	Student   *IDCardHolder `json:"student,omitempty"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import "time"
+
+// States of work done by a background job (archives, backups). It starts pending
+// until a worker of the job queue picks it up.
+const (
+	JobPending = "pending"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobFailed  = "failed"
+	// JobDead is a queued job out of attempts: it stays dead-lettered until an
+	// admin retries it (POST /admin/jobs/{id}/retry)
+	JobDead = "dead"
+)
+
+// JobRecord is what the job queue remembers of a job, for GET /admin/jobs.
+// A failed job is waiting for its next attempt at RetryAt.
+type JobRecord struct {
+	ID          int          `json:"id"`
+	Name        string       `json:"name"`
+	Status      string       `json:"status"`
+	Attempts    int          `json:"attempts"`
+	MaxAttempts int          `json:"max_attempts"`
+	Retryable   bool         `json:"retryable"`
+	Errors      []JobAttempt `json:"errors,omitempty"`
+	RetryAt     *time.Time   `json:"retry_at,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+// JobAttempt is the error of one failed attempt
+type JobAttempt struct {
+	Attempt int       `json:"attempt"`
+	Error   string    `json:"error"`
+	At      time.Time `json:"at"`
+}
@@ -0,0 +1,96 @@
+package models
+
+import "time"
+
+// Kinds of letter templates, for sorting them in the admin screens
+const (
+	LetterAdmission    = "admission"
+	LetterFeeReminder  = "fee_reminder"
+	LetterDisciplinary = "disciplinary"
+	LetterOther        = "other"
+)
+
+// LetterTemplate is one row of the letter_templates table: a letter admins
+// write once and print for any student. Subject and Body are Go templates
+// over LetterMergeFields, e.g. "Dear parent of {{.Student}}".
+type LetterTemplate struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Kind      string    `json:"kind"`
+	Subject   string    `json:"subject"`
+	Body      string    `json:"body"`
+	CreatedBy *int      `json:"created_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// LetterTemplateRequest is the body of POST /admin/letter-templates and
+// PUT /admin/letter-templates/{id}
+type LetterTemplateRequest struct {
+	Name    string `json:"name" validate:"required,max=100"`
+	Kind    string `json:"kind" validate:"required,oneof=admission fee_reminder disciplinary other"`
+	Subject string `json:"subject" validate:"required,max=200"`
+	Body    string `json:"body" validate:"required,max=20000"`
+}
+
+// LetterMergeFields are the values a letter template can use. A field
+// outside them fails the template when it is saved.
+type LetterMergeFields struct {
+	Student         string // First and last name
+	FirstName       string
+	LastName        string
+	Class           string
+	AdmissionNumber string
+	Status          string // The student's lifecycle state, e.g. "enrolled"
+	EnrollmentDate  string // DateLayout, "" when not recorded
+	Date            string // The day the letter is printed, DateLayout
+	School          string
+	SchoolAddress   string
+}
+
+// StudentLetterFields are the merge fields of a letter to s, printed on day
+func StudentLetterFields(s Student, school School, day string) LetterMergeFields {
+	return LetterMergeFields{
+		Student:         s.FirstName + " " + s.LastName,
+		FirstName:       s.FirstName,
+		LastName:        s.LastName,
+		Class:           s.Class,
+		AdmissionNumber: s.AdmissionNumber,
+		Status:          s.Status,
+		EnrollmentDate:  s.EnrollmentDate,
+		Date:            day,
+		School:          school.Name,
+		SchoolAddress:   school.Address,
+	}
+}
+
+// Formats of a rendered letter
+const (
+	DocumentPDF  = "pdf"
+	DocumentHTML = "html"
+)
+
+// Document is one row of the documents table: a file generated for a
+// student, such as a letter, kept in the uploads storage under StorageKey
+type Document struct {
+	ID          int        `json:"id"`
+	StudentID   int        `json:"student_id"`
+	TemplateID  *int       `json:"template_id,omitempty"` // nil once the template is deleted
+	Title       string     `json:"title"`
+	Format      string     `json:"format"`
+	ContentType string     `json:"content_type"`
+	Size        int64      `json:"size"`
+	StorageKey  string     `json:"-"`
+	EmailedTo   string     `json:"emailed_to,omitempty"`
+	EmailedAt   *time.Time `json:"emailed_at,omitempty"`
+	CreatedBy   *int       `json:"created_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// LetterRequest is the body of POST /students/{id}/letters. Email sends the
+// letter to the student's address as well, where guardians read the school's mail.
+type LetterRequest struct {
+	TemplateID int    `json:"template_id" validate:"required,gte=1"`
+	Format     string `json:"format" validate:"omitempty,oneof=pdf html"` // Default pdf
+	Email      bool   `json:"email"`
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"slices"
+	"time"
+)
+
+// MedicalRecord is one row of the student_medical table: what staff need to
+// know to keep a student safe. Only admins, nurses and the student's class
+// teacher read it, every read is audited, and notes are for admins and nurses
+// alone (see package redact).
+type MedicalRecord struct {
+	StudentID         int                `json:"student_id"`
+	Allergies         string             `json:"allergies,omitempty" validate:"max=1000"`
+	Conditions        string             `json:"conditions,omitempty" validate:"max=1000"`
+	Medications       string             `json:"medications,omitempty" validate:"max=1000"`
+	Notes             string             `json:"notes,omitempty" validate:"max=2000" visibility:"admin,nurse"`
+	EmergencyContacts []EmergencyContact `json:"emergency_contacts" validate:"max=5,dive"`
+	UpdatedBy         *int               `json:"updated_by,omitempty"`
+	UpdatedAt         time.Time          `json:"updated_at"`
+}
+
+// EmergencyContact is who to call about a student, in the order listed
+type EmergencyContact struct {
+	Name         string `json:"name" validate:"required,max=100"`
+	Relationship string `json:"relationship,omitempty" validate:"max=50"`
+	Phone        string `json:"phone" validate:"required,phone"` // Stored in E.164
+}
+
+// NormalizePhones stores the contacts' phones in E.164, as validation accepted them
+func (m *MedicalRecord) NormalizePhones() {
+	for i, c := range m.EmergencyContacts {
+		if phone, err := ParsePhone(c.Phone); err == nil {
+			m.EmergencyContacts[i].Phone = phone
+		}
+	}
+}
+
+// MedicalChanges names the fields that differ from before to after, for the
+// audit log, which must not hold the values themselves
+func MedicalChanges(before, after MedicalRecord) []string {
+	changed := make([]string, 0, 5)
+	for _, f := range []struct {
+		name string
+		same bool
+	}{
+		{"allergies", before.Allergies == after.Allergies},
+		{"conditions", before.Conditions == after.Conditions},
+		{"medications", before.Medications == after.Medications},
+		{"notes", before.Notes == after.Notes},
+		{"emergency_contacts", slices.Equal(before.EmergencyContacts, after.EmergencyContacts)},
+	} {
+		if !f.same {
+			changed = append(changed, f.name)
+		}
+	}
+	return changed
+}
+
+// Why a medical record was read, in the details of its audit entry
+const (
+	MedicalReadView           = "view"            // GET /students/{id}/medical
+	MedicalReadEmergencySheet = "emergency_sheet" // GET /trips/emergency-sheet
+)
@@ -0,0 +1,62 @@
+package models
+
+import "time"
+
+// SMS delivery states, normalized across providers
+const (
+	MessageQueued    = "queued"    // Recorded, not yet accepted by the provider
+	MessageSent      = "sent"      // Accepted by the provider
+	MessageDelivered = "delivered" // Handset confirmed via delivery webhook
+	MessageFailed    = "failed"    // Rejected or undeliverable
+)
+
+// SMS templates
+const (
+	TemplateAbsenceAlert = "absence_alert"
+	TemplateFeeReminder  = "fee_reminder"
+	// Sent by the scheduler only, see package jobs
+	TemplateAttendanceReminder = "attendance_reminder" // To a class teacher
+	TemplateAbsenceSummary     = "absence_summary"     // Weekly, to a guardian
+	// Sent when staff post in a messaging thread, see jobs.ThreadNotices
+	TemplateThreadMessage = "thread_message"
+	// Sent to the uploader when the virus scanner quarantines their file, see jobs.UploadScans
+	TemplateUploadQuarantined = "upload_quarantined"
+	// TemplateTripConsent asks a guardian to consent to a trip, with a link
+	TemplateTripConsent = "trip_consent"
+	// Sent when a student's attendance falls below a threshold, see jobs.AttendanceNotices
+	TemplateAttendanceNotice     = "attendance_notice"     // To a guardian, below the notice threshold
+	TemplateAttendanceEscalation = "attendance_escalation" // To a guardian, below the escalation threshold
+	TemplateAttendanceAtRisk     = "attendance_at_risk"    // To the class teacher, at either
+)
+
+// SMSMessage is one row of the sms_messages table: every text we try to send,
+// with the provider's ID so delivery webhooks can find it again
+type SMSMessage struct {
+	ID                int       `json:"id,omitempty"`
+	StudentID         *int      `json:"student_id,omitempty"`
+	To                string    `json:"to"`
+	SenderID          string    `json:"sender_id,omitempty"`
+	Template          string    `json:"template"`
+	Body              string    `json:"body"`
+	Provider          string    `json:"provider"`
+	ProviderMessageID string    `json:"provider_message_id,omitempty"`
+	Status            string    `json:"status"`
+	Error             string    `json:"error,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
+}
+
+// SMSRequest is the body of POST /admin/sms.
+// To may be left out when StudentID is set: the student's guardian phone is used.
+type SMSRequest struct {
+	StudentID *int              `json:"student_id"`
+	To        string            `json:"to" validate:"required_without=StudentID,omitempty,e164"`
+	Template  string            `json:"template" validate:"required,oneof=absence_alert fee_reminder"`
+	Params    map[string]string `json:"params"`
+}
+
+// SMSFilter narrows GET /admin/sms
+type SMSFilter struct {
+	StudentID int    `query:"student_id"`
+	Status    string `query:"status"`
+}
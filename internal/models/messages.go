@@ -0,0 +1,339 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultLanguage is used when the client sends no Accept-Language we support
+const DefaultLanguage = "en"
+
+// Message catalogs, keyed by language, then by message key. Lookup order for a
+// failed rule: "<Struct>.<Field>.<tag>" (a per-field message), "<tag>.<kind>",
+// "<tag>", then "invalid". "{param}" in a message is replaced with the rule's
+// parameter, e.g. the 32 in max=32.
+var (
+	catalogMu sync.RWMutex
+	catalogs  = map[string]map[string]string{
+		"en": {
+			"validation_failed":  "Validation failed",
+			"invalid":            "Invalid field",
+			"required":           "This field is required",
+			"required_if":        "This field is required",
+			"required_without":   "This field is required",
+			"required_unless":    "This field is required",
+			"email":              "Invalid email format",
+			"min":                "Must be at least {param} characters long",
+			"min.number":         "Must be at least {param}",
+			"min.items":          "Must contain at least {param} item(s)",
+			"max":                "Must be at most {param} characters long",
+			"max.number":         "Must be at most {param}",
+			"max.items":          "Must contain at most {param} item(s)",
+			"gte":                "Must be at least {param}",
+			"lte":                "Must be at most {param}",
+			"oneof":              "Must be one of: {param}",
+			"reference":          "Must be one of the school's values: {param}",
+			"e164":               "Phone number must be in international format, e.g. +2348012345678",
+			"datetime":           "Date must be in YYYY-MM-DD format",
+			"phone":              "Invalid phone number, e.g. +2348012345678 or 0803 123 4567",
+			"iso3166_1_alpha2":   "Must be a two-letter country code, e.g. NG",
+			"int":                "Must be a whole number",
+			"number":             "Must be a number",
+			"boolean":            "Must be true or false",
+			"filter":             "Invalid filter: {param}",
+			"timezone":           "Must be a time zone name, e.g. Africa/Lagos",
+			"language":           "Not a language this school offers",
+			"password_incorrect": "The current password is incorrect",
+			"password_unchanged": "Must differ from the current password",
+			"password_breached":  "This password has appeared in a data breach; choose another",
+
+			// Rules checked in code rather than struct tags
+			"end_before_start":      "End date must not be before the start date",
+			"duplicate_grade":       "Grade '{param}' appears more than once",
+			"duplicate_min_score":   "Two grades share the same minimum score",
+			"lowest_grade_not_zero": "The lowest grade must start at 0",
+			"date_in_future":        "Date must not be in the future",
+			"date_in_past":          "Date must not be in the past",
+			"consent_deadline":      "Must be between today and the trip's date",
+			"enrolled_before_birth": "Enrollment date must not be before the date of birth",
+			"custom_field_key":      "Use lowercase letters, digits and _, starting with a letter",
+			"custom_field_builtin":  "'{param}' is already a field of its own",
+			"custom_field_options":  "Enum fields need options, and only enum fields take them",
+			"custom_field_unknown":  "No custom field with this key",
+			"custom_field_text":     "Must be text",
+			"approval_payload":      "Must be an object with the fields of the request's type",
+			"template":              "Invalid template: {param}",
+			"staff_only":            "Only staff can be narrowed by role",
+			"taken":                 "'{param}' is already in use",
+			"duplicate_in_batch":    "'{param}' appears more than once in this batch",
+			"rollover_unknown":      "{param} is not in the rollover plan",
+			"rollover_graduates":    "The class graduates: give it a next class, or make the student repeat or graduate",
+			"escalate_above_notice": "Must not be above the notice threshold",
+			"exam_end_time":         "End time must be after the start time",
+			"duplicate_room":        "Room '{param}' appears more than once",
+			"exam_capacity":         "The rooms seat fewer than the {param} students sitting the exam",
+			"exam_invigilators":     "Only {param} teachers are free to invigilate at that time",
+			"student_transition":    "A student who is {param} can't be moved to this status",
+			"status_class":          "A class can only be given when the student enrolls",
+			"letter_email":          "The student has no email address to send the letter to",
+
+			"config_version":           "Unsupported config version, this server reads version {param}",
+			"duplicate_scheme_subject": "More than one grading scheme for subject '{param}'",
+
+			// Per-field messages
+			"Event.Class.required_if":               "Class is required when the audience is a class",
+			"TeacherStatusUpdate.IDs.min":           "Provide at least one teacher ID",
+			"GradingScheme.Boundaries.min":          "A grading scheme needs at least one grade",
+			"Student.AdmissionNumber.max":           "Admission number must be at most {param} characters long",
+			"SMSRequest.To.required_without":        "Provide a phone number or a student with a guardian phone on file",
+			"SMSRequest.To.e164":                    "Recipient must be a phone number in international format, e.g. +2348012345678",
+			"StudentScore.Score.gte":                "Score must be between 0 and 100",
+			"StudentScore.Score.lte":                "Score must be between 0 and 100",
+			"GradingScheme.Boundaries.MinScore.gte": "Minimum score must be between 0 and 100",
+			"GradingScheme.Boundaries.MinScore.lte": "Minimum score must be between 0 and 100",
+
+			"PromotionRequest.Criteria.AttendanceFrom.required_unless": "Give the attendance period when a minimum attendance is set",
+			"PromotionRequest.Criteria.AttendanceTo.required_unless":   "Give the attendance period when a minimum attendance is set",
+			"PromotionRequest.NextClass.min":                           "Map at least one class to the class it moves up to",
+			"BatchRequest.Path.startswith":                             "Path must start with /, e.g. /students",
+			"ExamSessionRequest.StartTime.datetime":                    "Time must be in HH:MM format",
+			"ExamSessionRequest.EndTime.datetime":                      "Time must be in HH:MM format",
+		},
+		"fr": {
+			"validation_failed":  "La validation a échoué",
+			"invalid":            "Champ invalide",
+			"required":           "Ce champ est obligatoire",
+			"required_if":        "Ce champ est obligatoire",
+			"required_without":   "Ce champ est obligatoire",
+			"required_unless":    "Ce champ est obligatoire",
+			"email":              "Format d'adresse e-mail invalide",
+			"min":                "Doit contenir au moins {param} caractères",
+			"min.number":         "Doit être au moins {param}",
+			"min.items":          "Doit contenir au moins {param} élément(s)",
+			"max":                "Doit contenir au plus {param} caractères",
+			"max.number":         "Doit être au plus {param}",
+			"max.items":          "Doit contenir au plus {param} élément(s)",
+			"gte":                "Doit être au moins {param}",
+			"lte":                "Doit être au plus {param}",
+			"oneof":              "Doit être l'une des valeurs : {param}",
+			"reference":          "Doit être l'une des valeurs de l'école : {param}",
+			"e164":               "Le numéro doit être au format international, par ex. +2348012345678",
+			"datetime":           "La date doit être au format AAAA-MM-JJ",
+			"phone":              "Numéro de téléphone invalide, par ex. +2348012345678 ou 0803 123 4567",
+			"iso3166_1_alpha2":   "Doit être un code pays à deux lettres, par ex. NG",
+			"int":                "Doit être un nombre entier",
+			"number":             "Doit être un nombre",
+			"boolean":            "Doit valoir true ou false",
+			"filter":             "Filtre invalide : {param}",
+			"timezone":           "Doit être un nom de fuseau horaire, par ex. Africa/Lagos",
+			"language":           "Cette langue n'est pas proposée par l'école",
+			"password_incorrect": "Le mot de passe actuel est incorrect",
+			"password_unchanged": "Doit être différent du mot de passe actuel",
+			"password_breached":  "Ce mot de passe figure dans une fuite de données ; choisissez-en un autre",
+
+			"end_before_start":      "La date de fin ne peut pas précéder la date de début",
+			"duplicate_grade":       "La note '{param}' apparaît plusieurs fois",
+			"duplicate_min_score":   "Deux notes ont le même score minimum",
+			"lowest_grade_not_zero": "La note la plus basse doit commencer à 0",
+			"date_in_future":        "La date ne peut pas être dans le futur",
+			"date_in_past":          "La date ne peut pas être dans le passé",
+			"consent_deadline":      "Doit être entre aujourd'hui et la date de la sortie",
+			"enrolled_before_birth": "La date d'inscription ne peut pas précéder la date de naissance",
+			"custom_field_key":      "Utilisez des minuscules, des chiffres et _, en commençant par une lettre",
+			"custom_field_builtin":  "'{param}' est déjà un champ à part entière",
+			"custom_field_options":  "Les champs enum ont besoin d'options, et eux seuls en prennent",
+			"custom_field_unknown":  "Aucun champ personnalisé avec cette clé",
+			"custom_field_text":     "Doit être du texte",
+			"approval_payload":      "Doit être un objet avec les champs du type de demande",
+			"template":              "Modèle invalide : {param}",
+			"staff_only":            "Seul le personnel peut être filtré par rôle",
+			"taken":                 "'{param}' est déjà utilisé",
+			"duplicate_in_batch":    "'{param}' apparaît plusieurs fois dans ce lot",
+			"rollover_unknown":      "{param} ne fait pas partie du plan de passage",
+			"rollover_graduates":    "La classe termine sa scolarité : donnez-lui une classe suivante, ou faites redoubler ou sortir l'élève",
+			"escalate_above_notice": "Ne doit pas dépasser le seuil d'avertissement",
+			"exam_end_time":         "L'heure de fin doit suivre l'heure de début",
+			"duplicate_room":        "La salle '{param}' apparaît plusieurs fois",
+			"exam_capacity":         "Les salles comptent moins de places que les {param} élèves qui passent l'épreuve",
+			"exam_invigilators":     "Seuls {param} enseignants sont libres pour surveiller à cette heure",
+			"student_transition":    "Un élève au statut {param} ne peut pas passer à ce statut",
+			"status_class":          "Une classe ne peut être donnée que lorsque l'élève s'inscrit",
+			"letter_email":          "L'élève n'a pas d'adresse e-mail à laquelle envoyer la lettre",
+
+			"config_version":           "Version de configuration non prise en charge, ce serveur lit la version {param}",
+			"duplicate_scheme_subject": "Plusieurs barèmes pour la matière '{param}'",
+
+			"Event.Class.required_if":               "La classe est obligatoire lorsque le public est une classe",
+			"TeacherStatusUpdate.IDs.min":           "Indiquez au moins un identifiant d'enseignant",
+			"GradingScheme.Boundaries.min":          "Un barème doit comporter au moins une note",
+			"Student.AdmissionNumber.max":           "Le numéro d'inscription doit contenir au plus {param} caractères",
+			"SMSRequest.To.required_without":        "Indiquez un numéro ou un élève dont le tuteur a un numéro enregistré",
+			"SMSRequest.To.e164":                    "Le destinataire doit être un numéro au format international, par ex. +2348012345678",
+			"StudentScore.Score.gte":                "Le score doit être compris entre 0 et 100",
+			"StudentScore.Score.lte":                "Le score doit être compris entre 0 et 100",
+			"GradingScheme.Boundaries.MinScore.gte": "Le score minimum doit être compris entre 0 et 100",
+			"GradingScheme.Boundaries.MinScore.lte": "Le score minimum doit être compris entre 0 et 100",
+
+			"PromotionRequest.Criteria.AttendanceFrom.required_unless": "Indiquez la période d'assiduité lorsqu'une assiduité minimale est fixée",
+			"PromotionRequest.Criteria.AttendanceTo.required_unless":   "Indiquez la période d'assiduité lorsqu'une assiduité minimale est fixée",
+			"PromotionRequest.NextClass.min":                           "Associez au moins une classe à la classe supérieure",
+			"BatchRequest.Path.startswith":                             "Le chemin doit commencer par /, par ex. /students",
+			"ExamSessionRequest.StartTime.datetime":                    "L'heure doit être au format HH:MM",
+			"ExamSessionRequest.EndTime.datetime":                      "L'heure doit être au format HH:MM",
+		},
+	}
+	// secondLanguage is the one language offered besides English ("" = English only)
+	secondLanguage string
+)
+
+// RegisterMessages adds or overrides messages for a language, e.g. a school's
+// own wording for a field or a catalog for a language we don't ship
+func RegisterMessages(lang string, messages map[string]string) {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+
+	lang = strings.ToLower(lang)
+	if catalogs[lang] == nil {
+		catalogs[lang] = make(map[string]string, len(messages))
+	}
+	for key, msg := range messages {
+		catalogs[lang][key] = msg
+	}
+}
+
+// LoadMessagesFile registers the catalogs in a JSON file shaped like
+// {"sw": {"required": "Sehemu hii inahitajika", "Teacher.Email.email": "..."}}
+func LoadMessagesFile(path string) error {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var file map[string]map[string]string
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return fmt.Errorf("bad validation messages file %s: %w", path, err)
+	}
+	for lang, messages := range file {
+		RegisterMessages(lang, messages)
+	}
+	return nil
+}
+
+// SetSecondLanguage picks the language offered alongside English. It must have a catalog.
+func SetSecondLanguage(lang string) error {
+	catalogMu.Lock()
+	defer catalogMu.Unlock()
+
+	lang = strings.ToLower(lang)
+	if lang != "" && catalogs[lang] == nil {
+		return fmt.Errorf("no validation messages for language %q", lang)
+	}
+	secondLanguage = lang
+	return nil
+}
+
+// SupportsLanguage reports whether messages are offered in lang: English or
+// the second language
+func SupportsLanguage(lang string) bool {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+	return lang == DefaultLanguage || (lang == secondLanguage && lang != "")
+}
+
+// LanguageFor negotiates the message language from an Accept-Language header,
+// e.g. "fr-CA,fr;q=0.9,en;q=0.8". Region subtags are ignored.
+func LanguageFor(acceptLanguage string) string {
+	catalogMu.RLock()
+	second := secondLanguage
+	catalogMu.RUnlock()
+
+	type candidate struct {
+		lang string
+		q    float64
+	}
+	var candidates []candidate
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+		base, _, _ := strings.Cut(strings.ToLower(tag), "-")
+		if q > 0 && base != "" {
+			candidates = append(candidates, candidate{base, q})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].q > candidates[j].q })
+
+	for _, c := range candidates {
+		if c.lang == DefaultLanguage || (c.lang == second && second != "") {
+			return c.lang
+		}
+	}
+	return DefaultLanguage
+}
+
+// Localize returns errs with their messages in lang. Errors built outside the
+// validator without a Tag keep their message as is.
+func Localize(errs []ValidationError, lang string) []ValidationError {
+	out := make([]ValidationError, len(errs))
+	for i, e := range errs {
+		out[i] = e
+		if e.Tag != "" {
+			out[i].Msg = msgForTag(lang, e)
+		}
+	}
+	return out
+}
+
+// Message returns a plain catalog message (e.g. "validation_failed") in lang
+func Message(lang, key string) string {
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+	return lookup(lang, key)
+}
+
+// msgForTag renders the message for a failed rule. The most specific key wins
+// within a language; English is only consulted when lang has none of them.
+func msgForTag(lang string, e ValidationError) string {
+	keys := []string{e.Tag}
+	if e.kind != "" {
+		keys = append([]string{e.Tag + "." + e.kind}, keys...)
+	}
+	if e.field != "" {
+		keys = append([]string{e.field + "." + e.Tag}, keys...)
+	}
+
+	catalogMu.RLock()
+	defer catalogMu.RUnlock()
+	for _, l := range []string{lang, DefaultLanguage} {
+		for _, key := range keys {
+			if msg := catalogs[l][key]; msg != "" {
+				return strings.ReplaceAll(msg, "{param}", formatParam(e.Tag, e.param))
+			}
+		}
+	}
+	return lookup(lang, "invalid")
+}
+
+// lookup reads one key in lang, then in English. Caller must hold catalogMu.
+func lookup(lang, key string) string {
+	if msg := catalogs[lang][key]; msg != "" {
+		return msg
+	}
+	return catalogs[DefaultLanguage][key]
+}
+
+// formatParam makes rule parameters readable: oneof's "a b c" becomes "a, b, c"
+func formatParam(tag, param string) string {
+	if tag == "oneof" {
+		return strings.Join(strings.Fields(param), ", ")
+	}
+	return param
+}
@@ -0,0 +1,33 @@
+package models
+
+import "time"
+
+// OutboxEvent is a change to publish to subscribers (the outbound webhook),
+// written to the outbox table in the transaction of the change itself, so
+// that an event exists exactly when its change committed. Every audited
+// change is one: Type is the audit action, e.g. "student.updated".
+type OutboxEvent struct {
+	ID         int            `json:"id"` // Receivers dedupe on it: delivery is at least once
+	Type       string         `json:"type"`
+	Entity     string         `json:"entity"`
+	EntityID   int            `json:"entity_id"`
+	ActorID    *int           `json:"actor_id,omitempty"`
+	Data       map[string]any `json:"data,omitempty"`
+	OccurredAt time.Time      `json:"occurred_at"`
+
+	// Delivery bookkeeping, not part of the published payload
+	Attempts  int    `json:"-"`
+	LastError string `json:"-"`
+}
+
+// OutboxEventFor is the event of an audit entry
+func OutboxEventFor(entry AuditEntry) OutboxEvent {
+	return OutboxEvent{
+		Type:       entry.Action,
+		Entity:     entry.Entity,
+		EntityID:   entry.EntityID,
+		ActorID:    entry.ActorID,
+		Data:       entry.Details,
+		OccurredAt: entry.CreatedAt,
+	}
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"fmt"
+	"simpleapi/pkg/phone"
+	"strings"
+)
+
+// ParsePhone normalizes a phone number to E.164 for storage or filtering.
+// "" stays "" (no number); anything unreadable is ErrInvalidInput.
+func ParsePhone(raw string) (string, error) {
+	if strings.TrimSpace(raw) == "" {
+		return "", nil
+	}
+	normalized, err := phone.Normalize(raw)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidInput, err)
+	}
+	return normalized, nil
+}
@@ -0,0 +1,114 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Promotion outcomes
+const (
+	PromotionPromote = "promote"
+	PromotionRepeat  = "repeat"
+	PromotionReview  = "review" // Borderline or missing data: an admin has to decide
+)
+
+// Promotion report states
+const (
+	PromotionDraft   = "draft"   // Open for overrides
+	PromotionApplied = "applied" // Students moved; the report is now a record
+)
+
+// PromotionCriteria are the year-end rules, evaluated by package promotion.
+// A zero threshold switches its rule off.
+type PromotionCriteria struct {
+	MinAverage float64 `json:"min_average" validate:"gte=0,lte=100"`
+	// MinAttendance is the percentage of marked days present or late, counted
+	// from AttendanceFrom to AttendanceTo (YYYY-MM-DD, both needed when it is set)
+	MinAttendance  float64 `json:"min_attendance" validate:"gte=0,lte=100"`
+	AttendanceFrom string  `json:"attendance_from,omitempty" validate:"required_unless=MinAttendance 0,omitempty,datetime=2006-01-02"`
+	AttendanceTo   string  `json:"attendance_to,omitempty" validate:"required_unless=MinAttendance 0,omitempty,datetime=2006-01-02"`
+	// MandatorySubjects must each average at least PassMark over the year
+	MandatorySubjects []string `json:"mandatory_subjects,omitempty" validate:"dive,required,max=100"`
+	PassMark          float64  `json:"pass_mark" validate:"gte=0,lte=100"`
+	// ReviewMargin sends a student who misses a threshold by no more than this
+	// to review instead of repeat (points for averages, percentage points for attendance)
+	ReviewMargin float64 `json:"review_margin" validate:"gte=0,lte=100"`
+}
+
+// PromotionRequest is the body of POST /admin/academic-years/{year}/promotions.
+// Only students of the classes in NextClass are evaluated.
+type PromotionRequest struct {
+	Criteria  PromotionCriteria `json:"criteria"`
+	NextClass map[string]string `json:"next_class" validate:"required,min=1,dive,keys,required,max=50,endkeys,required,max=50"`
+}
+
+// PromotionDecision is the verdict for one student. Outcome is the engine's;
+// an admin's Override, when set, wins.
+type PromotionDecision struct {
+	StudentID    int                `json:"student_id"`
+	Name         string             `json:"name"`
+	Class        string             `json:"class"`
+	NextClass    string             `json:"next_class"`
+	Average      float64            `json:"average"`
+	Subjects     map[string]float64 `json:"subjects"`
+	Attendance   *float64           `json:"attendance,omitempty"` // nil when no register covers the student
+	Outcome      string             `json:"outcome"`
+	Reasons      []string           `json:"reasons,omitempty"`
+	Override     string             `json:"override,omitempty"`
+	OverrideNote string             `json:"override_note,omitempty"`
+	OverriddenBy *int               `json:"overridden_by,omitempty"`
+	Moved        bool               `json:"moved,omitempty"` // Set when the report was applied
+}
+
+// Final is the outcome that counts
+func (d PromotionDecision) Final() string {
+	if d.Override != "" {
+		return d.Override
+	}
+	return d.Outcome
+}
+
+// PromotionOverride is the body of PATCH /admin/promotions/{id}/decisions/{studentId}
+type PromotionOverride struct {
+	Outcome string `json:"outcome" validate:"required,oneof=promote repeat"`
+	Note    string `json:"note" validate:"required,max=255"`
+}
+
+// PromotionReport is the year-end promotion proposal for a set of classes
+type PromotionReport struct {
+	ID        int                 `json:"id"`
+	Year      string              `json:"year"`
+	Status    string              `json:"status"`
+	Criteria  PromotionCriteria   `json:"criteria"`
+	NextClass map[string]string   `json:"next_class"`
+	Decisions []PromotionDecision `json:"decisions"`
+	CreatedBy *int                `json:"created_by,omitempty"`
+	CreatedAt time.Time           `json:"created_at"`
+	AppliedBy *int                `json:"applied_by,omitempty"`
+	AppliedAt *time.Time          `json:"applied_at,omitempty"`
+}
+
+// Pending counts decisions still waiting for an admin (review with no override)
+func (r PromotionReport) Pending() int {
+	n := 0
+	for _, d := range r.Decisions {
+		if d.Final() == PromotionReview {
+			n++
+		}
+	}
+	return n
+}
+
+// OverrideDecision sets an admin's outcome on the report's decision for studentID.
+// It fails with ErrNotFound when the student isn't in the report.
+func OverrideDecision(r *PromotionReport, studentID int, o PromotionOverride, actorID *int) error {
+	for i := range r.Decisions {
+		if r.Decisions[i].StudentID == studentID {
+			r.Decisions[i].Override = o.Outcome
+			r.Decisions[i].OverrideNote = o.Note
+			r.Decisions[i].OverriddenBy = actorID
+			return nil
+		}
+	}
+	return fmt.Errorf("student %d is not in promotion report %d: %w", studentID, r.ID, ErrNotFound)
+}
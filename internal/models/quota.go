@@ -0,0 +1,78 @@
+package models
+
+import (
+	"fmt"
+	"simpleapi/pkg/errcodes"
+)
+
+// Quotas of a school, as named in QuotaError and the Quota-Warning header
+const (
+	QuotaStudents = "students"
+	QuotaStorage  = "storage"
+	QuotaRequests = "requests"
+)
+
+// QuotaWarnPercent is how full a quota gets before the school is warned
+const QuotaWarnPercent = 80
+
+// SchoolQuotas cap what a school may use of a hosted, multi-school
+// installation, where each school has its own deployment. Zero is no limit,
+// which is what a school running its own deployment keeps.
+type SchoolQuotas struct {
+	MaxStudents int `json:"max_students" validate:"gte=0"`
+	// MaxStorageBytes caps the files users upload (see Upload); photos, the
+	// logo, archives and backups are the school's records and don't count
+	MaxStorageBytes   int64 `json:"max_storage_bytes" validate:"gte=0"`
+	RequestsPerMinute int   `json:"requests_per_minute" validate:"gte=0"` // All clients together
+}
+
+// QuotaUsage is how much of its quotas the school uses
+type QuotaUsage struct {
+	Students     int   `json:"students"`
+	StorageBytes int64 `json:"storage_bytes"`
+}
+
+// QuotaStatus is one quota, how much of it is used and whether that is past
+// QuotaWarnPercent, as GET /admin/school/quotas shows it
+type QuotaStatus struct {
+	Quota   string `json:"quota"`
+	Used    int64  `json:"used"`
+	Limit   int64  `json:"limit"` // 0: no limit
+	Warning bool   `json:"warning"`
+}
+
+// NearLimit reports whether used is at least QuotaWarnPercent of a set limit
+func NearLimit(used, limit int64) bool {
+	return limit > 0 && used*100 >= limit*QuotaWarnPercent
+}
+
+// Statuses lists the quotas with a limit against usage. The request rate
+// isn't kept between requests, so it only has a limit here.
+func (q SchoolQuotas) Statuses(u QuotaUsage) []QuotaStatus {
+	return []QuotaStatus{
+		{Quota: QuotaStudents, Used: int64(u.Students), Limit: int64(q.MaxStudents), Warning: NearLimit(int64(u.Students), int64(q.MaxStudents))},
+		{Quota: QuotaStorage, Used: u.StorageBytes, Limit: q.MaxStorageBytes, Warning: NearLimit(u.StorageBytes, q.MaxStorageBytes)},
+		{Quota: QuotaRequests, Limit: int64(q.RequestsPerMinute)},
+	}
+}
+
+// QuotaError is a 403 raised when a change would take the school past one of
+// its quotas. errors.Is(err, ErrForbidden) holds.
+type QuotaError struct {
+	Quota     string `json:"quota"`
+	Used      int64  `json:"used"`
+	Requested int64  `json:"requested"`
+	Limit     int64  `json:"limit"`
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("the school's %s quota is %d and %d is used; %d more would exceed it", e.Quota, e.Limit, e.Used, e.Requested)
+}
+
+func (e *QuotaError) Is(target error) bool {
+	return target == ErrForbidden
+}
+
+func (e *QuotaError) ErrorCode() errcodes.Code {
+	return errcodes.QuotaExceeded
+}
@@ -0,0 +1,119 @@
+package models
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// ReferenceData is the school's list of classes and subjects (tables classes
+// and subjects), edited with PUT /admin/reference. Teachers' and students'
+// class and subject must name one of them when created or changed. An empty
+// list accepts anything, as before the lists existed; values already stored
+// are left alone when a list changes.
+type ReferenceData struct {
+	Classes  []string `json:"classes" validate:"max=500,dive,required,max=50"`
+	Subjects []string `json:"subjects" validate:"max=500,dive,required,max=100"`
+}
+
+// Normalize trims, sorts and dedupes both lists
+func (d *ReferenceData) Normalize() {
+	d.Classes = normalizeNames(d.Classes)
+	d.Subjects = normalizeNames(d.Subjects)
+}
+
+func normalizeNames(names []string) []string {
+	out := make([]string, 0, len(names))
+	for _, n := range names {
+		if n = strings.TrimSpace(n); n != "" {
+			out = append(out, n)
+		}
+	}
+	slices.Sort(out)
+	return slices.Compact(out)
+}
+
+// CheckTeacher checks a teacher's class and subject
+func (d ReferenceData) CheckTeacher(t Teacher) []ValidationError {
+	return append(checkReference("Class", t.Class, d.Classes), checkReference("Subject", t.Subject, d.Subjects)...)
+}
+
+// CheckStudent checks a student's class
+func (d ReferenceData) CheckStudent(s Student) []ValidationError {
+	return checkReference("Class", s.Class, d.Classes)
+}
+
+// CheckTeacherUpdates checks the class and subject of a teacher patch, when it sets them
+func (d ReferenceData) CheckTeacherUpdates(updates map[string]any) []ValidationError {
+	var errs []ValidationError
+	if class, ok := updates["class"].(string); ok {
+		errs = append(errs, checkReference("Class", class, d.Classes)...)
+	}
+	if subject, ok := updates["subject"].(string); ok {
+		errs = append(errs, checkReference("Subject", subject, d.Subjects)...)
+	}
+	return errs
+}
+
+// CheckRollover checks the classes a rollover plan moves students and teachers
+// to. Graduates' alumni class is exempt: it is not a class anyone teaches. Like
+// the other checks, only changes are checked, not what teachers have already.
+func (d ReferenceData) CheckRollover(p RolloverPlan) []ValidationError {
+	var errs []ValidationError
+	for _, c := range p.Classes {
+		errs = append(errs, checkReference(fmt.Sprintf("NextClass[%s]", c.Class), c.NextClass, d.Classes)...)
+	}
+	for _, t := range p.Teachers {
+		field := fmt.Sprintf("Teachers[%d]", t.TeacherID)
+		if t.NextHomeClass != t.HomeClass {
+			errs = append(errs, checkReference(field+".HomeClass", t.NextHomeClass, d.Classes)...)
+		}
+		for _, c := range t.NextClasses {
+			if !slices.Contains(t.Classes, c) {
+				errs = append(errs, checkReference(field+".Classes", c, d.Classes)...)
+			}
+		}
+	}
+	return errs
+}
+
+// checkReference rejects a value missing from allowed, listing what is allowed.
+// Empty values are for the struct tags to judge.
+func checkReference(field, value string, allowed []string) []ValidationError {
+	if value == "" || len(allowed) == 0 || slices.Contains(allowed, value) {
+		return nil
+	}
+	e := RuleError(field, "reference", strings.Join(allowed, ", "))
+	e.Allowed = allowed
+	return []ValidationError{e}
+}
+
+// DiffReference lists the entries each list gained or lost, as a
+// comma-separated "from" (removed) and "to" (added)
+func DiffReference(before, after ReferenceData) map[string]FieldChange {
+	changes := make(map[string]FieldChange)
+	for _, l := range []struct {
+		name          string
+		before, after []string
+	}{
+		{"classes", before.Classes, after.Classes},
+		{"subjects", before.Subjects, after.Subjects},
+	} {
+		removed, added := missingFrom(l.before, l.after), missingFrom(l.after, l.before)
+		if len(removed) > 0 || len(added) > 0 {
+			changes[l.name] = FieldChange{From: strings.Join(removed, ", "), To: strings.Join(added, ", ")}
+		}
+	}
+	return changes
+}
+
+// missingFrom returns the names of a that b lacks
+func missingFrom(a, b []string) []string {
+	var out []string
+	for _, n := range a {
+		if !slices.Contains(b, n) {
+			out = append(out, n)
+		}
+	}
+	return out
+}
@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// Report is the response of the /reports endpoints. They are cached, so
+// GeneratedAt says how old the figures are.
+type Report[T any] struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	Rows        []T       `json:"rows"`
+}
+
+// EnrollmentCount is a row of GET /reports/enrollment
+type EnrollmentCount struct {
+	Class    string `json:"class"`
+	Students int    `json:"students"`
+}
+
+// GenderUnrecorded stands in the gender report for students with no gender on file
+const GenderUnrecorded = "unrecorded"
+
+// GenderCount is a row of GET /reports/genders
+type GenderCount struct {
+	Class    string `json:"class"`
+	Gender   string `json:"gender"` // GenderFemale, GenderMale, GenderOther or GenderUnrecorded
+	Students int    `json:"students"`
+}
+
+// MonthLayout is the month of the attendance report, e.g. "2026-09"
+const MonthLayout = "2006-01"
+
+// AttendanceRate is a row of GET /reports/attendance: a class's marks in one month
+type AttendanceRate struct {
+	Class string `json:"class"`
+	Month string `json:"month"` // MonthLayout
+	AttendanceTally
+	Rate float64 `json:"rate"` // AttendanceTally.Rate
+}
+
+// GradeCount is a row of GET /reports/grades: how many scores in a subject got a grade
+type GradeCount struct {
+	Subject string `json:"subject"`
+	Grade   string `json:"grade"`
+	Scores  int    `json:"scores"`
+}
+
+// SchoolKPIs are the live figures exported on /metrics for the principal's
+// dashboard. Attendance is the marks of one school day.
+type SchoolKPIs struct {
+	Students   int // Enrolled, i.e. every student on the roll
+	Teachers   int // Active accounts, not deactivated or in the trash
+	Attendance AttendanceTally
+}
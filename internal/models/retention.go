@@ -0,0 +1,112 @@
+package models
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Data categories the retention policy covers
+const (
+	RetentionMessages = "messages" // Guardian thread messages (thread_messages)
+	RetentionSMS      = "sms"      // Texts sent to guardians and staff (sms_messages)
+	RetentionLogs     = "logs"     // The audit trail (audit_log)
+)
+
+// What happens to a record past its retention period
+const (
+	RetentionAnonymize = "anonymize" // The row stays for counts and threads; its personal data is blanked
+	RetentionDelete    = "delete"
+)
+
+// Redacted replaces text blanked by the retention policy
+const Redacted = "[removed under the data retention policy]"
+
+// RetentionPolicy is how long one category of data is kept, and what is done with it after
+type RetentionPolicy struct {
+	Category string        `json:"category"`
+	Period   time.Duration `json:"-"`
+	Action   string        `json:"action"`
+}
+
+// DefaultRetention is the policy for categories RETENTION leaves out. The
+// action of a category is fixed: only the period is configurable.
+var DefaultRetention = []RetentionPolicy{
+	{Category: RetentionMessages, Period: 365 * 24 * time.Hour, Action: RetentionAnonymize},
+	{Category: RetentionSMS, Period: 365 * 24 * time.Hour, Action: RetentionAnonymize},
+	{Category: RetentionLogs, Period: 90 * 24 * time.Hour, Action: RetentionDelete},
+}
+
+// ParseRetention reads RETENTION, e.g. "messages=2y,logs=180d,sms=off".
+// Periods are days (d), years of 365 days (y), or Go durations; "off" keeps a
+// category forever. Categories left out keep their DefaultRetention period.
+func ParseRetention(s string) ([]RetentionPolicy, error) {
+	policies := make([]RetentionPolicy, len(DefaultRetention))
+	copy(policies, DefaultRetention)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		i := -1
+		for j, p := range policies {
+			if p.Category == name {
+				i = j
+			}
+		}
+		if !ok || i < 0 {
+			return nil, fmt.Errorf("bad retention %q, want <messages|sms|logs>=<period>", part)
+		}
+		if value == "off" {
+			policies = append(policies[:i], policies[i+1:]...)
+			continue
+		}
+		period, err := parseRetentionPeriod(value)
+		if err != nil {
+			return nil, fmt.Errorf("bad retention %q: %w", part, err)
+		}
+		policies[i].Period = period
+	}
+	return policies, nil
+}
+
+func parseRetentionPeriod(s string) (time.Duration, error) {
+	day := 24 * time.Hour
+	unit := day
+	switch {
+	case strings.HasSuffix(s, "y"):
+		unit = 365 * day
+		fallthrough
+	case strings.HasSuffix(s, "d"):
+		n, err := strconv.Atoi(s[:len(s)-1])
+		if err != nil {
+			return 0, fmt.Errorf("want a period such as 90d, 3y or 720h")
+		}
+		return checkRetentionPeriod(time.Duration(n) * unit)
+	}
+	period, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("want a period such as 90d, 3y or 720h")
+	}
+	return checkRetentionPeriod(period)
+}
+
+func checkRetentionPeriod(period time.Duration) (time.Duration, error) {
+	if period < 24*time.Hour {
+		return 0, fmt.Errorf("a period under a day would purge live data")
+	}
+	return period, nil
+}
+
+// RetentionResult is what one category's purge did, or would do on a dry run
+type RetentionResult struct {
+	Category   string    `json:"category"`
+	Action     string    `json:"action"`
+	PeriodDays int       `json:"period_days"`
+	Cutoff     time.Time `json:"cutoff"` // Records older than this are expired
+	Records    int       `json:"records"`
+	DryRun     bool      `json:"dry_run"`
+}
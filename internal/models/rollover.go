@@ -0,0 +1,130 @@
+package models
+
+import "time"
+
+// What the rollover does with a student
+const (
+	RolloverPromote  = "promote"  // To the next class of theirs
+	RolloverRepeat   = "repeat"   // Stays in the same class
+	RolloverGraduate = "graduate" // To the plan's alumni class, off every class roster
+)
+
+// Rollover plan states. Executing a plan runs it on the job queue through the
+// job states (JobPending, JobRunning, JobDone, JobFailed); a failed plan was
+// rolled back and can be adjusted and executed again.
+const RolloverDraft = "draft"
+
+// RolloverRequest is the body of POST /admin/rollover/plan. The planner guesses
+// each class's next one from its name ("JSS 1" -> "JSS 2"), and a class with
+// none graduates; NextClass overrides the guesses, "" making a class graduate.
+type RolloverRequest struct {
+	FromYear  string            `json:"from_year" validate:"required,max=20"` // e.g. "2025/26"
+	ToYear    string            `json:"to_year" validate:"required,max=20,nefield=FromYear"`
+	NextClass map[string]string `json:"next_class" validate:"dive,keys,required,max=50,endkeys,max=50"`
+	// Teachers "stay" with their classes (default) or "follow" them to the next
+	Teachers string `json:"teachers" validate:"omitempty,oneof=stay follow"`
+	// AlumniClass is where graduates go, "Graduated <from_year>" by default
+	AlumniClass string `json:"alumni_class" validate:"omitempty,max=50"`
+	// Archive builds the from_year archive before anyone moves (default true)
+	Archive *bool `json:"archive"`
+}
+
+// RolloverClass maps one class of this year to next year's
+type RolloverClass struct {
+	Class     string `json:"class"`
+	NextClass string `json:"next_class,omitempty"` // Empty when the class graduates
+	Students  int    `json:"students"`
+	Guessed   bool   `json:"guessed,omitempty"` // NextClass is the planner's guess
+}
+
+// RolloverStudent is what happens to one student. Their action follows their
+// class's mapping until an admin overrides it.
+type RolloverStudent struct {
+	StudentID  int    `json:"student_id"`
+	Name       string `json:"name"`
+	Class      string `json:"class"`
+	Action     string `json:"action"`
+	NextClass  string `json:"next_class"`
+	Overridden bool   `json:"overridden,omitempty"`
+	Moved      bool   `json:"moved,omitempty"` // Set once the plan was executed
+}
+
+// RolloverTeacher is a teacher's home class and assigned classes, now and next year
+type RolloverTeacher struct {
+	TeacherID     int      `json:"teacher_id"`
+	Name          string   `json:"name"`
+	HomeClass     string   `json:"home_class"`
+	NextHomeClass string   `json:"next_home_class"`
+	Classes       []string `json:"classes"`
+	NextClasses   []string `json:"next_classes"`
+}
+
+// Changed says whether executing the plan changes anything for the teacher
+func (t RolloverTeacher) Changed() bool {
+	if t.HomeClass != t.NextHomeClass || len(t.Classes) != len(t.NextClasses) {
+		return true
+	}
+	for i := range t.Classes {
+		if t.Classes[i] != t.NextClasses[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// RolloverProgress tells how far an executing plan got
+type RolloverProgress struct {
+	Step  string `json:"step,omitempty"` // "archive", "students" or "teachers"
+	Done  int    `json:"done"`
+	Total int    `json:"total"`
+}
+
+// RolloverPlan is a school-year rollover, from the planner's proposal through
+// the admins' adjustments to its execution
+type RolloverPlan struct {
+	ID          int               `json:"id"`
+	FromYear    string            `json:"from_year"`
+	ToYear      string            `json:"to_year"`
+	Status      string            `json:"status"`
+	AlumniClass string            `json:"alumni_class"`
+	Archive     bool              `json:"archive"`
+	Classes     []RolloverClass   `json:"classes"`
+	Students    []RolloverStudent `json:"students"`
+	Teachers    []RolloverTeacher `json:"teachers"`
+	Progress    RolloverProgress  `json:"progress"`
+	Error       string            `json:"error,omitempty"`
+	ArchiveID   *int              `json:"archive_id,omitempty"`
+	CreatedBy   *int              `json:"created_by,omitempty"`
+	CreatedAt   time.Time         `json:"created_at"`
+	ExecutedBy  *int              `json:"executed_by,omitempty"`
+	ExecutedAt  *time.Time        `json:"executed_at,omitempty"`
+	FinishedAt  *time.Time        `json:"finished_at,omitempty"`
+}
+
+// Editable says whether the plan can still be adjusted and executed
+func (p RolloverPlan) Editable() bool {
+	return p.Status == RolloverDraft || p.Status == JobFailed
+}
+
+// RolloverPatch is the body of PATCH /admin/rollover/plans/{id}. Only what it
+// sets changes; a class's students follow its new mapping unless overridden.
+type RolloverPatch struct {
+	// NextClass remaps classes, "" making one graduate
+	NextClass map[string]string `json:"next_class" validate:"dive,keys,required,max=50,endkeys,max=50"`
+	// Students sets actions by student ID; "" hands a student back to their class's mapping
+	Students    map[int]string                `json:"students" validate:"dive,omitempty,oneof=promote repeat graduate"`
+	Teachers    map[int]RolloverTeacherChange `json:"teachers" validate:"dive"`
+	AlumniClass *string                       `json:"alumni_class" validate:"omitempty,min=1,max=50"`
+	Archive     *bool                         `json:"archive"`
+}
+
+// RolloverTeacherChange sets a teacher's home class or classes for next year
+type RolloverTeacherChange struct {
+	HomeClass *string  `json:"home_class" validate:"omitempty,max=50"`
+	Classes   []string `json:"classes" validate:"omitempty,max=50,dive,required,max=50"`
+}
+
+// RolloverExecution is the body of POST /admin/rollover/execute
+type RolloverExecution struct {
+	PlanID int `json:"plan_id" validate:"required,min=1"`
+}
@@ -0,0 +1,407 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// JSONSchemaDialect is the JSON Schema version of GET /schemas/{entity}
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema is the subset of JSON Schema the validate tags translate to
+type JSONSchema struct {
+	Schema           string        `json:"$schema,omitempty"`
+	Title            string        `json:"title,omitempty"`
+	Type             string        `json:"type,omitempty"`
+	Format           string        `json:"format,omitempty"` // "phone" is ours: anything the server can read as a phone number
+	Pattern          string        `json:"pattern,omitempty"`
+	Enum             []any         `json:"enum,omitempty"`
+	Const            any           `json:"const,omitempty"`
+	MinLength        *int          `json:"minLength,omitempty"`
+	MaxLength        *int          `json:"maxLength,omitempty"`
+	Minimum          *float64      `json:"minimum,omitempty"`
+	Maximum          *float64      `json:"maximum,omitempty"`
+	ExclusiveMinimum *float64      `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum *float64      `json:"exclusiveMaximum,omitempty"`
+	MinItems         *int          `json:"minItems,omitempty"`
+	MaxItems         *int          `json:"maxItems,omitempty"`
+	Items            *JSONSchema   `json:"items,omitempty"`
+	Properties       SchemaProps   `json:"properties,omitempty"`
+	PropertyNames    *JSONSchema   `json:"propertyNames,omitempty"`
+	Additional       any           `json:"additionalProperties,omitempty"` // false, or the *JSONSchema of map values
+	Required         []string      `json:"required,omitempty"`
+	AllOf            []*JSONSchema `json:"allOf,omitempty"` // Conditional requirements (required_if and friends)
+	If               *JSONSchema   `json:"if,omitempty"`
+	Then             *JSONSchema   `json:"then,omitempty"`
+	Not              *JSONSchema   `json:"not,omitempty"`
+	WriteOnly        bool          `json:"writeOnly,omitempty"`
+}
+
+// SchemaProp is one property of an object schema
+type SchemaProp struct {
+	Name   string
+	Schema *JSONSchema
+}
+
+// SchemaProps keeps the properties in struct order, which forms render them in
+type SchemaProps []SchemaProp
+
+func (p SchemaProps) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, prop := range p {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(prop.Name)
+		schema, err := json.Marshal(prop.Schema)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(schema)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// SchemaEntity is a payload GET /schemas/{entity} describes: the JSON fields
+// of Model a create or edit form fills in, in form order. Custom names the
+// entity whose custom fields (see CustomField) go under "custom_fields".
+type SchemaEntity struct {
+	Model     any
+	Fields    []string
+	WriteOnly []string // Sent on create, never shown back (passwords)
+	Custom    string
+}
+
+// SchemaEntities are the entities the admin frontend builds forms for
+var SchemaEntities = map[string]SchemaEntity{
+	"teacher": {
+		Model:     Teacher{},
+		Fields:    []string{"first_name", "last_name", "email", "phone", "class", "subject", "password"},
+		WriteOnly: []string{"password"},
+	},
+	"student": {
+		Model:  Student{},
+		Fields: []string{"first_name", "last_name", "email", "class", "admission_number", "guardian_phone", "status", "date_of_birth", "gender", "nationality", "enrollment_date"},
+		Custom: CustomFieldStudent,
+	},
+	"event": {
+		Model:  Event{},
+		Fields: []string{"title", "description", "type", "start_date", "end_date", "audience", "class"},
+	},
+	"grading_scheme": {
+		Model:  GradingScheme{},
+		Fields: []string{"name", "subject", "boundaries"},
+	},
+	"custom_field": {
+		Model:  CustomField{},
+		Fields: []string{"entity", "key", "label", "type", "options", "required"},
+	},
+}
+
+// Schema builds the entity's JSON Schema from its struct's validate tags,
+// with custom, the definitions of its custom fields. Rules checked in code
+// (CheckDates and the like) aren't in it: the server still has the last word.
+func (e SchemaEntity) Schema(name string, custom []CustomField) *JSONSchema {
+	s := structSchema(reflect.TypeOf(e.Model), e.Fields)
+	s.Schema = JSONSchemaDialect
+	s.Title = schemaTitle(name)
+	for _, p := range s.Properties {
+		for _, w := range e.WriteOnly {
+			if p.Name == w {
+				p.Schema.WriteOnly = true
+			}
+		}
+	}
+	if e.Custom != "" {
+		s.Properties = append(s.Properties, SchemaProp{Name: "custom_fields", Schema: customFieldsSchema(custom)})
+	}
+	return s
+}
+
+func customFieldsSchema(defs []CustomField) *JSONSchema {
+	s := &JSONSchema{Type: "object", Additional: false}
+	for _, d := range defs {
+		p := &JSONSchema{Title: d.Label}
+		switch d.Type {
+		case CustomNumber:
+			p.Type = "number"
+		case CustomDate:
+			p.Type, p.Format = "string", "date"
+		case CustomEnum:
+			p.Type = "string"
+			for _, o := range d.Options {
+				p.Enum = append(p.Enum, o)
+			}
+		default:
+			p.Type, p.MaxLength = "string", intPtr(MaxCustomText)
+		}
+		s.Properties = append(s.Properties, SchemaProp{Name: d.Key, Schema: p})
+		if d.Required {
+			s.Required = append(s.Required, d.Key)
+		}
+	}
+	return s
+}
+
+// structSchema describes a struct's JSON fields; only is the fields to keep,
+// all of them when nil
+func structSchema(t reflect.Type, only []string) *JSONSchema {
+	s := &JSONSchema{Type: "object", Additional: false}
+	fields := jsonFields(t)
+	names := only
+	if names == nil {
+		for _, f := range fields {
+			names = append(names, f.name)
+		}
+	}
+	for _, name := range names {
+		f, ok := findField(fields, name)
+		if !ok {
+			panic(fmt.Sprintf("models: %s has no JSON field %q", t.Name(), name))
+		}
+		prop, required, conditions := fieldSchema(f.field.Type, f.field.Tag.Get("validate"), fields)
+		prop.Title = schemaTitle(name)
+		s.Properties = append(s.Properties, SchemaProp{Name: name, Schema: prop})
+		if required {
+			s.Required = append(s.Required, name)
+		}
+		for _, c := range conditions {
+			c.Then = &JSONSchema{Required: []string{name}}
+			s.AllOf = append(s.AllOf, c)
+		}
+	}
+	return s
+}
+
+type jsonField struct {
+	name  string
+	field reflect.StructField
+}
+
+// jsonFields lists the struct's fields by JSON name, embedded ones inlined
+func jsonFields(t reflect.Type) []jsonField {
+	var fields []jsonField
+	for i := range t.NumField() {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if f.Anonymous && name == "" {
+			fields = append(fields, jsonFields(f.Type)...)
+			continue
+		}
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, jsonField{name: name, field: f})
+	}
+	return fields
+}
+
+func findField(fields []jsonField, name string) (jsonField, bool) {
+	for _, f := range fields {
+		if f.name == name {
+			return f, true
+		}
+	}
+	return jsonField{}, false
+}
+
+// fieldSchema translates a field's type and validate tag. It reports whether
+// the field is required outright, and the if-clauses under which it is.
+func fieldSchema(t reflect.Type, tag string, siblings []jsonField) (*JSONSchema, bool, []*JSONSchema) {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	s := typeSchema(t)
+	rules := strings.Split(tag, ",")
+	var required bool
+	var conditions []*JSONSchema
+	for i, rule := range rules {
+		name, param, _ := strings.Cut(rule, "=")
+		switch name {
+		case "":
+		case "dive":
+			// The rest of the tag is about the elements (or, between keys and
+			// endkeys, the map's keys)
+			rest := rules[i+1:]
+			if len(rest) > 0 && rest[0] == "keys" {
+				end := len(rest)
+				for j, r := range rest {
+					if r == "endkeys" {
+						end = j
+						break
+					}
+				}
+				s.PropertyNames, _, _ = fieldSchema(t.Key(), strings.Join(rest[1:end], ","), nil)
+				rest = rest[min(end+1, len(rest)):]
+			}
+			elem, _, _ := fieldSchema(t.Elem(), strings.Join(rest, ","), nil)
+			if t.Kind() == reflect.Map {
+				s.Additional = elem
+			} else {
+				s.Items = elem
+			}
+			return s, required, conditions
+		case "required":
+			required = true
+			if t.Kind() == reflect.String {
+				s.MinLength = intPtr(1)
+			}
+		case "required_if", "required_unless", "required_without":
+			if c := requiredCondition(name, param, siblings); c != nil {
+				conditions = append(conditions, c)
+			}
+		default:
+			applyRule(s, t.Kind(), name, param)
+		}
+	}
+	return s, required, conditions
+}
+
+// requiredCondition turns a conditional required rule into an if-clause
+// over the sibling fields, by their JSON names
+func requiredCondition(rule, param string, siblings []jsonField) *JSONSchema {
+	jsonName := func(goName string) string {
+		for _, f := range siblings {
+			if f.field.Name == goName {
+				return f.name
+			}
+		}
+		return ""
+	}
+	if rule == "required_without" {
+		other := jsonName(param)
+		if other == "" {
+			return nil
+		}
+		return &JSONSchema{If: &JSONSchema{Not: &JSONSchema{Required: []string{other}}}}
+	}
+
+	// Pairs of field and value, all of which must hold (required_if) or not (required_unless)
+	words := strings.Fields(param)
+	match := &JSONSchema{}
+	for i := 0; i+1 < len(words); i += 2 {
+		other := jsonName(words[i])
+		if other == "" {
+			return nil
+		}
+		var value any = words[i+1]
+		if n, err := strconv.ParseFloat(words[i+1], 64); err == nil {
+			value = n
+		}
+		match.Properties = append(match.Properties, SchemaProp{Name: other, Schema: &JSONSchema{Const: value}})
+		match.Required = append(match.Required, other)
+	}
+	if rule == "required_unless" {
+		match = &JSONSchema{Not: match}
+	}
+	return &JSONSchema{If: match}
+}
+
+func typeSchema(t reflect.Type) *JSONSchema {
+	switch t.Kind() {
+	case reflect.String:
+		return &JSONSchema{Type: "string"}
+	case reflect.Bool:
+		return &JSONSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &JSONSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &JSONSchema{Type: "array", Items: typeSchema(t.Elem())}
+	case reflect.Map:
+		return &JSONSchema{Type: "object"}
+	case reflect.Struct:
+		return structSchema(t, nil)
+	}
+	return &JSONSchema{}
+}
+
+// Formats of the validator's string rules
+var (
+	schemaFormats = map[string]string{"email": "email", "url": "uri", "uuid": "uuid", "phone": "phone"}
+	e164Pattern   = `^\+[1-9][0-9]{1,14}$`
+	alpha2Pattern = `^[A-Z]{2}$`
+)
+
+// dateLayouts are the datetime= layouts with a JSON Schema format
+var dateLayouts = map[string]string{DateLayout: "date", "2006-01-02T15:04:05Z07:00": "date-time"}
+
+// applyRule adds one rule of a validate tag; rules with no JSON Schema
+// equivalent are left to the server
+func applyRule(s *JSONSchema, kind reflect.Kind, rule, param string) {
+	n, _ := strconv.ParseFloat(param, 64)
+	size := intPtr(int(n))
+	switch {
+	case schemaFormats[rule] != "":
+		s.Format = schemaFormats[rule]
+	case rule == "e164":
+		s.Pattern = e164Pattern
+	case rule == "iso3166_1_alpha2":
+		s.Pattern = alpha2Pattern
+	case rule == "startswith":
+		s.Pattern = "^" + regexp.QuoteMeta(param)
+	case rule == "datetime":
+		if f, ok := dateLayouts[param]; ok {
+			s.Format = f
+		}
+	case rule == "oneof":
+		for _, v := range strings.Fields(param) {
+			if s.Type == "string" {
+				s.Enum = append(s.Enum, v)
+			} else if n, err := strconv.ParseFloat(v, 64); err == nil {
+				s.Enum = append(s.Enum, n)
+			}
+		}
+	case kindOf(kind) == "number":
+		switch rule {
+		case "min", "gte":
+			s.Minimum = &n
+		case "max", "lte":
+			s.Maximum = &n
+		case "gt":
+			s.ExclusiveMinimum = &n
+		case "lt":
+			s.ExclusiveMaximum = &n
+		}
+	case kindOf(kind) == "items":
+		switch rule {
+		case "min", "gte":
+			s.MinItems = size
+		case "max", "lte":
+			s.MaxItems = size
+		case "len":
+			s.MinItems, s.MaxItems = size, size
+		}
+	case kind == reflect.String:
+		switch rule {
+		case "min", "gte":
+			s.MinLength = size
+		case "max", "lte":
+			s.MaxLength = size
+		case "len":
+			s.MinLength, s.MaxLength = size, size
+		}
+	}
+}
+
+// schemaTitle is a label for a JSON name, e.g. "Date of birth" for date_of_birth
+func schemaTitle(name string) string {
+	words := strings.ReplaceAll(name, "_", " ")
+	return strings.ToUpper(words[:1]) + words[1:]
+}
+
+func intPtr(n int) *int { return &n }
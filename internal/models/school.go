@@ -0,0 +1,83 @@
+package models
+
+import (
+	"strconv"
+	"time"
+)
+
+// School is the school's profile, one per deployment, edited with PUT
+// /admin/school. Transcripts, ID cards, the iCal feed, emails and SMS print it.
+type School struct {
+	Name         string `json:"name" validate:"required,max=200"`
+	Address      string `json:"address" validate:"max=500"`
+	ContactEmail string `json:"contact_email" validate:"omitempty,email,max=254"`
+	// Timezone is the school's IANA zone; empty falls back to SCHOOL_TIMEZONE
+	Timezone    string `json:"timezone" validate:"omitempty,timezone"`
+	CurrentTerm string `json:"current_term" validate:"max=20"` // e.g. "2025/26-T1"
+
+	// HasLogo is set by PUT /admin/school/logo, not by the profile's body
+	HasLogo bool `json:"has_logo"`
+	// Quotas are set by PUT /admin/school/quotas, not by the profile's body either
+	Quotas SchoolQuotas `json:"quotas"`
+	// AttendanceThresholds are set by PUT /admin/school/attendance-thresholds
+	AttendanceThresholds AttendanceThresholds `json:"attendance_thresholds"`
+	UpdatedAt            *time.Time           `json:"updated_at,omitempty"`
+	UpdatedBy            *int                 `json:"updated_by,omitempty"`
+}
+
+// PublicSchool is the part of the profile GET /school shows without logging in
+type PublicSchool struct {
+	Name         string `json:"name"`
+	Address      string `json:"address,omitempty"`
+	ContactEmail string `json:"contact_email,omitempty"`
+	CurrentTerm  string `json:"current_term,omitempty"`
+	LogoURL      string `json:"logo_url,omitempty"`
+}
+
+// Public picks the fields anyone may see
+func (s School) Public() PublicSchool {
+	p := PublicSchool{Name: s.Name, Address: s.Address, ContactEmail: s.ContactEmail, CurrentTerm: s.CurrentTerm}
+	if s.HasLogo {
+		p.LogoURL = "/api/v1/school/logo"
+	}
+	return p
+}
+
+// DiffSchool lists the profile fields an update changes, for the audit log
+func DiffSchool(before, after School) map[string]FieldChange {
+	changes := make(map[string]FieldChange)
+	for _, f := range []struct {
+		name     string
+		old, new string
+	}{
+		{"name", before.Name, after.Name},
+		{"address", before.Address, after.Address},
+		{"contact_email", before.ContactEmail, after.ContactEmail},
+		{"timezone", before.Timezone, after.Timezone},
+		{"current_term", before.CurrentTerm, after.CurrentTerm},
+	} {
+		if f.old != f.new {
+			changes[f.name] = FieldChange{From: f.old, To: f.new}
+		}
+	}
+	if before.HasLogo != after.HasLogo {
+		changes["logo"] = FieldChange{From: strconv.FormatBool(before.HasLogo), To: strconv.FormatBool(after.HasLogo)}
+	}
+	for _, f := range []struct {
+		name     string
+		old, new int64
+	}{
+		{"max_students", int64(before.Quotas.MaxStudents), int64(after.Quotas.MaxStudents)},
+		{"max_storage_bytes", before.Quotas.MaxStorageBytes, after.Quotas.MaxStorageBytes},
+		{"requests_per_minute", int64(before.Quotas.RequestsPerMinute), int64(after.Quotas.RequestsPerMinute)},
+		{"attendance_notice_below", int64(before.AttendanceThresholds.NoticeBelow), int64(after.AttendanceThresholds.NoticeBelow)},
+		{"attendance_escalate_below", int64(before.AttendanceThresholds.EscalateBelow), int64(after.AttendanceThresholds.EscalateBelow)},
+		{"attendance_window_days", int64(before.AttendanceThresholds.WindowDays), int64(after.AttendanceThresholds.WindowDays)},
+		{"attendance_min_days", int64(before.AttendanceThresholds.MinDays), int64(after.AttendanceThresholds.MinDays)},
+	} {
+		if f.old != f.new {
+			changes[f.name] = FieldChange{From: strconv.FormatInt(f.old, 10), To: strconv.FormatInt(f.new, 10)}
+		}
+	}
+	return changes
+}
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"golang.org/x/text/collate"
+	"golang.org/x/text/language"
+)
+
+// NameCollation is what name filters compare with in MySQL: accent- and
+// case-insensitive, so "SOREN" finds Søren and "Jose" finds José
+const NameCollation = "utf8mb4_0900_ai_ci"
+
+// NamesMatch compares two names like NameCollation does (both follow the
+// Unicode collation algorithm), for the in-memory stores
+func NamesMatch(a, b string) bool {
+	// A Collator keeps scratch buffers, so it can't be shared between goroutines
+	c := collate.New(language.Und, collate.IgnoreCase, collate.IgnoreDiacritics, collate.IgnoreWidth)
+	return c.CompareString(a, b) == 0
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// SecuritySummary is the answer of GET /admin/security/summary: authentication
+// events over the last day and week, as counted by this server process
+type SecuritySummary struct {
+	Since   time.Time        `json:"since"` // Process start: nothing earlier was counted
+	Windows []SecurityWindow `json:"windows"`
+}
+
+// SecurityWindow counts authentication events by kind (see metrics.AuthEvents)
+// and ranks the client IPs behind the failures
+type SecurityWindow struct {
+	Window string         `json:"window"` // e.g. "24h"
+	From   time.Time      `json:"from"`   // Start of the oldest hour counted
+	Counts map[string]int `json:"counts"`
+	TopIPs []IPCount      `json:"top_ips"`
+}
+
+// IPCount is how many failed authentications came from one client IP
+type IPCount struct {
+	IP       string `json:"ip"`
+	Failures int    `json:"failures"`
+}
@@ -1,19 +1,258 @@
 package models
 
+import (
+	"fmt"
+	"simpleapi/internal/expr"
+	"time"
+)
+
+// Genders recorded for statutory reporting
+const (
+	GenderFemale = "female"
+	GenderMale   = "male"
+	GenderOther  = "other"
+)
+
+// Lifecycle states of a student. Package enrollment holds the moves between
+// them; only enrolled students are on their class's roster.
+const (
+	StudentApplicant = "applicant" // Applied, not admitted yet
+	StudentEnrolled  = "enrolled"
+	StudentSuspended = "suspended"
+	StudentWithdrawn = "withdrawn" // Left the school; may re-enroll
+	StudentAlumni    = "alumni"    // Graduated
+)
+
 type Student struct {
 	ID        int    `json:"id,omitempty"`
 	FirstName string `json:"first_name,omitempty" validate:"required"`
 	LastName  string `json:"last_name,omitempty" validate:"required"`
-	Email     string `json:"email,omitempty" validate:"required,email"`
+	Email     string `json:"email,omitempty" validate:"required,email" visibility:"admin,registrar,teacher"`
 	Class     string `json:"class,omitempty" validate:"required"`
+	// AdmissionNumber is the school-issued ID printed on cards and used to key bulk imports
+	AdmissionNumber string `json:"admission_number,omitempty" validate:"omitempty,max=32"`
+	// GuardianPhone is the parent or guardian SMS notifications go to, stored in E.164
+	GuardianPhone string `json:"guardian_phone,omitempty" validate:"omitempty,phone" visibility:"admin,registrar,teacher,nurse"`
+	// Status is one of the lifecycle states, enrolled when a new student
+	// leaves it out. Later changes go through POST /students/{id}/status.
+	Status string `json:"status,omitempty" validate:"omitempty,oneof=applicant enrolled"`
+
+	// --- DEMOGRAPHICS (statutory returns) ---
+	// Dates are YYYY-MM-DD, like event dates. With RESPONSE_REDACTION on, only
+	// the office sees them (see package redact), and contact details only staff.
+	DateOfBirth    string `json:"date_of_birth,omitempty" validate:"omitempty,datetime=2006-01-02" visibility:"admin,registrar,nurse"`
+	Gender         string `json:"gender,omitempty" validate:"omitempty,oneof=female male other" visibility:"admin,registrar"`
+	Nationality    string `json:"nationality,omitempty" validate:"omitempty,iso3166_1_alpha2" visibility:"admin,registrar"` // e.g. "NG"
+	EnrollmentDate string `json:"enrollment_date,omitempty" validate:"omitempty,datetime=2006-01-02"`
+
+	// CustomFields are the values of the fields admins defined (see CustomField),
+	// checked against the definitions by the handler. What a school records
+	// there is up to it, so only staff who are logged in see them.
+	CustomFields map[string]any `json:"custom_fields,omitempty" visibility:"admin,registrar,teacher,nurse"`
+}
+
+// StudentPatch is the body of PATCH /students/{id}. Only custom fields can be
+// changed this way; a null value removes one.
+type StudentPatch struct {
+	CustomFields map[string]any `json:"custom_fields" validate:"required"`
+}
+
+// CheckDates reports date rules the tags can't express: nobody is born in the
+// future or enrolled before birth. today is the school's current date (YYYY-MM-DD).
+func (s Student) CheckDates(today string) []ValidationError {
+	var errs []ValidationError
+	if s.DateOfBirth != "" && s.DateOfBirth > today { // ISO dates compare correctly as strings
+		errs = append(errs, RuleError("DateOfBirth", "date_in_future", ""))
+	}
+	if s.DateOfBirth != "" && s.EnrollmentDate != "" && s.EnrollmentDate < s.DateOfBirth {
+		errs = append(errs, RuleError("EnrollmentDate", "enrolled_before_birth", ""))
+	}
+	return errs
+}
+
+// AgeOn returns the age in completed years on the given date, and false when
+// the date of birth is unknown. It matches TIMESTAMPDIFF(YEAR, dob, day) in MySQL.
+func AgeOn(dateOfBirth, day string) (int, bool) {
+	dob, err := time.Parse(DateLayout, dateOfBirth)
+	if err != nil {
+		return 0, false
+	}
+	on, err := time.Parse(DateLayout, day)
+	if err != nil {
+		return 0, false
+	}
+	age := on.Year() - dob.Year()
+	if on.Month() < dob.Month() || (on.Month() == dob.Month() && on.Day() < dob.Day()) {
+		age--
+	}
+	return age, true
+}
+
+// BornBy is the latest date of birth (YYYY-MM-DD) of someone who is at least
+// years old on day, as AgeOn counts
+func BornBy(day string, years int) string {
+	on, err := time.Parse(DateLayout, day)
+	if err != nil {
+		return day
+	}
+	y, m, d := on.Date()
+	if m == time.February && d == 29 && !isLeap(y-years) {
+		d = 28
+	}
+	return time.Date(y-years, m, d, 0, 0, 0, 0, time.UTC).Format(DateLayout)
+}
+
+func isLeap(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
+
+// NormalizePhone rewrites GuardianPhone in E.164 (see Teacher.NormalizePhone)
+func (s *Student) NormalizePhone() {
+	if normalized, err := ParsePhone(s.GuardianPhone); err == nil {
+		s.GuardianPhone = normalized
+	}
+}
+
+// DiffStudent lists the fields that differ between before and after.
+// Diffing against a zero Student gives the initial values of a new student.
+func DiffStudent(before, after Student) map[string]FieldChange {
+	changes := make(map[string]FieldChange)
+	fields := []struct {
+		name     string
+		old, new string
+	}{
+		{"first_name", before.FirstName, after.FirstName},
+		{"last_name", before.LastName, after.LastName},
+		{"email", before.Email, after.Email},
+		{"class", before.Class, after.Class},
+		{"admission_number", before.AdmissionNumber, after.AdmissionNumber},
+		{"guardian_phone", before.GuardianPhone, after.GuardianPhone},
+		{"status", before.Status, after.Status},
+		{"date_of_birth", before.DateOfBirth, after.DateOfBirth},
+		{"gender", before.Gender, after.Gender},
+		{"nationality", before.Nationality, after.Nationality},
+		{"enrollment_date", before.EnrollmentDate, after.EnrollmentDate},
+	}
+	for _, f := range fields {
+		if f.old != f.new {
+			changes[f.name] = FieldChange{From: f.old, To: f.new}
+		}
+	}
+	// Custom fields, by the union of their keys
+	for key := range MergeCustomValues(before.CustomFields, after.CustomFields) {
+		from, to := customValueText(before.CustomFields, key), customValueText(after.CustomFields, key)
+		if from != to {
+			changes["custom_fields."+key] = FieldChange{From: from, To: to}
+		}
+	}
+	return changes
 }
 
+func customValueText(values map[string]any, key string) string {
+	if v, ok := values[key]; ok {
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+// StudentFilter is the query string of student listings, read by utils.BindQuery;
+// Condition turns it into the repository's query.Options.Where
 type StudentFilter struct {
-	FirstName string
-	LastName  string
-	Email     string
-	Class     string
+	FirstName string `query:"first_name"`
+	LastName  string `query:"last_name"`
+	Email     string `query:"email"`
+	Class     string `query:"class"`
+	Status    string `query:"status" validate:"omitempty,oneof=applicant enrolled suspended withdrawn alumni"`
+	// GuardianPhone is E.164; the handler normalizes what the client typed
+	GuardianPhone string `query:"guardian_phone" validate:"omitempty,phone"`
+	Gender        string `query:"gender" validate:"omitempty,oneof=female male other"`
+	Nationality   string `query:"nationality"`
+
+	// MinAge and MaxAge (nil = no bound) are ages in completed years on Today
+	// (the school's date, YYYY-MM-DD); students without a date of birth never match
+	MinAge *int `query:"min_age" validate:"omitempty,gte=0"`
+	MaxAge *int `query:"max_age" validate:"omitempty,gte=0"`
+	Today  string
+
+	// Expr is ?filter=, an expression over StudentFields (see package expr)
+	// for what the parameters above can't say; the handler parses it into Where
+	Expr  string `query:"filter"`
+	Where expr.Expr
+
+	SortBy    string `query:"sortby" echo:"-"` // One of StudentSorts, e.g. "email"
+	SortOrder string `query:"order" echo:"-"`  // "ASC" or "DESC"
+}
+
+// Condition is the filter as one expression over StudentFields, for
+// query.Options. The age bounds become bounds on date_of_birth (see BornBy),
+// which leave out students without one.
+func (f StudentFilter) Condition() expr.Expr {
+	var minAge, maxAge expr.Expr
+	if f.MinAge != nil {
+		minAge = expr.Compare(StudentFields, "date_of_birth", "le", BornBy(f.Today, *f.MinAge))
+	}
+	if f.MaxAge != nil {
+		maxAge = expr.Compare(StudentFields, "date_of_birth", "gt", BornBy(f.Today, *f.MaxAge+1))
+	}
+	return expr.All(
+		equalUnlessEmpty(StudentFields, "first_name", f.FirstName),
+		equalUnlessEmpty(StudentFields, "last_name", f.LastName),
+		equalUnlessEmpty(StudentFields, "email", f.Email),
+		equalUnlessEmpty(StudentFields, "class", f.Class),
+		equalUnlessEmpty(StudentFields, "status", f.Status),
+		equalUnlessEmpty(StudentFields, "guardian_phone", f.GuardianPhone),
+		equalUnlessEmpty(StudentFields, "gender", f.Gender),
+		equalUnlessEmpty(StudentFields, "nationality", f.Nationality),
+		minAge, maxAge,
+		f.Where,
+	)
+}
+
+// StudentListFields are the fields of student listings, which ?fields= picks from
+var StudentListFields = []string{"id", "first_name", "last_name", "email", "class", "admission_number",
+	"guardian_phone", "status", "date_of_birth", "gender", "nationality", "enrollment_date", "custom_fields"}
+
+// StudentSorts are the fields ?sortby= accepts
+var StudentSorts = []string{"first_name", "last_name", "email", "class", "date_of_birth"}
+
+// StudentFields are the fields ?filter= expressions can test on students
+var StudentFields = expr.Fields{
+	"id":               {Type: expr.Number},
+	"first_name":       {Type: expr.Text, Column: "first_name COLLATE " + NameCollation, Equal: NamesMatch},
+	"last_name":        {Type: expr.Text, Column: "last_name COLLATE " + NameCollation, Equal: NamesMatch},
+	"email":            {Type: expr.Text},
+	"class":            {Type: expr.Text},
+	"admission_number": {Type: expr.Text},
+	"guardian_phone":   {Type: expr.Text},
+	"status":           {Type: expr.Text},
+	"gender":           {Type: expr.Text},
+	"nationality":      {Type: expr.Text},
+	"date_of_birth":    {Type: expr.Date},
+	"enrollment_date":  {Type: expr.Date},
+}
+
+// EnrolledStudents is the condition of the students attending the school
+func EnrolledStudents() expr.Expr {
+	return expr.Equal(StudentFields, "status", StudentEnrolled)
+}
+
+// ClassRoster is the condition of the students on class's roster: its
+// enrolled students, leaving out applicants, the suspended, the withdrawn
+// and alumni
+func ClassRoster(class string) expr.Expr {
+	return expr.All(expr.Equal(StudentFields, "class", class), EnrolledStudents())
+}
+
+// OnRoll reports whether the student is still one of the school's, if
+// suspended: they move up with their class at the year's rollover
+func (s Student) OnRoll() bool {
+	return s.Status == StudentEnrolled || s.Status == StudentSuspended
+}
 
-	SortBy    string // e.g. "email"
-	SortOrder string // e.g. "ASC" or "DESC"
+// StudentStatusRequest is the body of POST /students/{id}/status. A student
+// who re-enrolls may join another class than the one they left.
+type StudentStatusRequest struct {
+	Status string `json:"status" validate:"required,oneof=applicant enrolled suspended withdrawn alumni"`
+	Reason string `json:"reason" validate:"required,max=500"`
+	Class  string `json:"class,omitempty" validate:"omitempty,max=50"` // Only with status enrolled
 }
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"simpleapi/internal/expr"
 	"time"
 )
 
@@ -9,7 +10,8 @@ type Teacher struct {
 	ID        int    `json:"id,omitempty"`
 	FirstName string `json:"first_name,omitempty" validate:"required"`
 	LastName  string `json:"last_name,omitempty" validate:"required"`
-	Email     string `json:"email,omitempty" validate:"required,email"`
+	Email     string `json:"email,omitempty" validate:"required,email" visibility:"admin,registrar,teacher,self"`
+	Phone     string `json:"phone,omitempty" validate:"omitempty,phone" visibility:"admin,registrar,self"` // Stored in E.164
 	Role      string `json:"role"`
 	// --- SCHOOL DATA FIELDS ---
 	Class   string `json:"class,omitempty" validate:"required"`
@@ -22,24 +24,108 @@ type Teacher struct {
 	PasswordResetToken   *string    `json:"-"`
 	PasswordResetExpires *time.Time `json:"-"`
 
+	// ForcePasswordChange locks the account out of everything but PATCH
+	// /update-password, e.g. after login found the password in a breach
+	ForcePasswordChange bool `json:"force_password_change,omitempty"`
+
+	// --- DIRECTORY ---
+	// Opt-in: only published teachers appear on the public staff directory
+	PublishedInDirectory bool `json:"published_in_directory"`
+
+	// --- PREFERENCES ---
+	// How dates and messages are rendered for this teacher when a request
+	// doesn't say (see package locale); empty means the school's default
+	Language string `json:"language,omitempty"`
+	Timezone string `json:"timezone,omitempty"`
+
 	// --- META FIELDS ---
 	CreatedAt time.Time  `json:"created_at"`
 	UpdatedAt time.Time  `json:"updated_at"`
 	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	DeletedBy *int       `json:"deleted_by,omitempty"`
 	IsActive  bool       `json:"is_active"`
 }
 
-// TeacherFilter allows the Handler to tell the Repo what to search for
-// without passing the raw *http.Request
+// OwnerID makes a teacher's own account visible to them (visibility:"self",
+// see package redact)
+func (t Teacher) OwnerID() int {
+	return t.ID
+}
+
+// TeacherFilter is the query string of teacher listings (the handler binds it
+// with utils.BindQuery); Condition turns it into the repository's query.Options.Where
 type TeacherFilter struct {
-	FirstName string
-	LastName  string
-	Email     string
-	Class     string
-	Subject   string
+	FirstName string `query:"first_name"`
+	LastName  string `query:"last_name"`
+	Email     string `query:"email"`
+	Phone     string `query:"phone" validate:"omitempty,phone"` // E.164; the handler normalizes what the client typed
+	Class     string `query:"class"`
+	Subject   string `query:"subject"`
+
+	// Expr is ?filter=, an expression over TeacherFields (see package expr);
+	// the handler parses it into Where
+	Expr  string `query:"filter"`
+	Where expr.Expr
+
+	SortBy    string `query:"sortby" echo:"-"` // One of TeacherSorts, e.g. "email"
+	SortOrder string `query:"order" echo:"-"`  // "ASC" or "DESC"
+}
+
+// Condition is the filter as one expression over TeacherFields, for query.Options
+func (f TeacherFilter) Condition() expr.Expr {
+	return expr.All(
+		equalUnlessEmpty(TeacherFields, "first_name", f.FirstName),
+		equalUnlessEmpty(TeacherFields, "last_name", f.LastName),
+		equalUnlessEmpty(TeacherFields, "email", f.Email),
+		equalUnlessEmpty(TeacherFields, "phone", f.Phone),
+		equalUnlessEmpty(TeacherFields, "class", f.Class),
+		equalUnlessEmpty(TeacherFields, "subject", f.Subject),
+		f.Where,
+	)
+}
+
+// equalUnlessEmpty is field eq value, or nil (no condition) for an empty parameter
+func equalUnlessEmpty(fields expr.Fields, name, value string) expr.Expr {
+	if value == "" {
+		return nil
+	}
+	return expr.Equal(fields, name, value)
+}
+
+// TeacherListFields are the fields of teacher listings, which ?fields= picks from
+var TeacherListFields = []string{"id", "first_name", "last_name", "email", "phone", "class", "subject"}
+
+// TeacherSorts are the fields ?sortby= accepts
+var TeacherSorts = []string{"first_name", "last_name", "email", "class", "subject"}
+
+// TeacherFields are the fields ?filter= expressions can test on teachers
+var TeacherFields = expr.Fields{
+	"id":         {Type: expr.Number},
+	"first_name": {Type: expr.Text, Column: "first_name COLLATE " + NameCollation, Equal: NamesMatch},
+	"last_name":  {Type: expr.Text, Column: "last_name COLLATE " + NameCollation, Equal: NamesMatch},
+	"email":      {Type: expr.Text},
+	"phone":      {Type: expr.Text},
+	"class":      {Type: expr.Text},
+	"subject":    {Type: expr.Text},
+	"role":       {Type: expr.Text},
+	"is_active":  {Type: expr.Bool},
+}
 
-	SortBy    string // e.g. "email"
-	SortOrder string // e.g. "ASC" or "DESC"
+// ClassAssignments is the body and response of /admin/teachers/{id}/classes:
+// the classes a teacher teaches besides the one they are class teacher of
+// (Teacher.Class), e.g. as a subject teacher
+type ClassAssignments struct {
+	TeacherID int      `json:"teacher_id"`
+	HomeClass string   `json:"home_class"`
+	Classes   []string `json:"classes" validate:"max=50,dive,required,max=50"`
+}
+
+// NormalizePhone rewrites Phone in E.164. Call it after validation, which has
+// already rejected numbers that can't be normalized.
+func (t *Teacher) NormalizePhone() {
+	if normalized, err := ParsePhone(t.Phone); err == nil {
+		t.Phone = normalized
+	}
 }
 
 // Internal/models/teacher.go
@@ -71,3 +157,98 @@ func (t *Teacher) ChangedPasswordAfter(jwtTimestamp int64) bool {
 
 	return passwordChangedTimestamp > jwtTimestamp
 }
+
+// DeleteTeacherOptions is the cascade policy for deleting a teacher
+type DeleteTeacherOptions struct {
+	// ReassignTo is the teacher who takes over the class. When 0, deletion is
+	// blocked if it would leave a class with students but no teacher.
+	ReassignTo int
+	// ActorID is who performed the delete, for the audit log
+	ActorID *int
+}
+
+// Teacher roles
+const (
+	RoleAdmin   = "admin"
+	RoleTeacher = "teacher"
+	// RoleRegistrar is office staff: school-wide reports, no class rights
+	RoleRegistrar = "registrar"
+	// RoleNurse is the school nurse: students' medical records, no class rights
+	RoleNurse = "nurse"
+)
+
+// LoginRequest is the body of POST /login. CaptchaToken is the solution of the
+// CAPTCHA widget, asked for once the account failed too often (CAPTCHA_REQUIRED).
+type LoginRequest struct {
+	Email        string `json:"email"`
+	Password     string `json:"password"`
+	CaptchaToken string `json:"captcha_token,omitempty"`
+}
+
+// TeacherStatusUpdate is the body of PATCH /admin/teachers/status
+type TeacherStatusUpdate struct {
+	IDs    []int  `json:"ids" validate:"required,min=1"`
+	Status string `json:"status" validate:"required,oneof=active inactive"`
+}
+
+// DirectoryEntry is the public, unauthenticated view of a teacher.
+// Keep it minimal: anything added here is visible to the whole internet.
+type DirectoryEntry struct {
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Subject   string `json:"subject"`
+	Email     string `json:"email"`
+}
+
+// DirectoryListing is the body of PUT /teachers/{id}/directory
+type DirectoryListing struct {
+	Published bool `json:"published"`
+}
+
+// PasswordChange is the body of PATCH /update-password
+type PasswordChange struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=8,max=128"`
+}
+
+// Preferences is the body of PUT /teachers/{id}/preferences. Empty values
+// reset to the school's language and time zone.
+type Preferences struct {
+	Language string `json:"language" validate:"omitempty,language"`
+	Timezone string `json:"timezone" validate:"omitempty,timezone"` // IANA name, e.g. Africa/Nairobi
+}
+
+// FieldChange is one field's old and new value, in a change preview or an audit entry
+type FieldChange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// TeacherChange describes what a patch did (or, in a dry run, would do) to one teacher.
+// Changes is empty when the patch set every field to its current value.
+type TeacherChange struct {
+	ID      int                    `json:"id"`
+	Changes map[string]FieldChange `json:"changes"`
+}
+
+// DiffTeacher lists the patchable fields that differ between before and after
+func DiffTeacher(before, after Teacher) TeacherChange {
+	change := TeacherChange{ID: before.ID, Changes: make(map[string]FieldChange)}
+	fields := []struct {
+		name     string
+		old, new string
+	}{
+		{"first_name", before.FirstName, after.FirstName},
+		{"last_name", before.LastName, after.LastName},
+		{"email", before.Email, after.Email},
+		{"phone", before.Phone, after.Phone},
+		{"class", before.Class, after.Class},
+		{"subject", before.Subject, after.Subject},
+	}
+	for _, f := range fields {
+		if f.old != f.new {
+			change.Changes[f.name] = FieldChange{From: f.old, To: f.new}
+		}
+	}
+	return change
+}
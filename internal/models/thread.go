@@ -0,0 +1,69 @@
+package models
+
+import "time"
+
+// Thread scopes
+const (
+	ThreadStudent = "student" // About one student, with their guardian
+	ThreadClass   = "class"   // With every guardian of the class
+)
+
+// Thread is a conversation between the school and guardians about a student or
+// a class. Class is set for both scopes (a student thread takes the student's
+// class when it is opened): it decides which staff take part, see CanJoin.
+type Thread struct {
+	ID        int    `json:"id,omitempty"`
+	Scope     string `json:"scope"`
+	StudentID *int   `json:"student_id,omitempty"`
+	Class     string `json:"class"`
+	Subject   string `json:"subject"`
+
+	CreatedBy     int       `json:"created_by"`
+	CreatedAt     time.Time `json:"created_at"`
+	LastMessageAt time.Time `json:"last_message_at"`
+	// Unread counts the messages the current user hasn't read yet
+	Unread int `json:"unread"`
+}
+
+// CanJoin reports whether a staff member takes part in the thread: admins in
+// every thread, teachers in the threads of their own class
+func (t Thread) CanJoin(user Teacher) bool {
+	return user.Role == RoleAdmin || (user.Class != "" && user.Class == t.Class)
+}
+
+// ThreadMessage is one message of a thread. Messages are never edited.
+type ThreadMessage struct {
+	ID          int          `json:"id,omitempty"`
+	ThreadID    int          `json:"thread_id"`
+	SenderID    int          `json:"sender_id"`
+	Body        string       `json:"body" validate:"required,max=2000"`
+	Attachments []Attachment `json:"attachments"`
+	CreatedAt   time.Time    `json:"created_at"`
+}
+
+// Attachment is a file sent with a message. The file itself lives in the
+// uploads storage under Key; clients download it by its position in the message,
+// once its virus scan (the upload UploadID) has passed.
+type Attachment struct {
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	Key         string `json:"-"`
+	UploadID    int    `json:"upload_id,omitempty"` // 0 for files sent before uploads were scanned
+	ScanStatus  string `json:"scan_status"`         // One of the Scan* states, filled in on reads
+}
+
+// NewThread is the body of POST /threads: the thread and its first message
+type NewThread struct {
+	Scope     string `json:"scope" validate:"required,oneof=student class"`
+	StudentID *int   `json:"student_id" validate:"required_if=Scope student"`
+	Class     string `json:"class" validate:"required_if=Scope class,max=50"`
+	Subject   string `json:"subject" validate:"required,max=200"`
+	Body      string `json:"body" validate:"required,max=2000"`
+}
+
+// ThreadFilter narrows GET /threads. Teachers are always limited to their own class.
+type ThreadFilter struct {
+	StudentID int    `query:"student_id"`
+	Class     string `query:"class"`
+}
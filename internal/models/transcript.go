@@ -0,0 +1,61 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// AcademicYear extracts the year part of a term code ("2025/26-T1" -> "2025/26").
+// A term with no "-" suffix is treated as a year of its own.
+func AcademicYear(term string) string {
+	if i := strings.LastIndex(term, "-"); i > 0 {
+		return term[:i]
+	}
+	return term
+}
+
+// TranscriptSubject is a subject's final result for one academic year:
+// the average of its term scores, graded with the subject's scheme
+type TranscriptSubject struct {
+	Subject string  `json:"subject"`
+	Terms   int     `json:"terms"`
+	Average float64 `json:"average"`
+	Grade   string  `json:"grade"`
+	Points  float64 `json:"points"`
+}
+
+// TranscriptYear groups the final results of one academic year
+type TranscriptYear struct {
+	Year     string              `json:"year"`
+	Subjects []TranscriptSubject `json:"subjects"`
+	Average  float64             `json:"average"`
+	// GPA is omitted when none of the schemes involved define grade points
+	GPA *float64 `json:"gpa,omitempty"`
+}
+
+// Transcript is a student's academic record across years
+type Transcript struct {
+	Student           Student          `json:"student"`
+	Years             []TranscriptYear `json:"years"`
+	CumulativeAverage float64          `json:"cumulative_average"`
+	CumulativeGPA     *float64         `json:"cumulative_gpa,omitempty"`
+	GeneratedAt       time.Time        `json:"generated_at"`
+	// School is the issuing school as its profile stood when generated
+	School *PublicSchool `json:"school,omitempty"`
+}
+
+// SignedTranscript is the export handed to external institutions.
+// Payload is the base64url-encoded transcript JSON exactly as signed;
+// Signature is base64url HMAC-SHA256 over those bytes.
+type SignedTranscript struct {
+	Payload   string `json:"payload" validate:"required"`
+	Algorithm string `json:"alg"`
+	KeyID     string `json:"key_id"`
+	Signature string `json:"signature" validate:"required"`
+}
+
+// TranscriptVerification is the answer of POST /transcripts/verify
+type TranscriptVerification struct {
+	Valid      bool        `json:"valid"`
+	Transcript *Transcript `json:"transcript,omitempty"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// Entities that can be restored from the trash
+const (
+	TrashTeachers = "teachers"
+)
+
+// TrashItem is a soft-deleted record waiting to be restored or purged
+type TrashItem struct {
+	Entity    string    `json:"entity"`
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Email     string    `json:"email,omitempty"`
+	DeletedAt time.Time `json:"deleted_at"`
+	DeletedBy *int      `json:"deleted_by,omitempty"`
+	// PurgeAt is when the retention window closes and the record is hard-deleted
+	PurgeAt time.Time `json:"purge_at"`
+}
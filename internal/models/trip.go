@@ -0,0 +1,131 @@
+package models
+
+import (
+	"fmt"
+	"simpleapi/pkg/errcodes"
+	"time"
+)
+
+// Trip is one row of the trips table: an outing of a class on a date, which
+// each student may only go on with their guardian's consent
+type Trip struct {
+	ID          int    `json:"id,omitempty"`
+	Title       string `json:"title"`
+	Class       string `json:"class"`
+	Date        string `json:"date"` // YYYY-MM-DD
+	Destination string `json:"destination,omitempty"`
+	Details     string `json:"details,omitempty"`
+	// ConsentDeadline is the last day guardians can answer; the day before
+	// the trip when not set
+	ConsentDeadline string    `json:"consent_deadline,omitempty"`
+	CreatedBy       *int      `json:"created_by,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+// LastConsentDay is the last day, YYYY-MM-DD, a guardian's answer is taken
+func (t Trip) LastConsentDay() string {
+	if t.ConsentDeadline != "" {
+		return t.ConsentDeadline
+	}
+	day, err := time.Parse(DateLayout, t.Date)
+	if err != nil {
+		return t.Date
+	}
+	return day.AddDate(0, 0, -1).Format(DateLayout)
+}
+
+// TripRequest is the body of POST /trips
+type TripRequest struct {
+	Title           string `json:"title" validate:"required,max=150"`
+	Class           string `json:"class" validate:"required,max=50"`
+	Date            string `json:"date" validate:"required,datetime=2006-01-02"`
+	Destination     string `json:"destination,omitempty" validate:"max=200"`
+	Details         string `json:"details,omitempty" validate:"max=2000"`
+	ConsentDeadline string `json:"consent_deadline,omitempty" validate:"omitempty,datetime=2006-01-02"`
+}
+
+// States of a guardian's consent
+const (
+	ConsentPending = "pending" // Asked, no answer yet
+	ConsentGiven   = "given"
+	ConsentRefused = "refused"
+)
+
+// How a consent answer came in
+const (
+	ConsentViaLink  = "link"  // The guardian opened the link texted to them
+	ConsentViaStaff = "staff" // Staff entered it, e.g. from a paper slip
+)
+
+// TripConsent is one row of the trip_consents table: a student's consent for
+// a trip. The link texted to the guardian carries a token of which only the
+// SHA-256 hash is stored; a new request replaces it.
+type TripConsent struct {
+	TripID       int        `json:"trip_id"`
+	StudentID    int        `json:"student_id"`
+	Status       string     `json:"status"`
+	Via          string     `json:"via,omitempty"`
+	GuardianName string     `json:"guardian_name,omitempty"` // Who answered, as they signed
+	Note         string     `json:"note,omitempty"`
+	RecordedBy   *int       `json:"recorded_by,omitempty"` // Staff, for ConsentViaStaff
+	TokenHash    string     `json:"-"`
+	RequestedAt  time.Time  `json:"requested_at"`
+	RespondedAt  *time.Time `json:"responded_at,omitempty"`
+}
+
+// ConsentResponse is the body of POST /trip-consent/respond, from the link
+// texted to a guardian
+type ConsentResponse struct {
+	Token        string `json:"token" validate:"required,max=100"`
+	Decision     string `json:"decision" validate:"required,oneof=given refused"`
+	GuardianName string `json:"guardian_name" validate:"required,max=100"`
+	Note         string `json:"note,omitempty" validate:"max=500"`
+}
+
+// ConsentToken is the body of POST /trip-consent/view
+type ConsentToken struct {
+	Token string `json:"token" validate:"required,max=100"`
+}
+
+// ConsentRecord is the body of PUT /trips/{id}/consents/{student_id}: an
+// answer staff received another way
+type ConsentRecord struct {
+	Decision     string `json:"decision" validate:"required,oneof=given refused"`
+	GuardianName string `json:"guardian_name,omitempty" validate:"max=100"`
+	Note         string `json:"note,omitempty" validate:"max=500"`
+}
+
+// ConsentInvitation is what a guardian sees on opening the link: the trip,
+// and which child it's about
+type ConsentInvitation struct {
+	Trip        Trip        `json:"trip"`
+	StudentName string      `json:"student_name"`
+	Consent     TripConsent `json:"consent"`
+	LastDay     string      `json:"last_day"` // Answers are taken until the end of this day
+	CanRespond  bool        `json:"can_respond"`
+}
+
+// ConsentStatus is GET /trips/{id}/consent-status: every student of the
+// trip's class with their consent, and the totals
+type ConsentStatus struct {
+	Trip     Trip                 `json:"trip"`
+	Given    int                  `json:"given"`
+	Refused  int                  `json:"refused"`
+	Pending  int                  `json:"pending"`
+	Students []ConsentStatusEntry `json:"students"`
+}
+
+// ConsentStatusEntry is one student of a ConsentStatus. CanAttend is only
+// true with consent given.
+type ConsentStatusEntry struct {
+	StudentID       int          `json:"student_id"`
+	Name            string       `json:"name"`
+	AdmissionNumber string       `json:"admission_number,omitempty"`
+	CanAttend       bool         `json:"can_attend"`
+	Consent         *TripConsent `json:"consent"` // nil: never asked, e.g. joined the class since
+}
+
+// ErrConsentLinkInvalid is a consent token that is unknown, replaced, or past
+// the trip's last consent day
+var ErrConsentLinkInvalid error = &CodedError{Code: errcodes.ConsentLinkInvalid,
+	Err: fmt.Errorf("consent link is invalid, replaced or past its deadline: %w", ErrNotFound)}
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// Virus scan states of an upload. Only clean and released files can be downloaded.
+const (
+	ScanPending     = "pending"     // Stored, waiting for the scanner
+	ScanClean       = "clean"       // The scanner found nothing
+	ScanQuarantined = "quarantined" // The scanner flagged it; an admin reviews it
+	ScanReleased    = "released"    // Flagged, then cleared by an admin as a false positive
+	ScanRejected    = "rejected"    // Flagged and confirmed by an admin; the file is deleted
+)
+
+// Upload is one row of the uploads table: a file a user sent, kept in the
+// uploads storage under StorageKey, and the outcome of its virus scan
+type Upload struct {
+	ID          int        `json:"id,omitempty"`
+	StorageKey  string     `json:"-"`
+	Name        string     `json:"name"`
+	ContentType string     `json:"content_type"`
+	Size        int64      `json:"size"`
+	UploadedBy  int        `json:"uploaded_by"`
+	Status      string     `json:"status"`
+	Threat      string     `json:"threat,omitempty"` // What the scanner found
+	ScannedAt   *time.Time `json:"scanned_at,omitempty"`
+	ReviewedBy  *int       `json:"reviewed_by,omitempty"`
+	ReviewedAt  *time.Time `json:"reviewed_at,omitempty"`
+	ReviewNote  string     `json:"review_note,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// Downloadable reports whether the file may be served
+func (u Upload) Downloadable() bool {
+	return u.Status == ScanClean || u.Status == ScanReleased
+}
+
+// UploadReview is the body of POST /admin/uploads/{id}/review: an admin's call
+// on a quarantined file
+type UploadReview struct {
+	Decision string `json:"decision" validate:"required,oneof=release reject"`
+	Note     string `json:"note" validate:"max=500"`
+}
+
+// UploadFilter narrows GET /admin/uploads
+type UploadFilter struct {
+	Status string `query:"status" validate:"omitempty,oneof=pending clean quarantined released rejected"`
+}
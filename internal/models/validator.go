@@ -3,17 +3,43 @@ package models
 import (
 	"errors"
 	"log"
+	"reflect"
+	"regexp"
+	"simpleapi/pkg/phone"
 
 	"github.com/go-playground/validator/v10"
 )
 
-var validate = validator.New()
+var validate = newValidator()
 
-// ValidationError is your clean, public-facing error format
+func newValidator() *validator.Validate {
+	v := validator.New()
+	// "phone" accepts anything phone.Normalize can turn into E.164, e.g. "0803 123 4567"
+	v.RegisterValidation("phone", func(fl validator.FieldLevel) bool {
+		_, err := phone.Normalize(fl.Field().String())
+		return err == nil
+	})
+	// "language" is a message language the school offers, see SupportsLanguage
+	v.RegisterValidation("language", func(fl validator.FieldLevel) bool {
+		return SupportsLanguage(fl.Field().String())
+	})
+	return v
+}
+
+// ValidationError is your clean, public-facing error format.
+// Msg is English; Localize rewrites it for the client's language (see messages.go).
 type ValidationError struct {
 	Field string `json:"field"`
 	Msg   string `json:"msg"`
+	Tag   string `json:"tag,omitempty"`   // The failed rule, e.g. "max", for clients that map their own messages
 	Index *int   `json:"index,omitempty"` // Pointer so it's null if not applicable
+	// Allowed lists the accepted values when they come from the school's data
+	// rather than the rule itself (see ReferenceData)
+	Allowed []string `json:"allowed,omitempty"`
+
+	param string // Rule parameter, e.g. "32" for max=32
+	field string // Field path without indices, e.g. "GradingScheme.Boundaries.Grade"
+	kind  string // "number" or "items" when the rule applies to a non-string value
 }
 
 // 1. Helper: Converts raw validator engine errors into your clean format
@@ -24,9 +50,13 @@ func parseValidationErr(err error, index *int) []ValidationError {
 		for i, fieldErr := range validationErrors {
 			out[i] = ValidationError{
 				Field: fieldErr.Field(),
-				Msg:   msgForTag(fieldErr.Tag()),
+				Tag:   fieldErr.Tag(),
 				Index: index, // Adds index only if it exists
+				param: fieldErr.Param(),
+				field: sliceIndex.ReplaceAllString(fieldErr.StructNamespace(), ""),
+				kind:  kindOf(fieldErr.Kind()),
 			}
+			out[i].Msg = msgForTag(DefaultLanguage, out[i])
 		}
 		return out
 	}
@@ -62,12 +92,26 @@ func ValidateBatch[T any](dataset []T) []ValidationError {
 	return errorList
 }
 
-func msgForTag(tag string) string {
-	switch tag {
-	case "required":
-		return "This field is required"
-	case "email":
-		return "Invalid email format"
+// RuleError builds the error for a rule checked in code rather than a struct tag;
+// tag is its message key in the catalogs
+func RuleError(field, tag, param string) ValidationError {
+	e := ValidationError{Field: field, Tag: tag, param: param}
+	e.Msg = msgForTag(DefaultLanguage, e)
+	return e
+}
+
+var sliceIndex = regexp.MustCompile(`\[[^\]]*\]`)
+
+// kindOf picks the message variant: "max=32" means characters for a string,
+// a value for a number and entries for a list
+func kindOf(k reflect.Kind) string {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return "items"
 	}
-	return "Invalid field"
+	return ""
 }
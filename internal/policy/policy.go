@@ -0,0 +1,89 @@
+// Package policy decides which students' records a staff member may change.
+// Handlers ask it before writing teacher-scoped data (grades, attendance,
+// comments), so the rule lives in one place rather than in each handler.
+//
+// A teacher teaches their own class (Teacher.Class, the class they are class
+// teacher of) and every class assigned to them in teacher_classes. Admins
+// teach every class. Without a user there is nobody to check, and every
+// check fails: the policy never lets an anonymous request through.
+package policy
+
+import (
+	"context"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"simpleapi/pkg/errcodes"
+	"simpleapi/pkg/jsonpatch"
+	"slices"
+)
+
+// ErrNotYourClass is a 403 with its own code: the target belongs to a class
+// the teacher isn't assigned to. errors.Is(err, models.ErrForbidden) holds.
+var ErrNotYourClass error = &models.CodedError{Code: errcodes.ClassNotAssigned, Err: models.ErrForbidden}
+
+// ErrNoUser is what every check returns when there is no user to check, e.g.
+// on a route Protect doesn't cover. errors.Is(err, models.ErrForbidden) holds.
+var ErrNoUser = fmt.Errorf("policy: no authenticated user: %w", models.ErrForbidden)
+
+// Policy checks teachers against their class assignments
+type Policy struct {
+	Assignments repository.ClassAssignmentStore
+}
+
+// New is the constructor
+func New(assignments repository.ClassAssignmentStore) *Policy {
+	return &Policy{Assignments: assignments}
+}
+
+// Teaches reports whether user teaches class; ErrNoUser when user is nil
+func (p *Policy) Teaches(ctx context.Context, user *models.Teacher, class string) (bool, error) {
+	if user == nil {
+		return false, ErrNoUser
+	}
+	if user.Role == models.RoleAdmin || (user.Class != "" && user.Class == class) {
+		return true, nil
+	}
+	classes, err := p.Assignments.ClassesOf(ctx, user.ID)
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(classes, class), nil
+}
+
+// CanModifyClass returns ErrNotYourClass unless user teaches class
+func (p *Policy) CanModifyClass(ctx context.Context, user *models.Teacher, class string) error {
+	ok, err := p.Teaches(ctx, user, class)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("policy: teacher %d is not assigned to class %s: %w", user.ID, class, ErrNotYourClass)
+	}
+	return nil
+}
+
+// CanModifyStudent is CanModifyClass for the student's current class
+func (p *Policy) CanModifyStudent(ctx context.Context, user *models.Teacher, student models.Student) error {
+	if err := p.CanModifyClass(ctx, user, student.Class); err != nil {
+		return fmt.Errorf("policy: student %d: %w", student.ID, err)
+	}
+	return nil
+}
+
+// studentPatchPaths are the student paths each role may patch. Staff not
+// listed patch nothing. Status isn't a path for anyone: it only moves through
+// POST /students/{id}/status, which checks the transition and its reason.
+var studentPatchPaths = map[string]jsonpatch.Rules{
+	models.RoleAdmin:     {"/custom_fields/*": {jsonpatch.OpAdd, jsonpatch.OpReplace, jsonpatch.OpRemove, jsonpatch.OpTest}},
+	models.RoleRegistrar: {"/custom_fields/*": {jsonpatch.OpAdd, jsonpatch.OpReplace, jsonpatch.OpRemove, jsonpatch.OpTest}},
+}
+
+// StudentPatchRules returns the paths user may patch on a student record,
+// in either PATCH format; ErrNoUser when user is nil
+func StudentPatchRules(user *models.Teacher) (jsonpatch.Rules, error) {
+	if user == nil {
+		return nil, ErrNoUser
+	}
+	return studentPatchPaths[user.Role], nil
+}
@@ -0,0 +1,153 @@
+// Package promotion decides year-end promotions. Each criterion is a Rule; a
+// student's outcome is the strictest verdict any rule returns, so one failed
+// mandatory subject is enough to repeat the year however good the average.
+package promotion
+
+import (
+	"fmt"
+	"simpleapi/internal/models"
+	"sort"
+	"strings"
+)
+
+// Record is what the rules see of one student's year
+type Record struct {
+	Student  models.Student
+	Subjects map[string]float64 // Yearly average per subject
+	Average  float64            // Mean of the subject averages
+	// Attendance is the percentage of marked days present or late; nil when
+	// the register has no marks for the student in the period
+	Attendance *float64
+}
+
+// Verdict is a rule's objection to promoting a student
+type Verdict struct {
+	Outcome string // PromotionRepeat or PromotionReview
+	Reason  string
+}
+
+// Rule checks one criterion. ok is true when the student meets it.
+type Rule interface {
+	Check(r Record) (v Verdict, ok bool)
+}
+
+// RuleFunc adapts a function to Rule
+type RuleFunc func(r Record) (Verdict, bool)
+
+func (f RuleFunc) Check(r Record) (Verdict, bool) { return f(r) }
+
+// Engine evaluates a fixed set of rules
+type Engine struct {
+	Rules []Rule
+}
+
+// NewEngine builds the rules the criteria switch on
+func NewEngine(c models.PromotionCriteria) *Engine {
+	e := &Engine{Rules: []Rule{hasGrades}}
+	if c.MinAverage > 0 {
+		e.Rules = append(e.Rules, MinAverage(c.MinAverage, c.ReviewMargin))
+	}
+	if c.MinAttendance > 0 {
+		e.Rules = append(e.Rules, MinAttendance(c.MinAttendance, c.ReviewMargin))
+	}
+	if len(c.MandatorySubjects) > 0 {
+		e.Rules = append(e.Rules, MandatoryPasses(c.MandatorySubjects, c.PassMark, c.ReviewMargin))
+	}
+	return e
+}
+
+// Evaluate returns the strictest outcome and every reason behind it. A student
+// nobody objects to is promoted.
+func (e *Engine) Evaluate(r Record) (outcome string, reasons []string) {
+	outcome = models.PromotionPromote
+	for _, rule := range e.Rules {
+		v, ok := rule.Check(r)
+		if ok {
+			continue
+		}
+		reasons = append(reasons, v.Reason)
+		if severity[v.Outcome] > severity[outcome] {
+			outcome = v.Outcome
+		}
+	}
+	return outcome, reasons
+}
+
+var severity = map[string]int{
+	models.PromotionPromote: 0,
+	models.PromotionReview:  1,
+	models.PromotionRepeat:  2,
+}
+
+// hasGrades sends students without a single grade this year to review: the
+// engine can't tell a weak student from one whose scores were never entered
+var hasGrades = RuleFunc(func(r Record) (Verdict, bool) {
+	if len(r.Subjects) == 0 {
+		return Verdict{models.PromotionReview, "no grades recorded this year"}, false
+	}
+	return Verdict{}, true
+})
+
+// MinAverage requires an overall average of at least min
+func MinAverage(min, margin float64) Rule {
+	return RuleFunc(func(r Record) (Verdict, bool) {
+		if len(r.Subjects) == 0 || r.Average >= min {
+			return Verdict{}, true
+		}
+		return Verdict{belowBy(min-r.Average, margin), fmt.Sprintf("average %.2f is below %.2f", r.Average, min)}, false
+	})
+}
+
+// MinAttendance requires at least min percent attendance. A student with no
+// marks at all goes to review rather than being held back on missing data.
+func MinAttendance(min, margin float64) Rule {
+	return RuleFunc(func(r Record) (Verdict, bool) {
+		if r.Attendance == nil {
+			return Verdict{models.PromotionReview, "no attendance recorded in the period"}, false
+		}
+		if *r.Attendance >= min {
+			return Verdict{}, true
+		}
+		return Verdict{belowBy(min-*r.Attendance, margin), fmt.Sprintf("attendance %.1f%% is below %.1f%%", *r.Attendance, min)}, false
+	})
+}
+
+// MandatoryPasses requires a yearly average of at least passMark in every subject
+func MandatoryPasses(subjects []string, passMark, margin float64) Rule {
+	sorted := append([]string(nil), subjects...)
+	sort.Strings(sorted)
+	return RuleFunc(func(r Record) (Verdict, bool) {
+		if len(r.Subjects) == 0 {
+			return Verdict{}, true // hasGrades already objects
+		}
+		worst := Verdict{Outcome: models.PromotionPromote}
+		var failed []string
+		for _, subject := range sorted {
+			avg, ok := r.Subjects[subject]
+			outcome := models.PromotionReview // Not graded in a mandatory subject
+			if ok {
+				if avg >= passMark {
+					continue
+				}
+				outcome = belowBy(passMark-avg, margin)
+			}
+			failed = append(failed, subject)
+			if severity[outcome] > severity[worst.Outcome] {
+				worst.Outcome = outcome
+			}
+		}
+		if len(failed) == 0 {
+			return Verdict{}, true
+		}
+		worst.Reason = fmt.Sprintf("below %.2f or ungraded in mandatory subject(s): %s", passMark, strings.Join(failed, ", "))
+		return worst, false
+	})
+}
+
+// belowBy is the outcome for missing a threshold by shortfall
+func belowBy(shortfall, margin float64) string {
+	if shortfall <= margin {
+		return models.PromotionReview
+	}
+	return models.PromotionRepeat
+}
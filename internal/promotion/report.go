@@ -0,0 +1,87 @@
+package promotion
+
+import (
+	"math"
+	"simpleapi/internal/models"
+	"sort"
+)
+
+// Decide evaluates every student of the classes in req.NextClass. scores are the
+// year's (see ScoreStore.ListByYear) and tallies the attendance in the criteria's
+// period; subject averages are the mean of their term scores, as on transcripts.
+func Decide(req models.PromotionRequest, students []models.Student, scores []models.StudentScore, tallies map[int]models.AttendanceTally) []models.PromotionDecision {
+	type key struct {
+		student int
+		subject string
+	}
+	termScores := make(map[key][]float64)
+	for _, s := range scores {
+		k := key{s.StudentID, s.Subject}
+		termScores[k] = append(termScores[k], s.Score)
+	}
+	subjects := make(map[int]map[string]float64)
+	for k, values := range termScores {
+		if subjects[k.student] == nil {
+			subjects[k.student] = make(map[string]float64)
+		}
+		subjects[k.student][k.subject] = round2(mean(values))
+	}
+
+	engine := NewEngine(req.Criteria)
+	decisions := make([]models.PromotionDecision, 0)
+	for _, s := range students {
+		next, ok := req.NextClass[s.Class]
+		if !ok {
+			continue
+		}
+		record := Record{Student: s, Subjects: subjects[s.ID]}
+		if record.Subjects == nil {
+			record.Subjects = make(map[string]float64)
+		}
+		averages := make([]float64, 0, len(record.Subjects))
+		for _, avg := range record.Subjects {
+			averages = append(averages, avg)
+		}
+		record.Average = round2(mean(averages))
+		if t, ok := tallies[s.ID]; ok && t.Present+t.Late+t.Absent > 0 {
+			rate := math.Round(t.Rate()*10) / 10
+			record.Attendance = &rate
+		}
+
+		outcome, reasons := engine.Evaluate(record)
+		decisions = append(decisions, models.PromotionDecision{
+			StudentID:  s.ID,
+			Name:       s.FirstName + " " + s.LastName,
+			Class:      s.Class,
+			NextClass:  next,
+			Average:    record.Average,
+			Subjects:   record.Subjects,
+			Attendance: record.Attendance,
+			Outcome:    outcome,
+			Reasons:    reasons,
+		})
+	}
+
+	sort.Slice(decisions, func(i, j int) bool {
+		if decisions[i].Class != decisions[j].Class {
+			return decisions[i].Class < decisions[j].Class
+		}
+		return decisions[i].Name < decisions[j].Name
+	})
+	return decisions
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,236 @@
+// Package query is what the list and count methods of repositories take:
+// which rows (a filter expression, see package expr), in which order, which
+// page of them and which fields. Table turns Options into MySQL statements
+// whose identifiers all come from the table's whitelist and whose values are
+// all placeholders; the in-memory repositories read the same Options with
+// Match, Compare and Apply.
+package query
+
+import (
+	"fmt"
+	"simpleapi/internal/expr"
+	"slices"
+	"strings"
+)
+
+// Options narrow, order, page and trim a listing. The zero value lists every
+// row, whole, in ID order.
+type Options struct {
+	Where  expr.Expr // nil: every row
+	Sort   []Sort    // ties, and an empty list, fall back to ID order
+	Page   Page
+	Fields []string // nil: every field; the ID is always read
+}
+
+// Sort orders by one field
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+// Page is one page of a listing, counted from 1; a zero Size is no paging
+type Page struct {
+	Number int
+	Size   int
+}
+
+// Offset is the number of rows before the page
+func (p Page) Offset() int {
+	if p.Number < 1 {
+		return 0
+	}
+	return (p.Number - 1) * p.Size
+}
+
+// Apply cuts the page out of a sorted slice
+func Apply[T any](p Page, items []T) []T {
+	if p.Size == 0 {
+		return items
+	}
+	start := min(p.Offset(), len(items))
+	return items[start:min(start+p.Size, len(items))]
+}
+
+// Selects reports whether field is to be read: every field when opts lists none
+func (opts Options) Selects(field string) bool {
+	return opts.Fields == nil || field == "id" || slices.Contains(opts.Fields, field)
+}
+
+// Match tests a record against opts.Where; get is as for expr.Match
+func (opts Options) Match(get func(field string) any) bool {
+	return opts.Where == nil || expr.Match(opts.Where, get)
+}
+
+// Compare orders two records by opts.Sort, then by ID, the way MySQL orders
+// the rows of Table.Select. get returns field values as for expr.Match.
+func (opts Options) Compare(a, b func(field string) any) int {
+	for _, s := range opts.Sort {
+		c := compareValues(a(s.Field), b(s.Field))
+		if s.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return compareValues(a("id"), b("id"))
+}
+
+// compareValues orders NULLs first, like MySQL does in ascending order
+func compareValues(a, b any) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	switch a := a.(type) {
+	case string:
+		return strings.Compare(a, b.(string))
+	case int:
+		return a - b.(int)
+	case float64:
+		switch b := b.(float64); {
+		case a < b:
+			return -1
+		case a > b:
+			return 1
+		}
+	case bool:
+		switch b := b.(bool); {
+		case a == b:
+			return 0
+		case b:
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
+
+// Column is one readable field of a table
+type Column struct {
+	Field string
+	SQL   string // the select expression; empty means the field name
+}
+
+// Columns makes a column of each field, with the select expressions of those
+// that need one
+func Columns(fields []string, exprs map[string]string) []Column {
+	columns := make([]Column, len(fields))
+	for i, f := range fields {
+		columns[i] = Column{Field: f, SQL: exprs[f]}
+	}
+	return columns
+}
+
+// Table describes an entity's table to the statement builder
+type Table struct {
+	Name    string
+	Where   string   // condition every row meets, e.g. "deleted_at IS NULL"; empty for none
+	Columns []Column // readable fields, in select order, starting with "id"
+	Sorts   []string // fields Sort accepts
+}
+
+// Fields are the names of every column, in select order
+func (t Table) Fields() []string {
+	fields := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		fields[i] = c.Field
+	}
+	return fields
+}
+
+// SelectList is the select expressions of every column, for hand-written
+// statements that read whole rows
+func (t Table) SelectList() string {
+	list := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		list[i] = c.expr()
+	}
+	return strings.Join(list, ", ")
+}
+
+func (c Column) expr() string {
+	if c.SQL != "" {
+		return c.SQL
+	}
+	return c.Field
+}
+
+// Select renders the SELECT for opts. It returns the fields it reads, in
+// order, for the caller to scan into; a field or sort outside the table's
+// whitelist is an error rather than SQL.
+func (t Table) Select(opts Options) (string, []any, []string, error) {
+	var b strings.Builder
+	var fields []string
+	b.WriteString("SELECT ")
+	for _, c := range t.Columns {
+		if !opts.Selects(c.Field) {
+			continue
+		}
+		if len(fields) > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(c.expr())
+		fields = append(fields, c.Field)
+	}
+	for _, f := range opts.Fields {
+		if !slices.Contains(fields, f) {
+			return "", nil, nil, fmt.Errorf("query: %s has no field %q", t.Name, f)
+		}
+	}
+
+	args := t.where(&b, opts)
+
+	if len(opts.Sort) > 0 || opts.Page.Size > 0 {
+		b.WriteString(" ORDER BY ")
+		for _, s := range opts.Sort {
+			if !slices.Contains(t.Sorts, s.Field) {
+				return "", nil, nil, fmt.Errorf("query: %s can't sort by %q", t.Name, s.Field)
+			}
+			b.WriteString(s.Field)
+			if s.Desc {
+				b.WriteString(" DESC, ")
+			} else {
+				b.WriteString(" ASC, ")
+			}
+		}
+		b.WriteString("id ASC")
+	}
+	if opts.Page.Size > 0 {
+		b.WriteString(" LIMIT ? OFFSET ?")
+		args = append(args, opts.Page.Size, opts.Page.Offset())
+	}
+	return b.String(), args, fields, nil
+}
+
+// Count renders the SELECT COUNT(*) of the rows opts.Where matches
+func (t Table) Count(opts Options) (string, []any) {
+	var b strings.Builder
+	b.WriteString("SELECT COUNT(*)")
+	args := t.where(&b, opts)
+	return b.String(), args
+}
+
+func (t Table) where(b *strings.Builder, opts Options) []any {
+	b.WriteString(" FROM ")
+	b.WriteString(t.Name)
+	var conds []string
+	if t.Where != "" {
+		conds = append(conds, t.Where)
+	}
+	var args []any
+	if opts.Where != nil {
+		var clause string
+		clause, args = expr.SQL(opts.Where)
+		conds = append(conds, "("+clause+")")
+	}
+	if len(conds) > 0 {
+		b.WriteString(" WHERE ")
+		b.WriteString(strings.Join(conds, " AND "))
+	}
+	return args
+}
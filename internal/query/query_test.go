@@ -0,0 +1,147 @@
+package query
+
+import (
+	"reflect"
+	"simpleapi/internal/expr"
+	"slices"
+	"testing"
+)
+
+var students = Table{
+	Name:    "students",
+	Where:   "deleted_at IS NULL",
+	Columns: Columns([]string{"id", "first_name", "class", "email"}, map[string]string{"email": "COALESCE(email, '')"}),
+	Sorts:   []string{"first_name", "class"},
+}
+
+var studentFields = expr.Fields{"class": {Type: expr.Text}}
+
+func TestSelect(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   Options
+		sql    string
+		args   []any
+		fields []string
+	}{
+		{
+			"everything",
+			Options{},
+			"SELECT id, first_name, class, COALESCE(email, '') FROM students WHERE deleted_at IS NULL",
+			nil,
+			[]string{"id", "first_name", "class", "email"},
+		},
+		{
+			"filtered",
+			Options{Where: expr.Equal(studentFields, "class", "10A")},
+			"SELECT id, first_name, class, COALESCE(email, '') FROM students WHERE deleted_at IS NULL AND (class = ?)",
+			[]any{"10A"},
+			[]string{"id", "first_name", "class", "email"},
+		},
+		{
+			"sorted and paged",
+			Options{Sort: []Sort{{Field: "class", Desc: true}, {Field: "first_name"}}, Page: Page{Number: 3, Size: 20}},
+			"SELECT id, first_name, class, COALESCE(email, '') FROM students WHERE deleted_at IS NULL ORDER BY class DESC, first_name ASC, id ASC LIMIT ? OFFSET ?",
+			[]any{20, 40},
+			[]string{"id", "first_name", "class", "email"},
+		},
+		{
+			"paged without a sort keeps ID order",
+			Options{Page: Page{Number: 1, Size: 10}},
+			"SELECT id, first_name, class, COALESCE(email, '') FROM students WHERE deleted_at IS NULL ORDER BY id ASC LIMIT ? OFFSET ?",
+			[]any{10, 0},
+			[]string{"id", "first_name", "class", "email"},
+		},
+		{
+			"some fields, always the ID",
+			Options{Fields: []string{"email", "class"}, Where: expr.Compare(studentFields, "class", "startswith", "10")},
+			"SELECT id, class, COALESCE(email, '') FROM students WHERE deleted_at IS NULL AND (class LIKE ?)",
+			[]any{"10%"},
+			[]string{"id", "class", "email"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args, fields, err := students.Select(tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sql != tt.sql {
+				t.Errorf("SQL = %q\nwant  %q", sql, tt.sql)
+			}
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("args = %#v, want %#v", args, tt.args)
+			}
+			if !reflect.DeepEqual(fields, tt.fields) {
+				t.Errorf("fields = %v, want %v", fields, tt.fields)
+			}
+		})
+	}
+}
+
+func TestSelectRejectsUnlisted(t *testing.T) {
+	tests := []struct {
+		name string
+		opts Options
+	}{
+		{"field", Options{Fields: []string{"password"}}},
+		{"field written as SQL", Options{Fields: []string{"id; DROP TABLE students"}}},
+		{"sort", Options{Sort: []Sort{{Field: "email"}}}},
+		{"sort written as SQL", Options{Sort: []Sort{{Field: "(SELECT password FROM teachers LIMIT 1)"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if sql, _, _, err := students.Select(tt.opts); err == nil {
+				t.Errorf("Select rendered %q, want an error", sql)
+			}
+		})
+	}
+}
+
+func TestCount(t *testing.T) {
+	tests := []struct {
+		name  string
+		table Table
+		opts  Options
+		sql   string
+		args  []any
+	}{
+		{"every row", students, Options{}, "SELECT COUNT(*) FROM students WHERE deleted_at IS NULL", nil},
+		{"filtered", students, Options{Where: expr.Equal(studentFields, "class", "10A")},
+			"SELECT COUNT(*) FROM students WHERE deleted_at IS NULL AND (class = ?)", []any{"10A"}},
+		{"table without a condition", Table{Name: "events"}, Options{}, "SELECT COUNT(*) FROM events", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sql, args := tt.table.Count(tt.opts)
+			if sql != tt.sql {
+				t.Errorf("SQL = %q, want %q", sql, tt.sql)
+			}
+			if !reflect.DeepEqual(args, tt.args) {
+				t.Errorf("args = %#v, want %#v", args, tt.args)
+			}
+		})
+	}
+}
+
+func TestCompareAndApply(t *testing.T) {
+	rows := []map[string]any{
+		{"id": 1, "class": "10B"},
+		{"id": 2, "class": nil},
+		{"id": 3, "class": "10A"},
+		{"id": 4, "class": "10B"},
+	}
+	opts := Options{Sort: []Sort{{Field: "class", Desc: true}}, Page: Page{Number: 1, Size: 3}}
+	sorted := append([]map[string]any(nil), rows...)
+	slices.SortFunc(sorted, func(a, b map[string]any) int {
+		return opts.Compare(func(f string) any { return a[f] }, func(f string) any { return b[f] })
+	})
+	var ids []any
+	for _, r := range Apply(opts.Page, sorted) {
+		ids = append(ids, r["id"])
+	}
+	// Descending puts NULL last, as MySQL does; ties go by ID
+	if want := []any{1, 4, 3}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("page = %v, want %v", ids, want)
+	}
+}
@@ -0,0 +1,101 @@
+// Package quota warns a school nearing its quotas (models.SchoolQuotas).
+// Responses to requests that use a quota up carry a Quota-Warning header once
+// it is models.QuotaWarnPercent full, and the admins get an email, at most once
+// a day per quota. Enforcement itself is the repositories' (students, uploads)
+// and the rate limiter's (requests).
+package quota
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"simpleapi/internal/jobs"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"simpleapi/internal/schoolprofile"
+	"simpleapi/pkg/clock"
+	"sync"
+	"time"
+)
+
+// Header is the response header a warning goes in, one per quota, e.g.
+// "students; used=412; limit=500"
+const Header = "Quota-Warning"
+
+// notifyEvery is how often the admins are emailed about one quota while it stays near its limit
+const notifyEvery = 24 * time.Hour
+
+// Monitor checks usage against the school's quotas
+type Monitor struct {
+	Store   repository.SchoolStore
+	School  *schoolprofile.Profile
+	Notices *jobs.QuotaNotices
+	Queue   *jobs.Queue
+	Clock   clock.Clock
+
+	mu sync.Mutex
+	// notified is when the admins last heard about each quota. It lives in
+	// the process, so with several instances each may send its own email.
+	notified map[string]time.Time
+}
+
+// New is the constructor
+func New(store repository.SchoolStore, school *schoolprofile.Profile, notices *jobs.QuotaNotices, queue *jobs.Queue, clk clock.Clock) *Monitor {
+	return &Monitor{Store: store, School: school, Notices: notices, Queue: queue, Clock: clk, notified: make(map[string]time.Time)}
+}
+
+// Quotas are the school's current quotas
+func (m *Monitor) Quotas() models.SchoolQuotas {
+	return m.School.Get().Quotas
+}
+
+// Statuses measures usage against every quota
+func (m *Monitor) Statuses(ctx context.Context) ([]models.QuotaStatus, error) {
+	usage, err := m.Store.Usage(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return m.Quotas().Statuses(usage), nil
+}
+
+// Check warns about the stored quotas (students, storage) that are near their
+// limit. Handlers call it after a change that uses them up; it never fails
+// the request.
+func (m *Monitor) Check(w http.ResponseWriter, r *http.Request) {
+	q := m.Quotas()
+	if q.MaxStudents == 0 && q.MaxStorageBytes == 0 {
+		return
+	}
+	statuses, err := m.Statuses(r.Context())
+	if err != nil {
+		log.Printf("quota: could not measure usage: %v", err)
+		return
+	}
+	for _, s := range statuses {
+		if s.Warning {
+			m.Warn(w, s)
+		}
+	}
+}
+
+// Warn sets the warning header for s and, unless they heard about it lately,
+// queues an email to the admins
+func (m *Monitor) Warn(w http.ResponseWriter, s models.QuotaStatus) {
+	w.Header().Add(Header, fmt.Sprintf("%s; used=%d; limit=%d", s.Quota, s.Used, s.Limit))
+
+	now := m.Clock.Now()
+	m.mu.Lock()
+	last, ok := m.notified[s.Quota]
+	due := !ok || now.Sub(last) >= notifyEvery
+	if due {
+		m.notified[s.Quota] = now
+	}
+	m.mu.Unlock()
+	if !due {
+		return
+	}
+	if err := m.Queue.Enqueue(m.Notices.Job(s)); err != nil {
+		log.Printf("quota: could not queue %s quota notices: %v", s.Quota, err)
+	}
+}
@@ -0,0 +1,44 @@
+package ratelimit
+
+import (
+	"context"
+	"simpleapi/pkg/clock"
+	"sync"
+	"time"
+)
+
+// Memory keeps the buckets in this process. It is the default: enough for one
+// instance, but with several each enforces the limits on its own.
+type Memory struct {
+	mu    sync.Mutex
+	clock clock.Clock
+	tats  map[string]time.Time
+	swept time.Time
+}
+
+// NewMemory is the constructor
+func NewMemory(clk clock.Clock) *Memory {
+	return &Memory{clock: clk, tats: make(map[string]time.Time), swept: clk.Now()}
+}
+
+func (m *Memory) Name() string { return "memory" }
+
+func (m *Memory) Take(ctx context.Context, key string, q Quota) (Decision, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.clock.Now()
+	// Full buckets hold nothing worth keeping; drop them now and then so
+	// one-off visitors don't pile up
+	if now.Sub(m.swept) > time.Minute {
+		for k, tat := range m.tats {
+			if tat.Before(now) {
+				delete(m.tats, k)
+			}
+		}
+		m.swept = now
+	}
+	tat, d := take(now, m.tats[key], q)
+	m.tats[key] = tat
+	return d, nil
+}
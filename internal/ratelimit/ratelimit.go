@@ -0,0 +1,141 @@
+// Package ratelimit decides whether a client may make another request. Each
+// client has a token bucket, kept in process memory or, with SHARED_STATE=redis,
+// in Redis so that several API instances enforce one limit between them.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"simpleapi/internal/models"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Quota is how fast one client may go: Limit requests per Window sustained,
+// plus Burst more that a client who has been idle may spend at once. The zero
+// Quota is unlimited.
+type Quota struct {
+	Limit  int
+	Window time.Duration
+	Burst  int
+}
+
+// Unlimited reports whether the quota lets everything through
+func (q Quota) Unlimited() bool { return q.Limit <= 0 }
+
+// capacity is the size of the bucket
+func (q Quota) capacity() int { return q.Limit + q.Burst }
+
+// interval is how long the bucket takes to win back one request
+func (q Quota) interval() time.Duration { return q.Window / time.Duration(q.Limit) }
+
+// Decision is the outcome of one request against its client's bucket
+type Decision struct {
+	Allowed    bool
+	Limit      int           // The bucket's size, Quota.Limit + Quota.Burst
+	Remaining  int           // Requests left in the bucket
+	Reset      time.Duration // Until the bucket is full again
+	RetryAfter time.Duration // Until the next request is allowed; zero when Allowed
+}
+
+// Store keeps the buckets. key names the client and must also tell apart
+// limiters that share the store.
+type Store interface {
+	// Name identifies the store in logs
+	Name() string
+	Take(ctx context.Context, key string, q Quota) (Decision, error)
+}
+
+// take is the bucket arithmetic (GCRA). tat, the theoretical arrival time, is
+// when the bucket will be full if nothing more is taken; the bucket is the
+// whole of it. It returns the new tat, unchanged when the request is refused.
+// The Redis script does the same in microseconds.
+func take(now, tat time.Time, q Quota) (time.Time, Decision) {
+	interval := q.interval()
+	if tat.Before(now) {
+		tat = now
+	}
+	next := tat.Add(interval)
+	allowAt := next.Add(-time.Duration(q.capacity()) * interval)
+	d := Decision{Limit: q.capacity()}
+	if now.Before(allowAt) {
+		d.Reset = tat.Sub(now)
+		d.RetryAfter = allowAt.Sub(now)
+		return tat, d
+	}
+	d.Allowed = true
+	d.Remaining = int(now.Sub(allowAt) / interval)
+	d.Reset = next.Sub(now)
+	return next, d
+}
+
+// Client classes of Quotas besides the roles of models.Teacher
+const (
+	ClassAnonymous = "anonymous" // No valid token, limited by IP
+	ClassKiosk     = "kiosk"     // Front-desk tablets, by API key
+)
+
+// Quotas are the limits of each class of client: ClassAnonymous, ClassKiosk, or a role
+type Quotas map[string]Quota
+
+// DefaultQuotas are the limits RATE_LIMITS leaves out. Staff and kiosks get
+// more than anonymous traffic, which only reaches login and public pages.
+var DefaultQuotas = Quotas{
+	ClassAnonymous:       {Limit: 60, Window: time.Minute, Burst: 30},
+	models.RoleTeacher:   {Limit: 300, Window: time.Minute, Burst: 100},
+	models.RoleRegistrar: {Limit: 300, Window: time.Minute, Burst: 100},
+	models.RoleNurse:     {Limit: 300, Window: time.Minute, Burst: 100},
+	models.RoleAdmin:     {Limit: 600, Window: time.Minute, Burst: 200},
+	ClassKiosk:           {Limit: 600, Window: time.Minute, Burst: 200},
+}
+
+// ParseQuotas reads RATE_LIMITS, e.g. "anonymous=30/1m+10,admin=off": per
+// class, <limit>/<window> with an optional +<burst>, or "off" for no limit.
+// Classes left out keep their DefaultQuotas.
+func ParseQuotas(s string) (Quotas, error) {
+	quotas := maps.Clone(DefaultQuotas)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		class, value, ok := strings.Cut(part, "=")
+		class, value = strings.TrimSpace(class), strings.TrimSpace(value)
+		if _, known := quotas[class]; !ok || !known {
+			return nil, fmt.Errorf("bad rate limit %q, want <anonymous|teacher|registrar|nurse|admin|kiosk>=<limit>/<window>[+<burst>]", part)
+		}
+		q, err := parseQuota(value)
+		if err != nil {
+			return nil, fmt.Errorf("bad rate limit %q: %w", part, err)
+		}
+		quotas[class] = q
+	}
+	return quotas, nil
+}
+
+func parseQuota(s string) (Quota, error) {
+	if s == "off" {
+		return Quota{}, nil
+	}
+	rate, burst, hasBurst := strings.Cut(s, "+")
+	limit, window, ok := strings.Cut(rate, "/")
+	var q Quota
+	var err error
+	if q.Limit, err = strconv.Atoi(limit); !ok || err != nil || q.Limit < 1 {
+		return q, fmt.Errorf("want a limit such as 300/1m+100")
+	}
+	if q.Window, err = time.ParseDuration(window); err != nil || q.Window < time.Second {
+		return q, fmt.Errorf("want a window of at least 1s, such as 1m")
+	}
+	if hasBurst {
+		if q.Burst, err = strconv.Atoi(burst); err != nil || q.Burst < 0 {
+			return q, fmt.Errorf("want a burst such as +100")
+		}
+	}
+	if q.interval() < time.Microsecond {
+		return q, fmt.Errorf("limit is too high for the window")
+	}
+	return q, nil
+}
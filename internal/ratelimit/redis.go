@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"simpleapi/internal/redis"
+	"strconv"
+	"time"
+)
+
+// Redis keeps the buckets in Redis, so every API instance draws on the same
+// ones. The script is timed by the Redis server's clock, so the instances'
+// clocks don't matter.
+type Redis struct {
+	client *redis.Client
+}
+
+// NewRedis is the constructor
+func NewRedis(client *redis.Client) *Redis {
+	return &Redis{client: client}
+}
+
+func (s *Redis) Name() string { return "redis" }
+
+// redisTake is take in Lua, in microseconds. The tat is stored as a decimal
+// string, which Lua would otherwise write in exponent notation.
+const redisTake = `
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000000 + tonumber(t[2])
+local interval = tonumber(ARGV[1])
+local capacity = tonumber(ARGV[2])
+local tat = tonumber(redis.call('GET', KEYS[1]) or now)
+if tat < now then tat = now end
+local nxt = tat + interval
+local allow_at = nxt - capacity * interval
+if now < allow_at then
+  return {0, 0, tat - now, allow_at - now}
+end
+redis.call('SET', KEYS[1], string.format('%.0f', nxt), 'PX', math.ceil((nxt - now) / 1000))
+return {1, math.floor((now - allow_at) / interval), nxt - now, 0}
+`
+
+func (s *Redis) Take(ctx context.Context, key string, q Quota) (Decision, error) {
+	reply, err := s.client.Do(ctx, "EVAL", redisTake, "1", "ratelimit:"+key,
+		strconv.FormatInt(q.interval().Microseconds(), 10), strconv.Itoa(q.capacity()))
+	if err != nil {
+		return Decision{}, fmt.Errorf("ratelimit: %w", err)
+	}
+
+	values, ok := reply.([]any)
+	if !ok || len(values) != 4 {
+		return Decision{}, fmt.Errorf("ratelimit: unexpected reply %v", reply)
+	}
+	var n [4]int64
+	for i, v := range values {
+		if n[i], ok = v.(int64); !ok {
+			return Decision{}, fmt.Errorf("ratelimit: unexpected reply %v", reply)
+		}
+	}
+	return Decision{
+		Allowed:    n[0] == 1,
+		Limit:      q.capacity(),
+		Remaining:  int(n[1]),
+		Reset:      time.Duration(n[2]) * time.Microsecond,
+		RetryAfter: time.Duration(n[3]) * time.Microsecond,
+	}, nil
+}
@@ -0,0 +1,198 @@
+// Package redis is a minimal Redis client: enough RESP to run the commands the
+// shared stores need (rate limit buckets, caches, scheduler locks) when the
+// API runs as several instances (SHARED_STATE=redis).
+//
+//	REDIS_URL=redis://[[user]:password@]host[:6379][/db]
+package redis
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrServer wraps an error reply, e.g. "NOAUTH Authentication required."
+var ErrServer = errors.New("redis: server error")
+
+// idleConns caps the connections kept open between commands
+const idleConns = 16
+
+// Client sends commands over a small pool of connections
+type Client struct {
+	addr     string
+	user     string
+	password string
+	db       int
+	timeout  time.Duration
+	idle     chan *conn
+}
+
+// New parses REDIS_URL. It doesn't connect: the first command does.
+func New(raw string) (*Client, error) {
+	if raw == "" {
+		return nil, fmt.Errorf("redis: REDIS_URL is not set")
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "redis" || u.Host == "" {
+		// Not raw itself: it may hold the password
+		return nil, fmt.Errorf("redis: REDIS_URL must look like redis://:password@host:6379/0")
+	}
+	c := &Client{addr: u.Host, timeout: 500 * time.Millisecond, idle: make(chan *conn, idleConns)}
+	if u.Port() == "" {
+		c.addr = net.JoinHostPort(u.Hostname(), "6379")
+	}
+	if u.User != nil {
+		c.user = u.User.Username()
+		c.password, _ = u.User.Password()
+	}
+	if db := strings.TrimPrefix(u.Path, "/"); db != "" {
+		if c.db, err = strconv.Atoi(db); err != nil {
+			return nil, fmt.Errorf("redis: REDIS_URL database must be a number, got %q", db)
+		}
+	}
+	return c, nil
+}
+
+// Addr is the server's host:port, for logs
+func (c *Client) Addr() string { return c.addr }
+
+// Do sends a command and returns its reply: a string, an int64, nil, or a
+// []any of those. An error reply comes back as ErrServer.
+func (c *Client) Do(ctx context.Context, args ...string) (any, error) {
+	cn, err := c.conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	reply, err := cn.do(ctx, c.timeout, args...)
+	if err != nil {
+		cn.Close() // The stream may be half read
+		return nil, err
+	}
+	c.release(cn)
+	return reply, nil
+}
+
+// Ping checks the server answers, for the startup self-check
+func (c *Client) Ping(ctx context.Context) error {
+	reply, err := c.Do(ctx, "PING")
+	if err != nil {
+		return err
+	}
+	if reply != "PONG" {
+		return fmt.Errorf("redis: unexpected PING reply %v", reply)
+	}
+	return nil
+}
+
+// conn takes an idle connection, or opens a new one
+func (c *Client) conn(ctx context.Context) (*conn, error) {
+	select {
+	case cn := <-c.idle:
+		return cn, nil
+	default:
+	}
+	d := net.Dialer{Timeout: c.timeout}
+	nc, err := d.DialContext(ctx, "tcp", c.addr)
+	if err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	cn := &conn{Conn: nc, r: bufio.NewReader(nc)}
+	if c.password != "" {
+		args := []string{"AUTH", c.password}
+		if c.user != "" {
+			args = []string{"AUTH", c.user, c.password}
+		}
+		if _, err := cn.do(ctx, c.timeout, args...); err != nil {
+			cn.Close()
+			return nil, fmt.Errorf("redis: AUTH: %w", err)
+		}
+	}
+	if c.db != 0 {
+		if _, err := cn.do(ctx, c.timeout, "SELECT", strconv.Itoa(c.db)); err != nil {
+			cn.Close()
+			return nil, fmt.Errorf("redis: SELECT: %w", err)
+		}
+	}
+	return cn, nil
+}
+
+// release keeps the connection for the next command, or closes it if enough are idle
+func (c *Client) release(cn *conn) {
+	select {
+	case c.idle <- cn:
+	default:
+		cn.Close()
+	}
+}
+
+type conn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *conn) do(ctx context.Context, timeout time.Duration, args ...string) (any, error) {
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	c.SetDeadline(deadline)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, a := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if _, err := io.WriteString(c.Conn, b.String()); err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	return c.readReply()
+}
+
+func (c *conn) readReply() (any, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("redis: empty reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, fmt.Errorf("%w: %s", ErrServer, line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err // $-1 is nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return nil, fmt.Errorf("redis: %w", err)
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		values := make([]any, n)
+		for i := range values {
+			if values[i], err = c.readReply(); err != nil {
+				return nil, err
+			}
+		}
+		return values, nil
+	}
+	return nil, fmt.Errorf("redis: unexpected reply %q", line)
+}
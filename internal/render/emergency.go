@@ -0,0 +1,168 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+)
+
+// EmergencySheet is what a trip's staff carry about the students with them:
+// one row per student with their allergies, conditions, medications and who
+// to call. Nothing on it is shortened, so rows grow to fit.
+type EmergencySheet struct {
+	School    string
+	Title     string // The trip, e.g. "Museum visit"
+	Students  []EmergencyStudent
+	PrintedBy string
+	PrintedAt time.Time
+}
+
+// EmergencyStudent is one row of an EmergencySheet
+type EmergencyStudent struct {
+	Name            string // Last name first, as registers are called
+	Class           string
+	AdmissionNumber string
+	DateOfBirth     string
+	Allergies       string
+	Conditions      string
+	Medications     string
+	Contacts        []string // "Name (relationship) phone", in the order to call them
+}
+
+// Layout of the sheet, in points on A4 landscape
+const (
+	emergencyMargin    = 28.0
+	emergencyTop       = 88.0 // Where the table starts, under the title and warning
+	emergencyHeaderRow = 18.0
+	emergencyLine      = 10.0 // Height of a line of text in a row
+	emergencyPadding   = 6.0
+	emergencyFooter    = 20.0
+	emergencySize      = 8.0
+)
+
+// emergencyColumns are the titles and widths of the table's columns, which
+// fill A4Long less the margins
+var emergencyColumns = []struct {
+	title string
+	width float64
+}{
+	{"#", 18}, {"Student", 150}, {"Allergies", 150}, {"Conditions", 130}, {"Medications", 130}, {"Emergency contacts", 208},
+}
+
+// EmergencyPDF lays the sheet out on as many A4 landscape pages as its rows
+// need, repeating the title and header row on each
+func EmergencyPDF(s EmergencySheet) ([]byte, error) {
+	// Wrap every cell first: rows are as tall as their longest cell
+	cells := make([][][]string, len(s.Students))
+	heights := make([]float64, len(s.Students))
+	for i, st := range s.Students {
+		student := Wrap(st.Name, emergencySize, Bold, emergencyColumns[1].width-6)
+		student = append(student, Wrap(fmt.Sprintf("Class %s, adm. no. %s", st.Class, st.AdmissionNumber), emergencySize, Regular, emergencyColumns[1].width-6)...)
+		if st.DateOfBirth != "" {
+			student = append(student, "Born "+st.DateOfBirth)
+		}
+		var contacts []string
+		for _, c := range st.Contacts {
+			contacts = append(contacts, Wrap(c, emergencySize, Regular, emergencyColumns[5].width-6)...)
+		}
+		cells[i] = [][]string{
+			{fmt.Sprint(i + 1)},
+			student,
+			orNone(Wrap(st.Allergies, emergencySize, Regular, emergencyColumns[2].width-6)),
+			orNone(Wrap(st.Conditions, emergencySize, Regular, emergencyColumns[3].width-6)),
+			orNone(Wrap(st.Medications, emergencySize, Regular, emergencyColumns[4].width-6)),
+			orNone(contacts),
+		}
+		lines := 0
+		for _, c := range cells[i] {
+			lines = max(lines, len(c))
+		}
+		heights[i] = float64(lines)*emergencyLine + emergencyPadding
+	}
+
+	// Then fill pages; a row taller than a page still gets one to itself
+	room := A4Short - emergencyMargin - emergencyFooter - emergencyTop - emergencyHeaderRow
+	pages := [][]int{{}}
+	used := 0.0
+	for i, h := range heights {
+		if used+h > room && len(pages[len(pages)-1]) > 0 {
+			pages = append(pages, []int{})
+			used = 0
+		}
+		pages[len(pages)-1] = append(pages[len(pages)-1], i)
+		used += h
+	}
+
+	doc := NewDocument(A4Long, A4Short)
+	right := A4Long - emergencyMargin
+	for n, rows := range pages {
+		p := doc.AddPage()
+		title := s.School
+		if title == "" {
+			title = "Emergency information"
+		}
+		p.Text(emergencyMargin, emergencyMargin+14, 14, Bold, Fit(title, 14, Bold, right-emergencyMargin))
+		heading := "Emergency information"
+		if s.Title != "" {
+			heading += ": " + s.Title
+		}
+		p.Text(emergencyMargin, emergencyMargin+34, 11, Regular, Fit(heading, 11, Regular, right-emergencyMargin))
+		p.Text(emergencyMargin, emergencyMargin+50, 8, Bold,
+			"CONFIDENTIAL medical information. Keep it with the staff in charge and destroy it after the trip.")
+
+		y := emergencyTop
+		p.Fill(emergencyMargin, y, right-emergencyMargin, emergencyHeaderRow, 0.9)
+		x := emergencyMargin
+		for _, c := range emergencyColumns {
+			p.Text(x+3, y+12, emergencySize, Bold, c.title)
+			x += c.width
+		}
+		y += emergencyHeaderRow
+		for _, i := range rows {
+			x := emergencyMargin
+			for col, lines := range cells[i] {
+				for l, line := range lines {
+					font := Regular
+					if col == 1 && l == 0 {
+						font = Bold // The student's name
+					}
+					p.Text(x+3, y+emergencyPadding/2+emergencyLine*float64(l+1)-2, emergencySize, font, line)
+				}
+				x += emergencyColumns[col].width
+			}
+			y += heights[i]
+			p.Line(emergencyMargin, y, right, y, 0.4)
+		}
+
+		bottom := y
+		p.Line(emergencyMargin, emergencyTop, right, emergencyTop, 0.6)
+		x = emergencyMargin
+		p.Line(x, emergencyTop, x, bottom, 0.6)
+		for _, c := range emergencyColumns {
+			x += c.width
+			p.Line(x, emergencyTop, x, bottom, 0.6)
+		}
+
+		footer := fmt.Sprintf("Printed %s", s.PrintedAt.Format("2006-01-02 15:04"))
+		if s.PrintedBy != "" {
+			footer += " by " + s.PrintedBy
+		}
+		footer += fmt.Sprintf("   Page %d of %d", n+1, len(pages))
+		p.Text(emergencyMargin, A4Short-emergencyMargin, 7, Regular, footer)
+	}
+
+	var buf bytes.Buffer
+	if _, err := doc.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// orNone prints an empty cell as "None recorded", so a blank isn't mistaken
+// for a column left unfilled
+func orNone(lines []string) []string {
+	if len(lines) == 0 {
+		return []string{"None recorded"}
+	}
+	return lines
+}
@@ -0,0 +1,156 @@
+package render
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExamSheet is an exam session's seating plan, room by room: the list pinned
+// on each door and checked by its invigilators, with a box for each student's
+// signature as they hand in their paper
+type ExamSheet struct {
+	School    string
+	Title     string // The session, e.g. "Mathematics paper 1"
+	When      string // e.g. "2026-06-12, 09:00-11:00"
+	Rooms     []ExamSheetRoom
+	PrintedAt time.Time
+}
+
+// ExamSheetRoom is one room of an ExamSheet, its seats in order
+type ExamSheetRoom struct {
+	Name         string
+	Invigilators []string
+	Seats        []ExamSheetSeat
+}
+
+// ExamSheetSeat is one row of an ExamSheetRoom
+type ExamSheetSeat struct {
+	Seat            int
+	Row, Column     int
+	Name            string // Last name first, as registers are called
+	Class           string
+	AdmissionNumber string
+}
+
+// Layout of the sheet, in points on A4 landscape
+const (
+	examMargin    = 28.0
+	examTop       = 96.0 // Where the table starts, under the title and invigilators
+	examHeaderRow = 18.0
+	examRow       = 20.0
+	examFooter    = 20.0
+	examSize      = 9.0
+)
+
+// examColumns are the titles and widths of the table's columns, which fill
+// A4Long less the margins
+var examColumns = []struct {
+	title string
+	width float64
+}{
+	{"Seat", 40}, {"Row / col.", 60}, {"Name", 240}, {"Class", 90}, {"Adm. no.", 100}, {"Signature", 256},
+}
+
+// examRowsPerPage is how many seats fit between the header row and the
+// footer: (A4Short - examMargin - examFooter - examTop - examHeaderRow) / examRow
+const examRowsPerPage = 21
+
+// ExamPDF lays each room out on its own pages, so every room's list can be
+// handed to its invigilators
+func ExamPDF(s ExamSheet) ([]byte, error) {
+	type page struct {
+		room  ExamSheetRoom
+		first int // Index of the page's first seat in the room
+		n, of int // Page n of the room's
+	}
+	var pages []page
+	for _, room := range s.Rooms {
+		count := max(1, (len(room.Seats)+examRowsPerPage-1)/examRowsPerPage)
+		for n := range count {
+			pages = append(pages, page{room: room, first: n * examRowsPerPage, n: n + 1, of: count})
+		}
+	}
+
+	doc := NewDocument(A4Long, A4Short)
+	right := A4Long - examMargin
+	for i, pg := range pages {
+		p := doc.AddPage()
+		title := s.School
+		if title == "" {
+			title = "Exam seating"
+		}
+		p.Text(examMargin, examMargin+14, 14, Bold, Fit(title, 14, Bold, right-examMargin))
+		heading := fmt.Sprintf("%s, %s: room %s", s.Title, s.When, pg.room.Name)
+		if pg.of > 1 {
+			heading += fmt.Sprintf(" (%d of %d)", pg.n, pg.of)
+		}
+		p.Text(examMargin, examMargin+34, 11, Regular, Fit(heading, 11, Regular, right-examMargin))
+		invigilators := "Invigilators: not assigned"
+		if len(pg.room.Invigilators) > 0 {
+			invigilators = "Invigilators: " + strings.Join(pg.room.Invigilators, ", ")
+		}
+		p.Text(examMargin, examMargin+52, 9, Regular, Fit(invigilators, 9, Regular, right-examMargin))
+
+		y := examTop
+		p.Fill(examMargin, y, right-examMargin, examHeaderRow, 0.9)
+		x := examMargin
+		for _, c := range examColumns {
+			p.Text(x+3, y+12, examSize, Bold, c.title)
+			x += c.width
+		}
+		y += examHeaderRow
+		rows := pg.room.Seats[pg.first:min(pg.first+examRowsPerPage, len(pg.room.Seats))]
+		for _, seat := range rows {
+			cells := []string{strconv.Itoa(seat.Seat), fmt.Sprintf("%d / %d", seat.Row, seat.Column), seat.Name, seat.Class, seat.AdmissionNumber, ""}
+			x := examMargin
+			for col, text := range cells {
+				font := Regular
+				if col == 0 {
+					font = Bold
+				}
+				p.Text(x+3, y+14, examSize, font, Fit(text, examSize, font, examColumns[col].width-6))
+				x += examColumns[col].width
+			}
+			y += examRow
+			p.Line(examMargin, y, right, y, 0.4)
+		}
+
+		bottom := y
+		p.Line(examMargin, examTop, right, examTop, 0.6)
+		x = examMargin
+		p.Line(x, examTop, x, bottom, 0.6)
+		for _, c := range examColumns {
+			x += c.width
+			p.Line(x, examTop, x, bottom, 0.6)
+		}
+
+		footer := fmt.Sprintf("Printed %s   Page %d of %d", s.PrintedAt.Format("2006-01-02 15:04"), i+1, len(pages))
+		p.Text(examMargin, A4Short-examMargin, 7, Regular, footer)
+	}
+
+	var buf bytes.Buffer
+	if _, err := doc.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// ExamCSV writes the seating as a spreadsheet, a row per seat
+func ExamCSV(w io.Writer, s ExamSheet) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{"room", "seat", "row", "column", "admission_number", "name", "class", "invigilators"})
+	for _, room := range s.Rooms {
+		invigilators := strings.Join(room.Invigilators, "; ")
+		for _, seat := range room.Seats {
+			out.Write([]string{room.Name, strconv.Itoa(seat.Seat), strconv.Itoa(seat.Row), strconv.Itoa(seat.Column),
+				seat.AdmissionNumber, seat.Name, seat.Class, invigilators})
+		}
+	}
+	out.Flush()
+	return out.Error()
+}
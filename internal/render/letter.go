@@ -0,0 +1,123 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+// Letter is a merged letter template (see package letters): a letterhead,
+// the date, a subject line and the body, whose line breaks are kept
+type Letter struct {
+	School  string
+	Address string // Any number of lines
+	Date    string
+	Subject string
+	Body    string
+}
+
+// Layout of a letter, in points on A4 portrait
+const (
+	letterMargin = 56.0
+	letterSize   = 11.0
+	letterLine   = 15.0
+	letterFooter = 28.0
+)
+
+// LetterPDF lays the letter out on as many pages as its body needs, the
+// letterhead on the first
+func LetterPDF(l Letter) ([]byte, error) {
+	width, height := A4Short, A4Long // Portrait
+	text := width - 2*letterMargin
+
+	var lines []string
+	for _, para := range strings.Split(strings.ReplaceAll(l.Body, "\r\n", "\n"), "\n") {
+		if strings.TrimSpace(para) == "" {
+			lines = append(lines, "")
+			continue
+		}
+		lines = append(lines, Wrap(para, letterSize, Regular, text)...)
+	}
+
+	doc := NewDocument(width, height)
+	var pages []*Page
+	p := doc.AddPage()
+	pages = append(pages, p)
+	y := letterMargin + 16
+	p.Text(letterMargin, y, 16, Bold, Fit(l.School, 16, Bold, text))
+	for _, line := range Wrap(l.Address, 9, Regular, text) {
+		y += 12
+		p.Text(letterMargin, y, 9, Regular, line)
+	}
+	y += 10
+	p.Line(letterMargin, y, width-letterMargin, y, 0.6)
+	y += 28
+	p.Text(width-letterMargin-TextWidth(l.Date, letterSize, Regular), y, letterSize, Regular, l.Date)
+	y += 2 * letterLine
+	for _, line := range Wrap(l.Subject, letterSize, Bold, text) {
+		p.Text(letterMargin, y, letterSize, Bold, line)
+		y += letterLine
+	}
+	y += letterLine
+
+	bottom := height - letterMargin - letterFooter
+	for _, line := range lines {
+		if y > bottom {
+			p = doc.AddPage()
+			pages = append(pages, p)
+			y = letterMargin + letterSize
+		}
+		if line != "" {
+			p.Text(letterMargin, y, letterSize, Regular, line)
+		}
+		y += letterLine
+	}
+
+	if len(pages) > 1 {
+		for i, p := range pages {
+			footer := fmt.Sprintf("%s   Page %d of %d", l.Subject, i+1, len(pages))
+			p.Text(letterMargin, height-letterMargin+12, 7, Regular, Fit(footer, 7, Regular, text))
+		}
+	}
+
+	var buf bytes.Buffer
+	if _, err := doc.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var letterHTML = template.Must(template.New("letter").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Subject}}</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; font-size: 11pt; line-height: 1.4; max-width: 42em; margin: 2em auto; }
+header { border-bottom: 1px solid #000; margin-bottom: 2em; padding-bottom: 0.5em; }
+h1 { font-size: 16pt; margin: 0; }
+.address { font-size: 9pt; white-space: pre-line; }
+.date { text-align: right; }
+.subject { font-weight: bold; }
+.body { white-space: pre-wrap; }
+</style>
+</head>
+<body>
+<header>
+<h1>{{.School}}</h1>
+<div class="address">{{.Address}}</div>
+</header>
+<p class="date">{{.Date}}</p>
+<p class="subject">{{.Subject}}</p>
+<div class="body">{{.Body}}</div>
+</body>
+</html>
+`))
+
+// LetterHTML writes the letter as a standalone HTML page, for the browser
+// to show or print. The merged text is escaped, never read as markup.
+func LetterHTML(w io.Writer, l Letter) error {
+	return letterHTML.Execute(w, l)
+}
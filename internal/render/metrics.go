@@ -0,0 +1,78 @@
+package render
+
+import "strings"
+
+// helveticaWidths are the advance widths of Helvetica's printable ASCII
+// characters (space to ~), in thousandths of the font size
+var helveticaWidths = [...]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278, // space to /
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556, // 0 to ?
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778, // @ to O
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556, // P to _
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556, // ` to o
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584, // p to ~
+}
+
+// TextWidth is how wide s prints in points. Accented and other non-ASCII
+// letters count as an average letter; bold as a tenth wider, which errs on
+// the side of room to spare.
+func TextWidth(s string, size float64, font Font) float64 {
+	total := 0
+	for _, r := range s {
+		if r >= ' ' && r <= '~' {
+			total += helveticaWidths[r-' ']
+		} else {
+			total += 556
+		}
+	}
+	w := float64(total) * size / 1000
+	if font == Bold {
+		w *= 1.1
+	}
+	return w
+}
+
+// Fit shortens s with an ellipsis until it prints within width points
+func Fit(s string, size float64, font Font, width float64) string {
+	if TextWidth(s, size, font) <= width {
+		return s
+	}
+	runes := []rune(strings.TrimSpace(s))
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		if short := strings.TrimSpace(string(runes)) + "…"; TextWidth(short, size, font) <= width {
+			return short
+		}
+	}
+	return ""
+}
+
+// Wrap breaks s into lines that print within width points, at spaces where
+// it can and inside a word too long for a line. Newlines in s start a line.
+func Wrap(s string, size float64, font Font, width float64) []string {
+	var lines []string
+	for _, para := range strings.Split(strings.TrimSpace(s), "\n") {
+		line := ""
+		for _, word := range strings.Fields(para) {
+			if line != "" && TextWidth(line+" "+word, size, font) <= width {
+				line += " " + word
+				continue
+			}
+			if line != "" {
+				lines = append(lines, line)
+			}
+			line = ""
+			for _, r := range word {
+				if line != "" && TextWidth(line+string(r), size, font) > width {
+					lines = append(lines, line)
+					line = ""
+				}
+				line += string(r)
+			}
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
@@ -0,0 +1,199 @@
+// Package render lays out the API's printable documents. Pages are drawn
+// with text, rules, shading and JPEG images (the photo pipeline's output),
+// then written as PDF. Text uses the PDF's built-in Helvetica, so nothing
+// is embedded but the images; characters outside Windows-1252 print as "?".
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"image/jpeg"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// The sides of an A4 page in points: NewDocument(A4Long, A4Short) is
+// landscape, NewDocument(A4Short, A4Long) portrait
+const (
+	A4Long  = 842.0
+	A4Short = 595.0
+)
+
+// Font is one of the built-in fonts
+type Font int
+
+const (
+	Regular Font = iota
+	Bold
+)
+
+// Document is a PDF being drawn, page by page
+type Document struct {
+	width, height float64
+	pages         []*Page
+	images        []*Image
+}
+
+// Page is one page of a Document. Coordinates are in points from the top
+// left corner, like on screen; the PDF's bottom-left origin is handled here.
+type Page struct {
+	doc     *Document
+	content bytes.Buffer
+	images  []*Image
+}
+
+// Image is a JPEG added to a Document, which pages can draw any number of times
+type Image struct {
+	data          []byte
+	width, height int
+	colorSpace    string
+	name          string
+}
+
+// NewDocument starts a document of pages width by height points
+func NewDocument(width, height float64) *Document {
+	return &Document{width: width, height: height}
+}
+
+// AddPage appends a blank page
+func (d *Document) AddPage() *Page {
+	p := &Page{doc: d}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+// JPEG adds a JPEG image, as is: PDF readers decode it themselves
+func (d *Document) JPEG(data []byte) (*Image, error) {
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("render: reading JPEG: %w", err)
+	}
+	img := &Image{data: data, width: cfg.Width, height: cfg.Height, name: fmt.Sprintf("Im%d", len(d.images)+1)}
+	switch cfg.ColorModel {
+	case color.GrayModel:
+		img.colorSpace = "/DeviceGray"
+	case color.CMYKModel:
+		img.colorSpace = "/DeviceCMYK"
+	default:
+		img.colorSpace = "/DeviceRGB"
+	}
+	d.images = append(d.images, img)
+	return img, nil
+}
+
+// Text draws s with its baseline at y
+func (p *Page) Text(x, y, size float64, font Font, s string) {
+	fmt.Fprintf(&p.content, "BT /F%d %s Tf %s %s Td (%s) Tj ET\n", font+1, num(size), num(x), num(p.doc.height-y), encode(s))
+}
+
+// Line draws a rule width points thick
+func (p *Page) Line(x1, y1, x2, y2, width float64) {
+	fmt.Fprintf(&p.content, "%s w %s %s m %s %s l S\n", num(width), num(x1), num(p.doc.height-y1), num(x2), num(p.doc.height-y2))
+}
+
+// Fill shades a rectangle; gray goes from 0 (black) to 1 (white)
+func (p *Page) Fill(x, y, w, h, gray float64) {
+	fmt.Fprintf(&p.content, "q %s g %s %s %s %s re f Q\n", num(gray), num(x), num(p.doc.height-y-h), num(w), num(h))
+}
+
+// Image draws img fitted inside the w by h box at x, y, keeping its proportions
+func (p *Page) Image(img *Image, x, y, w, h float64) {
+	scale := min(w/float64(img.width), h/float64(img.height))
+	iw, ih := float64(img.width)*scale, float64(img.height)*scale
+	x, y = x+(w-iw)/2, y+(h-ih)/2
+	fmt.Fprintf(&p.content, "q %s 0 0 %s %s %s cm /%s Do Q\n", num(iw), num(ih), num(x), num(p.doc.height-y-ih), img.name)
+	for _, used := range p.images {
+		if used == img {
+			return
+		}
+	}
+	p.images = append(p.images, img)
+}
+
+// WriteTo writes the document as a PDF file
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	// Object numbers are known up front: catalog, page tree, the two fonts,
+	// the images, then a content stream and a page object per page
+	const catalog, tree, regular, bold = 1, 2, 3, 4
+	imageObject := make(map[*Image]int, len(d.images))
+	for i, img := range d.images {
+		imageObject[img] = 5 + i
+	}
+	firstPage := 5 + len(d.images)
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i+1)
+	}
+
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string, stream []byte) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s", len(offsets), body)
+		if stream != nil {
+			out.WriteString("\nstream\n")
+			out.Write(stream)
+			out.WriteString("\nendstream")
+		}
+		out.WriteString("\nendobj\n")
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	object(fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", tree), nil)
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids)), nil)
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>", nil)
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>", nil)
+	for _, img := range d.images {
+		object(fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace %s /BitsPerComponent 8 /Filter /DCTDecode /Length %d >>",
+			img.width, img.height, img.colorSpace, len(img.data)), img.data)
+	}
+	for i, p := range d.pages {
+		var xobjects strings.Builder
+		for _, img := range p.images {
+			fmt.Fprintf(&xobjects, " /%s %d 0 R", img.name, imageObject[img])
+		}
+		object(fmt.Sprintf("<< /Length %d >>", p.content.Len()), p.content.Bytes())
+		object(fmt.Sprintf("<< /Type /Page /Parent %d 0 R /MediaBox [0 0 %s %s] /Contents %d 0 R "+
+			"/Resources << /Font << /F1 %d 0 R /F2 %d 0 R >> /XObject <<%s >> >> >>",
+			tree, num(d.width), num(d.height), firstPage+2*i, regular, bold, xobjects.String()), nil)
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, catalog, xref)
+	return out.WriteTo(w)
+}
+
+// num formats a coordinate to the hundredth of a point, which is finer than print
+func num(f float64) string {
+	return strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64)
+}
+
+// encode turns s into a PDF string body in Windows-1252, escaping what must be
+func encode(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		c, ok := charmap.Windows1252.EncodeRune(r)
+		if !ok {
+			c = '?'
+		}
+		switch c {
+		case '(', ')', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case '\n', '\r', '\t':
+			b.WriteByte(' ')
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
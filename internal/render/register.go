@@ -0,0 +1,163 @@
+package render
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"simpleapi/internal/models"
+	"time"
+)
+
+// RegisterSheet is a class's paper register for a month: one row per
+// student, one blank box per school day, for when attendance can't be taken online
+type RegisterSheet struct {
+	School    string
+	Class     string
+	Month     time.Time
+	Days      []time.Time // The school days of the month, a column each
+	Students  []RegisterStudent
+	PrintedAt time.Time
+}
+
+// RegisterStudent is one row of a RegisterSheet
+type RegisterStudent struct {
+	Name            string // Last name first, as registers are called
+	AdmissionNumber string
+	Photo           []byte // JPEG thumbnail, nil when the student has none
+}
+
+// Layout of the register, in points on A4 landscape
+const (
+	registerMargin    = 28.0
+	registerTop       = 80.0 // Where the grid starts, under the title
+	registerHeaderRow = 22.0
+	registerRow       = 26.0
+	registerFooter    = 20.0
+	registerNumberCol = 18.0
+	registerPhotoCol  = 26.0
+	registerNameCol   = 140.0
+	registerAdmCol    = 64.0
+)
+
+// registerRowsPerPage is how many student rows fit between the header row
+// and the footer: (A4Short - registerMargin - registerFooter - registerTop - registerHeaderRow) / registerRow
+const registerRowsPerPage = 17
+
+// RegisterPDF lays the sheet out on as many A4 landscape pages as its students
+// need, repeating the title and header row on each
+func RegisterPDF(s RegisterSheet) ([]byte, error) {
+	doc := NewDocument(A4Long, A4Short)
+	photos := make([]*Image, len(s.Students))
+	for i, st := range s.Students {
+		if st.Photo == nil {
+			continue
+		}
+		img, err := doc.JPEG(st.Photo)
+		if err != nil {
+			// A damaged thumbnail shouldn't cost the class its register
+			log.Printf("render: photo of %s left out of the register: %v", st.AdmissionNumber, err)
+			continue
+		}
+		photos[i] = img
+	}
+
+	pages := max(1, (len(s.Students)+registerRowsPerPage-1)/registerRowsPerPage)
+	fixed := registerNumberCol + registerPhotoCol + registerNameCol + registerAdmCol
+	dayCol := (A4Long - 2*registerMargin - fixed) / float64(max(1, len(s.Days)))
+	right := registerMargin + fixed + dayCol*float64(len(s.Days))
+
+	for n := range pages {
+		p := doc.AddPage()
+		title := s.School
+		if title == "" {
+			title = "Class register"
+		}
+		p.Text(registerMargin, registerMargin+14, 14, Bold, Fit(title, 14, Bold, A4Long-2*registerMargin))
+		p.Text(registerMargin, registerMargin+34, 11, Regular,
+			fmt.Sprintf("Class register: %s, %s", s.Class, s.Month.Format("January 2006")))
+		legend := "P present   A absent   L late"
+		p.Text(right-TextWidth(legend, 8, Regular), registerMargin+34, 8, Regular, legend)
+
+		// Header row: column titles, then each day's date and weekday
+		y := registerTop
+		p.Fill(registerMargin, y, right-registerMargin, registerHeaderRow, 0.9)
+		x := registerMargin
+		p.Text(x+3, y+14, 8, Bold, "#")
+		x += registerNumberCol
+		p.Text(x+3, y+14, 8, Bold, "Photo")
+		x += registerPhotoCol
+		p.Text(x+3, y+14, 8, Bold, "Name")
+		x += registerNameCol
+		p.Text(x+3, y+14, 8, Bold, "Adm. no.")
+		x += registerAdmCol
+		for _, d := range s.Days {
+			day := fmt.Sprint(d.Day())
+			p.Text(x+(dayCol-TextWidth(day, 8, Bold))/2, y+10, 8, Bold, day)
+			weekday := d.Weekday().String()[:2]
+			p.Text(x+(dayCol-TextWidth(weekday, 6, Regular))/2, y+18, 6, Regular, weekday)
+			x += dayCol
+		}
+
+		first := n * registerRowsPerPage
+		rows := s.Students[first:min(first+registerRowsPerPage, len(s.Students))]
+		y += registerHeaderRow
+		for i, st := range rows {
+			x := registerMargin
+			p.Text(x+3, y+16, 8, Regular, fmt.Sprint(first+i+1))
+			x += registerNumberCol
+			if img := photos[first+i]; img != nil {
+				p.Image(img, x+2, y+2, registerPhotoCol-4, registerRow-4)
+			}
+			x += registerPhotoCol
+			p.Text(x+3, y+16, 9, Regular, Fit(st.Name, 9, Regular, registerNameCol-6))
+			x += registerNameCol
+			p.Text(x+3, y+16, 8, Regular, Fit(st.AdmissionNumber, 8, Regular, registerAdmCol-6))
+			y += registerRow
+		}
+
+		// The grid: a rule under every row and between every column
+		bottom := y
+		p.Line(registerMargin, registerTop, right, registerTop, 0.6)
+		for ry := registerTop + registerHeaderRow; ry <= bottom; ry += registerRow {
+			p.Line(registerMargin, ry, right, ry, 0.4)
+		}
+		x = registerMargin
+		for _, w := range []float64{0, registerNumberCol, registerPhotoCol, registerNameCol, registerAdmCol} {
+			x += w
+			p.Line(x, registerTop, x, bottom, 0.6)
+		}
+		for range s.Days {
+			x += dayCol
+			p.Line(x, registerTop, x, bottom, 0.4)
+		}
+
+		footer := fmt.Sprintf("Printed %s   Page %d of %d", s.PrintedAt.Format("2006-01-02 15:04"), n+1, pages)
+		p.Text(registerMargin, A4Short-registerMargin, 7, Regular, footer)
+	}
+
+	var buf bytes.Buffer
+	if _, err := doc.WriteTo(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// RegisterCSV writes the sheet as a spreadsheet: admission number and name,
+// then an empty cell per school day headed by its date
+func RegisterCSV(w io.Writer, s RegisterSheet) error {
+	out := csv.NewWriter(w)
+	header := []string{"admission_number", "name"}
+	for _, d := range s.Days {
+		header = append(header, d.Format(models.DateLayout))
+	}
+	out.Write(header)
+	for _, st := range s.Students {
+		record := make([]string, len(header))
+		record[0], record[1] = st.AdmissionNumber, st.Name
+		out.Write(record)
+	}
+	out.Flush()
+	return out.Error()
+}
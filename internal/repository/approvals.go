@@ -0,0 +1,197 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/tracing"
+)
+
+// ApprovalRepository stores approval requests and their decisions (table approvals)
+type ApprovalRepository struct {
+	DB Conn
+}
+
+// NewApprovalRepository is the constructor
+func NewApprovalRepository(db *sql.DB) *ApprovalRepository {
+	return &ApprovalRepository{DB: Pool(db)}
+}
+
+const approvalColumns = `id, type, payload, requested_by, approver_role, state, note, comment,
+	decided_by, decided_at, created_at, updated_at`
+
+func scanApproval(row interface{ Scan(...any) error }, a *models.Approval) error {
+	var payload []byte
+	var note, comment sql.NullString
+	var decidedBy sql.NullInt64
+	var decidedAt sql.NullTime
+	if err := row.Scan(&a.ID, &a.Type, &payload, &a.RequestedBy, &a.ApproverRole, &a.State, &note, &comment,
+		&decidedBy, &decidedAt, &a.CreatedAt, &a.UpdatedAt); err != nil {
+		return err
+	}
+	a.Payload = payload
+	a.Note, a.Comment = note.String, comment.String
+	if decidedBy.Valid {
+		id := int(decidedBy.Int64)
+		a.DecidedBy = &id
+	}
+	if decidedAt.Valid {
+		a.DecidedAt = &decidedAt.Time
+	}
+	return nil
+}
+
+// Create records a pending approval
+func (r *ApprovalRepository) Create(ctx context.Context, a models.Approval) (*models.Approval, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.approvals.Create")
+	defer span.End()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		"INSERT INTO approvals (type, payload, requested_by, approver_role, state, note) VALUES (?, ?, ?, ?, ?, ?)",
+		a.Type, []byte(a.Payload), a.RequestedBy, a.ApproverRole, models.ApprovalPending, nullString(a.Note))
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to insert approval: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to read approval id: %w", err)
+	}
+
+	if err := insertAudit(ctx, tx, models.AuditEntry{
+		ActorID:  &a.RequestedBy,
+		Action:   models.AuditApprovalSubmitted,
+		Entity:   "approval",
+		EntityID: int(id),
+		Details:  map[string]any{"type": a.Type, "approver_role": a.ApproverRole},
+	}); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("repo: failed to commit tx: %w", err)
+	}
+	return r.GetByID(ctx, int(id))
+}
+
+func (r *ApprovalRepository) GetByID(ctx context.Context, id int) (*models.Approval, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.approvals.GetByID")
+	defer span.End()
+
+	var a models.Approval
+	err := scanApproval(r.DB.QueryRowContext(ctx, "SELECT "+approvalColumns+" FROM approvals WHERE id = ?", id), &a)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("repo: approval %d not found: %w", id, models.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to get approval %d: %w", id, err)
+	}
+	return &a, nil
+}
+
+// List returns approvals newest first
+func (r *ApprovalRepository) List(ctx context.Context, filter models.ApprovalFilter) ([]models.Approval, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.approvals.List")
+	defer span.End()
+
+	query := "SELECT " + approvalColumns + " FROM approvals WHERE 1=1"
+	var args []interface{}
+	if filter.State != "" {
+		query += " AND state = ?"
+		args = append(args, filter.State)
+	}
+	if filter.Type != "" {
+		query += " AND type = ?"
+		args = append(args, filter.Type)
+	}
+	if filter.RequestedBy != 0 {
+		query += " AND requested_by = ?"
+		args = append(args, filter.RequestedBy)
+	}
+	if filter.ApproverRole != "" {
+		query += " AND approver_role = ?"
+		args = append(args, filter.ApproverRole)
+	}
+	query += " ORDER BY id DESC"
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to query approvals: %w", err)
+	}
+	defer rows.Close()
+
+	approvals := make([]models.Approval, 0)
+	for rows.Next() {
+		var a models.Approval
+		if err := scanApproval(rows, &a); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan approval row: %w", err)
+		}
+		approvals = append(approvals, a)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return approvals, nil
+}
+
+// Transition moves the approval from state from to to, with the decider and
+// their comment; back to pending it clears the decision
+func (r *ApprovalRepository) Transition(ctx context.Context, id int, from, to string, actorID int, comment string) (*models.Approval, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.approvals.Transition")
+	defer span.End()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	var res sql.Result
+	if to == models.ApprovalPending {
+		res, err = tx.ExecContext(ctx,
+			"UPDATE approvals SET state = ?, comment = ?, decided_by = NULL, decided_at = NULL WHERE id = ? AND state = ?",
+			to, nullString(comment), id, from)
+	} else {
+		res, err = tx.ExecContext(ctx,
+			"UPDATE approvals SET state = ?, comment = ?, decided_by = ?, decided_at = NOW() WHERE id = ? AND state = ?",
+			to, nullString(comment), actorID, id, from)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to update approval %d: %w", id, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		var state string
+		err := tx.QueryRowContext(ctx, "SELECT state FROM approvals WHERE id = ?", id).Scan(&state)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("repo: approval %d not found: %w", id, models.ErrNotFound)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("repo: failed to get approval %d: %w", id, err)
+		}
+		return nil, fmt.Errorf("repo: approval %d is %s, not %s: %w", id, state, from, models.ErrConflict)
+	}
+
+	details := map[string]any{"from": from, "to": to}
+	if comment != "" {
+		details["comment"] = comment
+	}
+	if err := insertAudit(ctx, tx, models.AuditEntry{
+		ActorID:  &actorID,
+		Action:   models.ApprovalAudit(to),
+		Entity:   "approval",
+		EntityID: id,
+		Details:  details,
+	}); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("repo: failed to commit tx: %w", err)
+	}
+	return r.GetByID(ctx, id)
+}
@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/tracing"
+)
+
+// ArchiveRepository tracks academic-year archives (table academic_year_archives).
+// The bundles themselves live in object storage.
+type ArchiveRepository struct {
+	DB Conn
+}
+
+// NewArchiveRepository is the constructor
+func NewArchiveRepository(db *sql.DB) *ArchiveRepository {
+	return &ArchiveRepository{DB: Pool(db)}
+}
+
+const archiveColumns = "id, year, status, storage_key, size, sha256, error, created_by, created_at, completed_at"
+
+func scanArchive(row interface{ Scan(...any) error }, a *models.YearArchive) error {
+	var storageKey, sha, errText sql.NullString
+	var createdBy sql.NullInt64
+	var completedAt sql.NullTime
+	if err := row.Scan(&a.ID, &a.Year, &a.Status, &storageKey, &a.Size, &sha, &errText,
+		&createdBy, &a.CreatedAt, &completedAt); err != nil {
+		return err
+	}
+	a.StorageKey = storageKey.String
+	a.SHA256 = sha.String
+	a.Error = errText.String
+	if createdBy.Valid {
+		id := int(createdBy.Int64)
+		a.CreatedBy = &id
+	}
+	if completedAt.Valid {
+		a.CompletedAt = &completedAt.Time
+	}
+	return nil
+}
+
+// Create records a pending archive. It fails with ErrConflict while another
+// archive of the same year is still pending or running.
+func (r *ArchiveRepository) Create(ctx context.Context, year string, createdBy *int) (*models.YearArchive, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.archives.Create")
+	defer span.End()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var inFlight int
+	if err := tx.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM academic_year_archives WHERE year = ? AND status IN (?, ?) FOR UPDATE",
+		year, models.JobPending, models.JobRunning).Scan(&inFlight); err != nil {
+		return nil, fmt.Errorf("repo: failed to check running archives: %w", err)
+	}
+	if inFlight > 0 {
+		return nil, fmt.Errorf("repo: archive of %s already in progress: %w", year, models.ErrConflict)
+	}
+
+	res, err := tx.ExecContext(ctx,
+		"INSERT INTO academic_year_archives (year, status, created_by) VALUES (?,?,?)",
+		year, models.JobPending, createdBy)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to insert archive: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("repo: failed to commit archive: %w", err)
+	}
+
+	id, _ := res.LastInsertId()
+	return r.GetByID(ctx, int(id))
+}
+
+func (r *ArchiveRepository) GetByID(ctx context.Context, id int) (*models.YearArchive, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.archives.GetByID")
+	defer span.End()
+
+	var a models.YearArchive
+	err := scanArchive(r.DB.QueryRowContext(ctx, "SELECT "+archiveColumns+" FROM academic_year_archives WHERE id = ?", id), &a)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("repo: archive %d not found: %w", id, models.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to get archive %d: %w", id, err)
+	}
+	return &a, nil
+}
+
+// List returns archives newest first, optionally for one year only
+func (r *ArchiveRepository) List(ctx context.Context, year string) ([]models.YearArchive, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.archives.List")
+	defer span.End()
+
+	query := "SELECT " + archiveColumns + " FROM academic_year_archives"
+	var args []interface{}
+	if year != "" {
+		query += " WHERE year = ?"
+		args = append(args, year)
+	}
+	query += " ORDER BY id DESC"
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to query archives: %w", err)
+	}
+	defer rows.Close()
+
+	archives := make([]models.YearArchive, 0)
+	for rows.Next() {
+		var a models.YearArchive
+		if err := scanArchive(rows, &a); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan archive row: %w", err)
+		}
+		archives = append(archives, a)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return archives, nil
+}
+
+func (r *ArchiveRepository) MarkRunning(ctx context.Context, id int) error {
+	ctx, span := tracing.StartQuery(ctx, "repo.archives.MarkRunning")
+	defer span.End()
+
+	if _, err := r.DB.ExecContext(ctx,
+		"UPDATE academic_year_archives SET status = ? WHERE id = ? AND status = ?",
+		models.JobRunning, id, models.JobPending); err != nil {
+		return fmt.Errorf("repo: failed to start archive %d: %w", id, err)
+	}
+	return nil
+}
+
+// Finish records the outcome of the job (result.Status is done or failed).
+// Finished archives are never touched again, which keeps done ones immutable.
+func (r *ArchiveRepository) Finish(ctx context.Context, id int, result models.YearArchive) error {
+	ctx, span := tracing.StartQuery(ctx, "repo.archives.Finish")
+	defer span.End()
+
+	if _, err := r.DB.ExecContext(ctx,
+		`UPDATE academic_year_archives SET status = ?, storage_key = ?, size = ?, sha256 = ?, error = ?, completed_at = NOW()
+		 WHERE id = ? AND status IN (?, ?)`,
+		result.Status, nullString(result.StorageKey), result.Size, nullString(result.SHA256), nullString(result.Error),
+		id, models.JobPending, models.JobRunning); err != nil {
+		return fmt.Errorf("repo: failed to finish archive %d: %w", id, err)
+	}
+	return nil
+}
+
+// FailUnfinished marks every pending or running archive failed. The job queue
+// lives in the process, so at startup those are jobs a restart interrupted.
+func (r *ArchiveRepository) FailUnfinished(ctx context.Context, reason string) (int, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.archives.FailUnfinished")
+	defer span.End()
+
+	res, err := r.DB.ExecContext(ctx,
+		"UPDATE academic_year_archives SET status = ?, error = ?, completed_at = NOW() WHERE status IN (?, ?)",
+		models.JobFailed, reason, models.JobPending, models.JobRunning)
+	if err != nil {
+		return 0, fmt.Errorf("repo: failed to fail unfinished archives: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("repo: failed to read rows affected: %w", err)
+	}
+	return int(n), nil
+}
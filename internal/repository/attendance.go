@@ -0,0 +1,303 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/tracing"
+	"time"
+)
+
+// AttendanceRepository stores class registers, one row per student per day (table attendance)
+type AttendanceRepository struct {
+	DB Conn
+}
+
+// NewAttendanceRepository is the constructor
+func NewAttendanceRepository(db *sql.DB) *AttendanceRepository {
+	return &AttendanceRepository{DB: Pool(db)}
+}
+
+// SaveRegister replaces the class's marks for the day with reg's, so a corrected
+// register doesn't leave stale marks behind
+func (r *AttendanceRepository) SaveRegister(ctx context.Context, reg models.AttendanceRegister) (*models.AttendanceRegister, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.attendance.SaveRegister")
+	defer span.End()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM attendance WHERE class = ? AND date = ?", reg.Class, reg.Date); err != nil {
+		return nil, fmt.Errorf("repo: failed to clear register: %w", err)
+	}
+	for _, m := range reg.Records {
+		// The unique key (student_id, date) catches a student marked in two classes the same day
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO attendance (student_id, class, date, status, note, taken_by) VALUES (?,?,?,?,?,?)",
+			m.StudentID, reg.Class, reg.Date, m.Status, nullString(m.Note), reg.TakenBy); err != nil {
+			if conflict := asDuplicateEntry(err); conflict != nil {
+				return nil, fmt.Errorf("repo: student %d already marked on %s in another class: %w", m.StudentID, reg.Date, conflict)
+			}
+			return nil, fmt.Errorf("repo: failed to save attendance of student %d: %w", m.StudentID, err)
+		}
+	}
+	if err := refreshDailyTally(ctx, tx, reg.Class, reg.Date); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("repo: failed to commit register: %w", err)
+	}
+	return r.GetRegister(ctx, reg.Class, reg.Date)
+}
+
+// GetRegister returns the class's register for the day, ErrNotFound if it wasn't taken
+func (r *AttendanceRepository) GetRegister(ctx context.Context, class, date string) (*models.AttendanceRegister, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.attendance.GetRegister")
+	defer span.End()
+
+	rows, err := r.DB.QueryContext(ctx,
+		"SELECT student_id, status, note, taken_by, taken_at FROM attendance WHERE class = ? AND date = ? ORDER BY student_id",
+		class, date)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to query register: %w", err)
+	}
+	defer rows.Close()
+
+	reg := models.AttendanceRegister{Class: class, Date: date, Records: make([]models.AttendanceMark, 0)}
+	for rows.Next() {
+		var m models.AttendanceMark
+		var note sql.NullString
+		var takenBy sql.NullInt64
+		var takenAt time.Time
+		if err := rows.Scan(&m.StudentID, &m.Status, &note, &takenBy, &takenAt); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan attendance row: %w", err)
+		}
+		m.Note = note.String
+		reg.Records = append(reg.Records, m)
+		if takenAt.After(reg.TakenAt) {
+			reg.TakenAt = takenAt
+			reg.TakenBy = nil
+			if takenBy.Valid {
+				id := int(takenBy.Int64)
+				reg.TakenBy = &id
+			}
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	if len(reg.Records) == 0 {
+		return nil, fmt.Errorf("repo: no register for %s on %s: %w", class, date, models.ErrNotFound)
+	}
+	return &reg, nil
+}
+
+// SubmittedClasses lists the classes whose register was taken on date
+func (r *AttendanceRepository) SubmittedClasses(ctx context.Context, date string) ([]string, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.attendance.SubmittedClasses")
+	defer span.End()
+
+	rows, err := r.DB.QueryContext(ctx, "SELECT DISTINCT class FROM attendance WHERE date = ? ORDER BY class", date)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to query submitted classes: %w", err)
+	}
+	defer rows.Close()
+
+	classes := make([]string, 0)
+	for rows.Next() {
+		var class string
+		if err := rows.Scan(&class); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan class: %w", err)
+		}
+		classes = append(classes, class)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return classes, nil
+}
+
+// ListAbsences returns every absence between from and to (inclusive), by student then date
+func (r *AttendanceRepository) ListAbsences(ctx context.Context, from, to string) ([]models.Absence, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.attendance.ListAbsences")
+	defer span.End()
+
+	rows, err := r.DB.QueryContext(ctx,
+		"SELECT student_id, date FROM attendance WHERE status = ? AND date BETWEEN ? AND ? ORDER BY student_id, date",
+		models.AttendanceAbsent, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to query absences: %w", err)
+	}
+	defer rows.Close()
+
+	absences := make([]models.Absence, 0)
+	for rows.Next() {
+		var a models.Absence
+		var date time.Time
+		if err := rows.Scan(&a.StudentID, &date); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan absence: %w", err)
+		}
+		a.Date = date.Format(models.DateLayout)
+		absences = append(absences, a)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return absences, nil
+}
+
+// TallyByStudent counts each student's marks between from and to (inclusive)
+func (r *AttendanceRepository) TallyByStudent(ctx context.Context, from, to string) (map[int]models.AttendanceTally, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.attendance.TallyByStudent")
+	defer span.End()
+
+	rows, err := r.DB.QueryContext(ctx,
+		"SELECT student_id, status, COUNT(*) FROM attendance WHERE date BETWEEN ? AND ? GROUP BY student_id, status",
+		from, to)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to tally attendance: %w", err)
+	}
+	defer rows.Close()
+
+	tallies := make(map[int]models.AttendanceTally)
+	for rows.Next() {
+		var studentID, count int
+		var status string
+		if err := rows.Scan(&studentID, &status, &count); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan attendance tally: %w", err)
+		}
+		t := tallies[studentID]
+		t.Add(status, count)
+		tallies[studentID] = t
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return tallies, nil
+}
+
+// RecordMovement saves a kiosk movement (table attendance_movements). A late
+// arrival also marks the student late if the register already has them absent.
+func (r *AttendanceRepository) RecordMovement(ctx context.Context, m models.AttendanceMovement) (*models.AttendanceMovement, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.attendance.RecordMovement")
+	defer span.End()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// The unique key (student_id, date, kind) allows one of each per day
+	res, err := tx.ExecContext(ctx,
+		"INSERT INTO attendance_movements (student_id, class, date, kind, reason, kiosk, at) VALUES (?,?,?,?,?,?,?)",
+		m.StudentID, m.Class, m.Date, m.Kind, nullString(m.Reason), m.Kiosk, m.At)
+	if err != nil {
+		if asDuplicateEntry(err) != nil {
+			return nil, fmt.Errorf("repo: student %d already has a %s on %s: %w", m.StudentID, m.Kind, m.Date, models.ErrConflict)
+		}
+		return nil, fmt.Errorf("repo: failed to save movement: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to read movement ID: %w", err)
+	}
+	m.ID = int(id)
+
+	if m.Kind == models.MovementLateArrival {
+		res, err := tx.ExecContext(ctx,
+			"UPDATE attendance SET status = ? WHERE student_id = ? AND date = ? AND status = ?",
+			models.AttendanceLate, m.StudentID, m.Date, models.AttendanceAbsent)
+		if err != nil {
+			return nil, fmt.Errorf("repo: failed to mark student %d late: %w", m.StudentID, err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			if err := refreshDailyTally(ctx, tx, m.Class, m.Date); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("repo: failed to commit movement: %w", err)
+	}
+	return &m, nil
+}
+
+// ListMovements returns the day's kiosk movements in the order they happened
+func (r *AttendanceRepository) ListMovements(ctx context.Context, date string) ([]models.AttendanceMovement, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.attendance.ListMovements")
+	defer span.End()
+
+	rows, err := r.DB.QueryContext(ctx,
+		"SELECT id, student_id, class, date, kind, reason, kiosk, at FROM attendance_movements WHERE date = ? ORDER BY at, id", date)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to query movements: %w", err)
+	}
+	defer rows.Close()
+
+	movements := make([]models.AttendanceMovement, 0)
+	for rows.Next() {
+		var m models.AttendanceMovement
+		var day time.Time
+		var reason sql.NullString
+		if err := rows.Scan(&m.ID, &m.StudentID, &m.Class, &day, &m.Kind, &reason, &m.Kiosk, &m.At); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan movement: %w", err)
+		}
+		m.Date, m.Reason = day.Format(models.DateLayout), reason.String
+		movements = append(movements, m)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return movements, nil
+}
+
+// Alerts reads attendance_alerts, one row per student with an alert
+func (r *AttendanceRepository) Alerts(ctx context.Context) (map[int]models.AttendanceAlert, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.attendance.Alerts")
+	defer span.End()
+
+	rows, err := r.DB.QueryContext(ctx, "SELECT student_id, level, rate, notified_at FROM attendance_alerts")
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to query attendance alerts: %w", err)
+	}
+	defer rows.Close()
+
+	alerts := make(map[int]models.AttendanceAlert)
+	for rows.Next() {
+		var a models.AttendanceAlert
+		if err := rows.Scan(&a.StudentID, &a.Level, &a.Rate, &a.NotifiedAt); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan attendance alert: %w", err)
+		}
+		alerts[a.StudentID] = a
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return alerts, nil
+}
+
+// SetAlert upserts the student's row, or deletes it for AttendanceAlertNone
+func (r *AttendanceRepository) SetAlert(ctx context.Context, a models.AttendanceAlert) error {
+	ctx, span := tracing.StartQuery(ctx, "repo.attendance.SetAlert")
+	defer span.End()
+
+	if a.Level == models.AttendanceAlertNone {
+		if _, err := r.DB.ExecContext(ctx, "DELETE FROM attendance_alerts WHERE student_id = ?", a.StudentID); err != nil {
+			return fmt.Errorf("repo: failed to clear the attendance alert of student %d: %w", a.StudentID, err)
+		}
+		return nil
+	}
+	_, err := r.DB.ExecContext(ctx,
+		`INSERT INTO attendance_alerts (student_id, level, rate, notified_at) VALUES (?, ?, ?, ?)
+		 ON DUPLICATE KEY UPDATE level = VALUES(level), rate = VALUES(rate), notified_at = VALUES(notified_at)`,
+		a.StudentID, a.Level, a.Rate, a.NotifiedAt)
+	if err != nil {
+		return fmt.Errorf("repo: failed to save the attendance alert of student %d: %w", a.StudentID, err)
+	}
+	return nil
+}
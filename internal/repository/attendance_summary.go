@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/tracing"
+)
+
+// The attendance_daily table holds each class's tally per day, so that a
+// month-long report sums a row per class and day rather than one per student
+// and day. It covers every day up to its latest (the watermark):
+// SummarizeAttendance fills it in nightly, and a register changed on a day it
+// covers refreshes that day in the same transaction. Later days, today among
+// them, are tallied from attendance when read.
+
+// dailyTally is the SELECT of a day's tally from attendance; its three
+// arguments are the statuses, in dailyStatuses order
+const dailyTally = "SELECT class, date, SUM(status = ?), SUM(status = ?), SUM(status = ?) FROM attendance"
+
+var dailyStatuses = []any{models.AttendancePresent, models.AttendanceLate, models.AttendanceAbsent}
+
+// refreshDailyTally recomputes the class's tally of date if the summary
+// already covers that day. A register cleared of every mark leaves no row.
+func refreshDailyTally(ctx context.Context, tx *Tx, class, date string) error {
+	var covered bool
+	if err := tx.QueryRowContext(ctx,
+		"SELECT COALESCE(MAX(date) >= ?, FALSE) FROM attendance_daily", date).Scan(&covered); err != nil {
+		return fmt.Errorf("repo: failed to read the attendance summary: %w", err)
+	}
+	if !covered {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM attendance_daily WHERE class = ? AND date = ?", class, date); err != nil {
+		return fmt.Errorf("repo: failed to refresh the attendance summary: %w", err)
+	}
+	args := append(append([]any{}, dailyStatuses...), class, date)
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO attendance_daily (class, date, present, late, absent) "+dailyTally+" WHERE class = ? AND date = ? GROUP BY class, date",
+		args...); err != nil {
+		return fmt.Errorf("repo: failed to refresh the attendance summary: %w", err)
+	}
+	return nil
+}
+
+// SummarizeAttendance tallies every class and day before before (DateLayout)
+// that has marks but no summary yet: the previous day on a nightly run, the
+// whole history on the first
+func (r *ReportRepository) SummarizeAttendance(ctx context.Context, before string) (int, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.reports.SummarizeAttendance")
+	defer span.End()
+
+	args := append(append([]any{}, dailyStatuses...), before)
+	res, err := r.DB.ExecContext(ctx,
+		`INSERT INTO attendance_daily (class, date, present, late, absent)
+		 SELECT a.class, a.date, SUM(a.status = ?), SUM(a.status = ?), SUM(a.status = ?)
+		 FROM attendance a LEFT JOIN attendance_daily d ON d.class = a.class AND d.date = a.date
+		 WHERE a.date < ? AND d.class IS NULL
+		 GROUP BY a.class, a.date`, args...)
+	if err != nil {
+		return 0, fmt.Errorf("repo: failed to summarize attendance: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("repo: failed to summarize attendance: %w", err)
+	}
+	return int(n), nil
+}
@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/tracing"
+	"simpleapi/pkg/utils"
+	"time"
+)
+
+// insertAudit writes an audit row, and its outbox event, inside the caller's
+// transaction, so both commit (or roll back) together with the change they describe
+func insertAudit(ctx context.Context, tx *Tx, entry models.AuditEntry) error {
+	var details []byte
+	if entry.Details != nil {
+		var err error
+		if details, err = json.Marshal(entry.Details); err != nil {
+			return fmt.Errorf("repo: failed to encode audit details: %w", err)
+		}
+	}
+
+	// The client IP comes from the request context (resolved by the RealIP middleware)
+	if entry.IPAddress == "" {
+		entry.IPAddress = utils.ClientIPFromContext(ctx)
+	}
+
+	_, err := tx.ExecContext(ctx,
+		"INSERT INTO audit_log (actor_id, action, entity, entity_id, details, ip_address) VALUES (?,?,?,?,?,?)",
+		entry.ActorID, entry.Action, entry.Entity, entry.EntityID, details, entry.IPAddress)
+	if err != nil {
+		return fmt.Errorf("repo: failed to write audit entry: %w", err)
+	}
+	// Every audited change is also an event for the webhook (see OutboxRepository)
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO outbox (event_type, entity, entity_id, actor_id, data) VALUES (?,?,?,?,?)",
+		entry.Action, entry.Entity, entry.EntityID, entry.ActorID, details)
+	if err != nil {
+		return fmt.Errorf("repo: failed to write outbox event: %w", err)
+	}
+	return nil
+}
+
+// AuditRepository reads the audit trail back (writes go through insertAudit)
+type AuditRepository struct {
+	DB Conn
+}
+
+// NewAuditRepository is the constructor
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{DB: Pool(db)}
+}
+
+// auditColumns are the columns scanAudit reads
+const auditColumns = "id, actor_id, action, entity, entity_id, details, COALESCE(ip_address, ''), created_at"
+
+func scanAudit(row interface{ Scan(...any) error }, e *models.AuditEntry) error {
+	var actorID sql.NullInt64
+	var details []byte
+	if err := row.Scan(&e.ID, &actorID, &e.Action, &e.Entity, &e.EntityID, &details, &e.IPAddress, &e.CreatedAt); err != nil {
+		return fmt.Errorf("repo: failed to scan audit row: %w", err)
+	}
+	if actorID.Valid {
+		actor := int(actorID.Int64)
+		e.ActorID = &actor
+	}
+	if len(details) > 0 {
+		if err := json.Unmarshal(details, &e.Details); err != nil {
+			return fmt.Errorf("repo: bad details in audit entry %d: %w", e.ID, err)
+		}
+	}
+	return nil
+}
+
+// ListByEntity returns an entity's audit entries, oldest first
+func (r *AuditRepository) ListByEntity(ctx context.Context, entity string, id int) ([]models.AuditEntry, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.audit.ListByEntity")
+	defer span.End()
+
+	rows, err := r.DB.QueryContext(ctx,
+		"SELECT "+auditColumns+" FROM audit_log WHERE entity = ? AND entity_id = ? ORDER BY created_at, id", entity, id)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]models.AuditEntry, 0)
+	for rows.Next() {
+		var e models.AuditEntry
+		if err := scanAudit(rows, &e); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return entries, nil
+}
+
+// Record writes an entry that isn't part of a change, such as a login, with
+// its outbox event
+func (r *AuditRepository) Record(ctx context.Context, entry models.AuditEntry) error {
+	ctx, span := tracing.StartQuery(ctx, "repo.audit.Record")
+	defer span.End()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("repo: failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := insertAudit(ctx, tx, entry); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("repo: failed to commit tx: %w", err)
+	}
+	return nil
+}
+
+// Stream calls fn with every entry written since, oldest first, reading rows
+// as fn consumes them so that an export of the whole trail isn't held in
+// memory. An error from fn stops it and is returned.
+func (r *AuditRepository) Stream(ctx context.Context, since time.Time, fn func(models.AuditEntry) error) error {
+	ctx, span := tracing.StartQuery(ctx, "repo.audit.Stream")
+	defer span.End()
+
+	rows, err := r.DB.QueryContext(ctx,
+		"SELECT "+auditColumns+" FROM audit_log WHERE created_at >= ? ORDER BY id", since)
+	if err != nil {
+		return fmt.Errorf("repo: failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e models.AuditEntry
+		if err := scanAudit(rows, &e); err != nil {
+			return err
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return nil
+}
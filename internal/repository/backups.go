@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/tracing"
+)
+
+// BackupRepository tracks database backups (table backups); the dumps live in object storage.
+// There is no in-memory twin: with DB_DRIVER=memory there is no database to back up.
+type BackupRepository struct {
+	DB Conn
+}
+
+// NewBackupRepository is the constructor
+func NewBackupRepository(db *sql.DB) *BackupRepository {
+	return &BackupRepository{DB: Pool(db)}
+}
+
+const backupColumns = "id, status, storage_key, size, sha256, tables_count, rows_count, error, created_by, created_at, completed_at"
+
+func scanBackup(row interface{ Scan(...any) error }, b *models.Backup) error {
+	var storageKey, sha, errText sql.NullString
+	var createdBy sql.NullInt64
+	var completedAt sql.NullTime
+	if err := row.Scan(&b.ID, &b.Status, &storageKey, &b.Size, &sha, &b.Tables, &b.Rows, &errText,
+		&createdBy, &b.CreatedAt, &completedAt); err != nil {
+		return err
+	}
+	b.StorageKey = storageKey.String
+	b.SHA256 = sha.String
+	b.Error = errText.String
+	if createdBy.Valid {
+		id := int(createdBy.Int64)
+		b.CreatedBy = &id
+	}
+	if completedAt.Valid {
+		b.CompletedAt = &completedAt.Time
+	}
+	return nil
+}
+
+// Create records a pending backup. It fails with ErrConflict while another backup
+// is pending or running, so a double click doesn't dump the database twice.
+func (r *BackupRepository) Create(ctx context.Context, createdBy *int) (*models.Backup, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.backups.Create")
+	defer span.End()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var inFlight int
+	if err := tx.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM backups WHERE status IN (?, ?) FOR UPDATE",
+		models.JobPending, models.JobRunning).Scan(&inFlight); err != nil {
+		return nil, fmt.Errorf("repo: failed to check running backups: %w", err)
+	}
+	if inFlight > 0 {
+		return nil, fmt.Errorf("repo: a backup is already in progress: %w", models.ErrConflict)
+	}
+
+	res, err := tx.ExecContext(ctx, "INSERT INTO backups (status, created_by) VALUES (?,?)", models.JobPending, createdBy)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to insert backup: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("repo: failed to commit backup: %w", err)
+	}
+
+	id, _ := res.LastInsertId()
+	return r.GetByID(ctx, int(id))
+}
+
+func (r *BackupRepository) GetByID(ctx context.Context, id int) (*models.Backup, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.backups.GetByID")
+	defer span.End()
+
+	var b models.Backup
+	err := scanBackup(r.DB.QueryRowContext(ctx, "SELECT "+backupColumns+" FROM backups WHERE id = ?", id), &b)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("repo: backup %d not found: %w", id, models.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to get backup %d: %w", id, err)
+	}
+	return &b, nil
+}
+
+// List returns backups newest first
+func (r *BackupRepository) List(ctx context.Context) ([]models.Backup, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.backups.List")
+	defer span.End()
+
+	rows, err := r.DB.QueryContext(ctx, "SELECT "+backupColumns+" FROM backups ORDER BY id DESC")
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to query backups: %w", err)
+	}
+	defer rows.Close()
+
+	backups := make([]models.Backup, 0)
+	for rows.Next() {
+		var b models.Backup
+		if err := scanBackup(rows, &b); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan backup row: %w", err)
+		}
+		backups = append(backups, b)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return backups, nil
+}
+
+func (r *BackupRepository) MarkRunning(ctx context.Context, id int) error {
+	ctx, span := tracing.StartQuery(ctx, "repo.backups.MarkRunning")
+	defer span.End()
+
+	if _, err := r.DB.ExecContext(ctx,
+		"UPDATE backups SET status = ? WHERE id = ? AND status = ?",
+		models.JobRunning, id, models.JobPending); err != nil {
+		return fmt.Errorf("repo: failed to start backup %d: %w", id, err)
+	}
+	return nil
+}
+
+// Finish records the outcome of the job (result.Status is done or failed)
+func (r *BackupRepository) Finish(ctx context.Context, id int, result models.Backup) error {
+	ctx, span := tracing.StartQuery(ctx, "repo.backups.Finish")
+	defer span.End()
+
+	if _, err := r.DB.ExecContext(ctx,
+		`UPDATE backups SET status = ?, storage_key = ?, size = ?, sha256 = ?, tables_count = ?, rows_count = ?, error = ?, completed_at = NOW()
+		 WHERE id = ? AND status IN (?, ?)`,
+		result.Status, nullString(result.StorageKey), result.Size, nullString(result.SHA256), result.Tables, result.Rows,
+		nullString(result.Error), id, models.JobPending, models.JobRunning); err != nil {
+		return fmt.Errorf("repo: failed to finish backup %d: %w", id, err)
+	}
+	return nil
+}
+
+// FailUnfinished marks backups a restart interrupted as failed (see ArchiveRepository.FailUnfinished)
+func (r *BackupRepository) FailUnfinished(ctx context.Context, reason string) (int, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.backups.FailUnfinished")
+	defer span.End()
+
+	res, err := r.DB.ExecContext(ctx,
+		"UPDATE backups SET status = ?, error = ?, completed_at = NOW() WHERE status IN (?, ?)",
+		models.JobFailed, reason, models.JobPending, models.JobRunning)
+	if err != nil {
+		return 0, fmt.Errorf("repo: failed to fail unfinished backups: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("repo: failed to read rows affected: %w", err)
+	}
+	return int(n), nil
+}
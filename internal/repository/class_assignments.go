@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"simpleapi/internal/tracing"
+)
+
+// ClassAssignmentRepository stores which classes teachers teach (table teacher_classes)
+type ClassAssignmentRepository struct {
+	DB Conn
+}
+
+// NewClassAssignmentRepository is the constructor
+func NewClassAssignmentRepository(db *sql.DB) *ClassAssignmentRepository {
+	return &ClassAssignmentRepository{DB: Pool(db)}
+}
+
+// WithTx is the repository bound to tx, for composing it into a larger
+// transaction (see UnitOfWork)
+func (r *ClassAssignmentRepository) WithTx(tx *sql.Tx) *ClassAssignmentRepository {
+	return &ClassAssignmentRepository{DB: bound(tx)}
+}
+
+// ClassesOf returns a teacher's assigned classes, sorted
+func (r *ClassAssignmentRepository) ClassesOf(ctx context.Context, teacherID int) ([]string, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.class_assignments.ClassesOf")
+	defer span.End()
+
+	rows, err := r.DB.QueryContext(ctx, "SELECT class FROM teacher_classes WHERE teacher_id = ? ORDER BY class", teacherID)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to query classes of teacher %d: %w", teacherID, err)
+	}
+	defer rows.Close()
+
+	classes := make([]string, 0)
+	for rows.Next() {
+		var class string
+		if err := rows.Scan(&class); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan class row: %w", err)
+		}
+		classes = append(classes, class)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return classes, nil
+}
+
+func (r *ClassAssignmentRepository) SetClasses(ctx context.Context, teacherID int, classes []string) error {
+	ctx, span := tracing.StartQuery(ctx, "repo.class_assignments.SetClasses")
+	defer span.End()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("repo: failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM teacher_classes WHERE teacher_id = ?", teacherID); err != nil {
+		return fmt.Errorf("repo: failed to clear classes of teacher %d: %w", teacherID, err)
+	}
+	// INSERT IGNORE: a class listed twice is assigned once
+	for _, class := range classes {
+		if _, err := tx.ExecContext(ctx,
+			"INSERT IGNORE INTO teacher_classes (teacher_id, class) VALUES (?, ?)", teacherID, class); err != nil {
+			return fmt.Errorf("repo: failed to assign class %s to teacher %d: %w", class, teacherID, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("repo: failed to commit class assignments: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/tracing"
+)
+
+// CommentRepository stores report-card comments (table student_comments)
+type CommentRepository struct {
+	DB Conn
+}
+
+// NewCommentRepository is the constructor
+func NewCommentRepository(db *sql.DB) *CommentRepository {
+	return &CommentRepository{DB: Pool(db)}
+}
+
+const commentColumns = "id, student_id, teacher_id, term, kind, subject, body, flagged, flag_reason, created_at"
+
+func scanComment(row interface{ Scan(...any) error }, c *models.StudentComment) error {
+	var subject, reason sql.NullString
+	if err := row.Scan(&c.ID, &c.StudentID, &c.TeacherID, &c.Term, &c.Kind, &subject, &c.Body, &c.Flagged, &reason, &c.CreatedAt); err != nil {
+		return err
+	}
+	c.Subject = subject.String
+	c.FlagReason = reason.String
+	return nil
+}
+
+// Create inserts a comment. The unique key (student_id, term, kind, subject) means a
+// second general comment for the same term comes back as a ConflictError.
+func (r *CommentRepository) Create(ctx context.Context, c models.StudentComment) (*models.StudentComment, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.comments.Create")
+	defer span.End()
+
+	res, err := r.DB.ExecContext(ctx,
+		"INSERT INTO student_comments (student_id, teacher_id, term, kind, subject, body) VALUES (?,?,?,?,?,?)",
+		c.StudentID, c.TeacherID, c.Term, c.Kind, c.Subject, c.Body)
+	if err != nil {
+		if conflict := asDuplicateEntry(err); conflict != nil {
+			return nil, fmt.Errorf("repo: failed to insert comment: %w", conflict)
+		}
+		return nil, fmt.Errorf("repo: failed to insert comment: %w", err)
+	}
+
+	id, _ := res.LastInsertId()
+	return r.getByID(ctx, int(id))
+}
+
+// ListByStudent returns a student's comments, optionally for one term only.
+// Flagged comments are left out unless includeFlagged is set (moderators).
+func (r *CommentRepository) ListByStudent(ctx context.Context, studentID int, term string, includeFlagged bool) ([]models.StudentComment, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.comments.ListByStudent")
+	defer span.End()
+
+	query := "SELECT " + commentColumns + " FROM student_comments WHERE student_id = ?"
+	args := []interface{}{studentID}
+	if term != "" {
+		query += " AND term = ?"
+		args = append(args, term)
+	}
+	if !includeFlagged {
+		query += " AND flagged = FALSE"
+	}
+	query += " ORDER BY term, kind, subject"
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to query comments: %w", err)
+	}
+	defer rows.Close()
+
+	comments := make([]models.StudentComment, 0)
+	for rows.Next() {
+		var c models.StudentComment
+		if err := scanComment(rows, &c); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan comment row: %w", err)
+		}
+		comments = append(comments, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return comments, nil
+}
+
+// SetFlag sets or clears the moderation flag on one of the student's comments
+func (r *CommentRepository) SetFlag(ctx context.Context, studentID, id int, mod models.CommentModeration) (*models.StudentComment, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.comments.SetFlag")
+	defer span.End()
+
+	reason := sql.NullString{String: mod.Reason, Valid: mod.Flagged && mod.Reason != ""}
+	if _, err := r.DB.ExecContext(ctx,
+		"UPDATE student_comments SET flagged = ?, flag_reason = ? WHERE id = ? AND student_id = ?",
+		mod.Flagged, reason, id, studentID); err != nil {
+		return nil, fmt.Errorf("repo: failed to moderate comment: %w", err)
+	}
+
+	// RowsAffected is 0 both for "missing" and "unchanged" in MySQL, so re-read instead
+	c, err := r.getByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if c.StudentID != studentID {
+		return nil, fmt.Errorf("repo: comment %d not found for student %d: %w", id, studentID, models.ErrNotFound)
+	}
+	return c, nil
+}
+
+func (r *CommentRepository) getByID(ctx context.Context, id int) (*models.StudentComment, error) {
+	var c models.StudentComment
+	err := scanComment(r.DB.QueryRowContext(ctx, "SELECT "+commentColumns+" FROM student_comments WHERE id = ?", id), &c)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("repo: comment %d not found: %w", id, models.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to get comment %d: %w", id, err)
+	}
+	return &c, nil
+}
@@ -0,0 +1,242 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/tracing"
+)
+
+// CampaignRepository stores bulk email campaigns (table communication_campaigns)
+// and the delivery of each of their recipients (table communication_recipients)
+type CampaignRepository struct {
+	DB Conn
+}
+
+// NewCampaignRepository is the constructor
+func NewCampaignRepository(db *sql.DB) *CampaignRepository {
+	return &CampaignRepository{DB: Pool(db)}
+}
+
+// The counts are tallied from the recipients rather than kept on the campaign,
+// so they can't drift from the rows they count
+const campaignQuery = `SELECT c.id, c.subject, c.body, c.audience, c.class, c.role, c.status, c.error,
+	c.created_by, c.created_at, c.completed_at,
+	COUNT(r.id), COALESCE(SUM(r.status = 'sent'), 0), COALESCE(SUM(r.status = 'failed'), 0)
+	FROM communication_campaigns c LEFT JOIN communication_recipients r ON r.campaign_id = c.id`
+
+func scanCampaign(row interface{ Scan(...any) error }, c *models.Campaign) error {
+	var class, role, errText sql.NullString
+	var completedAt sql.NullTime
+	if err := row.Scan(&c.ID, &c.Subject, &c.Body, &c.Recipients.Audience, &class, &role, &c.Status, &errText,
+		&c.CreatedBy, &c.CreatedAt, &completedAt, &c.Total, &c.Sent, &c.Failed); err != nil {
+		return err
+	}
+	c.Recipients.Class, c.Recipients.Role = class.String, role.String
+	c.Error = errText.String
+	if completedAt.Valid {
+		c.CompletedAt = &completedAt.Time
+	}
+	return nil
+}
+
+// Create records a pending campaign and its pending recipients
+func (r *CampaignRepository) Create(ctx context.Context, c models.Campaign, recipients []models.CampaignRecipient) (*models.Campaign, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.communications.Create")
+	defer span.End()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		"INSERT INTO communication_campaigns (subject, body, audience, class, role, status, created_by) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		c.Subject, c.Body, c.Recipients.Audience, nullString(c.Recipients.Class), nullString(c.Recipients.Role),
+		models.JobPending, c.CreatedBy)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to insert campaign: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to read campaign id: %w", err)
+	}
+
+	stmt, err := tx.PrepareContext(ctx,
+		`INSERT INTO communication_recipients (campaign_id, email, first_name, last_name, student, class, status)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to prepare recipient insert: %w", err)
+	}
+	defer stmt.Close()
+	for _, rc := range recipients {
+		if _, err := stmt.ExecContext(ctx, id, rc.Email, nullString(rc.FirstName), nullString(rc.LastName),
+			nullString(rc.Student), nullString(rc.Class), models.RecipientPending); err != nil {
+			return nil, fmt.Errorf("repo: failed to insert recipient %s: %w", rc.Email, err)
+		}
+	}
+
+	if err := insertAudit(ctx, tx, models.AuditEntry{
+		ActorID:  &c.CreatedBy,
+		Action:   models.AuditCampaignCreated,
+		Entity:   "campaign",
+		EntityID: int(id),
+		Details: map[string]any{
+			"subject":    c.Subject,
+			"audience":   c.Recipients.Audience,
+			"class":      c.Recipients.Class,
+			"role":       c.Recipients.Role,
+			"recipients": len(recipients),
+		},
+	}); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("repo: failed to commit tx: %w", err)
+	}
+	return r.GetByID(ctx, int(id))
+}
+
+func (r *CampaignRepository) GetByID(ctx context.Context, id int) (*models.Campaign, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.communications.GetByID")
+	defer span.End()
+
+	var c models.Campaign
+	err := scanCampaign(r.DB.QueryRowContext(ctx, campaignQuery+" WHERE c.id = ? GROUP BY c.id", id), &c)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("repo: campaign %d not found: %w", id, models.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to get campaign %d: %w", id, err)
+	}
+	return &c, nil
+}
+
+// List returns campaigns newest first
+func (r *CampaignRepository) List(ctx context.Context) ([]models.Campaign, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.communications.List")
+	defer span.End()
+
+	rows, err := r.DB.QueryContext(ctx, campaignQuery+" GROUP BY c.id ORDER BY c.id DESC")
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to query campaigns: %w", err)
+	}
+	defer rows.Close()
+
+	campaigns := make([]models.Campaign, 0)
+	for rows.Next() {
+		var c models.Campaign
+		if err := scanCampaign(rows, &c); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan campaign row: %w", err)
+		}
+		campaigns = append(campaigns, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return campaigns, nil
+}
+
+func (r *CampaignRepository) Recipients(ctx context.Context, campaignID int) ([]models.CampaignRecipient, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.communications.Recipients")
+	defer span.End()
+
+	rows, err := r.DB.QueryContext(ctx,
+		`SELECT id, campaign_id, email, first_name, last_name, student, class, status, error, sent_at
+		 FROM communication_recipients WHERE campaign_id = ? ORDER BY id`, campaignID)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to query recipients of campaign %d: %w", campaignID, err)
+	}
+	defer rows.Close()
+
+	recipients := make([]models.CampaignRecipient, 0)
+	for rows.Next() {
+		var rc models.CampaignRecipient
+		var firstName, lastName, student, class, errText sql.NullString
+		var sentAt sql.NullTime
+		if err := rows.Scan(&rc.ID, &rc.CampaignID, &rc.Email, &firstName, &lastName, &student, &class,
+			&rc.Status, &errText, &sentAt); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan recipient row: %w", err)
+		}
+		rc.FirstName, rc.LastName, rc.Student, rc.Class = firstName.String, lastName.String, student.String, class.String
+		rc.Error = errText.String
+		if sentAt.Valid {
+			rc.SentAt = &sentAt.Time
+		}
+		recipients = append(recipients, rc)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return recipients, nil
+}
+
+func (r *CampaignRepository) MarkRunning(ctx context.Context, id int) error {
+	ctx, span := tracing.StartQuery(ctx, "repo.communications.MarkRunning")
+	defer span.End()
+
+	if _, err := r.DB.ExecContext(ctx,
+		"UPDATE communication_campaigns SET status = ? WHERE id = ? AND status = ?",
+		models.JobRunning, id, models.JobPending); err != nil {
+		return fmt.Errorf("repo: failed to start campaign %d: %w", id, err)
+	}
+	return nil
+}
+
+// RecordDelivery only moves pending recipients, so a rerun never sends twice
+// to one already recorded
+func (r *CampaignRepository) RecordDelivery(ctx context.Context, recipientID int, sendErr string) error {
+	ctx, span := tracing.StartQuery(ctx, "repo.communications.RecordDelivery")
+	defer span.End()
+
+	var err error
+	if sendErr == "" {
+		_, err = r.DB.ExecContext(ctx,
+			"UPDATE communication_recipients SET status = ?, error = NULL, sent_at = NOW() WHERE id = ? AND status = ?",
+			models.RecipientSent, recipientID, models.RecipientPending)
+	} else {
+		_, err = r.DB.ExecContext(ctx,
+			"UPDATE communication_recipients SET status = ?, error = ? WHERE id = ? AND status = ?",
+			models.RecipientFailed, sendErr, recipientID, models.RecipientPending)
+	}
+	if err != nil {
+		return fmt.Errorf("repo: failed to record delivery to recipient %d: %w", recipientID, err)
+	}
+	return nil
+}
+
+// Finish records the outcome of the send job (status is done or failed)
+func (r *CampaignRepository) Finish(ctx context.Context, id int, status, errText string) error {
+	ctx, span := tracing.StartQuery(ctx, "repo.communications.Finish")
+	defer span.End()
+
+	if _, err := r.DB.ExecContext(ctx,
+		"UPDATE communication_campaigns SET status = ?, error = ?, completed_at = NOW() WHERE id = ? AND status IN (?, ?)",
+		status, nullString(errText), id, models.JobPending, models.JobRunning); err != nil {
+		return fmt.Errorf("repo: failed to finish campaign %d: %w", id, err)
+	}
+	return nil
+}
+
+// FailUnfinished marks every pending or running campaign failed, as
+// ArchiveRepository.FailUnfinished does; recipients not yet sent to stay pending
+func (r *CampaignRepository) FailUnfinished(ctx context.Context, reason string) (int, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.communications.FailUnfinished")
+	defer span.End()
+
+	res, err := r.DB.ExecContext(ctx,
+		"UPDATE communication_campaigns SET status = ?, error = ?, completed_at = NOW() WHERE status IN (?, ?)",
+		models.JobFailed, reason, models.JobPending, models.JobRunning)
+	if err != nil {
+		return 0, fmt.Errorf("repo: failed to fail unfinished campaigns: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("repo: failed to read rows affected: %w", err)
+	}
+	return int(n), nil
+}
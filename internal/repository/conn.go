@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Conn is where a MySQL repository's queries go: the connection pool, or one
+// transaction for a repository bound with WithTx inside a unit of work (see
+// UnitOfWork). Methods that need their own transaction still call BeginTx;
+// inside a unit of work that opens a savepoint instead, so the method stays
+// all-or-nothing and the unit commits or rolls back as a whole.
+type Conn struct {
+	db *sql.DB
+	tx *sql.Tx
+	// savepoints numbers the savepoints of the bound transaction
+	savepoints *int
+}
+
+// Pool is a Conn on the connection pool, what the New*Repository constructors use
+func Pool(db *sql.DB) Conn {
+	return Conn{db: db}
+}
+
+// bound is a Conn on a unit of work's transaction
+func bound(tx *sql.Tx) Conn {
+	return Conn{tx: tx, savepoints: new(int)}
+}
+
+func (c Conn) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	if c.tx != nil {
+		return c.tx.ExecContext(ctx, query, args...)
+	}
+	return c.db.ExecContext(ctx, query, args...)
+}
+
+func (c Conn) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if c.tx != nil {
+		return c.tx.QueryContext(ctx, query, args...)
+	}
+	return c.db.QueryContext(ctx, query, args...)
+}
+
+func (c Conn) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	if c.tx != nil {
+		return c.tx.QueryRowContext(ctx, query, args...)
+	}
+	return c.db.QueryRowContext(ctx, query, args...)
+}
+
+// BeginTx starts a transaction, or a savepoint in the bound one
+func (c Conn) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	if c.tx == nil {
+		tx, err := c.db.BeginTx(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		return &Tx{tx: tx}, nil
+	}
+
+	*c.savepoints++
+	name := fmt.Sprintf("sp_%d", *c.savepoints)
+	if _, err := c.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return nil, err
+	}
+	return &Tx{tx: c.tx, savepoint: name}, nil
+}
+
+// Tx is a transaction begun with Conn.BeginTx. Like *sql.Tx, Rollback after
+// Commit is a no-op, so `defer tx.Rollback()` stays the idiom.
+type Tx struct {
+	tx        *sql.Tx
+	savepoint string // Set when nested in a unit of work
+	done      bool
+}
+
+func (t *Tx) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	return t.tx.ExecContext(ctx, query, args...)
+}
+
+func (t *Tx) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return t.tx.QueryContext(ctx, query, args...)
+}
+
+func (t *Tx) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return t.tx.QueryRowContext(ctx, query, args...)
+}
+
+func (t *Tx) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return t.tx.PrepareContext(ctx, query)
+}
+
+// Commit commits the transaction, or releases the savepoint: the unit of work
+// commits it for real
+func (t *Tx) Commit() error {
+	if t.done {
+		return sql.ErrTxDone
+	}
+	t.done = true
+	if t.savepoint != "" {
+		_, err := t.tx.Exec("RELEASE SAVEPOINT " + t.savepoint)
+		return err
+	}
+	return t.tx.Commit()
+}
+
+// Rollback undoes the transaction, or what happened since the savepoint
+func (t *Tx) Rollback() error {
+	if t.done {
+		return sql.ErrTxDone
+	}
+	t.done = true
+	if t.savepoint != "" {
+		_, err := t.tx.Exec("ROLLBACK TO SAVEPOINT " + t.savepoint)
+		return err
+	}
+	return t.tx.Rollback()
+}
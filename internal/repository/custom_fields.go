@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/tracing"
+)
+
+// CustomFieldRepository stores custom field definitions (table custom_fields).
+// The values are in each entity's custom_fields JSON column.
+type CustomFieldRepository struct {
+	DB Conn
+}
+
+// NewCustomFieldRepository is the constructor
+func NewCustomFieldRepository(db *sql.DB) *CustomFieldRepository {
+	return &CustomFieldRepository{DB: Pool(db)}
+}
+
+// customFieldTables are the tables holding each entity's values
+var customFieldTables = map[string]string{models.CustomFieldStudent: "students"}
+
+// List returns an entity's fields in the order they were defined
+func (r *CustomFieldRepository) List(ctx context.Context, entity string) ([]models.CustomField, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.custom_fields.List")
+	defer span.End()
+
+	rows, err := r.DB.QueryContext(ctx,
+		"SELECT id, entity, field_key, label, type, options, required, created_at FROM custom_fields WHERE entity = ? ORDER BY id",
+		entity)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to query custom fields: %w", err)
+	}
+	defer rows.Close()
+
+	fields := make([]models.CustomField, 0)
+	for rows.Next() {
+		var f models.CustomField
+		var options []byte
+		if err := rows.Scan(&f.ID, &f.Entity, &f.Key, &f.Label, &f.Type, &options, &f.Required, &f.CreatedAt); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan custom field row: %w", err)
+		}
+		if len(options) > 0 {
+			if err := json.Unmarshal(options, &f.Options); err != nil {
+				return nil, fmt.Errorf("repo: bad options of custom field %d: %w", f.ID, err)
+			}
+		}
+		fields = append(fields, f)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return fields, nil
+}
+
+func (r *CustomFieldRepository) Create(ctx context.Context, f models.CustomField, actorID *int) (*models.CustomField, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.custom_fields.Create")
+	defer span.End()
+
+	var options []byte
+	if len(f.Options) > 0 {
+		var err error
+		if options, err = json.Marshal(f.Options); err != nil {
+			return nil, fmt.Errorf("repo: failed to encode options: %w", err)
+		}
+	}
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// UNIQUE (entity, field_key) catches a key defined twice
+	res, err := tx.ExecContext(ctx,
+		"INSERT INTO custom_fields (entity, field_key, label, type, options, required) VALUES (?,?,?,?,?,?)",
+		f.Entity, f.Key, f.Label, f.Type, options, f.Required)
+	if conflict := asDuplicateEntry(err); conflict != nil {
+		return nil, fmt.Errorf("repo: custom field %s.%s exists: %w", f.Entity, f.Key, conflict)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to insert custom field: %w", err)
+	}
+	id, _ := res.LastInsertId()
+	f.ID = int(id)
+
+	if err := insertAudit(ctx, tx, models.AuditEntry{
+		ActorID:  actorID,
+		Action:   models.AuditCustomFieldCreated,
+		Entity:   "custom_field",
+		EntityID: f.ID,
+		Details:  map[string]any{"entity": f.Entity, "key": f.Key, "type": f.Type},
+	}); err != nil {
+		return nil, err
+	}
+	if err := tx.QueryRowContext(ctx, "SELECT created_at FROM custom_fields WHERE id = ?", f.ID).Scan(&f.CreatedAt); err != nil {
+		return nil, fmt.Errorf("repo: failed to read back custom field: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("repo: failed to commit custom field: %w", err)
+	}
+	return &f, nil
+}
+
+func (r *CustomFieldRepository) Delete(ctx context.Context, id int, actorID *int) error {
+	ctx, span := tracing.StartQuery(ctx, "repo.custom_fields.Delete")
+	defer span.End()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("repo: failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var entity, key string
+	err = tx.QueryRowContext(ctx, "SELECT entity, field_key FROM custom_fields WHERE id = ? FOR UPDATE", id).Scan(&entity, &key)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("repo: custom field %d not found: %w", id, models.ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("repo: failed to get custom field %d: %w", id, err)
+	}
+
+	// The key was checked when the field was defined, so it's safe in the path
+	table, path := customFieldTables[entity], "'$."+key+"'"
+	res, err := tx.ExecContext(ctx,
+		"UPDATE "+table+" SET custom_fields = JSON_REMOVE(custom_fields, "+path+") WHERE JSON_CONTAINS_PATH(custom_fields, 'one', "+path+")")
+	if err != nil {
+		return fmt.Errorf("repo: failed to remove values of custom field %d: %w", id, err)
+	}
+	cleared, _ := res.RowsAffected()
+	if _, err := tx.ExecContext(ctx, "DELETE FROM custom_fields WHERE id = ?", id); err != nil {
+		return fmt.Errorf("repo: failed to delete custom field %d: %w", id, err)
+	}
+
+	if err := insertAudit(ctx, tx, models.AuditEntry{
+		ActorID:  actorID,
+		Action:   models.AuditCustomFieldDeleted,
+		Entity:   "custom_field",
+		EntityID: id,
+		Details:  map[string]any{"entity": entity, "key": key, "values_removed": cleared},
+	}); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("repo: failed to commit custom field deletion: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"simpleapi/internal/models"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// MySQL error numbers we translate into domain errors
+const (
+	mysqlErrDuplicateEntry  = 1062
+	mysqlErrNoReferencedRow = 1452
+)
+
+// Error 1062 reads: Duplicate entry 'a@b.com' for key 'teachers.email'
+// (MySQL 8 prefixes the key with the table name, 5.7 does not)
+var duplicateEntryRe = regexp.MustCompile(`Duplicate entry '(.*)' for key '(?:[^.']+\.)?([^']+)'`)
+
+// uniqueKeyFields maps index names that don't match their column to the API field.
+// Indexes named after their column (the default for UNIQUE on one column) need no entry.
+var uniqueKeyFields = map[string]string{
+	"PRIMARY":                    "id",
+	"uq_student_comments_term":   "term",
+	"uq_grading_schemes_subject": "subject",
+	"uq_student_scores_term":     "term",
+	"uq_custom_fields_key":       "key",
+}
+
+// asDuplicateEntry turns a MySQL 1062 into a *models.ConflictError naming the field
+// that clashed. It returns nil for any other error.
+func asDuplicateEntry(err error) *models.ConflictError {
+	var myErr *mysql.MySQLError
+	if !errors.As(err, &myErr) || myErr.Number != mysqlErrDuplicateEntry {
+		return nil
+	}
+
+	m := duplicateEntryRe.FindStringSubmatch(myErr.Message)
+	if m == nil {
+		return &models.ConflictError{Field: "unknown"}
+	}
+	return &models.ConflictError{Field: fieldForKey(m[2]), Value: m[1]}
+}
+
+// fieldForKey strips conventional prefixes (uq_, unique_, idx_, <table>_) from an index name
+func fieldForKey(key string) string {
+	if field, ok := uniqueKeyFields[key]; ok {
+		return field
+	}
+	for _, prefix := range []string{"uq_", "uniq_", "unique_", "ux_", "idx_"} {
+		key = strings.TrimPrefix(key, prefix)
+	}
+	for _, table := range []string{"teachers_", "students_"} {
+		key = strings.TrimPrefix(key, table)
+	}
+	return key
+}
+
+// takenValues returns which of values column holds already in table, as
+// conflicts on the API field. Values come back as stored, which the unique
+// key's collation may match ignoring case.
+func takenValues(ctx context.Context, db Conn, table, column, field string, values []any) ([]models.ConflictError, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	rows, err := db.QueryContext(ctx,
+		"SELECT "+column+" FROM "+table+" WHERE "+column+" IN (?"+strings.Repeat(",?", len(values)-1)+")", values...)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to look up taken %s: %w", field, err)
+	}
+	defer rows.Close()
+
+	var conflicts []models.ConflictError
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan taken %s: %w", field, err)
+		}
+		conflicts = append(conflicts, models.ConflictError{Field: field, Value: value})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return conflicts, nil
+}
+
+// isMissingReference reports whether err is a MySQL 1452: a foreign key
+// pointing at a row that doesn't exist
+func isMissingReference(err error) bool {
+	var myErr *mysql.MySQLError
+	return errors.As(err, &myErr) && myErr.Number == mysqlErrNoReferencedRow
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/tracing"
+	"time"
+)
+
+// DocumentRepository stores the documents generated for students (table documents)
+type DocumentRepository struct {
+	DB Conn
+}
+
+// NewDocumentRepository is the constructor
+func NewDocumentRepository(db *sql.DB) *DocumentRepository {
+	return &DocumentRepository{DB: Pool(db)}
+}
+
+const documentColumns = "id, student_id, template_id, title, format, content_type, size, storage_key, emailed_to, emailed_at, created_by, created_at"
+
+func scanDocument(row interface{ Scan(...any) error }, d *models.Document) error {
+	var templateID, createdBy sql.NullInt64
+	var emailedTo sql.NullString
+	var emailedAt sql.NullTime
+	if err := row.Scan(&d.ID, &d.StudentID, &templateID, &d.Title, &d.Format, &d.ContentType, &d.Size, &d.StorageKey,
+		&emailedTo, &emailedAt, &createdBy, &d.CreatedAt); err != nil {
+		return err
+	}
+	if templateID.Valid {
+		id := int(templateID.Int64)
+		d.TemplateID = &id
+	}
+	d.EmailedTo = emailedTo.String
+	if emailedAt.Valid {
+		d.EmailedAt = &emailedAt.Time
+	}
+	if createdBy.Valid {
+		id := int(createdBy.Int64)
+		d.CreatedBy = &id
+	}
+	return nil
+}
+
+func (r *DocumentRepository) Create(ctx context.Context, d models.Document) (*models.Document, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.documents.Create")
+	defer span.End()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO documents (student_id, template_id, title, format, content_type, size, storage_key, created_by)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		d.StudentID, d.TemplateID, d.Title, d.Format, d.ContentType, d.Size, d.StorageKey, d.CreatedBy)
+	if err != nil {
+		if isMissingReference(err) {
+			return nil, fmt.Errorf("repo: student %d not found: %w", d.StudentID, models.ErrNotFound)
+		}
+		return nil, fmt.Errorf("repo: failed to insert document: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to read document id: %w", err)
+	}
+
+	if err := insertAudit(ctx, tx, models.AuditEntry{
+		ActorID:  d.CreatedBy,
+		Action:   models.AuditDocumentCreated,
+		Entity:   "document",
+		EntityID: int(id),
+		Details:  map[string]any{"student_id": d.StudentID, "template_id": d.TemplateID, "title": d.Title, "format": d.Format},
+	}); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("repo: failed to commit tx: %w", err)
+	}
+	return r.GetByID(ctx, int(id))
+}
+
+func (r *DocumentRepository) GetByID(ctx context.Context, id int) (*models.Document, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.documents.GetByID")
+	defer span.End()
+
+	var d models.Document
+	err := scanDocument(r.DB.QueryRowContext(ctx, "SELECT "+documentColumns+" FROM documents WHERE id = ?", id), &d)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("repo: document %d not found: %w", id, models.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to read document: %w", err)
+	}
+	return &d, nil
+}
+
+func (r *DocumentRepository) ListByStudent(ctx context.Context, studentID int) ([]models.Document, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.documents.ListByStudent")
+	defer span.End()
+
+	rows, err := r.DB.QueryContext(ctx,
+		"SELECT "+documentColumns+" FROM documents WHERE student_id = ? ORDER BY created_at DESC, id DESC", studentID)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to query documents: %w", err)
+	}
+	defer rows.Close()
+
+	documents := make([]models.Document, 0)
+	for rows.Next() {
+		var d models.Document
+		if err := scanDocument(rows, &d); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan document: %w", err)
+		}
+		documents = append(documents, d)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return documents, nil
+}
+
+func (r *DocumentRepository) MarkEmailed(ctx context.Context, id int, address string, at time.Time) error {
+	ctx, span := tracing.StartQuery(ctx, "repo.documents.MarkEmailed")
+	defer span.End()
+
+	if _, err := r.DB.ExecContext(ctx, "UPDATE documents SET emailed_to = ?, emailed_at = ? WHERE id = ?", address, at, id); err != nil {
+		return fmt.Errorf("repo: failed to mark document %d emailed: %w", id, err)
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/tracing"
+	"time"
+)
+
+// EmailChangeRepository stores teachers' pending and past email changes (table
+// email_changes) and applies them to the teachers table
+type EmailChangeRepository struct {
+	DB Conn
+}
+
+// NewEmailChangeRepository is the constructor
+func NewEmailChangeRepository(db *sql.DB) *EmailChangeRepository {
+	return &EmailChangeRepository{DB: Pool(db)}
+}
+
+const emailChangeColumns = `id, teacher_id, old_email, new_email, status, confirm_token_hash, undo_token_hash,
+	expires_at, undo_expires_at, requested_by, created_at, confirmed_at, undone_at`
+
+func scanEmailChange(row interface{ Scan(...any) error }, c *models.EmailChange) error {
+	var requestedBy sql.NullInt64
+	var confirmedAt, undoneAt sql.NullTime
+	err := row.Scan(&c.ID, &c.TeacherID, &c.OldEmail, &c.NewEmail, &c.Status, &c.ConfirmTokenHash, &c.UndoTokenHash,
+		&c.ExpiresAt, &c.UndoExpiresAt, &requestedBy, &c.CreatedAt, &confirmedAt, &undoneAt)
+	if err != nil {
+		return err
+	}
+	if requestedBy.Valid {
+		id := int(requestedBy.Int64)
+		c.RequestedBy = &id
+	}
+	if confirmedAt.Valid {
+		c.ConfirmedAt = &confirmedAt.Time
+	}
+	if undoneAt.Valid {
+		c.UndoneAt = &undoneAt.Time
+	}
+	return nil
+}
+
+// Create records a pending change from the teacher's current email to c.NewEmail,
+// cancelling any change still pending for them
+func (r *EmailChangeRepository) Create(ctx context.Context, c models.EmailChange) (*models.EmailChange, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.email_changes.Create")
+	defer span.End()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx, "SELECT email FROM teachers WHERE id = ? AND deleted_at IS NULL FOR UPDATE", c.TeacherID).Scan(&c.OldEmail)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("repo: teacher %d not found: %w", c.TeacherID, models.ErrTeacherNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to read teacher %d: %w", c.TeacherID, err)
+	}
+	if c.NewEmail == c.OldEmail {
+		return nil, fmt.Errorf("repo: %s is already the teacher's email: %w", c.NewEmail, models.ErrInvalidInput)
+	}
+	// Trashed teachers count: they keep their email under the unique key
+	var taken bool
+	if err := tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM teachers WHERE email = ?)", c.NewEmail).Scan(&taken); err != nil {
+		return nil, fmt.Errorf("repo: failed to check email: %w", err)
+	}
+	if taken {
+		return nil, fmt.Errorf("repo: failed to request email change: %w", &models.ConflictError{Field: "email", Value: c.NewEmail})
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE email_changes SET status = ? WHERE teacher_id = ? AND status = ?",
+		models.EmailChangeCancelled, c.TeacherID, models.EmailChangePending); err != nil {
+		return nil, fmt.Errorf("repo: failed to cancel pending email changes: %w", err)
+	}
+
+	c.Status = models.EmailChangePending
+	res, err := tx.ExecContext(ctx,
+		`INSERT INTO email_changes (teacher_id, old_email, new_email, status, confirm_token_hash, undo_token_hash, expires_at, undo_expires_at, requested_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		c.TeacherID, c.OldEmail, c.NewEmail, c.Status, c.ConfirmTokenHash, c.UndoTokenHash, c.ExpiresAt, c.UndoExpiresAt, c.RequestedBy, c.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to insert email change: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to read email change id: %w", err)
+	}
+	c.ID = int(id)
+
+	if err := insertAudit(ctx, tx, models.AuditEntry{
+		ActorID:  c.RequestedBy,
+		Action:   models.AuditEmailChangeRequested,
+		Entity:   "teacher",
+		EntityID: c.TeacherID,
+		Details:  map[string]any{"email_change_id": c.ID, "old_email": c.OldEmail, "new_email": c.NewEmail},
+	}); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("repo: failed to commit tx: %w", err)
+	}
+	return &c, nil
+}
+
+// Confirm applies the pending, unexpired change whose confirm token hashes to tokenHash
+func (r *EmailChangeRepository) Confirm(ctx context.Context, tokenHash string, now time.Time) (*models.EmailChange, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.email_changes.Confirm")
+	defer span.End()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	c, err := r.getTx(ctx, tx, "confirm_token_hash", tokenHash)
+	if err != nil {
+		return nil, err
+	}
+	if c.Status != models.EmailChangePending || !now.Before(c.ExpiresAt) {
+		return nil, fmt.Errorf("repo: email change %d can't be confirmed: %w", c.ID, models.ErrEmailChangeLinkInvalid)
+	}
+
+	if err := r.setEmailTx(ctx, tx, c.TeacherID, c.OldEmail, c.NewEmail); err != nil {
+		return nil, err
+	}
+	c.Status, c.ConfirmedAt = models.EmailChangeConfirmed, &now
+	if _, err := tx.ExecContext(ctx, "UPDATE email_changes SET status = ?, confirmed_at = ? WHERE id = ?", c.Status, now, c.ID); err != nil {
+		return nil, fmt.Errorf("repo: failed to confirm email change %d: %w", c.ID, err)
+	}
+	if err := insertAudit(ctx, tx, models.AuditEntry{
+		Action:   models.AuditEmailChanged,
+		Entity:   "teacher",
+		EntityID: c.TeacherID,
+		Details: map[string]any{
+			"email_change_id": c.ID,
+			"changes":         map[string]models.FieldChange{"email": {From: c.OldEmail, To: c.NewEmail}},
+		},
+	}); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("repo: failed to commit tx: %w", err)
+	}
+	return c, nil
+}
+
+// Undo stops a pending change, or reverts a confirmed one, while the undo
+// token hashing to tokenHash is still valid
+func (r *EmailChangeRepository) Undo(ctx context.Context, tokenHash string, now time.Time) (*models.EmailChange, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.email_changes.Undo")
+	defer span.End()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	c, err := r.getTx(ctx, tx, "undo_token_hash", tokenHash)
+	if err != nil {
+		return nil, err
+	}
+	wasConfirmed := c.Status == models.EmailChangeConfirmed
+	if (c.Status != models.EmailChangePending && !wasConfirmed) || !now.Before(c.UndoExpiresAt) {
+		return nil, fmt.Errorf("repo: email change %d can't be undone: %w", c.ID, models.ErrEmailChangeLinkInvalid)
+	}
+
+	details := map[string]any{"email_change_id": c.ID}
+	c.Status, c.UndoneAt = models.EmailChangeCancelled, &now
+	if wasConfirmed {
+		if err := r.setEmailTx(ctx, tx, c.TeacherID, c.NewEmail, c.OldEmail); err != nil {
+			return nil, err
+		}
+		c.Status = models.EmailChangeUndone
+		details["changes"] = map[string]models.FieldChange{"email": {From: c.NewEmail, To: c.OldEmail}}
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE email_changes SET status = ?, undone_at = ? WHERE id = ?", c.Status, now, c.ID); err != nil {
+		return nil, fmt.Errorf("repo: failed to undo email change %d: %w", c.ID, err)
+	}
+	if err := insertAudit(ctx, tx, models.AuditEntry{
+		Action:   models.AuditEmailChangeUndone,
+		Entity:   "teacher",
+		EntityID: c.TeacherID,
+		Details:  details,
+	}); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("repo: failed to commit tx: %w", err)
+	}
+	return c, nil
+}
+
+// getTx reads and locks the change with the given token hash; column is one of the two hash columns
+func (r *EmailChangeRepository) getTx(ctx context.Context, tx *Tx, column, tokenHash string) (*models.EmailChange, error) {
+	var c models.EmailChange
+	row := tx.QueryRowContext(ctx, "SELECT "+emailChangeColumns+" FROM email_changes WHERE "+column+" = ? FOR UPDATE", tokenHash)
+	err := scanEmailChange(row, &c)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("repo: no email change for this token: %w", models.ErrEmailChangeLinkInvalid)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to read email change: %w", err)
+	}
+	return &c, nil
+}
+
+// setEmailTx moves the teacher from one email to another. It only applies while the
+// teacher still has from: a teacher deleted or edited since the link was sent makes it invalid.
+func (r *EmailChangeRepository) setEmailTx(ctx context.Context, tx *Tx, teacherID int, from, to string) error {
+	res, err := tx.ExecContext(ctx, "UPDATE teachers SET email = ? WHERE id = ? AND email = ? AND deleted_at IS NULL", to, teacherID, from)
+	if err != nil {
+		if conflict := asDuplicateEntry(err); conflict != nil {
+			return fmt.Errorf("repo: failed to change email of teacher %d: %w", teacherID, conflict)
+		}
+		return fmt.Errorf("repo: failed to change email of teacher %d: %w", teacherID, err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return fmt.Errorf("repo: teacher %d no longer has email %s: %w", teacherID, from, models.ErrEmailChangeLinkInvalid)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/tracing"
+	"time"
+)
+
+// EventRepository stores the school calendar (table events)
+type EventRepository struct {
+	DB Conn
+}
+
+// NewEventRepository is the constructor
+func NewEventRepository(db *sql.DB) *EventRepository {
+	return &EventRepository{DB: Pool(db)}
+}
+
+const eventColumns = "id, title, description, type, start_date, end_date, audience, class, created_by, created_at, updated_at"
+
+func scanEvent(row interface{ Scan(...any) error }, e *models.Event) error {
+	var start, end time.Time
+	var description, class sql.NullString
+	var createdBy sql.NullInt64
+	if err := row.Scan(&e.ID, &e.Title, &description, &e.Type, &start, &end, &e.Audience, &class,
+		&createdBy, &e.CreatedAt, &e.UpdatedAt); err != nil {
+		return err
+	}
+	e.Description = description.String
+	e.Class = class.String
+	e.StartDate = start.Format(models.DateLayout)
+	e.EndDate = end.Format(models.DateLayout)
+	if createdBy.Valid {
+		id := int(createdBy.Int64)
+		e.CreatedBy = &id
+	}
+	return nil
+}
+
+// List returns events ordered by start date
+func (r *EventRepository) List(ctx context.Context, filter models.EventFilter) ([]models.Event, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.events.List")
+	defer span.End()
+
+	query := "SELECT " + eventColumns + " FROM events WHERE 1=1"
+	var args []interface{}
+	// Overlap, not containment: a term that started last month is still "in" this week
+	if filter.From != "" {
+		query += " AND end_date >= ?"
+		args = append(args, filter.From)
+	}
+	if filter.To != "" {
+		query += " AND start_date <= ?"
+		args = append(args, filter.To)
+	}
+	if filter.Type != "" {
+		query += " AND type = ?"
+		args = append(args, filter.Type)
+	}
+	if filter.Class != "" {
+		query += " AND (audience = ? OR class = ?)"
+		args = append(args, models.AudienceSchool, filter.Class)
+	}
+	query += " ORDER BY start_date, id"
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to query events: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]models.Event, 0)
+	for rows.Next() {
+		var e models.Event
+		if err := scanEvent(rows, &e); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan event row: %w", err)
+		}
+		events = append(events, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return events, nil
+}
+
+func (r *EventRepository) GetByID(ctx context.Context, id int) (*models.Event, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.events.GetByID")
+	defer span.End()
+
+	var e models.Event
+	err := scanEvent(r.DB.QueryRowContext(ctx, "SELECT "+eventColumns+" FROM events WHERE id = ?", id), &e)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("repo: event %d not found: %w", id, models.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to get event %d: %w", id, err)
+	}
+	return &e, nil
+}
+
+func (r *EventRepository) Create(ctx context.Context, e models.Event) (*models.Event, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.events.Create")
+	defer span.End()
+
+	res, err := r.DB.ExecContext(ctx,
+		"INSERT INTO events (title, description, type, start_date, end_date, audience, class, created_by) VALUES (?,?,?,?,?,?,?,?)",
+		e.Title, nullString(e.Description), e.Type, e.StartDate, e.EndDate, e.Audience, nullString(e.Class), e.CreatedBy)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to insert event: %w", err)
+	}
+
+	id, _ := res.LastInsertId()
+	return r.GetByID(ctx, int(id))
+}
+
+func (r *EventRepository) Update(ctx context.Context, id int, e models.Event) (*models.Event, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.events.Update")
+	defer span.End()
+
+	if _, err := r.DB.ExecContext(ctx,
+		"UPDATE events SET title = ?, description = ?, type = ?, start_date = ?, end_date = ?, audience = ?, class = ? WHERE id = ?",
+		e.Title, nullString(e.Description), e.Type, e.StartDate, e.EndDate, e.Audience, nullString(e.Class), id); err != nil {
+		return nil, fmt.Errorf("repo: failed to update event %d: %w", id, err)
+	}
+
+	// RowsAffected is 0 for "unchanged" too, so re-read to tell missing from unchanged
+	return r.GetByID(ctx, id)
+}
+
+func (r *EventRepository) Delete(ctx context.Context, id int) (bool, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.events.Delete")
+	defer span.End()
+
+	res, err := r.DB.ExecContext(ctx, "DELETE FROM events WHERE id = ?", id)
+	if err != nil {
+		return false, fmt.Errorf("repo: delete failed: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("repo: failed to read rows affected: %w", err)
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/tracing"
+)
+
+// ExamRepository stores exam sessions (table exam_sessions). Classes, rooms,
+// the seating and the invigilators live in JSON columns, like rollover plans.
+type ExamRepository struct {
+	DB Conn
+}
+
+// NewExamRepository is the constructor
+func NewExamRepository(db *sql.DB) *ExamRepository {
+	return &ExamRepository{DB: Pool(db)}
+}
+
+const examColumns = "id, title, subject, DATE_FORMAT(exam_date, '%Y-%m-%d'), start_time, end_time, classes, rooms, seating, seats, " +
+	"invigilators, created_by, created_at, updated_at"
+
+func scanExam(row interface{ Scan(...any) error }, s *models.ExamSession) error {
+	var classes, rooms, seating, seats, invigilators []byte
+	var createdBy sql.NullInt64
+	if err := row.Scan(&s.ID, &s.Title, &s.Subject, &s.Date, &s.StartTime, &s.EndTime, &classes, &rooms, &seating, &seats,
+		&invigilators, &createdBy, &s.CreatedAt, &s.UpdatedAt); err != nil {
+		return err
+	}
+	for _, col := range []struct {
+		name string
+		data []byte
+		dst  any
+	}{
+		{"classes", classes, &s.Classes},
+		{"rooms", rooms, &s.Rooms},
+		{"seats", seats, &s.Seats},
+		{"invigilators", invigilators, &s.Invigilators},
+	} {
+		if err := json.Unmarshal(col.data, col.dst); err != nil {
+			return fmt.Errorf("repo: bad %s in exam session %d: %w", col.name, s.ID, err)
+		}
+	}
+	if seating != nil {
+		s.Seating = new(models.ExamSeating)
+		if err := json.Unmarshal(seating, s.Seating); err != nil {
+			return fmt.Errorf("repo: bad seating in exam session %d: %w", s.ID, err)
+		}
+	}
+	if createdBy.Valid {
+		id := int(createdBy.Int64)
+		s.CreatedBy = &id
+	}
+	return nil
+}
+
+// examJSON encodes the session's JSON columns; seating is nil before it is generated
+func examJSON(s models.ExamSession) (classes, rooms, seating, seats, invigilators []byte, err error) {
+	if s.Seats == nil {
+		s.Seats = []models.ExamSeat{}
+	}
+	if s.Invigilators == nil {
+		s.Invigilators = []models.ExamInvigilator{}
+	}
+	for _, col := range []struct {
+		name string
+		src  any
+		dst  *[]byte
+	}{
+		{"classes", s.Classes, &classes},
+		{"rooms", s.Rooms, &rooms},
+		{"seats", s.Seats, &seats},
+		{"invigilators", s.Invigilators, &invigilators},
+	} {
+		if *col.dst, err = json.Marshal(col.src); err != nil {
+			return nil, nil, nil, nil, nil, fmt.Errorf("repo: failed to encode %s: %w", col.name, err)
+		}
+	}
+	if s.Seating != nil {
+		if seating, err = json.Marshal(s.Seating); err != nil {
+			return nil, nil, nil, nil, nil, fmt.Errorf("repo: failed to encode seating: %w", err)
+		}
+	}
+	return classes, rooms, seating, seats, invigilators, nil
+}
+
+func (r *ExamRepository) Create(ctx context.Context, s models.ExamSession) (*models.ExamSession, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.exams.Create")
+	defer span.End()
+
+	classes, rooms, seating, seats, invigilators, err := examJSON(s)
+	if err != nil {
+		return nil, err
+	}
+	res, err := r.DB.ExecContext(ctx,
+		`INSERT INTO exam_sessions (title, subject, exam_date, start_time, end_time, classes, rooms, seating, seats, invigilators, created_by)
+		 VALUES (?,?,?,?,?,?,?,?,?,?,?)`,
+		s.Title, s.Subject, s.Date, s.StartTime, s.EndTime, classes, rooms, seating, seats, invigilators, s.CreatedBy)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to insert exam session: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to read exam session id: %w", err)
+	}
+	return r.GetByID(ctx, int(id))
+}
+
+func (r *ExamRepository) GetByID(ctx context.Context, id int) (*models.ExamSession, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.exams.GetByID")
+	defer span.End()
+
+	var s models.ExamSession
+	err := scanExam(r.DB.QueryRowContext(ctx, "SELECT "+examColumns+" FROM exam_sessions WHERE id = ?", id), &s)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("repo: exam session %d not found: %w", id, models.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to read exam session: %w", err)
+	}
+	return &s, nil
+}
+
+func (r *ExamRepository) List(ctx context.Context, date string) ([]models.ExamSession, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.exams.List")
+	defer span.End()
+
+	q, args := "SELECT "+examColumns+" FROM exam_sessions", []any{}
+	if date != "" {
+		q += " WHERE exam_date = ?"
+		args = append(args, date)
+	}
+	rows, err := r.DB.QueryContext(ctx, q+" ORDER BY exam_date, start_time, id", args...)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to query exam sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := make([]models.ExamSession, 0)
+	for rows.Next() {
+		var s models.ExamSession
+		if err := scanExam(rows, &s); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan exam session: %w", err)
+		}
+		sessions = append(sessions, s)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return sessions, nil
+}
+
+func (r *ExamRepository) Save(ctx context.Context, s models.ExamSession) (*models.ExamSession, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.exams.Save")
+	defer span.End()
+
+	classes, rooms, seating, seats, invigilators, err := examJSON(s)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.DB.ExecContext(ctx,
+		`UPDATE exam_sessions SET title = ?, subject = ?, exam_date = ?, start_time = ?, end_time = ?, classes = ?, rooms = ?,
+		   seating = ?, seats = ?, invigilators = ? WHERE id = ?`,
+		s.Title, s.Subject, s.Date, s.StartTime, s.EndTime, classes, rooms, seating, seats, invigilators, s.ID); err != nil {
+		return nil, fmt.Errorf("repo: failed to save exam session %d: %w", s.ID, err)
+	}
+	// A session deleted meanwhile is ErrNotFound here
+	return r.GetByID(ctx, s.ID)
+}
+
+func (r *ExamRepository) Delete(ctx context.Context, id int) error {
+	ctx, span := tracing.StartQuery(ctx, "repo.exams.Delete")
+	defer span.End()
+
+	res, err := r.DB.ExecContext(ctx, "DELETE FROM exam_sessions WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("repo: failed to delete exam session %d: %w", id, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("repo: failed to delete exam session %d: %w", id, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("repo: exam session %d not found: %w", id, models.ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/tracing"
+	"strings"
+)
+
+// GradingRepository stores grading schemes (table grading_schemes).
+// The school-wide default has an empty subject (not NULL) so the unique key on subject
+// allows exactly one default. Boundaries live in a JSON column: they are always
+// read and written as a whole.
+type GradingRepository struct {
+	DB Conn
+}
+
+// NewGradingRepository is the constructor
+func NewGradingRepository(db *sql.DB) *GradingRepository {
+	return &GradingRepository{DB: Pool(db)}
+}
+
+const schemeColumns = "id, name, subject, boundaries, created_at, updated_at"
+
+func scanScheme(row interface{ Scan(...any) error }, s *models.GradingScheme) error {
+	var boundaries []byte
+	if err := row.Scan(&s.ID, &s.Name, &s.Subject, &boundaries, &s.CreatedAt, &s.UpdatedAt); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(boundaries, &s.Boundaries); err != nil {
+		return fmt.Errorf("repo: bad boundaries in grading scheme %d: %w", s.ID, err)
+	}
+	return nil
+}
+
+func (r *GradingRepository) List(ctx context.Context) ([]models.GradingScheme, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.grading.List")
+	defer span.End()
+
+	rows, err := r.DB.QueryContext(ctx, "SELECT "+schemeColumns+" FROM grading_schemes ORDER BY subject")
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to query grading schemes: %w", err)
+	}
+	defer rows.Close()
+
+	schemes := make([]models.GradingScheme, 0)
+	for rows.Next() {
+		var s models.GradingScheme
+		if err := scanScheme(rows, &s); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan grading scheme row: %w", err)
+		}
+		schemes = append(schemes, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return schemes, nil
+}
+
+func (r *GradingRepository) GetByID(ctx context.Context, id int) (*models.GradingScheme, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.grading.GetByID")
+	defer span.End()
+
+	var s models.GradingScheme
+	err := scanScheme(r.DB.QueryRowContext(ctx, "SELECT "+schemeColumns+" FROM grading_schemes WHERE id = ?", id), &s)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("repo: grading scheme %d not found: %w", id, models.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to get grading scheme %d: %w", id, err)
+	}
+	return &s, nil
+}
+
+// ForSubject returns the scheme that applies to a subject: its own if it has one,
+// otherwise the school-wide default. ErrNotFound means no scheme is configured at all.
+func (r *GradingRepository) ForSubject(ctx context.Context, subject string) (*models.GradingScheme, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.grading.ForSubject")
+	defer span.End()
+
+	// (subject = '') is 0 for the subject's own scheme, so it sorts ahead of the default
+	var s models.GradingScheme
+	err := scanScheme(r.DB.QueryRowContext(ctx,
+		"SELECT "+schemeColumns+" FROM grading_schemes WHERE subject IN (?, '') ORDER BY (subject = '') LIMIT 1",
+		subject), &s)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("repo: no grading scheme for subject %q: %w", subject, models.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to get grading scheme for %q: %w", subject, err)
+	}
+	return &s, nil
+}
+
+// Create inserts a scheme. A second scheme for the same subject is a ConflictError.
+func (r *GradingRepository) Create(ctx context.Context, s models.GradingScheme) (*models.GradingScheme, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.grading.Create")
+	defer span.End()
+
+	boundaries, err := json.Marshal(s.Boundaries)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to encode boundaries: %w", err)
+	}
+
+	res, err := r.DB.ExecContext(ctx,
+		"INSERT INTO grading_schemes (name, subject, boundaries) VALUES (?,?,?)",
+		s.Name, s.Subject, boundaries)
+	if err != nil {
+		if conflict := asDuplicateEntry(err); conflict != nil {
+			return nil, fmt.Errorf("repo: failed to insert grading scheme: %w", conflict)
+		}
+		return nil, fmt.Errorf("repo: failed to insert grading scheme: %w", err)
+	}
+
+	id, _ := res.LastInsertId()
+	return r.GetByID(ctx, int(id))
+}
+
+// Update replaces a scheme's name, subject and boundaries.
+// Scores already posted keep the grade they were given.
+func (r *GradingRepository) Update(ctx context.Context, id int, s models.GradingScheme) (*models.GradingScheme, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.grading.Update")
+	defer span.End()
+
+	boundaries, err := json.Marshal(s.Boundaries)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to encode boundaries: %w", err)
+	}
+
+	if _, err := r.DB.ExecContext(ctx,
+		"UPDATE grading_schemes SET name = ?, subject = ?, boundaries = ? WHERE id = ?",
+		s.Name, s.Subject, boundaries, id); err != nil {
+		if conflict := asDuplicateEntry(err); conflict != nil {
+			return nil, fmt.Errorf("repo: failed to update grading scheme: %w", conflict)
+		}
+		return nil, fmt.Errorf("repo: failed to update grading scheme: %w", err)
+	}
+
+	// RowsAffected is 0 for "unchanged" too, so re-read to tell missing from unchanged
+	return r.GetByID(ctx, id)
+}
+
+func (r *GradingRepository) Delete(ctx context.Context, id int) (bool, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.grading.Delete")
+	defer span.End()
+
+	res, err := r.DB.ExecContext(ctx, "DELETE FROM grading_schemes WHERE id = ?", id)
+	if err != nil {
+		return false, fmt.Errorf("repo: delete failed: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("repo: failed to read rows affected: %w", err)
+	}
+	return n > 0, nil
+}
+
+// ReplaceAll makes the stored schemes exactly schemes, matched by subject, in one
+// transaction: existing subjects keep their ID (scores point at it), new ones are
+// inserted and subjects not in schemes are deleted
+func (r *GradingRepository) ReplaceAll(ctx context.Context, schemes []models.GradingScheme) error {
+	ctx, span := tracing.StartQuery(ctx, "repo.grading.ReplaceAll")
+	defer span.End()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("repo: failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	subjects := make([]interface{}, 0, len(schemes))
+	for _, s := range schemes {
+		boundaries, err := json.Marshal(s.Boundaries)
+		if err != nil {
+			return fmt.Errorf("repo: failed to encode boundaries: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO grading_schemes (name, subject, boundaries) VALUES (?,?,?)
+			 ON DUPLICATE KEY UPDATE name = VALUES(name), boundaries = VALUES(boundaries)`,
+			s.Name, s.Subject, boundaries); err != nil {
+			return fmt.Errorf("repo: failed to save grading scheme %q: %w", s.Subject, err)
+		}
+		subjects = append(subjects, s.Subject)
+	}
+
+	query := "DELETE FROM grading_schemes"
+	if len(subjects) > 0 {
+		query += " WHERE subject NOT IN (?" + strings.Repeat(",?", len(subjects)-1) + ")"
+	}
+	if _, err := tx.ExecContext(ctx, query, subjects...); err != nil {
+		return fmt.Errorf("repo: failed to delete grading schemes: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("repo: failed to commit grading schemes: %w", err)
+	}
+	return nil
+}
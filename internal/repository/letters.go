@@ -0,0 +1,195 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/tracing"
+)
+
+// LetterTemplateRepository stores letter templates (table letter_templates)
+type LetterTemplateRepository struct {
+	DB Conn
+}
+
+// NewLetterTemplateRepository is the constructor
+func NewLetterTemplateRepository(db *sql.DB) *LetterTemplateRepository {
+	return &LetterTemplateRepository{DB: Pool(db)}
+}
+
+const letterTemplateColumns = "id, name, kind, subject, body, created_by, created_at, updated_at"
+
+func scanLetterTemplate(row interface{ Scan(...any) error }, t *models.LetterTemplate) error {
+	var createdBy sql.NullInt64
+	if err := row.Scan(&t.ID, &t.Name, &t.Kind, &t.Subject, &t.Body, &createdBy, &t.CreatedAt, &t.UpdatedAt); err != nil {
+		return err
+	}
+	if createdBy.Valid {
+		id := int(createdBy.Int64)
+		t.CreatedBy = &id
+	}
+	return nil
+}
+
+func (r *LetterTemplateRepository) Create(ctx context.Context, t models.LetterTemplate) (*models.LetterTemplate, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.letter_templates.Create")
+	defer span.End()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		"INSERT INTO letter_templates (name, kind, subject, body, created_by) VALUES (?, ?, ?, ?, ?)",
+		t.Name, t.Kind, t.Subject, t.Body, t.CreatedBy)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to insert letter template: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to read letter template id: %w", err)
+	}
+
+	if err := insertAudit(ctx, tx, models.AuditEntry{
+		ActorID:  t.CreatedBy,
+		Action:   models.AuditLetterTemplateCreated,
+		Entity:   "letter_template",
+		EntityID: int(id),
+		Details:  map[string]any{"name": t.Name, "kind": t.Kind},
+	}); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("repo: failed to commit tx: %w", err)
+	}
+	return r.GetByID(ctx, int(id))
+}
+
+func (r *LetterTemplateRepository) GetByID(ctx context.Context, id int) (*models.LetterTemplate, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.letter_templates.GetByID")
+	defer span.End()
+
+	var t models.LetterTemplate
+	err := scanLetterTemplate(r.DB.QueryRowContext(ctx, "SELECT "+letterTemplateColumns+" FROM letter_templates WHERE id = ?", id), &t)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("repo: letter template %d not found: %w", id, models.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to read letter template: %w", err)
+	}
+	return &t, nil
+}
+
+func (r *LetterTemplateRepository) List(ctx context.Context, kind string) ([]models.LetterTemplate, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.letter_templates.List")
+	defer span.End()
+
+	q, args := "SELECT "+letterTemplateColumns+" FROM letter_templates", []any{}
+	if kind != "" {
+		q += " WHERE kind = ?"
+		args = append(args, kind)
+	}
+	rows, err := r.DB.QueryContext(ctx, q+" ORDER BY name, id", args...)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to query letter templates: %w", err)
+	}
+	defer rows.Close()
+
+	templates := make([]models.LetterTemplate, 0)
+	for rows.Next() {
+		var t models.LetterTemplate
+		if err := scanLetterTemplate(rows, &t); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan letter template: %w", err)
+		}
+		templates = append(templates, t)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return templates, nil
+}
+
+func (r *LetterTemplateRepository) Update(ctx context.Context, id int, req models.LetterTemplateRequest, actorID *int) (*models.LetterTemplate, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.letter_templates.Update")
+	defer span.End()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	var before models.LetterTemplate
+	err = scanLetterTemplate(tx.QueryRowContext(ctx, "SELECT "+letterTemplateColumns+" FROM letter_templates WHERE id = ? FOR UPDATE", id), &before)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("repo: letter template %d not found: %w", id, models.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to read letter template %d: %w", id, err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE letter_templates SET name = ?, kind = ?, subject = ?, body = ? WHERE id = ?",
+		req.Name, req.Kind, req.Subject, req.Body, id); err != nil {
+		return nil, fmt.Errorf("repo: failed to update letter template %d: %w", id, err)
+	}
+
+	if err := insertAudit(ctx, tx, models.AuditEntry{
+		ActorID:  actorID,
+		Action:   models.AuditLetterTemplateUpdated,
+		Entity:   "letter_template",
+		EntityID: id,
+		Details: map[string]any{
+			"name":          req.Name,
+			"previous_name": before.Name,
+			"kind":          req.Kind,
+			"text_changed":  req.Subject != before.Subject || req.Body != before.Body,
+		},
+	}); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("repo: failed to commit tx: %w", err)
+	}
+	return r.GetByID(ctx, id)
+}
+
+func (r *LetterTemplateRepository) Delete(ctx context.Context, id int, actorID *int) error {
+	ctx, span := tracing.StartQuery(ctx, "repo.letter_templates.Delete")
+	defer span.End()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("repo: failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	var name string
+	err = tx.QueryRowContext(ctx, "SELECT name FROM letter_templates WHERE id = ? FOR UPDATE", id).Scan(&name)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("repo: letter template %d not found: %w", id, models.ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("repo: failed to read letter template %d: %w", id, err)
+	}
+	// documents.template_id is ON DELETE SET NULL: the letters stay
+	if _, err := tx.ExecContext(ctx, "DELETE FROM letter_templates WHERE id = ?", id); err != nil {
+		return fmt.Errorf("repo: failed to delete letter template %d: %w", id, err)
+	}
+
+	if err := insertAudit(ctx, tx, models.AuditEntry{
+		ActorID:  actorID,
+		Action:   models.AuditLetterTemplateDeleted,
+		Entity:   "letter_template",
+		EntityID: id,
+		Details:  map[string]any{"name": name},
+	}); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("repo: failed to commit tx: %w", err)
+	}
+	return nil
+}
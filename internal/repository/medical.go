@@ -0,0 +1,155 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/tracing"
+	"strings"
+)
+
+// MedicalRepository stores students' medical records (table student_medical)
+type MedicalRepository struct {
+	DB Conn
+}
+
+// NewMedicalRepository is the constructor
+func NewMedicalRepository(db *sql.DB) *MedicalRepository {
+	return &MedicalRepository{DB: Pool(db)}
+}
+
+const medicalColumns = "student_id, allergies, conditions, medications, notes, emergency_contacts, updated_by, updated_at"
+
+func scanMedical(row interface{ Scan(...any) error }, m *models.MedicalRecord) error {
+	var contacts []byte
+	var updatedBy sql.NullInt64
+	if err := row.Scan(&m.StudentID, &m.Allergies, &m.Conditions, &m.Medications, &m.Notes, &contacts, &updatedBy, &m.UpdatedAt); err != nil {
+		return err
+	}
+	m.EmergencyContacts = make([]models.EmergencyContact, 0)
+	if len(contacts) > 0 {
+		if err := json.Unmarshal(contacts, &m.EmergencyContacts); err != nil {
+			return fmt.Errorf("bad emergency contacts of student %d: %w", m.StudentID, err)
+		}
+	}
+	if updatedBy.Valid {
+		id := int(updatedBy.Int64)
+		m.UpdatedBy = &id
+	}
+	return nil
+}
+
+// Get reads the records and audits each one in the same transaction
+func (r *MedicalRepository) Get(ctx context.Context, ids []int, actorID *int, reason string) ([]models.MedicalRecord, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.medical.Get")
+	defer span.End()
+
+	records := make([]models.MedicalRecord, 0, len(ids))
+	if len(ids) == 0 {
+		return records, nil
+	}
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	rows, err := tx.QueryContext(ctx,
+		"SELECT "+medicalColumns+" FROM student_medical WHERE student_id IN (?"+strings.Repeat(",?", len(ids)-1)+")", args...)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to query medical records: %w", err)
+	}
+	for rows.Next() {
+		var m models.MedicalRecord
+		if err := scanMedical(rows, &m); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("repo: failed to scan medical record: %w", err)
+		}
+		records = append(records, m)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+
+	// Only the reason goes in the details: the audit log and its outbox events
+	// are no place for the record itself
+	for _, m := range records {
+		if err := insertAudit(ctx, tx, models.AuditEntry{
+			ActorID:  actorID,
+			Action:   models.AuditMedicalViewed,
+			Entity:   "student",
+			EntityID: m.StudentID,
+			Details:  map[string]any{"reason": reason},
+		}); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("repo: failed to commit tx: %w", err)
+	}
+	return records, nil
+}
+
+// Save replaces the student's record. The audit entry names the fields that
+// changed, not their values.
+func (r *MedicalRepository) Save(ctx context.Context, rec models.MedicalRecord, actorID *int) (*models.MedicalRecord, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.medical.Save")
+	defer span.End()
+
+	contacts, err := json.Marshal(rec.EmergencyContacts)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to encode emergency contacts: %w", err)
+	}
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	var before models.MedicalRecord
+	err = scanMedical(tx.QueryRowContext(ctx,
+		"SELECT "+medicalColumns+" FROM student_medical WHERE student_id = ? FOR UPDATE", rec.StudentID), &before)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, fmt.Errorf("repo: failed to read medical record: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO student_medical (student_id, allergies, conditions, medications, notes, emergency_contacts, updated_by)
+		 VALUES (?, ?, ?, ?, ?, ?, ?)
+		 ON DUPLICATE KEY UPDATE allergies = VALUES(allergies), conditions = VALUES(conditions), medications = VALUES(medications),
+		 notes = VALUES(notes), emergency_contacts = VALUES(emergency_contacts), updated_by = VALUES(updated_by)`,
+		rec.StudentID, rec.Allergies, rec.Conditions, rec.Medications, rec.Notes, contacts, actorID)
+	if err != nil {
+		if isMissingReference(err) {
+			return nil, fmt.Errorf("repo: student %d not found: %w", rec.StudentID, models.ErrStudentNotFound)
+		}
+		return nil, fmt.Errorf("repo: failed to save medical record: %w", err)
+	}
+
+	if err := insertAudit(ctx, tx, models.AuditEntry{
+		ActorID:  actorID,
+		Action:   models.AuditMedicalUpdated,
+		Entity:   "student",
+		EntityID: rec.StudentID,
+		Details:  map[string]any{"fields": models.MedicalChanges(before, rec)},
+	}); err != nil {
+		return nil, err
+	}
+
+	var saved models.MedicalRecord
+	if err := scanMedical(tx.QueryRowContext(ctx,
+		"SELECT "+medicalColumns+" FROM student_medical WHERE student_id = ?", rec.StudentID), &saved); err != nil {
+		return nil, fmt.Errorf("repo: failed to read saved medical record: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("repo: failed to commit tx: %w", err)
+	}
+	return &saved, nil
+}
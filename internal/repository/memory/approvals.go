@@ -0,0 +1,105 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"sort"
+)
+
+// ApprovalRepository is the in-memory twin of repository.ApprovalRepository
+type ApprovalRepository struct {
+	db *DB
+}
+
+var _ repository.ApprovalStore = (*ApprovalRepository)(nil)
+
+// NewApprovalRepository is the constructor
+func NewApprovalRepository(db *DB) *ApprovalRepository {
+	return &ApprovalRepository{db: db}
+}
+
+func (r *ApprovalRepository) Create(ctx context.Context, a models.Approval) (*models.Approval, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	a.ID = r.db.newID("approvals")
+	a.State = models.ApprovalPending
+	a.Comment, a.DecidedBy, a.DecidedAt = "", nil, nil
+	a.CreatedAt = r.db.now()
+	a.UpdatedAt = a.CreatedAt
+	r.db.approvals[a.ID] = a
+	r.db.appendAudit(ctx, models.AuditEntry{
+		ActorID:  &a.RequestedBy,
+		Action:   models.AuditApprovalSubmitted,
+		Entity:   "approval",
+		EntityID: a.ID,
+		Details:  map[string]any{"type": a.Type, "approver_role": a.ApproverRole},
+	})
+	return &a, nil
+}
+
+func (r *ApprovalRepository) GetByID(ctx context.Context, id int) (*models.Approval, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	a, ok := r.db.approvals[id]
+	if !ok {
+		return nil, fmt.Errorf("repo: approval %d not found: %w", id, models.ErrNotFound)
+	}
+	return &a, nil
+}
+
+func (r *ApprovalRepository) List(ctx context.Context, filter models.ApprovalFilter) ([]models.Approval, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	approvals := make([]models.Approval, 0)
+	for _, a := range r.db.approvals {
+		if (filter.State != "" && a.State != filter.State) ||
+			(filter.Type != "" && a.Type != filter.Type) ||
+			(filter.RequestedBy != 0 && a.RequestedBy != filter.RequestedBy) ||
+			(filter.ApproverRole != "" && a.ApproverRole != filter.ApproverRole) {
+			continue
+		}
+		approvals = append(approvals, a)
+	}
+	sort.Slice(approvals, func(i, j int) bool { return approvals[i].ID > approvals[j].ID })
+	return approvals, nil
+}
+
+func (r *ApprovalRepository) Transition(ctx context.Context, id int, from, to string, actorID int, comment string) (*models.Approval, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	a, ok := r.db.approvals[id]
+	if !ok {
+		return nil, fmt.Errorf("repo: approval %d not found: %w", id, models.ErrNotFound)
+	}
+	if a.State != from {
+		return nil, fmt.Errorf("repo: approval %d is %s, not %s: %w", id, a.State, from, models.ErrConflict)
+	}
+
+	now := r.db.now()
+	a.State, a.Comment, a.UpdatedAt = to, comment, now
+	if to == models.ApprovalPending {
+		a.DecidedBy, a.DecidedAt = nil, nil
+	} else {
+		a.DecidedBy, a.DecidedAt = &actorID, &now
+	}
+	r.db.approvals[id] = a
+
+	details := map[string]any{"from": from, "to": to}
+	if comment != "" {
+		details["comment"] = comment
+	}
+	r.db.appendAudit(ctx, models.AuditEntry{
+		ActorID:  &actorID,
+		Action:   models.ApprovalAudit(to),
+		Entity:   "approval",
+		EntityID: id,
+		Details:  details,
+	})
+	return &a, nil
+}
@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"sort"
+)
+
+// ArchiveRepository is the in-memory twin of repository.ArchiveRepository
+type ArchiveRepository struct {
+	db *DB
+}
+
+var _ repository.ArchiveStore = (*ArchiveRepository)(nil)
+
+// NewArchiveRepository is the constructor
+func NewArchiveRepository(db *DB) *ArchiveRepository {
+	return &ArchiveRepository{db: db}
+}
+
+func unfinished(a models.YearArchive) bool {
+	return a.Status == models.JobPending || a.Status == models.JobRunning
+}
+
+func (r *ArchiveRepository) Create(ctx context.Context, year string, createdBy *int) (*models.YearArchive, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, a := range r.db.archives {
+		if a.Year == year && unfinished(a) {
+			return nil, fmt.Errorf("repo: archive of %s already in progress: %w", year, models.ErrConflict)
+		}
+	}
+	a := models.YearArchive{
+		ID:        r.db.newID("academic_year_archives"),
+		Year:      year,
+		Status:    models.JobPending,
+		CreatedBy: createdBy,
+		CreatedAt: r.db.now(),
+	}
+	r.db.archives[a.ID] = a
+	return &a, nil
+}
+
+func (r *ArchiveRepository) GetByID(ctx context.Context, id int) (*models.YearArchive, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	a, ok := r.db.archives[id]
+	if !ok {
+		return nil, fmt.Errorf("repo: archive %d not found: %w", id, models.ErrNotFound)
+	}
+	return &a, nil
+}
+
+func (r *ArchiveRepository) List(ctx context.Context, year string) ([]models.YearArchive, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	archives := make([]models.YearArchive, 0)
+	for _, a := range r.db.archives {
+		if year == "" || a.Year == year {
+			archives = append(archives, a)
+		}
+	}
+	sort.Slice(archives, func(i, j int) bool { return archives[i].ID > archives[j].ID })
+	return archives, nil
+}
+
+func (r *ArchiveRepository) MarkRunning(ctx context.Context, id int) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if a, ok := r.db.archives[id]; ok && a.Status == models.JobPending {
+		a.Status = models.JobRunning
+		r.db.archives[id] = a
+	}
+	return nil
+}
+
+func (r *ArchiveRepository) Finish(ctx context.Context, id int, result models.YearArchive) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	a, ok := r.db.archives[id]
+	if !ok || !unfinished(a) {
+		return nil
+	}
+	now := r.db.now()
+	a.Status = result.Status
+	a.StorageKey = result.StorageKey
+	a.Size = result.Size
+	a.SHA256 = result.SHA256
+	a.Error = result.Error
+	a.CompletedAt = &now
+	r.db.archives[id] = a
+	return nil
+}
+
+func (r *ArchiveRepository) FailUnfinished(ctx context.Context, reason string) (int, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	n := 0
+	now := r.db.now()
+	for id, a := range r.db.archives {
+		if unfinished(a) {
+			a.Status = models.JobFailed
+			a.Error = reason
+			a.CompletedAt = &now
+			r.db.archives[id] = a
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,190 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"sort"
+	"time"
+)
+
+type attendanceKey struct {
+	studentID int
+	date      string
+}
+
+type attendanceRow struct {
+	class   string
+	mark    models.AttendanceMark
+	takenBy *int
+	takenAt time.Time
+}
+
+// AttendanceRepository is the in-memory twin of repository.AttendanceRepository
+type AttendanceRepository struct {
+	db *DB
+}
+
+var _ repository.AttendanceStore = (*AttendanceRepository)(nil)
+
+// NewAttendanceRepository is the constructor
+func NewAttendanceRepository(db *DB) *AttendanceRepository {
+	return &AttendanceRepository{db: db}
+}
+
+func (r *AttendanceRepository) SaveRegister(ctx context.Context, reg models.AttendanceRegister) (*models.AttendanceRegister, error) {
+	r.db.mu.Lock()
+	for _, m := range reg.Records {
+		if row, ok := r.db.attendance[attendanceKey{m.StudentID, reg.Date}]; ok && row.class != reg.Class {
+			r.db.mu.Unlock()
+			return nil, fmt.Errorf("repo: student %d already marked on %s in another class: %w", m.StudentID, reg.Date,
+				&models.ConflictError{Field: "student_id", Value: fmt.Sprint(m.StudentID)})
+		}
+	}
+	for key, row := range r.db.attendance {
+		if key.date == reg.Date && row.class == reg.Class {
+			delete(r.db.attendance, key)
+		}
+	}
+	now := r.db.now()
+	for _, m := range reg.Records {
+		r.db.attendance[attendanceKey{m.StudentID, reg.Date}] = attendanceRow{class: reg.Class, mark: m, takenBy: reg.TakenBy, takenAt: now}
+	}
+	r.db.mu.Unlock()
+
+	return r.GetRegister(ctx, reg.Class, reg.Date)
+}
+
+func (r *AttendanceRepository) GetRegister(ctx context.Context, class, date string) (*models.AttendanceRegister, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	reg := models.AttendanceRegister{Class: class, Date: date, Records: make([]models.AttendanceMark, 0)}
+	for key, row := range r.db.attendance {
+		if key.date != date || row.class != class {
+			continue
+		}
+		reg.Records = append(reg.Records, row.mark)
+		if row.takenAt.After(reg.TakenAt) {
+			reg.TakenAt, reg.TakenBy = row.takenAt, row.takenBy
+		}
+	}
+	if len(reg.Records) == 0 {
+		return nil, fmt.Errorf("repo: no register for %s on %s: %w", class, date, models.ErrNotFound)
+	}
+	sort.Slice(reg.Records, func(i, j int) bool { return reg.Records[i].StudentID < reg.Records[j].StudentID })
+	return &reg, nil
+}
+
+func (r *AttendanceRepository) SubmittedClasses(ctx context.Context, date string) ([]string, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	classes := make([]string, 0)
+	for key, row := range r.db.attendance {
+		if key.date == date && !seen[row.class] {
+			seen[row.class] = true
+			classes = append(classes, row.class)
+		}
+	}
+	sort.Strings(classes)
+	return classes, nil
+}
+
+func (r *AttendanceRepository) ListAbsences(ctx context.Context, from, to string) ([]models.Absence, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	absences := make([]models.Absence, 0)
+	for key, row := range r.db.attendance {
+		if row.mark.Status == models.AttendanceAbsent && key.date >= from && key.date <= to {
+			absences = append(absences, models.Absence{StudentID: key.studentID, Date: key.date})
+		}
+	}
+	sort.Slice(absences, func(i, j int) bool {
+		if absences[i].StudentID != absences[j].StudentID {
+			return absences[i].StudentID < absences[j].StudentID
+		}
+		return absences[i].Date < absences[j].Date
+	})
+	return absences, nil
+}
+
+func (r *AttendanceRepository) TallyByStudent(ctx context.Context, from, to string) (map[int]models.AttendanceTally, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	tallies := make(map[int]models.AttendanceTally)
+	for key, row := range r.db.attendance {
+		if key.date >= from && key.date <= to {
+			t := tallies[key.studentID]
+			t.Add(row.mark.Status, 1)
+			tallies[key.studentID] = t
+		}
+	}
+	return tallies, nil
+}
+
+func (r *AttendanceRepository) RecordMovement(ctx context.Context, m models.AttendanceMovement) (*models.AttendanceMovement, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, other := range r.db.movements {
+		if other.StudentID == m.StudentID && other.Date == m.Date && other.Kind == m.Kind {
+			return nil, fmt.Errorf("repo: student %d already has a %s on %s: %w", m.StudentID, m.Kind, m.Date, models.ErrConflict)
+		}
+	}
+	m.ID = r.db.newID("attendance_movements")
+	r.db.movements[m.ID] = m
+
+	key := attendanceKey{m.StudentID, m.Date}
+	if row, ok := r.db.attendance[key]; ok && m.Kind == models.MovementLateArrival && row.mark.Status == models.AttendanceAbsent {
+		row.mark.Status = models.AttendanceLate
+		r.db.attendance[key] = row
+	}
+	return &m, nil
+}
+
+func (r *AttendanceRepository) ListMovements(ctx context.Context, date string) ([]models.AttendanceMovement, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	movements := make([]models.AttendanceMovement, 0)
+	for _, m := range r.db.movements {
+		if m.Date == date {
+			movements = append(movements, m)
+		}
+	}
+	sort.Slice(movements, func(i, j int) bool {
+		if !movements[i].At.Equal(movements[j].At) {
+			return movements[i].At.Before(movements[j].At)
+		}
+		return movements[i].ID < movements[j].ID
+	})
+	return movements, nil
+}
+
+func (r *AttendanceRepository) Alerts(ctx context.Context) (map[int]models.AttendanceAlert, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	alerts := make(map[int]models.AttendanceAlert, len(r.db.alerts))
+	for id, a := range r.db.alerts {
+		alerts[id] = a
+	}
+	return alerts, nil
+}
+
+func (r *AttendanceRepository) SetAlert(ctx context.Context, a models.AttendanceAlert) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if a.Level == models.AttendanceAlertNone {
+		delete(r.db.alerts, a.StudentID)
+		return nil
+	}
+	r.db.alerts[a.StudentID] = a
+	return nil
+}
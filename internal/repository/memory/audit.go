@@ -0,0 +1,62 @@
+package memory
+
+import (
+	"context"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"time"
+)
+
+// AuditRepository is the in-memory twin of repository.AuditRepository
+type AuditRepository struct {
+	db *DB
+}
+
+var _ repository.AuditStore = (*AuditRepository)(nil)
+
+// NewAuditRepository is the constructor
+func NewAuditRepository(db *DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// ListByEntity returns an entity's audit entries, oldest first (append order)
+func (r *AuditRepository) ListByEntity(ctx context.Context, entity string, id int) ([]models.AuditEntry, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	entries := make([]models.AuditEntry, 0)
+	for _, e := range r.db.audit {
+		if e.Entity == entity && e.EntityID == id {
+			entries = append(entries, e)
+		}
+	}
+	return entries, nil
+}
+
+// Record writes an entry that isn't part of a change, with its outbox event
+func (r *AuditRepository) Record(ctx context.Context, entry models.AuditEntry) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+	r.db.appendAudit(ctx, entry)
+	return nil
+}
+
+// Stream calls fn with every entry written since, oldest first. fn runs on a
+// copy, so it may take its time without holding the lock.
+func (r *AuditRepository) Stream(ctx context.Context, since time.Time, fn func(models.AuditEntry) error) error {
+	r.db.mu.RLock()
+	entries := make([]models.AuditEntry, 0)
+	for _, e := range r.db.audit {
+		if !e.CreatedAt.Before(since) {
+			entries = append(entries, e)
+		}
+	}
+	r.db.mu.RUnlock()
+
+	for _, e := range entries {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
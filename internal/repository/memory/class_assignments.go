@@ -0,0 +1,43 @@
+package memory
+
+import (
+	"context"
+	"simpleapi/internal/repository"
+	"slices"
+)
+
+// ClassAssignmentRepository is the in-memory twin of repository.ClassAssignmentRepository
+type ClassAssignmentRepository struct {
+	db *DB
+}
+
+var _ repository.ClassAssignmentStore = (*ClassAssignmentRepository)(nil)
+
+// NewClassAssignmentRepository is the constructor
+func NewClassAssignmentRepository(db *DB) *ClassAssignmentRepository {
+	return &ClassAssignmentRepository{db: db}
+}
+
+func (r *ClassAssignmentRepository) ClassesOf(ctx context.Context, teacherID int) ([]string, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	classes := slices.Clone(r.db.teacherClasses[teacherID])
+	if classes == nil {
+		classes = []string{}
+	}
+	return classes, nil
+}
+
+func (r *ClassAssignmentRepository) SetClasses(ctx context.Context, teacherID int, classes []string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	sorted := slices.Compact(slices.Sorted(slices.Values(classes)))
+	if len(sorted) == 0 {
+		delete(r.db.teacherClasses, teacherID)
+		return nil
+	}
+	r.db.teacherClasses[teacherID] = sorted
+	return nil
+}
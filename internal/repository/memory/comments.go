@@ -0,0 +1,82 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"sort"
+)
+
+// CommentRepository is the in-memory twin of repository.CommentRepository
+type CommentRepository struct {
+	db *DB
+}
+
+var _ repository.CommentStore = (*CommentRepository)(nil)
+
+// NewCommentRepository is the constructor
+func NewCommentRepository(db *DB) *CommentRepository {
+	return &CommentRepository{db: db}
+}
+
+func (r *CommentRepository) Create(ctx context.Context, c models.StudentComment) (*models.StudentComment, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	// Same unique key as MySQL: (student_id, term, kind, subject)
+	for _, existing := range r.db.comments {
+		if existing.StudentID == c.StudentID && existing.Term == c.Term &&
+			existing.Kind == c.Kind && existing.Subject == c.Subject {
+			return nil, fmt.Errorf("repo: failed to insert comment: %w", &models.ConflictError{Field: "term", Value: c.Term})
+		}
+	}
+
+	c.ID = r.db.newID("student_comments")
+	c.Flagged = false
+	c.FlagReason = ""
+	c.CreatedAt = r.db.now()
+	r.db.comments[c.ID] = c
+	return &c, nil
+}
+
+func (r *CommentRepository) ListByStudent(ctx context.Context, studentID int, term string, includeFlagged bool) ([]models.StudentComment, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	comments := make([]models.StudentComment, 0)
+	for _, c := range r.db.comments {
+		if c.StudentID != studentID || (term != "" && c.Term != term) || (c.Flagged && !includeFlagged) {
+			continue
+		}
+		comments = append(comments, c)
+	}
+	sort.Slice(comments, func(i, j int) bool {
+		a, b := comments[i], comments[j]
+		if a.Term != b.Term {
+			return a.Term < b.Term
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Subject < b.Subject
+	})
+	return comments, nil
+}
+
+func (r *CommentRepository) SetFlag(ctx context.Context, studentID, id int, mod models.CommentModeration) (*models.StudentComment, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	c, ok := r.db.comments[id]
+	if !ok || c.StudentID != studentID {
+		return nil, fmt.Errorf("repo: comment %d not found for student %d: %w", id, studentID, models.ErrNotFound)
+	}
+	c.Flagged = mod.Flagged
+	c.FlagReason = ""
+	if mod.Flagged {
+		c.FlagReason = mod.Reason
+	}
+	r.db.comments[id] = c
+	return &c, nil
+}
@@ -0,0 +1,166 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"sort"
+)
+
+// CampaignRepository is the in-memory twin of repository.CampaignRepository
+type CampaignRepository struct {
+	db *DB
+}
+
+var _ repository.CampaignStore = (*CampaignRepository)(nil)
+
+// NewCampaignRepository is the constructor
+func NewCampaignRepository(db *DB) *CampaignRepository {
+	return &CampaignRepository{db: db}
+}
+
+func (r *CampaignRepository) Create(ctx context.Context, c models.Campaign, recipients []models.CampaignRecipient) (*models.Campaign, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	c.ID = r.db.newID("communication_campaigns")
+	c.Status, c.Error, c.CompletedAt = models.JobPending, "", nil
+	c.CreatedAt = r.db.now()
+	r.db.campaigns[c.ID] = c
+	for _, rc := range recipients {
+		rc.ID = r.db.newID("communication_recipients")
+		rc.CampaignID = c.ID
+		rc.Status, rc.Error, rc.SentAt = models.RecipientPending, "", nil
+		r.db.recipients[rc.ID] = rc
+	}
+	r.db.appendAudit(ctx, models.AuditEntry{
+		ActorID:  &c.CreatedBy,
+		Action:   models.AuditCampaignCreated,
+		Entity:   "campaign",
+		EntityID: c.ID,
+		Details: map[string]any{
+			"subject":    c.Subject,
+			"audience":   c.Recipients.Audience,
+			"class":      c.Recipients.Class,
+			"role":       c.Recipients.Role,
+			"recipients": len(recipients),
+		},
+	})
+	return r.tally(c), nil
+}
+
+// tally fills in c's counts from its recipients. Caller must hold the lock.
+func (r *CampaignRepository) tally(c models.Campaign) *models.Campaign {
+	c.Total, c.Sent, c.Failed = 0, 0, 0
+	for _, rc := range r.db.recipients {
+		if rc.CampaignID != c.ID {
+			continue
+		}
+		c.Total++
+		switch rc.Status {
+		case models.RecipientSent:
+			c.Sent++
+		case models.RecipientFailed:
+			c.Failed++
+		}
+	}
+	return &c
+}
+
+func (r *CampaignRepository) GetByID(ctx context.Context, id int) (*models.Campaign, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	c, ok := r.db.campaigns[id]
+	if !ok {
+		return nil, fmt.Errorf("repo: campaign %d not found: %w", id, models.ErrNotFound)
+	}
+	return r.tally(c), nil
+}
+
+func (r *CampaignRepository) List(ctx context.Context) ([]models.Campaign, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	campaigns := make([]models.Campaign, 0, len(r.db.campaigns))
+	for _, c := range r.db.campaigns {
+		campaigns = append(campaigns, *r.tally(c))
+	}
+	sort.Slice(campaigns, func(i, j int) bool { return campaigns[i].ID > campaigns[j].ID })
+	return campaigns, nil
+}
+
+func (r *CampaignRepository) Recipients(ctx context.Context, campaignID int) ([]models.CampaignRecipient, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	recipients := make([]models.CampaignRecipient, 0)
+	for _, rc := range r.db.recipients {
+		if rc.CampaignID == campaignID {
+			recipients = append(recipients, rc)
+		}
+	}
+	sort.Slice(recipients, func(i, j int) bool { return recipients[i].ID < recipients[j].ID })
+	return recipients, nil
+}
+
+func (r *CampaignRepository) MarkRunning(ctx context.Context, id int) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if c, ok := r.db.campaigns[id]; ok && c.Status == models.JobPending {
+		c.Status = models.JobRunning
+		r.db.campaigns[id] = c
+	}
+	return nil
+}
+
+func (r *CampaignRepository) RecordDelivery(ctx context.Context, recipientID int, sendErr string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	rc, ok := r.db.recipients[recipientID]
+	if !ok || rc.Status != models.RecipientPending {
+		return nil
+	}
+	if sendErr == "" {
+		now := r.db.now()
+		rc.Status, rc.SentAt = models.RecipientSent, &now
+	} else {
+		rc.Status, rc.Error = models.RecipientFailed, sendErr
+	}
+	r.db.recipients[recipientID] = rc
+	return nil
+}
+
+func (r *CampaignRepository) Finish(ctx context.Context, id int, status, errText string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	c, ok := r.db.campaigns[id]
+	if !ok || (c.Status != models.JobPending && c.Status != models.JobRunning) {
+		return nil
+	}
+	now := r.db.now()
+	c.Status, c.Error, c.CompletedAt = status, errText, &now
+	r.db.campaigns[id] = c
+	return nil
+}
+
+func (r *CampaignRepository) FailUnfinished(ctx context.Context, reason string) (int, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	n := 0
+	now := r.db.now()
+	for id, c := range r.db.campaigns {
+		if c.Status != models.JobPending && c.Status != models.JobRunning {
+			continue
+		}
+		c.Status, c.Error, c.CompletedAt = models.JobFailed, reason, &now
+		r.db.campaigns[id] = c
+		n++
+	}
+	return n, nil
+}
@@ -0,0 +1,89 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"slices"
+	"sort"
+)
+
+// CustomFieldRepository is the in-memory twin of repository.CustomFieldRepository
+type CustomFieldRepository struct {
+	db *DB
+}
+
+var _ repository.CustomFieldStore = (*CustomFieldRepository)(nil)
+
+// NewCustomFieldRepository is the constructor
+func NewCustomFieldRepository(db *DB) *CustomFieldRepository {
+	return &CustomFieldRepository{db: db}
+}
+
+func (r *CustomFieldRepository) List(ctx context.Context, entity string) ([]models.CustomField, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	fields := make([]models.CustomField, 0)
+	for _, f := range r.db.customFields {
+		if f.Entity == entity {
+			f.Options = slices.Clone(f.Options)
+			fields = append(fields, f)
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].ID < fields[j].ID })
+	return fields, nil
+}
+
+func (r *CustomFieldRepository) Create(ctx context.Context, f models.CustomField, actorID *int) (*models.CustomField, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for _, existing := range r.db.customFields {
+		if existing.Entity == f.Entity && existing.Key == f.Key {
+			return nil, fmt.Errorf("repo: custom field %s.%s exists: %w", f.Entity, f.Key, &models.ConflictError{Field: "key", Value: f.Key})
+		}
+	}
+	f.ID = r.db.newID("custom_fields")
+	f.Options = slices.Clone(f.Options)
+	f.CreatedAt = r.db.now()
+	r.db.customFields[f.ID] = f
+	r.db.appendAudit(ctx, models.AuditEntry{
+		ActorID:  actorID,
+		Action:   models.AuditCustomFieldCreated,
+		Entity:   "custom_field",
+		EntityID: f.ID,
+		Details:  map[string]any{"entity": f.Entity, "key": f.Key, "type": f.Type},
+	})
+	return &f, nil
+}
+
+func (r *CustomFieldRepository) Delete(ctx context.Context, id int, actorID *int) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	f, ok := r.db.customFields[id]
+	if !ok {
+		return fmt.Errorf("repo: custom field %d not found: %w", id, models.ErrNotFound)
+	}
+	cleared := 0
+	if f.Entity == models.CustomFieldStudent {
+		for sid, s := range r.db.students {
+			if _, ok := s.CustomFields[f.Key]; ok {
+				s.CustomFields = models.MergeCustomValues(s.CustomFields, map[string]any{f.Key: nil})
+				r.db.students[sid] = s
+				cleared++
+			}
+		}
+	}
+	delete(r.db.customFields, id)
+	r.db.appendAudit(ctx, models.AuditEntry{
+		ActorID:  actorID,
+		Action:   models.AuditCustomFieldDeleted,
+		Entity:   "custom_field",
+		EntityID: id,
+		Details:  map[string]any{"entity": f.Entity, "key": f.Key, "values_removed": cleared},
+	})
+	return nil
+}
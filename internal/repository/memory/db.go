@@ -0,0 +1,132 @@
+// Package memory backs the repository stores with thread-safe maps.
+// It is selected with DB_DRIVER=memory for demos, tutorials and frontend work,
+// and loses all data when the process exits.
+package memory
+
+import (
+	"context"
+	"simpleapi/internal/models"
+	"simpleapi/pkg/clock"
+	"simpleapi/pkg/utils"
+	"sync"
+	"time"
+)
+
+// DB is the shared in-memory "database". Teacher and student repositories share
+// one instance so joins (a teacher's students) keep working.
+type DB struct {
+	mu sync.RWMutex
+	// work serializes units of work (see UnitOfWork)
+	work     sync.Mutex
+	teachers map[int]models.Teacher
+	// deletedTeachers is the trash: soft-deleted teachers, kept out of every other read
+	deletedTeachers map[int]models.Teacher
+	students        map[int]models.Student
+	comments        map[int]models.StudentComment
+	schemes         map[int]models.GradingScheme
+	scores          map[int]models.StudentScore
+	corrections     map[int]models.GradeCorrection
+	messages        map[int]models.SMSMessage
+	events          map[int]models.Event
+	archives        map[int]models.YearArchive
+	promotions      map[int]models.PromotionReport
+	rollovers       map[int]models.RolloverPlan
+	exams           map[int]models.ExamSession
+	threads         map[int]models.Thread
+	threadMessages  map[int]models.ThreadMessage
+	// threadReads is the last message each staff member has read, per thread
+	threadReads map[threadReadKey]int
+	uploads     map[int]models.Upload
+	// teacherClasses is teacher_classes: each teacher's assigned classes, sorted
+	teacherClasses map[int][]string
+	emailChanges   map[int]models.EmailChange
+	approvals      map[int]models.Approval
+	campaigns      map[int]models.Campaign
+	// recipients are the campaigns' recipients (communication_recipients)
+	recipients map[int]models.CampaignRecipient
+	// attendance is keyed like the MySQL unique key: student, then date
+	attendance map[attendanceKey]attendanceRow
+	movements  map[int]models.AttendanceMovement
+	// alerts is attendance_alerts, by student
+	alerts map[int]models.AttendanceAlert
+	// medical is student_medical, by student
+	medical map[int]models.MedicalRecord
+	trips   map[int]models.Trip
+	// consents is trip_consents, by trip and student
+	consents map[consentKey]models.TripConsent
+	letters  map[int]models.LetterTemplate
+	docs     map[int]models.Document
+	// customFields are the definitions; values live in the entities' CustomFields
+	customFields map[int]models.CustomField
+	audit        []models.AuditEntry
+	outbox       []outboxRow
+	school       *models.School // nil until first saved
+	reference    models.ReferenceData
+	nextID       map[string]int
+	clock        clock.Clock
+}
+
+// NewDB creates an empty in-memory database. clk stands in for MySQL's NOW()
+// (see now).
+func NewDB(clk clock.Clock) *DB {
+	return &DB{
+		clock:           clk,
+		teachers:        make(map[int]models.Teacher),
+		deletedTeachers: make(map[int]models.Teacher),
+		students:        make(map[int]models.Student),
+		comments:        make(map[int]models.StudentComment),
+		schemes:         make(map[int]models.GradingScheme),
+		scores:          make(map[int]models.StudentScore),
+		corrections:     make(map[int]models.GradeCorrection),
+		messages:        make(map[int]models.SMSMessage),
+		events:          make(map[int]models.Event),
+		archives:        make(map[int]models.YearArchive),
+		promotions:      make(map[int]models.PromotionReport),
+		rollovers:       make(map[int]models.RolloverPlan),
+		exams:           make(map[int]models.ExamSession),
+		threads:         make(map[int]models.Thread),
+		threadMessages:  make(map[int]models.ThreadMessage),
+		threadReads:     make(map[threadReadKey]int),
+		uploads:         make(map[int]models.Upload),
+		teacherClasses:  make(map[int][]string),
+		emailChanges:    make(map[int]models.EmailChange),
+		approvals:       make(map[int]models.Approval),
+		campaigns:       make(map[int]models.Campaign),
+		recipients:      make(map[int]models.CampaignRecipient),
+		attendance:      make(map[attendanceKey]attendanceRow),
+		movements:       make(map[int]models.AttendanceMovement),
+		alerts:          make(map[int]models.AttendanceAlert),
+		medical:         make(map[int]models.MedicalRecord),
+		trips:           make(map[int]models.Trip),
+		consents:        make(map[consentKey]models.TripConsent),
+		letters:         make(map[int]models.LetterTemplate),
+		docs:            make(map[int]models.Document),
+		customFields:    make(map[int]models.CustomField),
+		nextID:          make(map[string]int),
+	}
+}
+
+// now is the time stored on rows, in UTC like the MySQL session's
+func (db *DB) now() time.Time {
+	return db.clock.Now().UTC()
+}
+
+// appendAudit records an audit entry and its outbox event. Caller must hold the write lock.
+func (db *DB) appendAudit(ctx context.Context, entry models.AuditEntry) {
+	if entry.IPAddress == "" {
+		entry.IPAddress = utils.ClientIPFromContext(ctx)
+	}
+	entry.ID = db.newID("audit_log")
+	entry.CreatedAt = db.now()
+	db.audit = append(db.audit, entry)
+
+	event := models.OutboxEventFor(entry)
+	event.ID = db.newID("outbox")
+	db.outbox = append(db.outbox, outboxRow{event: event, nextAttempt: entry.CreatedAt})
+}
+
+// newID mimics AUTO_INCREMENT per table. Caller must hold the write lock.
+func (db *DB) newID(table string) int {
+	db.nextID[table]++
+	return db.nextID[table]
+}
@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"sort"
+	"time"
+)
+
+// DocumentRepository is the in-memory twin of repository.DocumentRepository
+type DocumentRepository struct {
+	db *DB
+}
+
+var _ repository.DocumentStore = (*DocumentRepository)(nil)
+
+// NewDocumentRepository is the constructor
+func NewDocumentRepository(db *DB) *DocumentRepository {
+	return &DocumentRepository{db: db}
+}
+
+// cloneDocument copies the pointers so callers can't edit the stored document
+func cloneDocument(d models.Document) *models.Document {
+	if d.TemplateID != nil {
+		id := *d.TemplateID
+		d.TemplateID = &id
+	}
+	if d.EmailedAt != nil {
+		at := *d.EmailedAt
+		d.EmailedAt = &at
+	}
+	return &d
+}
+
+func (r *DocumentRepository) Create(ctx context.Context, d models.Document) (*models.Document, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.students[d.StudentID]; !ok {
+		return nil, fmt.Errorf("repo: student %d not found: %w", d.StudentID, models.ErrNotFound)
+	}
+	d.ID = r.db.newID("documents")
+	d.CreatedAt = r.db.now()
+	d.EmailedTo, d.EmailedAt = "", nil
+	r.db.docs[d.ID] = *cloneDocument(d)
+	r.db.appendAudit(ctx, models.AuditEntry{
+		ActorID:  d.CreatedBy,
+		Action:   models.AuditDocumentCreated,
+		Entity:   "document",
+		EntityID: d.ID,
+		Details:  map[string]any{"student_id": d.StudentID, "template_id": d.TemplateID, "title": d.Title, "format": d.Format},
+	})
+	return cloneDocument(d), nil
+}
+
+func (r *DocumentRepository) GetByID(ctx context.Context, id int) (*models.Document, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	d, ok := r.db.docs[id]
+	if !ok {
+		return nil, fmt.Errorf("repo: document %d not found: %w", id, models.ErrNotFound)
+	}
+	return cloneDocument(d), nil
+}
+
+func (r *DocumentRepository) ListByStudent(ctx context.Context, studentID int) ([]models.Document, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	documents := make([]models.Document, 0)
+	for _, d := range r.db.docs {
+		if d.StudentID == studentID {
+			documents = append(documents, *cloneDocument(d))
+		}
+	}
+	sort.Slice(documents, func(i, j int) bool {
+		if !documents[i].CreatedAt.Equal(documents[j].CreatedAt) {
+			return documents[i].CreatedAt.After(documents[j].CreatedAt)
+		}
+		return documents[i].ID > documents[j].ID
+	})
+	return documents, nil
+}
+
+func (r *DocumentRepository) MarkEmailed(ctx context.Context, id int, address string, at time.Time) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	d, ok := r.db.docs[id]
+	if !ok {
+		// The UPDATE of the MySQL store doesn't fail on a missing row either
+		return nil
+	}
+	at = at.UTC()
+	d.EmailedTo, d.EmailedAt = address, &at
+	r.db.docs[id] = d
+	return nil
+}
@@ -0,0 +1,150 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"time"
+)
+
+// EmailChangeRepository is the in-memory twin of repository.EmailChangeRepository
+type EmailChangeRepository struct {
+	db *DB
+}
+
+var _ repository.EmailChangeStore = (*EmailChangeRepository)(nil)
+
+// NewEmailChangeRepository is the constructor
+func NewEmailChangeRepository(db *DB) *EmailChangeRepository {
+	return &EmailChangeRepository{db: db}
+}
+
+func (r *EmailChangeRepository) Create(ctx context.Context, c models.EmailChange) (*models.EmailChange, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	t, ok := r.db.teachers[c.TeacherID]
+	if !ok {
+		return nil, fmt.Errorf("repo: teacher %d not found: %w", c.TeacherID, models.ErrTeacherNotFound)
+	}
+	c.OldEmail = t.Email
+	if c.NewEmail == c.OldEmail {
+		return nil, fmt.Errorf("repo: %s is already the teacher's email: %w", c.NewEmail, models.ErrInvalidInput)
+	}
+	if r.emailTaken(c.NewEmail) {
+		return nil, fmt.Errorf("repo: failed to request email change: %w", &models.ConflictError{Field: "email", Value: c.NewEmail})
+	}
+
+	for id, pending := range r.db.emailChanges {
+		if pending.TeacherID == c.TeacherID && pending.Status == models.EmailChangePending {
+			pending.Status = models.EmailChangeCancelled
+			r.db.emailChanges[id] = pending
+		}
+	}
+
+	c.ID = r.db.newID("email_changes")
+	c.Status = models.EmailChangePending
+	r.db.emailChanges[c.ID] = c
+	r.db.appendAudit(ctx, models.AuditEntry{
+		ActorID:  c.RequestedBy,
+		Action:   models.AuditEmailChangeRequested,
+		Entity:   "teacher",
+		EntityID: c.TeacherID,
+		Details:  map[string]any{"email_change_id": c.ID, "old_email": c.OldEmail, "new_email": c.NewEmail},
+	})
+	return &c, nil
+}
+
+func (r *EmailChangeRepository) Confirm(ctx context.Context, tokenHash string, now time.Time) (*models.EmailChange, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	c, ok := r.find(func(c models.EmailChange) bool { return c.ConfirmTokenHash == tokenHash })
+	if !ok {
+		return nil, fmt.Errorf("repo: no email change for this token: %w", models.ErrEmailChangeLinkInvalid)
+	}
+	if c.Status != models.EmailChangePending || !now.Before(c.ExpiresAt) {
+		return nil, fmt.Errorf("repo: email change %d can't be confirmed: %w", c.ID, models.ErrEmailChangeLinkInvalid)
+	}
+	if err := r.setEmail(c.TeacherID, c.OldEmail, c.NewEmail); err != nil {
+		return nil, err
+	}
+
+	c.Status, c.ConfirmedAt = models.EmailChangeConfirmed, &now
+	r.db.emailChanges[c.ID] = c
+	r.db.appendAudit(ctx, models.AuditEntry{
+		Action:   models.AuditEmailChanged,
+		Entity:   "teacher",
+		EntityID: c.TeacherID,
+		Details: map[string]any{
+			"email_change_id": c.ID,
+			"changes":         map[string]models.FieldChange{"email": {From: c.OldEmail, To: c.NewEmail}},
+		},
+	})
+	return &c, nil
+}
+
+func (r *EmailChangeRepository) Undo(ctx context.Context, tokenHash string, now time.Time) (*models.EmailChange, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	c, ok := r.find(func(c models.EmailChange) bool { return c.UndoTokenHash == tokenHash })
+	if !ok {
+		return nil, fmt.Errorf("repo: no email change for this token: %w", models.ErrEmailChangeLinkInvalid)
+	}
+	wasConfirmed := c.Status == models.EmailChangeConfirmed
+	if (c.Status != models.EmailChangePending && !wasConfirmed) || !now.Before(c.UndoExpiresAt) {
+		return nil, fmt.Errorf("repo: email change %d can't be undone: %w", c.ID, models.ErrEmailChangeLinkInvalid)
+	}
+
+	details := map[string]any{"email_change_id": c.ID}
+	c.Status, c.UndoneAt = models.EmailChangeCancelled, &now
+	if wasConfirmed {
+		if err := r.setEmail(c.TeacherID, c.NewEmail, c.OldEmail); err != nil {
+			return nil, err
+		}
+		c.Status = models.EmailChangeUndone
+		details["changes"] = map[string]models.FieldChange{"email": {From: c.NewEmail, To: c.OldEmail}}
+	}
+	r.db.emailChanges[c.ID] = c
+	r.db.appendAudit(ctx, models.AuditEntry{
+		Action:   models.AuditEmailChangeUndone,
+		Entity:   "teacher",
+		EntityID: c.TeacherID,
+		Details:  details,
+	})
+	return &c, nil
+}
+
+// find returns the change matching a token. Caller must hold the lock.
+func (r *EmailChangeRepository) find(match func(models.EmailChange) bool) (models.EmailChange, bool) {
+	for _, c := range r.db.emailChanges {
+		if match(c) {
+			return c, true
+		}
+	}
+	return models.EmailChange{}, false
+}
+
+// setEmail mirrors the SQL setEmailTx: only a teacher still on from moves to to.
+// Caller must hold the write lock.
+func (r *EmailChangeRepository) setEmail(teacherID int, from, to string) error {
+	t, ok := r.db.teachers[teacherID]
+	if !ok || t.Email != from {
+		return fmt.Errorf("repo: teacher %d no longer has email %s: %w", teacherID, from, models.ErrEmailChangeLinkInvalid)
+	}
+	if r.emailTaken(to) {
+		return fmt.Errorf("repo: failed to change email of teacher %d: %w", teacherID, &models.ConflictError{Field: "email", Value: to})
+	}
+	t.Email = to
+	t.UpdatedAt = r.db.now()
+	r.db.teachers[teacherID] = t
+	return nil
+}
+
+// emailTaken is TeacherRepository.emailTaken without an exception: the teacher
+// changing their email never holds the new one. Caller must hold the lock.
+func (r *EmailChangeRepository) emailTaken(email string) bool {
+	return (&TeacherRepository{db: r.db}).emailTaken(email, 0)
+}
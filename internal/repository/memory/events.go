@@ -0,0 +1,97 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"sort"
+)
+
+// EventRepository is the in-memory twin of repository.EventRepository
+type EventRepository struct {
+	db *DB
+}
+
+var _ repository.EventStore = (*EventRepository)(nil)
+
+// NewEventRepository is the constructor
+func NewEventRepository(db *DB) *EventRepository {
+	return &EventRepository{db: db}
+}
+
+func (r *EventRepository) List(ctx context.Context, filter models.EventFilter) ([]models.Event, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	events := make([]models.Event, 0)
+	for _, e := range r.db.events {
+		// Dates are YYYY-MM-DD, so string comparison is date comparison
+		if (filter.From != "" && e.EndDate < filter.From) || (filter.To != "" && e.StartDate > filter.To) {
+			continue
+		}
+		if filter.Type != "" && e.Type != filter.Type {
+			continue
+		}
+		if filter.Class != "" && e.Audience != models.AudienceSchool && e.Class != filter.Class {
+			continue
+		}
+		events = append(events, e)
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].StartDate != events[j].StartDate {
+			return events[i].StartDate < events[j].StartDate
+		}
+		return events[i].ID < events[j].ID
+	})
+	return events, nil
+}
+
+func (r *EventRepository) GetByID(ctx context.Context, id int) (*models.Event, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	e, ok := r.db.events[id]
+	if !ok {
+		return nil, fmt.Errorf("repo: event %d not found: %w", id, models.ErrNotFound)
+	}
+	return &e, nil
+}
+
+func (r *EventRepository) Create(ctx context.Context, e models.Event) (*models.Event, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	e.ID = r.db.newID("events")
+	e.CreatedAt = r.db.now()
+	e.UpdatedAt = e.CreatedAt
+	r.db.events[e.ID] = e
+	return &e, nil
+}
+
+func (r *EventRepository) Update(ctx context.Context, id int, e models.Event) (*models.Event, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	existing, ok := r.db.events[id]
+	if !ok {
+		return nil, fmt.Errorf("repo: event %d not found: %w", id, models.ErrNotFound)
+	}
+	e.ID = id
+	e.CreatedBy = existing.CreatedBy
+	e.CreatedAt = existing.CreatedAt
+	e.UpdatedAt = r.db.now()
+	r.db.events[id] = e
+	return &e, nil
+}
+
+func (r *EventRepository) Delete(ctx context.Context, id int) (bool, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.events[id]; !ok {
+		return false, nil
+	}
+	delete(r.db.events, id)
+	return true, nil
+}
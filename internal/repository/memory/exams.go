@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"slices"
+	"sort"
+)
+
+// ExamRepository is the in-memory twin of repository.ExamRepository
+type ExamRepository struct {
+	db *DB
+}
+
+var _ repository.ExamStore = (*ExamRepository)(nil)
+
+// NewExamRepository is the constructor
+func NewExamRepository(db *DB) *ExamRepository {
+	return &ExamRepository{db: db}
+}
+
+// cloneExam copies the session's lists so callers can't edit the stored session
+func cloneExam(s models.ExamSession) *models.ExamSession {
+	s.Classes = slices.Clone(s.Classes)
+	s.Rooms = slices.Clone(s.Rooms)
+	s.Seats = slices.Clone(s.Seats)
+	s.Invigilators = slices.Clone(s.Invigilators)
+	if s.Seats == nil {
+		s.Seats = []models.ExamSeat{}
+	}
+	if s.Invigilators == nil {
+		s.Invigilators = []models.ExamInvigilator{}
+	}
+	if s.Seating != nil {
+		seating := *s.Seating
+		s.Seating = &seating
+	}
+	return &s
+}
+
+func (r *ExamRepository) Create(ctx context.Context, s models.ExamSession) (*models.ExamSession, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	s.ID = r.db.newID("exam_sessions")
+	s.CreatedAt = r.db.now()
+	s.UpdatedAt = s.CreatedAt
+	r.db.exams[s.ID] = *cloneExam(s)
+	return cloneExam(s), nil
+}
+
+func (r *ExamRepository) GetByID(ctx context.Context, id int) (*models.ExamSession, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	s, ok := r.db.exams[id]
+	if !ok {
+		return nil, fmt.Errorf("repo: exam session %d not found: %w", id, models.ErrNotFound)
+	}
+	return cloneExam(s), nil
+}
+
+func (r *ExamRepository) List(ctx context.Context, date string) ([]models.ExamSession, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	sessions := make([]models.ExamSession, 0)
+	for _, s := range r.db.exams {
+		if date == "" || s.Date == date {
+			sessions = append(sessions, *cloneExam(s))
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		a, b := sessions[i], sessions[j]
+		if a.Date != b.Date {
+			return a.Date < b.Date
+		}
+		if a.StartTime != b.StartTime {
+			return a.StartTime < b.StartTime
+		}
+		return a.ID < b.ID
+	})
+	return sessions, nil
+}
+
+func (r *ExamRepository) Save(ctx context.Context, s models.ExamSession) (*models.ExamSession, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	stored, ok := r.db.exams[s.ID]
+	if !ok {
+		return nil, fmt.Errorf("repo: exam session %d not found: %w", s.ID, models.ErrNotFound)
+	}
+	s.CreatedBy, s.CreatedAt = stored.CreatedBy, stored.CreatedAt
+	s.UpdatedAt = r.db.now()
+	r.db.exams[s.ID] = *cloneExam(s)
+	return cloneExam(s), nil
+}
+
+func (r *ExamRepository) Delete(ctx context.Context, id int) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.exams[id]; !ok {
+		return fmt.Errorf("repo: exam session %d not found: %w", id, models.ErrNotFound)
+	}
+	delete(r.db.exams, id)
+	return nil
+}
@@ -0,0 +1,163 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"sort"
+)
+
+// GradingRepository is the in-memory twin of repository.GradingRepository
+type GradingRepository struct {
+	db *DB
+}
+
+var _ repository.GradingStore = (*GradingRepository)(nil)
+
+// NewGradingRepository is the constructor
+func NewGradingRepository(db *DB) *GradingRepository {
+	return &GradingRepository{db: db}
+}
+
+func (r *GradingRepository) List(ctx context.Context) ([]models.GradingScheme, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	schemes := make([]models.GradingScheme, 0, len(r.db.schemes))
+	for _, s := range r.db.schemes {
+		schemes = append(schemes, copyScheme(s))
+	}
+	sort.Slice(schemes, func(i, j int) bool { return schemes[i].Subject < schemes[j].Subject })
+	return schemes, nil
+}
+
+func (r *GradingRepository) GetByID(ctx context.Context, id int) (*models.GradingScheme, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	s, ok := r.db.schemes[id]
+	if !ok {
+		return nil, fmt.Errorf("repo: grading scheme %d not found: %w", id, models.ErrNotFound)
+	}
+	s = copyScheme(s)
+	return &s, nil
+}
+
+func (r *GradingRepository) ForSubject(ctx context.Context, subject string) (*models.GradingScheme, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var fallback *models.GradingScheme
+	for _, s := range r.db.schemes {
+		if s.Subject == subject {
+			s = copyScheme(s)
+			return &s, nil
+		}
+		if s.Subject == "" {
+			s = copyScheme(s)
+			fallback = &s
+		}
+	}
+	if fallback == nil {
+		return nil, fmt.Errorf("repo: no grading scheme for subject %q: %w", subject, models.ErrNotFound)
+	}
+	return fallback, nil
+}
+
+func (r *GradingRepository) Create(ctx context.Context, s models.GradingScheme) (*models.GradingScheme, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if err := r.checkSubjectFree(0, s.Subject); err != nil {
+		return nil, fmt.Errorf("repo: failed to insert grading scheme: %w", err)
+	}
+
+	s = copyScheme(s)
+	s.ID = r.db.newID("grading_schemes")
+	s.CreatedAt = r.db.now()
+	s.UpdatedAt = s.CreatedAt
+	r.db.schemes[s.ID] = s
+	out := copyScheme(s)
+	return &out, nil
+}
+
+func (r *GradingRepository) Update(ctx context.Context, id int, s models.GradingScheme) (*models.GradingScheme, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	existing, ok := r.db.schemes[id]
+	if !ok {
+		return nil, fmt.Errorf("repo: grading scheme %d not found: %w", id, models.ErrNotFound)
+	}
+	if err := r.checkSubjectFree(id, s.Subject); err != nil {
+		return nil, fmt.Errorf("repo: failed to update grading scheme: %w", err)
+	}
+
+	existing.Name = s.Name
+	existing.Subject = s.Subject
+	existing.Boundaries = copyScheme(s).Boundaries
+	existing.UpdatedAt = r.db.now()
+	r.db.schemes[id] = existing
+	out := copyScheme(existing)
+	return &out, nil
+}
+
+func (r *GradingRepository) Delete(ctx context.Context, id int) (bool, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.schemes[id]; !ok {
+		return false, nil
+	}
+	delete(r.db.schemes, id)
+	return true, nil
+}
+
+func (r *GradingRepository) ReplaceAll(ctx context.Context, schemes []models.GradingScheme) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	bySubject := make(map[string]int, len(r.db.schemes))
+	for id, s := range r.db.schemes {
+		bySubject[s.Subject] = id
+	}
+	keep := make(map[int]bool, len(schemes))
+	now := r.db.now()
+	for _, s := range schemes {
+		s = copyScheme(s)
+		if id, ok := bySubject[s.Subject]; ok {
+			existing := r.db.schemes[id]
+			existing.Name, existing.Boundaries, existing.UpdatedAt = s.Name, s.Boundaries, now
+			r.db.schemes[id] = existing
+			keep[id] = true
+			continue
+		}
+		s.ID = r.db.newID("grading_schemes")
+		s.CreatedAt, s.UpdatedAt = now, now
+		r.db.schemes[s.ID] = s
+		keep[s.ID] = true
+	}
+	for id := range r.db.schemes {
+		if !keep[id] {
+			delete(r.db.schemes, id)
+		}
+	}
+	return nil
+}
+
+// checkSubjectFree mirrors the unique key on subject. Caller must hold the lock.
+func (r *GradingRepository) checkSubjectFree(selfID int, subject string) error {
+	for _, s := range r.db.schemes {
+		if s.ID != selfID && s.Subject == subject {
+			return &models.ConflictError{Field: "subject", Value: subject}
+		}
+	}
+	return nil
+}
+
+// copyScheme detaches the boundaries slice so callers can't mutate the stored scheme
+func copyScheme(s models.GradingScheme) models.GradingScheme {
+	s.Boundaries = append([]models.GradeBoundary(nil), s.Boundaries...)
+	return s
+}
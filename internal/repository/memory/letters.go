@@ -0,0 +1,122 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"sort"
+)
+
+// LetterTemplateRepository is the in-memory twin of repository.LetterTemplateRepository
+type LetterTemplateRepository struct {
+	db *DB
+}
+
+var _ repository.LetterTemplateStore = (*LetterTemplateRepository)(nil)
+
+// NewLetterTemplateRepository is the constructor
+func NewLetterTemplateRepository(db *DB) *LetterTemplateRepository {
+	return &LetterTemplateRepository{db: db}
+}
+
+func (r *LetterTemplateRepository) Create(ctx context.Context, t models.LetterTemplate) (*models.LetterTemplate, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	t.ID = r.db.newID("letter_templates")
+	t.CreatedAt = r.db.now()
+	t.UpdatedAt = t.CreatedAt
+	r.db.letters[t.ID] = t
+	r.db.appendAudit(ctx, models.AuditEntry{
+		ActorID:  t.CreatedBy,
+		Action:   models.AuditLetterTemplateCreated,
+		Entity:   "letter_template",
+		EntityID: t.ID,
+		Details:  map[string]any{"name": t.Name, "kind": t.Kind},
+	})
+	return &t, nil
+}
+
+func (r *LetterTemplateRepository) GetByID(ctx context.Context, id int) (*models.LetterTemplate, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	t, ok := r.db.letters[id]
+	if !ok {
+		return nil, fmt.Errorf("repo: letter template %d not found: %w", id, models.ErrNotFound)
+	}
+	return &t, nil
+}
+
+func (r *LetterTemplateRepository) List(ctx context.Context, kind string) ([]models.LetterTemplate, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	templates := make([]models.LetterTemplate, 0)
+	for _, t := range r.db.letters {
+		if kind == "" || t.Kind == kind {
+			templates = append(templates, t)
+		}
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		if templates[i].Name != templates[j].Name {
+			return templates[i].Name < templates[j].Name
+		}
+		return templates[i].ID < templates[j].ID
+	})
+	return templates, nil
+}
+
+func (r *LetterTemplateRepository) Update(ctx context.Context, id int, req models.LetterTemplateRequest, actorID *int) (*models.LetterTemplate, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	t, ok := r.db.letters[id]
+	if !ok {
+		return nil, fmt.Errorf("repo: letter template %d not found: %w", id, models.ErrNotFound)
+	}
+	before := t
+	t.Name, t.Kind, t.Subject, t.Body = req.Name, req.Kind, req.Subject, req.Body
+	t.UpdatedAt = r.db.now()
+	r.db.letters[id] = t
+	r.db.appendAudit(ctx, models.AuditEntry{
+		ActorID:  actorID,
+		Action:   models.AuditLetterTemplateUpdated,
+		Entity:   "letter_template",
+		EntityID: id,
+		Details: map[string]any{
+			"name":          req.Name,
+			"previous_name": before.Name,
+			"kind":          req.Kind,
+			"text_changed":  req.Subject != before.Subject || req.Body != before.Body,
+		},
+	})
+	return &t, nil
+}
+
+func (r *LetterTemplateRepository) Delete(ctx context.Context, id int, actorID *int) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	t, ok := r.db.letters[id]
+	if !ok {
+		return fmt.Errorf("repo: letter template %d not found: %w", id, models.ErrNotFound)
+	}
+	delete(r.db.letters, id)
+	// Like ON DELETE SET NULL on documents.template_id
+	for docID, d := range r.db.docs {
+		if d.TemplateID != nil && *d.TemplateID == id {
+			d.TemplateID = nil
+			r.db.docs[docID] = d
+		}
+	}
+	r.db.appendAudit(ctx, models.AuditEntry{
+		ActorID:  actorID,
+		Action:   models.AuditLetterTemplateDeleted,
+		Entity:   "letter_template",
+		EntityID: id,
+		Details:  map[string]any{"name": t.Name},
+	})
+	return nil
+}
@@ -0,0 +1,71 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"slices"
+)
+
+// MedicalRepository is the in-memory twin of repository.MedicalRepository
+type MedicalRepository struct {
+	db *DB
+}
+
+var _ repository.MedicalStore = (*MedicalRepository)(nil)
+
+// NewMedicalRepository is the constructor
+func NewMedicalRepository(db *DB) *MedicalRepository {
+	return &MedicalRepository{db: db}
+}
+
+func (r *MedicalRepository) Get(ctx context.Context, ids []int, actorID *int, reason string) ([]models.MedicalRecord, error) {
+	// The write lock: reads are audited
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	records := make([]models.MedicalRecord, 0, len(ids))
+	for _, id := range ids {
+		m, ok := r.db.medical[id]
+		if !ok {
+			continue
+		}
+		m.EmergencyContacts = slices.Clone(m.EmergencyContacts)
+		records = append(records, m)
+		r.db.appendAudit(ctx, models.AuditEntry{
+			ActorID:  actorID,
+			Action:   models.AuditMedicalViewed,
+			Entity:   "student",
+			EntityID: id,
+			Details:  map[string]any{"reason": reason},
+		})
+	}
+	return records, nil
+}
+
+func (r *MedicalRepository) Save(ctx context.Context, rec models.MedicalRecord, actorID *int) (*models.MedicalRecord, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.students[rec.StudentID]; !ok {
+		return nil, fmt.Errorf("repo: student %d not found: %w", rec.StudentID, models.ErrStudentNotFound)
+	}
+	before := r.db.medical[rec.StudentID]
+	rec.EmergencyContacts = slices.Clone(rec.EmergencyContacts)
+	if rec.EmergencyContacts == nil {
+		rec.EmergencyContacts = make([]models.EmergencyContact, 0)
+	}
+	rec.UpdatedBy = actorID
+	rec.UpdatedAt = r.db.now()
+	r.db.medical[rec.StudentID] = rec
+	r.db.appendAudit(ctx, models.AuditEntry{
+		ActorID:  actorID,
+		Action:   models.AuditMedicalUpdated,
+		Entity:   "student",
+		EntityID: rec.StudentID,
+		Details:  map[string]any{"fields": models.MedicalChanges(before, rec)},
+	})
+	rec.EmergencyContacts = slices.Clone(rec.EmergencyContacts)
+	return &rec, nil
+}
@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"sort"
+)
+
+// MessageRepository is the in-memory twin of repository.MessageRepository
+type MessageRepository struct {
+	db *DB
+}
+
+var _ repository.MessageStore = (*MessageRepository)(nil)
+
+// NewMessageRepository is the constructor
+func NewMessageRepository(db *DB) *MessageRepository {
+	return &MessageRepository{db: db}
+}
+
+func (r *MessageRepository) Create(ctx context.Context, m models.SMSMessage) (*models.SMSMessage, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	m.ID = r.db.newID("sms_messages")
+	m.CreatedAt = r.db.now()
+	m.UpdatedAt = m.CreatedAt
+	r.db.messages[m.ID] = m
+	return &m, nil
+}
+
+func (r *MessageRepository) MarkSent(ctx context.Context, id int, providerMessageID, status, errText string) (*models.SMSMessage, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	m, ok := r.db.messages[id]
+	if !ok {
+		return nil, fmt.Errorf("repo: message %d not found: %w", id, models.ErrNotFound)
+	}
+	m.ProviderMessageID = providerMessageID
+	m.Status = status
+	m.Error = errText
+	m.UpdatedAt = r.db.now()
+	r.db.messages[id] = m
+	return &m, nil
+}
+
+func (r *MessageRepository) UpdateStatus(ctx context.Context, provider, providerMessageID, status, errText string) (bool, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	for id, m := range r.db.messages {
+		if m.Provider != provider || m.ProviderMessageID != providerMessageID {
+			continue
+		}
+		// Same rule as MySQL: delivered is final
+		if m.Status != models.MessageDelivered {
+			m.Status = status
+			m.Error = errText
+			m.UpdatedAt = r.db.now()
+			r.db.messages[id] = m
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+func (r *MessageRepository) List(ctx context.Context, filter models.SMSFilter) ([]models.SMSMessage, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	messages := make([]models.SMSMessage, 0)
+	for _, m := range r.db.messages {
+		if filter.StudentID != 0 && (m.StudentID == nil || *m.StudentID != filter.StudentID) {
+			continue
+		}
+		if filter.Status != "" && m.Status != filter.Status {
+			continue
+		}
+		messages = append(messages, m)
+	}
+	sort.Slice(messages, func(i, j int) bool { return messages[i].ID > messages[j].ID })
+	return messages, nil
+}
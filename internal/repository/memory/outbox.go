@@ -0,0 +1,88 @@
+package memory
+
+import (
+	"context"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"time"
+)
+
+// outboxRow is an event with its delivery state, like a row of the outbox table
+type outboxRow struct {
+	event       models.OutboxEvent
+	nextAttempt time.Time
+	delivered   *time.Time
+}
+
+// OutboxRepository is the in-memory twin of repository.OutboxRepository
+type OutboxRepository struct {
+	db *DB
+}
+
+var _ repository.OutboxStore = (*OutboxRepository)(nil)
+
+// NewOutboxRepository is the constructor
+func NewOutboxRepository(db *DB) *OutboxRepository {
+	return &OutboxRepository{db: db}
+}
+
+func (r *OutboxRepository) Pending(ctx context.Context, now time.Time, limit int) ([]models.OutboxEvent, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	events := make([]models.OutboxEvent, 0)
+	for _, row := range r.db.outbox { // Append order is ID order
+		if row.delivered == nil && !row.nextAttempt.After(now) && len(events) < limit {
+			events = append(events, row.event)
+		}
+	}
+	return events, nil
+}
+
+func (r *OutboxRepository) MarkDelivered(ctx context.Context, id int, at time.Time) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if row := r.row(id); row != nil {
+		row.delivered = &at
+		row.event.LastError = ""
+	}
+	return nil
+}
+
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id int, reason string, retryAt time.Time) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if row := r.row(id); row != nil {
+		row.event.Attempts++
+		row.event.LastError = reason
+		row.nextAttempt = retryAt
+	}
+	return nil
+}
+
+func (r *OutboxRepository) PurgeDelivered(ctx context.Context, cutoff time.Time) (int, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	kept := r.db.outbox[:0]
+	for _, row := range r.db.outbox {
+		if row.delivered == nil || !row.delivered.Before(cutoff) {
+			kept = append(kept, row)
+		}
+	}
+	n := len(r.db.outbox) - len(kept)
+	r.db.outbox = kept
+	return n, nil
+}
+
+// row finds an event by ID. Caller must hold the write lock.
+func (r *OutboxRepository) row(id int) *outboxRow {
+	for i := range r.db.outbox {
+		if r.db.outbox[i].event.ID == id {
+			return &r.db.outbox[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"slices"
+	"sort"
+)
+
+// PromotionRepository is the in-memory twin of repository.PromotionRepository
+type PromotionRepository struct {
+	db *DB
+}
+
+var _ repository.PromotionStore = (*PromotionRepository)(nil)
+
+// NewPromotionRepository is the constructor
+func NewPromotionRepository(db *DB) *PromotionRepository {
+	return &PromotionRepository{db: db}
+}
+
+// clonePromotion copies the decisions so callers can't edit the stored report
+func clonePromotion(p models.PromotionReport) *models.PromotionReport {
+	p.Decisions = slices.Clone(p.Decisions)
+	return &p
+}
+
+func (r *PromotionRepository) Create(ctx context.Context, p models.PromotionReport) (*models.PromotionReport, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	p.ID = r.db.newID("promotion_reports")
+	p.Status = models.PromotionDraft
+	p.CreatedAt = r.db.now()
+	p.AppliedBy, p.AppliedAt = nil, nil
+	r.db.promotions[p.ID] = *clonePromotion(p)
+	return clonePromotion(p), nil
+}
+
+func (r *PromotionRepository) GetByID(ctx context.Context, id int) (*models.PromotionReport, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	p, ok := r.db.promotions[id]
+	if !ok {
+		return nil, fmt.Errorf("repo: promotion report %d not found: %w", id, models.ErrNotFound)
+	}
+	return clonePromotion(p), nil
+}
+
+func (r *PromotionRepository) List(ctx context.Context, year string) ([]models.PromotionReport, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	reports := make([]models.PromotionReport, 0)
+	for _, p := range r.db.promotions {
+		if year == "" || p.Year == year {
+			reports = append(reports, *clonePromotion(p))
+		}
+	}
+	sort.Slice(reports, func(i, j int) bool { return reports[i].ID > reports[j].ID })
+	return reports, nil
+}
+
+func (r *PromotionRepository) Override(ctx context.Context, id, studentID int, o models.PromotionOverride, actorID *int) (*models.PromotionReport, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	p, err := r.draft(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := models.OverrideDecision(p, studentID, o, actorID); err != nil {
+		return nil, fmt.Errorf("repo: %w", err)
+	}
+	r.db.promotions[id] = *p
+	return clonePromotion(*p), nil
+}
+
+func (r *PromotionRepository) Apply(ctx context.Context, id int, actorID *int) (*models.PromotionReport, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	p, err := r.draft(id)
+	if err != nil {
+		return nil, err
+	}
+	if n := p.Pending(); n > 0 {
+		return nil, fmt.Errorf("repo: promotion report %d has pending reviews: %w", id, &models.DependencyError{
+			Reason:   "Some students are up for review; override their decisions before applying the report",
+			Resource: "students",
+			Count:    n,
+		})
+	}
+
+	for i := range p.Decisions {
+		d := &p.Decisions[i]
+		s, ok := r.db.students[d.StudentID]
+		if d.Final() != models.PromotionPromote || !ok || s.Class != d.Class {
+			continue
+		}
+		s.Class = d.NextClass
+		r.db.students[d.StudentID] = s
+		d.Moved = true
+		r.db.appendAudit(ctx, models.AuditEntry{
+			ActorID:  actorID,
+			Action:   models.AuditStudentPromoted,
+			Entity:   "student",
+			EntityID: d.StudentID,
+			Details:  map[string]any{"from": d.Class, "to": d.NextClass, "report_id": id},
+		})
+	}
+
+	now := r.db.now()
+	p.Status, p.AppliedBy, p.AppliedAt = models.PromotionApplied, actorID, &now
+	r.db.promotions[id] = *p
+	return clonePromotion(*p), nil
+}
+
+// draft returns a copy of a draft report. Caller must hold the write lock.
+func (r *PromotionRepository) draft(id int) (*models.PromotionReport, error) {
+	p, ok := r.db.promotions[id]
+	if !ok {
+		return nil, fmt.Errorf("repo: promotion report %d not found: %w", id, models.ErrNotFound)
+	}
+	if p.Status != models.PromotionDraft {
+		return nil, fmt.Errorf("repo: promotion report %d was already applied: %w", id, models.ErrConflict)
+	}
+	return clonePromotion(p), nil
+}
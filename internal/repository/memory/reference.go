@@ -0,0 +1,54 @@
+package memory
+
+import (
+	"context"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"slices"
+)
+
+// ReferenceRepository is the in-memory twin of repository.ReferenceRepository
+type ReferenceRepository struct {
+	db *DB
+}
+
+var _ repository.ReferenceStore = (*ReferenceRepository)(nil)
+
+// NewReferenceRepository is the constructor
+func NewReferenceRepository(db *DB) *ReferenceRepository {
+	return &ReferenceRepository{db: db}
+}
+
+func (r *ReferenceRepository) Get(ctx context.Context) (*models.ReferenceData, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+	return cloneReference(r.db.reference), nil
+}
+
+func (r *ReferenceRepository) Replace(ctx context.Context, d models.ReferenceData, actorID *int) (*models.ReferenceData, error) {
+	d.Normalize()
+
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	before := r.db.reference
+	r.db.reference = *cloneReference(d)
+	r.db.appendAudit(ctx, models.AuditEntry{
+		ActorID: actorID,
+		Action:  models.AuditReferenceUpdated,
+		Entity:  "reference",
+		Details: map[string]any{"changes": models.DiffReference(before, d)},
+	})
+	return cloneReference(d), nil
+}
+
+func cloneReference(d models.ReferenceData) *models.ReferenceData {
+	out := models.ReferenceData{Classes: slices.Clone(d.Classes), Subjects: slices.Clone(d.Subjects)}
+	if out.Classes == nil {
+		out.Classes = []string{}
+	}
+	if out.Subjects == nil {
+		out.Subjects = []string{}
+	}
+	return &out
+}
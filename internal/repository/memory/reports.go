@@ -0,0 +1,140 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"maps"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"slices"
+)
+
+// ReportRepository is the in-memory twin of repository.ReportRepository
+type ReportRepository struct {
+	db *DB
+}
+
+var _ repository.ReportStore = (*ReportRepository)(nil)
+
+// NewReportRepository is the constructor
+func NewReportRepository(db *DB) *ReportRepository {
+	return &ReportRepository{db: db}
+}
+
+func (r *ReportRepository) Enrollment(ctx context.Context) ([]models.EnrollmentCount, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	byClass := make(map[string]int)
+	for _, s := range r.db.students {
+		if s.Status == models.StudentEnrolled {
+			byClass[s.Class]++
+		}
+	}
+	counts := make([]models.EnrollmentCount, 0, len(byClass))
+	for _, class := range slices.Sorted(maps.Keys(byClass)) {
+		counts = append(counts, models.EnrollmentCount{Class: class, Students: byClass[class]})
+	}
+	return counts, nil
+}
+
+func (r *ReportRepository) Genders(ctx context.Context) ([]models.GenderCount, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	byKey := make(map[models.GenderCount]int)
+	for _, s := range r.db.students {
+		if s.Status != models.StudentEnrolled {
+			continue
+		}
+		key := models.GenderCount{Class: s.Class, Gender: cmp.Or(s.Gender, models.GenderUnrecorded)}
+		byKey[key]++
+	}
+	counts := make([]models.GenderCount, 0, len(byKey))
+	for key, n := range byKey {
+		key.Students = n
+		counts = append(counts, key)
+	}
+	slices.SortFunc(counts, func(a, b models.GenderCount) int {
+		return cmp.Or(cmp.Compare(a.Class, b.Class), cmp.Compare(a.Gender, b.Gender))
+	})
+	return counts, nil
+}
+
+func (r *ReportRepository) AttendanceRates(ctx context.Context, from, to string) ([]models.AttendanceRate, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	type classMonth struct{ class, month string }
+	tallies := make(map[classMonth]models.AttendanceTally)
+	for key, row := range r.db.attendance {
+		if key.date < from || key.date > to {
+			continue
+		}
+		k := classMonth{row.class, key.date[:len(models.MonthLayout)]}
+		t := tallies[k]
+		t.Add(row.mark.Status, 1)
+		tallies[k] = t
+	}
+	rates := make([]models.AttendanceRate, 0, len(tallies))
+	for k, t := range tallies {
+		rates = append(rates, models.AttendanceRate{Class: k.class, Month: k.month, AttendanceTally: t, Rate: t.Rate()})
+	}
+	slices.SortFunc(rates, func(a, b models.AttendanceRate) int {
+		return cmp.Or(cmp.Compare(a.Class, b.Class), cmp.Compare(a.Month, b.Month))
+	})
+	return rates, nil
+}
+
+// SummarizeAttendance has nothing to do: AttendanceRates tallies the marks
+// themselves, which are few enough in memory
+func (r *ReportRepository) SummarizeAttendance(ctx context.Context, before string) (int, error) {
+	return 0, nil
+}
+
+func (r *ReportRepository) GradeDistribution(ctx context.Context, term string) ([]models.GradeCount, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	scores := &ScoreRepository{db: r.db}
+	byKey := make(map[models.GradeCount]int)
+	for _, s := range r.db.scores {
+		if term != "" && s.Term != term {
+			continue
+		}
+		s = scores.effective(s)
+		byKey[models.GradeCount{Subject: s.Subject, Grade: s.Grade}]++
+	}
+	counts := make([]models.GradeCount, 0, len(byKey))
+	for key, n := range byKey {
+		key.Scores = n
+		counts = append(counts, key)
+	}
+	slices.SortFunc(counts, func(a, b models.GradeCount) int {
+		return cmp.Or(cmp.Compare(a.Subject, b.Subject), cmp.Compare(a.Grade, b.Grade))
+	})
+	return counts, nil
+}
+
+func (r *ReportRepository) KPIs(ctx context.Context, day string) (models.SchoolKPIs, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var kpis models.SchoolKPIs
+	for _, s := range r.db.students {
+		if s.Status == models.StudentEnrolled {
+			kpis.Students++
+		}
+	}
+	for _, t := range r.db.teachers {
+		if t.IsActive {
+			kpis.Teachers++
+		}
+	}
+	for key, row := range r.db.attendance {
+		if key.date == day {
+			kpis.Attendance.Add(row.mark.Status, 1)
+		}
+	}
+	return kpis, nil
+}
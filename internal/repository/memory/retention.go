@@ -0,0 +1,77 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"slices"
+	"time"
+)
+
+// RetentionRepository is the in-memory twin of repository.RetentionRepository
+type RetentionRepository struct {
+	db *DB
+}
+
+var _ repository.RetentionStore = (*RetentionRepository)(nil)
+
+// NewRetentionRepository is the constructor
+func NewRetentionRepository(db *DB) *RetentionRepository {
+	return &RetentionRepository{db: db}
+}
+
+func (r *RetentionRepository) Expire(ctx context.Context, policy models.RetentionPolicy, cutoff time.Time, dryRun bool) (int, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	n := 0
+	switch policy.Category {
+	case models.RetentionMessages:
+		for id, m := range r.db.threadMessages {
+			if m.CreatedAt.Before(cutoff) && m.Body != models.Redacted {
+				n++
+				if !dryRun {
+					m.Body, m.Attachments = models.Redacted, []models.Attachment{}
+					r.db.threadMessages[id] = m
+				}
+			}
+		}
+	case models.RetentionSMS:
+		for id, m := range r.db.messages {
+			if m.CreatedAt.Before(cutoff) && m.Body != models.Redacted {
+				n++
+				if !dryRun {
+					m.Body, m.To = models.Redacted, ""
+					r.db.messages[id] = m
+				}
+			}
+		}
+	case models.RetentionLogs:
+		expired := func(e models.AuditEntry) bool { return e.CreatedAt.Before(cutoff) }
+		for _, e := range r.db.audit {
+			if expired(e) {
+				n++
+			}
+		}
+		if !dryRun {
+			r.db.audit = slices.DeleteFunc(r.db.audit, expired)
+		}
+	default:
+		return 0, fmt.Errorf("repo: no retention for category %q: %w", policy.Category, models.ErrInvalidInput)
+	}
+
+	if n > 0 && !dryRun {
+		r.db.appendAudit(ctx, models.AuditEntry{
+			Action: models.AuditRetentionPurged,
+			Entity: "retention",
+			Details: map[string]any{
+				"category": policy.Category,
+				"action":   policy.Action,
+				"cutoff":   cutoff,
+				"records":  n,
+			},
+		})
+	}
+	return n, nil
+}
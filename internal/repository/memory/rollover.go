@@ -0,0 +1,177 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"slices"
+	"sort"
+)
+
+// RolloverRepository is the in-memory twin of repository.RolloverRepository
+type RolloverRepository struct {
+	db *DB
+}
+
+var _ repository.RolloverStore = (*RolloverRepository)(nil)
+
+// NewRolloverRepository is the constructor
+func NewRolloverRepository(db *DB) *RolloverRepository {
+	return &RolloverRepository{db: db}
+}
+
+// cloneRollover copies the plan's lists so callers can't edit the stored plan
+func cloneRollover(p models.RolloverPlan) *models.RolloverPlan {
+	p.Classes = slices.Clone(p.Classes)
+	p.Students = slices.Clone(p.Students)
+	p.Teachers = slices.Clone(p.Teachers)
+	for i, t := range p.Teachers {
+		p.Teachers[i].Classes = slices.Clone(t.Classes)
+		p.Teachers[i].NextClasses = slices.Clone(t.NextClasses)
+	}
+	return &p
+}
+
+func (r *RolloverRepository) Create(ctx context.Context, p models.RolloverPlan) (*models.RolloverPlan, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	p.ID = r.db.newID("rollover_plans")
+	p.Status = models.RolloverDraft
+	p.CreatedAt = r.db.now()
+	p.Progress, p.Error, p.ArchiveID = models.RolloverProgress{}, "", nil
+	p.ExecutedBy, p.ExecutedAt, p.FinishedAt = nil, nil, nil
+	r.db.rollovers[p.ID] = *cloneRollover(p)
+	return cloneRollover(p), nil
+}
+
+func (r *RolloverRepository) GetByID(ctx context.Context, id int) (*models.RolloverPlan, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	p, ok := r.db.rollovers[id]
+	if !ok {
+		return nil, fmt.Errorf("repo: rollover plan %d not found: %w", id, models.ErrNotFound)
+	}
+	return cloneRollover(p), nil
+}
+
+func (r *RolloverRepository) List(ctx context.Context, fromYear string) ([]models.RolloverPlan, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	plans := make([]models.RolloverPlan, 0)
+	for _, p := range r.db.rollovers {
+		if fromYear == "" || p.FromYear == fromYear {
+			plans = append(plans, *cloneRollover(p))
+		}
+	}
+	sort.Slice(plans, func(i, j int) bool { return plans[i].ID > plans[j].ID })
+	return plans, nil
+}
+
+func (r *RolloverRepository) Save(ctx context.Context, p models.RolloverPlan) (*models.RolloverPlan, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	stored, err := r.editable(p.ID)
+	if err != nil {
+		return nil, err
+	}
+	stored.AlumniClass, stored.Archive = p.AlumniClass, p.Archive
+	stored.Classes, stored.Students, stored.Teachers = p.Classes, p.Students, p.Teachers
+	stored = cloneRollover(*stored)
+	r.db.rollovers[p.ID] = *stored
+	return cloneRollover(*stored), nil
+}
+
+func (r *RolloverRepository) Start(ctx context.Context, id int, actorID *int) (*models.RolloverPlan, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	p, err := r.editable(id)
+	if err != nil {
+		return nil, err
+	}
+	for _, other := range r.db.rollovers {
+		if other.ID == id {
+			continue
+		}
+		if other.Status == models.JobPending || other.Status == models.JobRunning ||
+			(other.Status == models.JobDone && other.FromYear == p.FromYear) {
+			return nil, fmt.Errorf("repo: another rollover is in progress or %s rolled over already: %w", p.FromYear, models.ErrConflict)
+		}
+	}
+	now := r.db.now()
+	p.Status, p.Progress, p.Error = models.JobPending, models.RolloverProgress{}, ""
+	p.ExecutedBy, p.ExecutedAt, p.FinishedAt = actorID, &now, nil
+	r.db.rollovers[id] = *p
+	return cloneRollover(*p), nil
+}
+
+func (r *RolloverRepository) MarkRunning(ctx context.Context, id int) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if p, ok := r.db.rollovers[id]; ok && p.Status == models.JobPending {
+		p.Status = models.JobRunning
+		r.db.rollovers[id] = p
+	}
+	return nil
+}
+
+func (r *RolloverRepository) Progress(ctx context.Context, id int, progress models.RolloverProgress) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if p, ok := r.db.rollovers[id]; ok && p.Status == models.JobRunning {
+		p.Progress = progress
+		r.db.rollovers[id] = p
+	}
+	return nil
+}
+
+func (r *RolloverRepository) Finish(ctx context.Context, id int, result models.RolloverPlan) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	p, ok := r.db.rollovers[id]
+	if !ok || (p.Status != models.JobPending && p.Status != models.JobRunning) {
+		return nil
+	}
+	now := r.db.now()
+	p.Status, p.Error, p.ArchiveID, p.Progress = result.Status, result.Error, result.ArchiveID, result.Progress
+	p.Students = slices.Clone(result.Students)
+	p.FinishedAt = &now
+	r.db.rollovers[id] = p
+	return nil
+}
+
+func (r *RolloverRepository) FailUnfinished(ctx context.Context, reason string) (int, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	n := 0
+	now := r.db.now()
+	for id, p := range r.db.rollovers {
+		if p.Status == models.JobPending || p.Status == models.JobRunning {
+			p.Status, p.Error, p.FinishedAt = models.JobFailed, reason, &now
+			r.db.rollovers[id] = p
+			n++
+		}
+	}
+	return n, nil
+}
+
+// editable returns a copy of a draft or failed plan. Caller must hold the write lock.
+func (r *RolloverRepository) editable(id int) (*models.RolloverPlan, error) {
+	p, ok := r.db.rollovers[id]
+	if !ok {
+		return nil, fmt.Errorf("repo: rollover plan %d not found: %w", id, models.ErrNotFound)
+	}
+	if !p.Editable() {
+		return nil, fmt.Errorf("repo: rollover plan %d is %s: %w", id, p.Status, models.ErrConflict)
+	}
+	return cloneRollover(p), nil
+}
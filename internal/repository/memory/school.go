@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+)
+
+// SchoolRepository is the in-memory twin of repository.SchoolRepository
+type SchoolRepository struct {
+	db *DB
+}
+
+var _ repository.SchoolStore = (*SchoolRepository)(nil)
+
+// NewSchoolRepository is the constructor
+func NewSchoolRepository(db *DB) *SchoolRepository {
+	return &SchoolRepository{db: db}
+}
+
+func (r *SchoolRepository) Get(ctx context.Context) (*models.School, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	if r.db.school == nil {
+		return nil, fmt.Errorf("repo: the school profile is not set: %w", models.ErrNotFound)
+	}
+	s := *r.db.school
+	return &s, nil
+}
+
+func (r *SchoolRepository) Save(ctx context.Context, s models.School, actorID *int) (*models.School, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	var before models.School
+	if r.db.school != nil {
+		before = *r.db.school
+	}
+	now := r.db.now()
+	s.UpdatedAt, s.UpdatedBy = &now, actorID
+	r.db.school = &s
+	r.db.appendAudit(ctx, models.AuditEntry{
+		ActorID:  actorID,
+		Action:   models.AuditSchoolUpdated,
+		Entity:   "school",
+		EntityID: 1,
+		Details:  map[string]any{"changes": models.DiffSchool(before, s)},
+	})
+	saved := s
+	return &saved, nil
+}
+
+func (r *SchoolRepository) Usage(ctx context.Context) (models.QuotaUsage, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+	return r.db.usage(), nil
+}
+
+// quotas are the saved profile's, none before one is saved. Caller must hold the lock.
+func (db *DB) quotas() models.SchoolQuotas {
+	if db.school == nil {
+		return models.SchoolQuotas{}
+	}
+	return db.school.Quotas
+}
+
+// usage measures what the quotas cap, like SchoolRepository.Usage. Caller must hold the lock.
+func (db *DB) usage() models.QuotaUsage {
+	u := models.QuotaUsage{Students: len(db.students)}
+	for _, up := range db.uploads {
+		if up.Status != models.ScanRejected {
+			u.StorageBytes += up.Size
+		}
+	}
+	return u
+}
@@ -0,0 +1,194 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"math"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"slices"
+	"sort"
+)
+
+// ScoreRepository is the in-memory twin of repository.ScoreRepository
+type ScoreRepository struct {
+	db *DB
+}
+
+var _ repository.ScoreStore = (*ScoreRepository)(nil)
+
+// NewScoreRepository is the constructor
+func NewScoreRepository(db *DB) *ScoreRepository {
+	return &ScoreRepository{db: db}
+}
+
+func (r *ScoreRepository) Create(ctx context.Context, s models.StudentScore) (*models.StudentScore, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	// Same unique key as MySQL: (student_id, term, subject)
+	for _, existing := range r.db.scores {
+		if existing.StudentID == s.StudentID && existing.Term == s.Term && existing.Subject == s.Subject {
+			return nil, fmt.Errorf("repo: score already recorded: %w", &models.ConflictError{Field: "term", Value: s.Term})
+		}
+	}
+	s.ID = r.db.newID("student_scores")
+	s.Corrections = 0
+	s.UpdatedAt = r.db.now()
+	r.db.scores[s.ID] = s
+	return &s, nil
+}
+
+func (r *ScoreRepository) GetByID(ctx context.Context, id int) (*models.StudentScore, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	s, ok := r.db.scores[id]
+	if !ok {
+		return nil, fmt.Errorf("repo: score %d not found: %w", id, models.ErrNotFound)
+	}
+	s = r.effective(s)
+	return &s, nil
+}
+
+// effective overlays the score's latest correction, like the MySQL join.
+// Caller must hold the lock.
+func (r *ScoreRepository) effective(s models.StudentScore) models.StudentScore {
+	var latest *models.GradeCorrection
+	for _, c := range r.db.corrections {
+		if c.ScoreID != s.ID {
+			continue
+		}
+		s.Corrections++
+		if latest == nil || c.ID > latest.ID {
+			latest = &c
+		}
+	}
+	if latest != nil {
+		s.Score, s.Grade, s.SchemeID, s.UpdatedAt = latest.Score, latest.Grade, latest.SchemeID, latest.CreatedAt
+	}
+	return s
+}
+
+func (r *ScoreRepository) ListByStudent(ctx context.Context, studentID int, term string) ([]models.StudentScore, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	scores := make([]models.StudentScore, 0)
+	for _, s := range r.db.scores {
+		if s.StudentID != studentID || (term != "" && s.Term != term) {
+			continue
+		}
+		scores = append(scores, r.effective(s))
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		if scores[i].Term != scores[j].Term {
+			return scores[i].Term < scores[j].Term
+		}
+		return scores[i].Subject < scores[j].Subject
+	})
+	return scores, nil
+}
+
+func (r *ScoreRepository) ListByYear(ctx context.Context, year string) ([]models.StudentScore, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	scores := make([]models.StudentScore, 0)
+	for _, s := range r.db.scores {
+		if models.AcademicYear(s.Term) == year {
+			scores = append(scores, r.effective(s))
+		}
+	}
+	sort.Slice(scores, func(i, j int) bool {
+		a, b := scores[i], scores[j]
+		if a.StudentID != b.StudentID {
+			return a.StudentID < b.StudentID
+		}
+		if a.Term != b.Term {
+			return a.Term < b.Term
+		}
+		return a.Subject < b.Subject
+	})
+	return scores, nil
+}
+
+func (r *ScoreRepository) AddCorrection(ctx context.Context, c models.GradeCorrection) (*models.GradeCorrection, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.scores[c.ScoreID]; !ok {
+		return nil, fmt.Errorf("repo: score %d not found: %w", c.ScoreID, models.ErrNotFound)
+	}
+	c.ID = r.db.newID("grade_corrections")
+	c.CreatedAt = r.db.now()
+	r.db.corrections[c.ID] = c
+	return &c, nil
+}
+
+func (r *ScoreRepository) ListCorrections(ctx context.Context, scoreID int) ([]models.GradeCorrection, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	corrections := make([]models.GradeCorrection, 0)
+	for _, c := range r.db.corrections {
+		if c.ScoreID == scoreID {
+			corrections = append(corrections, c)
+		}
+	}
+	sort.Slice(corrections, func(i, j int) bool { return corrections[i].ID < corrections[j].ID })
+	return corrections, nil
+}
+
+func (r *ScoreRepository) ClassStats(ctx context.Context, class, subject, term string) (*models.ClassGradeStats, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	stats := models.ClassGradeStats{Class: class, Subject: subject, Term: term,
+		Distribution: make([]models.GradeTally, 0), Students: make([]models.StudentRank, 0)}
+	for _, s := range r.db.scores {
+		st, ok := r.db.students[s.StudentID]
+		if !ok || st.Class != class || s.Subject != subject || s.Term != term {
+			continue
+		}
+		s = r.effective(s)
+		stats.Students = append(stats.Students, models.StudentRank{
+			StudentID: s.StudentID, FirstName: st.FirstName, LastName: st.LastName, Score: s.Score, Grade: s.Grade})
+	}
+	n := len(stats.Students)
+	if n == 0 {
+		return &stats, nil
+	}
+
+	slices.SortFunc(stats.Students, func(a, b models.StudentRank) int {
+		return cmp.Or(cmp.Compare(b.Score, a.Score), cmp.Compare(a.LastName, b.LastName),
+			cmp.Compare(a.FirstName, b.FirstName), cmp.Compare(a.StudentID, b.StudentID))
+	})
+	byGrade := make(map[string]int)
+	var sum float64
+	for i := range stats.Students {
+		s := &stats.Students[i]
+		s.Rank = i + 1
+		if i > 0 && s.Score == stats.Students[i-1].Score {
+			s.Rank = stats.Students[i-1].Rank
+		}
+		byGrade[s.Grade]++
+		sum += s.Score
+	}
+	for _, grade := range slices.Sorted(maps.Keys(byGrade)) {
+		stats.Distribution = append(stats.Distribution, models.GradeTally{Grade: grade, Scores: byGrade[grade]})
+	}
+
+	stats.Scores = n
+	stats.Max, stats.Min = stats.Students[0].Score, stats.Students[n-1].Score
+	stats.Mean = sum / float64(n)
+	stats.Median = (stats.Students[(n-1)/2].Score + stats.Students[n/2].Score) / 2
+	var squares float64
+	for _, s := range stats.Students {
+		squares += (s.Score - stats.Mean) * (s.Score - stats.Mean)
+	}
+	stats.StdDev = math.Sqrt(squares / float64(n))
+	return &stats, nil
+}
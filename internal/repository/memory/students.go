@@ -0,0 +1,309 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"simpleapi/internal/models"
+	"simpleapi/internal/query"
+	"simpleapi/internal/repository"
+	"slices"
+)
+
+// StudentRepository is the in-memory twin of repository.StudentRepositoty
+type StudentRepository struct {
+	db *DB
+}
+
+var _ repository.StudentStore = (*StudentRepository)(nil)
+
+// NewStudentRepository is the constructor
+func NewStudentRepository(db *DB) *StudentRepository {
+	return &StudentRepository{db: db}
+}
+
+func (r *StudentRepository) GetAll(ctx context.Context, opts query.Options) ([]models.Student, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	students := make([]models.Student, 0)
+	for _, s := range r.db.students {
+		if opts.Match(studentGetter(s)) {
+			students = append(students, s)
+		}
+	}
+	slices.SortFunc(students, func(a, b models.Student) int {
+		return opts.Compare(studentGetter(a), studentGetter(b))
+	})
+	students = query.Apply(opts.Page, students)
+	for i := range students {
+		students[i] = selectStudentFields(students[i], opts)
+	}
+	return students, nil
+}
+
+func (r *StudentRepository) Count(ctx context.Context, opts query.Options) (int, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	count := 0
+	for _, s := range r.db.students {
+		if opts.Match(studentGetter(s)) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *StudentRepository) CountByClass(ctx context.Context) (map[string]int, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	counts := make(map[string]int)
+	for _, s := range r.db.students {
+		if s.Status == models.StudentEnrolled {
+			counts[s.Class]++
+		}
+	}
+	return counts, nil
+}
+
+func (r *StudentRepository) GetByID(ctx context.Context, id int) (*models.Student, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	s, ok := r.db.students[id]
+	if !ok {
+		return nil, fmt.Errorf("Student %d not found: %w", id, models.ErrStudentNotFound)
+	}
+	return &s, nil
+}
+
+func (r *StudentRepository) FindByKey(ctx context.Context, key string) (*models.Student, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var byEmail *models.Student
+	for _, s := range r.db.students {
+		if s.AdmissionNumber != "" && s.AdmissionNumber == key {
+			return &s, nil
+		}
+		if s.Email == key && byEmail == nil {
+			match := s
+			byEmail = &match
+		}
+	}
+	if byEmail != nil {
+		return byEmail, nil
+	}
+	return nil, fmt.Errorf("Student %s not found: %w", key, models.ErrStudentNotFound)
+}
+
+func (r *StudentRepository) Conflicts(ctx context.Context, students []models.Student) ([]models.ConflictError, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	emails := make(map[string]bool)
+	admissions := make(map[string]bool)
+	for _, s := range r.db.students {
+		emails[s.Email] = true
+		admissions[s.AdmissionNumber] = s.AdmissionNumber != ""
+	}
+	var conflicts []models.ConflictError
+	for _, s := range students {
+		if s.Email != "" && emails[s.Email] {
+			conflicts = append(conflicts, models.ConflictError{Field: "email", Value: s.Email})
+		}
+		if admissions[s.AdmissionNumber] {
+			conflicts = append(conflicts, models.ConflictError{Field: "admission_number", Value: s.AdmissionNumber})
+		}
+	}
+	return conflicts, nil
+}
+
+func (r *StudentRepository) CreateBulk(ctx context.Context, students []models.Student) ([]models.Student, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if limit := r.db.quotas().MaxStudents; limit > 0 && len(r.db.students)+len(students) > limit {
+		return nil, &models.QuotaError{Quota: models.QuotaStudents, Used: int64(len(r.db.students)), Requested: int64(len(students)), Limit: int64(limit)}
+	}
+
+	seen := make(map[string]bool)
+	seenAdmission := make(map[string]bool)
+	for _, s := range r.db.students {
+		seen[s.Email] = true
+		seenAdmission[s.AdmissionNumber] = s.AdmissionNumber != ""
+	}
+	for i, s := range students {
+		if seen[s.Email] {
+			return nil, &models.ItemError{Index: i, Err: fmt.Errorf("Failed to insert student: %w", &models.ConflictError{Field: "email", Value: s.Email})}
+		}
+		if seenAdmission[s.AdmissionNumber] {
+			return nil, &models.ItemError{Index: i, Err: fmt.Errorf("Failed to insert student: %w", &models.ConflictError{Field: "admission_number", Value: s.AdmissionNumber})}
+		}
+		seen[s.Email] = true
+		seenAdmission[s.AdmissionNumber] = s.AdmissionNumber != ""
+	}
+
+	result := make([]models.Student, len(students))
+	for i, s := range students {
+		s.ID = r.db.newID("students")
+		if s.Status == "" {
+			s.Status = models.StudentEnrolled
+		}
+		r.db.students[s.ID] = s
+		result[i] = s
+		r.db.appendAudit(ctx, models.AuditEntry{
+			Action:   models.AuditStudentCreated,
+			Entity:   "student",
+			EntityID: s.ID,
+			Details:  map[string]any{"changes": models.DiffStudent(models.Student{}, s)},
+		})
+	}
+	return result, nil
+}
+
+func (r *StudentRepository) SetCustomFields(ctx context.Context, id int, values map[string]any, actorID *int) (*models.Student, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	before, ok := r.db.students[id]
+	if !ok {
+		return nil, fmt.Errorf("repo: student %d not found: %w", id, models.ErrStudentNotFound)
+	}
+	after := before
+	after.CustomFields = nil
+	if len(values) > 0 {
+		after.CustomFields = maps.Clone(values) // Like the JSON column, not shared with the caller
+	}
+	r.db.students[id] = after
+	if changes := models.DiffStudent(before, after); len(changes) > 0 {
+		r.db.appendAudit(ctx, models.AuditEntry{
+			ActorID:  actorID,
+			Action:   models.AuditStudentUpdated,
+			Entity:   "student",
+			EntityID: id,
+			Details:  map[string]any{"changes": changes},
+		})
+	}
+	return &after, nil
+}
+
+func (r *StudentRepository) MoveClass(ctx context.Context, id int, from, to string, entry models.AuditEntry) (bool, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	s, ok := r.db.students[id]
+	if !ok || s.Class != from {
+		return false, nil
+	}
+	s.Class = to
+	r.db.students[id] = s
+	entry.Entity, entry.EntityID = "student", id
+	entry.Details = maps.Clone(entry.Details)
+	if entry.Details == nil {
+		entry.Details = make(map[string]any)
+	}
+	entry.Details["from"], entry.Details["to"] = from, to
+	r.db.appendAudit(ctx, entry)
+	return true, nil
+}
+
+func (r *StudentRepository) SetStatus(ctx context.Context, id int, from string, req models.StudentStatusRequest, actorID *int) (*models.Student, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	before, ok := r.db.students[id]
+	if !ok {
+		return nil, fmt.Errorf("repo: student %d not found: %w", id, models.ErrStudentNotFound)
+	}
+	if before.Status != from {
+		return nil, fmt.Errorf("repo: student %d is %s, not %s: %w", id, before.Status, from, models.ErrConflict)
+	}
+	after := before
+	after.Status = req.Status
+	if req.Class != "" {
+		after.Class = req.Class
+	}
+	r.db.students[id] = after
+
+	details := map[string]any{"from": from, "to": after.Status, "reason": req.Reason}
+	if after.Class != before.Class {
+		details["class"] = models.FieldChange{From: before.Class, To: after.Class}
+	}
+	r.db.appendAudit(ctx, models.AuditEntry{
+		ActorID:  actorID,
+		Action:   models.AuditStudentStatusChanged,
+		Entity:   "student",
+		EntityID: id,
+		Details:  details,
+	})
+	return &after, nil
+}
+
+// selectStudentFields blanks what opts doesn't select, like the columns MySQL doesn't read
+func selectStudentFields(s models.Student, opts query.Options) models.Student {
+	if opts.Fields == nil {
+		return s
+	}
+	picked := models.Student{ID: s.ID, CustomFields: s.CustomFields}
+	if !opts.Selects("custom_fields") {
+		picked.CustomFields = nil
+	}
+	for field, dst := range map[string]*string{
+		"first_name": &picked.FirstName, "last_name": &picked.LastName, "email": &picked.Email,
+		"class": &picked.Class, "admission_number": &picked.AdmissionNumber, "guardian_phone": &picked.GuardianPhone,
+		"status": &picked.Status, "date_of_birth": &picked.DateOfBirth, "gender": &picked.Gender, "nationality": &picked.Nationality,
+		"enrollment_date": &picked.EnrollmentDate,
+	} {
+		if opts.Selects(field) {
+			*dst, _ = studentField(s, field).(string)
+		}
+	}
+	return picked
+}
+
+func studentGetter(s models.Student) func(string) any {
+	return func(name string) any { return studentField(s, name) }
+}
+
+// studentField is a student's value of one of models.StudentFields, nil
+// where MySQL has NULL
+func studentField(s models.Student, name string) any {
+	switch name {
+	case "id":
+		return s.ID
+	case "first_name":
+		return s.FirstName
+	case "last_name":
+		return s.LastName
+	case "email":
+		return s.Email
+	case "class":
+		return s.Class
+	case "admission_number":
+		return nullIfEmpty(s.AdmissionNumber)
+	case "guardian_phone":
+		return s.GuardianPhone
+	case "status":
+		return s.Status
+	case "gender":
+		return s.Gender
+	case "nationality":
+		return s.Nationality
+	case "date_of_birth":
+		return nullIfEmpty(s.DateOfBirth)
+	case "enrollment_date":
+		return nullIfEmpty(s.EnrollmentDate)
+	}
+	// Anything else is a custom field; a missing value is NULL, like in the JSON column
+	return s.CustomFields[name]
+}
+
+func nullIfEmpty(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
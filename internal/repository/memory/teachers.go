@@ -0,0 +1,660 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/query"
+	"simpleapi/internal/repository"
+	"slices"
+	"sort"
+	"time"
+)
+
+// TeacherRepository is the in-memory twin of repository.TeacherRepository
+type TeacherRepository struct {
+	db *DB
+}
+
+var _ repository.TeacherStore = (*TeacherRepository)(nil)
+
+// NewTeacherRepository is the constructor
+func NewTeacherRepository(db *DB) *TeacherRepository {
+	return &TeacherRepository{db: db}
+}
+
+// --- READ ---
+
+func (r *TeacherRepository) GetAll(ctx context.Context, opts query.Options) ([]models.Teacher, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	teachers := make([]models.Teacher, 0)
+	for _, t := range r.db.teachers {
+		if opts.Match(teacherGetter(t)) {
+			teachers = append(teachers, publicTeacher(t))
+		}
+	}
+	slices.SortFunc(teachers, func(a, b models.Teacher) int {
+		return opts.Compare(teacherGetter(a), teacherGetter(b))
+	})
+	teachers = query.Apply(opts.Page, teachers)
+	for i := range teachers {
+		teachers[i] = selectTeacherFields(teachers[i], opts)
+	}
+	return teachers, nil
+}
+
+func (r *TeacherRepository) Count(ctx context.Context, opts query.Options) (int, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	count := 0
+	for _, t := range r.db.teachers {
+		if opts.Match(teacherGetter(t)) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *TeacherRepository) GetByID(ctx context.Context, id int) (*models.Teacher, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	t, ok := r.db.teachers[id]
+	if !ok {
+		return nil, fmt.Errorf("repo: teacher %d not found: %w", id, models.ErrTeacherNotFound)
+	}
+	t = publicTeacher(t)
+	return &t, nil
+}
+
+func (r *TeacherRepository) GetByEmail(ctx context.Context, email string) (*models.Teacher, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	for _, t := range r.db.teachers {
+		if t.Email == email {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("repo: teacher with email %s not found: %w", email, models.ErrTeacherNotFound)
+}
+
+func (r *TeacherRepository) GetStudents(ctx context.Context, teacherID int, includeInactive bool) ([]models.Student, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	students := make([]models.Student, 0)
+	t, ok := r.db.teachers[teacherID]
+	if !ok {
+		return students, nil // Same as the SQL inner join: no teacher, no rows
+	}
+	for _, s := range r.db.students {
+		if s.Class == t.Class && (includeInactive || s.Status == models.StudentEnrolled) {
+			students = append(students, s)
+		}
+	}
+	sort.Slice(students, func(i, j int) bool { return students[i].ID < students[j].ID })
+	return students, nil
+}
+
+func (r *TeacherRepository) ListDirectory(ctx context.Context) ([]models.DirectoryEntry, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	entries := make([]models.DirectoryEntry, 0)
+	for _, t := range r.db.teachers {
+		if t.PublishedInDirectory && t.IsActive {
+			entries = append(entries, models.DirectoryEntry{FirstName: t.FirstName, LastName: t.LastName, Subject: t.Subject, Email: t.Email})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].LastName != entries[j].LastName {
+			return entries[i].LastName < entries[j].LastName
+		}
+		return entries[i].FirstName < entries[j].FirstName
+	})
+	return entries, nil
+}
+
+// --- CREATE ---
+
+func (r *TeacherRepository) Conflicts(ctx context.Context, teachers []models.Teacher) ([]models.ConflictError, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	var conflicts []models.ConflictError
+	for _, t := range teachers {
+		if t.Email != "" && r.emailTaken(t.Email, 0) {
+			conflicts = append(conflicts, models.ConflictError{Field: "email", Value: t.Email})
+		}
+	}
+	return conflicts, nil
+}
+
+func (r *TeacherRepository) CreateBulk(ctx context.Context, teachers []models.Teacher) ([]models.Teacher, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	// Validate the whole batch first so a conflict leaves nothing behind (like the SQL tx)
+	seen := make(map[string]bool)
+	for i, t := range teachers {
+		if seen[t.Email] || r.emailTaken(t.Email, 0) {
+			return nil, &models.ItemError{Index: i, Err: fmt.Errorf("repo: failed to insert teacher: %w", &models.ConflictError{Field: "email", Value: t.Email})}
+		}
+		seen[t.Email] = true
+	}
+
+	now := r.db.now()
+	result := make([]models.Teacher, len(teachers))
+	for i, t := range teachers {
+		t.ID = r.db.newID("teachers")
+		t.Password = ""
+		// Column defaults from the MySQL schema; the INSERT never sets the role
+		t.Role = models.RoleTeacher
+		t.IsActive = true
+		t.CreatedAt = now
+		t.UpdatedAt = now
+		r.db.teachers[t.ID] = t
+		result[i] = publicTeacher(t)
+	}
+	return result, nil
+}
+
+func (r *TeacherRepository) CreateFirstAdmin(ctx context.Context, t models.Teacher) (*models.Teacher, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	// Trashed teachers count, as they're still rows in MySQL
+	if len(r.db.teachers) > 0 || len(r.db.deletedTeachers) > 0 {
+		return nil, fmt.Errorf("repo: teachers exist already: %w", models.ErrConflict)
+	}
+	now := r.db.now()
+	t.ID = r.db.newID("teachers")
+	t.Password = ""
+	t.Role = models.RoleAdmin
+	t.IsActive = true
+	t.CreatedAt = now
+	t.UpdatedAt = now
+	r.db.teachers[t.ID] = t
+	created := publicTeacher(t)
+	return &created, nil
+}
+
+// --- UPDATE & PATCH ---
+
+func (r *TeacherRepository) UpdateFull(ctx context.Context, id int, update models.Teacher, actorID *int) (*models.Teacher, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	current, ok := r.db.teachers[id]
+	if !ok {
+		return nil, fmt.Errorf("repo: teacher %d not found: %w", id, models.ErrTeacherNotFound)
+	}
+	if update.Email != current.Email {
+		return nil, fmt.Errorf("repo: failed to update teacher: %w", models.ErrEmailChangeRequired)
+	}
+
+	phone, err := models.ParsePhone(update.Phone)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to update teacher: %w", err)
+	}
+
+	before := current
+	current.FirstName = update.FirstName
+	current.LastName = update.LastName
+	current.Email = update.Email
+	current.Phone = phone
+	current.Class = update.Class
+	current.Subject = update.Subject
+	current.UpdatedAt = r.db.now()
+	r.db.teachers[id] = current
+	r.auditChange(ctx, models.DiffTeacher(before, current), actorID)
+
+	update.ID = id
+	update.Phone = phone
+	return &update, nil
+}
+
+func (r *TeacherRepository) SetDirectoryListing(ctx context.Context, id int, published bool) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	t, ok := r.db.teachers[id]
+	if !ok {
+		return fmt.Errorf("repo: teacher %d not found: %w", id, models.ErrTeacherNotFound)
+	}
+	t.PublishedInDirectory = published
+	r.db.teachers[id] = t
+	return nil
+}
+
+func (r *TeacherRepository) SetPreferences(ctx context.Context, id int, prefs models.Preferences) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	t, ok := r.db.teachers[id]
+	if !ok {
+		return fmt.Errorf("repo: teacher %d not found: %w", id, models.ErrTeacherNotFound)
+	}
+	t.Language, t.Timezone = prefs.Language, prefs.Timezone
+	r.db.teachers[id] = t
+	return nil
+}
+
+func (r *TeacherRepository) UpdatePasswordHash(ctx context.Context, id int, hash string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	t, ok := r.db.teachers[id]
+	if !ok {
+		return fmt.Errorf("repo: teacher %d not found: %w", id, models.ErrTeacherNotFound)
+	}
+	t.PasswordHash = hash
+	r.db.teachers[id] = t
+	return nil
+}
+
+func (r *TeacherRepository) RequirePasswordChange(ctx context.Context, id int, reason string) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	t, ok := r.db.teachers[id]
+	if !ok {
+		return fmt.Errorf("repo: teacher %d not found: %w", id, models.ErrTeacherNotFound)
+	}
+	t.ForcePasswordChange = true
+	r.db.teachers[id] = t
+	r.db.appendAudit(ctx, models.AuditEntry{
+		Action:   models.AuditPasswordFlagged,
+		Entity:   "teacher",
+		EntityID: id,
+		Details:  map[string]any{"reason": reason},
+	})
+	return nil
+}
+
+func (r *TeacherRepository) ChangePassword(ctx context.Context, id int, hash string, changedAt time.Time) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	t, ok := r.db.teachers[id]
+	if !ok {
+		return fmt.Errorf("repo: teacher %d not found: %w", id, models.ErrTeacherNotFound)
+	}
+	t.PasswordHash, t.PasswordChangedAt, t.ForcePasswordChange = hash, &changedAt, false
+	r.db.teachers[id] = t
+	r.db.appendAudit(ctx, models.AuditEntry{
+		ActorID:  &id,
+		Action:   models.AuditPasswordChanged,
+		Entity:   "teacher",
+		EntityID: id,
+	})
+	return nil
+}
+
+func (r *TeacherRepository) Patch(ctx context.Context, id int, updates map[string]interface{}, actorID *int) (*models.Teacher, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	current, ok := r.db.teachers[id]
+	if !ok {
+		return nil, fmt.Errorf("repo: teacher %d not found: %w", id, models.ErrTeacherNotFound)
+	}
+	before := current
+	if err := applyTeacherPatch(&current, updates, r.db.now()); err != nil {
+		return nil, err
+	}
+	r.db.teachers[id] = current
+	r.auditChange(ctx, models.DiffTeacher(before, current), actorID)
+
+	current = publicTeacher(current)
+	return &current, nil
+}
+
+func (r *TeacherRepository) BulkPatch(ctx context.Context, updates []map[string]interface{}, actorID *int, dryRun bool) ([]models.TeacherChange, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	// Work on copies and only commit once every patch succeeded (never, for a dry run)
+	staged := make(map[int]models.Teacher)
+	changes := make([]models.TeacherChange, 0, len(updates))
+	for i, update := range updates {
+		idFloat, ok := update["id"].(float64)
+		if !ok {
+			return nil, &models.ItemError{Index: i, Err: fmt.Errorf("repo: missing or invalid 'id' in patch data: %w", models.ErrInvalidInput)}
+		}
+		id := int(idFloat)
+
+		current, ok := staged[id]
+		if !ok {
+			current, ok = r.db.teachers[id]
+		}
+		if !ok {
+			return nil, &models.ItemError{Index: i, ID: id, Err: fmt.Errorf("repo: teacher %d not found: %w", id, models.ErrTeacherNotFound)}
+		}
+		before := current
+		if err := applyTeacherPatch(&current, update, r.db.now()); err != nil {
+			return nil, &models.ItemError{Index: i, ID: id, Err: fmt.Errorf("repo: patch failed for id %d: %w", id, err)}
+		}
+		staged[id] = current
+		changes = append(changes, models.DiffTeacher(before, current))
+	}
+
+	if dryRun {
+		return changes, nil
+	}
+	for id, t := range staged {
+		r.db.teachers[id] = t
+	}
+	for _, change := range changes {
+		r.auditChange(ctx, change, actorID)
+	}
+	return changes, nil
+}
+
+// --- DELETE ---
+
+func (r *TeacherRepository) Delete(ctx context.Context, id int, opts models.DeleteTeacherOptions) (bool, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	t, ok := r.db.teachers[id]
+	if !ok {
+		return false, nil
+	}
+
+	details := map[string]any{"class": t.Class}
+	if opts.ReassignTo != 0 {
+		if opts.ReassignTo == id {
+			return false, fmt.Errorf("repo: cannot reassign class to the teacher being deleted: %w", models.ErrInvalidInput)
+		}
+		target, ok := r.db.teachers[opts.ReassignTo]
+		if !ok {
+			return false, fmt.Errorf("repo: reassign target teacher %d not found: %w", opts.ReassignTo, models.ErrInvalidInput)
+		}
+		r.db.appendAudit(ctx, models.AuditEntry{
+			ActorID:  opts.ActorID,
+			Action:   models.AuditClassReassigned,
+			Entity:   "teacher",
+			EntityID: target.ID,
+			Details:  map[string]any{"class": t.Class, "previous_class": target.Class, "from_teacher": id},
+		})
+		target.Class = t.Class
+		r.db.teachers[target.ID] = target
+		details["reassigned_to"] = target.ID
+	} else if students := r.orphanedStudents(t); students > 0 {
+		return false, fmt.Errorf("repo: teacher %d still has a class: %w", id, &models.DependencyError{
+			Reason:   fmt.Sprintf("Teacher is the class teacher of '%s'; pass ?reassign_to=<teacher id> to hand the class over", t.Class),
+			Resource: "students",
+			Count:    students,
+		})
+	}
+
+	r.trash(id, opts.ActorID)
+	r.db.appendAudit(ctx, models.AuditEntry{
+		ActorID:  opts.ActorID,
+		Action:   models.AuditTeacherDeleted,
+		Entity:   "teacher",
+		EntityID: id,
+		Details:  details,
+	})
+	return true, nil
+}
+
+// orphanedStudents counts the students left without a teacher if t went away.
+// Caller must hold the lock.
+func (r *TeacherRepository) orphanedStudents(t models.Teacher) int {
+	for id, other := range r.db.teachers {
+		if id != t.ID && other.Class == t.Class {
+			return 0
+		}
+	}
+	count := 0
+	for _, s := range r.db.students {
+		if s.Class == t.Class {
+			count++
+		}
+	}
+	return count
+}
+
+func (r *TeacherRepository) BulkDelete(ctx context.Context, ids []int, actorID *int, dryRun bool) ([]int, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	var validIds []int
+	for _, id := range ids {
+		if _, ok := r.db.teachers[id]; ok && !slices.Contains(validIds, id) {
+			if !dryRun {
+				r.trash(id, actorID)
+			}
+			validIds = append(validIds, id)
+		}
+	}
+	return validIds, nil
+}
+
+func (r *TeacherRepository) SetActiveBulk(ctx context.Context, ids []int, active bool, actorID *int) ([]int, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	action := models.AuditTeacherDeactivated
+	if active {
+		action = models.AuditTeacherReactivated
+	}
+
+	var validIds []int
+	for _, id := range ids {
+		t, ok := r.db.teachers[id]
+		if !ok {
+			continue
+		}
+		t.IsActive = active
+		r.db.teachers[id] = t
+		r.db.appendAudit(ctx, models.AuditEntry{ActorID: actorID, Action: action, Entity: "teacher", EntityID: id})
+		validIds = append(validIds, id)
+	}
+	return validIds, nil
+}
+
+// --- TRASH ---
+
+func (r *TeacherRepository) ListDeleted(ctx context.Context) ([]models.Teacher, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	teachers := make([]models.Teacher, 0, len(r.db.deletedTeachers))
+	for _, t := range r.db.deletedTeachers {
+		teachers = append(teachers, publicTeacher(t))
+	}
+	sort.Slice(teachers, func(i, j int) bool { return teachers[i].DeletedAt.After(*teachers[j].DeletedAt) })
+	return teachers, nil
+}
+
+func (r *TeacherRepository) Restore(ctx context.Context, id int, actorID *int) (*models.Teacher, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	t, ok := r.db.deletedTeachers[id]
+	if !ok {
+		return nil, fmt.Errorf("repo: teacher %d is not in the trash: %w", id, models.ErrTeacherNotFound)
+	}
+	delete(r.db.deletedTeachers, id)
+	t.DeletedAt = nil
+	t.DeletedBy = nil
+	r.db.teachers[id] = t
+	r.db.appendAudit(ctx, models.AuditEntry{ActorID: actorID, Action: models.AuditTeacherRestored, Entity: "teacher", EntityID: id})
+
+	t = publicTeacher(t)
+	return &t, nil
+}
+
+func (r *TeacherRepository) PurgeDeleted(ctx context.Context, cutoff time.Time) (int, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	purged := 0
+	for id, t := range r.db.deletedTeachers {
+		if t.DeletedAt.Before(cutoff) {
+			delete(r.db.deletedTeachers, id)
+			r.db.appendAudit(ctx, models.AuditEntry{Action: models.AuditTeacherPurged, Entity: "teacher", EntityID: id})
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func (r *TeacherRepository) ExpireResetTokens(ctx context.Context, now time.Time) (int, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	expired := 0
+	for id, t := range r.db.teachers {
+		if t.PasswordResetExpires != nil && t.PasswordResetExpires.Before(now) {
+			t.PasswordResetToken, t.PasswordResetExpires = nil, nil
+			r.db.teachers[id] = t
+			expired++
+		}
+	}
+	return expired, nil
+}
+
+// trash moves a teacher into the trash. Caller must hold the write lock.
+func (r *TeacherRepository) trash(id int, actorID *int) {
+	t := r.db.teachers[id]
+	now := r.db.now()
+	t.DeletedAt = &now
+	t.DeletedBy = actorID
+	delete(r.db.teachers, id)
+	r.db.deletedTeachers[id] = t
+}
+
+// --- HELPERS ---
+
+// auditChange records a teacher's field-level changes, skipping no-op updates.
+// Caller must hold the write lock.
+func (r *TeacherRepository) auditChange(ctx context.Context, change models.TeacherChange, actorID *int) {
+	if len(change.Changes) == 0 {
+		return
+	}
+	r.db.appendAudit(ctx, models.AuditEntry{
+		ActorID:  actorID,
+		Action:   models.AuditTeacherUpdated,
+		Entity:   "teacher",
+		EntityID: change.ID,
+		Details:  map[string]any{"changes": change.Changes},
+	})
+}
+
+// emailTaken reports whether another teacher (not exceptID) already uses the email.
+// Caller must hold the lock.
+func (r *TeacherRepository) emailTaken(email string, exceptID int) bool {
+	for id, t := range r.db.teachers {
+		if id != exceptID && t.Email == email {
+			return true
+		}
+	}
+	// Trashed teachers keep their email, like the unique key in MySQL
+	for _, t := range r.db.deletedTeachers {
+		if t.Email == email {
+			return true
+		}
+	}
+	return false
+}
+
+// applyTeacherPatch mirrors the SQL Patch rules: unknown keys are ignored, values must be strings,
+// and the email can only be sent back unchanged
+func applyTeacherPatch(t *models.Teacher, updates map[string]interface{}, now time.Time) error {
+	for k, v := range updates {
+		switch k {
+		case "first_name", "last_name", "email", "phone", "class", "subject":
+		default:
+			continue
+		}
+
+		strVal, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("repo: field %s expected string: %w", k, models.ErrInvalidInput)
+		}
+
+		switch k {
+		case "first_name":
+			t.FirstName = strVal
+		case "last_name":
+			t.LastName = strVal
+		case "email":
+			if strVal != t.Email {
+				return models.ErrEmailChangeRequired
+			}
+		case "phone":
+			normalized, err := models.ParsePhone(strVal)
+			if err != nil {
+				return fmt.Errorf("repo: field phone: %w", err)
+			}
+			t.Phone = normalized
+		case "class":
+			t.Class = strVal
+		case "subject":
+			t.Subject = strVal
+		}
+	}
+	t.UpdatedAt = now
+	return nil
+}
+
+// publicTeacher strips credential fields, matching the columns the SQL reads select
+func publicTeacher(t models.Teacher) models.Teacher {
+	t.PasswordHash = ""
+	t.Password = ""
+	return t
+}
+
+// selectTeacherFields keeps only the listing columns opts selects, like the MySQL read
+func selectTeacherFields(t models.Teacher, opts query.Options) models.Teacher {
+	if opts.Fields == nil {
+		return t
+	}
+	picked := models.Teacher{ID: t.ID}
+	for field, dst := range map[string]*string{
+		"first_name": &picked.FirstName, "last_name": &picked.LastName, "email": &picked.Email,
+		"phone": &picked.Phone, "class": &picked.Class, "subject": &picked.Subject,
+	} {
+		if opts.Selects(field) {
+			*dst = teacherField(t, field).(string)
+		}
+	}
+	return picked
+}
+
+func teacherGetter(t models.Teacher) func(string) any {
+	return func(name string) any { return teacherField(t, name) }
+}
+
+// teacherField is a teacher's value of one of models.TeacherFields
+func teacherField(t models.Teacher, name string) any {
+	switch name {
+	case "id":
+		return t.ID
+	case "first_name":
+		return t.FirstName
+	case "last_name":
+		return t.LastName
+	case "email":
+		return t.Email
+	case "phone":
+		return t.Phone
+	case "class":
+		return t.Class
+	case "subject":
+		return t.Subject
+	case "role":
+		return t.Role
+	case "is_active":
+		return t.IsActive
+	}
+	return nil
+}
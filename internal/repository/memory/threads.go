@@ -0,0 +1,161 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"slices"
+	"sort"
+)
+
+// ThreadRepository is the in-memory twin of repository.ThreadRepository
+type ThreadRepository struct {
+	db *DB
+}
+
+var _ repository.ThreadStore = (*ThreadRepository)(nil)
+
+// NewThreadRepository is the constructor
+func NewThreadRepository(db *DB) *ThreadRepository {
+	return &ThreadRepository{db: db}
+}
+
+// threadReadKey is thread_reads' primary key
+type threadReadKey struct {
+	threadID, teacherID int
+}
+
+// withUnread fills in the viewer's unread count. Caller must hold the lock.
+func (r *ThreadRepository) withUnread(t models.Thread, viewerID int) models.Thread {
+	lastRead := r.db.threadReads[threadReadKey{t.ID, viewerID}]
+	t.Unread = 0
+	for _, m := range r.db.threadMessages {
+		if m.ThreadID == t.ID && m.SenderID != viewerID && m.ID > lastRead {
+			t.Unread++
+		}
+	}
+	return t
+}
+
+// addMessage stores a message, which reads the thread for its sender. Caller must hold the write lock.
+func (r *ThreadRepository) addMessage(m models.ThreadMessage) models.ThreadMessage {
+	m.ID = r.db.newID("thread_messages")
+	m.CreatedAt = r.db.now()
+	m.Attachments = slices.Clone(m.Attachments)
+	if m.Attachments == nil {
+		m.Attachments = []models.Attachment{}
+	}
+	r.db.threadMessages[m.ID] = m
+	r.db.threadReads[threadReadKey{m.ThreadID, m.SenderID}] = m.ID
+
+	t := r.db.threads[m.ThreadID]
+	t.LastMessageAt = m.CreatedAt
+	r.db.threads[m.ThreadID] = t
+	return m
+}
+
+func (r *ThreadRepository) Create(ctx context.Context, t models.Thread, first models.ThreadMessage) (*models.Thread, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	t.ID = r.db.newID("message_threads")
+	t.CreatedAt = r.db.now()
+	t.LastMessageAt = t.CreatedAt
+	r.db.threads[t.ID] = t
+
+	first.ThreadID = t.ID
+	r.addMessage(first)
+
+	t = r.withUnread(r.db.threads[t.ID], t.CreatedBy)
+	return &t, nil
+}
+
+func (r *ThreadRepository) GetByID(ctx context.Context, id, viewerID int) (*models.Thread, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	t, ok := r.db.threads[id]
+	if !ok {
+		return nil, fmt.Errorf("repo: thread %d not found: %w", id, models.ErrNotFound)
+	}
+	t = r.withUnread(t, viewerID)
+	return &t, nil
+}
+
+func (r *ThreadRepository) List(ctx context.Context, filter models.ThreadFilter, viewerID int) ([]models.Thread, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	threads := make([]models.Thread, 0)
+	for _, t := range r.db.threads {
+		if filter.StudentID != 0 && (t.StudentID == nil || *t.StudentID != filter.StudentID) {
+			continue
+		}
+		if filter.Class != "" && t.Class != filter.Class {
+			continue
+		}
+		threads = append(threads, r.withUnread(t, viewerID))
+	}
+	sort.Slice(threads, func(i, j int) bool {
+		if !threads[i].LastMessageAt.Equal(threads[j].LastMessageAt) {
+			return threads[i].LastMessageAt.After(threads[j].LastMessageAt)
+		}
+		return threads[i].ID > threads[j].ID
+	})
+	return threads, nil
+}
+
+func (r *ThreadRepository) AddMessage(ctx context.Context, m models.ThreadMessage) (*models.ThreadMessage, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if _, ok := r.db.threads[m.ThreadID]; !ok {
+		return nil, fmt.Errorf("repo: thread %d not found: %w", m.ThreadID, models.ErrNotFound)
+	}
+	m = r.addMessage(m)
+	m.Attachments = slices.Clone(m.Attachments)
+	return &m, nil
+}
+
+func (r *ThreadRepository) GetMessage(ctx context.Context, threadID, id int) (*models.ThreadMessage, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	m, ok := r.db.threadMessages[id]
+	if !ok || m.ThreadID != threadID {
+		return nil, fmt.Errorf("repo: message %d not found in thread %d: %w", id, threadID, models.ErrNotFound)
+	}
+	m.Attachments = slices.Clone(m.Attachments)
+	return &m, nil
+}
+
+func (r *ThreadRepository) ListMessages(ctx context.Context, threadID int) ([]models.ThreadMessage, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	messages := make([]models.ThreadMessage, 0)
+	for _, m := range r.db.threadMessages {
+		if m.ThreadID == threadID {
+			m.Attachments = slices.Clone(m.Attachments)
+			messages = append(messages, m)
+		}
+	}
+	sort.Slice(messages, func(i, j int) bool { return messages[i].ID < messages[j].ID })
+	return messages, nil
+}
+
+func (r *ThreadRepository) MarkRead(ctx context.Context, threadID, userID int) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	latest := 0
+	for _, m := range r.db.threadMessages {
+		if m.ThreadID == threadID && m.ID > latest {
+			latest = m.ID
+		}
+	}
+	key := threadReadKey{threadID, userID}
+	r.db.threadReads[key] = max(r.db.threadReads[key], latest)
+	return nil
+}
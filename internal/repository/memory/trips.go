@@ -0,0 +1,155 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"sort"
+)
+
+// TripRepository is the in-memory twin of repository.TripRepository
+type TripRepository struct {
+	db *DB
+}
+
+var _ repository.TripStore = (*TripRepository)(nil)
+
+// NewTripRepository is the constructor
+func NewTripRepository(db *DB) *TripRepository {
+	return &TripRepository{db: db}
+}
+
+// consentKey is trip_consents' primary key
+type consentKey struct{ trip, student int }
+
+func (r *TripRepository) Create(ctx context.Context, t models.Trip) (*models.Trip, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	t.ID = r.db.newID("trips")
+	t.CreatedAt = r.db.now()
+	r.db.trips[t.ID] = t
+	r.db.appendAudit(ctx, models.AuditEntry{
+		ActorID:  t.CreatedBy,
+		Action:   models.AuditTripCreated,
+		Entity:   "trip",
+		EntityID: t.ID,
+		Details:  map[string]any{"title": t.Title, "class": t.Class, "date": t.Date},
+	})
+	return &t, nil
+}
+
+func (r *TripRepository) GetByID(ctx context.Context, id int) (*models.Trip, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	t, ok := r.db.trips[id]
+	if !ok {
+		return nil, fmt.Errorf("repo: trip %d not found: %w", id, models.ErrNotFound)
+	}
+	return &t, nil
+}
+
+func (r *TripRepository) List(ctx context.Context, class string) ([]models.Trip, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	trips := make([]models.Trip, 0)
+	for _, t := range r.db.trips {
+		if class == "" || t.Class == class {
+			trips = append(trips, t)
+		}
+	}
+	sort.Slice(trips, func(i, j int) bool {
+		if trips[i].Date != trips[j].Date {
+			return trips[i].Date < trips[j].Date
+		}
+		return trips[i].ID < trips[j].ID
+	})
+	return trips, nil
+}
+
+func (r *TripRepository) Consents(ctx context.Context, tripID int) ([]models.TripConsent, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	consents := make([]models.TripConsent, 0)
+	for key, c := range r.db.consents {
+		if key.trip == tripID {
+			consents = append(consents, c)
+		}
+	}
+	sort.Slice(consents, func(i, j int) bool { return consents[i].StudentID < consents[j].StudentID })
+	return consents, nil
+}
+
+func (r *TripRepository) Request(ctx context.Context, consents []models.TripConsent) ([]models.TripConsent, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	requested := make([]models.TripConsent, 0, len(consents))
+	for _, c := range consents {
+		key := consentKey{c.TripID, c.StudentID}
+		if old, ok := r.db.consents[key]; ok {
+			if old.Status != models.ConsentPending {
+				continue
+			}
+			old.TokenHash, old.RequestedAt = c.TokenHash, c.RequestedAt
+			c = old
+		} else {
+			c.Status = models.ConsentPending
+		}
+		r.db.consents[key] = c
+		requested = append(requested, c)
+	}
+	return requested, nil
+}
+
+func (r *TripRepository) ConsentByToken(ctx context.Context, tokenHash string) (*models.TripConsent, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	for _, c := range r.db.consents {
+		if tokenHash != "" && c.TokenHash == tokenHash {
+			return &c, nil
+		}
+	}
+	return nil, fmt.Errorf("repo: no consent for this token: %w", models.ErrConsentLinkInvalid)
+}
+
+func (r *TripRepository) Answer(ctx context.Context, c models.TripConsent, actorID *int) (*models.TripConsent, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	key := consentKey{c.TripID, c.StudentID}
+	saved, ok := r.db.consents[key]
+	if c.TokenHash != "" {
+		if !ok || saved.TokenHash != c.TokenHash {
+			return nil, fmt.Errorf("repo: consent token was replaced: %w", models.ErrConsentLinkInvalid)
+		}
+		saved.RecordedBy = nil
+	} else {
+		if _, ok := r.db.trips[c.TripID]; !ok {
+			return nil, fmt.Errorf("repo: trip %d or student %d not found: %w", c.TripID, c.StudentID, models.ErrNotFound)
+		}
+		if _, ok := r.db.students[c.StudentID]; !ok {
+			return nil, fmt.Errorf("repo: trip %d or student %d not found: %w", c.TripID, c.StudentID, models.ErrNotFound)
+		}
+		if !ok {
+			saved = models.TripConsent{TripID: c.TripID, StudentID: c.StudentID, RequestedAt: r.db.now()}
+		}
+		saved.RecordedBy = actorID
+	}
+	now := r.db.now()
+	saved.Status, saved.Via, saved.GuardianName, saved.Note, saved.RespondedAt = c.Status, c.Via, c.GuardianName, c.Note, &now
+	r.db.consents[key] = saved
+	r.db.appendAudit(ctx, models.AuditEntry{
+		ActorID:  actorID,
+		Action:   models.AuditConsentAnswered,
+		Entity:   "trip",
+		EntityID: c.TripID,
+		Details:  map[string]any{"student_id": c.StudentID, "status": c.Status, "via": c.Via},
+	})
+	return &saved, nil
+}
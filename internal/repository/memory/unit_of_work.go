@@ -0,0 +1,74 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"slices"
+)
+
+// UnitOfWork is the in-memory twin of repository.TxUnitOfWork. Units of work
+// run one at a time; each snapshots the tables its Repos write and puts them
+// back if fn fails. Unlike MySQL, writes other requests make to those tables
+// while a failing unit runs are rolled back with it: fine for a demo store.
+type UnitOfWork struct {
+	db *DB
+}
+
+var _ repository.UnitOfWork = (*UnitOfWork)(nil)
+
+// NewUnitOfWork is the constructor
+func NewUnitOfWork(db *DB) *UnitOfWork {
+	return &UnitOfWork{db: db}
+}
+
+// Do runs fn, undoing its changes if it returns an error
+func (u *UnitOfWork) Do(ctx context.Context, fn func(ctx context.Context, repos repository.Repos) error) error {
+	u.db.work.Lock()
+	defer u.db.work.Unlock()
+
+	snap := u.db.snapshot()
+	repos := repository.Repos{
+		Teachers:         NewTeacherRepository(u.db),
+		Students:         NewStudentRepository(u.db),
+		ClassAssignments: NewClassAssignmentRepository(u.db),
+	}
+	if err := fn(ctx, repos); err != nil {
+		u.db.restore(snap)
+		return err
+	}
+	return nil
+}
+
+// unitSnapshot is what a unit of work's Repos can change. IDs aren't rewound,
+// as MySQL doesn't reuse auto-increment values after a rollback either.
+type unitSnapshot struct {
+	teachers        map[int]models.Teacher
+	deletedTeachers map[int]models.Teacher
+	students        map[int]models.Student
+	teacherClasses  map[int][]string
+	audit           []models.AuditEntry
+}
+
+func (db *DB) snapshot() unitSnapshot {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+	return unitSnapshot{
+		teachers:        maps.Clone(db.teachers),
+		deletedTeachers: maps.Clone(db.deletedTeachers),
+		students:        maps.Clone(db.students),
+		teacherClasses:  maps.Clone(db.teacherClasses),
+		audit:           slices.Clone(db.audit),
+	}
+}
+
+func (db *DB) restore(s unitSnapshot) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+	db.teachers = s.teachers
+	db.deletedTeachers = s.deletedTeachers
+	db.students = s.students
+	db.teacherClasses = s.teacherClasses
+	db.audit = s.audit
+}
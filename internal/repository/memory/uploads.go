@@ -0,0 +1,118 @@
+package memory
+
+import (
+	"context"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"sort"
+)
+
+// UploadRepository is the in-memory twin of repository.UploadRepository
+type UploadRepository struct {
+	db *DB
+}
+
+var _ repository.UploadStore = (*UploadRepository)(nil)
+
+// NewUploadRepository is the constructor
+func NewUploadRepository(db *DB) *UploadRepository {
+	return &UploadRepository{db: db}
+}
+
+func (r *UploadRepository) Create(ctx context.Context, u models.Upload) (*models.Upload, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	if limit, used := r.db.quotas().MaxStorageBytes, r.db.usage().StorageBytes; limit > 0 && used+u.Size > limit {
+		return nil, &models.QuotaError{Quota: models.QuotaStorage, Used: used, Requested: u.Size, Limit: limit}
+	}
+
+	u.ID = r.db.newID("uploads")
+	u.Status = models.ScanPending
+	u.Threat, u.ScannedAt, u.ReviewedBy, u.ReviewedAt, u.ReviewNote = "", nil, nil, nil, ""
+	u.CreatedAt = r.db.now()
+	r.db.uploads[u.ID] = u
+	return &u, nil
+}
+
+func (r *UploadRepository) GetByID(ctx context.Context, id int) (*models.Upload, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	u, ok := r.db.uploads[id]
+	if !ok {
+		return nil, fmt.Errorf("repo: upload %d not found: %w", id, models.ErrNotFound)
+	}
+	return &u, nil
+}
+
+func (r *UploadRepository) List(ctx context.Context, filter models.UploadFilter) ([]models.Upload, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	uploads := make([]models.Upload, 0)
+	for _, u := range r.db.uploads {
+		if filter.Status != "" && u.Status != filter.Status {
+			continue
+		}
+		uploads = append(uploads, u)
+	}
+	sort.Slice(uploads, func(i, j int) bool { return uploads[i].ID > uploads[j].ID })
+	return uploads, nil
+}
+
+func (r *UploadRepository) Statuses(ctx context.Context, ids []int) (map[int]string, error) {
+	r.db.mu.RLock()
+	defer r.db.mu.RUnlock()
+
+	statuses := make(map[int]string, len(ids))
+	for _, id := range ids {
+		if u, ok := r.db.uploads[id]; ok {
+			statuses[id] = u.Status
+		}
+	}
+	return statuses, nil
+}
+
+// transition applies a status change to an upload in state from. Caller must hold the write lock.
+func (r *UploadRepository) transition(id int, from string, apply func(*models.Upload)) (*models.Upload, error) {
+	u, ok := r.db.uploads[id]
+	if !ok {
+		return nil, fmt.Errorf("repo: upload %d not found: %w", id, models.ErrNotFound)
+	}
+	if u.Status != from {
+		return nil, fmt.Errorf("repo: upload %d is %s, not %s: %w", id, u.Status, from, models.ErrConflict)
+	}
+	apply(&u)
+	r.db.uploads[id] = u
+	return &u, nil
+}
+
+func (r *UploadRepository) RecordScan(ctx context.Context, id int, status, threat string) (*models.Upload, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	return r.transition(id, models.ScanPending, func(u *models.Upload) {
+		now := r.db.now()
+		u.Status, u.Threat, u.ScannedAt = status, threat, &now
+	})
+}
+
+func (r *UploadRepository) Review(ctx context.Context, id int, status string, reviewerID int, note string) (*models.Upload, error) {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	return r.transition(id, models.ScanQuarantined, func(u *models.Upload) {
+		now := r.db.now()
+		u.Status, u.ReviewedBy, u.ReviewedAt, u.ReviewNote = status, &reviewerID, &now, note
+	})
+}
+
+func (r *UploadRepository) Delete(ctx context.Context, id int) error {
+	r.db.mu.Lock()
+	defer r.db.mu.Unlock()
+
+	delete(r.db.uploads, id)
+	return nil
+}
@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/tracing"
+)
+
+// MessageRepository records outgoing SMS and their delivery status (table sms_messages)
+type MessageRepository struct {
+	DB Conn
+}
+
+// NewMessageRepository is the constructor
+func NewMessageRepository(db *sql.DB) *MessageRepository {
+	return &MessageRepository{DB: Pool(db)}
+}
+
+const messageColumns = "id, student_id, to_number, sender_id, template, body, provider, provider_message_id, status, error, created_at, updated_at"
+
+func scanMessage(row interface{ Scan(...any) error }, m *models.SMSMessage) error {
+	var studentID sql.NullInt64
+	var senderID, providerID, errText sql.NullString
+	if err := row.Scan(&m.ID, &studentID, &m.To, &senderID, &m.Template, &m.Body, &m.Provider,
+		&providerID, &m.Status, &errText, &m.CreatedAt, &m.UpdatedAt); err != nil {
+		return err
+	}
+	if studentID.Valid {
+		id := int(studentID.Int64)
+		m.StudentID = &id
+	}
+	m.SenderID = senderID.String
+	m.ProviderMessageID = providerID.String
+	m.Error = errText.String
+	return nil
+}
+
+func (r *MessageRepository) Create(ctx context.Context, m models.SMSMessage) (*models.SMSMessage, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.messages.Create")
+	defer span.End()
+
+	res, err := r.DB.ExecContext(ctx,
+		"INSERT INTO sms_messages (student_id, to_number, sender_id, template, body, provider, status) VALUES (?,?,?,?,?,?,?)",
+		m.StudentID, m.To, m.SenderID, m.Template, m.Body, m.Provider, m.Status)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to insert message: %w", err)
+	}
+
+	id, _ := res.LastInsertId()
+	return r.getByID(ctx, int(id))
+}
+
+// MarkSent stores the provider's answer to the send call
+func (r *MessageRepository) MarkSent(ctx context.Context, id int, providerMessageID, status, errText string) (*models.SMSMessage, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.messages.MarkSent")
+	defer span.End()
+
+	if _, err := r.DB.ExecContext(ctx,
+		"UPDATE sms_messages SET provider_message_id = ?, status = ?, error = ? WHERE id = ?",
+		nullString(providerMessageID), status, nullString(errText), id); err != nil {
+		return nil, fmt.Errorf("repo: failed to update message %d: %w", id, err)
+	}
+	return r.getByID(ctx, id)
+}
+
+// UpdateStatus applies a delivery webhook and reports whether the message is ours.
+// Webhooks can arrive out of order, so a delivered message never goes back to sent.
+func (r *MessageRepository) UpdateStatus(ctx context.Context, provider, providerMessageID, status, errText string) (bool, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.messages.UpdateStatus")
+	defer span.End()
+
+	res, err := r.DB.ExecContext(ctx,
+		"UPDATE sms_messages SET status = ?, error = ? WHERE provider = ? AND provider_message_id = ? AND status <> ?",
+		status, nullString(errText), provider, providerMessageID, models.MessageDelivered)
+	if err != nil {
+		return false, fmt.Errorf("repo: failed to update message status: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return true, nil
+	}
+
+	// Nothing changed: either already delivered or not one of ours
+	var exists bool
+	err = r.DB.QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM sms_messages WHERE provider = ? AND provider_message_id = ?)",
+		provider, providerMessageID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("repo: failed to look up message: %w", err)
+	}
+	return exists, nil
+}
+
+// List returns messages newest first, optionally for one student or status
+func (r *MessageRepository) List(ctx context.Context, filter models.SMSFilter) ([]models.SMSMessage, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.messages.List")
+	defer span.End()
+
+	query := "SELECT " + messageColumns + " FROM sms_messages WHERE 1=1"
+	var args []interface{}
+	if filter.StudentID != 0 {
+		query += " AND student_id = ?"
+		args = append(args, filter.StudentID)
+	}
+	if filter.Status != "" {
+		query += " AND status = ?"
+		args = append(args, filter.Status)
+	}
+	query += " ORDER BY id DESC"
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to query messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := make([]models.SMSMessage, 0)
+	for rows.Next() {
+		var m models.SMSMessage
+		if err := scanMessage(rows, &m); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan message row: %w", err)
+		}
+		messages = append(messages, m)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return messages, nil
+}
+
+func (r *MessageRepository) getByID(ctx context.Context, id int) (*models.SMSMessage, error) {
+	var m models.SMSMessage
+	err := scanMessage(r.DB.QueryRowContext(ctx, "SELECT "+messageColumns+" FROM sms_messages WHERE id = ?", id), &m)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("repo: message %d not found: %w", id, models.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to get message %d: %w", id, err)
+	}
+	return &m, nil
+}
+
+// nullString stores "" as NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
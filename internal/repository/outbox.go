@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/tracing"
+	"time"
+)
+
+// OutboxRepository is the relay's side of the outbox table: events are
+// written by insertAudit, in the transaction of their change, and read back
+// here until they are delivered
+type OutboxRepository struct {
+	DB Conn
+}
+
+// NewOutboxRepository is the constructor
+func NewOutboxRepository(db *sql.DB) *OutboxRepository {
+	return &OutboxRepository{DB: Pool(db)}
+}
+
+// Pending returns up to limit undelivered events due for an attempt at now, oldest first
+func (r *OutboxRepository) Pending(ctx context.Context, now time.Time, limit int) ([]models.OutboxEvent, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.outbox.Pending")
+	defer span.End()
+
+	rows, err := r.DB.QueryContext(ctx,
+		`SELECT id, event_type, entity, entity_id, actor_id, data, created_at, attempts, COALESCE(last_error, '')
+		 FROM outbox WHERE delivered_at IS NULL AND next_attempt_at <= ? ORDER BY id LIMIT ?`, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to query outbox: %w", err)
+	}
+	defer rows.Close()
+
+	events := make([]models.OutboxEvent, 0)
+	for rows.Next() {
+		var e models.OutboxEvent
+		var actorID sql.NullInt64
+		var data []byte
+		if err := rows.Scan(&e.ID, &e.Type, &e.Entity, &e.EntityID, &actorID, &data, &e.OccurredAt, &e.Attempts, &e.LastError); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan outbox row: %w", err)
+		}
+		if actorID.Valid {
+			actor := int(actorID.Int64)
+			e.ActorID = &actor
+		}
+		if len(data) > 0 {
+			if err := json.Unmarshal(data, &e.Data); err != nil {
+				return nil, fmt.Errorf("repo: bad data in outbox event %d: %w", e.ID, err)
+			}
+		}
+		events = append(events, e)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return events, nil
+}
+
+// MarkDelivered records that the event was published
+func (r *OutboxRepository) MarkDelivered(ctx context.Context, id int, at time.Time) error {
+	ctx, span := tracing.StartQuery(ctx, "repo.outbox.MarkDelivered")
+	defer span.End()
+
+	if _, err := r.DB.ExecContext(ctx, "UPDATE outbox SET delivered_at = ?, last_error = NULL WHERE id = ?", at, id); err != nil {
+		return fmt.Errorf("repo: failed to mark outbox event %d delivered: %w", id, err)
+	}
+	return nil
+}
+
+// MarkFailed records a failed attempt and when to try again
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id int, reason string, retryAt time.Time) error {
+	ctx, span := tracing.StartQuery(ctx, "repo.outbox.MarkFailed")
+	defer span.End()
+
+	if len(reason) > 500 {
+		reason = reason[:500]
+	}
+	_, err := r.DB.ExecContext(ctx,
+		"UPDATE outbox SET attempts = attempts + 1, last_error = ?, next_attempt_at = ? WHERE id = ?", reason, retryAt, id)
+	if err != nil {
+		return fmt.Errorf("repo: failed to record outbox attempt %d: %w", id, err)
+	}
+	return nil
+}
+
+// PurgeDelivered deletes events delivered before cutoff and returns how many
+func (r *OutboxRepository) PurgeDelivered(ctx context.Context, cutoff time.Time) (int, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.outbox.PurgeDelivered")
+	defer span.End()
+
+	res, err := r.DB.ExecContext(ctx, "DELETE FROM outbox WHERE delivered_at < ?", cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("repo: failed to purge outbox: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("repo: failed to read rows affected: %w", err)
+	}
+	return int(n), nil
+}
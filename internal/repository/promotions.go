@@ -0,0 +1,244 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/tracing"
+)
+
+// PromotionRepository stores promotion reports (table promotion_reports).
+// Criteria, the class map and the decisions live in JSON columns: a report is
+// always read whole, and overrides rewrite its decisions under a row lock.
+type PromotionRepository struct {
+	DB Conn
+}
+
+// NewPromotionRepository is the constructor
+func NewPromotionRepository(db *sql.DB) *PromotionRepository {
+	return &PromotionRepository{DB: Pool(db)}
+}
+
+const promotionColumns = "id, year, status, criteria, next_class, decisions, created_by, created_at, applied_by, applied_at"
+
+func scanPromotion(row interface{ Scan(...any) error }, p *models.PromotionReport) error {
+	var criteria, nextClass, decisions []byte
+	var createdBy, appliedBy sql.NullInt64
+	var appliedAt sql.NullTime
+	if err := row.Scan(&p.ID, &p.Year, &p.Status, &criteria, &nextClass, &decisions,
+		&createdBy, &p.CreatedAt, &appliedBy, &appliedAt); err != nil {
+		return err
+	}
+	if err := json.Unmarshal(criteria, &p.Criteria); err != nil {
+		return fmt.Errorf("repo: bad criteria in promotion report %d: %w", p.ID, err)
+	}
+	if err := json.Unmarshal(nextClass, &p.NextClass); err != nil {
+		return fmt.Errorf("repo: bad class map in promotion report %d: %w", p.ID, err)
+	}
+	if err := json.Unmarshal(decisions, &p.Decisions); err != nil {
+		return fmt.Errorf("repo: bad decisions in promotion report %d: %w", p.ID, err)
+	}
+	if createdBy.Valid {
+		id := int(createdBy.Int64)
+		p.CreatedBy = &id
+	}
+	if appliedBy.Valid {
+		id := int(appliedBy.Int64)
+		p.AppliedBy = &id
+	}
+	if appliedAt.Valid {
+		p.AppliedAt = &appliedAt.Time
+	}
+	return nil
+}
+
+func (r *PromotionRepository) Create(ctx context.Context, p models.PromotionReport) (*models.PromotionReport, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.promotions.Create")
+	defer span.End()
+
+	criteria, err := json.Marshal(p.Criteria)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to encode criteria: %w", err)
+	}
+	nextClass, err := json.Marshal(p.NextClass)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to encode class map: %w", err)
+	}
+	decisions, err := json.Marshal(p.Decisions)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to encode decisions: %w", err)
+	}
+
+	res, err := r.DB.ExecContext(ctx,
+		"INSERT INTO promotion_reports (year, status, criteria, next_class, decisions, created_by) VALUES (?,?,?,?,?,?)",
+		p.Year, models.PromotionDraft, criteria, nextClass, decisions, p.CreatedBy)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to insert promotion report: %w", err)
+	}
+	id, _ := res.LastInsertId()
+	return r.GetByID(ctx, int(id))
+}
+
+func (r *PromotionRepository) GetByID(ctx context.Context, id int) (*models.PromotionReport, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.promotions.GetByID")
+	defer span.End()
+
+	var p models.PromotionReport
+	err := scanPromotion(r.DB.QueryRowContext(ctx, "SELECT "+promotionColumns+" FROM promotion_reports WHERE id = ?", id), &p)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("repo: promotion report %d not found: %w", id, models.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to get promotion report %d: %w", id, err)
+	}
+	return &p, nil
+}
+
+// List returns reports newest first, optionally for one academic year
+func (r *PromotionRepository) List(ctx context.Context, year string) ([]models.PromotionReport, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.promotions.List")
+	defer span.End()
+
+	query := "SELECT " + promotionColumns + " FROM promotion_reports"
+	var args []interface{}
+	if year != "" {
+		query += " WHERE year = ?"
+		args = append(args, year)
+	}
+	query += " ORDER BY id DESC"
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to query promotion reports: %w", err)
+	}
+	defer rows.Close()
+
+	reports := make([]models.PromotionReport, 0)
+	for rows.Next() {
+		var p models.PromotionReport
+		if err := scanPromotion(rows, &p); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan promotion report row: %w", err)
+		}
+		reports = append(reports, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return reports, nil
+}
+
+// Override records an admin's outcome for one student of a draft report
+func (r *PromotionRepository) Override(ctx context.Context, id, studentID int, o models.PromotionOverride, actorID *int) (*models.PromotionReport, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.promotions.Override")
+	defer span.End()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	p, err := r.getDraftTx(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := models.OverrideDecision(p, studentID, o, actorID); err != nil {
+		return nil, fmt.Errorf("repo: %w", err)
+	}
+
+	decisions, err := json.Marshal(p.Decisions)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to encode decisions: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE promotion_reports SET decisions = ? WHERE id = ?", decisions, id); err != nil {
+		return nil, fmt.Errorf("repo: failed to save override: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("repo: failed to commit override: %w", err)
+	}
+	return r.GetByID(ctx, id)
+}
+
+// Apply moves every student whose final outcome is promote to their next class
+// and marks the report applied. Students who changed class since the report was
+// made are left where they are (Moved stays false). Reviews still pending make
+// it fail with a DependencyError: every student needs a decision first.
+func (r *PromotionRepository) Apply(ctx context.Context, id int, actorID *int) (*models.PromotionReport, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.promotions.Apply")
+	defer span.End()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	p, err := r.getDraftTx(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	if n := p.Pending(); n > 0 {
+		return nil, fmt.Errorf("repo: promotion report %d has pending reviews: %w", id, &models.DependencyError{
+			Reason:   "Some students are up for review; override their decisions before applying the report",
+			Resource: "students",
+			Count:    n,
+		})
+	}
+
+	for i := range p.Decisions {
+		d := &p.Decisions[i]
+		if d.Final() != models.PromotionPromote {
+			continue
+		}
+		res, err := tx.ExecContext(ctx, "UPDATE students SET class = ? WHERE id = ? AND class = ?", d.NextClass, d.StudentID, d.Class)
+		if err != nil {
+			return nil, fmt.Errorf("repo: failed to promote student %d: %w", d.StudentID, err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			continue
+		}
+		d.Moved = true
+		if err := insertAudit(ctx, tx, models.AuditEntry{
+			ActorID:  actorID,
+			Action:   models.AuditStudentPromoted,
+			Entity:   "student",
+			EntityID: d.StudentID,
+			Details:  map[string]any{"from": d.Class, "to": d.NextClass, "report_id": id},
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	decisions, err := json.Marshal(p.Decisions)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to encode decisions: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE promotion_reports SET status = ?, decisions = ?, applied_by = ?, applied_at = NOW() WHERE id = ?",
+		models.PromotionApplied, decisions, actorID, id); err != nil {
+		return nil, fmt.Errorf("repo: failed to close promotion report: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("repo: failed to commit promotion: %w", err)
+	}
+	return r.GetByID(ctx, id)
+}
+
+// getDraftTx locks a report for update; applied reports are read-only (ErrConflict)
+func (r *PromotionRepository) getDraftTx(ctx context.Context, tx *Tx, id int) (*models.PromotionReport, error) {
+	var p models.PromotionReport
+	err := scanPromotion(tx.QueryRowContext(ctx, "SELECT "+promotionColumns+" FROM promotion_reports WHERE id = ? FOR UPDATE", id), &p)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("repo: promotion report %d not found: %w", id, models.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to get promotion report %d: %w", id, err)
+	}
+	if p.Status != models.PromotionDraft {
+		return nil, fmt.Errorf("repo: promotion report %d was already applied: %w", id, models.ErrConflict)
+	}
+	return &p, nil
+}
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/tracing"
+)
+
+// ReferenceRepository stores the school's classes and subjects (tables classes and subjects)
+type ReferenceRepository struct {
+	DB Conn
+}
+
+// NewReferenceRepository is the constructor
+func NewReferenceRepository(db *sql.DB) *ReferenceRepository {
+	return &ReferenceRepository{DB: Pool(db)}
+}
+
+// referenceTables are the lists' tables, each with a name column
+var referenceTables = []string{"classes", "subjects"}
+
+func (r *ReferenceRepository) Get(ctx context.Context) (*models.ReferenceData, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.reference.Get")
+	defer span.End()
+	return readReference(ctx, r.DB, "")
+}
+
+// queryer is a Conn or a Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+// readReference reads both lists; suffix is appended to each query, e.g. " FOR UPDATE"
+func readReference(ctx context.Context, q queryer, suffix string) (*models.ReferenceData, error) {
+	var d models.ReferenceData
+	for i, table := range referenceTables {
+		names, err := readNames(ctx, q, "SELECT name FROM "+table+" ORDER BY name"+suffix)
+		if err != nil {
+			return nil, fmt.Errorf("repo: failed to read %s: %w", table, err)
+		}
+		if i == 0 {
+			d.Classes = names
+		} else {
+			d.Subjects = names
+		}
+	}
+	return &d, nil
+}
+
+func readNames(ctx context.Context, q queryer, query string) ([]string, error) {
+	rows, err := q.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := make([]string, 0)
+	for rows.Next() {
+		var n string
+		if err := rows.Scan(&n); err != nil {
+			return nil, err
+		}
+		names = append(names, n)
+	}
+	return names, rows.Err()
+}
+
+func (r *ReferenceRepository) Replace(ctx context.Context, d models.ReferenceData, actorID *int) (*models.ReferenceData, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.reference.Replace")
+	defer span.End()
+
+	d.Normalize()
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	before, err := readReference(ctx, tx, " FOR UPDATE")
+	if err != nil {
+		return nil, err
+	}
+	for _, l := range []struct {
+		table string
+		names []string
+	}{{"classes", d.Classes}, {"subjects", d.Subjects}} {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+l.table); err != nil {
+			return nil, fmt.Errorf("repo: failed to clear %s: %w", l.table, err)
+		}
+		for _, n := range l.names {
+			if _, err := tx.ExecContext(ctx, "INSERT INTO "+l.table+" (name) VALUES (?)", n); err != nil {
+				return nil, fmt.Errorf("repo: failed to add %q to %s: %w", n, l.table, err)
+			}
+		}
+	}
+	if err := insertAudit(ctx, tx, models.AuditEntry{
+		ActorID: actorID,
+		Action:  models.AuditReferenceUpdated,
+		Entity:  "reference",
+		Details: map[string]any{"changes": models.DiffReference(*before, d)},
+	}); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("repo: failed to commit reference data: %w", err)
+	}
+	return &d, nil
+}
@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/tracing"
+)
+
+// ReportRepository runs the school-wide aggregate queries behind /reports.
+// They scan whole tables, which is why the handler caches their results.
+type ReportRepository struct {
+	DB Conn
+}
+
+// NewReportRepository is the constructor
+func NewReportRepository(db *sql.DB) *ReportRepository {
+	return &ReportRepository{DB: Pool(db)}
+}
+
+func (r *ReportRepository) Enrollment(ctx context.Context) ([]models.EnrollmentCount, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.reports.Enrollment")
+	defer span.End()
+
+	rows, err := r.DB.QueryContext(ctx, "SELECT class, COUNT(*) FROM students WHERE status = ? GROUP BY class ORDER BY class", models.StudentEnrolled)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to count enrollment: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make([]models.EnrollmentCount, 0)
+	for rows.Next() {
+		var c models.EnrollmentCount
+		if err := rows.Scan(&c.Class, &c.Students); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan enrollment row: %w", err)
+		}
+		counts = append(counts, c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return counts, nil
+}
+
+func (r *ReportRepository) Genders(ctx context.Context) ([]models.GenderCount, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.reports.Genders")
+	defer span.End()
+
+	rows, err := r.DB.QueryContext(ctx,
+		"SELECT class, COALESCE(NULLIF(gender, ''), ?) AS g, COUNT(*) FROM students WHERE status = ? GROUP BY class, g ORDER BY class, g",
+		models.GenderUnrecorded, models.StudentEnrolled)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to count genders: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make([]models.GenderCount, 0)
+	for rows.Next() {
+		var c models.GenderCount
+		if err := rows.Scan(&c.Class, &c.Gender, &c.Students); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan gender row: %w", err)
+		}
+		counts = append(counts, c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return counts, nil
+}
+
+// AttendanceRates sums the daily summaries up to their watermark and tallies
+// the marks of later days (see attendance_summary.go), in one statement so
+// both halves see the same watermark
+func (r *ReportRepository) AttendanceRates(ctx context.Context, from, to string) ([]models.AttendanceRate, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.reports.AttendanceRates")
+	defer span.End()
+
+	args := []any{from, to}
+	args = append(append(args, dailyStatuses...), from, to)
+	rows, err := r.DB.QueryContext(ctx,
+		`SELECT class, DATE_FORMAT(date, '%Y-%m') AS month, SUM(present), SUM(late), SUM(absent) FROM attendance_daily
+		 WHERE date BETWEEN ? AND ? GROUP BY class, month
+		 UNION ALL
+		 SELECT class, DATE_FORMAT(date, '%Y-%m') AS month, SUM(status = ?), SUM(status = ?), SUM(status = ?) FROM attendance
+		 WHERE date BETWEEN ? AND ? AND date > COALESCE((SELECT MAX(date) FROM attendance_daily), '1000-01-01')
+		 GROUP BY class, month
+		 ORDER BY class, month`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to tally attendance: %w", err)
+	}
+	defer rows.Close()
+
+	// Rows come sorted by class and month, so each (class, month) is one run of
+	// at most two: the summarized days and the later ones
+	rates := make([]models.AttendanceRate, 0)
+	for rows.Next() {
+		var class, month string
+		var t models.AttendanceTally
+		if err := rows.Scan(&class, &month, &t.Present, &t.Late, &t.Absent); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan attendance tally: %w", err)
+		}
+		if n := len(rates); n == 0 || rates[n-1].Class != class || rates[n-1].Month != month {
+			rates = append(rates, models.AttendanceRate{Class: class, Month: month})
+		}
+		last := &rates[len(rates)-1].AttendanceTally
+		last.Present, last.Late, last.Absent = last.Present+t.Present, last.Late+t.Late, last.Absent+t.Absent
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	for i := range rates {
+		rates[i].Rate = rates[i].AttendanceTally.Rate()
+	}
+	return rates, nil
+}
+
+func (r *ReportRepository) GradeDistribution(ctx context.Context, term string) ([]models.GradeCount, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.reports.GradeDistribution")
+	defer span.End()
+
+	query := "SELECT s.subject, COALESCE(c.grade, s.grade) AS g, COUNT(*)" + scoreSource
+	var args []any
+	if term != "" {
+		query += " WHERE s.term = ?"
+		args = append(args, term)
+	}
+	rows, err := r.DB.QueryContext(ctx, query+" GROUP BY s.subject, g ORDER BY s.subject, g", args...)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to count grades: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make([]models.GradeCount, 0)
+	for rows.Next() {
+		var c models.GradeCount
+		if err := rows.Scan(&c.Subject, &c.Grade, &c.Scores); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan grade row: %w", err)
+		}
+		counts = append(counts, c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return counts, nil
+}
+
+func (r *ReportRepository) KPIs(ctx context.Context, day string) (models.SchoolKPIs, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.reports.KPIs")
+	defer span.End()
+
+	var kpis models.SchoolKPIs
+	err := r.DB.QueryRowContext(ctx,
+		"SELECT (SELECT COUNT(*) FROM students WHERE status = ?),"+
+			" (SELECT COUNT(*) FROM teachers WHERE is_active = TRUE AND deleted_at IS NULL)",
+		models.StudentEnrolled,
+	).Scan(&kpis.Students, &kpis.Teachers)
+	if err != nil {
+		return kpis, fmt.Errorf("repo: failed to count students and teachers: %w", err)
+	}
+
+	rows, err := r.DB.QueryContext(ctx, "SELECT status, COUNT(*) FROM attendance WHERE date = ? GROUP BY status", day)
+	if err != nil {
+		return kpis, fmt.Errorf("repo: failed to tally attendance: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status string
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return kpis, fmt.Errorf("repo: failed to scan attendance tally: %w", err)
+		}
+		kpis.Attendance.Add(status, count)
+	}
+	if err = rows.Err(); err != nil {
+		return kpis, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return kpis, nil
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/tracing"
+	"time"
+)
+
+// RetentionRepository applies the data retention policy to the tables of each category
+type RetentionRepository struct {
+	DB Conn
+}
+
+// NewRetentionRepository is the constructor
+func NewRetentionRepository(db *sql.DB) *RetentionRepository {
+	return &RetentionRepository{DB: Pool(db)}
+}
+
+// retentionQueries are the count and the purge of a category's expired rows.
+// Anonymized rows no longer match the count, so a purge never redoes them.
+var retentionQueries = map[string]struct{ count, purge string }{
+	models.RetentionMessages: {
+		"SELECT COUNT(*) FROM thread_messages WHERE created_at < ? AND body <> ?",
+		"UPDATE thread_messages SET body = ?, attachments = '[]' WHERE created_at < ? AND body <> ?",
+	},
+	models.RetentionSMS: {
+		"SELECT COUNT(*) FROM sms_messages WHERE created_at < ? AND body <> ?",
+		"UPDATE sms_messages SET body = ?, to_number = '' WHERE created_at < ? AND body <> ?",
+	},
+	models.RetentionLogs: {
+		"SELECT COUNT(*) FROM audit_log WHERE created_at < ?",
+		"DELETE FROM audit_log WHERE created_at < ?",
+	},
+}
+
+// Expire anonymizes or deletes the category's records from before cutoff and
+// returns how many. A dry run only counts them. A purge that changed anything
+// writes an audit entry.
+func (r *RetentionRepository) Expire(ctx context.Context, policy models.RetentionPolicy, cutoff time.Time, dryRun bool) (int, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.retention.Expire")
+	defer span.End()
+
+	q, ok := retentionQueries[policy.Category]
+	if !ok {
+		return 0, fmt.Errorf("repo: no retention for category %q: %w", policy.Category, models.ErrInvalidInput)
+	}
+	countArgs, purgeArgs := []any{cutoff}, []any{cutoff}
+	if policy.Action == models.RetentionAnonymize {
+		countArgs = []any{cutoff, models.Redacted}
+		purgeArgs = []any{models.Redacted, cutoff, models.Redacted}
+	}
+
+	var n int
+	if dryRun {
+		if err := r.DB.QueryRowContext(ctx, q.count, countArgs...).Scan(&n); err != nil {
+			return 0, fmt.Errorf("repo: failed to count expired %s: %w", policy.Category, err)
+		}
+		return n, nil
+	}
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("repo: failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, q.purge, purgeArgs...)
+	if err != nil {
+		return 0, fmt.Errorf("repo: failed to purge expired %s: %w", policy.Category, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("repo: failed to count purged %s: %w", policy.Category, err)
+	}
+	if n = int(affected); n == 0 {
+		return 0, nil
+	}
+	if err := insertAudit(ctx, tx, retentionAuditEntry(policy, cutoff, n)); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("repo: failed to commit purge of %s: %w", policy.Category, err)
+	}
+	return n, nil
+}
+
+// retentionAuditEntry records a purge. It isn't about one entity: the details
+// say which category went and how much.
+func retentionAuditEntry(policy models.RetentionPolicy, cutoff time.Time, n int) models.AuditEntry {
+	return models.AuditEntry{
+		Action: models.AuditRetentionPurged,
+		Entity: "retention",
+		Details: map[string]any{
+			"category": policy.Category,
+			"action":   policy.Action,
+			"cutoff":   cutoff,
+			"records":  n,
+		},
+	}
+}
@@ -0,0 +1,312 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/tracing"
+)
+
+// RolloverRepository stores school-year rollover plans (table rollover_plans).
+// The class map, students, teachers and progress live in JSON columns, like
+// promotion reports: a plan is always read and rewritten whole.
+type RolloverRepository struct {
+	DB Conn
+}
+
+// NewRolloverRepository is the constructor
+func NewRolloverRepository(db *sql.DB) *RolloverRepository {
+	return &RolloverRepository{DB: Pool(db)}
+}
+
+const rolloverColumns = "id, from_year, to_year, status, alumni_class, archive, archive_id, classes, students, teachers, progress, error, " +
+	"created_by, created_at, executed_by, executed_at, finished_at"
+
+func scanRollover(row interface{ Scan(...any) error }, p *models.RolloverPlan) error {
+	var classes, students, teachers, progress []byte
+	var errText sql.NullString
+	var archiveID, createdBy, executedBy sql.NullInt64
+	var executedAt, finishedAt sql.NullTime
+	if err := row.Scan(&p.ID, &p.FromYear, &p.ToYear, &p.Status, &p.AlumniClass, &p.Archive, &archiveID,
+		&classes, &students, &teachers, &progress, &errText,
+		&createdBy, &p.CreatedAt, &executedBy, &executedAt, &finishedAt); err != nil {
+		return err
+	}
+	for _, col := range []struct {
+		name string
+		data []byte
+		dst  any
+	}{
+		{"class map", classes, &p.Classes},
+		{"students", students, &p.Students},
+		{"teachers", teachers, &p.Teachers},
+		{"progress", progress, &p.Progress},
+	} {
+		if err := json.Unmarshal(col.data, col.dst); err != nil {
+			return fmt.Errorf("repo: bad %s in rollover plan %d: %w", col.name, p.ID, err)
+		}
+	}
+	p.Error = errText.String
+	if archiveID.Valid {
+		id := int(archiveID.Int64)
+		p.ArchiveID = &id
+	}
+	if createdBy.Valid {
+		id := int(createdBy.Int64)
+		p.CreatedBy = &id
+	}
+	if executedBy.Valid {
+		id := int(executedBy.Int64)
+		p.ExecutedBy = &id
+	}
+	if executedAt.Valid {
+		p.ExecutedAt = &executedAt.Time
+	}
+	if finishedAt.Valid {
+		p.FinishedAt = &finishedAt.Time
+	}
+	return nil
+}
+
+// rolloverJSON encodes the plan's JSON columns: classes, students, teachers
+func rolloverJSON(p models.RolloverPlan) (classes, students, teachers []byte, err error) {
+	if classes, err = json.Marshal(p.Classes); err != nil {
+		return nil, nil, nil, fmt.Errorf("repo: failed to encode class map: %w", err)
+	}
+	if students, err = json.Marshal(p.Students); err != nil {
+		return nil, nil, nil, fmt.Errorf("repo: failed to encode students: %w", err)
+	}
+	if teachers, err = json.Marshal(p.Teachers); err != nil {
+		return nil, nil, nil, fmt.Errorf("repo: failed to encode teachers: %w", err)
+	}
+	return classes, students, teachers, nil
+}
+
+func (r *RolloverRepository) Create(ctx context.Context, p models.RolloverPlan) (*models.RolloverPlan, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.rollover.Create")
+	defer span.End()
+
+	classes, students, teachers, err := rolloverJSON(p)
+	if err != nil {
+		return nil, err
+	}
+	res, err := r.DB.ExecContext(ctx,
+		`INSERT INTO rollover_plans (from_year, to_year, status, alumni_class, archive, classes, students, teachers, progress, created_by)
+		 VALUES (?,?,?,?,?,?,?,?,'{}',?)`,
+		p.FromYear, p.ToYear, models.RolloverDraft, p.AlumniClass, p.Archive, classes, students, teachers, p.CreatedBy)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to insert rollover plan: %w", err)
+	}
+	id, _ := res.LastInsertId()
+	return r.GetByID(ctx, int(id))
+}
+
+func (r *RolloverRepository) GetByID(ctx context.Context, id int) (*models.RolloverPlan, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.rollover.GetByID")
+	defer span.End()
+
+	var p models.RolloverPlan
+	err := scanRollover(r.DB.QueryRowContext(ctx, "SELECT "+rolloverColumns+" FROM rollover_plans WHERE id = ?", id), &p)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("repo: rollover plan %d not found: %w", id, models.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to get rollover plan %d: %w", id, err)
+	}
+	return &p, nil
+}
+
+// List returns plans newest first, optionally those rolling over one year only
+func (r *RolloverRepository) List(ctx context.Context, fromYear string) ([]models.RolloverPlan, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.rollover.List")
+	defer span.End()
+
+	query := "SELECT " + rolloverColumns + " FROM rollover_plans"
+	var args []interface{}
+	if fromYear != "" {
+		query += " WHERE from_year = ?"
+		args = append(args, fromYear)
+	}
+	query += " ORDER BY id DESC"
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to query rollover plans: %w", err)
+	}
+	defer rows.Close()
+
+	plans := make([]models.RolloverPlan, 0)
+	for rows.Next() {
+		var p models.RolloverPlan
+		if err := scanRollover(rows, &p); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan rollover plan row: %w", err)
+		}
+		plans = append(plans, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return plans, nil
+}
+
+// Save rewrites an editable plan's mapping with p's: alumni class, archive
+// switch, classes, students and teachers
+func (r *RolloverRepository) Save(ctx context.Context, p models.RolloverPlan) (*models.RolloverPlan, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.rollover.Save")
+	defer span.End()
+
+	classes, students, teachers, err := rolloverJSON(p)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := r.getEditableTx(ctx, tx, p.ID); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx,
+		"UPDATE rollover_plans SET alumni_class = ?, archive = ?, classes = ?, students = ?, teachers = ? WHERE id = ?",
+		p.AlumniClass, p.Archive, classes, students, teachers, p.ID); err != nil {
+		return nil, fmt.Errorf("repo: failed to save rollover plan %d: %w", p.ID, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("repo: failed to commit rollover plan: %w", err)
+	}
+	return r.GetByID(ctx, p.ID)
+}
+
+// Start queues an editable plan for execution. Only one plan runs at a time,
+// and a year rolls over once: another plan in flight, or one of the same year
+// done already, fail it with ErrConflict.
+func (r *RolloverRepository) Start(ctx context.Context, id int, actorID *int) (*models.RolloverPlan, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.rollover.Start")
+	defer span.End()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	p, err := r.getEditableTx(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+	var blocking int
+	if err := tx.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM rollover_plans WHERE id <> ? AND (status IN (?, ?) OR (status = ? AND from_year = ?)) FOR UPDATE",
+		id, models.JobPending, models.JobRunning, models.JobDone, p.FromYear).Scan(&blocking); err != nil {
+		return nil, fmt.Errorf("repo: failed to check running rollovers: %w", err)
+	}
+	if blocking > 0 {
+		return nil, fmt.Errorf("repo: another rollover is in progress or %s rolled over already: %w", p.FromYear, models.ErrConflict)
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE rollover_plans SET status = ?, progress = '{}', error = NULL, executed_by = ?, executed_at = NOW(), finished_at = NULL
+		 WHERE id = ?`,
+		models.JobPending, actorID, id); err != nil {
+		return nil, fmt.Errorf("repo: failed to start rollover plan %d: %w", id, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("repo: failed to commit rollover start: %w", err)
+	}
+	return r.GetByID(ctx, id)
+}
+
+func (r *RolloverRepository) MarkRunning(ctx context.Context, id int) error {
+	ctx, span := tracing.StartQuery(ctx, "repo.rollover.MarkRunning")
+	defer span.End()
+
+	if _, err := r.DB.ExecContext(ctx,
+		"UPDATE rollover_plans SET status = ? WHERE id = ? AND status = ?",
+		models.JobRunning, id, models.JobPending); err != nil {
+		return fmt.Errorf("repo: failed to start rollover plan %d: %w", id, err)
+	}
+	return nil
+}
+
+// Progress records how far the running plan got
+func (r *RolloverRepository) Progress(ctx context.Context, id int, progress models.RolloverProgress) error {
+	ctx, span := tracing.StartQuery(ctx, "repo.rollover.Progress")
+	defer span.End()
+
+	data, err := json.Marshal(progress)
+	if err != nil {
+		return fmt.Errorf("repo: failed to encode progress: %w", err)
+	}
+	if _, err := r.DB.ExecContext(ctx,
+		"UPDATE rollover_plans SET progress = ? WHERE id = ? AND status = ?", data, id, models.JobRunning); err != nil {
+		return fmt.Errorf("repo: failed to record progress of rollover plan %d: %w", id, err)
+	}
+	return nil
+}
+
+// Finish records the outcome of the job: result's status (done or failed),
+// error, archive, progress and students, whose Moved flags say who moved.
+// Like archives, finished plans are never touched by the job again.
+func (r *RolloverRepository) Finish(ctx context.Context, id int, result models.RolloverPlan) error {
+	ctx, span := tracing.StartQuery(ctx, "repo.rollover.Finish")
+	defer span.End()
+
+	students, err := json.Marshal(result.Students)
+	if err != nil {
+		return fmt.Errorf("repo: failed to encode students: %w", err)
+	}
+	progress, err := json.Marshal(result.Progress)
+	if err != nil {
+		return fmt.Errorf("repo: failed to encode progress: %w", err)
+	}
+	if _, err := r.DB.ExecContext(ctx,
+		`UPDATE rollover_plans SET status = ?, error = ?, archive_id = ?, progress = ?, students = ?, finished_at = NOW()
+		 WHERE id = ? AND status IN (?, ?)`,
+		result.Status, nullString(result.Error), result.ArchiveID, progress, students,
+		id, models.JobPending, models.JobRunning); err != nil {
+		return fmt.Errorf("repo: failed to finish rollover plan %d: %w", id, err)
+	}
+	return nil
+}
+
+// FailUnfinished marks every pending or running plan failed, at startup. The
+// job rolls back everything but the archive when it stops midway, so those
+// plans can simply be executed again.
+func (r *RolloverRepository) FailUnfinished(ctx context.Context, reason string) (int, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.rollover.FailUnfinished")
+	defer span.End()
+
+	res, err := r.DB.ExecContext(ctx,
+		"UPDATE rollover_plans SET status = ?, error = ?, finished_at = NOW() WHERE status IN (?, ?)",
+		models.JobFailed, reason, models.JobPending, models.JobRunning)
+	if err != nil {
+		return 0, fmt.Errorf("repo: failed to fail unfinished rollover plans: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("repo: failed to read rows affected: %w", err)
+	}
+	return int(n), nil
+}
+
+// getEditableTx locks a plan for update; only draft and failed plans can
+// change or be executed (ErrConflict otherwise)
+func (r *RolloverRepository) getEditableTx(ctx context.Context, tx *Tx, id int) (*models.RolloverPlan, error) {
+	var p models.RolloverPlan
+	err := scanRollover(tx.QueryRowContext(ctx, "SELECT "+rolloverColumns+" FROM rollover_plans WHERE id = ? FOR UPDATE", id), &p)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("repo: rollover plan %d not found: %w", id, models.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to get rollover plan %d: %w", id, err)
+	}
+	if !p.Editable() {
+		return nil, fmt.Errorf("repo: rollover plan %d is %s: %w", id, p.Status, models.ErrConflict)
+	}
+	return &p, nil
+}
@@ -0,0 +1,141 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/tracing"
+)
+
+// SchoolRepository stores the school's profile (table school, one row with id 1)
+type SchoolRepository struct {
+	DB Conn
+}
+
+// NewSchoolRepository is the constructor
+func NewSchoolRepository(db *sql.DB) *SchoolRepository {
+	return &SchoolRepository{DB: Pool(db)}
+}
+
+const selectSchool = `SELECT name, address, contact_email, timezone, current_term, has_logo,
+	quota_students, quota_storage_bytes, quota_requests_per_minute, attendance_notice_below, attendance_escalate_below,
+	attendance_window_days, attendance_min_days, updated_at, updated_by FROM school WHERE id = 1`
+
+func (r *SchoolRepository) Get(ctx context.Context) (*models.School, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.school.Get")
+	defer span.End()
+	return scanSchool(r.DB.QueryRowContext(ctx, selectSchool))
+}
+
+func scanSchool(row *sql.Row) (*models.School, error) {
+	var s models.School
+	var updatedAt sql.NullTime
+	var updatedBy sql.NullInt64
+	err := row.Scan(&s.Name, &s.Address, &s.ContactEmail, &s.Timezone, &s.CurrentTerm, &s.HasLogo,
+		&s.Quotas.MaxStudents, &s.Quotas.MaxStorageBytes, &s.Quotas.RequestsPerMinute,
+		&s.AttendanceThresholds.NoticeBelow, &s.AttendanceThresholds.EscalateBelow,
+		&s.AttendanceThresholds.WindowDays, &s.AttendanceThresholds.MinDays, &updatedAt, &updatedBy)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("repo: the school profile is not set: %w", models.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to get the school profile: %w", err)
+	}
+	if updatedAt.Valid {
+		s.UpdatedAt = &updatedAt.Time
+	}
+	if updatedBy.Valid {
+		id := int(updatedBy.Int64)
+		s.UpdatedBy = &id
+	}
+	return &s, nil
+}
+
+func (r *SchoolRepository) Save(ctx context.Context, s models.School, actorID *int) (*models.School, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.school.Save")
+	defer span.End()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// The first save has nothing to diff against
+	var before models.School
+	if current, err := scanSchool(tx.QueryRowContext(ctx, selectSchool+" FOR UPDATE")); err == nil {
+		before = *current
+	} else if !errors.Is(err, models.ErrNotFound) {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO school (id, name, address, contact_email, timezone, current_term, has_logo,
+		   quota_students, quota_storage_bytes, quota_requests_per_minute, attendance_notice_below,
+		   attendance_escalate_below, attendance_window_days, attendance_min_days, updated_by)
+		 VALUES (1, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		 ON DUPLICATE KEY UPDATE name = VALUES(name), address = VALUES(address), contact_email = VALUES(contact_email),
+		   timezone = VALUES(timezone), current_term = VALUES(current_term), has_logo = VALUES(has_logo),
+		   quota_students = VALUES(quota_students), quota_storage_bytes = VALUES(quota_storage_bytes),
+		   quota_requests_per_minute = VALUES(quota_requests_per_minute),
+		   attendance_notice_below = VALUES(attendance_notice_below), attendance_escalate_below = VALUES(attendance_escalate_below),
+		   attendance_window_days = VALUES(attendance_window_days), attendance_min_days = VALUES(attendance_min_days),
+		   updated_by = VALUES(updated_by)`,
+		s.Name, s.Address, s.ContactEmail, s.Timezone, s.CurrentTerm, s.HasLogo,
+		s.Quotas.MaxStudents, s.Quotas.MaxStorageBytes, s.Quotas.RequestsPerMinute,
+		s.AttendanceThresholds.NoticeBelow, s.AttendanceThresholds.EscalateBelow,
+		s.AttendanceThresholds.WindowDays, s.AttendanceThresholds.MinDays, actorID)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to save the school profile: %w", err)
+	}
+	if err := insertAudit(ctx, tx, models.AuditEntry{
+		ActorID:  actorID,
+		Action:   models.AuditSchoolUpdated,
+		Entity:   "school",
+		EntityID: 1,
+		Details:  map[string]any{"changes": models.DiffSchool(before, s)},
+	}); err != nil {
+		return nil, err
+	}
+	saved, err := scanSchool(tx.QueryRowContext(ctx, selectSchool))
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("repo: failed to commit the school profile: %w", err)
+	}
+	return saved, nil
+}
+
+// Usage counts the students and the bytes of uploads the scanner hasn't had
+// deleted, which is what the quotas cap
+func (r *SchoolRepository) Usage(ctx context.Context) (models.QuotaUsage, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.school.Usage")
+	defer span.End()
+
+	var u models.QuotaUsage
+	err := r.DB.QueryRowContext(ctx,
+		"SELECT (SELECT COUNT(*) FROM students), (SELECT COALESCE(SUM(size), 0) FROM uploads WHERE status <> ?)",
+		models.ScanRejected).Scan(&u.Students, &u.StorageBytes)
+	if err != nil {
+		return u, fmt.Errorf("repo: failed to measure quota usage: %w", err)
+	}
+	return u, nil
+}
+
+// quotaLimit reads one of the school's quota columns, locking the row so
+// that concurrent creations are checked one after the other. With no profile
+// saved there is no limit.
+func quotaLimit(ctx context.Context, tx *Tx, column string) (int64, error) {
+	var limit int64
+	err := tx.QueryRowContext(ctx, "SELECT "+column+" FROM school WHERE id = 1 FOR UPDATE").Scan(&limit)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("repo: failed to read the %s quota: %w", column, err)
+	}
+	return limit, nil
+}
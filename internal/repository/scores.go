@@ -0,0 +1,275 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/tracing"
+)
+
+// ScoreRepository stores raw scores and their computed grades (table student_scores)
+// and their corrections (table grade_corrections). Neither table is ever updated:
+// the effective score is the original row overlaid with its latest correction.
+type ScoreRepository struct {
+	DB Conn
+}
+
+// NewScoreRepository is the constructor
+func NewScoreRepository(db *sql.DB) *ScoreRepository {
+	return &ScoreRepository{DB: Pool(db)}
+}
+
+// scoreColumns and scoreSource read effective scores: c is the latest correction, if any
+const scoreColumns = "s.id, s.student_id, s.teacher_id, s.term, s.subject, " +
+	"COALESCE(c.score, s.score), COALESCE(c.grade, s.grade), COALESCE(c.scheme_id, s.scheme_id), " +
+	"(SELECT COUNT(*) FROM grade_corrections n WHERE n.score_id = s.id), COALESCE(c.created_at, s.updated_at)"
+
+const scoreSource = " FROM student_scores s LEFT JOIN grade_corrections c" +
+	" ON c.id = (SELECT MAX(l.id) FROM grade_corrections l WHERE l.score_id = s.id)"
+
+func scanScore(row interface{ Scan(...any) error }, s *models.StudentScore) error {
+	return row.Scan(&s.ID, &s.StudentID, &s.TeacherID, &s.Term, &s.Subject, &s.Score, &s.Grade, &s.SchemeID, &s.Corrections, &s.UpdatedAt)
+}
+
+const correctionColumns = "id, score_id, score, grade, scheme_id, reason, approved_by, created_at"
+
+func scanCorrection(row interface{ Scan(...any) error }, c *models.GradeCorrection) error {
+	return row.Scan(&c.ID, &c.ScoreID, &c.Score, &c.Grade, &c.SchemeID, &c.Reason, &c.ApprovedBy, &c.CreatedAt)
+}
+
+// Create records a score. The unique key uq_student_scores_term (student_id, term, subject)
+// turns a second score for the same term and subject into a ConflictError.
+func (r *ScoreRepository) Create(ctx context.Context, s models.StudentScore) (*models.StudentScore, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.scores.Create")
+	defer span.End()
+
+	res, err := r.DB.ExecContext(ctx,
+		"INSERT INTO student_scores (student_id, teacher_id, term, subject, score, grade, scheme_id) VALUES (?,?,?,?,?,?,?)",
+		s.StudentID, s.TeacherID, s.Term, s.Subject, s.Score, s.Grade, s.SchemeID)
+	if err != nil {
+		if conflict := asDuplicateEntry(err); conflict != nil {
+			return nil, fmt.Errorf("repo: score already recorded: %w", conflict)
+		}
+		return nil, fmt.Errorf("repo: failed to save score: %w", err)
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to get score ID: %w", err)
+	}
+	return r.GetByID(ctx, int(id))
+}
+
+// GetByID returns the effective score
+func (r *ScoreRepository) GetByID(ctx context.Context, id int) (*models.StudentScore, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.scores.GetByID")
+	defer span.End()
+
+	var s models.StudentScore
+	err := scanScore(r.DB.QueryRowContext(ctx, "SELECT "+scoreColumns+scoreSource+" WHERE s.id = ?", id), &s)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("repo: score %d not found: %w", id, models.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to get score %d: %w", id, err)
+	}
+	return &s, nil
+}
+
+// ListByStudent returns a student's scores, optionally for one term only
+func (r *ScoreRepository) ListByStudent(ctx context.Context, studentID int, term string) ([]models.StudentScore, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.scores.ListByStudent")
+	defer span.End()
+
+	query := "SELECT " + scoreColumns + scoreSource + " WHERE s.student_id = ?"
+	args := []interface{}{studentID}
+	if term != "" {
+		query += " AND s.term = ?"
+		args = append(args, term)
+	}
+	query += " ORDER BY s.term, s.subject"
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to query scores: %w", err)
+	}
+	defer rows.Close()
+
+	scores := make([]models.StudentScore, 0)
+	for rows.Next() {
+		var s models.StudentScore
+		if err := scanScore(rows, &s); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan score row: %w", err)
+		}
+		scores = append(scores, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return scores, nil
+}
+
+// ListByYear returns the scores of every term of an academic year ("2025/26"
+// matches "2025/26-T1", "2025/26-T2", ...), ordered for export
+func (r *ScoreRepository) ListByYear(ctx context.Context, year string) ([]models.StudentScore, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.scores.ListByYear")
+	defer span.End()
+
+	// LIKE narrows it down; models.AcademicYear has the final say on what belongs to the year
+	rows, err := r.DB.QueryContext(ctx,
+		"SELECT "+scoreColumns+scoreSource+" WHERE s.term = ? OR s.term LIKE CONCAT(?, '-%') ORDER BY s.student_id, s.term, s.subject",
+		year, year)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to query scores: %w", err)
+	}
+	defer rows.Close()
+
+	scores := make([]models.StudentScore, 0)
+	for rows.Next() {
+		var s models.StudentScore
+		if err := scanScore(rows, &s); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan score row: %w", err)
+		}
+		if models.AcademicYear(s.Term) == year {
+			scores = append(scores, s)
+		}
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return scores, nil
+}
+
+// AddCorrection inserts a correction. The score row is locked so corrections of
+// the same score are numbered in the order they were approved.
+func (r *ScoreRepository) AddCorrection(ctx context.Context, c models.GradeCorrection) (*models.GradeCorrection, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.scores.AddCorrection")
+	defer span.End()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var scoreID int
+	err = tx.QueryRowContext(ctx, "SELECT id FROM student_scores WHERE id = ? FOR UPDATE", c.ScoreID).Scan(&scoreID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("repo: score %d not found: %w", c.ScoreID, models.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to lock score %d: %w", c.ScoreID, err)
+	}
+
+	res, err := tx.ExecContext(ctx,
+		"INSERT INTO grade_corrections (score_id, score, grade, scheme_id, reason, approved_by) VALUES (?,?,?,?,?,?)",
+		c.ScoreID, c.Score, c.Grade, c.SchemeID, c.Reason, c.ApprovedBy)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to save correction of score %d: %w", c.ScoreID, err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to get correction ID: %w", err)
+	}
+
+	var saved models.GradeCorrection
+	if err := scanCorrection(tx.QueryRowContext(ctx, "SELECT "+correctionColumns+" FROM grade_corrections WHERE id = ?", id), &saved); err != nil {
+		return nil, fmt.Errorf("repo: failed to read back correction %d: %w", id, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("repo: failed to commit correction: %w", err)
+	}
+	return &saved, nil
+}
+
+func (r *ScoreRepository) ListCorrections(ctx context.Context, scoreID int) ([]models.GradeCorrection, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.scores.ListCorrections")
+	defer span.End()
+
+	rows, err := r.DB.QueryContext(ctx,
+		"SELECT "+correctionColumns+" FROM grade_corrections WHERE score_id = ? ORDER BY id", scoreID)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to query corrections: %w", err)
+	}
+	defer rows.Close()
+
+	corrections := make([]models.GradeCorrection, 0)
+	for rows.Next() {
+		var c models.GradeCorrection
+		if err := scanCorrection(rows, &c); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan correction row: %w", err)
+		}
+		corrections = append(corrections, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return corrections, nil
+}
+
+// classScores is the effective scores of the students now in a class, in a
+// subject and term, as a common table expression named class_scores
+const classScores = "WITH class_scores AS (SELECT s.student_id, st.first_name, st.last_name," +
+	" COALESCE(c.score, s.score) AS score, COALESCE(c.grade, s.grade) AS grade" + scoreSource +
+	" JOIN students st ON st.id = s.student_id WHERE st.class = ? AND s.subject = ? AND s.term = ?) "
+
+// ClassStats computes the figures in MySQL (8.0 or later, for the window
+// functions), so only the summary and one row per student leave the database
+func (r *ScoreRepository) ClassStats(ctx context.Context, class, subject, term string) (*models.ClassGradeStats, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.scores.ClassStats")
+	defer span.End()
+
+	stats := models.ClassGradeStats{Class: class, Subject: subject, Term: term}
+	// The median is the middle score, or the mean of the two middle ones
+	err := r.DB.QueryRowContext(ctx, classScores+", ordered AS ("+
+		"SELECT score, ROW_NUMBER() OVER (ORDER BY score) AS n, COUNT(*) OVER () AS total FROM class_scores) "+
+		"SELECT COUNT(*), COALESCE(MIN(score), 0), COALESCE(MAX(score), 0), COALESCE(AVG(score), 0), COALESCE(STDDEV_POP(score), 0), "+
+		"COALESCE((SELECT AVG(score) FROM ordered WHERE n IN (FLOOR((total + 1) / 2), CEIL((total + 1) / 2))), 0) "+
+		"FROM class_scores", class, subject, term).
+		Scan(&stats.Scores, &stats.Min, &stats.Max, &stats.Mean, &stats.StdDev, &stats.Median)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to summarize %s scores of class %s: %w", subject, class, err)
+	}
+
+	rows, err := r.DB.QueryContext(ctx, classScores+
+		"SELECT grade, COUNT(*) FROM class_scores GROUP BY grade ORDER BY grade", class, subject, term)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to count grades of class %s: %w", class, err)
+	}
+	defer rows.Close()
+	stats.Distribution = make([]models.GradeTally, 0)
+	for rows.Next() {
+		var t models.GradeTally
+		if err := rows.Scan(&t.Grade, &t.Scores); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan grade row: %w", err)
+		}
+		stats.Distribution = append(stats.Distribution, t)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+
+	ranks, err := r.DB.QueryContext(ctx, classScores+
+		"SELECT student_id, first_name, last_name, score, grade, RANK() OVER (ORDER BY score DESC) AS place "+
+		"FROM class_scores ORDER BY place, last_name, first_name, student_id", class, subject, term)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to rank class %s: %w", class, err)
+	}
+	defer ranks.Close()
+	stats.Students = make([]models.StudentRank, 0)
+	for ranks.Next() {
+		var s models.StudentRank
+		if err := ranks.Scan(&s.StudentID, &s.FirstName, &s.LastName, &s.Score, &s.Grade, &s.Rank); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan rank row: %w", err)
+		}
+		stats.Students = append(stats.Students, s)
+	}
+	if err = ranks.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return &stats, nil
+}
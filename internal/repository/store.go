@@ -0,0 +1,463 @@
+package repository
+
+import (
+	"context"
+	"simpleapi/internal/models"
+	"simpleapi/internal/query"
+	"time"
+)
+
+// TeacherStore is everything the handlers and middlewares need from teacher persistence.
+// Both the MySQL TeacherRepository and the in-memory one (package memory) satisfy it.
+type TeacherStore interface {
+	// GetAll and Count take Where over models.TeacherFields and Fields of the
+	// listing columns (id, names, email, phone, class, subject)
+	GetAll(ctx context.Context, opts query.Options) ([]models.Teacher, error)
+	Count(ctx context.Context, opts query.Options) (int, error)
+	GetByID(ctx context.Context, id int) (*models.Teacher, error)
+	// GetByEmail returns the credential fields too (password hash, role, is_active) for login
+	GetByEmail(ctx context.Context, email string) (*models.Teacher, error)
+	// GetStudents lists the roster of the teacher's class, every student in
+	// it with includeInactive (see models.ClassRoster)
+	GetStudents(ctx context.Context, teacherID int, includeInactive bool) ([]models.Student, error)
+	ListDirectory(ctx context.Context) ([]models.DirectoryEntry, error)
+	SetDirectoryListing(ctx context.Context, id int, published bool) error
+	// SetPreferences stores the teacher's language and time zone (see package locale)
+	SetPreferences(ctx context.Context, id int, prefs models.Preferences) error
+	CreateBulk(ctx context.Context, teachers []models.Teacher) ([]models.Teacher, error)
+	// Conflicts lists the teachers' emails that are taken already, trashed
+	// teachers included, as CreateBulk would find them
+	Conflicts(ctx context.Context, teachers []models.Teacher) ([]models.ConflictError, error)
+	// CreateFirstAdmin bootstraps a fresh install: it adds an admin only while
+	// there are no teachers at all, and fails with ErrConflict after that
+	CreateFirstAdmin(ctx context.Context, t models.Teacher) (*models.Teacher, error)
+	UpdateFull(ctx context.Context, id int, update models.Teacher, actorID *int) (*models.Teacher, error)
+	UpdatePasswordHash(ctx context.Context, id int, hash string) error
+	// RequirePasswordChange makes the teacher change their password before
+	// anything else (Protect enforces it); reason is audited, e.g. "breached"
+	RequirePasswordChange(ctx context.Context, id int, reason string) error
+	// ChangePassword stores the new hash, lifts RequirePasswordChange and
+	// revokes the sessions issued before changedAt
+	ChangePassword(ctx context.Context, id int, hash string, changedAt time.Time) error
+	Patch(ctx context.Context, id int, updates map[string]interface{}, actorID *int) (*models.Teacher, error)
+	// BulkPatch and BulkDelete run every check but persist nothing when dryRun is set
+	BulkPatch(ctx context.Context, updates []map[string]interface{}, actorID *int, dryRun bool) ([]models.TeacherChange, error)
+	Delete(ctx context.Context, id int, opts models.DeleteTeacherOptions) (bool, error)
+	BulkDelete(ctx context.Context, ids []int, actorID *int, dryRun bool) ([]int, error)
+	SetActiveBulk(ctx context.Context, ids []int, active bool, actorID *int) ([]int, error)
+	// Delete and BulkDelete are soft deletes; these manage the trash they fill
+	ListDeleted(ctx context.Context) ([]models.Teacher, error)
+	Restore(ctx context.Context, id int, actorID *int) (*models.Teacher, error)
+	PurgeDeleted(ctx context.Context, cutoff time.Time) (int, error)
+	// ExpireResetTokens clears password-reset tokens that expired before now
+	ExpireResetTokens(ctx context.Context, now time.Time) (int, error)
+}
+
+// StudentStore is the student counterpart of TeacherStore
+type StudentStore interface {
+	// GetAll and Count take Where over models.StudentFields (and custom fields)
+	GetAll(ctx context.Context, opts query.Options) ([]models.Student, error)
+	Count(ctx context.Context, opts query.Options) (int, error)
+	// CountByClass counts the enrolled students of each class
+	CountByClass(ctx context.Context) (map[string]int, error)
+	GetByID(ctx context.Context, id int) (*models.Student, error)
+	// FindByKey matches an admission number first, then an email
+	FindByKey(ctx context.Context, key string) (*models.Student, error)
+	// CreateBulk fails with a models.QuotaError when the students don't fit the school's quota
+	CreateBulk(ctx context.Context, students []models.Student) ([]models.Student, error)
+	// Conflicts lists the students' emails and admission numbers that are taken already
+	Conflicts(ctx context.Context, students []models.Student) ([]models.ConflictError, error)
+	// SetCustomFields replaces the student's custom field values
+	SetCustomFields(ctx context.Context, id int, values map[string]any, actorID *int) (*models.Student, error)
+	// MoveClass moves a student still in class from to class to, auditing it
+	// as entry says (action, actor, details); false when they are in from no more
+	MoveClass(ctx context.Context, id int, from, to string, entry models.AuditEntry) (bool, error)
+	// SetStatus moves a student in status from to req's status (and class,
+	// when it names one), auditing it with the reason. A student no longer
+	// in from is a models.ErrConflict; package enrollment says which moves are allowed.
+	SetStatus(ctx context.Context, id int, from string, req models.StudentStatusRequest, actorID *int) (*models.Student, error)
+}
+
+// CommentStore persists report-card comments
+type CommentStore interface {
+	Create(ctx context.Context, c models.StudentComment) (*models.StudentComment, error)
+	ListByStudent(ctx context.Context, studentID int, term string, includeFlagged bool) ([]models.StudentComment, error)
+	SetFlag(ctx context.Context, studentID, id int, mod models.CommentModeration) (*models.StudentComment, error)
+}
+
+// GradingStore persists grading schemes
+type GradingStore interface {
+	List(ctx context.Context) ([]models.GradingScheme, error)
+	GetByID(ctx context.Context, id int) (*models.GradingScheme, error)
+	// ForSubject falls back to the school-wide default when the subject has no scheme of its own
+	ForSubject(ctx context.Context, subject string) (*models.GradingScheme, error)
+	Create(ctx context.Context, s models.GradingScheme) (*models.GradingScheme, error)
+	Update(ctx context.Context, id int, s models.GradingScheme) (*models.GradingScheme, error)
+	Delete(ctx context.Context, id int) (bool, error)
+	// ReplaceAll makes the stored schemes exactly these (see schoolconfig)
+	ReplaceAll(ctx context.Context, schemes []models.GradingScheme) error
+}
+
+// ScoreStore persists raw scores with the grade computed when they were posted.
+// Scores are append-only: a change is a GradeCorrection, and every read returns
+// the effective value.
+type ScoreStore interface {
+	// Create fails with a ConflictError if the student already has a score for the term and subject
+	Create(ctx context.Context, s models.StudentScore) (*models.StudentScore, error)
+	GetByID(ctx context.Context, id int) (*models.StudentScore, error)
+	ListByStudent(ctx context.Context, studentID int, term string) ([]models.StudentScore, error)
+	// ListByYear returns every score of an academic year (all its terms), see models.AcademicYear
+	ListByYear(ctx context.Context, year string) ([]models.StudentScore, error)
+	// AddCorrection appends a correction to a score, ErrNotFound if the score doesn't exist
+	AddCorrection(ctx context.Context, c models.GradeCorrection) (*models.GradeCorrection, error)
+	// ListCorrections returns a score's corrections, oldest first
+	ListCorrections(ctx context.Context, scoreID int) ([]models.GradeCorrection, error)
+	// ClassStats summarizes the scores in a subject and term of the students now in class
+	ClassStats(ctx context.Context, class, subject, term string) (*models.ClassGradeStats, error)
+}
+
+// MessageStore records outgoing SMS and their delivery status
+type MessageStore interface {
+	Create(ctx context.Context, m models.SMSMessage) (*models.SMSMessage, error)
+	MarkSent(ctx context.Context, id int, providerMessageID, status, errText string) (*models.SMSMessage, error)
+	// UpdateStatus applies a delivery webhook; false means no such message
+	UpdateStatus(ctx context.Context, provider, providerMessageID, status, errText string) (bool, error)
+	List(ctx context.Context, filter models.SMSFilter) ([]models.SMSMessage, error)
+}
+
+// EventStore persists the school calendar
+type EventStore interface {
+	List(ctx context.Context, filter models.EventFilter) ([]models.Event, error)
+	GetByID(ctx context.Context, id int) (*models.Event, error)
+	Create(ctx context.Context, e models.Event) (*models.Event, error)
+	Update(ctx context.Context, id int, e models.Event) (*models.Event, error)
+	Delete(ctx context.Context, id int) (bool, error)
+}
+
+// AuditStore reads the audit trail; entries are written by the stores that
+// make the changes, except for events that change nothing (Record)
+type AuditStore interface {
+	ListByEntity(ctx context.Context, entity string, id int) ([]models.AuditEntry, error)
+	Record(ctx context.Context, entry models.AuditEntry) error
+	Stream(ctx context.Context, since time.Time, fn func(models.AuditEntry) error) error
+}
+
+// AttendanceStore persists class registers and the late arrivals and early
+// departures checked in at kiosks
+type AttendanceStore interface {
+	SaveRegister(ctx context.Context, reg models.AttendanceRegister) (*models.AttendanceRegister, error)
+	GetRegister(ctx context.Context, class, date string) (*models.AttendanceRegister, error)
+	SubmittedClasses(ctx context.Context, date string) ([]string, error)
+	ListAbsences(ctx context.Context, from, to string) ([]models.Absence, error)
+	TallyByStudent(ctx context.Context, from, to string) (map[int]models.AttendanceTally, error)
+	// RecordMovement saves a kiosk check-in or check-out; ErrConflict if the
+	// student already has one of that kind that day
+	RecordMovement(ctx context.Context, m models.AttendanceMovement) (*models.AttendanceMovement, error)
+	ListMovements(ctx context.Context, date string) ([]models.AttendanceMovement, error)
+	// Alerts returns the level each student was last alerted at (table
+	// attendance_alerts), by student; those without an alert are left out
+	Alerts(ctx context.Context) (map[int]models.AttendanceAlert, error)
+	// SetAlert records a student's alert, or clears it when a.Level is AttendanceAlertNone
+	SetAlert(ctx context.Context, a models.AttendanceAlert) error
+}
+
+// PromotionStore keeps year-end promotion reports. Apply moves the promoted
+// students to their next class and closes the report, in one go.
+type PromotionStore interface {
+	Create(ctx context.Context, report models.PromotionReport) (*models.PromotionReport, error)
+	GetByID(ctx context.Context, id int) (*models.PromotionReport, error)
+	List(ctx context.Context, year string) ([]models.PromotionReport, error)
+	// Override and Apply fail with ErrConflict once the report has been applied
+	Override(ctx context.Context, id, studentID int, o models.PromotionOverride, actorID *int) (*models.PromotionReport, error)
+	Apply(ctx context.Context, id int, actorID *int) (*models.PromotionReport, error)
+}
+
+// RolloverStore keeps school-year rollover plans. Save and Start take only
+// draft or failed plans (ErrConflict otherwise); the rollover job reports
+// through MarkRunning, Progress and Finish like the archive job.
+type RolloverStore interface {
+	Create(ctx context.Context, p models.RolloverPlan) (*models.RolloverPlan, error)
+	GetByID(ctx context.Context, id int) (*models.RolloverPlan, error)
+	List(ctx context.Context, fromYear string) ([]models.RolloverPlan, error)
+	Save(ctx context.Context, p models.RolloverPlan) (*models.RolloverPlan, error)
+	// Start fails with ErrConflict while another plan is in flight, or once
+	// a plan of the same year is done
+	Start(ctx context.Context, id int, actorID *int) (*models.RolloverPlan, error)
+	MarkRunning(ctx context.Context, id int) error
+	Progress(ctx context.Context, id int, progress models.RolloverProgress) error
+	Finish(ctx context.Context, id int, result models.RolloverPlan) error
+	FailUnfinished(ctx context.Context, reason string) (int, error)
+}
+
+// ExamStore keeps exam sessions (table exam_sessions). A session is read and
+// saved whole, its rooms, seats and invigilators included.
+type ExamStore interface {
+	Create(ctx context.Context, s models.ExamSession) (*models.ExamSession, error)
+	GetByID(ctx context.Context, id int) (*models.ExamSession, error)
+	// List returns the sessions on date (DateLayout), or every session when
+	// "", in the order they start
+	List(ctx context.Context, date string) ([]models.ExamSession, error)
+	Save(ctx context.Context, s models.ExamSession) (*models.ExamSession, error)
+	Delete(ctx context.Context, id int) error
+}
+
+// LetterTemplateStore keeps the letter templates (table letter_templates).
+// Changes are audited; deleting a template keeps the documents printed from it.
+type LetterTemplateStore interface {
+	Create(ctx context.Context, t models.LetterTemplate) (*models.LetterTemplate, error)
+	GetByID(ctx context.Context, id int) (*models.LetterTemplate, error)
+	// List returns the templates of kind, or of every kind when "", by name
+	List(ctx context.Context, kind string) ([]models.LetterTemplate, error)
+	Update(ctx context.Context, id int, req models.LetterTemplateRequest, actorID *int) (*models.LetterTemplate, error)
+	Delete(ctx context.Context, id int, actorID *int) error
+}
+
+// DocumentStore keeps the files generated for students (table documents);
+// the files themselves are in the uploads storage
+type DocumentStore interface {
+	// Create records a document, audited as document.created
+	Create(ctx context.Context, d models.Document) (*models.Document, error)
+	GetByID(ctx context.Context, id int) (*models.Document, error)
+	// ListByStudent returns the student's documents, newest first
+	ListByStudent(ctx context.Context, studentID int) ([]models.Document, error)
+	// MarkEmailed records that the document was sent to address at
+	MarkEmailed(ctx context.Context, id int, address string, at time.Time) error
+}
+
+// ThreadStore persists guardian messaging threads. Unread counts are per
+// viewer: messages after the last one the viewer read, not counting their own.
+type ThreadStore interface {
+	Create(ctx context.Context, t models.Thread, first models.ThreadMessage) (*models.Thread, error)
+	GetByID(ctx context.Context, id, viewerID int) (*models.Thread, error)
+	List(ctx context.Context, filter models.ThreadFilter, viewerID int) ([]models.Thread, error)
+	AddMessage(ctx context.Context, m models.ThreadMessage) (*models.ThreadMessage, error)
+	GetMessage(ctx context.Context, threadID, id int) (*models.ThreadMessage, error)
+	ListMessages(ctx context.Context, threadID int) ([]models.ThreadMessage, error)
+	MarkRead(ctx context.Context, threadID, userID int) error
+}
+
+// ArchiveStore tracks academic-year archives; the background job that builds
+// a bundle reports its progress through MarkRunning and Finish
+type ArchiveStore interface {
+	Create(ctx context.Context, year string, createdBy *int) (*models.YearArchive, error)
+	GetByID(ctx context.Context, id int) (*models.YearArchive, error)
+	List(ctx context.Context, year string) ([]models.YearArchive, error)
+	MarkRunning(ctx context.Context, id int) error
+	Finish(ctx context.Context, id int, result models.YearArchive) error
+	FailUnfinished(ctx context.Context, reason string) (int, error)
+}
+
+// BackupStore tracks database backups, driven by the backup job like ArchiveStore
+type BackupStore interface {
+	Create(ctx context.Context, createdBy *int) (*models.Backup, error)
+	GetByID(ctx context.Context, id int) (*models.Backup, error)
+	List(ctx context.Context) ([]models.Backup, error)
+	MarkRunning(ctx context.Context, id int) error
+	Finish(ctx context.Context, id int, result models.Backup) error
+	FailUnfinished(ctx context.Context, reason string) (int, error)
+}
+
+// UploadStore tracks uploaded files through their virus scan. RecordScan only
+// moves pending uploads and Review only quarantined ones, otherwise ErrConflict.
+type UploadStore interface {
+	// Create fails with a models.QuotaError when the file doesn't fit the school's storage quota
+	Create(ctx context.Context, u models.Upload) (*models.Upload, error)
+	GetByID(ctx context.Context, id int) (*models.Upload, error)
+	List(ctx context.Context, filter models.UploadFilter) ([]models.Upload, error)
+	Statuses(ctx context.Context, ids []int) (map[int]string, error)
+	RecordScan(ctx context.Context, id int, status, threat string) (*models.Upload, error)
+	Review(ctx context.Context, id int, status string, reviewerID int, note string) (*models.Upload, error)
+	Delete(ctx context.Context, id int) error
+}
+
+// ClassAssignmentStore keeps the classes each teacher is assigned to (table
+// teacher_classes), on top of the class they are class teacher of
+type ClassAssignmentStore interface {
+	ClassesOf(ctx context.Context, teacherID int) ([]string, error)
+	// SetClasses replaces the teacher's assignments
+	SetClasses(ctx context.Context, teacherID int, classes []string) error
+}
+
+// EmailChangeStore keeps teachers' email changes. A change is applied only
+// once its confirm token comes back, and the old address's undo token can stop
+// or revert it; Confirm and Undo fail with ErrEmailChangeLinkInvalid for an
+// unknown, expired or spent token. Each step writes an audit entry.
+type EmailChangeStore interface {
+	// Create records a pending change from the teacher's current email (set in
+	// the result), cancelling their earlier pending one; ConflictError if the new email is taken
+	Create(ctx context.Context, c models.EmailChange) (*models.EmailChange, error)
+	Confirm(ctx context.Context, tokenHash string, now time.Time) (*models.EmailChange, error)
+	Undo(ctx context.Context, tokenHash string, now time.Time) (*models.EmailChange, error)
+}
+
+// RetentionStore applies the data retention policy (see models.RetentionPolicy)
+type RetentionStore interface {
+	// Expire anonymizes or deletes the category's records from before cutoff
+	// and returns how many, or only counts them on a dry run
+	Expire(ctx context.Context, policy models.RetentionPolicy, cutoff time.Time, dryRun bool) (int, error)
+}
+
+// CustomFieldStore keeps the custom field definitions (see models.CustomField)
+type CustomFieldStore interface {
+	List(ctx context.Context, entity string) ([]models.CustomField, error)
+	// Create fails with ErrConflict if the entity already has a field with the key
+	Create(ctx context.Context, f models.CustomField, actorID *int) (*models.CustomField, error)
+	// Delete removes the definition and every value stored for it
+	Delete(ctx context.Context, id int, actorID *int) error
+}
+
+// SchoolStore keeps the school's profile, a single row
+type SchoolStore interface {
+	// Get fails with ErrNotFound until the profile is first saved
+	Get(ctx context.Context) (*models.School, error)
+	// Save writes the whole profile, audited as a school.updated entry
+	Save(ctx context.Context, s models.School, actorID *int) (*models.School, error)
+	// Usage measures what the quotas (models.SchoolQuotas) cap
+	Usage(ctx context.Context) (models.QuotaUsage, error)
+}
+
+// ReferenceStore keeps the school's classes and subjects (tables classes and subjects)
+type ReferenceStore interface {
+	Get(ctx context.Context) (*models.ReferenceData, error)
+	// Replace makes the lists exactly d (normalized), audited as a reference.updated entry
+	Replace(ctx context.Context, d models.ReferenceData, actorID *int) (*models.ReferenceData, error)
+}
+
+// ApprovalStore keeps approval requests (see package approvals). Transition
+// moves an approval only out of state from, failing with ErrConflict once it
+// has left it, so two approvers can't both decide one. Each step is audited.
+type ApprovalStore interface {
+	Create(ctx context.Context, a models.Approval) (*models.Approval, error)
+	GetByID(ctx context.Context, id int) (*models.Approval, error)
+	// List returns the matching approvals, newest first
+	List(ctx context.Context, filter models.ApprovalFilter) ([]models.Approval, error)
+	// Transition records actorID as the decider, or clears the decision when to is pending
+	Transition(ctx context.Context, id int, from, to string, actorID int, comment string) (*models.Approval, error)
+}
+
+// MedicalStore keeps students' medical records (table student_medical). Reads
+// are audited like changes, in the same transaction: a record that can't be
+// logged isn't returned.
+type MedicalStore interface {
+	// Get returns the records of the students in ids that have one, in no
+	// particular order, audited as read by actorID for reason (models.MedicalRead*)
+	Get(ctx context.Context, ids []int, actorID *int, reason string) ([]models.MedicalRecord, error)
+	// Save creates or replaces a student's record; ErrNotFound if there's no such student
+	Save(ctx context.Context, rec models.MedicalRecord, actorID *int) (*models.MedicalRecord, error)
+}
+
+// TripStore keeps school trips and their guardians' consents (tables trips,
+// trip_consents). Consents are keyed by trip and student; answers are audited.
+type TripStore interface {
+	// Create records a trip, audited as trip.created
+	Create(ctx context.Context, t models.Trip) (*models.Trip, error)
+	GetByID(ctx context.Context, id int) (*models.Trip, error)
+	// List returns the trips of class, or of every class when "", soonest first
+	List(ctx context.Context, class string) ([]models.Trip, error)
+	Consents(ctx context.Context, tripID int) ([]models.TripConsent, error)
+	// Request asks for consents: each one is created pending, or when still
+	// pending gets the new token hash. It returns those it asked for: one
+	// answered meanwhile is left alone.
+	Request(ctx context.Context, consents []models.TripConsent) ([]models.TripConsent, error)
+	// ConsentByToken returns the consent a link's token hash belongs to, or ErrConsentLinkInvalid
+	ConsentByToken(ctx context.Context, tokenHash string) (*models.TripConsent, error)
+	// Answer records c's status. With a TokenHash it answers only the consent
+	// holding that hash (ErrConsentLinkInvalid if none); without one, staff
+	// answer for any student, asked or not.
+	Answer(ctx context.Context, c models.TripConsent, actorID *int) (*models.TripConsent, error)
+}
+
+// CampaignStore keeps bulk email campaigns and their recipients. The send
+// job reports through MarkRunning, RecordDelivery and Finish, like ArchiveStore.
+type CampaignStore interface {
+	// Create records a pending campaign with its recipients, audited as campaign.created
+	Create(ctx context.Context, c models.Campaign, recipients []models.CampaignRecipient) (*models.Campaign, error)
+	GetByID(ctx context.Context, id int) (*models.Campaign, error)
+	// List returns campaigns newest first
+	List(ctx context.Context) ([]models.Campaign, error)
+	// Recipients returns a campaign's recipients in the order they were resolved
+	Recipients(ctx context.Context, campaignID int) ([]models.CampaignRecipient, error)
+	MarkRunning(ctx context.Context, id int) error
+	// RecordDelivery sets a pending recipient's outcome: sent when sendErr is "", failed otherwise
+	RecordDelivery(ctx context.Context, recipientID int, sendErr string) error
+	Finish(ctx context.Context, id int, status, errText string) error
+	FailUnfinished(ctx context.Context, reason string) (int, error)
+}
+
+// ReportStore runs the aggregate queries of the /reports endpoints. Students
+// are counted while enrolled.
+type ReportStore interface {
+	Enrollment(ctx context.Context) ([]models.EnrollmentCount, error)
+	Genders(ctx context.Context) ([]models.GenderCount, error)
+	// AttendanceRates tallies the marks between from and to (DateLayout, inclusive) per class and month
+	AttendanceRates(ctx context.Context, from, to string) ([]models.AttendanceRate, error)
+	// SummarizeAttendance materializes the daily tallies of the classes' days
+	// before before (DateLayout) that have none yet, returning how many it added
+	SummarizeAttendance(ctx context.Context, before string) (int, error)
+	// GradeDistribution counts effective grades per subject, of one term or of all if term is ""
+	GradeDistribution(ctx context.Context, term string) ([]models.GradeCount, error)
+	// KPIs counts enrolled students, active teachers and the attendance marks of day (DateLayout)
+	KPIs(ctx context.Context, day string) (models.SchoolKPIs, error)
+}
+
+// OutboxStore is what the outbox relay reads and updates. Events are never
+// added through it: insertAudit writes them with the change they describe.
+type OutboxStore interface {
+	// Pending returns up to limit undelivered events due at now, oldest first
+	Pending(ctx context.Context, now time.Time, limit int) ([]models.OutboxEvent, error)
+	MarkDelivered(ctx context.Context, id int, at time.Time) error
+	// MarkFailed counts a failed attempt and holds the event back until retryAt
+	MarkFailed(ctx context.Context, id int, reason string, retryAt time.Time) error
+	// PurgeDelivered deletes events delivered before cutoff
+	PurgeDelivered(ctx context.Context, cutoff time.Time) (int, error)
+}
+
+// Repos are the stores a unit of work composes. Grow it as services need more.
+type Repos struct {
+	Teachers         TeacherStore
+	Students         StudentStore
+	ClassAssignments ClassAssignmentStore
+}
+
+// UnitOfWork runs cross-entity operations (create a class, enroll its students,
+// assign its teacher) as one transaction: fn gets Repos bound to it, and every
+// change they make commits if fn returns nil and rolls back if it returns an
+// error. Store methods inside fn keep their own all-or-nothing guarantee.
+type UnitOfWork interface {
+	Do(ctx context.Context, fn func(ctx context.Context, repos Repos) error) error
+}
+
+// Compile-time checks that the MySQL repositories implement the stores
+var (
+	_ TeacherStore    = (*TeacherRepository)(nil)
+	_ StudentStore    = (*StudentRepositoty)(nil)
+	_ CommentStore    = (*CommentRepository)(nil)
+	_ GradingStore    = (*GradingRepository)(nil)
+	_ ScoreStore      = (*ScoreRepository)(nil)
+	_ MessageStore    = (*MessageRepository)(nil)
+	_ EventStore      = (*EventRepository)(nil)
+	_ AuditStore      = (*AuditRepository)(nil)
+	_ AttendanceStore = (*AttendanceRepository)(nil)
+	_ ArchiveStore    = (*ArchiveRepository)(nil)
+	_ PromotionStore  = (*PromotionRepository)(nil)
+	_ ThreadStore     = (*ThreadRepository)(nil)
+	_ BackupStore     = (*BackupRepository)(nil)
+	_ UploadStore     = (*UploadRepository)(nil)
+
+	_ ClassAssignmentStore = (*ClassAssignmentRepository)(nil)
+	_ EmailChangeStore     = (*EmailChangeRepository)(nil)
+	_ RetentionStore       = (*RetentionRepository)(nil)
+	_ ReportStore          = (*ReportRepository)(nil)
+	_ CustomFieldStore     = (*CustomFieldRepository)(nil)
+	_ OutboxStore          = (*OutboxRepository)(nil)
+	_ SchoolStore          = (*SchoolRepository)(nil)
+	_ ReferenceStore       = (*ReferenceRepository)(nil)
+	_ ApprovalStore        = (*ApprovalRepository)(nil)
+	_ CampaignStore        = (*CampaignRepository)(nil)
+	_ MedicalStore         = (*MedicalRepository)(nil)
+	_ TripStore            = (*TripRepository)(nil)
+	_ RolloverStore        = (*RolloverRepository)(nil)
+	_ ExamStore            = (*ExamRepository)(nil)
+	_ LetterTemplateStore  = (*LetterTemplateRepository)(nil)
+	_ DocumentStore        = (*DocumentRepository)(nil)
+)
@@ -3,28 +3,115 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"maps"
 	"simpleapi/internal/models"
-	"strings"
+	"simpleapi/internal/query"
+	"simpleapi/internal/tracing"
 )
 
 type StudentRepositoty struct {
-	DB *sql.DB
+	DB Conn
 }
 
 func NewStudentRepository(db *sql.DB) *StudentRepositoty {
-	return &StudentRepositoty{DB: db}
+	return &StudentRepositoty{DB: Pool(db)}
 }
 
-func (r *StudentRepositoty) GetAll(ctx context.Context, filter models.StudentFilter) ([]models.Student, error) {
-	query := "SELECT id, first_name, last_name,email, class FROM students WHERE 1=1"
-	var args []interface{}
+// WithTx is the repository bound to tx, for composing it into a larger
+// transaction (see UnitOfWork)
+func (r *StudentRepositoty) WithTx(tx *sql.Tx) *StudentRepositoty {
+	return &StudentRepositoty{DB: bound(tx)}
+}
+
+// studentTable is what listings read. Unknown admission numbers read as "".
+var studentTable = query.Table{
+	Name:    "students",
+	Columns: query.Columns(models.StudentListFields, map[string]string{"admission_number": "COALESCE(admission_number, '')"}),
+	Sorts:   models.StudentSorts,
+}
+
+// studentColumns is shared by every query that returns whole students (see scanStudent)
+var studentColumns, studentFields = studentTable.SelectList(), studentTable.Fields()
+
+// scanStudent reads one studentColumns row
+func scanStudent(row interface{ Scan(...any) error }, s *models.Student) error {
+	return scanStudentFields(row, s, studentFields)
+}
+
+// scanStudentFields reads a row of the given studentTable fields; unknown
+// dates come back as ""
+func scanStudentFields(row interface{ Scan(...any) error }, s *models.Student, fields []string) error {
+	var dob, enrolled sql.NullTime
+	var custom []byte
+	dest := make([]any, len(fields))
+	for i, field := range fields {
+		switch field {
+		case "id":
+			dest[i] = &s.ID
+		case "first_name":
+			dest[i] = &s.FirstName
+		case "last_name":
+			dest[i] = &s.LastName
+		case "email":
+			dest[i] = &s.Email
+		case "class":
+			dest[i] = &s.Class
+		case "admission_number":
+			dest[i] = &s.AdmissionNumber
+		case "guardian_phone":
+			dest[i] = &s.GuardianPhone
+		case "status":
+			dest[i] = &s.Status
+		case "date_of_birth":
+			dest[i] = &dob
+		case "gender":
+			dest[i] = &s.Gender
+		case "nationality":
+			dest[i] = &s.Nationality
+		case "enrollment_date":
+			dest[i] = &enrolled
+		case "custom_fields":
+			dest[i] = &custom
+		}
+	}
+	if err := row.Scan(dest...); err != nil {
+		return err
+	}
+	if dob.Valid {
+		s.DateOfBirth = dob.Time.Format(models.DateLayout)
+	}
+	if enrolled.Valid {
+		s.EnrollmentDate = enrolled.Time.Format(models.DateLayout)
+	}
+	if len(custom) > 0 {
+		if err := json.Unmarshal(custom, &s.CustomFields); err != nil {
+			return fmt.Errorf("bad custom fields of student %d: %w", s.ID, err)
+		}
+	}
+	return nil
+}
 
-	query, args = r.addFilter(filter, query, args)
-	query = r.addSorts(filter, query)
+// customFieldsJSON encodes custom field values for the custom_fields column, NULL when there are none
+func customFieldsJSON(values map[string]any) ([]byte, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(values)
+}
+
+func (r *StudentRepositoty) GetAll(ctx context.Context, opts query.Options) ([]models.Student, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.students.GetAll")
+	defer span.End()
+
+	stmt, args, fields, err := studentTable.Select(opts)
+	if err != nil {
+		return nil, fmt.Errorf("repo: %w", err)
+	}
 
 	// the context ctx serves as a kill switch for operations; if user closes the browser kill the request; or you can manually set a timeout for the context- this is purely server side kill switch for DB operations;
-	rows, err := r.DB.QueryContext(ctx, query, args...)
+	rows, err := r.DB.QueryContext(ctx, stmt, args...)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to query students: %w", err)
 	}
@@ -36,7 +123,7 @@ func (r *StudentRepositoty) GetAll(ctx context.Context, filter models.StudentFil
 
 	for rows.Next() {
 		var student models.Student
-		if err := rows.Scan(&student.ID, &student.FirstName, &student.LastName, &student.Email, &student.Class); err != nil {
+		if err := scanStudentFields(rows, &student, fields); err != nil {
 			return nil, fmt.Errorf("Failed to scan teacher row: %w", err)
 		}
 		students = append(students, student)
@@ -49,17 +136,58 @@ func (r *StudentRepositoty) GetAll(ctx context.Context, filter models.StudentFil
 
 }
 
+// Count honors the same filter as GetAll but lets MySQL do the counting
+func (r *StudentRepositoty) Count(ctx context.Context, opts query.Options) (int, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.students.Count")
+	defer span.End()
+
+	stmt, args := studentTable.Count(opts)
+	var count int
+	if err := r.DB.QueryRowContext(ctx, stmt, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("Failed to count students: %w", err)
+	}
+	return count, nil
+}
+
+// CountByClass returns a class -> number of enrolled students map
+func (r *StudentRepositoty) CountByClass(ctx context.Context) (map[string]int, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.students.CountByClass")
+	defer span.End()
+
+	rows, err := r.DB.QueryContext(ctx, "SELECT class, COUNT(*) FROM students WHERE status = ? GROUP BY class", models.StudentEnrolled)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to count students by class: %w", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var class string
+		var count int
+		if err := rows.Scan(&class, &count); err != nil {
+			return nil, fmt.Errorf("Failed to scan class count: %w", err)
+		}
+		counts[class] = count
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("Error iterating rows: %w", err)
+	}
+	return counts, nil
+}
+
 func (r *StudentRepositoty) GetByID(ctx context.Context, id int) (*models.Student, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.students.GetByID")
+	defer span.End()
+
 	var s models.Student
-	query := "SELECT id, first_name, last_name, email, class FROM students WHERE id = ?"
+	query := "SELECT " + studentColumns + " FROM students WHERE id = ?"
 
-	err := r.DB.QueryRowContext(ctx, query, id).Scan(
-		&s.ID, &s.FirstName, &s.LastName, &s.Email, &s.Class,
-	)
+	err := scanStudent(r.DB.QueryRowContext(ctx, query, id), &s)
 
 	// 1. Translation: DB "No Rows" -> Domain "Not Found"
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("Student %d not found: %w", id, models.ErrNotFound)
+		return nil, fmt.Errorf("Student %d not found: %w", id, models.ErrStudentNotFound)
 	}
 	// 2. System Error
 	if err != nil {
@@ -68,14 +196,79 @@ func (r *StudentRepositoty) GetByID(ctx context.Context, id int) (*models.Studen
 	return &s, nil
 }
 
+// FindByKey looks a student up by admission number or, failing that, by email
+func (r *StudentRepositoty) FindByKey(ctx context.Context, key string) (*models.Student, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.students.FindByKey")
+	defer span.End()
+
+	var s models.Student
+	query := `SELECT ` + studentColumns + ` FROM students
+			  WHERE admission_number = ? OR email = ?
+			  ORDER BY admission_number = ? DESC LIMIT 1`
+
+	err := scanStudent(r.DB.QueryRowContext(ctx, query, key, key, key), &s)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("Student %s not found: %w", key, models.ErrStudentNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to find student %s: %w", key, err)
+	}
+	return &s, nil
+}
+
+func (r *StudentRepositoty) Conflicts(ctx context.Context, students []models.Student) ([]models.ConflictError, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.students.Conflicts")
+	defer span.End()
+
+	var emails, admissions []any
+	for _, s := range students {
+		if s.Email != "" {
+			emails = append(emails, s.Email)
+		}
+		if s.AdmissionNumber != "" {
+			admissions = append(admissions, s.AdmissionNumber)
+		}
+	}
+	conflicts, err := takenValues(ctx, r.DB, "students", "email", "email", emails)
+	if err != nil {
+		return nil, err
+	}
+	taken, err := takenValues(ctx, r.DB, "students", "admission_number", "admission_number", admissions)
+	if err != nil {
+		return nil, err
+	}
+	return append(conflicts, taken...), nil
+}
+
 func (r *StudentRepositoty) CreateBulk(ctx context.Context, students []models.Student) ([]models.Student, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.students.CreateBulk")
+	defer span.End()
+
 	tx, err := r.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	stmt, err := tx.PrepareContext(ctx, "INSERT INTO students (first_name, last_name, email, class) VALUES(?,?,?,?)")
+	maxStudents, err := quotaLimit(ctx, tx, "quota_students")
+	if err != nil {
+		return nil, err
+	}
+	if maxStudents > 0 {
+		var count int64
+		if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM students").Scan(&count); err != nil {
+			return nil, fmt.Errorf("Failed to count students: %w", err)
+		}
+		if count+int64(len(students)) > maxStudents {
+			return nil, &models.QuotaError{Quota: models.QuotaStudents, Used: count, Requested: int64(len(students)), Limit: maxStudents}
+		}
+	}
+
+	// NULLIF keeps the unique index on admission_number happy for students without one,
+	// and stores unknown dates as NULL
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO students
+		(first_name, last_name, email, class, admission_number, guardian_phone, status, date_of_birth, gender, nationality, enrollment_date, custom_fields)
+		VALUES(?,?,?,?,NULLIF(?,''),?,?,NULLIF(?,''),?,?,NULLIF(?,''),?)`)
 
 	if err != nil {
 		return nil, fmt.Errorf("Failed to prepare statement: %w", err)
@@ -85,24 +278,42 @@ func (r *StudentRepositoty) CreateBulk(ctx context.Context, students []models.St
 
 	result := make([]models.Student, len(students))
 	for i, s := range students {
-		res, err := stmt.ExecContext(ctx, s.FirstName, s.LastName, s.Email, s.Class)
+		if s.Status == "" {
+			s.Status = models.StudentEnrolled
+		}
+		custom, err := customFieldsJSON(s.CustomFields)
+		if err != nil {
+			return nil, &models.ItemError{Index: i, Err: fmt.Errorf("Failed to encode custom fields: %w", err)}
+		}
+		res, err := stmt.ExecContext(ctx, s.FirstName, s.LastName, s.Email, s.Class, s.AdmissionNumber, s.GuardianPhone, s.Status,
+			s.DateOfBirth, s.Gender, s.Nationality, s.EnrollmentDate, custom)
 		if err != nil {
 			// Pro Tip: Check for MySQL duplicate entry error (Error 1062)
-			if strings.Contains(err.Error(), "Duplicate entry") {
-				return nil, fmt.Errorf("Duplicate email %s: %w", s.Email, models.ErrConflict)
+			if conflict := asDuplicateEntry(err); conflict != nil {
+				return nil, &models.ItemError{Index: i, Err: fmt.Errorf("Failed to insert student: %w", conflict)}
 			}
-			// Check for Foreign Key Constraint Failure (Error 1452)
-			if strings.Contains(err.Error(), "1452") {
-				// We map this to ErrConflict or ErrInvalidInput depending on your preference
-				return nil, fmt.Errorf("cannot assign student to class '%s' (class does not exist): %w", s.Class, err)
+			// The handler checks the class against the school's classes first
+			// (models.ReferenceData); a foreign key on class is the backstop
+			if isMissingReference(err) {
+				return nil, &models.ItemError{Index: i, Err: fmt.Errorf("cannot assign student to class '%s' (class does not exist): %w", s.Class, models.ErrInvalidInput)}
 			}
 
-			return nil, fmt.Errorf("Failed to insert student: %w", err)
+			return nil, &models.ItemError{Index: i, Err: fmt.Errorf("Failed to insert student: %w", err)}
 		}
 
 		id, _ := res.LastInsertId()
 		s.ID = int(id)
 		result[i] = s
+
+		// The initial values open the student's change history
+		if err := insertAudit(ctx, tx, models.AuditEntry{
+			Action:   models.AuditStudentCreated,
+			Entity:   "student",
+			EntityID: s.ID,
+			Details:  map[string]any{"changes": models.DiffStudent(models.Student{}, s)},
+		}); err != nil {
+			return nil, err
+		}
 	}
 
 	if err := tx.Commit(); err != nil {
@@ -111,35 +322,139 @@ func (r *StudentRepositoty) CreateBulk(ctx context.Context, students []models.St
 	return result, nil
 }
 
-func (r *StudentRepositoty) addSorts(filter models.StudentFilter, query string) string {
-	validSorts := map[string]bool{"first_name": true, "last_name": true, "email": true, "class": true}
-	if validSorts[filter.SortBy] {
-		order := "ASC"
-		if strings.ToUpper(filter.SortOrder) == "DESC" {
-			order = "DESC"
+// SetCustomFields replaces the student's custom field values, auditing what changed
+func (r *StudentRepositoty) SetCustomFields(ctx context.Context, id int, values map[string]any, actorID *int) (*models.Student, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.students.SetCustomFields")
+	defer span.End()
+
+	custom, err := customFieldsJSON(values)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to encode custom fields: %w", err)
+	}
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var before models.Student
+	err = scanStudent(tx.QueryRowContext(ctx, "SELECT "+studentColumns+" FROM students WHERE id = ? FOR UPDATE", id), &before)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("repo: student %d not found: %w", id, models.ErrStudentNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to get student %d: %w", id, err)
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE students SET custom_fields = ? WHERE id = ?", custom, id); err != nil {
+		return nil, fmt.Errorf("repo: failed to update custom fields of student %d: %w", id, err)
+	}
+
+	after := before
+	after.CustomFields = values
+	if changes := models.DiffStudent(before, after); len(changes) > 0 {
+		if err := insertAudit(ctx, tx, models.AuditEntry{
+			ActorID:  actorID,
+			Action:   models.AuditStudentUpdated,
+			Entity:   "student",
+			EntityID: id,
+			Details:  map[string]any{"changes": changes},
+		}); err != nil {
+			return nil, err
 		}
-		query += fmt.Sprintf(" ORDER BY %s %s", filter.SortBy, order)
 	}
-	return query
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("repo: failed to commit custom fields: %w", err)
+	}
+	return &after, nil
 }
 
-func (r *StudentRepositoty) addFilter(filter models.StudentFilter, query string, args []interface{}) (string, []interface{}) {
-	if filter.FirstName != "" {
-		query += " AND first_name = ?"
-		args = append(args, filter.FirstName)
+// MoveClass moves the student from one class to another with its audit entry,
+// whose details gain "from" and "to". A student who changed class meanwhile is
+// left alone.
+func (r *StudentRepositoty) MoveClass(ctx context.Context, id int, from, to string, entry models.AuditEntry) (bool, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.students.MoveClass")
+	defer span.End()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("repo: failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "UPDATE students SET class = ? WHERE id = ? AND class = ?", to, id, from)
+	if err != nil {
+		return false, fmt.Errorf("repo: failed to move student %d: %w", id, err)
 	}
-	if filter.LastName != "" {
-		query += " AND last_name = ?"
-		args = append(args, filter.LastName)
+	if n, _ := res.RowsAffected(); n == 0 {
+		return false, nil
 	}
-	if filter.Email != "" {
-		query += " AND email = ?"
-		args = append(args, filter.Email)
+	entry.Entity, entry.EntityID = "student", id
+	entry.Details = maps.Clone(entry.Details)
+	if entry.Details == nil {
+		entry.Details = make(map[string]any)
 	}
-	if filter.Class != "" {
-		query += " AND class = ?"
-		args = append(args, filter.Class)
+	entry.Details["from"], entry.Details["to"] = from, to
+	if err := insertAudit(ctx, tx, entry); err != nil {
+		return false, err
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("repo: failed to commit class move: %w", err)
 	}
+	return true, nil
+}
+
+// SetStatus moves the student from one lifecycle state to another, and to
+// req.Class when it names one, auditing the move with its reason
+func (r *StudentRepositoty) SetStatus(ctx context.Context, id int, from string, req models.StudentStatusRequest, actorID *int) (*models.Student, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.students.SetStatus")
+	defer span.End()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
 
-	return query, args
+	var before models.Student
+	err = scanStudent(tx.QueryRowContext(ctx, "SELECT "+studentColumns+" FROM students WHERE id = ? FOR UPDATE", id), &before)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("repo: student %d not found: %w", id, models.ErrStudentNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to get student %d: %w", id, err)
+	}
+	if before.Status != from {
+		return nil, fmt.Errorf("repo: student %d is %s, not %s: %w", id, before.Status, from, models.ErrConflict)
+	}
+
+	after := before
+	after.Status = req.Status
+	if req.Class != "" {
+		after.Class = req.Class
+	}
+	if _, err := tx.ExecContext(ctx, "UPDATE students SET status = ?, class = ? WHERE id = ?", after.Status, after.Class, id); err != nil {
+		if isMissingReference(err) {
+			return nil, fmt.Errorf("cannot assign student to class '%s' (class does not exist): %w", after.Class, models.ErrInvalidInput)
+		}
+		return nil, fmt.Errorf("repo: failed to update status of student %d: %w", id, err)
+	}
+
+	details := map[string]any{"from": from, "to": after.Status, "reason": req.Reason}
+	if after.Class != before.Class {
+		details["class"] = models.FieldChange{From: before.Class, To: after.Class}
+	}
+	if err := insertAudit(ctx, tx, models.AuditEntry{
+		ActorID:  actorID,
+		Action:   models.AuditStudentStatusChanged,
+		Entity:   "student",
+		EntityID: id,
+		Details:  details,
+	}); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("repo: failed to commit status change: %w", err)
+	}
+	return &after, nil
 }
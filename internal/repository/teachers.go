@@ -5,29 +5,72 @@ import (
 	"database/sql"
 	"fmt"
 	"simpleapi/internal/models"
+	"simpleapi/internal/query"
+	"simpleapi/internal/tracing"
 	"strings"
+	"time"
 )
 
 // TeacherRepository holds the dependency (the DB connection)
 type TeacherRepository struct {
-	DB *sql.DB
+	DB Conn
 }
 
 // NewTeacherRepository is the constructor
 func NewTeacherRepository(db *sql.DB) *TeacherRepository {
-	return &TeacherRepository{DB: db}
+	return &TeacherRepository{DB: Pool(db)}
+}
+
+// WithTx is the repository bound to tx, for composing it into a larger
+// transaction (see UnitOfWork)
+func (r *TeacherRepository) WithTx(tx *sql.Tx) *TeacherRepository {
+	return &TeacherRepository{DB: bound(tx)}
 }
 
 // --- READ ---
 
-func (r *TeacherRepository) GetAll(ctx context.Context, filter models.TeacherFilter) ([]models.Teacher, error) {
-	query := "SELECT id, first_name, last_name, email, class, subject FROM teachers WHERE 1=1"
-	var args []interface{}
+// teacherTable is what listings read: soft-deleted teachers live in the
+// trash (see ListDeleted) and are invisible everywhere else
+var teacherTable = query.Table{
+	Name:    "teachers",
+	Where:   "deleted_at IS NULL",
+	Columns: query.Columns(models.TeacherListFields, nil),
+	Sorts:   models.TeacherSorts,
+}
 
-	query, args = r.addFilter(filter, query, args)
-	query = r.addSorts(filter, query)
+// scanTeacher reads a row of the given teacherTable fields
+func scanTeacher(row interface{ Scan(...any) error }, t *models.Teacher, fields []string) error {
+	dest := make([]any, len(fields))
+	for i, field := range fields {
+		switch field {
+		case "id":
+			dest[i] = &t.ID
+		case "first_name":
+			dest[i] = &t.FirstName
+		case "last_name":
+			dest[i] = &t.LastName
+		case "email":
+			dest[i] = &t.Email
+		case "phone":
+			dest[i] = &t.Phone
+		case "class":
+			dest[i] = &t.Class
+		case "subject":
+			dest[i] = &t.Subject
+		}
+	}
+	return row.Scan(dest...)
+}
 
-	rows, err := r.DB.QueryContext(ctx, query, args...)
+func (r *TeacherRepository) GetAll(ctx context.Context, opts query.Options) ([]models.Teacher, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.teachers.GetAll")
+	defer span.End()
+
+	stmt, args, fields, err := teacherTable.Select(opts)
+	if err != nil {
+		return nil, fmt.Errorf("repo: %w", err)
+	}
+	rows, err := r.DB.QueryContext(ctx, stmt, args...)
 	if err != nil {
 		return nil, fmt.Errorf("repo: failed to query teachers: %w", err)
 	}
@@ -37,7 +80,7 @@ func (r *TeacherRepository) GetAll(ctx context.Context, filter models.TeacherFil
 	teachers := make([]models.Teacher, 0)
 	for rows.Next() {
 		var t models.Teacher
-		if err := rows.Scan(&t.ID, &t.FirstName, &t.LastName, &t.Email, &t.Class, &t.Subject); err != nil {
+		if err := scanTeacher(rows, &t, fields); err != nil {
 			return nil, fmt.Errorf("repo: failed to scan teacher row: %w", err)
 		}
 		teachers = append(teachers, t)
@@ -49,35 +92,146 @@ func (r *TeacherRepository) GetAll(ctx context.Context, filter models.TeacherFil
 	return teachers, nil
 }
 
+// Count honors the same filter as GetAll but lets MySQL do the counting
+func (r *TeacherRepository) Count(ctx context.Context, opts query.Options) (int, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.teachers.Count")
+	defer span.End()
+
+	stmt, args := teacherTable.Count(opts)
+	var count int
+	if err := r.DB.QueryRowContext(ctx, stmt, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("repo: failed to count teachers: %w", err)
+	}
+	return count, nil
+}
+
 func (r *TeacherRepository) GetByID(ctx context.Context, id int) (*models.Teacher, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.teachers.GetByID")
+	defer span.End()
+
 	var t models.Teacher
-	query := "SELECT id, first_name, last_name, email, class, subject FROM teachers WHERE id = ?"
+	var passwordChangedAt sql.NullTime
+	query := `SELECT id, first_name, last_name, email, phone, class, subject, role, is_active, published_in_directory, language, timezone,
+		force_password_change, password_changed_at, updated_at FROM teachers WHERE id = ? AND deleted_at IS NULL`
 
 	err := r.DB.QueryRowContext(ctx, query, id).Scan(
-		&t.ID, &t.FirstName, &t.LastName, &t.Email, &t.Class, &t.Subject,
+		&t.ID, &t.FirstName, &t.LastName, &t.Email, &t.Phone, &t.Class, &t.Subject, &t.Role, &t.IsActive, &t.PublishedInDirectory, &t.Language, &t.Timezone,
+		&t.ForcePasswordChange, &passwordChangedAt, &t.UpdatedAt,
 	)
 
 	// 1. Translation: DB "No Rows" -> Domain "Not Found"
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("repo: teacher %d not found: %w", id, models.ErrNotFound)
+		return nil, fmt.Errorf("repo: teacher %d not found: %w", id, models.ErrTeacherNotFound)
 	}
 	// 2. System Error
 	if err != nil {
 		return nil, fmt.Errorf("repo: failed to get teacher %d: %w", id, err)
 	}
+	// Protect compares it with the token's issue time
+	if passwordChangedAt.Valid {
+		t.PasswordChangedAt = &passwordChangedAt.Time
+	}
+	return &t, nil
+}
+
+func (r *TeacherRepository) GetByEmail(ctx context.Context, email string) (*models.Teacher, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.teachers.GetByEmail")
+	defer span.End()
+
+	var t models.Teacher
+	query := "SELECT id, first_name, last_name, password_hash, is_active, role, force_password_change FROM teachers WHERE email = ? AND deleted_at IS NULL"
+
+	err := r.DB.QueryRowContext(ctx, query, email).Scan(
+		&t.ID, &t.FirstName, &t.LastName, &t.PasswordHash, &t.IsActive, &t.Role, &t.ForcePasswordChange,
+	)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("repo: teacher with email %s not found: %w", email, models.ErrTeacherNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to get teacher by email: %w", err)
+	}
+	t.Email = email
 	return &t, nil
 }
 
+// GetStudents returns the students in the class the teacher is assigned to,
+// only the enrolled ones unless includeInactive
+func (r *TeacherRepository) GetStudents(ctx context.Context, teacherID int, includeInactive bool) ([]models.Student, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.teachers.GetStudents")
+	defer span.End()
+
+	query := `SELECT ` + studentColumns + ` FROM students
+			  WHERE class = (SELECT class FROM teachers WHERE id = ? AND deleted_at IS NULL)`
+	args := []any{teacherID}
+	if !includeInactive {
+		query += " AND status = ?"
+		args = append(args, models.StudentEnrolled)
+	}
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to query students of teacher %d: %w", teacherID, err)
+	}
+	defer rows.Close()
+
+	students := make([]models.Student, 0)
+	for rows.Next() {
+		var s models.Student
+		if err := scanStudent(rows, &s); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan student row: %w", err)
+		}
+		students = append(students, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return students, nil
+}
+
+// ListDirectory returns the public staff directory: active teachers who opted in
+func (r *TeacherRepository) ListDirectory(ctx context.Context) ([]models.DirectoryEntry, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.teachers.ListDirectory")
+	defer span.End()
+
+	query := `SELECT first_name, last_name, subject, email FROM teachers
+			  WHERE published_in_directory = TRUE AND is_active = TRUE AND deleted_at IS NULL
+			  ORDER BY last_name, first_name`
+
+	rows, err := r.DB.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to query directory: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]models.DirectoryEntry, 0)
+	for rows.Next() {
+		var e models.DirectoryEntry
+		if err := rows.Scan(&e.FirstName, &e.LastName, &e.Subject, &e.Email); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan directory row: %w", err)
+		}
+		entries = append(entries, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return entries, nil
+}
+
 // --- CREATE ---
 
 func (r *TeacherRepository) CreateBulk(ctx context.Context, teachers []models.Teacher) ([]models.Teacher, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.teachers.CreateBulk")
+	defer span.End()
+
 	tx, err := r.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("repo: failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
-	stmt, err := tx.PrepareContext(ctx, "INSERT INTO teachers (first_name, last_name, email, class, subject,password_hash) VALUES(?,?,?,?,?,?)")
+	stmt, err := tx.PrepareContext(ctx, "INSERT INTO teachers (first_name, last_name, email, phone, class, subject,password_hash) VALUES(?,?,?,?,?,?,?)")
 	if err != nil {
 		return nil, fmt.Errorf("repo: failed to prepare statement: %w", err)
 	}
@@ -85,16 +239,17 @@ func (r *TeacherRepository) CreateBulk(ctx context.Context, teachers []models.Te
 
 	result := make([]models.Teacher, len(teachers))
 	for i, t := range teachers {
-		res, err := stmt.ExecContext(ctx, t.FirstName, t.LastName, t.Email, t.Class, t.Subject, t.PasswordHash)
+		res, err := stmt.ExecContext(ctx, t.FirstName, t.LastName, t.Email, t.Phone, t.Class, t.Subject, t.PasswordHash)
 		if err != nil {
 			// Pro Tip: Check for MySQL duplicate entry error (Error 1062)
-			if strings.Contains(err.Error(), "Duplicate entry") {
-				return nil, fmt.Errorf("repo: duplicate email %s: %w", t.Email, models.ErrConflict)
+			if conflict := asDuplicateEntry(err); conflict != nil {
+				return nil, &models.ItemError{Index: i, Err: fmt.Errorf("repo: failed to insert teacher: %w", conflict)}
 			}
-			return nil, fmt.Errorf("repo: failed to insert teacher: %w", err)
+			return nil, &models.ItemError{Index: i, Err: fmt.Errorf("repo: failed to insert teacher: %w", err)}
 		}
 		id, _ := res.LastInsertId()
-		t.ID = int(id)
+		// The role column keeps its default whatever the request said
+		t.ID, t.Role = int(id), models.RoleTeacher
 		result[i] = t
 	}
 
@@ -104,116 +259,309 @@ func (r *TeacherRepository) CreateBulk(ctx context.Context, teachers []models.Te
 	return result, nil
 }
 
-// --- UPDATE & PATCH ---
+// Conflicts looks the emails up in every row, trashed teachers' included,
+// since the unique key still holds theirs
+func (r *TeacherRepository) Conflicts(ctx context.Context, teachers []models.Teacher) ([]models.ConflictError, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.teachers.Conflicts")
+	defer span.End()
+
+	emails := make([]any, 0, len(teachers))
+	for _, t := range teachers {
+		if t.Email != "" {
+			emails = append(emails, t.Email)
+		}
+	}
+	return takenValues(ctx, r.DB, "teachers", "email", "email", emails)
+}
 
-func (r *TeacherRepository) UpdateFull(ctx context.Context, id int, update models.Teacher) (*models.Teacher, error) {
-	query := "UPDATE teachers SET first_name=?, last_name=?, email=?, class=?, subject=? WHERE id=?"
-	res, err := r.DB.ExecContext(ctx, query, update.FirstName, update.LastName, update.Email, update.Class, update.Subject, id)
+// CreateFirstAdmin adds t as an admin while the table has no teachers at all,
+// deleted ones included; once it has, it fails with ErrConflict
+func (r *TeacherRepository) CreateFirstAdmin(ctx context.Context, t models.Teacher) (*models.Teacher, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.teachers.CreateFirstAdmin")
+	defer span.End()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, fmt.Errorf("repo: failed to update teacher: %w", err)
+		return nil, fmt.Errorf("repo: failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// FOR UPDATE locks the (empty) index too, so two setups can't both see no one
+	var n int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM teachers FOR UPDATE").Scan(&n); err != nil {
+		return nil, fmt.Errorf("repo: failed to count teachers: %w", err)
+	}
+	if n > 0 {
+		return nil, fmt.Errorf("repo: teachers exist already: %w", models.ErrConflict)
+	}
+
+	res, err := tx.ExecContext(ctx,
+		"INSERT INTO teachers (first_name, last_name, email, phone, class, subject, password_hash, role) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		t.FirstName, t.LastName, t.Email, t.Phone, t.Class, t.Subject, t.PasswordHash, models.RoleAdmin)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to insert the first admin: %w", err)
 	}
+	id, _ := res.LastInsertId()
 
-	rows, err := res.RowsAffected()
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("repo: failed to commit transaction: %w", err)
+	}
+	t.ID, t.Role, t.IsActive = int(id), models.RoleAdmin, true
+	t.Password, t.PasswordHash = "", ""
+	return &t, nil
+}
+
+// --- UPDATE & PATCH ---
+
+// UpdateFull replaces the editable fields and audits what actually changed
+func (r *TeacherRepository) UpdateFull(ctx context.Context, id int, update models.Teacher, actorID *int) (*models.Teacher, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.teachers.UpdateFull")
+	defer span.End()
+
+	phone, err := models.ParsePhone(update.Phone)
 	if err != nil {
-		return nil, fmt.Errorf("repo: failed to get rows affected: %w", err)
+		return nil, fmt.Errorf("repo: failed to update teacher: %w", err)
 	}
+	update.Phone = phone
 
-	// Translation: 0 rows affected -> Domain "Not Found"
-	if rows == 0 {
-		return nil, fmt.Errorf("repo: teacher %d not found: %w", id, models.ErrNotFound)
+	fields := map[string]interface{}{
+		"first_name": update.FirstName,
+		"last_name":  update.LastName,
+		"email":      update.Email,
+		"phone":      update.Phone,
+		"class":      update.Class,
+		"subject":    update.Subject,
+	}
+	if _, err := r.patchAudited(ctx, id, fields, actorID); err != nil {
+		return nil, fmt.Errorf("repo: failed to update teacher: %w", err)
 	}
 
 	update.ID = id
 	return &update, nil
 }
 
-func (r *TeacherRepository) Patch(ctx context.Context, id int, updates map[string]interface{}) (*models.Teacher, error) {
-	// Re-use GetByID (it handles Not Found logic for us!)
-	current, err := r.GetByID(ctx, id)
+func (r *TeacherRepository) SetDirectoryListing(ctx context.Context, id int, published bool) error {
+	ctx, span := tracing.StartQuery(ctx, "repo.teachers.SetDirectoryListing")
+	defer span.End()
+
+	// Re-use GetByID for the Not Found check (RowsAffected is 0 for "unchanged" too)
+	if _, err := r.GetByID(ctx, id); err != nil {
+		return err
+	}
+	if _, err := r.DB.ExecContext(ctx, "UPDATE teachers SET published_in_directory = ? WHERE id = ?", published, id); err != nil {
+		return fmt.Errorf("repo: failed to update directory listing of teacher %d: %w", id, err)
+	}
+	return nil
+}
+
+func (r *TeacherRepository) SetPreferences(ctx context.Context, id int, prefs models.Preferences) error {
+	ctx, span := tracing.StartQuery(ctx, "repo.teachers.SetPreferences")
+	defer span.End()
+
+	if _, err := r.GetByID(ctx, id); err != nil {
+		return err
+	}
+	if _, err := r.DB.ExecContext(ctx, "UPDATE teachers SET language = ?, timezone = ? WHERE id = ?", prefs.Language, prefs.Timezone, id); err != nil {
+		return fmt.Errorf("repo: failed to update preferences of teacher %d: %w", id, err)
+	}
+	return nil
+}
+
+func (r *TeacherRepository) UpdatePasswordHash(ctx context.Context, id int, hash string) error {
+	ctx, span := tracing.StartQuery(ctx, "repo.teachers.UpdatePasswordHash")
+	defer span.End()
+
+	if _, err := r.DB.ExecContext(ctx, "UPDATE teachers SET password_hash = ? WHERE id = ?", hash, id); err != nil {
+		return fmt.Errorf("repo: failed to update password hash for teacher %d: %w", id, err)
+	}
+	return nil
+}
+
+func (r *TeacherRepository) RequirePasswordChange(ctx context.Context, id int, reason string) error {
+	ctx, span := tracing.StartQuery(ctx, "repo.teachers.RequirePasswordChange")
+	defer span.End()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, err
+		return fmt.Errorf("repo: failed to begin tx: %w", err)
 	}
+	defer tx.Rollback()
 
-	query := "UPDATE teachers SET "
-	var args []interface{}
-	var columns []string
-	allowedCols := map[string]bool{"first_name": true, "last_name": true, "email": true, "class": true, "subject": true}
+	// No RowsAffected check: MySQL counts a teacher already flagged as unchanged
+	_, err = tx.ExecContext(ctx, "UPDATE teachers SET force_password_change = TRUE WHERE id = ? AND deleted_at IS NULL", id)
+	if err != nil {
+		return fmt.Errorf("repo: failed to flag the password of teacher %d: %w", id, err)
+	}
+	if err := insertAudit(ctx, tx, models.AuditEntry{
+		Action:   models.AuditPasswordFlagged,
+		Entity:   "teacher",
+		EntityID: id,
+		Details:  map[string]any{"reason": reason},
+	}); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("repo: commit failed: %w", err)
+	}
+	return nil
+}
 
-	for k, v := range updates {
-		if !allowedCols[k] {
-			continue
-		}
+func (r *TeacherRepository) ChangePassword(ctx context.Context, id int, hash string, changedAt time.Time) error {
+	ctx, span := tracing.StartQuery(ctx, "repo.teachers.ChangePassword")
+	defer span.End()
 
-		strVal, ok := v.(string)
-		if !ok {
-			return nil, fmt.Errorf("repo: field %s expected string: %w", k, models.ErrInvalidInput)
-		}
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("repo: failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
 
-		columns = append(columns, fmt.Sprintf("%s = ?", k))
-		args = append(args, strVal)
+	res, err := tx.ExecContext(ctx,
+		"UPDATE teachers SET password_hash = ?, password_changed_at = ?, force_password_change = FALSE WHERE id = ? AND deleted_at IS NULL",
+		hash, changedAt, id)
+	if err != nil {
+		return fmt.Errorf("repo: failed to change the password of teacher %d: %w", id, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("repo: teacher %d not found: %w", id, models.ErrTeacherNotFound)
+	}
+	if err := insertAudit(ctx, tx, models.AuditEntry{
+		ActorID:  &id,
+		Action:   models.AuditPasswordChanged,
+		Entity:   "teacher",
+		EntityID: id,
+	}); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("repo: commit failed: %w", err)
+	}
+	return nil
+}
 
-		// Update in-memory struct
-		switch k {
-		case "first_name":
-			current.FirstName = strVal
-		case "last_name":
-			current.LastName = strVal
-		case "email":
-			current.Email = strVal
-		case "class":
-			current.Class = strVal
-		case "subject":
-			current.Subject = strVal
-		}
+func (r *TeacherRepository) Patch(ctx context.Context, id int, updates map[string]interface{}, actorID *int) (*models.Teacher, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.teachers.Patch")
+	defer span.End()
+
+	if _, err := r.patchAudited(ctx, id, updates, actorID); err != nil {
+		return nil, fmt.Errorf("repo: failed to patch teacher: %w", err)
+	}
+	return r.GetByID(ctx, id)
+}
+
+// patchAudited applies one teacher's update in its own transaction, with an audit entry
+// listing the field-level changes (the source for GET /teachers/{id}/history)
+func (r *TeacherRepository) patchAudited(ctx context.Context, id int, updates map[string]interface{}, actorID *int) (models.TeacherChange, error) {
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return models.TeacherChange{}, fmt.Errorf("repo: failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	change, err := r.patchTx(ctx, tx, id, updates, actorID)
+	if err != nil {
+		return change, err
+	}
+	if err := tx.Commit(); err != nil {
+		return change, fmt.Errorf("repo: failed to commit tx: %w", err)
 	}
+	return change, nil
+}
 
-	if len(columns) > 0 {
-		query += strings.Join(columns, ", ") + " WHERE id = ?"
-		args = append(args, id)
-		if _, err := r.DB.ExecContext(ctx, query, args...); err != nil {
-			return nil, fmt.Errorf("repo: failed to patch teacher: %w", err)
+// patchTx locks and reads the teacher, applies the update, diffs before/after and
+// audits the diff. Reading first matters: RowsAffected can't tell "missing" from "unchanged".
+func (r *TeacherRepository) patchTx(ctx context.Context, tx *Tx, id int, updates map[string]interface{}, actorID *int) (models.TeacherChange, error) {
+	before, err := r.getPatchableTx(ctx, tx, id, true)
+	if err != nil {
+		return models.TeacherChange{}, err
+	}
+	// Sending the current email back is fine; a new one goes through the email change flow
+	if email, ok := updates["email"].(string); ok && email != before.Email {
+		return models.TeacherChange{}, models.ErrEmailChangeRequired
+	}
+	if _, err := r.updateTeacherTx(ctx, tx, id, updates); err != nil {
+		return models.TeacherChange{}, err
+	}
+	after, err := r.getPatchableTx(ctx, tx, id, false)
+	if err != nil {
+		return models.TeacherChange{}, err
+	}
+
+	change := models.DiffTeacher(*before, *after)
+	if len(change.Changes) > 0 {
+		if err := insertAudit(ctx, tx, models.AuditEntry{
+			ActorID:  actorID,
+			Action:   models.AuditTeacherUpdated,
+			Entity:   "teacher",
+			EntityID: id,
+			Details:  map[string]any{"changes": change.Changes},
+		}); err != nil {
+			return change, err
 		}
 	}
-	return current, nil
+	return change, nil
 }
 
-func (r *TeacherRepository) BulkPatch(ctx context.Context, updates []map[string]interface{}) ([]int, error) {
+// BulkPatch applies every patch in one transaction and reports what changed per teacher.
+// With dryRun the same checks run but the transaction is rolled back.
+func (r *TeacherRepository) BulkPatch(ctx context.Context, updates []map[string]interface{}, actorID *int, dryRun bool) ([]models.TeacherChange, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.teachers.BulkPatch")
+	defer span.End()
+
 	tx, err := r.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("repo: failed to begin tx: %w", err)
 	}
 	defer tx.Rollback()
 
-	var updatedIds []int
-	for _, update := range updates {
+	changes := make([]models.TeacherChange, 0, len(updates))
+	for i, update := range updates {
 		idFloat, ok := update["id"].(float64)
 		if !ok {
-			return nil, fmt.Errorf("repo: missing or invalid 'id' in patch data: %w", models.ErrInvalidInput)
+			return nil, &models.ItemError{Index: i, Err: fmt.Errorf("repo: missing or invalid 'id' in patch data: %w", models.ErrInvalidInput)}
 		}
 		id := int(idFloat)
 
-		rows, err := r.updateTeacherTx(ctx, tx, id, update)
+		// In bulk ops, if one ID is missing, we fail the batch (common practice)
+		change, err := r.patchTx(ctx, tx, id, update, actorID)
 		if err != nil {
-			return nil, fmt.Errorf("repo: patch failed for id %d: %w", id, err)
-		}
-		if rows == 0 {
-			// In bulk ops, if one ID is missing, we fail the batch (common practice)
-			return nil, fmt.Errorf("repo: teacher %d not found: %w", id, models.ErrNotFound)
+			return nil, &models.ItemError{Index: i, ID: id, Err: fmt.Errorf("repo: patch failed for id %d: %w", id, err)}
 		}
-		updatedIds = append(updatedIds, id)
+		changes = append(changes, change)
 	}
 
+	if dryRun {
+		return changes, nil // Deferred Rollback discards everything, audit entries included
+	}
 	if err := tx.Commit(); err != nil {
 		return nil, fmt.Errorf("repo: failed to commit tx: %w", err)
 	}
-	return updatedIds, nil
+	return changes, nil
 }
 
-func (r *TeacherRepository) updateTeacherTx(ctx context.Context, tx *sql.Tx, id int, updates map[string]interface{}) (int64, error) {
+// getPatchableTx reads the fields BulkPatch may change, optionally locking the row
+func (r *TeacherRepository) getPatchableTx(ctx context.Context, tx *Tx, id int, lock bool) (*models.Teacher, error) {
+	query := "SELECT id, first_name, last_name, email, phone, class, subject FROM teachers WHERE id = ? AND deleted_at IS NULL"
+	if lock {
+		query += " FOR UPDATE"
+	}
+
+	var t models.Teacher
+	err := tx.QueryRowContext(ctx, query, id).Scan(&t.ID, &t.FirstName, &t.LastName, &t.Email, &t.Phone, &t.Class, &t.Subject)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("repo: teacher %d not found: %w", id, models.ErrTeacherNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to read teacher %d: %w", id, err)
+	}
+	return &t, nil
+}
+
+func (r *TeacherRepository) updateTeacherTx(ctx context.Context, tx *Tx, id int, updates map[string]interface{}) (int64, error) {
 	query := "UPDATE teachers SET "
 	var args []interface{}
 	var columns []string
-	allowedCols := map[string]bool{"first_name": true, "last_name": true, "email": true, "class": true, "subject": true}
+	allowedCols := map[string]bool{"first_name": true, "last_name": true, "email": true, "phone": true, "class": true, "subject": true}
 
 	for k, v := range updates {
 		if k == "id" || !allowedCols[k] {
@@ -222,7 +570,14 @@ func (r *TeacherRepository) updateTeacherTx(ctx context.Context, tx *sql.Tx, id
 
 		strVal, ok := v.(string)
 		if !ok {
-			return 0, fmt.Errorf("field %s expected string", k)
+			return 0, fmt.Errorf("field %s expected string: %w", k, models.ErrInvalidInput)
+		}
+		if k == "phone" {
+			normalized, err := models.ParsePhone(strVal)
+			if err != nil {
+				return 0, err
+			}
+			strVal = normalized
 		}
 		columns = append(columns, fmt.Sprintf("%s=?", k))
 		args = append(args, strVal)
@@ -237,6 +592,9 @@ func (r *TeacherRepository) updateTeacherTx(ctx context.Context, tx *sql.Tx, id
 
 	res, err := tx.ExecContext(ctx, query, args...)
 	if err != nil {
+		if conflict := asDuplicateEntry(err); conflict != nil {
+			return 0, conflict
+		}
 		return 0, err
 	}
 	return res.RowsAffected()
@@ -244,21 +602,123 @@ func (r *TeacherRepository) updateTeacherTx(ctx context.Context, tx *sql.Tx, id
 
 // --- DELETE ---
 
-func (r *TeacherRepository) Delete(ctx context.Context, id int) (bool, error) {
-	res, err := r.DB.ExecContext(ctx, "DELETE FROM teachers WHERE id = ?", id)
+// Delete removes a teacher while applying the class cascade policy (see DeleteTeacherOptions).
+// The check, optional reassignment, delete and audit entry all happen in one transaction.
+func (r *TeacherRepository) Delete(ctx context.Context, id int, opts models.DeleteTeacherOptions) (bool, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.teachers.Delete")
+	defer span.End()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
 	if err != nil {
+		return false, fmt.Errorf("repo: failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	// 1. Lock the teacher so nobody reassigns them mid-delete
+	var class string
+	err = tx.QueryRowContext(ctx, "SELECT class FROM teachers WHERE id = ? AND deleted_at IS NULL FOR UPDATE", id).Scan(&class)
+	if err == sql.ErrNoRows {
+		// We return 'false' if 0 rows deleted, Handler converts this to 404
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("repo: failed to lock teacher %d: %w", id, err)
+	}
+
+	// 2. Apply the cascade policy
+	if opts.ReassignTo != 0 {
+		if err := r.reassignClassTx(ctx, tx, id, opts.ReassignTo, class, opts.ActorID); err != nil {
+			return false, err
+		}
+	} else if err := r.checkClassNotOrphanedTx(ctx, tx, id, class); err != nil {
+		return false, err
+	}
+
+	// 3. Delete + audit
+	// Soft delete: the row goes to the trash until restored or purged
+	if _, err := tx.ExecContext(ctx, "UPDATE teachers SET deleted_at = NOW(), deleted_by = ? WHERE id = ?", opts.ActorID, id); err != nil {
 		return false, fmt.Errorf("repo: delete failed: %w", err)
 	}
-	rows, err := res.RowsAffected()
+
+	details := map[string]any{"class": class}
+	if opts.ReassignTo != 0 {
+		details["reassigned_to"] = opts.ReassignTo
+	}
+	if err := insertAudit(ctx, tx, models.AuditEntry{
+		ActorID:  opts.ActorID,
+		Action:   models.AuditTeacherDeleted,
+		Entity:   "teacher",
+		EntityID: id,
+		Details:  details,
+	}); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("repo: commit failed: %w", err)
+	}
+	return true, nil
+}
+
+// checkClassNotOrphanedTx blocks the delete when the teacher is the last one on a class that still has students
+func (r *TeacherRepository) checkClassNotOrphanedTx(ctx context.Context, tx *Tx, id int, class string) error {
+	var otherTeachers int
+	err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM teachers WHERE class = ? AND id <> ? AND deleted_at IS NULL", class, id).Scan(&otherTeachers)
 	if err != nil {
-		return false, fmt.Errorf("repo: failed to check rows: %w", err)
+		return fmt.Errorf("repo: failed to count class teachers: %w", err)
+	}
+	if otherTeachers > 0 {
+		return nil
 	}
 
-	// We return 'false' if 0 rows deleted, Handler converts this to 404
-	return rows > 0, nil
+	var students int
+	if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM students WHERE class = ?", class).Scan(&students); err != nil {
+		return fmt.Errorf("repo: failed to count class students: %w", err)
+	}
+	if students > 0 {
+		return fmt.Errorf("repo: teacher %d still has a class: %w", id, &models.DependencyError{
+			Reason:   fmt.Sprintf("Teacher is the class teacher of '%s'; pass ?reassign_to=<teacher id> to hand the class over", class),
+			Resource: "students",
+			Count:    students,
+		})
+	}
+	return nil
 }
 
-func (r *TeacherRepository) BulkDelete(ctx context.Context, ids []int) ([]int, error) {
+// reassignClassTx hands the deleted teacher's class over to another teacher
+func (r *TeacherRepository) reassignClassTx(ctx context.Context, tx *Tx, fromID, toID int, class string, actorID *int) error {
+	if toID == fromID {
+		return fmt.Errorf("repo: cannot reassign class to the teacher being deleted: %w", models.ErrInvalidInput)
+	}
+
+	var previousClass string
+	err := tx.QueryRowContext(ctx, "SELECT class FROM teachers WHERE id = ? AND deleted_at IS NULL FOR UPDATE", toID).Scan(&previousClass)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("repo: reassign target teacher %d not found: %w", toID, models.ErrInvalidInput)
+	}
+	if err != nil {
+		return fmt.Errorf("repo: failed to lock teacher %d: %w", toID, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE teachers SET class = ? WHERE id = ?", class, toID); err != nil {
+		return fmt.Errorf("repo: failed to reassign class: %w", err)
+	}
+
+	return insertAudit(ctx, tx, models.AuditEntry{
+		ActorID:  actorID,
+		Action:   models.AuditClassReassigned,
+		Entity:   "teacher",
+		EntityID: toID,
+		Details:  map[string]any{"class": class, "previous_class": previousClass, "from_teacher": fromID},
+	})
+}
+
+// BulkDelete moves the given teachers to the trash and returns the IDs that existed.
+// With dryRun the rows are still locked and checked, then the transaction is rolled back.
+func (r *TeacherRepository) BulkDelete(ctx context.Context, ids []int, actorID *int, dryRun bool) ([]int, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.teachers.BulkDelete")
+	defer span.End()
+
 	tx, err := r.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("repo: failed to begin tx: %w", err)
@@ -277,7 +737,7 @@ func (r *TeacherRepository) BulkDelete(ctx context.Context, ids []int) ([]int, e
 		args[i] = id
 	}
 
-	querySelect := fmt.Sprintf("SELECT id FROM teachers WHERE id IN (%s) FOR UPDATE", strings.Join(placeholders, ","))
+	querySelect := fmt.Sprintf("SELECT id FROM teachers WHERE id IN (%s) AND deleted_at IS NULL FOR UPDATE", strings.Join(placeholders, ","))
 	rows, err := tx.QueryContext(ctx, querySelect, args...)
 	if err != nil {
 		return nil, fmt.Errorf("repo: failed to check bulk IDs: %w", err)
@@ -300,58 +760,225 @@ func (r *TeacherRepository) BulkDelete(ctx context.Context, ids []int) ([]int, e
 
 	// 2. Delete valid
 	validPlaceholders := make([]string, len(validIds))
-	validArgs := make([]interface{}, len(validIds))
+	validArgs := []interface{}{actorID}
 	for i, id := range validIds {
 		validPlaceholders[i] = "?"
-		validArgs[i] = id
+		validArgs = append(validArgs, id)
 	}
 
-	queryDelete := fmt.Sprintf("DELETE FROM teachers WHERE id IN (%s)", strings.Join(validPlaceholders, ","))
+	queryDelete := fmt.Sprintf("UPDATE teachers SET deleted_at = NOW(), deleted_by = ? WHERE id IN (%s)", strings.Join(validPlaceholders, ","))
 	if _, err := tx.ExecContext(ctx, queryDelete, validArgs...); err != nil {
 		return nil, fmt.Errorf("repo: bulk delete failed: %w", err)
 	}
 
+	if dryRun {
+		return validIds, nil // Deferred Rollback restores the rows
+	}
 	if err := tx.Commit(); err != nil {
 		return nil, fmt.Errorf("repo: commit failed: %w", err)
 	}
 	return validIds, nil
 }
 
-// func (r *TeacherRepository)
+// SetActiveBulk activates or deactivates accounts in one transaction, locking rows like BulkDelete.
+// Returns the IDs that existed and were updated; each change gets an audit entry.
+func (r *TeacherRepository) SetActiveBulk(ctx context.Context, ids []int, active bool, actorID *int) ([]int, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.teachers.SetActiveBulk")
+	defer span.End()
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	// 1. Verify existence using FOR UPDATE (Locks rows)
+	placeholders := make([]string, len(ids))
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		placeholders[i] = "?"
+		args[i] = id
+	}
+
+	querySelect := fmt.Sprintf("SELECT id FROM teachers WHERE id IN (%s) AND deleted_at IS NULL FOR UPDATE", strings.Join(placeholders, ","))
+	rows, err := tx.QueryContext(ctx, querySelect, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to check bulk IDs: %w", err)
+	}
 
-// --- HELPERS ---
-func (r *TeacherRepository) addSorts(filter models.TeacherFilter, query string) string {
-	validSorts := map[string]bool{"first_name": true, "last_name": true, "email": true, "class": true, "subject": true}
-	if validSorts[filter.SortBy] {
-		order := "ASC"
-		if strings.ToUpper(filter.SortOrder) == "DESC" {
-			order = "DESC"
+	var validIds []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("repo: scan failed: %w", err)
+		}
+		validIds = append(validIds, id)
+	}
+	rows.Close()
+
+	if len(validIds) == 0 {
+		return nil, nil
+	}
+
+	// 2. Update valid + audit each account
+	action := models.AuditTeacherDeactivated
+	if active {
+		action = models.AuditTeacherReactivated
+	}
+	for _, id := range validIds {
+		if _, err := tx.ExecContext(ctx, "UPDATE teachers SET is_active = ? WHERE id = ?", active, id); err != nil {
+			return nil, fmt.Errorf("repo: failed to update status of teacher %d: %w", id, err)
+		}
+		if err := insertAudit(ctx, tx, models.AuditEntry{
+			ActorID:  actorID,
+			Action:   action,
+			Entity:   "teacher",
+			EntityID: id,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("repo: commit failed: %w", err)
+	}
+	return validIds, nil
+}
+
+// --- TRASH ---
+
+// ListDeleted returns soft-deleted teachers, most recently deleted first
+func (r *TeacherRepository) ListDeleted(ctx context.Context) ([]models.Teacher, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.teachers.ListDeleted")
+	defer span.End()
+
+	rows, err := r.DB.QueryContext(ctx,
+		`SELECT id, first_name, last_name, email, phone, class, subject, deleted_at, deleted_by
+		 FROM teachers WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to query deleted teachers: %w", err)
+	}
+	defer rows.Close()
+
+	teachers := make([]models.Teacher, 0)
+	for rows.Next() {
+		var t models.Teacher
+		var deletedBy sql.NullInt64
+		if err := rows.Scan(&t.ID, &t.FirstName, &t.LastName, &t.Email, &t.Phone, &t.Class, &t.Subject, &t.DeletedAt, &deletedBy); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan deleted teacher row: %w", err)
+		}
+		if deletedBy.Valid {
+			id := int(deletedBy.Int64)
+			t.DeletedBy = &id
 		}
-		query += fmt.Sprintf(" ORDER BY %s %s", filter.SortBy, order)
+		teachers = append(teachers, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return teachers, nil
+}
+
+// Restore takes a teacher out of the trash. ErrNotFound means it isn't in the trash
+// (never deleted, or already purged).
+func (r *TeacherRepository) Restore(ctx context.Context, id int, actorID *int) (*models.Teacher, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.teachers.Restore")
+	defer span.End()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "UPDATE teachers SET deleted_at = NULL, deleted_by = NULL WHERE id = ? AND deleted_at IS NOT NULL", id)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to restore teacher %d: %w", id, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, fmt.Errorf("repo: teacher %d is not in the trash: %w", id, models.ErrTeacherNotFound)
 	}
-	return query
+	if err := insertAudit(ctx, tx, models.AuditEntry{
+		ActorID:  actorID,
+		Action:   models.AuditTeacherRestored,
+		Entity:   "teacher",
+		EntityID: id,
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("repo: commit failed: %w", err)
+	}
+	return r.GetByID(ctx, id)
 }
 
-func (r *TeacherRepository) addFilter(filter models.TeacherFilter, query string, args []interface{}) (string, []interface{}) {
-	if filter.FirstName != "" {
-		query += " AND first_name = ?"
-		args = append(args, filter.FirstName)
+// PurgeDeleted hard-deletes teachers that have been in the trash since before cutoff
+// and returns how many went
+func (r *TeacherRepository) PurgeDeleted(ctx context.Context, cutoff time.Time) (int, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.teachers.PurgeDeleted")
+	defer span.End()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("repo: failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, "SELECT id FROM teachers WHERE deleted_at < ? FOR UPDATE", cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("repo: failed to find expired teachers: %w", err)
+	}
+	var ids []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("repo: scan failed: %w", err)
+		}
+		ids = append(ids, id)
 	}
-	if filter.LastName != "" {
-		query += " AND last_name = ?"
-		args = append(args, filter.LastName)
+	rows.Close()
+
+	for _, id := range ids {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM teachers WHERE id = ?", id); err != nil {
+			return 0, fmt.Errorf("repo: failed to purge teacher %d: %w", id, err)
+		}
+		if err := insertAudit(ctx, tx, models.AuditEntry{
+			Action:   models.AuditTeacherPurged,
+			Entity:   "teacher",
+			EntityID: id,
+		}); err != nil {
+			return 0, err
+		}
 	}
-	if filter.Email != "" {
-		query += " AND email = ?"
-		args = append(args, filter.Email)
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("repo: commit failed: %w", err)
 	}
-	if filter.Class != "" {
-		query += " AND class = ?"
-		args = append(args, filter.Class)
+	return len(ids), nil
+}
+
+// ExpireResetTokens clears reset tokens past their expiry, so a leaked old reset
+// link is useless even if nobody ever clicks it
+func (r *TeacherRepository) ExpireResetTokens(ctx context.Context, now time.Time) (int, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.teachers.ExpireResetTokens")
+	defer span.End()
+
+	res, err := r.DB.ExecContext(ctx,
+		"UPDATE teachers SET password_reset_token = NULL, password_reset_expires = NULL WHERE password_reset_expires < ?", now)
+	if err != nil {
+		return 0, fmt.Errorf("repo: failed to expire reset tokens: %w", err)
 	}
-	if filter.Subject != "" {
-		query += " AND subject = ?"
-		args = append(args, filter.Subject)
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("repo: failed to read rows affected: %w", err)
 	}
-	return query, args
+	return int(n), nil
 }
@@ -0,0 +1,275 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/tracing"
+)
+
+// ThreadRepository stores guardian messaging (tables message_threads,
+// thread_messages and thread_reads). Attachments live in a JSON column of their
+// message; thread_reads keeps the last message each staff member has read.
+type ThreadRepository struct {
+	DB Conn
+}
+
+// NewThreadRepository is the constructor
+func NewThreadRepository(db *sql.DB) *ThreadRepository {
+	return &ThreadRepository{DB: Pool(db)}
+}
+
+// threadColumns ends with the viewer's unread count: bind the viewer's ID twice
+const threadColumns = "t.id, t.scope, t.student_id, t.class, t.subject, t.created_by, t.created_at, t.last_message_at, " +
+	"(SELECT COUNT(*) FROM thread_messages m WHERE m.thread_id = t.id AND m.sender_id <> ? AND m.id > " +
+	"COALESCE((SELECT rd.last_read_id FROM thread_reads rd WHERE rd.thread_id = t.id AND rd.teacher_id = ?), 0))"
+
+func scanThread(row interface{ Scan(...any) error }, t *models.Thread) error {
+	var studentID sql.NullInt64
+	if err := row.Scan(&t.ID, &t.Scope, &studentID, &t.Class, &t.Subject, &t.CreatedBy,
+		&t.CreatedAt, &t.LastMessageAt, &t.Unread); err != nil {
+		return err
+	}
+	if studentID.Valid {
+		id := int(studentID.Int64)
+		t.StudentID = &id
+	}
+	return nil
+}
+
+const threadMessageColumns = "id, thread_id, sender_id, body, attachments, created_at"
+
+// storedAttachment is an attachment as kept in the JSON column, with its storage key
+type storedAttachment struct {
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	Key         string `json:"key"`
+	UploadID    int    `json:"upload_id,omitempty"`
+}
+
+func scanThreadMessage(row interface{ Scan(...any) error }, m *models.ThreadMessage) error {
+	var attachments []byte
+	if err := row.Scan(&m.ID, &m.ThreadID, &m.SenderID, &m.Body, &attachments, &m.CreatedAt); err != nil {
+		return err
+	}
+	var stored []storedAttachment
+	if err := json.Unmarshal(attachments, &stored); err != nil {
+		return fmt.Errorf("repo: bad attachments in message %d: %w", m.ID, err)
+	}
+	m.Attachments = make([]models.Attachment, len(stored))
+	for i, a := range stored {
+		m.Attachments[i] = models.Attachment{Name: a.Name, ContentType: a.ContentType, Size: a.Size, Key: a.Key, UploadID: a.UploadID}
+	}
+	return nil
+}
+
+func insertThreadMessage(ctx context.Context, tx *Tx, m models.ThreadMessage) (int64, error) {
+	stored := make([]storedAttachment, len(m.Attachments))
+	for i, a := range m.Attachments {
+		stored[i] = storedAttachment{Name: a.Name, ContentType: a.ContentType, Size: a.Size, Key: a.Key, UploadID: a.UploadID}
+	}
+	attachments, err := json.Marshal(stored)
+	if err != nil {
+		return 0, err
+	}
+	res, err := tx.ExecContext(ctx,
+		"INSERT INTO thread_messages (thread_id, sender_id, body, attachments) VALUES (?,?,?,?)",
+		m.ThreadID, m.SenderID, m.Body, attachments)
+	if err != nil {
+		return 0, err
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	// Posting reads the thread up to one's own message
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO thread_reads (thread_id, teacher_id, last_read_id) VALUES (?,?,?)
+		 ON DUPLICATE KEY UPDATE last_read_id = GREATEST(last_read_id, VALUES(last_read_id))`,
+		m.ThreadID, m.SenderID, id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// Create opens a thread with its first message
+func (r *ThreadRepository) Create(ctx context.Context, t models.Thread, first models.ThreadMessage) (*models.Thread, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.threads.Create")
+	defer span.End()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var studentID sql.NullInt64
+	if t.StudentID != nil {
+		studentID = sql.NullInt64{Int64: int64(*t.StudentID), Valid: true}
+	}
+	res, err := tx.ExecContext(ctx,
+		"INSERT INTO message_threads (scope, student_id, class, subject, created_by, last_message_at) VALUES (?,?,?,?,?,NOW())",
+		t.Scope, studentID, t.Class, t.Subject, t.CreatedBy)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to insert thread: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to get thread ID: %w", err)
+	}
+
+	first.ThreadID = int(id)
+	if _, err := insertThreadMessage(ctx, tx, first); err != nil {
+		return nil, fmt.Errorf("repo: failed to insert first message of thread %d: %w", id, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("repo: failed to commit thread: %w", err)
+	}
+	return r.GetByID(ctx, int(id), t.CreatedBy)
+}
+
+// GetByID returns a thread with viewerID's unread count
+func (r *ThreadRepository) GetByID(ctx context.Context, id, viewerID int) (*models.Thread, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.threads.GetByID")
+	defer span.End()
+
+	var t models.Thread
+	err := scanThread(r.DB.QueryRowContext(ctx,
+		"SELECT "+threadColumns+" FROM message_threads t WHERE t.id = ?", viewerID, viewerID, id), &t)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("repo: thread %d not found: %w", id, models.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to get thread %d: %w", id, err)
+	}
+	return &t, nil
+}
+
+// List returns threads with viewerID's unread counts, most recently active first
+func (r *ThreadRepository) List(ctx context.Context, filter models.ThreadFilter, viewerID int) ([]models.Thread, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.threads.List")
+	defer span.End()
+
+	query := "SELECT " + threadColumns + " FROM message_threads t WHERE 1=1"
+	args := []interface{}{viewerID, viewerID}
+	if filter.StudentID != 0 {
+		query += " AND t.student_id = ?"
+		args = append(args, filter.StudentID)
+	}
+	if filter.Class != "" {
+		query += " AND t.class = ?"
+		args = append(args, filter.Class)
+	}
+	query += " ORDER BY t.last_message_at DESC, t.id DESC"
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to query threads: %w", err)
+	}
+	defer rows.Close()
+
+	threads := make([]models.Thread, 0)
+	for rows.Next() {
+		var t models.Thread
+		if err := scanThread(rows, &t); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan thread row: %w", err)
+		}
+		threads = append(threads, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return threads, nil
+}
+
+// AddMessage appends a message and moves the thread's last activity to it
+func (r *ThreadRepository) AddMessage(ctx context.Context, m models.ThreadMessage) (*models.ThreadMessage, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.threads.AddMessage")
+	defer span.End()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, "UPDATE message_threads SET last_message_at = NOW() WHERE id = ?", m.ThreadID)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to update thread %d: %w", m.ThreadID, err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, fmt.Errorf("repo: thread %d not found: %w", m.ThreadID, models.ErrNotFound)
+	}
+	id, err := insertThreadMessage(ctx, tx, m)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to insert message in thread %d: %w", m.ThreadID, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("repo: failed to commit message: %w", err)
+	}
+	return r.GetMessage(ctx, m.ThreadID, int(id))
+}
+
+// GetMessage returns a message of a thread, ErrNotFound if it belongs to another thread
+func (r *ThreadRepository) GetMessage(ctx context.Context, threadID, id int) (*models.ThreadMessage, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.threads.GetMessage")
+	defer span.End()
+
+	var m models.ThreadMessage
+	err := scanThreadMessage(r.DB.QueryRowContext(ctx,
+		"SELECT "+threadMessageColumns+" FROM thread_messages WHERE id = ? AND thread_id = ?", id, threadID), &m)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("repo: message %d not found in thread %d: %w", id, threadID, models.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to get message %d: %w", id, err)
+	}
+	return &m, nil
+}
+
+// ListMessages returns a thread's messages, oldest first
+func (r *ThreadRepository) ListMessages(ctx context.Context, threadID int) ([]models.ThreadMessage, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.threads.ListMessages")
+	defer span.End()
+
+	rows, err := r.DB.QueryContext(ctx,
+		"SELECT "+threadMessageColumns+" FROM thread_messages WHERE thread_id = ? ORDER BY id", threadID)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to query messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := make([]models.ThreadMessage, 0)
+	for rows.Next() {
+		var m models.ThreadMessage
+		if err := scanThreadMessage(rows, &m); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan message row: %w", err)
+		}
+		messages = append(messages, m)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return messages, nil
+}
+
+// MarkRead records that userID has read the thread up to its latest message
+func (r *ThreadRepository) MarkRead(ctx context.Context, threadID, userID int) error {
+	ctx, span := tracing.StartQuery(ctx, "repo.threads.MarkRead")
+	defer span.End()
+
+	_, err := r.DB.ExecContext(ctx,
+		`INSERT INTO thread_reads (thread_id, teacher_id, last_read_id)
+		 SELECT ?, ?, COALESCE(MAX(id), 0) FROM thread_messages WHERE thread_id = ?
+		 ON DUPLICATE KEY UPDATE last_read_id = GREATEST(last_read_id, VALUES(last_read_id))`,
+		threadID, userID, threadID)
+	if err != nil {
+		return fmt.Errorf("repo: failed to mark thread %d read: %w", threadID, err)
+	}
+	return nil
+}
@@ -0,0 +1,287 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/tracing"
+)
+
+// TripRepository stores trips and their consents (tables trips, trip_consents)
+type TripRepository struct {
+	DB Conn
+}
+
+// NewTripRepository is the constructor
+func NewTripRepository(db *sql.DB) *TripRepository {
+	return &TripRepository{DB: Pool(db)}
+}
+
+const tripColumns = "id, title, class, DATE_FORMAT(trip_date, '%Y-%m-%d'), destination, details, COALESCE(DATE_FORMAT(consent_deadline, '%Y-%m-%d'), ''), created_by, created_at"
+
+func scanTrip(row interface{ Scan(...any) error }, t *models.Trip) error {
+	var destination, details sql.NullString
+	var createdBy sql.NullInt64
+	if err := row.Scan(&t.ID, &t.Title, &t.Class, &t.Date, &destination, &details, &t.ConsentDeadline, &createdBy, &t.CreatedAt); err != nil {
+		return err
+	}
+	t.Destination, t.Details = destination.String, details.String
+	if createdBy.Valid {
+		id := int(createdBy.Int64)
+		t.CreatedBy = &id
+	}
+	return nil
+}
+
+const consentColumns = "trip_id, student_id, status, COALESCE(via, ''), COALESCE(guardian_name, ''), COALESCE(note, ''), recorded_by, COALESCE(token_hash, ''), requested_at, responded_at"
+
+func scanConsent(row interface{ Scan(...any) error }, c *models.TripConsent) error {
+	var recordedBy sql.NullInt64
+	var respondedAt sql.NullTime
+	if err := row.Scan(&c.TripID, &c.StudentID, &c.Status, &c.Via, &c.GuardianName, &c.Note, &recordedBy, &c.TokenHash, &c.RequestedAt, &respondedAt); err != nil {
+		return err
+	}
+	if recordedBy.Valid {
+		id := int(recordedBy.Int64)
+		c.RecordedBy = &id
+	}
+	if respondedAt.Valid {
+		c.RespondedAt = &respondedAt.Time
+	}
+	return nil
+}
+
+// Create records the trip
+func (r *TripRepository) Create(ctx context.Context, t models.Trip) (*models.Trip, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.trips.Create")
+	defer span.End()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx,
+		"INSERT INTO trips (title, class, trip_date, destination, details, consent_deadline, created_by) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		t.Title, t.Class, t.Date, nullString(t.Destination), nullString(t.Details), nullString(t.ConsentDeadline), t.CreatedBy)
+	if err != nil {
+		if isMissingReference(err) {
+			return nil, fmt.Errorf("repo: class %s does not exist: %w", t.Class, models.ErrInvalidInput)
+		}
+		return nil, fmt.Errorf("repo: failed to insert trip: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to read trip id: %w", err)
+	}
+
+	if err := insertAudit(ctx, tx, models.AuditEntry{
+		ActorID:  t.CreatedBy,
+		Action:   models.AuditTripCreated,
+		Entity:   "trip",
+		EntityID: int(id),
+		Details:  map[string]any{"title": t.Title, "class": t.Class, "date": t.Date},
+	}); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("repo: failed to commit tx: %w", err)
+	}
+	return r.GetByID(ctx, int(id))
+}
+
+func (r *TripRepository) GetByID(ctx context.Context, id int) (*models.Trip, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.trips.GetByID")
+	defer span.End()
+
+	var t models.Trip
+	err := scanTrip(r.DB.QueryRowContext(ctx, "SELECT "+tripColumns+" FROM trips WHERE id = ?", id), &t)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("repo: trip %d not found: %w", id, models.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to read trip: %w", err)
+	}
+	return &t, nil
+}
+
+func (r *TripRepository) List(ctx context.Context, class string) ([]models.Trip, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.trips.List")
+	defer span.End()
+
+	q, args := "SELECT "+tripColumns+" FROM trips", []any{}
+	if class != "" {
+		q += " WHERE class = ?"
+		args = append(args, class)
+	}
+	rows, err := r.DB.QueryContext(ctx, q+" ORDER BY trip_date, id", args...)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to query trips: %w", err)
+	}
+	defer rows.Close()
+
+	trips := make([]models.Trip, 0)
+	for rows.Next() {
+		var t models.Trip
+		if err := scanTrip(rows, &t); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan trip: %w", err)
+		}
+		trips = append(trips, t)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return trips, nil
+}
+
+func (r *TripRepository) Consents(ctx context.Context, tripID int) ([]models.TripConsent, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.trips.Consents")
+	defer span.End()
+
+	rows, err := r.DB.QueryContext(ctx, "SELECT "+consentColumns+" FROM trip_consents WHERE trip_id = ? ORDER BY student_id", tripID)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to query consents: %w", err)
+	}
+	defer rows.Close()
+
+	consents := make([]models.TripConsent, 0)
+	for rows.Next() {
+		var c models.TripConsent
+		if err := scanConsent(rows, &c); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan consent: %w", err)
+		}
+		consents = append(consents, c)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return consents, nil
+}
+
+// Request locks the trip's consents so an answer can't slip in between the
+// check and the new token
+func (r *TripRepository) Request(ctx context.Context, consents []models.TripConsent) ([]models.TripConsent, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.trips.Request")
+	defer span.End()
+
+	requested := make([]models.TripConsent, 0, len(consents))
+	if len(consents) == 0 {
+		return requested, nil
+	}
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, c := range consents {
+		var status string
+		err := tx.QueryRowContext(ctx,
+			"SELECT status FROM trip_consents WHERE trip_id = ? AND student_id = ? FOR UPDATE", c.TripID, c.StudentID).Scan(&status)
+		switch {
+		case err == sql.ErrNoRows:
+			_, err = tx.ExecContext(ctx,
+				"INSERT INTO trip_consents (trip_id, student_id, status, token_hash, requested_at) VALUES (?, ?, ?, ?, ?)",
+				c.TripID, c.StudentID, models.ConsentPending, c.TokenHash, c.RequestedAt)
+		case err != nil:
+		case status != models.ConsentPending:
+			continue
+		default:
+			_, err = tx.ExecContext(ctx,
+				"UPDATE trip_consents SET token_hash = ?, requested_at = ? WHERE trip_id = ? AND student_id = ?",
+				c.TokenHash, c.RequestedAt, c.TripID, c.StudentID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("repo: failed to request consent for student %d: %w", c.StudentID, err)
+		}
+		c.Status = models.ConsentPending
+		requested = append(requested, c)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("repo: failed to commit tx: %w", err)
+	}
+	return requested, nil
+}
+
+func (r *TripRepository) ConsentByToken(ctx context.Context, tokenHash string) (*models.TripConsent, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.trips.ConsentByToken")
+	defer span.End()
+
+	var c models.TripConsent
+	err := scanConsent(r.DB.QueryRowContext(ctx, "SELECT "+consentColumns+" FROM trip_consents WHERE token_hash = ?", tokenHash), &c)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("repo: no consent for this token: %w", models.ErrConsentLinkInvalid)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to read consent: %w", err)
+	}
+	return &c, nil
+}
+
+func (r *TripRepository) Answer(ctx context.Context, c models.TripConsent, actorID *int) (*models.TripConsent, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.trips.Answer")
+	defer span.End()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if c.TokenHash != "" {
+		res, err := tx.ExecContext(ctx,
+			`UPDATE trip_consents SET status = ?, via = ?, guardian_name = ?, note = ?, recorded_by = NULL, responded_at = NOW()
+			 WHERE trip_id = ? AND student_id = ? AND token_hash = ?`,
+			c.Status, c.Via, nullString(c.GuardianName), nullString(c.Note), c.TripID, c.StudentID, c.TokenHash)
+		if err != nil {
+			return nil, fmt.Errorf("repo: failed to record consent: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			// The same answer twice changes no row: tell it from a replaced token
+			var same int
+			if err := tx.QueryRowContext(ctx,
+				"SELECT COUNT(*) FROM trip_consents WHERE trip_id = ? AND student_id = ? AND token_hash = ?",
+				c.TripID, c.StudentID, c.TokenHash).Scan(&same); err != nil {
+				return nil, fmt.Errorf("repo: failed to read consent: %w", err)
+			}
+			if same == 0 {
+				return nil, fmt.Errorf("repo: consent token was replaced: %w", models.ErrConsentLinkInvalid)
+			}
+		}
+	} else {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO trip_consents (trip_id, student_id, status, via, guardian_name, note, recorded_by, requested_at, responded_at)
+			 VALUES (?, ?, ?, ?, ?, ?, ?, NOW(), NOW())
+			 ON DUPLICATE KEY UPDATE status = VALUES(status), via = VALUES(via), guardian_name = VALUES(guardian_name),
+			 note = VALUES(note), recorded_by = VALUES(recorded_by), responded_at = NOW()`,
+			c.TripID, c.StudentID, c.Status, c.Via, nullString(c.GuardianName), nullString(c.Note), actorID)
+		if err != nil {
+			if isMissingReference(err) {
+				return nil, fmt.Errorf("repo: trip %d or student %d not found: %w", c.TripID, c.StudentID, models.ErrNotFound)
+			}
+			return nil, fmt.Errorf("repo: failed to record consent: %w", err)
+		}
+	}
+
+	if err := insertAudit(ctx, tx, models.AuditEntry{
+		ActorID:  actorID,
+		Action:   models.AuditConsentAnswered,
+		Entity:   "trip",
+		EntityID: c.TripID,
+		Details:  map[string]any{"student_id": c.StudentID, "status": c.Status, "via": c.Via},
+	}); err != nil {
+		return nil, err
+	}
+
+	var saved models.TripConsent
+	if err := scanConsent(tx.QueryRowContext(ctx,
+		"SELECT "+consentColumns+" FROM trip_consents WHERE trip_id = ? AND student_id = ?", c.TripID, c.StudentID), &saved); err != nil {
+		return nil, fmt.Errorf("repo: failed to read consent: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("repo: failed to commit tx: %w", err)
+	}
+	return &saved, nil
+}
@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"simpleapi/internal/tracing"
+)
+
+// TxUnitOfWork is the MySQL UnitOfWork: one database transaction per Do
+type TxUnitOfWork struct {
+	DB *sql.DB
+}
+
+var _ UnitOfWork = (*TxUnitOfWork)(nil)
+
+// NewUnitOfWork is the constructor
+func NewUnitOfWork(db *sql.DB) *TxUnitOfWork {
+	return &TxUnitOfWork{DB: db}
+}
+
+// Do runs fn in a transaction, committing it if fn returns nil
+func (u *TxUnitOfWork) Do(ctx context.Context, fn func(ctx context.Context, repos Repos) error) error {
+	ctx, span := tracing.StartQuery(ctx, "repo.unitOfWork.Do")
+	defer span.End()
+
+	tx, err := u.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("repo: failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// One Conn for all of them, so their savepoints are numbered together
+	conn := bound(tx)
+	repos := Repos{
+		Teachers:         &TeacherRepository{DB: conn},
+		Students:         &StudentRepositoty{DB: conn},
+		ClassAssignments: &ClassAssignmentRepository{DB: conn},
+	}
+	if err := fn(ctx, repos); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("repo: failed to commit transaction: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,224 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"simpleapi/internal/models"
+	"simpleapi/internal/tracing"
+	"strings"
+)
+
+// UploadRepository tracks uploaded files through their virus scan (table uploads);
+// the files themselves live in the uploads storage
+type UploadRepository struct {
+	DB Conn
+}
+
+// NewUploadRepository is the constructor
+func NewUploadRepository(db *sql.DB) *UploadRepository {
+	return &UploadRepository{DB: Pool(db)}
+}
+
+const uploadColumns = "id, storage_key, name, content_type, size, uploaded_by, status, threat, scanned_at, reviewed_by, reviewed_at, review_note, created_at"
+
+func scanUpload(row interface{ Scan(...any) error }, u *models.Upload) error {
+	var threat, note sql.NullString
+	var reviewedBy sql.NullInt64
+	var scannedAt, reviewedAt sql.NullTime
+	if err := row.Scan(&u.ID, &u.StorageKey, &u.Name, &u.ContentType, &u.Size, &u.UploadedBy, &u.Status,
+		&threat, &scannedAt, &reviewedBy, &reviewedAt, &note, &u.CreatedAt); err != nil {
+		return err
+	}
+	u.Threat = threat.String
+	u.ReviewNote = note.String
+	if scannedAt.Valid {
+		u.ScannedAt = &scannedAt.Time
+	}
+	if reviewedBy.Valid {
+		id := int(reviewedBy.Int64)
+		u.ReviewedBy = &id
+	}
+	if reviewedAt.Valid {
+		u.ReviewedAt = &reviewedAt.Time
+	}
+	return nil
+}
+
+// Create records a stored file, pending its scan. It fails with a
+// models.QuotaError when the file takes the school past its storage quota.
+func (r *UploadRepository) Create(ctx context.Context, u models.Upload) (*models.Upload, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.uploads.Create")
+	defer span.End()
+
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to begin tx: %w", err)
+	}
+	defer tx.Rollback()
+
+	maxBytes, err := quotaLimit(ctx, tx, "quota_storage_bytes")
+	if err != nil {
+		return nil, err
+	}
+	if maxBytes > 0 {
+		var used int64
+		if err := tx.QueryRowContext(ctx,
+			"SELECT COALESCE(SUM(size), 0) FROM uploads WHERE status <> ?", models.ScanRejected).Scan(&used); err != nil {
+			return nil, fmt.Errorf("repo: failed to measure uploads: %w", err)
+		}
+		if used+u.Size > maxBytes {
+			return nil, &models.QuotaError{Quota: models.QuotaStorage, Used: used, Requested: u.Size, Limit: maxBytes}
+		}
+	}
+
+	res, err := tx.ExecContext(ctx,
+		"INSERT INTO uploads (storage_key, name, content_type, size, uploaded_by, status) VALUES (?,?,?,?,?,?)",
+		u.StorageKey, u.Name, u.ContentType, u.Size, u.UploadedBy, models.ScanPending)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to insert upload: %w", err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to get upload ID: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("repo: failed to commit tx: %w", err)
+	}
+	return r.GetByID(ctx, int(id))
+}
+
+func (r *UploadRepository) GetByID(ctx context.Context, id int) (*models.Upload, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.uploads.GetByID")
+	defer span.End()
+
+	var u models.Upload
+	err := scanUpload(r.DB.QueryRowContext(ctx, "SELECT "+uploadColumns+" FROM uploads WHERE id = ?", id), &u)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("repo: upload %d not found: %w", id, models.ErrNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to get upload %d: %w", id, err)
+	}
+	return &u, nil
+}
+
+// List returns uploads newest first
+func (r *UploadRepository) List(ctx context.Context, filter models.UploadFilter) ([]models.Upload, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.uploads.List")
+	defer span.End()
+
+	query := "SELECT " + uploadColumns + " FROM uploads WHERE 1=1"
+	var args []interface{}
+	if filter.Status != "" {
+		query += " AND status = ?"
+		args = append(args, filter.Status)
+	}
+	query += " ORDER BY id DESC"
+
+	rows, err := r.DB.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to query uploads: %w", err)
+	}
+	defer rows.Close()
+
+	uploads := make([]models.Upload, 0)
+	for rows.Next() {
+		var u models.Upload
+		if err := scanUpload(rows, &u); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan upload row: %w", err)
+		}
+		uploads = append(uploads, u)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return uploads, nil
+}
+
+// Statuses returns the scan status of each upload in ids; unknown IDs are left out
+func (r *UploadRepository) Statuses(ctx context.Context, ids []int) (map[int]string, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.uploads.Statuses")
+	defer span.End()
+
+	statuses := make(map[int]string, len(ids))
+	if len(ids) == 0 {
+		return statuses, nil
+	}
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	rows, err := r.DB.QueryContext(ctx,
+		"SELECT id, status FROM uploads WHERE id IN (?"+strings.Repeat(",?", len(ids)-1)+")", args...)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to query upload statuses: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int
+		var status string
+		if err := rows.Scan(&id, &status); err != nil {
+			return nil, fmt.Errorf("repo: failed to scan upload status: %w", err)
+		}
+		statuses[id] = status
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("repo: error iterating rows: %w", err)
+	}
+	return statuses, nil
+}
+
+// RecordScan stores the scanner's verdict (status is clean or quarantined). It
+// fails with ErrConflict unless the upload is still pending, so a scan that
+// ran twice can't undo an admin's review.
+func (r *UploadRepository) RecordScan(ctx context.Context, id int, status, threat string) (*models.Upload, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.uploads.RecordScan")
+	defer span.End()
+
+	return r.transition(ctx, id, models.ScanPending,
+		"UPDATE uploads SET status = ?, threat = ?, scanned_at = NOW() WHERE id = ? AND status = ?",
+		status, nullString(threat), id, models.ScanPending)
+}
+
+// Review records an admin's call on a quarantined upload (status is released
+// or rejected); ErrConflict if it isn't quarantined
+func (r *UploadRepository) Review(ctx context.Context, id int, status string, reviewerID int, note string) (*models.Upload, error) {
+	ctx, span := tracing.StartQuery(ctx, "repo.uploads.Review")
+	defer span.End()
+
+	return r.transition(ctx, id, models.ScanQuarantined,
+		"UPDATE uploads SET status = ?, reviewed_by = ?, reviewed_at = NOW(), review_note = ? WHERE id = ? AND status = ?",
+		status, reviewerID, nullString(note), id, models.ScanQuarantined)
+}
+
+// transition runs a guarded status update and returns the updated upload,
+// telling a missing upload apart from one in another state
+func (r *UploadRepository) transition(ctx context.Context, id int, from, query string, args ...any) (*models.Upload, error) {
+	res, err := r.DB.ExecContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("repo: failed to update upload %d: %w", id, err)
+	}
+	u, err := r.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, fmt.Errorf("repo: upload %d is %s, not %s: %w", id, u.Status, from, models.ErrConflict)
+	}
+	return u, nil
+}
+
+// Delete forgets an upload whose file was never put to use
+func (r *UploadRepository) Delete(ctx context.Context, id int) error {
+	ctx, span := tracing.StartQuery(ctx, "repo.uploads.Delete")
+	defer span.End()
+
+	if _, err := r.DB.ExecContext(ctx, "DELETE FROM uploads WHERE id = ?", id); err != nil {
+		return fmt.Errorf("repo: failed to delete upload %d: %w", id, err)
+	}
+	return nil
+}
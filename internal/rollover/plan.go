@@ -0,0 +1,275 @@
+// Package rollover plans the move from one school year to the next: which
+// class each class becomes, who repeats, who graduates and where the teachers
+// go. A plan is a proposal admins adjust until it looks right; the rollover
+// job (package jobs) then carries it out in one go.
+package rollover
+
+import (
+	"cmp"
+	"fmt"
+	"regexp"
+	"simpleapi/internal/models"
+	"slices"
+	"strconv"
+)
+
+// Input is what the planner sees of the school
+type Input struct {
+	Students []models.Student
+	Teachers []models.Teacher
+	// Assignments are the teachers' assigned classes (teacher_classes), by teacher
+	Assignments map[int][]string
+	// Classes are the school's classes; without a list, the classes students
+	// and teachers are in stand in for it
+	Classes []string
+	// Alumni are the alumni classes of earlier rollovers, whose students are
+	// left out: they graduated already
+	Alumni []string
+}
+
+var number = regexp.MustCompile(`\d+`)
+
+// NextClassName guesses the class after class by adding one to its last number:
+// "JSS 1" -> "JSS 2", "Grade 9B" -> "Grade 10B". It is "" without a number.
+func NextClassName(class string) string {
+	locs := number.FindAllStringIndex(class, -1)
+	if len(locs) == 0 {
+		return ""
+	}
+	loc := locs[len(locs)-1]
+	n, err := strconv.Atoi(class[loc[0]:loc[1]])
+	if err != nil {
+		return ""
+	}
+	return class[:loc[0]] + strconv.Itoa(n+1) + class[loc[1]:]
+}
+
+// Propose builds the draft plan of req. Classes req doesn't map get the guess
+// of NextClassName when it names a known class, and graduate otherwise. The
+// errors are classes of req.NextClass that have no students.
+func Propose(req models.RolloverRequest, in Input) (models.RolloverPlan, []models.ValidationError) {
+	alumni := make(map[string]bool)
+	for _, c := range in.Alumni {
+		alumni[c] = true
+	}
+	known := make(map[string]bool)
+	for _, c := range in.Classes {
+		known[c] = true
+	}
+	if len(in.Classes) == 0 {
+		for _, s := range in.Students {
+			known[s.Class] = true
+		}
+		for _, t := range in.Teachers {
+			known[t.Class] = true
+		}
+		delete(known, "")
+	}
+
+	p := models.RolloverPlan{
+		FromYear:    req.FromYear,
+		ToYear:      req.ToYear,
+		Status:      models.RolloverDraft,
+		AlumniClass: req.AlumniClass,
+		Archive:     req.Archive == nil || *req.Archive,
+		Classes:     make([]models.RolloverClass, 0),
+		Students:    make([]models.RolloverStudent, 0),
+		Teachers:    make([]models.RolloverTeacher, 0),
+	}
+	if p.AlumniClass == "" {
+		p.AlumniClass = "Graduated " + req.FromYear
+	}
+	alumni[p.AlumniClass] = true
+
+	classes := make(map[string]bool)
+	for _, s := range in.Students {
+		if s.Class == "" || alumni[s.Class] {
+			continue
+		}
+		classes[s.Class] = true
+		p.Students = append(p.Students, models.RolloverStudent{
+			StudentID: s.ID,
+			Name:      s.FirstName + " " + s.LastName,
+			Class:     s.Class,
+		})
+	}
+	slices.SortFunc(p.Students, func(a, b models.RolloverStudent) int {
+		return cmp.Or(cmp.Compare(a.Class, b.Class), cmp.Compare(a.Name, b.Name), cmp.Compare(a.StudentID, b.StudentID))
+	})
+
+	var errs []models.ValidationError
+	for class := range req.NextClass {
+		if !classes[class] {
+			errs = append(errs, models.RuleError("NextClass", "rollover_unknown", fmt.Sprintf("Class %q", class)))
+		}
+	}
+	for class := range classes {
+		c := models.RolloverClass{Class: class}
+		if next, ok := req.NextClass[class]; ok {
+			c.NextClass = next
+		} else if guess := NextClassName(class); known[guess] {
+			c.NextClass, c.Guessed = guess, true
+		}
+		p.Classes = append(p.Classes, c)
+	}
+	slices.SortFunc(p.Classes, func(a, b models.RolloverClass) int { return cmp.Compare(a.Class, b.Class) })
+
+	next := nextClasses(p)
+	for _, t := range in.Teachers {
+		assigned := slices.Clone(in.Assignments[t.ID])
+		if t.Class == "" && len(assigned) == 0 {
+			continue
+		}
+		rt := models.RolloverTeacher{
+			TeacherID:     t.ID,
+			Name:          t.FirstName + " " + t.LastName,
+			HomeClass:     t.Class,
+			NextHomeClass: t.Class,
+			Classes:       assigned,
+			NextClasses:   slices.Clone(assigned),
+		}
+		if rt.Classes == nil {
+			rt.Classes, rt.NextClasses = []string{}, []string{}
+		}
+		if req.Teachers == "follow" {
+			rt.NextHomeClass = follow(next, t.Class)
+			for i, c := range rt.NextClasses {
+				rt.NextClasses[i] = follow(next, c)
+			}
+			rt.NextClasses = sortedClasses(rt.NextClasses)
+		}
+		p.Teachers = append(p.Teachers, rt)
+	}
+	slices.SortFunc(p.Teachers, func(a, b models.RolloverTeacher) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.TeacherID, b.TeacherID))
+	})
+
+	resolve(&p)
+	return p, errs
+}
+
+// Adjust applies an admin's patch to a draft plan. The errors name the
+// classes, students and teachers of the patch the plan doesn't have; the plan
+// is left untouched then.
+func Adjust(p *models.RolloverPlan, patch models.RolloverPatch) []models.ValidationError {
+	var errs []models.ValidationError
+	classes := make(map[string]int)
+	for i, c := range p.Classes {
+		classes[c.Class] = i
+	}
+	students := make(map[int]int)
+	for i, s := range p.Students {
+		students[s.StudentID] = i
+	}
+	teachers := make(map[int]int)
+	for i, t := range p.Teachers {
+		teachers[t.TeacherID] = i
+	}
+	for class := range patch.NextClass {
+		if _, ok := classes[class]; !ok {
+			errs = append(errs, models.RuleError("NextClass", "rollover_unknown", fmt.Sprintf("Class %q", class)))
+		}
+	}
+	for id := range patch.Students {
+		if _, ok := students[id]; !ok {
+			errs = append(errs, models.RuleError("Students", "rollover_unknown", fmt.Sprintf("Student %d", id)))
+		}
+	}
+	for id := range patch.Teachers {
+		if _, ok := teachers[id]; !ok {
+			errs = append(errs, models.RuleError("Teachers", "rollover_unknown", fmt.Sprintf("Teacher %d", id)))
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	for class, next := range patch.NextClass {
+		c := &p.Classes[classes[class]]
+		c.NextClass, c.Guessed = next, false
+	}
+	for id, action := range patch.Students {
+		s := &p.Students[students[id]]
+		s.Action, s.Overridden = action, action != ""
+	}
+	for id, change := range patch.Teachers {
+		t := &p.Teachers[teachers[id]]
+		if change.HomeClass != nil {
+			t.NextHomeClass = *change.HomeClass
+		}
+		if change.Classes != nil {
+			t.NextClasses = sortedClasses(slices.Clone(change.Classes))
+		}
+	}
+	if patch.AlumniClass != nil {
+		p.AlumniClass = *patch.AlumniClass
+	}
+	if patch.Archive != nil {
+		p.Archive = *patch.Archive
+	}
+	resolve(p)
+	return nil
+}
+
+// Check lists what stops the plan from executing: students promoted out of a
+// class that graduates, so they'd have nowhere to go. The school's class list
+// is checked separately (see models.ReferenceData.CheckRollover).
+func Check(p models.RolloverPlan) []models.ValidationError {
+	var errs []models.ValidationError
+	for _, s := range p.Students {
+		if s.Action == models.RolloverPromote && s.NextClass == "" {
+			errs = append(errs, models.RuleError(fmt.Sprintf("Students[%d]", s.StudentID), "rollover_graduates", ""))
+		}
+	}
+	return errs
+}
+
+// resolve works out each student's action and next class from their class's
+// mapping and the overrides, and counts the students of each class
+func resolve(p *models.RolloverPlan) {
+	next := nextClasses(*p)
+	counts := make(map[string]int)
+	for i := range p.Students {
+		s := &p.Students[i]
+		counts[s.Class]++
+		if !s.Overridden {
+			s.Action = models.RolloverPromote
+			if next[s.Class] == "" {
+				s.Action = models.RolloverGraduate
+			}
+		}
+		switch s.Action {
+		case models.RolloverPromote:
+			s.NextClass = next[s.Class]
+		case models.RolloverRepeat:
+			s.NextClass = s.Class
+		case models.RolloverGraduate:
+			s.NextClass = p.AlumniClass
+		}
+	}
+	for i := range p.Classes {
+		p.Classes[i].Students = counts[p.Classes[i].Class]
+	}
+}
+
+func nextClasses(p models.RolloverPlan) map[string]string {
+	next := make(map[string]string, len(p.Classes))
+	for _, c := range p.Classes {
+		next[c.Class] = c.NextClass
+	}
+	return next
+}
+
+// follow is where a teacher of class goes when teachers follow their classes.
+// A class that graduates or isn't in the plan keeps its teacher.
+func follow(next map[string]string, class string) string {
+	if n := next[class]; n != "" {
+		return n
+	}
+	return class
+}
+
+func sortedClasses(classes []string) []string {
+	slices.Sort(classes)
+	return slices.Compact(classes)
+}
@@ -0,0 +1,97 @@
+package scan
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// ClamAV streams files to a clamd daemon with its INSTREAM command.
+//
+//	CLAMAV_ADDR=localhost:3310 (TCP) or unix:/run/clamav/clamd.ctl
+//
+// clamd refuses streams over its StreamMaxLength (25 MB by default), which is
+// above the largest upload the API accepts.
+type ClamAV struct {
+	network, addr string
+	timeout       time.Duration
+}
+
+func NewClamAVFromEnv() (*ClamAV, error) {
+	addr := os.Getenv("CLAMAV_ADDR")
+	if addr == "" {
+		return nil, fmt.Errorf("scan: clamav needs CLAMAV_ADDR")
+	}
+	c := &ClamAV{network: "tcp", addr: addr, timeout: 2 * time.Minute}
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		c.network, c.addr = "unix", path
+	}
+	return c, nil
+}
+
+func (c *ClamAV) Name() string { return "clamav" }
+
+const clamChunkSize = 64 << 10
+
+func (c *ClamAV) Scan(ctx context.Context, r io.Reader) (Verdict, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, c.network, c.addr)
+	if err != nil {
+		return Verdict{}, fmt.Errorf("scan: clamav: %w", err)
+	}
+	defer conn.Close()
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	// zINSTREAM, then length-prefixed chunks, then a zero length
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return Verdict{}, fmt.Errorf("scan: clamav: %w", err)
+	}
+	buf := make([]byte, 4+clamChunkSize)
+	for {
+		n, rerr := io.ReadFull(r, buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, err := conn.Write(buf[:4+n]); err != nil {
+				return Verdict{}, fmt.Errorf("scan: clamav: %w", err)
+			}
+		}
+		if rerr == io.EOF || rerr == io.ErrUnexpectedEOF {
+			break
+		}
+		if rerr != nil {
+			return Verdict{}, fmt.Errorf("scan: clamav: reading file: %w", rerr)
+		}
+	}
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return Verdict{}, fmt.Errorf("scan: clamav: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && reply == "" {
+		return Verdict{}, fmt.Errorf("scan: clamav: reading reply: %w", err)
+	}
+	return parseClamReply(strings.TrimRight(reply, "\x00\n"))
+}
+
+// parseClamReply reads "stream: OK", "stream: <signature> FOUND" or "<message> ERROR"
+func parseClamReply(reply string) (Verdict, error) {
+	result := strings.TrimSpace(strings.TrimPrefix(reply, "stream:"))
+	switch {
+	case result == "OK":
+		return Verdict{Clean: true}, nil
+	case strings.HasSuffix(result, " FOUND"):
+		return Verdict{Threat: strings.TrimSuffix(result, " FOUND")}, nil
+	default:
+		return Verdict{}, fmt.Errorf("scan: clamav: %s", result)
+	}
+}
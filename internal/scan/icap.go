@@ -0,0 +1,136 @@
+package scan
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ICAP sends files to an ICAP server (RFC 3507) as RESPMOD requests, the way a
+// proxy would submit a download. Most commercial gateways and c-icap speak it.
+//
+//	ICAP_URL=icap://localhost:1344/avscan
+type ICAP struct {
+	url     *url.URL
+	timeout time.Duration
+}
+
+func NewICAPFromEnv() (*ICAP, error) {
+	raw := os.Getenv("ICAP_URL")
+	if raw == "" {
+		return nil, fmt.Errorf("scan: icap needs ICAP_URL")
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "icap" || u.Host == "" {
+		return nil, fmt.Errorf("scan: ICAP_URL must look like icap://host:1344/service, got %q", raw)
+	}
+	if u.Port() == "" {
+		u.Host = net.JoinHostPort(u.Hostname(), "1344")
+	}
+	return &ICAP{url: u, timeout: 2 * time.Minute}, nil
+}
+
+func (c *ICAP) Name() string { return "icap" }
+
+// Headers ICAP servers use to name what they found
+var icapThreatHeaders = []string{"X-Infection-Found", "X-Violations-Found", "X-Virus-Id", "X-Virus-Name"}
+
+func (c *ICAP) Scan(ctx context.Context, r io.Reader) (Verdict, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", c.url.Host)
+	if err != nil {
+		return Verdict{}, fmt.Errorf("scan: icap: %w", err)
+	}
+	defer conn.Close()
+	deadline := time.Now().Add(c.timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+
+	// The file goes in as the body of a made-up HTTP response
+	resHdr := "HTTP/1.1 200 OK\r\nContent-Type: application/octet-stream\r\nTransfer-Encoding: chunked\r\n\r\n"
+	w := bufio.NewWriter(conn)
+	fmt.Fprintf(w, "RESPMOD %s ICAP/1.0\r\n", c.url.String())
+	fmt.Fprintf(w, "Host: %s\r\n", c.url.Host)
+	fmt.Fprintf(w, "Allow: 204\r\n")
+	fmt.Fprintf(w, "Encapsulated: res-hdr=0, res-body=%d\r\n\r\n", len(resHdr))
+	w.WriteString(resHdr)
+
+	buf := make([]byte, 64<<10)
+	for {
+		n, rerr := io.ReadFull(r, buf)
+		if n > 0 {
+			fmt.Fprintf(w, "%x\r\n", n)
+			w.Write(buf[:n])
+			w.WriteString("\r\n")
+		}
+		if rerr == io.EOF || rerr == io.ErrUnexpectedEOF {
+			break
+		}
+		if rerr != nil {
+			return Verdict{}, fmt.Errorf("scan: icap: reading file: %w", rerr)
+		}
+	}
+	w.WriteString("0\r\n\r\n")
+	if err := w.Flush(); err != nil {
+		return Verdict{}, fmt.Errorf("scan: icap: %w", err)
+	}
+
+	tp := textproto.NewReader(bufio.NewReader(conn))
+	status, err := tp.ReadLine()
+	if err != nil {
+		return Verdict{}, fmt.Errorf("scan: icap: reading reply: %w", err)
+	}
+	header, err := tp.ReadMIMEHeader()
+	if err != nil && len(header) == 0 {
+		return Verdict{}, fmt.Errorf("scan: icap: reading reply headers: %w", err)
+	}
+
+	// "ICAP/1.0 204 No Content" means unmodified, i.e. clean
+	fields := strings.Fields(status)
+	if len(fields) < 2 {
+		return Verdict{}, fmt.Errorf("scan: icap: bad status line %q", status)
+	}
+	code, _ := strconv.Atoi(fields[1])
+	switch code {
+	case 204:
+		return Verdict{Clean: true}, nil
+	case 200:
+	default:
+		return Verdict{}, fmt.Errorf("scan: icap: server answered %q", status)
+	}
+
+	// 200 means the server rewrote the response, normally into a block page
+	for _, h := range icapThreatHeaders {
+		if v := header.Get(h); v != "" {
+			return Verdict{Threat: icapThreat(v)}, nil
+		}
+	}
+	if header.Get("Encapsulated") != "" {
+		if line, err := tp.ReadLine(); err == nil {
+			if f := strings.Fields(line); len(f) >= 2 && f[1] == "200" {
+				return Verdict{Clean: true}, nil
+			}
+		}
+	}
+	return Verdict{Threat: "blocked by the ICAP server"}, nil
+}
+
+// icapThreat pulls the name out of "Type=0; Resolution=2; Threat=Eicar-Test-Signature;"
+func icapThreat(v string) string {
+	for _, part := range strings.Split(v, ";") {
+		if name, ok := strings.CutPrefix(strings.TrimSpace(part), "Threat="); ok && name != "" {
+			return name
+		}
+	}
+	return strings.TrimSpace(v)
+}
@@ -0,0 +1,53 @@
+// Package scan checks uploaded files for malware through a pluggable scanner
+// (a clamd daemon, an ICAP server, or a no-op for development).
+//
+//	UPLOAD_SCANNER=none | clamav | icap   (empty means none)
+//	CLAMAV_ADDR=localhost:3310             (or unix:/run/clamav/clamd.ctl)
+//	ICAP_URL=icap://localhost:1344/avscan
+package scan
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Verdict is a scanner's opinion of one file
+type Verdict struct {
+	Clean  bool
+	Threat string // What was found, e.g. "Eicar-Test-Signature"; empty when clean
+}
+
+// Scanner inspects a file. An error means the file could not be scanned, not
+// that it is infected: callers keep the file blocked and try again later.
+type Scanner interface {
+	// Name identifies the scanner in logs
+	Name() string
+	Scan(ctx context.Context, r io.Reader) (Verdict, error)
+}
+
+// Nop passes every file. It is the default, so development setups don't need
+// a virus scanner; production should set UPLOAD_SCANNER.
+type Nop struct{}
+
+func (Nop) Name() string { return "none" }
+
+func (Nop) Scan(ctx context.Context, r io.Reader) (Verdict, error) {
+	return Verdict{Clean: true}, nil
+}
+
+// FromEnv builds the scanner selected by UPLOAD_SCANNER
+func FromEnv() (Scanner, error) {
+	switch strings.ToLower(os.Getenv("UPLOAD_SCANNER")) {
+	case "", "none":
+		return Nop{}, nil
+	case "clamav":
+		return NewClamAVFromEnv()
+	case "icap":
+		return NewICAPFromEnv()
+	default:
+		return nil, fmt.Errorf("scan: unknown UPLOAD_SCANNER %q (want none, clamav or icap)", os.Getenv("UPLOAD_SCANNER"))
+	}
+}
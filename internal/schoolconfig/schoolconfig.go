@@ -0,0 +1,87 @@
+// Package schoolconfig exports the school's setup as a models.SchoolConfig
+// document and works out what importing one would change (GET/PUT /admin/config)
+package schoolconfig
+
+import (
+	"context"
+	"reflect"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"sort"
+)
+
+// SectionGradingSchemes names the grading schemes in ConfigChange.Section
+const SectionGradingSchemes = "grading_schemes"
+
+// Export reads the current setup
+func Export(ctx context.Context, schemes repository.GradingStore) (*models.SchoolConfig, error) {
+	current, err := schemes.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &models.SchoolConfig{Version: models.ConfigVersion, GradingSchemes: make([]models.ConfigGradingScheme, 0, len(current))}
+	for _, s := range current {
+		cfg.GradingSchemes = append(cfg.GradingSchemes, fromScheme(s))
+	}
+	return cfg, nil
+}
+
+// Plan lists the changes that turn current into cfg (a normalized document).
+// Entries missing from cfg are deleted: the document is the whole setup.
+func Plan(current []models.GradingScheme, cfg *models.SchoolConfig) []models.ConfigChange {
+	have := make(map[string]models.ConfigGradingScheme, len(current))
+	for _, s := range current {
+		have[s.Subject] = fromScheme(s)
+	}
+
+	changes := make([]models.ConfigChange, 0)
+	want := make(map[string]bool, len(cfg.GradingSchemes))
+	for _, s := range cfg.GradingSchemes {
+		want[s.Subject] = true
+		before, ok := have[s.Subject]
+		switch {
+		case !ok:
+			changes = append(changes, models.ConfigChange{Section: SectionGradingSchemes, Key: schemeKey(s.Subject), Action: models.ConfigCreate, After: s})
+		case !reflect.DeepEqual(before, s):
+			changes = append(changes, models.ConfigChange{Section: SectionGradingSchemes, Key: schemeKey(s.Subject), Action: models.ConfigUpdate, Before: before, After: s})
+		}
+	}
+	for subject, s := range have {
+		if !want[subject] {
+			changes = append(changes, models.ConfigChange{Section: SectionGradingSchemes, Key: schemeKey(subject), Action: models.ConfigDelete, Before: s})
+		}
+	}
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].Section != changes[j].Section {
+			return changes[i].Section < changes[j].Section
+		}
+		return changes[i].Key < changes[j].Key
+	})
+	return changes
+}
+
+// Schemes turns a document's grading schemes into the models the store takes
+func Schemes(cfg *models.SchoolConfig) []models.GradingScheme {
+	schemes := make([]models.GradingScheme, len(cfg.GradingSchemes))
+	for i, s := range cfg.GradingSchemes {
+		schemes[i] = toScheme(s)
+	}
+	return schemes
+}
+
+// schemeKey is how a scheme is named in a diff; the default has no subject
+func schemeKey(subject string) string {
+	if subject == "" {
+		return "(default)"
+	}
+	return subject
+}
+
+func fromScheme(s models.GradingScheme) models.ConfigGradingScheme {
+	return models.ConfigGradingScheme{Name: s.Name, Subject: s.Subject, Boundaries: s.Boundaries}
+}
+
+func toScheme(s models.ConfigGradingScheme) models.GradingScheme {
+	return models.GradingScheme{Name: s.Name, Subject: s.Subject, Boundaries: s.Boundaries}
+}
@@ -0,0 +1,99 @@
+// Package schoolprofile keeps the school's profile (models.School) at hand
+// for what prints it: transcripts, ID cards, the iCal feed, emails and SMS.
+// Until an admin first saves one, the SCHOOL_NAME and SCHOOL_TIMEZONE
+// settings stand in. The profile's time zone drives the app's clock, so
+// school days follow it.
+//
+// Each instance holds a copy: the one that saves a change applies it at
+// once, the others on their next Refresh (see Start).
+package schoolprofile
+
+import (
+	"context"
+	"errors"
+	"log"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"simpleapi/pkg/clock"
+	"sync"
+	"time"
+)
+
+// Profile is the current profile of the school
+type Profile struct {
+	store    repository.SchoolStore
+	clock    *clock.System
+	fallback models.School
+	zone     *time.Location // The clock's zone at startup, SCHOOL_TIMEZONE
+
+	mu      sync.RWMutex
+	current models.School
+}
+
+// New starts from the settings' fallback (name and, through clk, time zone)
+// until Refresh reads a saved profile
+func New(store repository.SchoolStore, clk *clock.System, fallback models.School) *Profile {
+	return &Profile{store: store, clock: clk, fallback: fallback, zone: clk.Location(), current: fallback}
+}
+
+// Get returns the current profile
+func (p *Profile) Get() models.School {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.current
+}
+
+// Name is the school's name, "" when neither the profile nor SCHOOL_NAME sets it
+func (p *Profile) Name() string {
+	return p.Get().Name
+}
+
+// Refresh reads the saved profile. With none saved yet the fallback stays.
+func (p *Profile) Refresh(ctx context.Context) error {
+	s, err := p.store.Get(ctx)
+	if errors.Is(err, models.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	p.Apply(*s)
+	return nil
+}
+
+// Apply makes s the current profile and moves the clock to its time zone,
+// back to SCHOOL_TIMEZONE when it has none
+func (p *Profile) Apply(s models.School) {
+	loc := p.zone
+	if s.Timezone != "" {
+		if l, err := time.LoadLocation(s.Timezone); err == nil {
+			loc = l
+		} else {
+			log.Printf("schoolprofile: keeping the time zone %s: %v", loc, err)
+		}
+	}
+
+	p.mu.Lock()
+	p.current = s
+	p.mu.Unlock()
+	p.clock.SetLocation(loc)
+}
+
+// Start refreshes the profile every interval until ctx is cancelled, picking
+// up what admins saved through other instances
+func (p *Profile) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			if err := p.Refresh(ctx); err != nil {
+				log.Printf("schoolprofile: refresh: %v", err)
+			}
+		}
+	}()
+}
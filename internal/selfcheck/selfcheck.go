@@ -0,0 +1,313 @@
+package selfcheck
+
+import (
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"os"
+	"simpleapi/internal/models"
+	"simpleapi/internal/redis"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Minimums for the JWT signing secret (HS256 wants at least 256 bits of key)
+const (
+	minSecretLength      = 32
+	minSecretEntropyBits = 128
+	maxClockDrift        = time.Minute
+)
+
+// RequiredTables must exist before we accept traffic
+var RequiredTables = []string{"teachers", "students", "audit_log", "student_comments", "grading_schemes", "student_scores", "grade_corrections", "sms_messages", "events", "academic_year_archives", "backups", "attendance", "promotion_reports", "message_threads", "thread_messages", "thread_reads", "uploads", "teacher_classes", "email_changes", "attendance_movements", "custom_fields", "outbox", "school", "classes", "subjects", "approvals", "communication_campaigns", "communication_recipients", "student_medical", "trips", "trip_consents", "rollover_plans", "attendance_alerts", "exam_sessions", "letter_templates", "documents"}
+
+// RequiredColumns are columns added to existing tables after they were created
+// ("table.column"), with what adds them
+var RequiredColumns = map[string]string{
+	"teachers.phone":                 "schoolctl migrate",
+	"students.guardian_phone":        "schoolctl migrate",
+	"students.date_of_birth":         "schoolctl migrate",
+	"students.gender":                "schoolctl migrate",
+	"students.nationality":           "schoolctl migrate",
+	"students.enrollment_date":       "schoolctl migrate",
+	"students.custom_fields":         "schoolctl migrate",
+	"students.status":                "schoolctl migrate",
+	"students.admission_number":      "schoolctl migrate",
+	"teachers.language":              "schoolctl migrate",
+	"teachers.timezone":              "schoolctl migrate",
+	"teachers.force_password_change": "schoolctl migrate",
+	"teachers.password_changed_at":   "schoolctl migrate",
+	"school.attendance_notice_below": "schoolctl migrate",
+}
+
+// Config lists what the self-check should look at.
+// Zero values skip the related check (e.g. DB is nil in tooling).
+type Config struct {
+	DB        *sql.DB
+	JWTSecret string
+	CertFile  string
+	KeyFile   string
+	Tables    []string
+	Redis     *redis.Client // Set with SHARED_STATE=redis
+}
+
+// Result is one line of the startup summary
+type Result struct {
+	Name    string
+	OK      bool
+	Message string
+	Hint    string // What the operator should do about a failure
+}
+
+// Run executes every check and returns all results (it never stops at the first failure,
+// so the operator sees everything that is wrong in one go)
+func Run(ctx context.Context, cfg Config) []Result {
+	tables := cfg.Tables
+	if tables == nil {
+		tables = RequiredTables
+	}
+
+	results := []Result{
+		checkJWTSecret(cfg.JWTSecret),
+		checkTLSFiles(cfg.CertFile, cfg.KeyFile),
+	}
+	if cfg.DB != nil {
+		results = append(results, checkTables(ctx, cfg.DB, tables), checkColumns(ctx, cfg.DB, RequiredColumns))
+	}
+	if cfg.Redis != nil {
+		results = append(results, checkRedis(ctx, cfg.Redis))
+	}
+	results = append(results, checkClock(ctx, cfg.DB))
+	return results
+}
+
+// Report prints a structured summary and returns true if every check passed
+func Report(w io.Writer, results []Result) bool {
+	allOK := true
+	fmt.Fprintln(w, "Startup self-check:")
+	for _, r := range results {
+		status := "OK  "
+		if !r.OK {
+			status = "FAIL"
+			allOK = false
+		}
+		fmt.Fprintf(w, "  [%s] %-12s %s\n", status, r.Name, r.Message)
+		if !r.OK && r.Hint != "" {
+			fmt.Fprintf(w, "         %-12s -> %s\n", "", r.Hint)
+		}
+	}
+	return allOK
+}
+
+// --- CHECKS ---
+
+func checkJWTSecret(secret string) Result {
+	res := Result{Name: "jwt_secret"}
+	if secret == "" {
+		res.Message = "JWT_SECRET_KEY is not set"
+		res.Hint = "generate one with: openssl rand -base64 48"
+		return res
+	}
+	if len(secret) < minSecretLength {
+		res.Message = fmt.Sprintf("secret is %d bytes, need at least %d", len(secret), minSecretLength)
+		res.Hint = "generate one with: openssl rand -base64 48"
+		return res
+	}
+	if bits := estimateEntropyBits(secret); bits < minSecretEntropyBits {
+		res.Message = fmt.Sprintf("secret looks guessable (~%.0f bits of entropy, need %d)", bits, minSecretEntropyBits)
+		res.Hint = "use a random value instead of a word or repeated characters"
+		return res
+	}
+	res.OK = true
+	res.Message = fmt.Sprintf("%d bytes", len(secret))
+	return res
+}
+
+// estimateEntropyBits is a Shannon estimate over the characters actually used.
+// It's crude, but it catches "aaaaaaaa..." and "changemechangeme..." style secrets.
+func estimateEntropyBits(s string) float64 {
+	freq := make(map[rune]float64)
+	for _, c := range s {
+		freq[c]++
+	}
+	n := float64(len([]rune(s)))
+	var perChar float64
+	for _, count := range freq {
+		p := count / n
+		perChar -= p * math.Log2(p)
+	}
+	return perChar * n
+}
+
+func checkTLSFiles(certFile, keyFile string) Result {
+	res := Result{Name: "tls"}
+	for _, f := range []string{certFile, keyFile} {
+		if _, err := os.Stat(f); err != nil {
+			res.Message = fmt.Sprintf("cannot read %s: %v", f, err)
+			res.Hint = "create a dev pair with: openssl req -x509 -newkey rsa:2048 -nodes -keyout key.pem -out cert.pem -days 365"
+			return res
+		}
+	}
+	if _, err := tls.LoadX509KeyPair(certFile, keyFile); err != nil {
+		res.Message = fmt.Sprintf("invalid key pair: %v", err)
+		res.Hint = "make sure the certificate and key belong together and are PEM encoded"
+		return res
+	}
+	res.OK = true
+	res.Message = fmt.Sprintf("%s / %s", certFile, keyFile)
+	return res
+}
+
+// Pending lists what the migrations still have to add to the schema: required
+// tables, then required columns, each with the command that adds it
+func Pending(ctx context.Context, db *sql.DB) ([]models.PendingMigration, error) {
+	pending := []models.PendingMigration{}
+	for _, table := range RequiredTables {
+		ok, err := exists(ctx, db, "SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?", table)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			pending = append(pending, models.PendingMigration{Missing: table, Fix: "go run ./cmd/schoolctl migrate"})
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(RequiredColumns)) {
+		table, column, _ := strings.Cut(name, ".")
+		ok, err := exists(ctx, db, "SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?", table, column)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			pending = append(pending, models.PendingMigration{Missing: name, Fix: RequiredColumns[name]})
+		}
+	}
+	return pending, nil
+}
+
+func exists(ctx context.Context, db *sql.DB, query string, args ...any) (bool, error) {
+	var n int
+	if err := db.QueryRowContext(ctx, query, args...).Scan(&n); err != nil {
+		return false, fmt.Errorf("selfcheck: could not inspect schema: %w", err)
+	}
+	return n > 0, nil
+}
+
+func checkTables(ctx context.Context, db *sql.DB, tables []string) Result {
+	res := Result{Name: "schema"}
+
+	var missing []string
+	for _, table := range tables {
+		var n int
+		err := db.QueryRowContext(ctx,
+			"SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?",
+			table).Scan(&n)
+		if err != nil {
+			res.Message = fmt.Sprintf("could not inspect schema: %v", err)
+			res.Hint = "check that DB_USERNAME can read information_schema"
+			return res
+		}
+		if n == 0 {
+			missing = append(missing, table)
+		}
+	}
+
+	if len(missing) > 0 {
+		res.Message = "missing tables: " + strings.Join(missing, ", ")
+		res.Hint = "run the database migrations against DB_NAME"
+		return res
+	}
+	res.OK = true
+	res.Message = fmt.Sprintf("%d required tables present", len(tables))
+	return res
+}
+
+func checkColumns(ctx context.Context, db *sql.DB, columns map[string]string) Result {
+	res := Result{Name: "columns"}
+
+	names := make([]string, 0, len(columns))
+	for name := range columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var missing, fixes []string
+	for _, name := range names {
+		table, column, _ := strings.Cut(name, ".")
+		var n int
+		err := db.QueryRowContext(ctx,
+			"SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ? AND column_name = ?",
+			table, column).Scan(&n)
+		if err != nil {
+			res.Message = fmt.Sprintf("could not inspect schema: %v", err)
+			res.Hint = "check that DB_USERNAME can read information_schema"
+			return res
+		}
+		if n == 0 {
+			missing = append(missing, name)
+			if fix := columns[name]; !slices.Contains(fixes, fix) {
+				fixes = append(fixes, fix)
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		res.Message = "missing columns: " + strings.Join(missing, ", ")
+		res.Hint = "run " + strings.Join(fixes, " and ")
+		return res
+	}
+	res.OK = true
+	res.Message = fmt.Sprintf("%d required columns present", len(columns))
+	return res
+}
+
+// checkRedis makes sure the shared state is reachable: without it every
+// instance would fall back to letting requests through and skipping jobs
+func checkRedis(ctx context.Context, client *redis.Client) Result {
+	res := Result{Name: "redis"}
+	if err := client.Ping(ctx); err != nil {
+		res.Message = fmt.Sprintf("%s: %v", client.Addr(), err)
+		res.Hint = "check REDIS_URL, or set SHARED_STATE=memory to run a single instance"
+		return res
+	}
+	res.OK = true
+	res.Message = "shared state at " + client.Addr()
+	return res
+}
+
+// checkClock catches badly wrong host clocks (and drift against MySQL when we have it),
+// which otherwise show up later as every JWT being "expired"
+func checkClock(ctx context.Context, db *sql.DB) Result {
+	res := Result{Name: "clock"}
+	now := time.Now()
+
+	if now.Year() < 2024 {
+		res.Message = fmt.Sprintf("system time %s is in the past", now.Format(time.RFC3339))
+		res.Hint = "enable NTP on the host"
+		return res
+	}
+
+	if db != nil {
+		var dbNow time.Time
+		if err := db.QueryRowContext(ctx, "SELECT UTC_TIMESTAMP()").Scan(&dbNow); err == nil {
+			drift := now.UTC().Sub(dbNow)
+			if drift < 0 {
+				drift = -drift
+			}
+			if drift > maxClockDrift {
+				res.Message = fmt.Sprintf("app and database clocks differ by %s", drift.Round(time.Second))
+				res.Hint = "sync both hosts with NTP"
+				return res
+			}
+		}
+	}
+
+	res.OK = true
+	res.Message = now.UTC().Format(time.RFC3339)
+	return res
+}
@@ -0,0 +1,168 @@
+package siem
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"simpleapi/internal/models"
+	"strings"
+	"sync"
+	"time"
+)
+
+// syslogFacility is "log audit" (13) in RFC 5424
+const syslogFacility = 13
+
+// Forwarder sends each outbox event to a SIEM, over syslog or HTTP. It is a
+// jobs.Publisher: the outbox relay retries what fails, so delivery is at
+// least once and the SIEM dedupes on the externalId (CEF) or id (JSON).
+type Forwarder struct {
+	kind   string // "tcp", "udp", "tls" or "http"
+	target string // host:port for syslog, the URL for HTTP
+	token  string
+	format string
+	host   string
+	client *http.Client
+
+	mu   sync.Mutex
+	conn net.Conn // syslog: dialed on first use, and again after an error
+}
+
+// NewForwarder checks the URL (see the package doc) and the format, without connecting
+func NewForwarder(rawURL, format, token string) (*Forwarder, error) {
+	if format == "" {
+		format = FormatCEF
+	}
+	if format != FormatCEF && format != FormatJSONL {
+		return nil, fmt.Errorf("siem: unknown SIEM_FORMAT %q, want %s or %s", format, FormatCEF, FormatJSONL)
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("siem: bad SIEM_FORWARD_URL: %w", err)
+	}
+	host, _ := os.Hostname()
+	if host == "" {
+		host = "-"
+	}
+	f := &Forwarder{format: format, token: token, host: host}
+	switch u.Scheme {
+	case "syslog+tcp", "syslog+udp", "syslog+tls":
+		if u.Port() == "" {
+			return nil, fmt.Errorf("siem: SIEM_FORWARD_URL %q needs a port", rawURL)
+		}
+		f.kind, f.target = strings.TrimPrefix(u.Scheme, "syslog+"), u.Host
+	case "http", "https":
+		f.kind, f.target = "http", rawURL
+		f.client = &http.Client{Timeout: 10 * time.Second}
+	default:
+		return nil, fmt.Errorf("siem: SIEM_FORWARD_URL %q must be syslog+tcp, syslog+udp, syslog+tls, http or https", rawURL)
+	}
+	return f, nil
+}
+
+// Name is where events go, for the logs (never the token)
+func (f *Forwarder) Name() string {
+	if f.kind == "http" {
+		if u, err := url.Parse(f.target); err == nil {
+			return u.Host
+		}
+	}
+	return f.kind + "://" + f.target
+}
+
+// Publish forwards one event
+func (f *Forwarder) Publish(ctx context.Context, e models.OutboxEvent) error {
+	event := FromOutbox(e)
+	line, err := Format(event, f.format)
+	if err != nil {
+		return err
+	}
+	if f.kind == "http" {
+		return f.post(ctx, event, line)
+	}
+	return f.syslog(ctx, event, line)
+}
+
+// post sends the line as the body of a POST
+func (f *Forwarder) post(ctx context.Context, e Event, line []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.target, bytes.NewReader(append(line, '\n')))
+	if err != nil {
+		return fmt.Errorf("siem: %w", err)
+	}
+	if f.format == FormatJSONL {
+		req.Header.Set("Content-Type", "application/x-ndjson")
+	} else {
+		req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	}
+	if f.token != "" {
+		req.Header.Set("Authorization", "Bearer "+f.token)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("siem: event %d to %s: %w", e.ID, f.Name(), err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("siem: event %d to %s: %s", e.ID, f.Name(), resp.Status)
+	}
+	return nil
+}
+
+// syslog sends the line as an RFC 5424 message; over TCP and TLS framed by
+// its length (RFC 6587), over UDP as one datagram
+func (f *Forwarder) syslog(ctx context.Context, e Event, line []byte) error {
+	severity := 6 // Informational
+	if Severity(e.Action) >= 5 {
+		severity = 4 // Warning
+	}
+	msg := fmt.Sprintf("<%d>1 %s %s school-api - %s - %s",
+		syslogFacility*8+severity, e.Time.UTC().Format(time.RFC3339Nano), f.host, strings.ReplaceAll(e.Action, " ", "_"), line)
+	if f.kind != "udp" {
+		msg = fmt.Sprintf("%d %s", len(msg), msg)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.conn == nil {
+		conn, err := f.dial(ctx)
+		if err != nil {
+			return fmt.Errorf("siem: connecting to %s: %w", f.Name(), err)
+		}
+		f.conn = conn
+	}
+	f.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	if _, err := io.WriteString(f.conn, msg); err != nil {
+		f.conn.Close()
+		f.conn = nil
+		return fmt.Errorf("siem: event %d to %s: %w", e.ID, f.Name(), err)
+	}
+	return nil
+}
+
+func (f *Forwarder) dial(ctx context.Context) (net.Conn, error) {
+	d := &net.Dialer{Timeout: 10 * time.Second}
+	if f.kind == "tls" {
+		td := &tls.Dialer{NetDialer: d}
+		return td.DialContext(ctx, "tcp", f.target)
+	}
+	return d.DialContext(ctx, f.kind, f.target)
+}
+
+// Close closes the syslog connection, if any
+func (f *Forwarder) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.conn == nil {
+		return nil
+	}
+	err := f.conn.Close()
+	f.conn = nil
+	return err
+}
@@ -0,0 +1,116 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"simpleapi/internal/models"
+	"simpleapi/pkg/secrets"
+	"strings"
+	"time"
+)
+
+// AfricasTalking sends through the Africa's Talking bulk SMS API.
+// Delivery reports are configured on their dashboard to point at our webhook.
+//
+//	AT_USERNAME=myschool (use "sandbox" with the sandbox API key)
+//	AT_API_KEY=... (or AT_API_KEY_FILE)
+type AfricasTalking struct {
+	username string
+	apiKey   string
+	baseURL  string
+	client   *http.Client
+}
+
+func NewAfricasTalkingFromEnv() (*AfricasTalking, error) {
+	key, err := secrets.FromEnv("AT_API_KEY")
+	if err != nil {
+		return nil, err
+	}
+	a := &AfricasTalking{
+		username: os.Getenv("AT_USERNAME"),
+		apiKey:   key,
+		baseURL:  "https://api.africastalking.com",
+		client:   &http.Client{Timeout: 10 * time.Second},
+	}
+	if a.username == "sandbox" {
+		a.baseURL = "https://api.sandbox.africastalking.com"
+	}
+	if a.username == "" || a.apiKey == "" {
+		return nil, fmt.Errorf("sms: africastalking needs AT_USERNAME and AT_API_KEY")
+	}
+	return a, nil
+}
+
+func (a *AfricasTalking) Name() string { return "africastalking" }
+
+func (a *AfricasTalking) Send(ctx context.Context, msg Message) (string, error) {
+	form := url.Values{"username": {a.username}, "to": {msg.To}, "message": {msg.Body}}
+	if msg.From != "" {
+		form.Set("from", msg.From)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.baseURL+"/version1/messaging", strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("sms: africastalking request: %w", err)
+	}
+	req.Header.Set("apiKey", a.apiKey)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("sms: africastalking request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("sms: africastalking returned %s", resp.Status)
+	}
+
+	var body struct {
+		SMSMessageData struct {
+			Message    string `json:"Message"`
+			Recipients []struct {
+				MessageID string `json:"messageId"`
+				Status    string `json:"status"`
+			} `json:"Recipients"`
+		} `json:"SMSMessageData"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("sms: invalid africastalking response: %w", err)
+	}
+	// One recipient per call, so a missing entry means it was rejected outright
+	if len(body.SMSMessageData.Recipients) == 0 {
+		return "", fmt.Errorf("sms: africastalking rejected message: %s", body.SMSMessageData.Message)
+	}
+	recipient := body.SMSMessageData.Recipients[0]
+	if recipient.Status != "Success" {
+		return "", fmt.Errorf("sms: africastalking rejected message: %s", recipient.Status)
+	}
+	return recipient.MessageID, nil
+}
+
+// ParseReport reads the delivery report callback (form fields id, status, failureReason)
+func (a *AfricasTalking) ParseReport(r *http.Request) (DeliveryReport, error) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("id") == "" {
+		return DeliveryReport{}, ErrBadReport
+	}
+
+	report := DeliveryReport{ProviderMessageID: r.PostForm.Get("id")}
+	switch r.PostForm.Get("status") {
+	case "Success":
+		report.Status = models.MessageDelivered
+	case "Failed", "Rejected":
+		report.Status = models.MessageFailed
+		report.Error = r.PostForm.Get("failureReason")
+	case "Buffered", "Submitted":
+		report.Status = models.MessageSent
+	default:
+		report.Status = models.MessageQueued
+	}
+	return report, nil
+}
@@ -0,0 +1,40 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"simpleapi/internal/models"
+	"sync/atomic"
+)
+
+// LogSender prints messages instead of sending them (development and demos).
+// Every message is reported as delivered straight away.
+type LogSender struct {
+	seq atomic.Int64
+}
+
+func NewLogSender() *LogSender {
+	return &LogSender{}
+}
+
+func (s *LogSender) Name() string { return "log" }
+
+func (s *LogSender) Send(ctx context.Context, msg Message) (string, error) {
+	id := fmt.Sprintf("log-%d", s.seq.Add(1))
+	log.Printf("sms[%s]: from=%q to=%s body=%q", id, msg.From, msg.To, msg.Body)
+	return id, nil
+}
+
+// ParseReport accepts form fields id and status, handy for exercising the webhook by hand
+func (s *LogSender) ParseReport(r *http.Request) (DeliveryReport, error) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("id") == "" {
+		return DeliveryReport{}, ErrBadReport
+	}
+	status := r.PostForm.Get("status")
+	if status == "" {
+		status = models.MessageDelivered
+	}
+	return DeliveryReport{ProviderMessageID: r.PostForm.Get("id"), Status: status}, nil
+}
@@ -0,0 +1,237 @@
+package sms
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"simpleapi/internal/models"
+	"simpleapi/internal/repository"
+	"simpleapi/internal/schoolprofile"
+	"strconv"
+	"strings"
+)
+
+// Notifier renders a template, records the message and sends it. It is the entry
+// point for anything that texts parents (admin endpoint today, scheduled jobs later).
+type Notifier struct {
+	Sender   Sender
+	Messages repository.MessageStore
+	SenderID string                 // SMS_SENDER_ID
+	School   *schoolprofile.Profile // Its name is filled in as {{.school}} in every template
+}
+
+// NewNotifier is the constructor
+func NewNotifier(sender Sender, messages repository.MessageStore, senderID string, school *schoolprofile.Profile) *Notifier {
+	return &Notifier{Sender: sender, Messages: messages, SenderID: senderID, School: school}
+}
+
+// Send texts one recipient. The message is recorded before it goes out, so a provider
+// failure still leaves a "failed" row behind; the error is returned as well.
+func (n *Notifier) Send(ctx context.Context, req models.SMSRequest) (*models.SMSMessage, error) {
+	params := map[string]string{"school": n.School.Name()}
+	for k, v := range req.Params {
+		params[k] = v
+	}
+	body, err := Render(req.Template, params)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := n.Messages.Create(ctx, models.SMSMessage{
+		StudentID: req.StudentID,
+		To:        req.To,
+		SenderID:  n.SenderID,
+		Template:  req.Template,
+		Body:      body,
+		Provider:  n.Sender.Name(),
+		Status:    models.MessageQueued,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	providerID, sendErr := n.Sender.Send(ctx, Message{To: req.To, From: n.SenderID, Body: body})
+	status, errText := models.MessageSent, ""
+	if sendErr != nil {
+		status, errText = models.MessageFailed, sendErr.Error()
+	}
+
+	updated, err := n.Messages.MarkSent(ctx, msg.ID, providerID, status, errText)
+	if err != nil {
+		// The provider has the message already; losing the status update only costs us tracking
+		log.Printf("sms: could not record status of message %d: %v", msg.ID, err)
+		updated = msg
+	}
+	if sendErr != nil {
+		return updated, fmt.Errorf("sms: send failed: %w", sendErr)
+	}
+	return updated, nil
+}
+
+// SendAbsenceAlert tells a student's guardian they were marked absent.
+// ErrNoPhone means the student has no guardian phone on file.
+func (n *Notifier) SendAbsenceAlert(ctx context.Context, student models.Student, date string) (*models.SMSMessage, error) {
+	if student.GuardianPhone == "" {
+		return nil, fmt.Errorf("sms: student %d: %w", student.ID, ErrNoPhone)
+	}
+	return n.Send(ctx, models.SMSRequest{
+		StudentID: &student.ID,
+		To:        student.GuardianPhone,
+		Template:  models.TemplateAbsenceAlert,
+		Params:    map[string]string{"student": student.FirstName + " " + student.LastName, "date": date},
+	})
+}
+
+// SendFeeReminder reminds a student's guardian of an outstanding fee
+func (n *Notifier) SendFeeReminder(ctx context.Context, student models.Student, amount, dueDate string) (*models.SMSMessage, error) {
+	if student.GuardianPhone == "" {
+		return nil, fmt.Errorf("sms: student %d: %w", student.ID, ErrNoPhone)
+	}
+	return n.Send(ctx, models.SMSRequest{
+		StudentID: &student.ID,
+		To:        student.GuardianPhone,
+		Template:  models.TemplateFeeReminder,
+		Params:    map[string]string{"student": student.FirstName + " " + student.LastName, "amount": amount, "due_date": dueDate},
+	})
+}
+
+// SendAttendanceReminder tells a class teacher their register for date is late.
+// ErrNoPhone means the teacher has no phone on file.
+func (n *Notifier) SendAttendanceReminder(ctx context.Context, teacher models.Teacher, date string) (*models.SMSMessage, error) {
+	if teacher.Phone == "" {
+		return nil, fmt.Errorf("sms: teacher %d: %w", teacher.ID, ErrNoPhone)
+	}
+	return n.Send(ctx, models.SMSRequest{
+		To:       teacher.Phone,
+		Template: models.TemplateAttendanceReminder,
+		Params:   map[string]string{"class": teacher.Class, "date": date},
+	})
+}
+
+// SendAbsenceSummary tells a student's guardian which days of the week they were absent
+func (n *Notifier) SendAbsenceSummary(ctx context.Context, student models.Student, dates []string) (*models.SMSMessage, error) {
+	if student.GuardianPhone == "" {
+		return nil, fmt.Errorf("sms: student %d: %w", student.ID, ErrNoPhone)
+	}
+	return n.Send(ctx, models.SMSRequest{
+		StudentID: &student.ID,
+		To:        student.GuardianPhone,
+		Template:  models.TemplateAbsenceSummary,
+		Params: map[string]string{
+			"student": student.FirstName + " " + student.LastName,
+			"count":   strconv.Itoa(len(dates)),
+			"dates":   strings.Join(dates, ", "),
+		},
+	})
+}
+
+// SendThreadMessage tells a student's guardian that staff posted in a thread
+// about them or their class. about names the student or the class; the body is
+// cut to fit a single SMS where possible.
+func (n *Notifier) SendThreadMessage(ctx context.Context, student models.Student, sender models.Teacher, about, body string) (*models.SMSMessage, error) {
+	if student.GuardianPhone == "" {
+		return nil, fmt.Errorf("sms: student %d: %w", student.ID, ErrNoPhone)
+	}
+	return n.Send(ctx, models.SMSRequest{
+		StudentID: &student.ID,
+		To:        student.GuardianPhone,
+		Template:  models.TemplateThreadMessage,
+		Params: map[string]string{
+			"sender":  sender.FirstName + " " + sender.LastName,
+			"about":   about,
+			"excerpt": excerpt(body, 80),
+		},
+	})
+}
+
+// SendTripConsent asks a student's guardian to consent to a trip by
+// deadline (YYYY-MM-DD), through link
+func (n *Notifier) SendTripConsent(ctx context.Context, student models.Student, trip models.Trip, deadline, link string) (*models.SMSMessage, error) {
+	if student.GuardianPhone == "" {
+		return nil, fmt.Errorf("sms: student %d: %w", student.ID, ErrNoPhone)
+	}
+	return n.Send(ctx, models.SMSRequest{
+		StudentID: &student.ID,
+		To:        student.GuardianPhone,
+		Template:  models.TemplateTripConsent,
+		Params: map[string]string{
+			"student":  student.FirstName,
+			"trip":     excerpt(trip.Title, 40),
+			"date":     trip.Date,
+			"deadline": deadline,
+			"link":     link,
+		},
+	})
+}
+
+// SendAttendanceAlert tells a student's guardian their attendance rate over the
+// last days fell below the threshold of level (models.AttendanceAlertNotice or
+// models.AttendanceAlertEscalated). ErrNoPhone means no guardian phone on file.
+func (n *Notifier) SendAttendanceAlert(ctx context.Context, student models.Student, level string, rate float64, days, threshold int) (*models.SMSMessage, error) {
+	if student.GuardianPhone == "" {
+		return nil, fmt.Errorf("sms: student %d: %w", student.ID, ErrNoPhone)
+	}
+	tmpl := models.TemplateAttendanceNotice
+	if level == models.AttendanceAlertEscalated {
+		tmpl = models.TemplateAttendanceEscalation
+	}
+	return n.Send(ctx, models.SMSRequest{
+		StudentID: &student.ID,
+		To:        student.GuardianPhone,
+		Template:  tmpl,
+		Params: map[string]string{
+			"student":   student.FirstName + " " + student.LastName,
+			"rate":      strconv.Itoa(int(rate)), // Rounded down, so 89.6 never reads as 90
+			"days":      strconv.Itoa(days),
+			"threshold": strconv.Itoa(threshold),
+		},
+	})
+}
+
+// SendAttendanceAtRisk tells a class teacher a student of theirs fell below an
+// attendance threshold. ErrNoPhone means the teacher has no phone on file.
+func (n *Notifier) SendAttendanceAtRisk(ctx context.Context, teacher models.Teacher, student models.Student, rate float64, days, threshold int) (*models.SMSMessage, error) {
+	if teacher.Phone == "" {
+		return nil, fmt.Errorf("sms: teacher %d: %w", teacher.ID, ErrNoPhone)
+	}
+	return n.Send(ctx, models.SMSRequest{
+		To:       teacher.Phone,
+		Template: models.TemplateAttendanceAtRisk,
+		Params: map[string]string{
+			"student":   student.FirstName + " " + student.LastName,
+			"class":     student.Class,
+			"rate":      strconv.Itoa(int(rate)),
+			"days":      strconv.Itoa(days),
+			"threshold": strconv.Itoa(threshold),
+		},
+	})
+}
+
+// SendUploadQuarantined tells a staff member the virus scanner blocked a file they uploaded.
+// ErrNoPhone means the teacher has no phone on file.
+func (n *Notifier) SendUploadQuarantined(ctx context.Context, teacher models.Teacher, fileName string) (*models.SMSMessage, error) {
+	if teacher.Phone == "" {
+		return nil, fmt.Errorf("sms: teacher %d: %w", teacher.ID, ErrNoPhone)
+	}
+	return n.Send(ctx, models.SMSRequest{
+		To:       teacher.Phone,
+		Template: models.TemplateUploadQuarantined,
+		Params:   map[string]string{"file": excerpt(fileName, 40)},
+	})
+}
+
+// excerpt shortens s to at most n runes, marking the cut with "..."
+func excerpt(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	return strings.TrimSpace(string(runes[:n-3])) + "..."
+}
+
+// ApplyReport records a delivery webhook. Unknown message IDs are ignored (false),
+// since providers retry webhooks and may report messages sent by another environment.
+func (n *Notifier) ApplyReport(ctx context.Context, report DeliveryReport) (bool, error) {
+	return n.Messages.UpdateStatus(ctx, n.Sender.Name(), report.ProviderMessageID, report.Status, report.Error)
+}
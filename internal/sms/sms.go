@@ -0,0 +1,60 @@
+// Package sms sends text messages to parents through a pluggable provider
+// (Twilio, Africa's Talking, or a logging stub for development) and turns the
+// providers' delivery webhooks into a common status.
+//
+//	SMS_PROVIDER=log | twilio | africastalking   (empty means log)
+//	SMS_SENDER_ID=MySchool                         (alphanumeric sender ID or number)
+package sms
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// ErrBadReport means a delivery webhook could not be parsed
+var ErrBadReport = errors.New("sms: malformed delivery report")
+
+// ErrNoPhone means the recipient has no phone number on file
+var ErrNoPhone = errors.New("sms: no phone number on file")
+
+// Message is one outgoing text
+type Message struct {
+	To   string // E.164, e.g. +2348012345678
+	From string // Sender ID; empty uses the provider account default
+	Body string
+}
+
+// DeliveryReport is a provider's delivery webhook, normalized
+type DeliveryReport struct {
+	ProviderMessageID string
+	Status            string // One of the models.Message* states
+	Error             string
+}
+
+// Sender delivers messages through one provider
+type Sender interface {
+	// Name is stored with each message so webhooks are matched to the right provider
+	Name() string
+	// Send hands the message to the provider and returns the provider's message ID
+	Send(ctx context.Context, msg Message) (string, error)
+	// ParseReport reads the provider's delivery-status webhook
+	ParseReport(r *http.Request) (DeliveryReport, error)
+}
+
+// SenderFromEnv builds the sender selected by SMS_PROVIDER
+func SenderFromEnv() (Sender, error) {
+	switch strings.ToLower(os.Getenv("SMS_PROVIDER")) {
+	case "", "log":
+		return NewLogSender(), nil
+	case "twilio":
+		return NewTwilioFromEnv()
+	case "africastalking":
+		return NewAfricasTalkingFromEnv()
+	default:
+		return nil, fmt.Errorf("sms: unknown SMS_PROVIDER %q (want log, twilio or africastalking)", os.Getenv("SMS_PROVIDER"))
+	}
+}
@@ -0,0 +1,46 @@
+package sms
+
+import (
+	"fmt"
+	"simpleapi/internal/models"
+	"strings"
+	"text/template"
+)
+
+// Keep messages under 160 GSM characters where possible: longer ones are billed as several SMS
+var templates = map[string]*template.Template{
+	models.TemplateAbsenceAlert: template.Must(template.New(models.TemplateAbsenceAlert).Option("missingkey=error").Parse(
+		"{{.school}}: {{.student}} was marked absent on {{.date}}. Please contact the school if this is unexpected.")),
+	models.TemplateFeeReminder: template.Must(template.New(models.TemplateFeeReminder).Option("missingkey=error").Parse(
+		"{{.school}}: fees of {{.amount}} for {{.student}} are due on {{.due_date}}. Kindly pay before the due date.")),
+	models.TemplateAttendanceReminder: template.Must(template.New(models.TemplateAttendanceReminder).Option("missingkey=error").Parse(
+		"{{.school}}: the {{.class}} register for {{.date}} has not been taken yet. Please submit it as soon as possible.")),
+	models.TemplateAbsenceSummary: template.Must(template.New(models.TemplateAbsenceSummary).Option("missingkey=error").Parse(
+		"{{.school}}: {{.student}} was absent {{.count}} day(s) this week ({{.dates}}). Please contact the school if this is unexpected.")),
+	models.TemplateThreadMessage: template.Must(template.New(models.TemplateThreadMessage).Option("missingkey=error").Parse(
+		"{{.school}}: message from {{.sender}} about {{.about}}: {{.excerpt}}")),
+	models.TemplateUploadQuarantined: template.Must(template.New(models.TemplateUploadQuarantined).Option("missingkey=error").Parse(
+		"{{.school}}: your file {{.file}} was blocked by the virus scanner and is held for review by an administrator.")),
+	models.TemplateTripConsent: template.Must(template.New(models.TemplateTripConsent).Option("missingkey=error").Parse(
+		"{{.school}}: may {{.student}} go on {{.trip}} on {{.date}}? Please answer by {{.deadline}}: {{.link}}")),
+	models.TemplateAttendanceNotice: template.Must(template.New(models.TemplateAttendanceNotice).Option("missingkey=error").Parse(
+		"{{.school}}: {{.student}} attended {{.rate}}% of school days in the last {{.days}} days, below our {{.threshold}}% target. Please contact the school.")),
+	models.TemplateAttendanceEscalation: template.Must(template.New(models.TemplateAttendanceEscalation).Option("missingkey=error").Parse(
+		"{{.school}}: {{.student}}'s attendance has fallen to {{.rate}}% in the last {{.days}} days. Please contact the school to arrange a meeting.")),
+	models.TemplateAttendanceAtRisk: template.Must(template.New(models.TemplateAttendanceAtRisk).Option("missingkey=error").Parse(
+		"{{.school}}: {{.student}} ({{.class}}) attended {{.rate}}% in the last {{.days}} days, below {{.threshold}}%. Their guardian has been told.")),
+}
+
+// Render fills a template. A missing parameter is an ErrInvalidInput naming it.
+func Render(name string, params map[string]string) (string, error) {
+	tmpl, ok := templates[name]
+	if !ok {
+		return "", fmt.Errorf("sms: unknown template %q: %w", name, models.ErrInvalidInput)
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, params); err != nil {
+		return "", fmt.Errorf("sms: template %s: %v: %w", name, err, models.ErrInvalidInput)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,104 @@
+package sms
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"simpleapi/internal/models"
+	"simpleapi/pkg/secrets"
+	"strings"
+	"time"
+)
+
+// Twilio sends through the Programmable Messaging REST API.
+//
+//	TWILIO_ACCOUNT_SID=AC...
+//	TWILIO_AUTH_TOKEN=... (or TWILIO_AUTH_TOKEN_FILE)
+//	SMS_STATUS_CALLBACK_URL=https://api.example.com/api/v1/webhooks/sms?token=...
+type Twilio struct {
+	accountSID  string
+	authToken   string
+	callbackURL string
+	baseURL     string
+	client      *http.Client
+}
+
+func NewTwilioFromEnv() (*Twilio, error) {
+	token, err := secrets.FromEnv("TWILIO_AUTH_TOKEN")
+	if err != nil {
+		return nil, err
+	}
+	t := &Twilio{
+		accountSID:  os.Getenv("TWILIO_ACCOUNT_SID"),
+		authToken:   token,
+		callbackURL: os.Getenv("SMS_STATUS_CALLBACK_URL"),
+		baseURL:     "https://api.twilio.com",
+		client:      &http.Client{Timeout: 10 * time.Second},
+	}
+	if t.accountSID == "" || t.authToken == "" {
+		return nil, fmt.Errorf("sms: twilio needs TWILIO_ACCOUNT_SID and TWILIO_AUTH_TOKEN")
+	}
+	return t, nil
+}
+
+func (t *Twilio) Name() string { return "twilio" }
+
+func (t *Twilio) Send(ctx context.Context, msg Message) (string, error) {
+	form := url.Values{"To": {msg.To}, "Body": {msg.Body}}
+	if msg.From != "" {
+		form.Set("From", msg.From)
+	}
+	if t.callbackURL != "" {
+		form.Set("StatusCallback", t.callbackURL)
+	}
+
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/Messages.json", t.baseURL, t.accountSID)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("sms: twilio request: %w", err)
+	}
+	req.SetBasicAuth(t.accountSID, t.authToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("sms: twilio request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		SID     string `json:"sid"`
+		Message string `json:"message"` // Set on errors
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("sms: invalid twilio response (%s): %w", resp.Status, err)
+	}
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("sms: twilio returned %s: %s", resp.Status, body.Message)
+	}
+	return body.SID, nil
+}
+
+// ParseReport reads Twilio's StatusCallback form (MessageSid, MessageStatus, ErrorCode)
+func (t *Twilio) ParseReport(r *http.Request) (DeliveryReport, error) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("MessageSid") == "" {
+		return DeliveryReport{}, ErrBadReport
+	}
+
+	report := DeliveryReport{ProviderMessageID: r.PostForm.Get("MessageSid")}
+	switch r.PostForm.Get("MessageStatus") {
+	case "delivered", "read":
+		report.Status = models.MessageDelivered
+	case "failed", "undelivered":
+		report.Status = models.MessageFailed
+		report.Error = "twilio error " + r.PostForm.Get("ErrorCode")
+	case "queued", "accepted", "scheduled":
+		report.Status = models.MessageQueued
+	default: // sending, sent
+		report.Status = models.MessageSent
+	}
+	return report, nil
+}
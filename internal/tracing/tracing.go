@@ -0,0 +1,113 @@
+// Package tracing wires OpenTelemetry so a single request can be followed
+// through middleware -> handler -> MySQL in Jaeger/Tempo.
+//
+// Configuration is via env:
+//
+//	TRACING_ENABLED=true                       turn tracing on (off by default)
+//	OTEL_EXPORTER_OTLP_ENDPOINT=http://host:4318  OTLP/HTTP collector (standard OTel var)
+//	OTEL_SERVICE_NAME=school-api               overrides the default service name
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"simpleapi/internal/metrics"
+	"strconv"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	tracerName         = "simpleapi"
+	defaultServiceName = "school-api"
+)
+
+// Init installs the global tracer provider and propagator.
+// The returned shutdown flushes pending spans and must be called before exit.
+// When tracing is disabled, the global no-op provider stays in place and every
+// span in the codebase costs next to nothing.
+func Init(ctx context.Context) (func(context.Context) error, error) {
+	// Always accept incoming W3C trace context, even if we don't export
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{},
+	))
+
+	if enabled, _ := strconv.ParseBool(os.Getenv("TRACING_ENABLED")); !enabled {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("tracing: failed to create OTLP exporter: %w", err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", defaultServiceName)),
+		resource.WithFromEnv(), // OTEL_SERVICE_NAME / OTEL_RESOURCE_ATTRIBUTES win
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("tracing: failed to build resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the application tracer (resolved lazily so it follows Init)
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// Start opens a child span of whatever span is already in ctx
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartQuery opens a client span for a repository call against MySQL. Ending
+// it also times the call for metrics.QueryLatency, tracing on or off.
+func StartQuery(ctx context.Context, name string) (context.Context, trace.Span) {
+	ctx, span := Tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("db.system", "mysql")),
+	)
+	return ctx, querySpan{Span: span, start: time.Now()}
+}
+
+type querySpan struct {
+	trace.Span
+	start time.Time
+}
+
+func (s querySpan) End(options ...trace.SpanEndOption) {
+	metrics.QueryLatency.Observe(time.Since(s.start))
+	s.Span.End(options...)
+}
+
+// RecordError marks the span as failed. Safe to call with a nil error. A
+// cancelled context is the client leaving, not a failure: it is only noted.
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	if errors.Is(err, context.Canceled) {
+		span.SetAttributes(attribute.Bool("canceled", true))
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
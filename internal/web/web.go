@@ -0,0 +1,26 @@
+// Package web embeds the compiled admin SPA so small deployments can serve it
+// from the API binary instead of a separate web server. Build the SPA into
+// internal/web/dist (index.html at its root, hashed bundles under assets/ or
+// static/) before go build; SERVE_SPA=true then mounts it under /.
+package web
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed all:dist
+var dist embed.FS
+
+// Dist returns the embedded build, and false when the binary was built
+// without one (dist holds only its placeholder)
+func Dist() (fs.FS, bool) {
+	files, err := fs.Sub(dist, "dist")
+	if err != nil {
+		return nil, false
+	}
+	if _, err := fs.Stat(files, "index.html"); err != nil {
+		return nil, false
+	}
+	return files, true
+}
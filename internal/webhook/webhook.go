@@ -0,0 +1,77 @@
+// Package webhook publishes outbox events to the school's endpoint
+// (OUTBOX_WEBHOOK_URL) as signed JSON POSTs, following the Standard Webhooks
+// headers so receivers can verify them with an off-the-shelf library:
+//
+//	webhook-id: 42                      (the event ID; delivery is at least once, dedupe on it)
+//	webhook-timestamp: 1767225600       (Unix seconds; reject stale ones)
+//	webhook-signature: v1,<base64 HMAC-SHA256 of "<id>.<timestamp>.<body>">
+//
+// The HMAC key is OUTBOX_WEBHOOK_SECRET.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"simpleapi/internal/models"
+	"simpleapi/pkg/clock"
+	"strconv"
+	"time"
+)
+
+// Publisher posts events to one endpoint
+type Publisher struct {
+	url    string
+	secret []byte
+	clock  clock.Clock
+	client *http.Client
+}
+
+// New is the constructor
+func New(url string, secret []byte, clk clock.Clock) *Publisher {
+	return &Publisher{url: url, secret: secret, clock: clk, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// Publish delivers one event; anything but a 2xx answer is an error, and the
+// relay tries again later
+func (p *Publisher) Publish(ctx context.Context, e models.OutboxEvent) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("webhook: encoding event %d: %w", e.ID, err)
+	}
+	id := strconv.Itoa(e.ID)
+	timestamp := strconv.FormatInt(p.clock.Now().Unix(), 10)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("webhook-id", id)
+	req.Header.Set("webhook-timestamp", timestamp)
+	req.Header.Set("webhook-signature", "v1,"+p.sign(id, timestamp, body))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook: posting event %d: %w", e.ID, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10)) // Drain, so the connection is reused
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook: event %d answered %s", e.ID, resp.Status)
+	}
+	return nil
+}
+
+func (p *Publisher) sign(id, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write([]byte(id + "." + timestamp + "."))
+	mac.Write(body)
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,137 @@
+// Package errcodes is the registry of machine-readable error codes. Every
+// error response carries one in its "code" member next to the human message,
+// so clients branch on the code and are free to show (or translate) the message.
+//
+// Codes are part of the API contract: never rename or reuse one. A new code
+// goes here with its doc comment, and api/envelope.ts is regenerated (go
+// generate ./pkg/utils) so the frontend sees it too.
+package errcodes
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Code is the "code" member of every error envelope
+type Code string
+
+// Generic codes, one per status, used when nothing more specific applies
+const (
+	BadRequest         Code = "BAD_REQUEST"         // 400: malformed body or parameter
+	InvalidID          Code = "INVALID_ID"          // 400: a path ID that isn't a whole number from 1 to 2147483647
+	ValidationFailed   Code = "VALIDATION_FAILED"   // 400: details lists the failing fields
+	Unauthorized       Code = "UNAUTHORIZED"        // 401
+	Forbidden          Code = "FORBIDDEN"           // 403: logged in, but not allowed
+	NotFound           Code = "NOT_FOUND"           // 404
+	Conflict           Code = "CONFLICT"            // 409
+	PreconditionFailed Code = "PRECONDITION_FAILED" // 412: If-Match no longer matches
+	TooLarge           Code = "PAYLOAD_TOO_LARGE"   // 413
+	RateLimited        Code = "RATE_LIMITED"        // 429
+	RequestCanceled    Code = "REQUEST_CANCELED"    // 499: the client closed the request before the response was ready
+	Internal           Code = "INTERNAL"            // 500
+	Unavailable        Code = "SERVICE_UNAVAILABLE" // 503: not configured or overloaded, try later
+)
+
+// Authentication
+const (
+	NotLoggedIn        Code = "NOT_LOGGED_IN"        // No session cookie or bearer token
+	InvalidCredentials Code = "INVALID_CREDENTIALS"  // Login with a wrong email or password
+	TokenInvalid       Code = "TOKEN_INVALID"        // Bad signature or not a token at all
+	TokenExpired       Code = "TOKEN_EXPIRED"        // Log in (or refresh) again
+	TokenWrongAudience Code = "TOKEN_WRONG_AUDIENCE" // Token minted for another kind of client (e.g. a kiosk)
+	PasswordChanged    Code = "PASSWORD_CHANGED"     // Token issued before the last password change
+	AccountDeactivated Code = "ACCOUNT_DEACTIVATED"  // Login or session of a deactivated account
+	AccountGone        Code = "ACCOUNT_GONE"         // The token's user no longer exists
+	// The account must change its password (PATCH /update-password) before anything else
+	PasswordChangeRequired Code = "PASSWORD_CHANGE_REQUIRED"
+	// Too many failed logins for the account: wait for Retry-After (see package loginguard)
+	LoginThrottled Code = "LOGIN_THROTTLED"
+	// The account failed too often: log in again with a captcha_token from the widget
+	CaptchaRequired Code = "CAPTCHA_REQUIRED"
+)
+
+// Authorization
+const (
+	ClassNotAssigned Code = "CLASS_NOT_ASSIGNED" // The student or class belongs to a class the teacher isn't assigned to
+)
+
+// Resources
+const (
+	TeacherNotFound      Code = "TEACHER_NOT_FOUND"
+	StudentNotFound      Code = "STUDENT_NOT_FOUND"
+	EmailTaken           Code = "EMAIL_TAKEN"            // Unique email already in use
+	AdmissionNumberTaken Code = "ADMISSION_NUMBER_TAKEN" // Unique admission number already in use
+	HasDependents        Code = "HAS_DEPENDENTS"         // Would orphan records, see details
+	QuotaExceeded        Code = "QUOTA_EXCEEDED"         // Would take the school past a quota, see details
+)
+
+// Uploads
+const (
+	UploadPendingScan Code = "UPLOAD_PENDING_SCAN" // Not downloadable until the virus scan passes; retry later
+	UploadBlocked     Code = "UPLOAD_BLOCKED"      // Quarantined or rejected by the virus scan
+)
+
+// Email changes
+const (
+	EmailChangeRequired    Code = "EMAIL_CHANGE_REQUIRED"     // Emails change through POST /teachers/{id}/email-change, not PATCH or PUT
+	EmailChangeLinkInvalid Code = "EMAIL_CHANGE_LINK_INVALID" // Confirm or undo link unknown, expired or already used
+)
+
+// Trips
+const (
+	ConsentLinkInvalid Code = "CONSENT_LINK_INVALID" // Consent link unknown, replaced by a newer one, or past the trip's consent deadline
+)
+
+// JSON Patch (application/json-patch+json)
+const (
+	PatchInvalid    Code = "PATCH_INVALID"     // An operation is malformed, unsupported, or not allowed on its path
+	PatchTestFailed Code = "PATCH_TEST_FAILED" // A "test" operation didn't match: the record changed since it was read
+)
+
+// StatusClientClosedRequest is nginx's 499, which net/http has no name for: the
+// client went away, so nobody reads the response and it isn't a server failure
+const StatusClientClosedRequest = 499
+
+// ForStatus is the generic code of an HTTP status
+func ForStatus(status int) Code {
+	switch status {
+	case http.StatusBadRequest:
+		return BadRequest
+	case http.StatusUnauthorized:
+		return Unauthorized
+	case http.StatusForbidden:
+		return Forbidden
+	case http.StatusNotFound:
+		return NotFound
+	case http.StatusConflict:
+		return Conflict
+	case http.StatusPreconditionFailed:
+		return PreconditionFailed
+	case http.StatusRequestEntityTooLarge:
+		return TooLarge
+	case http.StatusTooManyRequests:
+		return RateLimited
+	case StatusClientClosedRequest:
+		return RequestCanceled
+	case http.StatusServiceUnavailable:
+		return Unavailable
+	}
+	if status >= 500 {
+		return Internal
+	}
+	return BadRequest
+}
+
+// Coder is implemented by errors that know their code (e.g. models.ConflictError)
+type Coder interface {
+	ErrorCode() Code
+}
+
+// Of returns the code of the first error in err's chain that has one, or ""
+func Of(err error) Code {
+	var c Coder
+	if errors.As(err, &c) {
+		return c.ErrorCode()
+	}
+	return ""
+}
@@ -0,0 +1,201 @@
+// Package jsonpatch applies JSON Patch documents (RFC 6902, Content-Type
+// application/json-patch+json) to a record's patchable fields, so clients can
+// use a standard patch library instead of our map format.
+//
+// Only the add, replace, remove and test operations are supported, on object
+// members the caller's Rules allow; move and copy, and arrays, are refused.
+// The record is given as a JSON object (map[string]any) and changed in place:
+// the caller diffs it against the original and saves the changes its usual way.
+package jsonpatch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// MediaType is the Content-Type of a JSON Patch
+const MediaType = "application/json-patch+json"
+
+// Operations
+const (
+	OpAdd     = "add"
+	OpReplace = "replace"
+	OpRemove  = "remove"
+	OpTest    = "test"
+)
+
+var (
+	// ErrInvalid is a malformed patch or an operation the Rules don't allow
+	ErrInvalid = errors.New("invalid patch")
+	// ErrTestFailed is a test operation whose value didn't match: the record
+	// changed since the client read it
+	ErrTestFailed = errors.New("test failed")
+)
+
+// Operation is one step of a patch
+type Operation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value,omitempty"`
+	From  string          `json:"from,omitempty"` // move and copy, which are refused
+}
+
+// Patch is a JSON Patch document
+type Patch []Operation
+
+// Error is the failure of one operation; it wraps ErrInvalid or ErrTestFailed
+type Error struct {
+	Index  int // Of the operation in the patch
+	Op     string
+	Path   string
+	Reason string
+	Err    error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("operation %d (%s %s): %s", e.Index, e.Op, e.Path, e.Reason)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Rules maps each patchable path to the operations allowed on it. A last
+// segment of "*" stands for any member of the object above it, e.g.
+// "/custom_fields/*".
+type Rules map[string][]string
+
+// Allows reports whether op may be applied at path
+func (r Rules) Allows(op, path string) bool {
+	if ops, ok := r[path]; ok {
+		return slices.Contains(ops, op)
+	}
+	if i := strings.LastIndexByte(path, '/'); i >= 0 {
+		if ops, ok := r[path[:i]+"/*"]; ok {
+			return slices.Contains(ops, op)
+		}
+	}
+	return false
+}
+
+// Decode reads a patch: a JSON array of operations, at most max of them
+func Decode(body io.Reader, max int) (Patch, error) {
+	var p Patch
+	decoder := json.NewDecoder(body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&p); err != nil {
+		return nil, fmt.Errorf("%w: not a JSON array of operations: %v", ErrInvalid, err)
+	}
+	if len(p) == 0 {
+		return nil, fmt.Errorf("%w: no operations", ErrInvalid)
+	}
+	if len(p) > max {
+		return nil, fmt.Errorf("%w: more than %d operations", ErrInvalid, max)
+	}
+	return p, nil
+}
+
+// Apply applies the operations to doc in order. It stops at the first that
+// fails, leaving doc partly patched: callers save nothing on an error.
+func (p Patch) Apply(doc map[string]any, rules Rules) error {
+	for i, op := range p {
+		fail := func(err error, reason string, args ...any) error {
+			return &Error{Index: i, Op: op.Op, Path: op.Path, Reason: fmt.Sprintf(reason, args...), Err: err}
+		}
+		switch op.Op {
+		case OpAdd, OpReplace, OpRemove, OpTest:
+		case "move", "copy":
+			return fail(ErrInvalid, "%s isn't supported", op.Op)
+		default:
+			return fail(ErrInvalid, "unknown operation %q", op.Op)
+		}
+		tokens, err := parsePointer(op.Path)
+		if err != nil {
+			return fail(ErrInvalid, "%v", err)
+		}
+		if len(tokens) == 0 || !rules.Allows(op.Op, op.Path) {
+			return fail(ErrInvalid, "%s isn't allowed on this path", op.Op)
+		}
+
+		parent, err := resolve(doc, tokens[:len(tokens)-1])
+		if err != nil {
+			return fail(ErrInvalid, "%v", err)
+		}
+		key := tokens[len(tokens)-1]
+		current, exists := parent[key]
+
+		var value any
+		if op.Op != OpRemove {
+			if len(op.Value) == 0 {
+				return fail(ErrInvalid, "missing value")
+			}
+			if err := json.Unmarshal(op.Value, &value); err != nil {
+				return fail(ErrInvalid, "bad value: %v", err)
+			}
+		}
+		switch op.Op {
+		case OpAdd:
+			parent[key] = value
+		case OpReplace:
+			if !exists {
+				return fail(ErrInvalid, "nothing to replace")
+			}
+			parent[key] = value
+		case OpRemove:
+			if !exists {
+				return fail(ErrInvalid, "nothing to remove")
+			}
+			delete(parent, key)
+		case OpTest:
+			if !exists || !reflect.DeepEqual(normalize(current), value) {
+				return fail(ErrTestFailed, "value differs")
+			}
+		}
+	}
+	return nil
+}
+
+// parsePointer splits a JSON Pointer (RFC 6901) into its unescaped tokens
+func parsePointer(path string) ([]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(path, "/") {
+		return nil, fmt.Errorf("path %q must start with /", path)
+	}
+	tokens := strings.Split(path[1:], "/")
+	for i, t := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(t)
+	}
+	return tokens, nil
+}
+
+// resolve walks tokens down nested objects
+func resolve(doc map[string]any, tokens []string) (map[string]any, error) {
+	node := doc
+	for i, t := range tokens {
+		child, ok := node[t].(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("/%s is not an object", strings.Join(tokens[:i+1], "/"))
+		}
+		node = child
+	}
+	return node, nil
+}
+
+// normalize makes a Go value compare equal to its JSON decoding, so a test
+// against the record's int or typed fields matches the client's number
+func normalize(v any) any {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out any
+	if json.Unmarshal(b, &out) != nil {
+		return v
+	}
+	return out
+}
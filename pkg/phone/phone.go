@@ -0,0 +1,57 @@
+// Package phone normalizes phone numbers to E.164 (+<country code><number>).
+// Staff type numbers the way they are printed locally ("0803 123 4567"), so
+// numbers without a country code are read in the school's default region
+// (PHONE_DEFAULT_REGION, e.g. NG). The numbering plans themselves come from
+// libphonenumber (github.com/ttacon/libphonenumber, a port of Google's).
+package phone
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ttacon/libphonenumber"
+)
+
+// ErrInvalid means the input can't be read as a phone number
+var ErrInvalid = errors.New("phone: invalid phone number")
+
+var (
+	mu            sync.RWMutex
+	defaultRegion string
+)
+
+// SetDefaultRegion sets the region (ISO 3166 code) for numbers given without a
+// country code. Empty means such numbers are rejected.
+func SetDefaultRegion(code string) error {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if _, ok := libphonenumber.GetSupportedRegions()[code]; code != "" && !ok {
+		return fmt.Errorf("phone: unsupported region %q", code)
+	}
+	mu.Lock()
+	defaultRegion = code
+	mu.Unlock()
+	return nil
+}
+
+// Normalize returns raw in E.164 form. "+" or the region's international
+// prefix (e.g. "00") starts an international number, anything else is
+// national; the number must be valid in its country's numbering plan.
+func Normalize(raw string) (string, error) {
+	mu.RLock()
+	region := defaultRegion
+	mu.RUnlock()
+	if region == "" {
+		region = "ZZ" // Unknown region: only international numbers parse
+	}
+
+	number, err := libphonenumber.Parse(raw, region)
+	if err != nil {
+		return "", fmt.Errorf("%w: %q", ErrInvalid, raw)
+	}
+	if !libphonenumber.IsValidNumber(number) {
+		return "", fmt.Errorf("%w: %q", ErrInvalid, raw)
+	}
+	return libphonenumber.Format(number, libphonenumber.E164), nil
+}
@@ -0,0 +1,86 @@
+package phone
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		region string
+		raw    string
+		want   string // Empty when raw is invalid
+	}{
+		{"NG", "0803 123 4567", "+2348031234567"},
+		{"NG", "803-123-4567", "+2348031234567"},
+		{"NG", "2348031234567", "+2348031234567"},        // Country code without the "+"
+		{"NG", "+234 (0)803 123 4567", "+2348031234567"}, // Trunk 0 written after the country code
+		{"NG", "+234 803 123 4567", "+2348031234567"},
+		{"NG", "009 234 803 123 4567", "+2348031234567"}, // Nigeria dials out with 009
+		{"NG", "01 234 5678", "+23412345678"},            // Lagos landline, 8 digits
+		{"NG", "+234 1 234 5678", "+23412345678"},
+		{"NG", "0803 123 456", ""},
+		{"NG", "+234 803 123 45678", ""}, // Too long for Nigeria
+		{"NG", "+44 7911 123456", "+447911123456"},
+		{"NG", "+1 (415) 555-0132", "+14155550132"},
+		{"NG", "+86 139 1234 5678", "+8613912345678"},
+		{"NG", "+44 20 1234 567", ""}, // Too short for London
+		{"NG", "+0 803 123 4567", ""},
+		{"NG", "+1234567", ""}, // Under 8 digits
+		{"NG", "+1234567890123456", ""},
+		{"NG", "call me", ""},
+		{"NG", "+", ""},
+		{"NG", "", ""},
+		{"GH", "024 123 4567", "+233241234567"},
+		{"KE", "0712 345678", "+254712345678"},
+		{"ZA", "082 123 4567", "+27821234567"},
+		{"GB", "07911 123456", "+447911123456"},
+		{"GB", "020 7946 0018", "+442079460018"},
+		{"GB", "+44 (0)20 7946 0018", "+442079460018"},
+		{"FR", "06.12.34.56.78", "+33612345678"},
+		{"US", "(415) 555-0132", "+14155550132"},
+		{"US", "1 415 555 0132", "+14155550132"}, // US trunk prefix
+		{"", "0803 123 4567", ""},                // No default region: national numbers are refused
+		{"", "+234 803 123 4567", "+2348031234567"},
+		{"GB", "00234 803 123 4567", "+2348031234567"},
+	}
+	defer SetDefaultRegion("")
+	for _, tt := range tests {
+		t.Run(tt.region+" "+tt.raw, func(t *testing.T) {
+			if err := SetDefaultRegion(tt.region); err != nil {
+				t.Fatal(err)
+			}
+			got, err := Normalize(tt.raw)
+			if tt.want == "" {
+				if !errors.Is(err, ErrInvalid) {
+					t.Errorf("Normalize(%q) = %q, %v, want ErrInvalid", tt.raw, got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("Normalize(%q) = %q, %v, want %q", tt.raw, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetDefaultRegion(t *testing.T) {
+	defer SetDefaultRegion("")
+	tests := []struct {
+		code    string
+		wantErr bool
+	}{
+		{"", false},
+		{"NG", false},
+		{" ng ", false},
+		{"XX", true},
+		{"Nigeria", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			if err := SetDefaultRegion(tt.code); (err != nil) != tt.wantErr {
+				t.Errorf("SetDefaultRegion(%q) = %v, want error %v", tt.code, err, tt.wantErr)
+			}
+		})
+	}
+}
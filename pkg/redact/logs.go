@@ -0,0 +1,207 @@
+package redact
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces what a LogHandler hides
+const Redacted = "[redacted]"
+
+// DefaultLogFields are the field names a LogHandler hides the values of:
+// secrets and what identifies a person. Patterns match names case-insensitively
+// and ignoring _, - and ., so "first_name" also hides FirstName.
+var DefaultLogFields = []string{
+	"*password*", "*secret*", "*token*", "*pepper*", "*apikey*", "*authorization*", "*cookie*", "*signature*",
+	"*email*", "*phone*", "name", "first_name", "last_name", "full_name", "guardian_name", "date_of_birth", "address",
+}
+
+var (
+	// pair is key=value, key: value or "key":"value" in free text, as the log
+	// package's Printf and %+v of a struct write them
+	pair = regexp.MustCompile(`("?)([A-Za-z_][\w.-]*)("?\s*[:=]\s*)("(?:[^"\\]|\\.)*"|'[^']*'|[^\s,;&)}\]]+)`)
+
+	email  = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	bearer = regexp.MustCompile(`(?i)\b(bearer)\s+[A-Za-z0-9._~+/-]{16,}=*`)
+	jwt    = regexp.MustCompile(`\beyJ[\w-]*\.[\w-]+\.[\w-]*`)
+	// MySQL echoes the offending value of a unique key back in its error
+	duplicate = regexp.MustCompile(`(Duplicate entry )'(?:[^'\\]|\\.)*'`)
+)
+
+// LogHandler hides secrets and personal data from the logs of the slog
+// handler it wraps: attributes named like Fields, the same keys inside
+// messages and values (errors, request bodies, structs), email addresses,
+// bearer tokens and the values MySQL quotes in its errors. It can't spot a
+// name in plain prose, so log IDs rather than people.
+type LogHandler struct {
+	next   slog.Handler
+	fields []string
+}
+
+// NewLogHandler wraps next, hiding fields (see DefaultLogFields). It fails on a
+// malformed pattern.
+func NewLogHandler(next slog.Handler, fields []string) (*LogHandler, error) {
+	h := &LogHandler{next: next}
+	for _, f := range fields {
+		p := normalizeField(f)
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("redact: bad log field pattern %q: %w", f, err)
+		}
+		h.fields = append(h.fields, p)
+	}
+	return h, nil
+}
+
+func (h *LogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *LogHandler) Handle(ctx context.Context, r slog.Record) error {
+	clean := slog.NewRecord(r.Time, r.Level, h.Text(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		clean.AddAttrs(h.attr(a))
+		return true
+	})
+	return h.next.Handle(ctx, clean)
+}
+
+func (h *LogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clean := make([]slog.Attr, len(attrs))
+	for i, a := range attrs {
+		clean[i] = h.attr(a)
+	}
+	return &LogHandler{next: h.next.WithAttrs(clean), fields: h.fields}
+}
+
+func (h *LogHandler) WithGroup(name string) slog.Handler {
+	return &LogHandler{next: h.next.WithGroup(name), fields: h.fields}
+}
+
+// Text hides what the handler would in a log message
+func (h *LogHandler) Text(s string) string {
+	if s == "" {
+		return s
+	}
+	// Tokens first: "Authorization: Bearer x" would only hide the word Bearer
+	s = bearer.ReplaceAllString(s, "$1 "+Redacted)
+	s = jwt.ReplaceAllString(s, Redacted)
+	s = replacePairs(s, h.hides)
+	s = duplicate.ReplaceAllString(s, "$1'"+Redacted+"'")
+	return email.ReplaceAllString(s, Redacted)
+}
+
+// hides says whether the values of field are hidden
+func (h *LogHandler) hides(field string) bool {
+	field = normalizeField(field)
+	for _, p := range h.fields {
+		if ok, _ := path.Match(p, field); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *LogHandler) attr(a slog.Attr) slog.Attr {
+	v := a.Value.Resolve()
+	if v.Kind() == slog.KindGroup {
+		group := v.Group()
+		clean := make([]slog.Attr, len(group))
+		for i, ga := range group {
+			clean[i] = h.attr(ga)
+		}
+		return slog.Attr{Key: a.Key, Value: slog.GroupValue(clean...)}
+	}
+	if h.hides(a.Key) {
+		return slog.String(a.Key, Redacted)
+	}
+	return slog.Attr{Key: a.Key, Value: h.value(v)}
+}
+
+func (h *LogHandler) value(v slog.Value) slog.Value {
+	switch v.Kind() {
+	case slog.KindString:
+		return slog.StringValue(h.Text(v.String()))
+	case slog.KindAny:
+	default:
+		return v
+	}
+
+	switch x := v.Any().(type) {
+	case nil:
+		return v
+	case error:
+		return slog.StringValue(h.Text(x.Error()))
+	case fmt.Stringer:
+		return slog.StringValue(h.Text(x.String()))
+	case []byte: // A request body
+		return slog.StringValue(h.Text(string(x)))
+	}
+	// Anything else is logged as its JSON, with the hidden keys hidden
+	data, err := json.Marshal(v.Any())
+	if err != nil {
+		return slog.StringValue(h.Text(fmt.Sprintf("%+v", v.Any())))
+	}
+	var tree any
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return slog.StringValue(h.Text(string(data)))
+	}
+	return slog.AnyValue(h.tree(tree))
+}
+
+// tree hides the keys and text of a decoded JSON value, in place
+func (h *LogHandler) tree(v any) any {
+	switch x := v.(type) {
+	case map[string]any:
+		for k, e := range x {
+			if h.hides(k) {
+				x[k] = Redacted
+			} else {
+				x[k] = h.tree(e)
+			}
+		}
+	case []any:
+		for i, e := range x {
+			x[i] = h.tree(e)
+		}
+	case string:
+		return h.Text(x)
+	}
+	return v
+}
+
+// replacePairs hides the values of the key-value pairs of s whose key hides says to
+func replacePairs(s string, hides func(string) bool) string {
+	matches := pair.FindAllStringSubmatchIndex(s, -1)
+	if matches == nil {
+		return s
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		key, value := s[m[4]:m[5]], s[m[8]:m[9]]
+		if !hides(key) {
+			continue
+		}
+		b.WriteString(s[last:m[8]])
+		switch value[0] {
+		case '"', '\'':
+			b.WriteByte(value[0])
+			b.WriteString(Redacted)
+			b.WriteByte(value[0])
+		default:
+			b.WriteString(Redacted)
+		}
+		last = m[9]
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+func normalizeField(f string) string {
+	return strings.NewReplacer("_", "", "-", "", ".", "").Replace(strings.ToLower(strings.TrimSpace(f)))
+}
@@ -0,0 +1,173 @@
+// Package redact hides response fields from viewers who may not see them.
+// A field says who may with its visibility tag, a comma-separated list of
+// roles plus "self", the viewer the value describes (see Owner):
+//
+//	Phone string `json:"phone,omitempty" visibility:"admin,registrar,self"`
+//
+// Fields without the tag are visible to everyone. A hidden field is zeroed,
+// so tag only omitempty fields or the key stays with its zero value.
+//
+// LogHandler does the same for logs: it hides secrets and personal data from
+// log messages and attributes by field name (see DefaultLogFields).
+package redact
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Tag is the struct tag naming who may see a field
+const Tag = "visibility"
+
+// Self in a visibility tag admits the viewer the value describes
+const Self = "self"
+
+// Viewer is who a response is for. The zero Viewer is anonymous: it sees only
+// untagged fields.
+type Viewer struct {
+	ID   int
+	Role string
+}
+
+// Owner is implemented by values that describe a viewer, e.g. a teacher's own
+// account, for visibility:"self"
+type Owner interface {
+	OwnerID() int
+}
+
+// Apply returns v with every field viewer may not see zeroed. v itself is
+// left alone: what holds a hidden field is copied, the rest is shared.
+func Apply(v any, viewer Viewer) any {
+	if v == nil {
+		return nil
+	}
+	rv := reflect.ValueOf(v)
+	if !needs(rv.Type()) {
+		return v
+	}
+	return apply(rv, viewer).Interface()
+}
+
+func apply(v reflect.Value, viewer Viewer) reflect.Value {
+	if !needs(v.Type()) {
+		return v
+	}
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.New(v.Type().Elem())
+		out.Elem().Set(apply(v.Elem(), viewer))
+		return out
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.New(v.Type()).Elem()
+		out.Set(apply(v.Elem(), viewer))
+		return out
+
+	case reflect.Struct:
+		out := reflect.New(v.Type()).Elem()
+		out.Set(v)
+		self := false
+		if o, ok := v.Interface().(Owner); ok {
+			self = viewer.ID != 0 && o.OwnerID() == viewer.ID
+		}
+		for i := range v.NumField() {
+			f := v.Type().Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			if tag, ok := f.Tag.Lookup(Tag); ok && !visible(tag, viewer, self) {
+				out.Field(i).SetZero()
+				continue
+			}
+			if needs(f.Type) {
+				out.Field(i).Set(apply(v.Field(i), viewer))
+			}
+		}
+		return out
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := range v.Len() {
+			out.Index(i).Set(apply(v.Index(i), viewer))
+		}
+		return out
+
+	case reflect.Array:
+		out := reflect.New(v.Type()).Elem()
+		for i := range v.Len() {
+			out.Index(i).Set(apply(v.Index(i), viewer))
+		}
+		return out
+
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		out := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			out.SetMapIndex(iter.Key(), apply(iter.Value(), viewer))
+		}
+		return out
+	}
+	return v
+}
+
+func visible(tag string, viewer Viewer, self bool) bool {
+	allowed := strings.Split(tag, ",")
+	if self && slices.Contains(allowed, Self) {
+		return true
+	}
+	return viewer.Role != "" && slices.Contains(allowed, viewer.Role)
+}
+
+// needsCache remembers, per type, whether a value of it may hold a tagged field
+var needsCache sync.Map // reflect.Type -> bool
+
+// needs reports whether values of t may hold a tagged field. Interfaces may
+// hold anything, so they are always looked into.
+func needs(t reflect.Type) bool {
+	if n, ok := needsCache.Load(t); ok {
+		return n.(bool)
+	}
+	n := search(t, map[reflect.Type]bool{})
+	needsCache.Store(t, n)
+	return n
+}
+
+// search is needs without the cache. A recursive type (a tree) reaches itself
+// again; that path adds nothing the first visit doesn't find.
+func search(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return search(t.Elem(), seen)
+	case reflect.Struct:
+		for i := range t.NumField() {
+			f := t.Field(i)
+			if !f.IsExported() {
+				continue
+			}
+			if _, ok := f.Tag.Lookup(Tag); ok || search(f.Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,157 @@
+// Package secrets resolves credentials without requiring plaintext values in .env.
+//
+// Lookup order for a secret NAME:
+//  1. the external manager, when SECRETS_PROVIDER is set (vault | aws)
+//  2. the file named by NAME_FILE (Docker/Kubernetes secrets)
+//  3. the NAME environment variable
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Provider fetches a secret by name from an external secrets manager
+type Provider interface {
+	Fetch(ctx context.Context, name string) (string, error)
+}
+
+// FromEnv reads NAME_FILE if set, otherwise NAME. Trailing newlines
+// (which every editor and `echo` add to secret files) are stripped.
+func FromEnv(name string) (string, error) {
+	if path := os.Getenv(name + "_FILE"); path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("secrets: failed to read %s_FILE: %w", name, err)
+		}
+		return strings.TrimRight(string(b), "\r\n"), nil
+	}
+	return os.Getenv(name), nil
+}
+
+// ProviderFromEnv builds the external provider selected by SECRETS_PROVIDER.
+// It returns nil (no provider) when the variable is empty.
+func ProviderFromEnv() (Provider, error) {
+	switch strings.ToLower(os.Getenv("SECRETS_PROVIDER")) {
+	case "":
+		return nil, nil
+	case "vault":
+		return NewVaultFromEnv()
+	case "aws":
+		return NewAWSFromEnv()
+	default:
+		return nil, fmt.Errorf("secrets: unknown SECRETS_PROVIDER %q (want vault or aws)", os.Getenv("SECRETS_PROVIDER"))
+	}
+}
+
+// Store caches resolved secrets and notifies subscribers when a value rotates
+type Store struct {
+	provider Provider
+
+	mu     sync.RWMutex
+	values map[string]string
+	hooks  map[string][]func(string)
+}
+
+// NewStore creates a store on top of an optional external provider (nil is fine)
+func NewStore(p Provider) *Store {
+	return &Store{
+		provider: p,
+		values:   make(map[string]string),
+		hooks:    make(map[string][]func(string)),
+	}
+}
+
+// Get resolves a secret (see package doc for the lookup order) and remembers it for Refresh
+func (s *Store) Get(ctx context.Context, name string) (string, error) {
+	value, err := s.resolve(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	s.mu.Lock()
+	s.values[name] = value
+	s.mu.Unlock()
+	return value, nil
+}
+
+// Current returns the last resolved value without hitting the provider.
+// Safe to call on hot paths (e.g. for every new DB connection).
+func (s *Store) Current(name string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.values[name]
+}
+
+// OnRotate registers fn to run whenever Refresh sees a new value for name
+func (s *Store) OnRotate(name string, fn func(newValue string)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hooks[name] = append(s.hooks[name], fn)
+}
+
+// Refresh re-resolves every known secret and fires rotation hooks for those that changed.
+// A failed fetch keeps the old value: a flaky secrets manager must not take the API down.
+func (s *Store) Refresh(ctx context.Context) {
+	s.mu.RLock()
+	names := make([]string, 0, len(s.values))
+	for name := range s.values {
+		names = append(names, name)
+	}
+	s.mu.RUnlock()
+
+	for _, name := range names {
+		value, err := s.resolve(ctx, name)
+		if err != nil {
+			log.Printf("secrets: refresh of %s failed, keeping previous value: %v", name, err)
+			continue
+		}
+
+		s.mu.Lock()
+		changed := value != s.values[name]
+		s.values[name] = value
+		hooks := append([]func(string){}, s.hooks[name]...)
+		s.mu.Unlock()
+
+		if changed {
+			log.Printf("secrets: %s rotated", name)
+			for _, fn := range hooks {
+				fn(value)
+			}
+		}
+	}
+}
+
+// StartRotation calls Refresh every interval until ctx is cancelled
+func (s *Store) StartRotation(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.Refresh(ctx)
+			}
+		}
+	}()
+}
+
+func (s *Store) resolve(ctx context.Context, name string) (string, error) {
+	if s.provider != nil {
+		value, err := s.provider.Fetch(ctx, name)
+		if err != nil {
+			return "", err
+		}
+		if value != "" {
+			return value, nil
+		}
+		// Not in the manager: fall back to file/env so partial migrations work
+	}
+	return FromEnv(name)
+}
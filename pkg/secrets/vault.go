@@ -0,0 +1,70 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// Vault reads a KV v2 secret whose keys are our secret names, e.g.
+// `vault kv put secret/school-api DB_PASSWORD=... JWT_SECRET_KEY=...`
+//
+//	VAULT_ADDR=https://vault:8200
+//	VAULT_TOKEN=... (or VAULT_TOKEN_FILE)
+//	VAULT_SECRET_PATH=secret/data/school-api
+type Vault struct {
+	addr   string
+	token  string
+	path   string
+	client *http.Client
+}
+
+func NewVaultFromEnv() (*Vault, error) {
+	token, err := FromEnv("VAULT_TOKEN")
+	if err != nil {
+		return nil, err
+	}
+	v := &Vault{
+		addr:   strings.TrimRight(os.Getenv("VAULT_ADDR"), "/"),
+		token:  token,
+		path:   strings.Trim(os.Getenv("VAULT_SECRET_PATH"), "/"),
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+	if v.addr == "" || v.token == "" || v.path == "" {
+		return nil, fmt.Errorf("secrets: vault needs VAULT_ADDR, VAULT_TOKEN and VAULT_SECRET_PATH")
+	}
+	return v, nil
+}
+
+func (v *Vault) Fetch(ctx context.Context, name string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.addr+"/v1/"+v.path, nil)
+	if err != nil {
+		return "", fmt.Errorf("secrets: vault request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("secrets: vault request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("secrets: vault returned %s for %s", resp.Status, v.path)
+	}
+
+	// KV v2 nests the payload: {"data": {"data": {...}, "metadata": {...}}}
+	var body struct {
+		Data struct {
+			Data map[string]string `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("secrets: invalid vault response: %w", err)
+	}
+	return body.Data.Data[name], nil
+}
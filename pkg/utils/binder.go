@@ -0,0 +1,138 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"simpleapi/internal/models"
+	"strconv"
+	"strings"
+)
+
+// BindQuery fills dst, a pointer to a struct, from the query string. Fields opt in
+// with a `query:"name"` tag and may set `default:"value"` for when the parameter is
+// missing or empty. Values are coerced to the field's type (string, integers,
+// floats, bool, pointers to those for "not given", and slices, which take repeated
+// or comma-separated values), then the struct's validate tags run.
+//
+// Errors are validation errors in the usual format, named after the parameter
+// rather than the Go field, so they can go straight to the client.
+func BindQuery(r *http.Request, dst any) []models.ValidationError {
+	query := r.URL.Query()
+	return bind(dst, "query", func(name string) []string { return query[name] })
+}
+
+// BindPath is BindQuery for path wildcards, using `path:"name"` tags
+func BindPath(r *http.Request, dst any) []models.ValidationError {
+	return bind(dst, "path", func(name string) []string {
+		if v := r.PathValue(name); v != "" {
+			return []string{v}
+		}
+		return nil
+	})
+}
+
+func bind(dst any, tag string, lookup func(name string) []string) []models.ValidationError {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("utils: cannot bind %s parameters into %T, want a pointer to a struct", tag, dst))
+	}
+	v = v.Elem()
+
+	params := make(map[string]string) // Go field name -> parameter name, to rename validator errors
+	var errs []models.ValidationError
+	for i := range v.NumField() {
+		field := v.Type().Field(i)
+		name := field.Tag.Get(tag)
+		if name == "" || !field.IsExported() {
+			continue
+		}
+		params[field.Name] = name
+
+		values := lookup(name)
+		if len(values) == 0 || (len(values) == 1 && values[0] == "") {
+			def, ok := field.Tag.Lookup("default")
+			if !ok {
+				continue
+			}
+			values = []string{def}
+		}
+		if rule := setField(v.Field(i), values); rule != "" {
+			errs = append(errs, models.RuleError(name, rule, ""))
+		}
+	}
+	// Don't validate half-bound values; the type errors say enough
+	if len(errs) > 0 {
+		return errs
+	}
+
+	errs = models.ValidateOne(dst)
+	for i := range errs {
+		if name, ok := params[errs[i].Field]; ok {
+			errs[i].Field = name
+		}
+	}
+	return errs
+}
+
+// setField coerces values into fv. It returns the message key of the type the
+// value failed to parse as ("int", "number", "boolean"), or "" on success.
+func setField(fv reflect.Value, values []string) string {
+	switch fv.Kind() {
+	case reflect.Pointer:
+		elem := reflect.New(fv.Type().Elem())
+		if rule := setField(elem.Elem(), values); rule != "" {
+			return rule
+		}
+		fv.Set(elem)
+		return ""
+	case reflect.Slice:
+		var parts []string
+		for _, v := range values {
+			parts = append(parts, strings.Split(v, ",")...)
+		}
+		slice := reflect.MakeSlice(fv.Type(), 0, len(parts))
+		for _, p := range parts {
+			elem := reflect.New(fv.Type().Elem()).Elem()
+			if rule := setField(elem, []string{strings.TrimSpace(p)}); rule != "" {
+				return rule
+			}
+			slice = reflect.Append(slice, elem)
+		}
+		fv.Set(slice)
+		return ""
+	}
+
+	s := values[0] // Like Query().Get, the first value wins for scalars
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, fv.Type().Bits())
+		if err != nil {
+			return "int"
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, fv.Type().Bits())
+		if err != nil {
+			return "int"
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, fv.Type().Bits())
+		if err != nil {
+			return "number"
+		}
+		fv.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return "boolean"
+		}
+		fv.SetBool(b)
+	default:
+		panic(fmt.Sprintf("utils: cannot bind a parameter into a %s field", fv.Type()))
+	}
+	return ""
+}
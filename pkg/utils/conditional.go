@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// WriteJSONCached sends a success response with an ETag (and Last-Modified when known).
+// If the client's cached copy is still fresh, it answers 304 Not Modified with no body.
+func WriteJSONCached(w http.ResponseWriter, r *http.Request, code int, message string, data any, lastModified time.Time) {
+	data = redactFor(w, data) // Viewers who see less get their own ETag
+	etag, err := computeETag(data)
+	if err != nil {
+		// Can't fingerprint the payload, so just send it uncached
+		writeJSON(w, code, message, data)
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+	// private: profiles sit behind auth, so shared caches must not store them
+	// no-cache: clients may keep a copy but must revalidate it every time
+	w.Header().Set("Cache-Control", "private, no-cache")
+	if !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if isNotModified(r, etag, lastModified) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	writeJSON(w, code, message, data)
+}
+
+// computeETag fingerprints the serialized payload, so any change to the
+// resource (including its updated_at) produces a new tag
+func computeETag(data any) (string, error) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`, nil
+}
+
+// isNotModified follows RFC 9110: If-None-Match wins; If-Modified-Since is only
+// consulted when the client didn't send an ETag
+func isNotModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, candidate := range strings.Split(inm, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !lastModified.IsZero() {
+		t, err := http.ParseTime(ims)
+		if err != nil {
+			return false
+		}
+		// HTTP dates only have second precision
+		return !lastModified.Truncate(time.Second).After(t)
+	}
+	return false
+}
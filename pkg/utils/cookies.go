@@ -0,0 +1,160 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default cookie names. SessionCookieName carries the access token for web
+// clients; RefreshCookieName carries the refresh token, which only POST
+// /refresh reads.
+const (
+	SessionCookieName = "session_token"
+	RefreshCookieName = "refresh_token"
+)
+
+// CookieConfig is how the session cookies are named and scoped. The zero
+// value of a field means its default.
+type CookieConfig struct {
+	AccessName  string        // Default SessionCookieName
+	RefreshName string        // Default RefreshCookieName
+	Domain      string        // Default none: host-only. Set it to share the session with subdomains
+	Path        string        // Of the access cookie, default "/"
+	RefreshPath string        // Of the refresh cookie, default "/api/v1/refresh", so no other request sends it
+	SameSite    http.SameSite // Default Lax, which prevents CSRF
+	Insecure    bool          // Drop the Secure flag, for local development over plain HTTP only
+}
+
+// CookieManager sets, reads and clears the session cookies the same way at
+// login, refresh and logout, so a cookie is always cleared with the name,
+// Domain and Path it was set with (or the browser keeps it).
+type CookieManager struct {
+	cfg CookieConfig
+}
+
+// NewCookieManager fills in cfg's defaults and checks the combination is one
+// browsers accept
+func NewCookieManager(cfg CookieConfig) (*CookieManager, error) {
+	if cfg.AccessName == "" {
+		cfg.AccessName = SessionCookieName
+	}
+	if cfg.RefreshName == "" {
+		cfg.RefreshName = RefreshCookieName
+	}
+	if cfg.Path == "" {
+		cfg.Path = "/"
+	}
+	if cfg.RefreshPath == "" {
+		cfg.RefreshPath = "/api/v1/refresh"
+	}
+	if cfg.SameSite == 0 {
+		cfg.SameSite = http.SameSiteLaxMode
+	}
+
+	if cfg.AccessName == cfg.RefreshName {
+		return nil, fmt.Errorf("cookies: access and refresh cookies can't both be named %q", cfg.AccessName)
+	}
+	if cfg.SameSite == http.SameSiteNoneMode && cfg.Insecure {
+		return nil, fmt.Errorf("cookies: browsers reject SameSite=None cookies that aren't Secure")
+	}
+	for _, c := range []struct{ name, path string }{{cfg.AccessName, cfg.Path}, {cfg.RefreshName, cfg.RefreshPath}} {
+		if !strings.HasPrefix(c.path, "/") {
+			return nil, fmt.Errorf("cookies: path %q must start with /", c.path)
+		}
+		// Browsers drop prefixed cookies that break the prefix's rules
+		if strings.HasPrefix(c.name, "__Secure-") && cfg.Insecure {
+			return nil, fmt.Errorf("cookies: %s must be Secure", c.name)
+		}
+		if strings.HasPrefix(c.name, "__Host-") && (cfg.Insecure || cfg.Domain != "" || c.path != "/") {
+			return nil, fmt.Errorf("cookies: %s must be Secure, with no Domain and Path /", c.name)
+		}
+	}
+	return &CookieManager{cfg: cfg}, nil
+}
+
+// CookiesFromEnv reads COOKIE_ACCESS_NAME, COOKIE_REFRESH_NAME, COOKIE_DOMAIN,
+// COOKIE_PATH, COOKIE_REFRESH_PATH, COOKIE_SAMESITE (lax, strict or none) and
+// COOKIE_SECURE (true unless set to false)
+func CookiesFromEnv() (*CookieManager, error) {
+	cfg := CookieConfig{
+		AccessName:  os.Getenv("COOKIE_ACCESS_NAME"),
+		RefreshName: os.Getenv("COOKIE_REFRESH_NAME"),
+		Domain:      os.Getenv("COOKIE_DOMAIN"),
+		Path:        os.Getenv("COOKIE_PATH"),
+		RefreshPath: os.Getenv("COOKIE_REFRESH_PATH"),
+	}
+	switch v := strings.ToLower(strings.TrimSpace(os.Getenv("COOKIE_SAMESITE"))); v {
+	case "", "lax":
+		cfg.SameSite = http.SameSiteLaxMode
+	case "strict":
+		cfg.SameSite = http.SameSiteStrictMode
+	case "none":
+		cfg.SameSite = http.SameSiteNoneMode
+	default:
+		return nil, fmt.Errorf("cookies: unknown COOKIE_SAMESITE %q, want lax, strict or none", v)
+	}
+	if v := os.Getenv("COOKIE_SECURE"); v != "" {
+		secure, err := strconv.ParseBool(v)
+		if err != nil {
+			return nil, fmt.Errorf("cookies: COOKIE_SECURE %q is not true or false", v)
+		}
+		cfg.Insecure = !secure
+	}
+	return NewCookieManager(cfg)
+}
+
+// SetAccess sets the access token cookie
+func (m *CookieManager) SetAccess(w http.ResponseWriter, token string, expires time.Time) {
+	http.SetCookie(w, m.cookie(m.cfg.AccessName, m.cfg.Path, token, expires))
+}
+
+// SetRefresh sets the refresh token cookie
+func (m *CookieManager) SetRefresh(w http.ResponseWriter, token string, expires time.Time) {
+	http.SetCookie(w, m.cookie(m.cfg.RefreshName, m.cfg.RefreshPath, token, expires))
+}
+
+// Clear expires both cookies
+func (m *CookieManager) Clear(w http.ResponseWriter) {
+	for _, c := range []*http.Cookie{
+		m.cookie(m.cfg.AccessName, m.cfg.Path, "", time.Unix(0, 0)),
+		m.cookie(m.cfg.RefreshName, m.cfg.RefreshPath, "", time.Unix(0, 0)),
+	} {
+		c.MaxAge = -1 // Force deletion
+		http.SetCookie(w, c)
+	}
+}
+
+// AccessToken is the access token cookie's value, "" without one
+func (m *CookieManager) AccessToken(r *http.Request) string {
+	return cookieValue(r, m.cfg.AccessName)
+}
+
+// RefreshToken is the refresh token cookie's value, "" without one
+func (m *CookieManager) RefreshToken(r *http.Request) string {
+	return cookieValue(r, m.cfg.RefreshName)
+}
+
+func (m *CookieManager) cookie(name, path, value string, expires time.Time) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Domain:   m.cfg.Domain,
+		Path:     path,
+		Expires:  expires,
+		HttpOnly: true,            // Prevents JavaScript (XSS) access
+		Secure:   !m.cfg.Insecure, // Only sent over HTTPS
+		SameSite: m.cfg.SameSite,
+	}
+}
+
+func cookieValue(r *http.Request, name string) string {
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
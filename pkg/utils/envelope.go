@@ -0,0 +1,90 @@
+package utils
+
+// The response envelope is part of the API contract, so the frontend's types are
+// generated from this file rather than written by hand. Run go generate after
+// changing anything here (or the models listed below).
+//
+//go:generate go run simpleapi/cmd/tsgen -out ../../api/envelope.ts envelope.go problem.go ../errcodes/errcodes.go ../../internal/models/validator.go ../../internal/models/bulk.go
+
+import "simpleapi/pkg/errcodes"
+
+// ResponseStatus is the "status" member of every envelope
+type ResponseStatus string
+
+const (
+	StatusSuccess ResponseStatus = "success" // 2xx
+	StatusFail    ResponseStatus = "fail"    // 4xx: the request can be fixed by the client
+	StatusError   ResponseStatus = "error"   // 5xx
+)
+
+// statusFor picks the envelope status for an HTTP status code
+func statusFor(code int) ResponseStatus {
+	switch {
+	case code < 400:
+		return StatusSuccess
+	case code < 500:
+		return StatusFail
+	}
+	return StatusError
+}
+
+// APIResponse is the success envelope written by WriteJSON.
+// Members are encoded in field order, so keep the order stable.
+type APIResponse struct {
+	Status     ResponseStatus `json:"status"`
+	StatusCode int            `json:"statusCode"`
+	Message    string         `json:"message"`
+	Data       any            `json:"data" ts:"T"`
+}
+
+// ErrorBody is the classic error envelope written by WriteError
+// (application/problem+json clients get ProblemDetails instead)
+type ErrorBody struct {
+	Status     ResponseStatus `json:"status"`
+	StatusCode int            `json:"statusCode"`
+	Code       errcodes.Code  `json:"code"`
+	Message    string         `json:"message"`
+	Details    any            `json:"details,omitempty"`
+}
+
+// ListMeta describes a list payload. Page and Total are only set by paginated endpoints,
+// Query by endpoints that take filters.
+type ListMeta struct {
+	Count int        `json:"count"`
+	Page  int        `json:"page,omitempty"`
+	Total int        `json:"total,omitempty"`
+	Query *QueryMeta `json:"query,omitempty"`
+}
+
+// QueryMeta echoes how a list endpoint read its query string, after validation
+// and normalization, so a parameter that had no effect shows up as such
+type QueryMeta struct {
+	Filters map[string]any `json:"filters"`          // Parameters that narrowed the list, as applied (e.g. a phone in E.164)
+	Sort    *SortMeta      `json:"sort"`             // null: the default order (by ID)
+	Fields  []string       `json:"fields,omitempty"` // ?fields=, when the items carry only those
+	Ignored []IgnoredParam `json:"ignored,omitempty"`
+}
+
+// SortMeta is the order a list came back in
+type SortMeta struct {
+	Field string `json:"field"`
+	Order string `json:"order"` // ASC or DESC
+}
+
+// IgnoredParam is a query parameter that was accepted but had no effect
+type IgnoredParam struct {
+	Param  string `json:"param"`
+	Value  string `json:"value"`
+	Reason string `json:"reason"`
+}
+
+// List is the data of list endpoints: the meta members, then the items
+type List[T any] struct {
+	ListMeta
+	Data []T `json:"data"`
+}
+
+// NewList wraps an unpaginated list
+func NewList[T any](items []T) List[T] {
+	return List[T]{ListMeta: ListMeta{Count: len(items)}, Data: items}
+}
@@ -1,19 +1,14 @@
 package utils
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"net/http"
 	"simpleapi/internal/models"
+	"simpleapi/pkg/errcodes"
 )
 
-type APIResponse struct {
-	Status     string `json:"status"`
-	StatusCode int    `json:"statusCode"`
-	Message    string `json:"message"`
-	Data       any    `json:"data"`
-}
-
 // func ErrorHandler(err error, message string) error {
 // 	errorLogger := log.New(os.Stderr, "ERROR: ", log.Ldate|log.Ltime|log.Lshortfile)
 // 	errorLogger.Println(message, err)
@@ -22,22 +17,67 @@ type APIResponse struct {
 
 // ResponseError inspects the error to set the status code,
 // but allows you to override the client-facing message.
+// The error code is the one the error carries (see errcodes.Coder), else the status's generic one.
 func ResponseError(w http.ResponseWriter, err error, message string) {
+	code := errcodes.Of(err)
+
+	// 0. The client cancelled (closed the tab, navigated away): a query failing
+	// with context.Canceled is no fault of ours, so no 500
+	if errors.Is(err, context.Canceled) {
+		WriteErrorCode(w, errcodes.StatusClientClosedRequest, errcodes.RequestCanceled, "Request cancelled by the client")
+		return
+	}
+
 	// 1. Check: Is it a 404 Not Found?
 	if errors.Is(err, models.ErrNotFound) {
 		if message == "" {
 			message = err.Error() // Default
 		}
-		WriteError(w, http.StatusNotFound, message)
+		WriteErrorCode(w, http.StatusNotFound, code, message)
 		return
 	}
 
 	// 2. Check: Is it a 409 Conflict?
+	// A ConflictError knows the clashing field, so surface {field, value} as details
+	var conflict *models.ConflictError
+	if errors.As(err, &conflict) {
+		if message == "" {
+			message = conflict.Error()
+		}
+		WriteErrorCode(w, http.StatusConflict, code, message, conflict)
+		return
+	}
+	// A DependencyError explains what would be orphaned
+	var dependency *models.DependencyError
+	if errors.As(err, &dependency) {
+		if message == "" {
+			message = dependency.Error()
+		}
+		WriteErrorCode(w, http.StatusConflict, code, message, dependency)
+		return
+	}
 	if errors.Is(err, models.ErrConflict) {
 		if message == "" {
 			message = err.Error() // Default
 		}
-		WriteError(w, http.StatusConflict, message)
+		WriteErrorCode(w, http.StatusConflict, code, message)
+		return
+	}
+
+	// A QuotaError says which quota and how much of it is used
+	var quota *models.QuotaError
+	if errors.As(err, &quota) {
+		if message == "" {
+			message = quota.Error()
+		}
+		WriteErrorCode(w, http.StatusForbidden, code, message, quota)
+		return
+	}
+	if errors.Is(err, models.ErrForbidden) {
+		if message == "" {
+			message = err.Error() // Default
+		}
+		WriteErrorCode(w, http.StatusForbidden, code, message)
 		return
 	}
 
@@ -46,7 +86,7 @@ func ResponseError(w http.ResponseWriter, err error, message string) {
 		if message == "" {
 			message = err.Error() // Default
 		}
-		WriteError(w, http.StatusBadRequest, message)
+		WriteErrorCode(w, http.StatusBadRequest, code, message)
 		return
 	}
 
@@ -60,41 +100,74 @@ func ResponseError(w http.ResponseWriter, err error, message string) {
 	WriteError(w, http.StatusInternalServerError, message)
 }
 
+// ErrorStatus is the HTTP status ResponseError would answer err with, for
+// places that report errors inside a body, e.g. per item of a bulk result
+func ErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return errcodes.StatusClientClosedRequest
+	case errors.Is(err, models.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, models.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, models.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, models.ErrInvalidInput):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
 // WriteError sends the JSON response (The "Dumb" Formatter)
+// Switches to application/problem+json when configured or negotiated.
+// The error code is the status's generic one, or VALIDATION_FAILED for field errors.
 func WriteError(w http.ResponseWriter, code int, message string, details ...any) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
-
-	status := "error"
-	if code >= 400 && code < 500 {
-		status = "fail"
-	}
+	WriteErrorCode(w, code, "", message, details...)
+}
 
+// WriteErrorCode is WriteError with a specific error code (see package errcodes).
+// An empty errCode picks the generic one, like WriteError.
+func WriteErrorCode(w http.ResponseWriter, code int, errCode errcodes.Code, message string, details ...any) {
 	var detailsVaue any
 	if len(details) > 0 {
-		detailsVaue = details[0]
+		detailsVaue = redactFor(w, details[0])
+	}
+	if errCode == "" {
+		errCode = errcodes.ForStatus(code)
+		if _, ok := detailsVaue.([]models.ValidationError); ok {
+			errCode = errcodes.ValidationFailed
+		}
+	}
+
+	if WantsProblem(w) {
+		writeProblem(w, code, errCode, message, detailsVaue)
+		return
 	}
 
-	json.NewEncoder(w).Encode(struct {
-		Status     string `json:"status"`
-		StatusCode int    `json:"statusCode"`
-		Message    string `json:"message"`
-		Details    any    `json:"details,omitempty"`
-	}{
-		Status:     status,
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	json.NewEncoder(w).Encode(ErrorBody{
+		Status:     statusFor(code),
 		StatusCode: code,
+		Code:       errCode,
 		Message:    message,
 		Details:    detailsVaue,
 	})
 }
 
-// WriteJSON sends success response
+// WriteJSON sends success response, without the fields the viewer may not
+// see when redaction is on (see RedactsFields)
 func WriteJSON(w http.ResponseWriter, code int, message string, data any) {
+	writeJSON(w, code, message, redactFor(w, data))
+}
+
+func writeJSON(w http.ResponseWriter, code int, message string, data any) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(code)
 
 	response := APIResponse{
-		Status:     "success",
+		Status:     StatusSuccess,
 		StatusCode: code,
 		Message:    message,
 		Data:       data,
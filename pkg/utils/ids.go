@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// MaxID is the largest ID a row can have: IDs are signed INT columns
+const MaxID = math.MaxInt32
+
+// ErrInvalidID is returned by ParseID for anything that can't be a row's ID
+var ErrInvalidID = errors.New("invalid ID")
+
+// ParseID reads a resource ID: digits only, from 1 to MaxID. Unlike
+// strconv.Atoi it refuses signs, so "-1" and "+1" are as invalid as "abc".
+func ParseID(s string) (int, error) {
+	if s == "" || len(s) > 10 {
+		return 0, ErrInvalidID
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return 0, ErrInvalidID
+		}
+	}
+	id, err := strconv.Atoi(s)
+	if err != nil || id < 1 || id > MaxID {
+		return 0, ErrInvalidID
+	}
+	return id, nil
+}
+
+// PathID returns the ID in a path wildcard. Routes behind middlewares.PathIDs
+// never get this far with a bad ID, so there is no error to handle; elsewhere a
+// bad ID reads as 0, which matches no row and ends in a 404.
+func PathID(r *http.Request, name string) int {
+	id, _ := ParseID(r.PathValue(name))
+	return id
+}
@@ -2,62 +2,241 @@ package utils
 
 import (
 	"errors"
-	"log"
-	"os"
+	"fmt"
+	"net/http"
+	"simpleapi/pkg/clock"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
-	"github.com/joho/godotenv"
 )
 
-var jwtKey []byte
+type CustomClaims struct {
+	UserID string `json:"user_id"`
+	Role   string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// DefaultTokenIssuer is the "iss" of every token unless JWT_ISSUER says otherwise
+const DefaultTokenIssuer = "school-app"
+
+// Audience is the kind of client a token is minted for (its "aud" claim).
+// Protected routes say which audiences they accept, so a token can't be
+// replayed from a less trusted client against routes it wasn't meant for.
+type Audience string
+
+const (
+	AudienceWeb    Audience = "web"    // The browser app
+	AudienceMobile Audience = "mobile" // Mobile apps and other API clients
+	AudienceKiosk  Audience = "kiosk"  // Read-only displays (e.g. the calendar screen in the hall)
+	// AudienceRefresh tokens only buy a new web token at POST /refresh
+	AudienceRefresh Audience = "refresh"
+)
+
+// ErrWrongAudience is returned by TokenService.Verify for a valid token minted for another audience
+var ErrWrongAudience = errors.New("token not valid for this client")
+
+// DefaultTokenTTLs are the token lifetimes JWT_TTL can override. OWASP
+// recommends short-lived access tokens; a kiosk display stays logged in for
+// the school day, and the web app refreshes its token for a week.
+var DefaultTokenTTLs = map[Audience]time.Duration{
+	AudienceWeb:     15 * time.Minute,
+	AudienceMobile:  15 * time.Minute,
+	AudienceKiosk:   12 * time.Hour,
+	AudienceRefresh: 7 * 24 * time.Hour,
+}
+
+// ParseTokenTTLs reads JWT_TTL, e.g. "mobile=1h,kiosk=8h,refresh=72h". Audiences left out keep their default.
+func ParseTokenTTLs(s string) (map[Audience]time.Duration, error) {
+	ttls := make(map[Audience]time.Duration)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(part, "=")
+		aud := Audience(strings.TrimSpace(name))
+		if _, known := DefaultTokenTTLs[aud]; !ok || !known {
+			return nil, fmt.Errorf("bad token TTL %q, want <web|mobile|kiosk|refresh>=<duration>", part)
+		}
+		ttl, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || ttl <= 0 {
+			return nil, fmt.Errorf("bad token TTL %q, want a positive duration such as 30m", part)
+		}
+		ttls[aud] = ttl
+	}
+	return ttls, nil
+}
 
-// Initialize the key from environment variables
-func init() {
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found, relying on system env")
+// AudienceFor picks the audience of a login: "X-Client-Type: kiosk" asks for a
+// kiosk token, API clients (see IsAPIClient) get a mobile one, browsers a web one
+func AudienceFor(r *http.Request) Audience {
+	if strings.EqualFold(strings.TrimSpace(r.Header.Get("X-Client-Type")), "kiosk") {
+		return AudienceKiosk
 	}
-	key := os.Getenv("JWT_SECRET_KEY")
-	if key == "" {
-		// As an AppSec engineer, never let the app run with a default or empty key
-		panic("JWT_SECRET_KEY environment variable is not set")
+	if IsAPIClient(r) {
+		return AudienceMobile
 	}
-	jwtKey = []byte(key)
+	return AudienceWeb
 }
 
-type CustomClaims struct {
-	UserID string `json:"user_id"`
-	Role   string `json:"role"`
-	jwt.RegisteredClaims
+// TokenSigner mints access tokens; handlers that start sessions take one
+type TokenSigner interface {
+	Sign(userID, role string, aud Audience) (string, error)
+	TTL(aud Audience) time.Duration
+}
+
+// TokenVerifier checks access tokens; the auth middleware takes one
+type TokenVerifier interface {
+	Verify(token string, allowed ...Audience) (*CustomClaims, error)
+}
+
+// Tokens both mints and checks tokens, for the handlers that renew sessions
+type Tokens interface {
+	TokenSigner
+	TokenVerifier
+}
+
+// TokenConfig is what main reads from the environment for a TokenService
+type TokenConfig struct {
+	Keys   [][]byte                   // Current first; the rest only verify tokens they signed
+	Issuer string                     // Defaults to DefaultTokenIssuer
+	TTLs   map[Audience]time.Duration // Overrides DefaultTokenTTLs per audience
+	Clock  clock.Clock                // Decides "now", so tests can freeze time
+}
+
+// TokenService signs and verifies the API's access tokens (HS256 JWTs). Each
+// token names the key that signed it in its "kid" header, so a rotated key
+// keeps verifying the tokens it signed until they expire.
+type TokenService struct {
+	issuer string
+	ttls   map[Audience]time.Duration
+	clock  clock.Clock
+
+	mu   sync.RWMutex
+	keys [][]byte
+}
+
+var (
+	_ Tokens = (*TokenService)(nil)
+)
+
+// NewTokenService is the constructor. It refuses to run without a signing key.
+func NewTokenService(cfg TokenConfig) (*TokenService, error) {
+	s := &TokenService{issuer: cfg.Issuer, ttls: make(map[Audience]time.Duration), clock: cfg.Clock}
+	if s.issuer == "" {
+		s.issuer = DefaultTokenIssuer
+	}
+	if s.clock == nil {
+		s.clock = clock.New(time.UTC)
+	}
+	for aud, ttl := range DefaultTokenTTLs {
+		s.ttls[aud] = ttl
+	}
+	for aud, ttl := range cfg.TTLs {
+		s.ttls[aud] = ttl
+	}
+	if err := s.SetKeys(cfg.Keys...); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ErrNoTokenKey means the token service was given no signing key
+var ErrNoTokenKey = errors.New("JWT signing key is not set")
+
+// SetKeys replaces the keys, current first. Tokens signed with a key left out
+// stop verifying immediately.
+func (s *TokenService) SetKeys(keys ...[]byte) error {
+	var clean [][]byte
+	for _, k := range keys {
+		if len(k) > 0 {
+			clean = append(clean, k)
+		}
+	}
+	// As an AppSec engineer, never let the app run with a default or empty key
+	if len(keys) == 0 || len(keys[0]) == 0 {
+		return ErrNoTokenKey
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = clean
+	return nil
+}
+
+// Rotate makes key the current one, e.g. from a secrets rotation hook. The
+// key it replaces still verifies the tokens it signed; older keys are dropped.
+func (s *TokenService) Rotate(key []byte) error {
+	if len(key) == 0 {
+		return ErrNoTokenKey
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case len(s.keys) == 0:
+		s.keys = [][]byte{key}
+	case string(s.keys[0]) != string(key):
+		s.keys = [][]byte{key, s.keys[0]}
+	}
+	return nil
+}
+
+// TTL is how long tokens for aud live, 0 for an unknown audience
+func (s *TokenService) TTL(aud Audience) time.Duration {
+	return s.ttls[aud]
 }
 
-func GenerateJWT(userID string, role string) (string, error) {
+// Sign issues an access token for one audience, living as long as that
+// audience's TTL
+func (s *TokenService) Sign(userID string, role string, aud Audience) (string, error) {
+	ttl := s.TTL(aud)
+	if ttl == 0 {
+		return "", fmt.Errorf("unknown token audience %q", aud)
+	}
+
+	now := s.clock.Now()
 	claims := CustomClaims{
 		UserID: userID,
 		Role:   role,
 		RegisteredClaims: jwt.RegisteredClaims{
-			// OWASP recommends short-lived access tokens
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(15 * time.Minute)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			Issuer:    "school-app", // Identify who created the token
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+			IssuedAt:  jwt.NewNumericDate(now),
+			Issuer:    s.issuer, // Identify who created the token
 			Subject:   userID,
+			Audience:  jwt.ClaimStrings{string(aud)},
 		},
 	}
 
+	key := s.currentKey()
+	if key == nil {
+		return "", ErrNoTokenKey
+	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(jwtKey)
+	token.Header["kid"] = signingKeyID(key)
+	return token.SignedString(key)
 }
 
-func ValidateJWT(tokenString string) (*CustomClaims, error) {
+// ErrTokenExpired is returned by Verify for a well-signed token past its expiry
+var ErrTokenExpired = errors.New("token expired")
+
+// Verify checks signature, issuer and expiry, and that the token was minted
+// for one of the allowed audiences (ErrWrongAudience if not)
+func (s *TokenService) Verify(tokenString string, allowed ...Audience) (*CustomClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &CustomClaims{}, func(token *jwt.Token) (interface{}, error) {
 		// AppSec Check: Ensure the algorithm is HMAC.
 		// This prevents the "alg: none" attack where users bypass auth.
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("unexpected signing method")
 		}
-		return jwtKey, nil
-	})
+		kid, _ := token.Header["kid"].(string)
+		return s.keyByID(kid)
+	}, jwt.WithTimeFunc(s.clock.Now), jwt.WithIssuer(s.issuer), jwt.WithExpirationRequired())
 
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return nil, ErrTokenExpired
+	}
 	if err != nil || !token.Valid {
 		return nil, errors.New("invalid or expired token")
 	}
@@ -67,5 +246,40 @@ func ValidateJWT(tokenString string) (*CustomClaims, error) {
 		return nil, errors.New("could not parse claims")
 	}
 
-	return claims, nil
+	// Tokens from before audiences existed have none and are turned away too
+	for _, aud := range allowed {
+		if slices.Contains(claims.Audience, string(aud)) {
+			return claims, nil
+		}
+	}
+	return nil, ErrWrongAudience
+}
+
+// currentKey is nil for a service that was never given a key
+func (s *TokenService) currentKey() []byte {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.keys) == 0 {
+		return nil
+	}
+	return s.keys[0]
+}
+
+// keyByID finds the key a token names. Tokens from before key IDs have none
+// and only verify with the current key.
+func (s *TokenService) keyByID(kid string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.keys) == 0 {
+		return nil, ErrNoTokenKey
+	}
+	if kid == "" {
+		return s.keys[0], nil
+	}
+	for _, k := range s.keys {
+		if signingKeyID(k) == kid {
+			return k, nil
+		}
+	}
+	return nil, errors.New("unknown signing key")
 }
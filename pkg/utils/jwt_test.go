@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"errors"
+	"simpleapi/pkg/clock"
+	"testing"
+	"time"
+)
+
+func TestTokenServiceRotation(t *testing.T) {
+	s, err := NewTokenService(TokenConfig{
+		Keys:  [][]byte{[]byte("first")},
+		Clock: clock.NewFrozen(time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sign := func() string {
+		t.Helper()
+		token, err := s.Sign("7", "teacher", AudienceWeb)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return token
+	}
+
+	first := sign()
+	if err := s.Rotate([]byte("second")); err != nil {
+		t.Fatal(err)
+	}
+	second := sign()
+	if err := s.Rotate([]byte("second")); err != nil { // Rotating to the current key changes nothing
+		t.Fatal(err)
+	}
+	if _, err := s.Verify(first, AudienceWeb); err != nil {
+		t.Errorf("token of the key just replaced: %v", err)
+	}
+	if err := s.Rotate([]byte("third")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		valid bool
+	}{
+		{"current key", sign(), true},
+		{"key just replaced", second, true},
+		{"key replaced twice", first, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Verify(tt.token, AudienceWeb)
+			if (err == nil) != tt.valid {
+				t.Errorf("Verify error = %v, want valid %v", err, tt.valid)
+			}
+		})
+	}
+
+	if err := s.Rotate(nil); !errors.Is(err, ErrNoTokenKey) {
+		t.Errorf("Rotate(nil) = %v, want ErrNoTokenKey", err)
+	}
+}
+
+func TestTokenServiceWithoutKeys(t *testing.T) {
+	if _, err := NewTokenService(TokenConfig{}); !errors.Is(err, ErrNoTokenKey) {
+		t.Errorf("NewTokenService without keys = %v, want ErrNoTokenKey", err)
+	}
+
+	// A zero TokenService has no key: it signs nothing until one is rotated in
+	s := &TokenService{ttls: DefaultTokenTTLs, clock: clock.New(time.UTC)}
+	if _, err := s.Sign("7", "teacher", AudienceWeb); !errors.Is(err, ErrNoTokenKey) {
+		t.Errorf("Sign without a key = %v, want ErrNoTokenKey", err)
+	}
+	if err := s.Rotate([]byte("first")); err != nil {
+		t.Fatal(err)
+	}
+	token, err := s.Sign("7", "teacher", AudienceWeb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Verify(token, AudienceWeb); err != nil {
+		t.Errorf("Verify after the first Rotate: %v", err)
+	}
+}
@@ -33,7 +33,8 @@ type Argon2Hash struct {
 	Version     uint32
 	Memory      uint32
 	Iterations  uint32
-	Parallelism uint8 // Note: uint8 for Argon2 threads parameter
+	Parallelism uint8  // Note: uint8 for Argon2 threads parameter
+	KeyID       uint32 // Pepper version (keyid=), 0 for hashes made without a pepper
 	Salt        []byte
 	Hash        []byte
 }
@@ -44,8 +45,10 @@ type Argon2Hash struct {
 
 // HashPassword creates a secure Argon2id hash from a plaintext password.
 // Returns a PHC-formatted string: $argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+// With a pepper configured (see PasswordHasher.SetPeppers) the password is HMACed with it first
+// and the parameters gain its version: m=65536,t=3,p=2,keyid=2
 // Memory zeroing: Password bytes are zeroed after use.
-func HashPassword(password string) (string, error) {
+func (h *PasswordHasher) HashPassword(password string) (string, error) {
 	if err := validatePasswordInput(password); err != nil {
 		return "", err
 	}
@@ -54,6 +57,14 @@ func HashPassword(password string) (string, error) {
 	passwordBytes := []byte(password)
 	defer zeroBytes(passwordBytes)
 
+	pepper := h.currentPepper()
+	keyID := uint32(0)
+	if pepper != nil {
+		keyID = pepper.Version
+	}
+	input := pepperPassword(passwordBytes, pepper)
+	defer zeroBytes(input)
+
 	salt, err := generateSalt(defaultSaltLength)
 	if err != nil {
 		return "", fmt.Errorf("salt generation failed: %w", err)
@@ -62,7 +73,7 @@ func HashPassword(password string) (string, error) {
 
 	// Derive cryptographic hash using Argon2id
 	derivedHash := argon2.IDKey(
-		passwordBytes,
+		input,
 		salt,
 		defaultIterations,
 		defaultMemory,
@@ -71,13 +82,13 @@ func HashPassword(password string) (string, error) {
 	)
 	defer zeroBytes(derivedHash)
 
-	return encodePHCString(salt, derivedHash), nil
+	return encodePHCString(salt, derivedHash, keyID), nil
 }
 
 // CheckPassword verifies a password against a stored Argon2 hash.
 // Uses constant-time comparison to prevent timing attacks.
 // Memory zeroing: All sensitive bytes are zeroed after comparison.
-func CheckPassword(password, encodedHash string) (bool, error) {
+func (h *PasswordHasher) CheckPassword(password, encodedHash string) (bool, error) {
 	if err := validatePasswordInput(password); err != nil {
 		return false, err
 	}
@@ -93,9 +104,19 @@ func CheckPassword(password, encodedHash string) (bool, error) {
 	}
 	defer storedHash.zero() // Zero all components
 
+	// Hashes remember their pepper version, so older versions verify during a rotation
+	var pepper *Pepper
+	if storedHash.KeyID != 0 {
+		if pepper, err = h.pepperVersion(storedHash.KeyID); err != nil {
+			return false, err
+		}
+	}
+	input := pepperPassword(passwordBytes, pepper)
+	defer zeroBytes(input)
+
 	// Derive hash from provided password using stored parameters
 	derivedHash := argon2.IDKey(
-		passwordBytes,
+		input,
 		storedHash.Salt,
 		storedHash.Iterations,
 		storedHash.Memory,
@@ -113,9 +134,9 @@ func CheckPassword(password, encodedHash string) (bool, error) {
 //   - newHash: The new hash string (or original if no upgrade needed)
 //   - didUpgrade: Boolean true if the hash was actually changed (signal to save to DB)
 //   - error: Any processing error
-func UpgradeHashIfNeeded(password, encodedHash string) (string, bool, error) {
+func (h *PasswordHasher) UpgradeHashIfNeeded(password, encodedHash string) (string, bool, error) {
 	// First verify the password is correct
-	match, err := CheckPassword(password, encodedHash)
+	match, err := h.CheckPassword(password, encodedHash)
 	if err != nil {
 		return "", false, fmt.Errorf("password verification failed: %w", err)
 	}
@@ -131,8 +152,8 @@ func UpgradeHashIfNeeded(password, encodedHash string) (string, bool, error) {
 	defer storedHash.zero()
 
 	// Check if current parameters meet minimum security requirements
-	if needsUpgrade(storedHash) {
-		newHash, err := HashPassword(password)
+	if h.needsUpgrade(storedHash) {
+		newHash, err := h.HashPassword(password)
 		if err != nil {
 			return "", false, err
 		}
@@ -148,18 +169,22 @@ func UpgradeHashIfNeeded(password, encodedHash string) (string, bool, error) {
 // ============================================================================
 
 // encodePHCString encodes hash components into PHC string format.
-// Format: $argon2id$v=19$m=65536,t=3,p=2$<b64salt>$<b64hash>
-func encodePHCString(salt, hash []byte) string {
+// Format: $argon2id$v=19$m=65536,t=3,p=2$<b64salt>$<b64hash>, with ",keyid=N"
+// after p when a pepper was used
+func encodePHCString(salt, hash []byte, keyID uint32) string {
 	b64Salt := base64.RawStdEncoding.EncodeToString(salt)
 	b64Hash := base64.RawStdEncoding.EncodeToString(hash)
 
+	params := fmt.Sprintf("m=%d,t=%d,p=%d", defaultMemory, defaultIterations, defaultParallelism)
+	if keyID != 0 {
+		params += fmt.Sprintf(",keyid=%d", keyID)
+	}
+
 	return fmt.Sprintf(
-		"$%s$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		"$%s$v=%d$%s$%s$%s",
 		algorithmName,
 		argon2.Version,
-		defaultMemory,
-		defaultIterations,
-		defaultParallelism,
+		params,
 		b64Salt,
 		b64Hash,
 	)
@@ -254,6 +279,7 @@ func parseParameters(paramPart string, hash *Argon2Hash) error {
 	hash.Memory = uint32(params["m"])
 	hash.Iterations = uint32(params["t"])
 	hash.Parallelism = uint8(params["p"]) // Critical: Convert to uint8 for Argon2
+	hash.KeyID = uint32(params["keyid"])  // Optional: absent on unpeppered hashes
 
 	return nil
 }
@@ -292,7 +318,7 @@ func validatePasswordInput(password string) error {
 }
 
 // needsUpgrade determines if a hash should be re-hashed with stronger parameters
-func needsUpgrade(hash *Argon2Hash) bool {
+func (h *PasswordHasher) needsUpgrade(hash *Argon2Hash) bool {
 	// Check against current security standards
 	if hash.Memory < defaultMemory {
 		return true
@@ -303,7 +329,12 @@ func needsUpgrade(hash *Argon2Hash) bool {
 	if hash.Parallelism < defaultParallelism {
 		return true
 	}
-	return false
+	// Rehash under the current pepper: adds one to old hashes, finishes rotations
+	currentKeyID := uint32(0)
+	if pepper := h.currentPepper(); pepper != nil {
+		currentKeyID = pepper.Version
+	}
+	return hash.KeyID != currentKeyID
 }
 
 // generateSalt creates cryptographically secure random salt
@@ -343,6 +374,7 @@ func (h *Argon2Hash) zero() {
 	h.Memory = 0
 	h.Iterations = 0
 	h.Parallelism = 0
+	h.KeyID = 0
 	h.Version = 0
 	h.Algorithm = ""
 }
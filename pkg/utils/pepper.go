@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// minPepperLength keeps a pepper out of reach of brute force on its own
+const minPepperLength = 32
+
+// Pepper is a server-side secret mixed into every password before hashing
+// (HMAC-SHA256, then Argon2id), so a database dump alone isn't enough to crack
+// the hashes offline. Version is recorded in each hash as its keyid parameter.
+type Pepper struct {
+	Version uint32
+	Key     []byte
+}
+
+// ParsePeppers reads PASSWORD_PEPPER: "version:secret" pairs separated by
+// commas, current first, e.g. "2:<new secret>,1:<old secret>". Versions start
+// at 1. Empty means no pepper.
+func ParsePeppers(s string) ([]Pepper, error) {
+	var parsed []Pepper
+	seen := make(map[uint32]bool)
+	for entry := range strings.SplitSeq(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		version, key, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("pepper entry must be version:secret")
+		}
+		v, err := strconv.ParseUint(version, 10, 32)
+		if err != nil || v == 0 {
+			return nil, fmt.Errorf("pepper version %q must be a number from 1", version)
+		}
+		if seen[uint32(v)] {
+			return nil, fmt.Errorf("pepper version %d is listed twice", v)
+		}
+		if len(key) < minPepperLength {
+			return nil, fmt.Errorf("pepper version %d must be at least %d characters", v, minPepperLength)
+		}
+		seen[uint32(v)] = true
+		parsed = append(parsed, Pepper{Version: uint32(v), Key: []byte(key)})
+	}
+	return parsed, nil
+}
+
+// PasswordHashing hashes and checks passwords; handlers that set or check
+// passwords take one
+type PasswordHashing interface {
+	HashPassword(password string) (string, error)
+	CheckPassword(password, encodedHash string) (bool, error)
+	UpgradeHashIfNeeded(password, encodedHash string) (string, bool, error)
+}
+
+// PasswordHasher hashes passwords with Argon2id, peppered with PASSWORD_PEPPER
+// when it is set. main builds one and hands it to the handlers, and swaps its
+// peppers when the secret rotates.
+type PasswordHasher struct {
+	mu      sync.RWMutex
+	peppers []Pepper // Current first; the rest only verify older hashes
+}
+
+var _ PasswordHashing = (*PasswordHasher)(nil)
+
+// NewPasswordHasher is the constructor; with no peppers, passwords are hashed as they are
+func NewPasswordHasher(peppers []Pepper) *PasswordHasher {
+	return &PasswordHasher{peppers: peppers}
+}
+
+// SetPeppers installs the peppers, current first, e.g. from a secrets rotation
+// hook. Hashes made with a listed older version still verify and are re-hashed
+// with the current one at the next login (see UpgradeHashIfNeeded).
+func (h *PasswordHasher) SetPeppers(p []Pepper) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.peppers = p
+}
+
+// currentPepper is the pepper new hashes use, nil when none is configured
+func (h *PasswordHasher) currentPepper() *Pepper {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if len(h.peppers) == 0 {
+		return nil
+	}
+	return &h.peppers[0]
+}
+
+func (h *PasswordHasher) pepperVersion(version uint32) (*Pepper, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for i := range h.peppers {
+		if h.peppers[i].Version == version {
+			return &h.peppers[i], nil
+		}
+	}
+	return nil, fmt.Errorf("password pepper version %d is not configured", version)
+}
+
+// pepperPassword is what goes into Argon2: the password itself without a
+// pepper, its HMAC under the pepper's key otherwise. The caller zeroes it.
+func pepperPassword(password []byte, p *Pepper) []byte {
+	if p == nil {
+		return append([]byte(nil), password...)
+	}
+	mac := hmac.New(sha256.New, p.Key)
+	mac.Write(password)
+	return mac.Sum(nil)
+}
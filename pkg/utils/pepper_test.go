@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParsePeppers(t *testing.T) {
+	secret := strings.Repeat("s", minPepperLength)
+	tests := []struct {
+		value    string
+		versions []uint32
+		wantErr  bool
+	}{
+		{"", nil, false},
+		{"1:" + secret, []uint32{1}, false},
+		{" 2:" + secret + " , 1:" + secret, []uint32{2, 1}, false},
+		{secret, nil, true},
+		{"0:" + secret, nil, true},
+		{"x:" + secret, nil, true},
+		{"1:short", nil, true},
+		{"1:" + secret + ",1:" + secret, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			peppers, err := ParsePeppers(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if len(peppers) != len(tt.versions) {
+				t.Fatalf("got %d peppers, want %d", len(peppers), len(tt.versions))
+			}
+			for i, p := range peppers {
+				if p.Version != tt.versions[i] {
+					t.Errorf("pepper %d has version %d, want %d", i, p.Version, tt.versions[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPasswordHasherPepperRotation(t *testing.T) {
+	v1 := Pepper{Version: 1, Key: []byte(strings.Repeat("1", minPepperLength))}
+	v2 := Pepper{Version: 2, Key: []byte(strings.Repeat("2", minPepperLength))}
+	h := NewPasswordHasher(nil)
+
+	plain, err := h.HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.SetPeppers([]Pepper{v1})
+	peppered, err := h.HashPassword("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(peppered, ",keyid=1$") {
+		t.Errorf("hash %q doesn't record pepper version 1", peppered)
+	}
+	h.SetPeppers([]Pepper{v2, v1})
+
+	tests := []struct {
+		name     string
+		hash     string
+		password string
+		match    bool
+		upgrade  bool
+	}{
+		{"unpeppered hash gains the pepper", plain, "correct horse", true, true},
+		{"older pepper verifies and moves on", peppered, "correct horse", true, true},
+		{"wrong password", peppered, "battery staple", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, err := h.CheckPassword(tt.password, tt.hash)
+			if err != nil || ok != tt.match {
+				t.Fatalf("CheckPassword = %v, %v, want %v", ok, err, tt.match)
+			}
+			if !tt.match {
+				return
+			}
+			upgraded, did, err := h.UpgradeHashIfNeeded(tt.password, tt.hash)
+			if err != nil || did != tt.upgrade {
+				t.Fatalf("UpgradeHashIfNeeded upgraded = %v, %v, want %v", did, err, tt.upgrade)
+			}
+			if !strings.Contains(upgraded, ",keyid=2$") {
+				t.Errorf("upgraded hash %q isn't under the current pepper", upgraded)
+			}
+		})
+	}
+
+	// Once version 1 is dropped its hashes no longer verify
+	h.SetPeppers([]Pepper{v2})
+	if _, err := h.CheckPassword("correct horse", peppered); err == nil {
+		t.Error("a hash under a dropped pepper still verified")
+	}
+}
@@ -0,0 +1,122 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+	"simpleapi/pkg/errcodes"
+	"strings"
+)
+
+// ProblemContentType is the RFC 7807 media type for error bodies
+const ProblemContentType = "application/problem+json"
+
+// Problem type URIs, one per domain error. Relative references are allowed by
+// RFC 7807 and resolve against the API's own base URL.
+const (
+	ProblemTypeNotFound     = "/problems/not-found"
+	ProblemTypeConflict     = "/problems/conflict"
+	ProblemTypeValidation   = "/problems/validation"
+	ProblemTypeBadRequest   = "/problems/bad-request"
+	ProblemTypeUnauthorized = "/problems/unauthorized"
+	ProblemTypeForbidden    = "/problems/forbidden"
+	ProblemTypeRateLimited  = "/problems/rate-limited"
+	ProblemTypeInternal     = "/problems/internal"
+)
+
+// ErrorFormat selects the body shape used by WriteError
+type ErrorFormat int
+
+const (
+	// ErrorFormatJSON is our classic {status, statusCode, message, details} envelope
+	ErrorFormatJSON ErrorFormat = iota
+	// ErrorFormatProblem is application/problem+json (RFC 7807)
+	ErrorFormatProblem
+)
+
+// ParseErrorFormat maps the ERROR_FORMAT env value to an ErrorFormat.
+// Anything other than "problem" keeps the classic envelope.
+func ParseErrorFormat(s string) ErrorFormat {
+	if strings.EqualFold(strings.TrimSpace(s), "problem") {
+		return ErrorFormatProblem
+	}
+	return ErrorFormatJSON
+}
+
+// ProblemDetails is the RFC 7807 body. Code (see package errcodes) and Errors,
+// carrying field-level validation details, are our extension members.
+type ProblemDetails struct {
+	Type     string        `json:"type"`
+	Title    string        `json:"title"`
+	Status   int           `json:"status"`
+	Code     errcodes.Code `json:"code"`
+	Detail   string        `json:"detail,omitempty"`
+	Instance string        `json:"instance,omitempty"`
+	Errors   any           `json:"errors,omitempty"`
+}
+
+// ProblemPreferrer is implemented by response writers that know the response
+// takes problem+json errors, because the client asked or ERROR_FORMAT says so
+// (see middlewares.NegotiateErrorFormat)
+type ProblemPreferrer interface {
+	WantsProblemJSON() bool
+}
+
+// WantsProblem reports whether errors written to w take problem+json, looking
+// through the writers that wrap the one NegotiateErrorFormat marked
+func WantsProblem(w http.ResponseWriter) bool {
+	p, ok := writerAs[ProblemPreferrer](w)
+	return ok && p.WantsProblemJSON()
+}
+
+// writerAs finds the first writer in w's chain of wrappers (see Unwrap) that is a T
+func writerAs[T any](w http.ResponseWriter) (T, bool) {
+	for {
+		if t, ok := w.(T); ok {
+			return t, true
+		}
+		u, ok := w.(interface{ Unwrap() http.ResponseWriter })
+		if !ok {
+			var zero T
+			return zero, false
+		}
+		w = u.Unwrap()
+	}
+}
+
+// problemTypeFor picks the type URI from the status code; a 400 carrying
+// details is a validation failure rather than a generic bad request
+func problemTypeFor(code int, hasDetails bool) string {
+	switch {
+	case code == http.StatusNotFound:
+		return ProblemTypeNotFound
+	case code == http.StatusConflict:
+		return ProblemTypeConflict
+	case code == http.StatusBadRequest && hasDetails:
+		return ProblemTypeValidation
+	case code == http.StatusBadRequest:
+		return ProblemTypeBadRequest
+	case code == http.StatusUnauthorized:
+		return ProblemTypeUnauthorized
+	case code == http.StatusForbidden:
+		return ProblemTypeForbidden
+	case code == http.StatusTooManyRequests:
+		return ProblemTypeRateLimited
+	case code >= 500:
+		return ProblemTypeInternal
+	}
+	return "about:blank"
+}
+
+func writeProblem(w http.ResponseWriter, code int, errCode errcodes.Code, message string, details any) {
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(code)
+
+	json.NewEncoder(w).Encode(ProblemDetails{
+		Type:   problemTypeFor(code, details != nil),
+		Title:  http.StatusText(code),
+		Status: code,
+		Code:   errCode,
+		Detail: message,
+		Errors: details,
+	})
+}
@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"fmt"
+	"maps"
+	"net/http"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// EchoQuery starts the QueryMeta of a list endpoint from the filter struct
+// BindQuery filled. Every `query` field with a value is echoed under its
+// parameter name, except fields tagged `echo:"-"` (sorting, which the caller
+// settles with ApplySort). Parameters the struct doesn't know are reported as
+// ignored, unless listed in extra because the handler reads them itself (e.g. format).
+func EchoQuery(r *http.Request, bound any, extra ...string) *QueryMeta {
+	meta := &QueryMeta{Filters: make(map[string]any)}
+	known := make(map[string]bool)
+	for _, name := range extra {
+		known[name] = true
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(bound))
+	for i := range v.NumField() {
+		field := v.Type().Field(i)
+		name := field.Tag.Get("query")
+		if name == "" || !field.IsExported() {
+			continue
+		}
+		known[name] = true
+		fv := v.Field(i)
+		if field.Tag.Get("echo") == "-" || fv.IsZero() {
+			continue
+		}
+		meta.Filters[name] = reflect.Indirect(fv).Interface()
+	}
+
+	query := r.URL.Query()
+	for _, name := range slices.Sorted(maps.Keys(query)) {
+		if !known[name] {
+			meta.Ignored = append(meta.Ignored, IgnoredParam{Param: name, Value: query.Get(name), Reason: "unknown parameter"})
+		}
+	}
+	return meta
+}
+
+// ApplySort settles ?sortby= and ?order=: a field outside sortable is dropped
+// (the list keeps its default order) and order becomes ASC or DESC. Whatever
+// was dropped or defaulted is recorded in meta.
+func (meta *QueryMeta) ApplySort(sortBy, order *string, sortable []string) {
+	if *sortBy != "" && !slices.Contains(sortable, *sortBy) {
+		meta.Ignored = append(meta.Ignored, IgnoredParam{
+			Param:  "sortby",
+			Value:  *sortBy,
+			Reason: fmt.Sprintf("not a sortable field (%s)", strings.Join(sortable, ", ")),
+		})
+		*sortBy = ""
+	}
+
+	given := *order
+	*order = strings.ToUpper(*order)
+	if *order != "DESC" {
+		*order = "ASC"
+	}
+	switch {
+	case given != "" && *sortBy == "":
+		meta.Ignored = append(meta.Ignored, IgnoredParam{Param: "order", Value: given, Reason: "only applies with a valid sortby"})
+		*order = ""
+	case given != "" && !strings.EqualFold(given, *order):
+		meta.Ignored = append(meta.Ignored, IgnoredParam{Param: "order", Value: given, Reason: "must be ASC or DESC; sorted ASC"})
+	}
+
+	if *sortBy != "" {
+		meta.Sort = &SortMeta{Field: *sortBy, Order: *order}
+	}
+}
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"net/http"
+	"simpleapi/pkg/redact"
+)
+
+// FieldRedactor is implemented by response writers whose responses hide the
+// fields their viewer may not see (see package redact and middlewares.RedactFields)
+type FieldRedactor interface {
+	RedactsFields() bool
+}
+
+// RedactsFields reports whether responses written to w are redacted, looking
+// through the writers that wrap the one RedactFields marked
+func RedactsFields(w http.ResponseWriter) bool {
+	f, ok := writerAs[FieldRedactor](w)
+	return ok && f.RedactsFields()
+}
+
+// ViewerWriter is implemented by response writers that know who the response
+// is for (see ForViewer). Without one the viewer is anonymous.
+type ViewerWriter interface {
+	Viewer() redact.Viewer
+}
+
+// ForViewer marks w as answering viewer. The auth middleware does it for
+// every authenticated request; handlers that authenticate someone themselves
+// (login, registration) do it for the account they answer about.
+func ForViewer(w http.ResponseWriter, viewer redact.Viewer) http.ResponseWriter {
+	return &viewerWriter{ResponseWriter: w, viewer: viewer}
+}
+
+type viewerWriter struct {
+	http.ResponseWriter
+	viewer redact.Viewer
+}
+
+func (v *viewerWriter) Viewer() redact.Viewer {
+	return v.viewer
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (v *viewerWriter) Unwrap() http.ResponseWriter {
+	return v.ResponseWriter
+}
+
+// redactFor hides what w's viewer may not see of data, when redaction is on
+func redactFor(w http.ResponseWriter, data any) any {
+	if !RedactsFields(w) {
+		return data
+	}
+	var viewer redact.Viewer
+	if vw, ok := writerAs[ViewerWriter](w); ok {
+		viewer = vw.Viewer()
+	}
+	return redact.Apply(data, viewer)
+}
@@ -0,0 +1,163 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+)
+
+// SigningAlgorithm is advertised next to every document signature
+const SigningAlgorithm = "HS256"
+
+// ErrNoSigningKey means TRANSCRIPT_SIGNING_KEY was never configured
+var ErrNoSigningKey = errors.New("document signing key is not configured")
+
+// DocumentSigning signs exported documents and checks them when they come back
+type DocumentSigning interface {
+	Sign(payload []byte) (signature, keyID string, err error)
+	Verify(payload []byte, signature, keyID string) bool
+}
+
+// DocumentSigner signs exported documents (transcripts, ID card QR codes,
+// download links) with HMAC-SHA256. It is kept apart from the JWT keys so
+// rotating one doesn't invalidate the other. Each signature comes with the ID
+// of its key, and like TokenService the signer keeps the keys it replaced, so
+// a transcript exported before a rotation still verifies after it.
+type DocumentSigner struct {
+	mu   sync.RWMutex
+	keys [][]byte
+}
+
+var _ DocumentSigning = (*DocumentSigner)(nil)
+
+// NewDocumentSigner is the constructor. keys go current first; with no current
+// key, signing answers ErrNoSigningKey and nothing verifies.
+func NewDocumentSigner(keys ...[]byte) *DocumentSigner {
+	s := &DocumentSigner{}
+	s.SetKeys(keys...)
+	return s
+}
+
+// SetKeys replaces the keys, current first. Documents signed with a key left
+// out stop verifying immediately.
+func (s *DocumentSigner) SetKeys(keys ...[]byte) {
+	var clean [][]byte
+	if len(keys) > 0 && len(keys[0]) > 0 {
+		for _, k := range keys {
+			if len(k) > 0 {
+				clean = append(clean, k)
+			}
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keys = clean
+}
+
+// Rotate makes key the current one, e.g. from a secrets rotation hook. Every
+// key it replaces keeps verifying: signed documents outlive sessions.
+func (s *DocumentSigner) Rotate(key []byte) error {
+	if len(key) == 0 {
+		return ErrNoSigningKey
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keys := [][]byte{key}
+	for _, k := range s.keys {
+		if string(k) != string(key) {
+			keys = append(keys, k)
+		}
+	}
+	s.keys = keys
+	return nil
+}
+
+// Sign returns the base64url HMAC-SHA256 of payload and the ID of the key used.
+// The key ID is a fingerprint, so verifiers can tell which key signed an older export.
+func (s *DocumentSigner) Sign(payload []byte) (signature, keyID string, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.keys) == 0 {
+		return "", "", ErrNoSigningKey
+	}
+	key := s.keys[0]
+	return base64.RawURLEncoding.EncodeToString(documentMAC(key, payload)), signingKeyID(key), nil
+}
+
+// Verify checks a signature made by Sign in constant time, with the key
+// keyID names. An empty keyID means the current key, for documents signed
+// before they carried one.
+func (s *DocumentSigner) Verify(payload []byte, signature, keyID string) bool {
+	got, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	key := s.key(keyID)
+	if key == nil {
+		return false
+	}
+	return hmac.Equal(got, documentMAC(key, payload))
+}
+
+// key finds a key by ID, nil if it isn't kept (anymore)
+func (s *DocumentSigner) key(keyID string) []byte {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.keys) == 0 {
+		return nil
+	}
+	if keyID == "" {
+		return s.keys[0]
+	}
+	for _, k := range s.keys {
+		if signingKeyID(k) == keyID {
+			return k
+		}
+	}
+	return nil
+}
+
+// SignToken signs payload into "payload.keyID.signature", the form QR codes
+// and download links carry
+func SignToken(s DocumentSigning, payload string) (token, keyID string, err error) {
+	signature, keyID, err := s.Sign([]byte(payload))
+	if err != nil {
+		return "", "", err
+	}
+	return payload + "." + keyID + "." + signature, keyID, nil
+}
+
+// VerifyToken checks a token made by SignToken and returns its payload. The
+// older "payload.signature" form, without a key ID, is checked against the
+// current key.
+func VerifyToken(s DocumentSigning, token string) (payload string, ok bool) {
+	parts := strings.Split(token, ".")
+	var keyID, signature string
+	switch len(parts) {
+	case 2:
+		signature = parts[1]
+	case 3:
+		keyID, signature = parts[1], parts[2]
+	default:
+		return "", false
+	}
+	if !s.Verify([]byte(parts[0]), signature, keyID) {
+		return "", false
+	}
+	return parts[0], true
+}
+
+func documentMAC(key, payload []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func signingKeyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestDocumentSignerRotation(t *testing.T) {
+	s := NewDocumentSigner([]byte("first"))
+	payload := []byte(`{"student_id":7}`)
+	oldSig, oldKID, err := s.Sign(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Rotate([]byte("second")); err != nil {
+		t.Fatal(err)
+	}
+	newSig, newKID, err := s.Sign(payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newKID == oldKID {
+		t.Fatalf("key ID didn't change with the key: %s", newKID)
+	}
+
+	tests := []struct {
+		name      string
+		signature string
+		keyID     string
+		want      bool
+	}{
+		{"current key", newSig, newKID, true},
+		{"rotated key by its ID", oldSig, oldKID, true},
+		{"no key ID means the current key", newSig, "", true},
+		{"rotated key without its ID", oldSig, "", false},
+		{"signature under the wrong key ID", oldSig, newKID, false},
+		{"unknown key ID", newSig, "deadbeef", false},
+		{"not base64url", "!!", newKID, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := s.Verify(payload, tt.signature, tt.keyID); got != tt.want {
+				t.Errorf("Verify = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if s.Verify([]byte(`{"student_id":8}`), newSig, newKID) {
+		t.Error("a tampered payload verified")
+	}
+
+	s.SetKeys([]byte("second"))
+	if s.Verify(payload, oldSig, oldKID) {
+		t.Error("a key left out of SetKeys still verifies")
+	}
+}
+
+func TestDocumentSignerWithoutKey(t *testing.T) {
+	s := NewDocumentSigner(nil, []byte("previous"))
+	if _, _, err := s.Sign([]byte("x")); !errors.Is(err, ErrNoSigningKey) {
+		t.Errorf("Sign err = %v, want ErrNoSigningKey", err)
+	}
+	if err := s.Rotate(nil); !errors.Is(err, ErrNoSigningKey) {
+		t.Errorf("Rotate(nil) err = %v, want ErrNoSigningKey", err)
+	}
+}
+
+func TestSignToken(t *testing.T) {
+	s := NewDocumentSigner([]byte("first"))
+	token, keyID, err := SignToken(s, "eyJ0eXAiOiJpZCJ9")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(token, "eyJ0eXAiOiJpZCJ9."+keyID+".") {
+		t.Fatalf("token = %s", token)
+	}
+	s.Rotate([]byte("second"))
+	if payload, ok := VerifyToken(s, token); !ok || payload != "eyJ0eXAiOiJpZCJ9" {
+		t.Errorf("VerifyToken after a rotation = %q, %v", payload, ok)
+	}
+
+	// Tokens issued before they named their key: checked with the current one
+	signature, _, _ := s.Sign([]byte("legacy"))
+	if _, ok := VerifyToken(s, "legacy."+signature); !ok {
+		t.Error("two-part token didn't verify")
+	}
+	for _, bad := range []string{"", "legacy", "a.b.c.d", "legacy." + keyID + "." + signature} {
+		if _, ok := VerifyToken(s, bad); ok {
+			t.Errorf("VerifyToken(%q) passed", bad)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// TokenDelivery decides how login hands out the JWT, and so where Protect looks for it.
+// main reads it from AUTH_TOKEN_DELIVERY and gives it to the login handler, Protect
+// and the rate limiter; the zero value negotiates. A login never returns both: a token in the JSON body next to an HttpOnly cookie
+// only invites web apps to copy it into localStorage.
+type TokenDelivery int
+
+const (
+	// TokenDeliveryNegotiate gives web clients the cookie and API clients (see
+	// IsAPIClient) the bearer token, and accepts either on protected routes
+	TokenDeliveryNegotiate TokenDelivery = iota
+	// TokenDeliveryCookie is cookie only, for deployments that only serve the web app
+	TokenDeliveryCookie
+	// TokenDeliveryBearer is bearer only, for deployments without a browser front end
+	TokenDeliveryBearer
+)
+
+// ParseTokenDelivery maps the AUTH_TOKEN_DELIVERY env value; empty means negotiate
+func ParseTokenDelivery(s string) (TokenDelivery, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "negotiate":
+		return TokenDeliveryNegotiate, nil
+	case "cookie":
+		return TokenDeliveryCookie, nil
+	case "bearer":
+		return TokenDeliveryBearer, nil
+	}
+	return TokenDeliveryNegotiate, fmt.Errorf("unknown token delivery %q, want negotiate, cookie or bearer", s)
+}
+
+// IsAPIClient reports a non-browser client: it must both accept JSON and say
+// so explicitly with "X-Client-Type: api", which a browser never sends on its own
+func IsAPIClient(r *http.Request) bool {
+	return strings.EqualFold(strings.TrimSpace(r.Header.Get("X-Client-Type")), "api") &&
+		strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+// DeliverAsBearer reports whether this login should get the token in the body
+// (true) or as the session cookie (false)
+func (d TokenDelivery) DeliverAsBearer(r *http.Request) bool {
+	switch d {
+	case TokenDeliveryCookie:
+		return false
+	case TokenDeliveryBearer:
+		return true
+	}
+	return IsAPIClient(r)
+}
+
+// AcceptsCookieToken and AcceptsBearerToken tell Protect which credentials the mode allows
+func (d TokenDelivery) AcceptsCookieToken() bool { return d != TokenDeliveryBearer }
+
+func (d TokenDelivery) AcceptsBearerToken() bool { return d != TokenDeliveryCookie }
+
+// Negotiates is true when the login response depends on request headers
+func (d TokenDelivery) Negotiates() bool { return d == TokenDeliveryNegotiate }